@@ -20,10 +20,19 @@ func ArmorKey(input []byte) (string, error) {
 
 // ArmorWithTypeBuffered returns a io.WriteCloser which, when written to, writes
 // armored data to w with the given armorType.
+// The output always uses DefaultLineLength, a checksum and \n line endings,
+// use ArmorWithTypeBufferedAndOptions to change them.
 func ArmorWithTypeBuffered(w io.Writer, armorType string) (io.WriteCloser, error) {
 	return armor.Encode(w, armorType, nil)
 }
 
+// ArmorWithTypeBufferedAndOptions returns a io.WriteCloser which, when written to,
+// writes armored data to w with the given armorType and options.
+// The checksum is only written when the WriteCloser is closed.
+func ArmorWithTypeBufferedAndOptions(w io.Writer, armorType string, options *Options) (io.WriteCloser, error) {
+	return encode(w, armorType, nil, options)
+}
+
 // ArmorWithType armors input with the given armorType.
 func ArmorWithType(input []byte, armorType string) (string, error) {
 	return armorWithTypeAndHeaders(input, armorType, internal.ArmorHeaders)
@@ -42,7 +51,36 @@ func ArmorWithTypeAndCustomHeaders(input []byte, armorType, version, comment str
 	return armorWithTypeAndHeaders(input, armorType, headers)
 }
 
+// ArmorWithTypeAndOptions armors input with the given armorType, headers and
+// options for the line length and checksum.
+// Empty headers are omitted.
+func ArmorWithTypeAndOptions(input []byte, armorType, version, comment string, options *Options) (string, error) {
+	headers := make(map[string]string)
+	if version != "" {
+		headers["Version"] = version
+	}
+	if comment != "" {
+		headers["Comment"] = comment
+	}
+	var b bytes.Buffer
+	w, err := encode(&b, armorType, sortHeaders(headers), options)
+	if err != nil {
+		return "", err
+	}
+	if _, err = w.Write(input); err != nil {
+		return "", errors.Wrap(err, "gopengp: unable to write armored to buffer")
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, "gopengp: unable to close armor buffer")
+	}
+	return b.String(), nil
+}
+
 // Unarmor unarmors an armored input into a byte array.
+// The framing variants written by some implementations, e.g. CRLF line
+// endings, a missing blank line after the headers, a checksum on the last
+// line of data or no checksum, are tolerated unless strict mode is set,
+// see SetStrictMode and GetFramingIssues.
 func Unarmor(input string) ([]byte, error) {
 	b, err := internal.Unarmor(input)
 	if err != nil {
@@ -51,6 +89,21 @@ func Unarmor(input string) ([]byte, error) {
 	return ioutil.ReadAll(b.Body)
 }
 
+// SetStrictMode sets whether unarmoring rejects the framing variants which are
+// otherwise tolerated, including a missing checksum. CRLF line endings are
+// accepted in strict mode too, since they can be written with Options.CRLF.
+// Armoring always uses the canonical framing.
+func SetStrictMode(strict bool) {
+	internal.SetStrictArmor(strict)
+}
+
+// GetFramingIssues returns the framing variants of the armored input which
+// are tolerated when unarmoring, empty if the framing is canonical.
+func GetFramingIssues(input string) []string {
+	_, issues := internal.NormalizeArmor(input)
+	return issues
+}
+
 func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]string) (string, error) {
 	var b bytes.Buffer
 
@@ -67,3 +120,29 @@ func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]
 	}
 	return b.String(), nil
 }
+
+// SanitizeArmor repairs the armored blocks of input altered by a copy-paste
+// from rich text, e.g. HTML mail: it replaces the non-ASCII spaces, the
+// typographic dashes of the armor lines and quotes of the headers, drops the
+// zero-width characters and the indentation, and joins the wrapped header
+// lines. Returns the sanitized input and the repairs which were made.
+// Unarmor never makes these repairs, see UnarmorLenient.
+func SanitizeArmor(input string) (sanitized string, fixes []string) {
+	return internal.SanitizeArmor(input)
+}
+
+// UnarmorLenient unarmors an armored input after repairing it like
+// SanitizeArmor, and tolerates the framing variants even in strict mode.
+// Returns the repairs and framing variants which were fixed, see
+// GetFramingIssues.
+func UnarmorLenient(input string) (data []byte, fixes []string, err error) {
+	b, fixes, err := internal.UnarmorLenient(input)
+	if err != nil {
+		return nil, fixes, errors.Wrap(err, "gopengp: unable to unarmor")
+	}
+	data, err = ioutil.ReadAll(b.Body)
+	if err != nil {
+		return nil, fixes, errors.Wrap(err, "gopengp: unable to unarmor")
+	}
+	return data, fixes, nil
+}
@@ -0,0 +1,169 @@
+package armor
+
+import (
+	"encoding/base64"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLineLength is the length of the base64 lines of the armor,
+// as emitted by ArmorWithType and ArmorWithTypeBuffered.
+const DefaultLineLength = 64
+
+// MaxLineLength is the maximum length of the base64 lines of the armor.
+const MaxLineLength = 76
+
+// Options configures the output of the armor encoder.
+// Parsing accepts any line length, with or without checksum.
+type Options struct {
+	// LineLength is the length of the base64 lines, a multiple of 4 up to
+	// MaxLineLength. If 0, DefaultLineLength is used.
+	LineLength int
+	// OmitChecksum omits the optional CRC24 footer, deprecated by RFC 9580.
+	OmitChecksum bool
+	// CRLF ends all the lines with \r\n instead of \n, e.g. to embed the
+	// armor in an SMTP payload.
+	CRLF bool
+}
+
+// NewOptions creates armor options with the given line length and checksum
+// setting.
+func NewOptions(lineLength int, withChecksum bool) *Options {
+	return &Options{LineLength: lineLength, OmitChecksum: !withChecksum}
+}
+
+const crc24Init = 0xb704ce
+const crc24Poly = 0x1864cfb
+
+// crc24 calculates the OpenPGP checksum as specified in RFC 4880, section 6.1.
+func crc24(crc uint32, d []byte) uint32 {
+	for _, b := range d {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc
+}
+
+// lineBreaker splits the written data into lines of lineLength bytes.
+type lineBreaker struct {
+	out        io.Writer
+	lineLength int
+	newline    string
+	used       int
+}
+
+func (l *lineBreaker) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		if l.used == l.lineLength {
+			if _, err = io.WriteString(l.out, l.newline); err != nil {
+				return n, err
+			}
+			l.used = 0
+		}
+		chunk := l.lineLength - l.used
+		if chunk > len(b) {
+			chunk = len(b)
+		}
+		if _, err = l.out.Write(b[:chunk]); err != nil {
+			return n, err
+		}
+		l.used += chunk
+		n += chunk
+		b = b[chunk:]
+	}
+	return n, nil
+}
+
+// encoder writes the armor body and, when closed, the optional checksum and
+// the armor footer.
+type encoder struct {
+	out          io.Writer
+	breaker      *lineBreaker
+	b64          io.WriteCloser
+	crc          uint32
+	omitChecksum bool
+	armorType    string
+}
+
+func (e *encoder) Write(data []byte) (n int, err error) {
+	e.crc = crc24(e.crc, data)
+	return e.b64.Write(data)
+}
+
+func (e *encoder) Close() (err error) {
+	if err = e.b64.Close(); err != nil {
+		return err
+	}
+	newline := e.breaker.newline
+	footer := ""
+	if e.breaker.used > 0 {
+		footer = newline
+	}
+	if !e.omitChecksum {
+		checksum := []byte{byte(e.crc >> 16), byte(e.crc >> 8), byte(e.crc)}
+		footer += "=" + base64.StdEncoding.EncodeToString(checksum) + newline
+	}
+	footer += "-----END " + e.armorType + "-----"
+	_, err = io.WriteString(e.out, footer)
+	return err
+}
+
+// sortHeaders returns the headers sorted by key.
+func sortHeaders(headers map[string]string) []Header {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]Header, 0, len(keys))
+	for _, k := range keys {
+		sorted = append(sorted, Header{Key: k, Value: headers[k]})
+	}
+	return sorted
+}
+
+// encode returns a WriteCloser which armors the data written to it into out,
+// with the headers in the given order.
+func encode(out io.Writer, armorType string, headers []Header, options *Options) (io.WriteCloser, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	lineLength := options.LineLength
+	if lineLength == 0 {
+		lineLength = DefaultLineLength
+	}
+	if lineLength < 0 || lineLength > MaxLineLength || lineLength%4 != 0 {
+		return nil, errors.Errorf("gopenpgp: invalid armor line length %d", lineLength)
+	}
+
+	newline := "\n"
+	if options.CRLF {
+		newline = "\r\n"
+	}
+	header := "-----BEGIN " + armorType + "-----" + newline
+	for _, h := range headers {
+		header += h.Key + ": " + h.Value + newline
+	}
+	header += newline
+	if _, err := io.WriteString(out, header); err != nil {
+		return nil, err
+	}
+
+	e := &encoder{
+		out:          out,
+		breaker:      &lineBreaker{out: out, lineLength: lineLength, newline: newline},
+		crc:          crc24Init,
+		omitChecksum: options.OmitChecksum,
+		armorType:    armorType,
+	}
+	e.b64 = base64.NewEncoder(base64.StdEncoding, e.breaker)
+	return e, nil
+}
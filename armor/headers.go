@@ -0,0 +1,62 @@
+package armor
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Header is an armor header line, e.g. "Comment: https://gopenpgp.org".
+type Header struct {
+	Key   string
+	Value string
+}
+
+// ReadHeaders returns the headers of the first armored block of input,
+// in their order, including repeated and unknown headers.
+func ReadHeaders(input string) ([]Header, error) {
+	lines := strings.Split(input, "\n")
+	begin := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "-----BEGIN ") {
+			begin = i
+			break
+		}
+	}
+	if begin < 0 {
+		return nil, errors.New("gopenpgp: armor header line not found")
+	}
+
+	headers := []Header{}
+	for _, line := range lines[begin+1:] {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			break
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			// No blank line after the header line
+			break
+		}
+		headers = append(headers, Header{Key: line[:i], Value: strings.TrimLeft(line[i+1:], " ")})
+	}
+	return headers, nil
+}
+
+// ArmorWithTypeAndHeaders armors input with the given armorType and headers,
+// written in the given order.
+func ArmorWithTypeAndHeaders(input []byte, armorType string, headers []Header) (string, error) {
+	var b bytes.Buffer
+	w, err := encode(&b, armorType, headers, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err = w.Write(input); err != nil {
+		return "", errors.Wrap(err, "gopengp: unable to write armored to buffer")
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, "gopengp: unable to close armor buffer")
+	}
+	return b.String(), nil
+}
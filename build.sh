@@ -88,6 +88,7 @@ import github.com/ProtonMail/gopenpgp/v2/constants
 import github.com/ProtonMail/gopenpgp/v2/models
 import github.com/ProtonMail/gopenpgp/v2/subtle
 import github.com/ProtonMail/gopenpgp/v2/helper
+import github.com/ProtonMail/gopenpgp/v2/helper/mobile
 
 ######## ======== Main ===========
 
@@ -0,0 +1,80 @@
+package constants
+
+// Cipher is a symmetric cipher, whose value is its OpenPGP identifier. It is
+// the typed alternative to the cipher suite names, e.g. AES256, which remain
+// accepted by the functions taking a name.
+type Cipher int
+
+// Symmetric ciphers.
+const (
+	CIPHER_3DES   Cipher = 2
+	CIPHER_CAST5  Cipher = 3
+	CIPHER_AES128 Cipher = 7
+	CIPHER_AES192 Cipher = 8
+	CIPHER_AES256 Cipher = 9
+)
+
+// String returns the cipher suite name of the cipher, e.g. AES256, or
+// "unknown".
+func (cipher Cipher) String() string {
+	switch cipher {
+	case CIPHER_3DES:
+		return ThreeDES
+	case CIPHER_CAST5:
+		return CAST5
+	case CIPHER_AES128:
+		return AES128
+	case CIPHER_AES192:
+		return AES192
+	case CIPHER_AES256:
+		return AES256
+	}
+	return "unknown"
+}
+
+// ParseCipher returns the cipher with the given cipher suite name, and false
+// if the name is unknown.
+func ParseCipher(name string) (Cipher, bool) {
+	switch name {
+	case ThreeDES, TripleDES:
+		return CIPHER_3DES, true
+	case CAST5:
+		return CIPHER_CAST5, true
+	case AES128:
+		return CIPHER_AES128, true
+	case AES192:
+		return CIPHER_AES192, true
+	case AES256:
+		return CIPHER_AES256, true
+	}
+	return 0, false
+}
+
+// Compression is a compression algorithm, whose value is its OpenPGP
+// identifier, one of COMPRESSION_NONE, COMPRESSION_ZIP and COMPRESSION_ZLIB.
+type Compression int
+
+// String returns the name of the compression algorithm, "none", "zip" or
+// "zlib", or "unknown".
+func (compression Compression) String() string {
+	switch compression {
+	case COMPRESSION_NONE:
+		return "none"
+	case COMPRESSION_ZIP:
+		return "zip"
+	case COMPRESSION_ZLIB:
+		return "zlib"
+	}
+	return "unknown"
+}
+
+// ParseCompression returns the compression algorithm with the given name, as
+// returned by Compression.String, and false if the name is unknown.
+func ParseCompression(name string) (Compression, bool) {
+	for _, compression := range []Compression{COMPRESSION_NONE, COMPRESSION_ZIP, COMPRESSION_ZLIB} {
+		if name == compression.String() {
+			return compression, true
+		}
+	}
+	return 0, false
+}
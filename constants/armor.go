@@ -10,3 +10,10 @@ const (
 	PublicKeyHeader    = "PGP PUBLIC KEY BLOCK"
 	PrivateKeyHeader   = "PGP PRIVATE KEY BLOCK"
 )
+
+// Framings of a PGP message, as detected by crypto.NewPGPMessageAuto.
+const (
+	MESSAGE_FORMAT_BINARY    int = 0
+	MESSAGE_FORMAT_ARMORED   int = 1
+	MESSAGE_FORMAT_CLEARTEXT int = 2
+)
@@ -10,12 +10,166 @@ const (
 	AES256    = "aes256"
 )
 
+// Hash function names.
+const (
+	SHA256 = "sha256"
+	SHA384 = "sha384"
+	SHA512 = "sha512"
+	// Deprecated hash functions, see crypto.DeprecationEvent.
+	MD5       = "md5"
+	SHA1      = "sha1"
+	RIPEMD160 = "ripemd160"
+)
+
+// Operations reporting deprecated material, see crypto.DeprecationEvent.
+const (
+	DEPRECATION_OPERATION_KEY_PARSING  = "key_parsing"
+	DEPRECATION_OPERATION_VERIFICATION = "verification"
+	DEPRECATION_OPERATION_DECRYPTION   = "decryption"
+)
+
 const (
 	SIGNATURE_OK          int = 0
 	SIGNATURE_NOT_SIGNED  int = 1
 	SIGNATURE_NO_VERIFIER int = 2
 	SIGNATURE_FAILED      int = 3
+	// The signature is valid, but its key is too weak for the verification
+	// policy, see crypto.VerifyOptions.
+	SIGNATURE_WEAK_KEY int = 4
+	// The signature is valid, but its key is not one of the required
+	// signers, see crypto.VerifyOptions.
+	SIGNATURE_WRONG_SIGNER int = 5
+	// The signature is valid, but only with the key embedded in the message,
+	// which proves nothing about the signer, see
+	// crypto.VerifyOptions.
+	SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY int = 6
+	// The signature is valid, but was created before the maximum age allowed
+	// by the verification, see crypto.VerifyOptions.
+	SIGNATURE_TOO_OLD int = 7
+)
+
+// Policies for the signatures made by weak keys, i.e. RSA keys of less than
+// 2048 bits and DSA keys, see crypto.VerifyOptions.
+const (
+	// As set by crypto.SetRejectWeakSigningKeys, accepted by default.
+	WEAK_SIGNING_KEYS_DEFAULT int = 0
+	// Accepted.
+	WEAK_SIGNING_KEYS_ALLOW int = 1
+	// Rejected with the SIGNATURE_WEAK_KEY status.
+	WEAK_SIGNING_KEYS_REJECT int = 2
+)
+
+// Special verification times, see crypto.ResolveVerifyTime.
+const (
+	// Disables the checks of the signature and key creation and expiration
+	// times.
+	VERIFY_TIME_DISABLED int64 = 0
+	// Verifies at the current time of the time provider, see
+	// crypto.SetTimeProvider.
+	VERIFY_TIME_NOW int64 = -1
+)
+
+// Types of signatures, see crypto.PGPSignature.GetSignatureType.
+const (
+	// Signature of binary data.
+	SIGNATURE_TYPE_BINARY int = 0x00
+	// Signature of canonical text.
+	SIGNATURE_TYPE_TEXT int = 0x01
+	// Third-party confirmation of another signature, see
+	// crypto.KeyRing.SignOverSignature.
+	SIGNATURE_TYPE_THIRD_PARTY_CONFIRMATION int = 0x50
+)
+
+// Hashing of the data by crypto.KeyRing.VerifyDetachedWithMode.
+const (
+	// As determined by the type of the signature packet.
+	SIGNATURE_MODE_AUTO int = 0
+	// As binary data, whatever the type of the signature.
+	SIGNATURE_MODE_BINARY int = 1
+	// As canonical text, whatever the type of the signature.
+	SIGNATURE_MODE_TEXT int = 2
+)
+
+// Presence of the secret material of a key or subkey, see
+// Key.GetSecretKeyPresence.
+const (
+	// Public key only.
+	SECRET_KEY_ABSENT int = 0
+	// Secret key packet without secret material, e.g. the primary key
+	// exported by gpg --export-secret-subkeys (GNU dummy S2K).
+	SECRET_KEY_STUB int = 1
+	// Secret material, possibly locked.
+	SECRET_KEY_PRESENT int = 2
+)
+
+// Framings of the signature of an encrypted and signed message.
+const (
+	// One-pass signature packet before the literal data, signature packet after it.
+	SIGNATURE_FRAMING_ONE_PASS int = 0
+	// Signature packet before the literal data.
+	SIGNATURE_FRAMING_PREFIXED int = 1
+)
+
+// Formats of the literal data of a message, see PlainMessage.GetFormat.
+const (
+	LITERAL_FORMAT_BINARY = "b"
+	LITERAL_FORMAT_TEXT   = "t"
+	// Text, declared to be UTF-8.
+	LITERAL_FORMAT_UTF8 = "u"
+)
+
+// Protection levels of the data packet of a decrypted message, see
+// crypto.PlainMessage.GetProtection. Data packets without integrity
+// protection are rejected.
+const (
+	// The message is not encrypted.
+	PROTECTION_LEVEL_NONE int = 0
+	// Symmetrically encrypted integrity protected data packet (SEIPD),
+	// authenticated by a modification detection code (MDC).
+	PROTECTION_LEVEL_MDC int = 1
+	// AEAD encrypted data packet.
+	PROTECTION_LEVEL_AEAD int = 2
+)
+
+// Algorithms of raw signatures, see crypto.Key.SignRaw. These are not OpenPGP
+// signatures.
+const (
+	// Pure Ed25519 (RFC 8032) over the data, 64-byte signature.
+	RAW_SIGNATURE_ED25519 = "ed25519"
+	// RSASSA-PSS (RFC 8017) with SHA-256, MGF1 with SHA-256 and a 32-byte
+	// salt, signature of the size of the modulus.
+	RAW_SIGNATURE_RSA_PSS_SHA256 = "rsa-pss-sha256"
+	// ECDSA with SHA-256, ASN.1 DER encoded signature (SEQUENCE of r and s).
+	RAW_SIGNATURE_ECDSA_SHA256 = "ecdsa-sha256"
+)
+
+// AEAD modes, see crypto.Protection.
+const (
+	AEAD_MODE_EAX = "eax"
+	AEAD_MODE_OCB = "ocb"
+	AEAD_MODE_GCM = "gcm"
+)
+
+// S2K types of the secret key packets, see crypto.KeyProtectionScheme.
+const (
+	// The secret key material is not encrypted.
+	S2K_TYPE_NONE = "none"
+	// Legacy protection, with a cipher and the simple S2K with MD5.
+	S2K_TYPE_LEGACY   = "legacy"
+	S2K_TYPE_SIMPLE   = "simple"
+	S2K_TYPE_SALTED   = "salted"
+	S2K_TYPE_ITERATED = "iterated"
+	// The secret key material is absent (GnuPG stub).
+	S2K_TYPE_GNU_DUMMY = "gnu-dummy"
 )
 
 const DefaultCompression = 2      // ZLIB
 const DefaultCompressionLevel = 6 // Corresponds to default -1 for ZLIB
+
+// Compression algorithms, see crypto.CompressionOptions. They are untyped,
+// to be passed both as an int and as a Compression.
+const (
+	COMPRESSION_NONE = 0
+	COMPRESSION_ZIP  = 1
+	COMPRESSION_ZLIB = 2
+)
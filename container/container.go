@@ -0,0 +1,173 @@
+// Package container packs many binary OpenPGP messages in a single stream,
+// e.g. for backups. The framing is specific to gopenpgp, it is not part of
+// the OpenPGP standard.
+//
+// A container is the concatenation of its entries, without any header or
+// trailer. Each entry is:
+//   - the length in bytes of the message, as an unsigned LEB128 varint
+//     (encoding/binary.PutUvarint): 7 bits per byte, least significant group
+//     first, with the high bit set on every byte but the last, in at most
+//     MaxLengthSize bytes;
+//   - the binary message itself, i.e. PGPMessage.GetBinary().
+//
+// For example, a 300-byte message is framed as the bytes 0xac 0x02 followed
+// by the 300 bytes of the message. An empty container is an empty stream.
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	goerrors "errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+const (
+	// MaxLengthSize is the maximum size of the length prefix of an entry.
+	MaxLengthSize = binary.MaxVarintLen32
+	// MaxEntryLength is the maximum length of a message in a container.
+	MaxEntryLength = 1<<32 - 1
+)
+
+// ErrCorruptContainer is returned by MessageContainerReader.Next when the
+// framing of the container is invalid, e.g. when it is truncated: the
+// following entries can't be read.
+var ErrCorruptContainer = goerrors.New("gopenpgp: corrupt message container")
+
+// InvalidEntryError is returned by MessageContainerReader.Next when an entry
+// is well framed but doesn't parse as an OpenPGP message. The next entries
+// can still be read.
+type InvalidEntryError struct {
+	// Index of the entry in the container, starting at 0
+	Index int
+	Err   error
+}
+
+func (err *InvalidEntryError) Error() string {
+	return "gopenpgp: invalid message in container entry " + strconv.Itoa(err.Index) + ": " + err.Err.Error()
+}
+
+func (err *InvalidEntryError) Unwrap() error {
+	return err.Err
+}
+
+// WriteMessageContainer writes the binary messages to w, each prefixed by its
+// length, see the package documentation.
+func WriteMessageContainer(w io.Writer, msgs ...*crypto.PGPMessage) error {
+	var prefix [MaxLengthSize]byte
+	for i, msg := range msgs {
+		if msg == nil {
+			return errors.New("gopenpgp: nil message at container entry " + strconv.Itoa(i))
+		}
+		data := msg.GetBinary()
+		if uint64(len(data)) > MaxEntryLength {
+			return errors.New("gopenpgp: message too long for container entry " + strconv.Itoa(i))
+		}
+		n := binary.PutUvarint(prefix[:], uint64(len(data)))
+		if _, err := w.Write(prefix[:n]); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in writing message container")
+		}
+		if _, err := w.Write(data); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in writing message container")
+		}
+	}
+	return nil
+}
+
+// MessageContainerReader reads the messages of a container one at a time,
+// see ReadMessageContainer.
+type MessageContainerReader struct {
+	r     *bufio.Reader
+	index int
+	err   error
+}
+
+// ReadMessageContainer returns a reader of the messages of the container
+// read from r, which is only read as the messages are requested.
+func ReadMessageContainer(r io.Reader) *MessageContainerReader {
+	return &MessageContainerReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next message of the container, or io.EOF after the last
+// one. If the entry isn't a valid OpenPGP message, Next returns an
+// InvalidEntryError, and the next call returns the following entry. If the
+// framing of the container is invalid, Next wraps ErrCorruptContainer, and
+// all the following calls return the same error.
+func (reader *MessageContainerReader) Next() (*crypto.PGPMessage, error) {
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	data, err := reader.readEntry()
+	if err != nil {
+		reader.err = err
+		return nil, err
+	}
+	index := reader.index
+	reader.index++
+	if err := validateMessage(data); err != nil {
+		return nil, &InvalidEntryError{Index: index, Err: err}
+	}
+	return crypto.NewPGPMessage(data), nil
+}
+
+// readEntry reads the length prefix and the data of the next entry.
+func (reader *MessageContainerReader) readEntry() ([]byte, error) {
+	if _, err := reader.r.Peek(1); errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	length, err := binary.ReadUvarint(reader.r)
+	if err != nil {
+		return nil, errors.Wrap(ErrCorruptContainer, "gopenpgp: invalid length of entry "+strconv.Itoa(reader.index))
+	}
+	if length > MaxEntryLength {
+		return nil, errors.Wrap(ErrCorruptContainer, "gopenpgp: entry "+strconv.Itoa(reader.index)+" too long")
+	}
+	// Not allocated upfront, as the length may be corrupt
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, reader.r, int64(length)); err != nil {
+		return nil, errors.Wrap(ErrCorruptContainer, "gopenpgp: truncated entry "+strconv.Itoa(reader.index))
+	}
+	return data.Bytes(), nil
+}
+
+// validateMessage checks that data is a sequence of OpenPGP packets. The
+// bodies of the data packets are skipped, not decrypted nor decompressed.
+func validateMessage(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("gopenpgp: empty message")
+	}
+	packets := packet.NewReader(bytes.NewReader(data))
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in parsing message")
+		}
+
+		var body io.Reader
+		switch p := p.(type) {
+		case *packet.SymmetricallyEncrypted:
+			body = p.Contents
+		case *packet.AEADEncrypted:
+			body = p.Contents
+		case *packet.LiteralData:
+			body = p.Body
+		case *packet.Compressed:
+			// The compressed packet holds the rest of the message
+			return nil
+		}
+		if body != nil {
+			if _, err := io.Copy(ioutil.Discard, body); err != nil {
+				return errors.Wrap(err, "gopenpgp: error in parsing message")
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func encryptWithPassword(t *testing.T, text string) *crypto.PGPMessage {
+	message, err := crypto.EncryptMessageWithPassword(crypto.NewPlainMessageFromString(text), []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	return message
+}
+
+func TestMessageContainer(t *testing.T) {
+	messages := []*crypto.PGPMessage{
+		encryptWithPassword(t, "first"),
+		crypto.NewPGPMessage([]byte("not a message")),
+		encryptWithPassword(t, string(bytes.Repeat([]byte("long "), 100))),
+	}
+	var container bytes.Buffer
+	if err := WriteMessageContainer(&container, messages...); err != nil {
+		t.Fatal("Expected no error while writing container, got:", err)
+	}
+	// The first entry is prefixed by its one-byte length
+	assert.Exactly(t, byte(len(messages[0].GetBinary())), container.Bytes()[0])
+	assert.Exactly(t, messages[0].GetBinary(), container.Bytes()[1:1+len(messages[0].GetBinary())])
+
+	reader := ReadMessageContainer(bytes.NewReader(container.Bytes()))
+	read, err := reader.Next()
+	if err != nil {
+		t.Fatal("Expected no error while reading first entry, got:", err)
+	}
+	assert.Exactly(t, messages[0].GetBinary(), read.GetBinary())
+
+	_, err = reader.Next()
+	var entryErr *InvalidEntryError
+	if !errors.As(err, &entryErr) {
+		t.Fatal("Expected an InvalidEntryError, got:", err)
+	}
+	assert.Exactly(t, 1, entryErr.Index)
+
+	read, err = reader.Next()
+	if err != nil {
+		t.Fatal("Expected no error while reading entry after invalid one, got:", err)
+	}
+	assert.Exactly(t, messages[2].GetBinary(), read.GetBinary())
+	_, err = reader.Next()
+	assert.Exactly(t, io.EOF, err)
+
+	_, err = ReadMessageContainer(bytes.NewReader(nil)).Next()
+	assert.Exactly(t, io.EOF, err)
+}
+
+func TestMessageContainerTruncated(t *testing.T) {
+	var container bytes.Buffer
+	if err := WriteMessageContainer(&container, encryptWithPassword(t, "first"), encryptWithPassword(t, "second")); err != nil {
+		t.Fatal("Expected no error while writing container, got:", err)
+	}
+
+	reader := ReadMessageContainer(bytes.NewReader(container.Bytes()[:container.Len()-1]))
+	if _, err := reader.Next(); err != nil {
+		t.Fatal("Expected no error while reading first entry, got:", err)
+	}
+	_, err := reader.Next()
+	assert.True(t, errors.Is(err, ErrCorruptContainer))
+	_, err = reader.Next()
+	assert.True(t, errors.Is(err, ErrCorruptContainer))
+
+	// Length prefix with more than 32 bits
+	reader = ReadMessageContainer(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x01}))
+	_, err = reader.Next()
+	assert.True(t, errors.Is(err, ErrCorruptContainer))
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+func TestUnarmorFramingVariants(t *testing.T) {
+	canonical, err := NewPGPMessageFromArmored(readTestFile("message_protectionMDC", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	for file, expectedIssues := range map[string][]string{
+		"armor_crlf": {
+			internal.ArmorIssueCRLF,
+			internal.ArmorIssueTrailingSpace,
+		},
+		"armor_inlineChecksum": {
+			internal.ArmorIssueInlineChecksum,
+		},
+		"armor_missingBlankLine": {
+			internal.ArmorIssueMissingBlankLine,
+			internal.ArmorIssueMissingChecksum,
+		},
+	} {
+		armored := readTestFile(file, false)
+		assert.Exactly(t, expectedIssues, armor.GetFramingIssues(armored), file)
+
+		message, err := NewPGPMessageFromArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring "+file+", got:", err)
+		}
+		assert.Exactly(t, canonical.GetBinary(), message.GetBinary(), file)
+
+		// Armoring again uses the canonical framing
+		rearmored, err := message.GetArmored()
+		if err != nil {
+			t.Fatal("Expected no error when armoring, got:", err)
+		}
+		assert.Empty(t, armor.GetFramingIssues(rearmored), file)
+		unarmored, err := armor.Unarmor(rearmored)
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring, got:", err)
+		}
+		assert.Exactly(t, canonical.GetBinary(), unarmored, file)
+
+		armor.SetStrictMode(true)
+		_, err = NewPGPMessageFromArmored(armored)
+		armor.SetStrictMode(false)
+		assert.Error(t, err, file)
+	}
+
+	armor.SetStrictMode(true)
+	defer armor.SetStrictMode(false)
+	_, err = NewPGPMessageFromArmored(readTestFile("message_protectionMDC", false))
+	assert.NoError(t, err)
+}
+
+func TestArmorCRLF(t *testing.T) {
+	message := NewPlainMessageFromString("Hello\r\nWorld!")
+	pgpMessage, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+
+	options := &armor.Options{CRLF: true}
+	armoredMessage, err := pgpMessage.GetArmoredWithCustomHeadersAndOptions("comment", "version", options)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	armoredSignature, err := signature.GetArmoredWithCustomHeadersAndOptions("", "", options)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	lfMessage, err := pgpMessage.GetArmoredWithCustomHeadersAndOptions("comment", "version", nil)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	lfSignature, err := signature.GetArmoredWithCustomHeadersAndOptions("", "", nil)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	assert.NotContains(t, lfMessage, "\r")
+	for armored, lf := range map[string]string{armoredMessage: lfMessage, armoredSignature: lfSignature} {
+		// Every line, including the headers and the checksum, ends with CRLF
+		assert.Exactly(t, lf, strings.ReplaceAll(armored, "\r\n", "\n"))
+		assert.Exactly(t, strings.Count(armored, "\n"), strings.Count(armored, "\r\n"))
+		assert.Exactly(t, []string{internal.ArmorIssueCRLF}, armor.GetFramingIssues(armored))
+	}
+
+	// The streaming writer writes the same output
+	var streamed bytes.Buffer
+	w, err := armor.ArmorWithTypeBufferedAndOptions(&streamed, "PGP MESSAGE", options)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	for _, b := range pgpMessage.GetBinary() {
+		_, _ = w.Write([]byte{b})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	expected, err := armor.ArmorWithTypeAndOptions(pgpMessage.GetBinary(), "PGP MESSAGE", "", "", options)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	assert.Exactly(t, expected, streamed.String())
+
+	// CRLF output is accepted in strict mode and decrypts and verifies identically
+	armor.SetStrictMode(true)
+	defer armor.SetStrictMode(false)
+	parsedMessage, err := NewPGPMessageFromArmored(armoredMessage)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	assert.Exactly(t, pgpMessage.GetBinary(), parsedMessage.GetBinary())
+	decrypted, err := keyRingTestPrivate.Decrypt(parsedMessage, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	parsedSignature, err := NewPGPSignatureFromArmored(armoredSignature)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	assert.Exactly(t, signature.GetBinary(), parsedSignature.GetBinary())
+	assert.NoError(t, keyRingTestPublic.VerifyDetached(message, parsedSignature, GetUnixTime()))
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+func TestUnarmorLenient(t *testing.T) {
+	canonical, err := armor.Unarmor(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	for file, expectedFixes := range map[string][]string{
+		"armor_mangled_nbsp": {
+			internal.ArmorFixUnicodeSpaces,
+			internal.ArmorFixInvisibleChars,
+		},
+		"armor_mangled_wrapped": {
+			internal.ArmorFixDashes,
+			internal.ArmorFixSmartQuotes,
+			internal.ArmorFixWrappedHeaders,
+		},
+		"armor_mangled_indented": {
+			internal.ArmorFixUnicodeSpaces,
+			internal.ArmorFixLeadingSpace,
+			internal.ArmorFixWrappedHeaders,
+			internal.ArmorIssueCRLF,
+			internal.ArmorIssueTrailingSpace,
+		},
+	} {
+		mangled := readTestFile(file, false)
+
+		// The strict parser doesn't repair the armor
+		_, err := armor.Unarmor(mangled)
+		assert.Error(t, err, file)
+
+		unarmored, fixes, err := armor.UnarmorLenient(mangled)
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring "+file+" leniently, got:", err)
+		}
+		assert.Exactly(t, canonical, unarmored, file)
+		assert.Exactly(t, expectedFixes, fixes, file)
+
+		sanitized, _ := armor.SanitizeArmor(mangled)
+		key, err := NewKeyFromArmored(sanitized)
+		if err != nil {
+			t.Fatal("Expected no error when parsing sanitized "+file+", got:", err)
+		}
+		serialized, err := key.GetPublicKey()
+		if err != nil {
+			t.Fatal("Expected no error when serializing key, got:", err)
+		}
+		assert.Exactly(t, canonical, serialized, file)
+	}
+
+	// The canonical armor and the text around the blocks are kept as is
+	armored := readTestFile("keyring_publicKey", false)
+	sanitized, fixes := armor.SanitizeArmor(armored)
+	assert.Exactly(t, armored, sanitized)
+	assert.Empty(t, fixes)
+	mangled := readTestFile("armor_mangled_nbsp", false)
+	sanitized, _ = armor.SanitizeArmor(mangled)
+	assert.Contains(t, sanitized, "here is my key:")
+	assert.Contains(t, sanitized, "Best regards")
+
+	armor.SetStrictMode(true)
+	defer armor.SetStrictMode(false)
+	unarmored, _, err := armor.UnarmorLenient(mangled)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring leniently in strict mode, got:", err)
+	}
+	assert.Exactly(t, canonical, unarmored)
+}
@@ -6,7 +6,6 @@ import (
 	"io/ioutil"
 	"runtime"
 	"sync"
-	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
@@ -21,14 +20,20 @@ type AttachmentProcessor struct {
 	done             sync.WaitGroup
 	split            *PGPSplitMessage
 	garbageCollector int
+	processed        int64
 	err              error
 }
 
 // Process writes attachment data to be encrypted.
+// It does nothing once the processor is aborted.
 func (ap *AttachmentProcessor) Process(plainData []byte) {
+	if ap.err != nil {
+		return
+	}
 	if _, err := (*ap.w).Write(plainData); err != nil {
 		panic(err)
 	}
+	ap.processed += int64(len(plainData))
 	if ap.garbageCollector > 0 {
 		defer runtime.GC()
 	}
@@ -64,11 +69,43 @@ func (ap *AttachmentProcessor) Finish() (*PGPSplitMessage, error) {
 	return splitMsg, nil
 }
 
+// GetProcessedBytes returns the number of bytes of the attachment processed,
+// for progress reporting.
+func (ap *AttachmentProcessor) GetProcessedBytes() int64 {
+	return ap.processed
+}
+
+// Abort stops the encryption and discards the encrypted data, after which
+// Finish returns ErrAttachmentAborted.
+func (ap *AttachmentProcessor) Abort() {
+	if ap.err != nil {
+		return
+	}
+	if ap.pipe != nil {
+		_ = ap.pipe.CloseWithError(ErrAttachmentAborted)
+	}
+	ap.done.Wait()
+	ap.err = ErrAttachmentAborted
+	ap.w = nil
+	ap.split = nil
+}
+
 // newAttachmentProcessor creates an AttachmentProcessor which can be used to encrypt
 // a file. It takes an estimatedSize and fileName as hints about the file.
 func (keyRing *KeyRing) newAttachmentProcessor(
 	estimatedSize int, filename string, isBinary bool, modTime uint32, garbageCollector int,
 ) (*AttachmentProcessor, error) {
+	config := &packet.Config{
+		Rand:          getRandom(),
+		DefaultCipher: packet.CipherAES256,
+		Time:          getTimeGenerator(),
+	}
+
+	recipients, err := keyRing.getEncryptionEntities(config)
+	if err != nil {
+		return nil, err
+	}
+
 	attachmentProc := &AttachmentProcessor{}
 	// You could also add these one at a time if needed.
 	attachmentProc.done.Add(1)
@@ -77,12 +114,7 @@ func (keyRing *KeyRing) newAttachmentProcessor(
 	hints := &openpgp.FileHints{
 		FileName: filename,
 		IsBinary: isBinary,
-		ModTime:  time.Unix(int64(modTime), 0),
-	}
-
-	config := &packet.Config{
-		DefaultCipher: packet.CipherAES256,
-		Time:          getTimeGenerator(),
+		ModTime:  getModTime(modTime),
 	}
 
 	reader, writer := io.Pipe()
@@ -102,7 +134,7 @@ func (keyRing *KeyRing) newAttachmentProcessor(
 
 	var ew io.WriteCloser
 	var encryptErr error
-	ew, encryptErr = openpgp.Encrypt(writer, keyRing.entities, nil, hints, config)
+	ew, encryptErr = openpgp.Encrypt(writer, recipients, nil, hints, config)
 	if encryptErr != nil {
 		return nil, errors.Wrap(encryptErr, "gopengpp: unable to encrypt attachment")
 	}
@@ -117,6 +149,9 @@ func (keyRing *KeyRing) newAttachmentProcessor(
 // Returns a PGPSplitMessage containing a session key packet and symmetrically encrypted data.
 // Specifically designed for attachments rather than text messages.
 func (keyRing *KeyRing) EncryptAttachment(message *PlainMessage, filename string) (*PGPSplitMessage, error) {
+	if err := checkPlaintextSize(message); err != nil {
+		return nil, err
+	}
 	if filename == "" {
 		filename = message.Filename
 	}
@@ -153,7 +188,7 @@ func (keyRing *KeyRing) NewLowMemoryAttachmentProcessor(
 // and returns a decrypted PlainMessage
 // Specifically designed for attachments rather than text messages.
 func (keyRing *KeyRing) DecryptAttachment(message *PGPSplitMessage) (*PlainMessage, error) {
-	privKeyEntries := keyRing.entities
+	privKeyEntries := keyRing.getEntities()
 
 	keyReader := bytes.NewReader(message.GetBinaryKeyPacket())
 	dataReader := bytes.NewReader(message.GetBinaryDataPacket())
@@ -162,7 +197,7 @@ func (keyRing *KeyRing) DecryptAttachment(message *PGPSplitMessage) (*PlainMessa
 
 	config := &packet.Config{Time: getTimeGenerator()}
 
-	md, err := openpgp.ReadMessage(encryptedReader, privKeyEntries, nil, config)
+	md, _, err := readMessage(encryptedReader, privKeyEntries, nil, config)
 	if err != nil {
 		return nil, errors.Wrap(err, "gopengpp: unable to read attachment")
 	}
@@ -178,5 +213,6 @@ func (keyRing *KeyRing) DecryptAttachment(message *PGPSplitMessage) (*PlainMessa
 		TextType: !md.LiteralData.IsBinary,
 		Filename: md.LiteralData.FileName,
 		Time:     md.LiteralData.Time,
+		format:   getLiteralFormat(md.LiteralData.Format),
 	}, nil
 }
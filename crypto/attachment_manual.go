@@ -6,7 +6,6 @@ import (
 	"runtime"
 	"runtime/debug"
 	"sync"
-	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
@@ -78,6 +77,18 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 		return nil, errors.New("gopenpgp: can't give a nil or empty buffer to process the attachement")
 	}
 
+	// encryption config
+	config := &packet.Config{
+		Rand:          getRandom(),
+		DefaultCipher: packet.CipherAES256,
+		Time:          getTimeGenerator(),
+	}
+
+	recipients, err := keyRing.getEncryptionEntities(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// forces the gc to be called often
 	debug.SetGCPercent(10)
 
@@ -85,17 +96,14 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 
 	// hints for the encrypted file
 	isBinary := true
-	modTime := GetUnixTime()
+	modTime, err := checkModTime(GetUnixTime())
+	if err != nil {
+		return nil, err
+	}
 	hints := &openpgp.FileHints{
 		FileName: filename,
 		IsBinary: isBinary,
-		ModTime:  time.Unix(modTime, 0),
-	}
-
-	// encryption config
-	config := &packet.Config{
-		DefaultCipher: packet.CipherAES256,
-		Time:          getTimeGenerator(),
+		ModTime:  getModTime(modTime),
 	}
 
 	// goroutine that reads the key packet
@@ -128,7 +136,7 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 	// We generate the encrypting writer
 	var ew io.WriteCloser
 	var encryptErr error
-	ew, encryptErr = openpgp.EncryptSplit(keyWriter, dataWriter, keyRing.entities, nil, hints, config)
+	ew, encryptErr = openpgp.EncryptSplit(keyWriter, dataWriter, recipients, nil, hints, config)
 	if encryptErr != nil {
 		return nil, errors.Wrap(encryptErr, "gopengpp: unable to encrypt attachment")
 	}
@@ -0,0 +1,171 @@
+package crypto
+
+import (
+	"bytes"
+	goerrors "errors"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// ErrAttachmentAborted is returned by the attachment processors once aborted.
+var ErrAttachmentAborted = goerrors.New("gopenpgp: attachment processing aborted")
+
+// ResumableAttachmentProcessor encrypts an attachment chunk by chunk, and
+// writes its data packet to a Writer as it goes, tolerating the transient
+// errors of the writer:
+//   - if the writer fails, Process returns the error and keeps the ciphertext
+//     of the chunk which was not written. The caller may then retry by calling
+//     Process with the same chunk, which writes the pending ciphertext without
+//     encrypting the chunk again. Any other chunk is rejected until then;
+//   - Finish may be retried the same way;
+//   - Abort discards the state at any time.
+//
+// The processor is not safe for concurrent use.
+type ResumableAttachmentProcessor struct {
+	keyPacket        bytes.Buffer
+	plaintextWriter  io.WriteCloser
+	dataPacketWriter Writer
+	// Ciphertext not accepted by dataPacketWriter yet
+	pending bytes.Buffer
+	// Length of the chunk whose ciphertext is pending, -1 if none
+	pendingChunk int
+	processed    int64
+	maxSize      int64
+	closed       bool
+	err          error
+}
+
+// NewResumableAttachmentProcessor creates a ResumableAttachmentProcessor
+// encrypting a binary file to the keyring, whose data packet is written to
+// dataPacketWriter. The key packet is returned by Finish.
+func (keyRing *KeyRing) NewResumableAttachmentProcessor(
+	filename string, dataPacketWriter Writer,
+) (*ResumableAttachmentProcessor, error) {
+	config := &packet.Config{
+		Rand:          getRandom(),
+		DefaultCipher: packet.CipherAES256,
+		Time:          getTimeGenerator(),
+	}
+
+	recipients, err := keyRing.getEncryptionEntities(config)
+	if err != nil {
+		return nil, err
+	}
+
+	modTime, err := checkModTime(GetUnixTime())
+	if err != nil {
+		return nil, err
+	}
+	hints := &openpgp.FileHints{
+		FileName: filename,
+		IsBinary: true,
+		ModTime:  getModTime(modTime),
+	}
+
+	ap := &ResumableAttachmentProcessor{
+		dataPacketWriter: dataPacketWriter,
+		pendingChunk:     -1,
+	}
+	ap.plaintextWriter, err = openpgp.EncryptSplit(&ap.keyPacket, &ap.pending, recipients, nil, hints, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopengpp: unable to encrypt attachment")
+	}
+	return ap, nil
+}
+
+// SetMaxSize sets the maximum size in bytes of the attachment: Process
+// returns a PlaintextTooLargeError, without processing the chunk, if it would
+// exceed it. If size is 0, the default, the size is not limited.
+func (ap *ResumableAttachmentProcessor) SetMaxSize(size int64) {
+	ap.maxSize = size
+}
+
+// GetProcessedBytes returns the number of bytes of the attachment processed
+// successfully, for progress reporting.
+func (ap *ResumableAttachmentProcessor) GetProcessedBytes() int64 {
+	return ap.processed
+}
+
+// Process encrypts a chunk of the attachment and writes the ciphertext to the
+// data packet writer. After an error of the writer, Process must be called
+// again with the same chunk, see ResumableAttachmentProcessor.
+func (ap *ResumableAttachmentProcessor) Process(plainData []byte) error {
+	if ap.err != nil {
+		return ap.err
+	}
+	if ap.closed {
+		return errors.New("gopenpgp: the attachment processor is already finished")
+	}
+
+	if ap.pendingChunk == -1 {
+		size := ap.processed + int64(len(plainData))
+		if ap.maxSize > 0 && size > ap.maxSize {
+			return PlaintextTooLargeError{Limit: ap.maxSize, Size: size}
+		}
+		if _, err := ap.plaintextWriter.Write(plainData); err != nil {
+			ap.err = errors.Wrap(err, "gopenpgp: couldn't write attachment data")
+			return ap.err
+		}
+		ap.pendingChunk = len(plainData)
+	} else if len(plainData) != ap.pendingChunk {
+		return errors.New("gopenpgp: the chunk which failed to be written must be processed again first")
+	}
+
+	if err := ap.flush(); err != nil {
+		return err
+	}
+	ap.processed += int64(ap.pendingChunk)
+	ap.pendingChunk = -1
+	return nil
+}
+
+// Finish finalizes the encryption, writes the end of the data packet, and
+// returns the key packet. After an error of the writer, Finish may be called
+// again.
+func (ap *ResumableAttachmentProcessor) Finish() (keyPacket []byte, err error) {
+	if ap.err != nil {
+		return nil, ap.err
+	}
+	if ap.pendingChunk != -1 {
+		return nil, errors.New("gopenpgp: the chunk which failed to be written must be processed again first")
+	}
+	if !ap.closed {
+		if err := ap.plaintextWriter.Close(); err != nil {
+			ap.err = errors.Wrap(err, "gopengpp: unable to close the plaintext writer")
+			return nil, ap.err
+		}
+		ap.closed = true
+	}
+	if err := ap.flush(); err != nil {
+		return nil, err
+	}
+	return clone(ap.keyPacket.Bytes()), nil
+}
+
+// Abort discards the state of the processor, after which Process and Finish
+// return ErrAttachmentAborted. Nothing is written to the data packet writer.
+func (ap *ResumableAttachmentProcessor) Abort() {
+	ap.err = ErrAttachmentAborted
+	ap.plaintextWriter = nil
+	ap.pending = bytes.Buffer{}
+	ap.keyPacket = bytes.Buffer{}
+}
+
+// flush writes the pending ciphertext, keeping the part which the writer
+// didn't accept if it fails.
+func (ap *ResumableAttachmentProcessor) flush() error {
+	for ap.pending.Len() > 0 {
+		n, err := ap.dataPacketWriter.Write(ap.pending.Bytes())
+		ap.pending.Next(n)
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: couldn't write the data packet")
+		}
+		if n == 0 {
+			return errors.Wrap(io.ErrShortWrite, "gopenpgp: couldn't write the data packet")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyWriter fails every third write, after writing half of the data.
+type flakyWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *flakyWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes%3 == 0 {
+		n, _ := w.Buffer.Write(b[:len(b)/2])
+		return n, errors.New("transient error")
+	}
+	return w.Buffer.Write(b)
+}
+
+func TestResumableAttachmentProcessor(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("resumable attachment "), 5000)
+	dataPacket := &flakyWriter{}
+	ap, err := keyRingTestPublic.NewResumableAttachmentProcessor("attachment.bin", dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while creating processor, got:", err)
+	}
+
+	failures := 0
+	for offset := 0; offset < len(plaintext); offset += 1000 {
+		end := offset + 1000
+		if end > len(plaintext) {
+			end = len(plaintext)
+		}
+		chunk := plaintext[offset:end]
+		for ap.Process(chunk) != nil {
+			failures++
+			// Only the failed chunk is accepted
+			assert.Error(t, ap.Process([]byte("another chunk")))
+		}
+		assert.Exactly(t, int64(end), ap.GetProcessedBytes())
+	}
+	keyPacket, err := ap.Finish()
+	for err != nil {
+		failures++
+		keyPacket, err = ap.Finish()
+	}
+	assert.NotZero(t, failures)
+
+	decrypted, err := keyRingTestPrivate.DecryptAttachment(NewPGPSplitMessage(keyPacket, dataPacket.Bytes()))
+	if err != nil {
+		t.Fatal("Expected no error while decrypting attachment, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.GetBinary())
+	assert.Exactly(t, "attachment.bin", decrypted.GetFilename())
+}
+
+func TestResumableAttachmentProcessorLimits(t *testing.T) {
+	var dataPacket bytes.Buffer
+	ap, err := keyRingTestPublic.NewResumableAttachmentProcessor("attachment.bin", &dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while creating processor, got:", err)
+	}
+	ap.SetMaxSize(10)
+	if err := ap.Process([]byte("12345")); err != nil {
+		t.Fatal("Expected no error while processing, got:", err)
+	}
+	assert.True(t, errors.Is(ap.Process([]byte("123456")), ErrPlaintextTooLarge))
+	assert.Exactly(t, int64(5), ap.GetProcessedBytes())
+
+	written := dataPacket.Len()
+	ap.Abort()
+	assert.True(t, errors.Is(ap.Process([]byte("1")), ErrAttachmentAborted))
+	_, err = ap.Finish()
+	assert.True(t, errors.Is(err, ErrAttachmentAborted))
+	assert.Exactly(t, written, dataPacket.Len())
+}
+
+func TestAttachmentProcessorAbort(t *testing.T) {
+	ap, err := keyRingTestPublic.NewLowMemoryAttachmentProcessor(1000, "attachment.bin")
+	if err != nil {
+		t.Fatal("Expected no error while creating processor, got:", err)
+	}
+	ap.Process(bytes.Repeat([]byte("a"), 1000))
+	assert.Exactly(t, int64(1000), ap.GetProcessedBytes())
+
+	ap.Abort()
+	ap.Process([]byte("ignored"))
+	assert.Exactly(t, int64(1000), ap.GetProcessedBytes())
+	_, err = ap.Finish()
+	assert.True(t, errors.Is(err, ErrAttachmentAborted))
+}
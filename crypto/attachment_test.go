@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
 // const testAttachmentEncrypted =
@@ -73,7 +74,11 @@ func TestAttachmentEncrypt(t *testing.T) {
 		t.Fatal("Expected no error while decrypting attachment, got:", err)
 	}
 
-	assert.Exactly(t, message, redecData)
+	assert.Exactly(t, message.GetBinary(), redecData.GetBinary())
+	assert.Exactly(t, message.GetFilename(), redecData.GetFilename())
+	assert.Exactly(t, message.GetTime(), redecData.GetTime())
+	assert.Exactly(t, message.IsBinary(), redecData.IsBinary())
+	assert.Exactly(t, constants.AES256, redecData.GetCipher())
 }
 
 func TestAttachmentDecrypt(t *testing.T) {
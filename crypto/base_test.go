@@ -27,6 +27,8 @@ func readTestFile(name string, trimNewlines bool) string {
 }
 
 func init() {
+	// The fixtures are checked at testTime
+	SetTimeFrozen(true)
 	UpdateTime(testTime) // 2019-05-13T13:37:07+00:00
 
 	initGenerateKeys()
@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	goerrors "errors"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// BatchResult is the result of the decryption of a message by DecryptBatch.
+type BatchResult struct {
+	// Message is the decrypted message, nil if the decryption failed.
+	Message *PlainMessage
+	// SignatureStatus is one of the constants.SIGNATURE_* statuses,
+	// constants.SIGNATURE_NO_VERIFIER if no verification keyring is given.
+	SignatureStatus int
+	// Error is the decryption error, signature errors are only reported
+	// in SignatureStatus.
+	Error error
+}
+
+// DecryptBatch decrypts the messages with keyRing, using up to workers
+// concurrent goroutines, or runtime.GOMAXPROCS(0) if workers is 0.
+// If verifyKeyRing is not nil, the embedded signatures are verified at
+// verifyTime, as in KeyRing.Decrypt.
+// The results are in the order of the messages.
+func DecryptBatch(
+	keyRing *KeyRing, messages []*PGPMessage, verifyKeyRing *KeyRing, verifyTime int64, workers int,
+) ([]*BatchResult, error) {
+	if keyRing == nil {
+		return nil, errors.New("gopenpgp: no decryption key ring provided")
+	}
+	if workers < 0 {
+		return nil, errors.New("gopenpgp: the number of workers can't be negative")
+	}
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(messages) {
+		workers = len(messages)
+	}
+
+	results := make([]*BatchResult, len(messages))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = decryptBatchMessage(keyRing, messages[index], verifyKeyRing, verifyTime)
+			}
+		}()
+	}
+	for index := range messages {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, nil
+}
+
+func decryptBatchMessage(keyRing *KeyRing, message *PGPMessage, verifyKeyRing *KeyRing, verifyTime int64) *BatchResult {
+	result := &BatchResult{SignatureStatus: constants.SIGNATURE_NO_VERIFIER}
+	if message == nil {
+		result.Error = errors.New("gopenpgp: nil message provided")
+		return result
+	}
+
+	plainMessage, err := keyRing.Decrypt(message, verifyKeyRing, verifyTime)
+	var sigErr SignatureVerificationError
+	switch {
+	case err == nil:
+		if verifyKeyRing != nil {
+			result.SignatureStatus = constants.SIGNATURE_OK
+		}
+	case goerrors.As(err, &sigErr):
+		result.SignatureStatus = sigErr.Status
+	default:
+		result.Error = err
+		return result
+	}
+	result.Message = plainMessage
+	return result
+}
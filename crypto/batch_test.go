@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestDecryptBatch(t *testing.T) {
+	var messages []*PGPMessage
+	for i := 0; i < 20; i++ {
+		var signKeyRing *KeyRing
+		if i%2 == 0 {
+			signKeyRing = keyRingTestPrivate
+		}
+		message, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("message "+strconv.Itoa(i)), signKeyRing)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		messages = append(messages, message)
+	}
+	messages[5] = NewPGPMessage([]byte("not a message"))
+
+	for _, workers := range []int{0, 1, 4} {
+		results, err := DecryptBatch(keyRingTestPrivate, messages, keyRingTestPublic, GetUnixTime(), workers)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting batch, got:", err)
+		}
+		assert.Len(t, results, len(messages))
+
+		for i, result := range results {
+			if i == 5 {
+				assert.Error(t, result.Error)
+				assert.Nil(t, result.Message)
+				continue
+			}
+			if result.Error != nil {
+				t.Fatal("Expected no error while decrypting, got:", result.Error)
+			}
+			assert.Exactly(t, "message "+strconv.Itoa(i), result.Message.GetString())
+			if i%2 == 0 {
+				assert.Exactly(t, constants.SIGNATURE_OK, result.SignatureStatus)
+			} else {
+				assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, result.SignatureStatus)
+			}
+		}
+	}
+
+	results, err := DecryptBatch(keyRingTestPrivate, messages[:1], nil, 0, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting batch, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, results[0].SignatureStatus)
+
+	_, err = DecryptBatch(keyRingTestPrivate, messages, nil, 0, -1)
+	assert.Error(t, err)
+}
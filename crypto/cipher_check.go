@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	goerrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"golang.org/x/crypto/cast5" //nolint:staticcheck
+)
+
+// maxCipherBlockSize is the largest block size of the supported ciphers.
+const maxCipherBlockSize = 16
+
+// cipherNames lists the names of the supported ciphers, in a fixed order.
+var cipherNames = []string{constants.AES128, constants.AES192, constants.AES256, constants.CAST5, constants.TripleDES}
+
+// ErrCipherMismatch is matched by CipherMismatchError with errors.Is.
+var ErrCipherMismatch = goerrors.New("gopenpgp: session key cipher mismatch")
+
+// CipherMismatchError is returned when decrypting a data packet which is not
+// encrypted with the cipher declared along with the session key.
+type CipherMismatchError struct {
+	DeclaredCipher string
+	UsedCipher     string
+}
+
+// Error is the base method for all errors.
+func (e CipherMismatchError) Error() string {
+	return fmt.Sprintf(
+		"gopenpgp: session key cipher mismatch, declared %s but the data is encrypted with %s",
+		e.DeclaredCipher, e.UsedCipher,
+	)
+}
+
+// Is matches ErrCipherMismatch.
+func (e CipherMismatchError) Is(target error) bool {
+	return target == ErrCipherMismatch
+}
+
+// checkDataPacketCipher checks that the data packet is encrypted with the
+// declared cipher. If the key size doesn't match the cipher, or the quick check
+// of the encrypted prefix (RFC 4880, section 5.7) fails while it succeeds with
+// another cipher of the key size, a CipherMismatchError is returned.
+// Otherwise, errors are left to the decryption of the packet.
+func checkDataPacketCipher(se *packet.SymmetricallyEncrypted, declared packet.CipherFunction, key []byte) error {
+	prefix := make([]byte, maxCipherBlockSize+2)
+	n, _ := io.ReadFull(se.Contents, prefix)
+	prefix = prefix[:n]
+	se.Contents = io.MultiReader(bytes.NewReader(prefix), se.Contents)
+
+	if declared.KeySize() == len(key) && quickCheck(declared, key, prefix) {
+		return nil
+	}
+	for _, algo := range cipherNames {
+		cf := symKeyAlgos[algo]
+		if cf != declared && cf.KeySize() == len(key) && quickCheck(cf, key, prefix) {
+			return CipherMismatchError{DeclaredCipher: getCipherName(declared), UsedCipher: algo}
+		}
+	}
+	return nil
+}
+
+// quickCheck decrypts the prefix of the data with the cipher and checks that
+// its last two bytes repeat the previous ones.
+func quickCheck(cf packet.CipherFunction, key, prefix []byte) bool {
+	block, err := newBlockCipher(cf, key)
+	if err != nil {
+		return false
+	}
+
+	blockSize := block.BlockSize()
+	if len(prefix) < blockSize+2 {
+		return false
+	}
+	decrypted := clone(prefix[:blockSize+2])
+	packet.NewOCFBDecrypter(block, decrypted, packet.OCFBNoResync)
+	return decrypted[blockSize-2] == decrypted[blockSize] && decrypted[blockSize-1] == decrypted[blockSize+1]
+}
+
+// newBlockCipher returns the block cipher of cf, which the library does not
+// expose, with the given key.
+func newBlockCipher(cf packet.CipherFunction, key []byte) (cipher.Block, error) {
+	switch cf {
+	case packet.CipherAES128, packet.CipherAES192, packet.CipherAES256:
+		return aes.NewCipher(key)
+	case packet.Cipher3DES:
+		return des.NewTripleDESCipher(key) //nolint:gosec
+	case packet.CipherCAST5:
+		return cast5.NewCipher(key)
+	}
+	return nil, fmt.Errorf("gopenpgp: unsupported cipher function: %v", cf)
+}
+
+// getCipherName returns the name of a cipher, or its ID if it is unsupported.
+func getCipherName(cf packet.CipherFunction) string {
+	for _, algo := range cipherNames {
+		if symKeyAlgos[algo] == cf {
+			return algo
+		}
+	}
+	return fmt.Sprintf("cipher %d", cf)
+}
+
+// getDecryptedCipherName returns the name of the cipher of a decrypted message,
+// or an empty string if it wasn't encrypted.
+func getDecryptedCipherName(cf packet.CipherFunction) string {
+	if cf == 0 {
+		return ""
+	}
+	return getCipherName(cf)
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestCipherMismatch(t *testing.T) {
+	sk, err := GenerateSessionKeyAlgo(constants.AES128)
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	dataPacket, err := sk.Encrypt(NewPlainMessageFromString("message"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	encryptionKey, ok := keyRingTestPublic.entities[0].EncryptionKey(getNow())
+	if !ok {
+		t.Fatal("Expected an encryption key")
+	}
+	for _, declared := range []packet.CipherFunction{packet.CipherCAST5, packet.CipherAES256} {
+		var keyPacket bytes.Buffer
+		if err := packet.SerializeEncryptedKey(&keyPacket, encryptionKey.PublicKey, declared, sk.Key, nil); err != nil {
+			t.Fatal("Expected no error while encrypting session key, got:", err)
+		}
+		message := NewPGPSplitMessage(keyPacket.Bytes(), dataPacket).GetPGPMessage()
+
+		_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+		var mismatchError CipherMismatchError
+		assert.True(t, errors.Is(err, ErrCipherMismatch))
+		assert.True(t, errors.As(err, &mismatchError))
+		assert.Exactly(t, getCipherName(declared), mismatchError.DeclaredCipher)
+		assert.Exactly(t, constants.AES128, mismatchError.UsedCipher)
+	}
+
+	// Same key size, the session key is declared for CAST5
+	_, err = NewSessionKeyFromToken(sk.Key, constants.CAST5).Decrypt(dataPacket)
+	assert.True(t, errors.Is(err, ErrCipherMismatch))
+
+	decrypted, err := sk.Decrypt(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.AES128, decrypted.GetCipher())
+
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sk)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(NewPGPSplitMessage(keyPacket, dataPacket).GetPGPMessage(), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.AES128, decrypted.GetCipher())
+}
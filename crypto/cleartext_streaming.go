@@ -0,0 +1,355 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+const (
+	clearTextBegin     = "-----BEGIN PGP SIGNED MESSAGE-----"
+	clearTextSignature = "-----BEGIN PGP SIGNATURE-----"
+)
+
+var crlf = []byte{'\r', '\n'}
+
+// clearTextHashes maps the values of the Hash armor header of a cleartext
+// message to the allowed hashes.
+var clearTextHashes = map[string]crypto.Hash{
+	"SHA224": crypto.SHA224,
+	"SHA256": crypto.SHA256,
+	"SHA384": crypto.SHA384,
+	"SHA512": crypto.SHA512,
+}
+
+// ClearTextMessageReader reads the text of a cleartext signed message from an
+// armored stream, without loading it entirely, and verifies its signature
+// once the text has been read, see NewClearTextMessageReader.
+type ClearTextMessageReader struct {
+	armored       *bufio.Reader
+	verifyKeyRing *KeyRing
+	verifyTime    int64
+
+	// Hashes of the text, for each algorithm announced by the Hash header, for
+	// binary and text signatures
+	hashes     map[crypto.Hash]hash.Hash
+	textHashes map[crypto.Hash]hash.Hash
+	// Writes the canonicalized text to out and to the hashes
+	text io.Writer
+	// Canonicalized text not returned by Read yet
+	out bytes.Buffer
+	// Whitespace at the end of the line fragment read, which is trimmed if it
+	// ends the line
+	pending   []byte
+	lineStart bool
+	firstLine bool
+	textDone  bool
+	signature []byte
+	readAll   bool
+	err       error
+}
+
+// NewClearTextMessageReader parses the header of an armored cleartext signed
+// message, and returns a reader of its text, canonicalized like
+// ClearTextMessage.GetBinary: dash-escaping removed, whitespace trimmed at the
+// end of the lines, and CRLF line endings.
+// If verifyKeyRing is not nil, ClearTextMessageReader.VerifySignature() will
+// verify the text signature with the given key ring and verification time.
+// The text is hashed with the algorithms listed by the Hash header, or with
+// all the allowed ones if there is none.
+func NewClearTextMessageReader(armored Reader, verifyKeyRing *KeyRing, verifyTime int64) (*ClearTextMessageReader, error) {
+	msg := &ClearTextMessageReader{
+		armored:       bufio.NewReader(armored),
+		verifyKeyRing: verifyKeyRing,
+		verifyTime:    verifyTime,
+		hashes:        make(map[crypto.Hash]hash.Hash),
+		textHashes:    make(map[crypto.Hash]hash.Hash),
+		lineStart:     true,
+		firstLine:     true,
+	}
+	if err := msg.readHeader(); err != nil {
+		return nil, err
+	}
+
+	writers := []io.Writer{&msg.out}
+	for algo, h := range msg.hashes {
+		msg.textHashes[algo] = algo.New()
+		writers = append(writers, h, openpgp.NewCanonicalTextHash(msg.textHashes[algo]))
+	}
+	msg.text = io.MultiWriter(writers...)
+	return msg, nil
+}
+
+// Read reads the canonicalized text of the message.
+// Makes ClearTextMessageReader implement the Reader interface.
+func (msg *ClearTextMessageReader) Read(b []byte) (n int, err error) {
+	for msg.out.Len() == 0 && !msg.textDone && msg.err == nil {
+		msg.err = msg.readFragment()
+	}
+	if msg.out.Len() > 0 {
+		return msg.out.Read(b)
+	}
+	if msg.err != nil {
+		return 0, msg.err
+	}
+	msg.readAll = true
+	return 0, io.EOF
+}
+
+// GetSignature returns the signature of the message, which is only available
+// once the text has been read entirely.
+func (msg *ClearTextMessageReader) GetSignature() (*PGPSignature, error) {
+	if !msg.readAll {
+		return nil, errors.New("gopenpgp: the signature follows the text, which hasn't been read entirely")
+	}
+	return NewPGPSignature(msg.signature), nil
+}
+
+// VerifySignature is used to verify that the signature is valid.
+// This method needs to be called once all the text has been read.
+// It will return an error if the signature is invalid
+// or if the text hasn't been read entirely.
+func (msg *ClearTextMessageReader) VerifySignature() error {
+	if !msg.readAll {
+		return errors.New("gopenpgp: can't verify the signature until the message reader has been read entirely")
+	}
+	if msg.verifyKeyRing == nil {
+		return errors.New("gopenpgp: no verify keyring was provided before reading")
+	}
+	_, err := msg.verify()
+	return err
+}
+
+// verify verifies the signature of the text read with msg.verifyKeyRing.
+func (msg *ClearTextMessageReader) verify() (*VerificationResult, error) {
+	return verifySignatureHashesResult(
+		msg.verifyKeyRing.getEntities(), msg.hashes, msg.textHashes, msg.signature, msg.verifyTime,
+		getDefaultSignerPolicy(),
+	)
+}
+
+// readHeader skips the data before the start of the cleartext message, and
+// reads the Hash armor headers.
+func (msg *ClearTextMessageReader) readHeader() error {
+	lineStart := true
+	for {
+		line, complete, err := msg.readLine()
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: no cleartext message found")
+		}
+		if lineStart && complete && string(trimCR(line)) == clearTextBegin {
+			break
+		}
+		lineStart = complete
+	}
+
+	for {
+		line, complete, err := msg.readLine()
+		if err != nil || !complete {
+			return errors.New("gopenpgp: invalid cleartext message header")
+		}
+		line = trimCR(line)
+		if len(line) == 0 {
+			break
+		}
+		i := bytes.IndexByte(line, ':')
+		if i < 0 || strings.TrimSpace(string(line[:i])) != "Hash" {
+			return errors.New("gopenpgp: invalid cleartext message header")
+		}
+		for _, name := range strings.Split(string(line[i+1:]), ",") {
+			if algo, ok := clearTextHashes[strings.ToUpper(strings.TrimSpace(name))]; ok {
+				msg.hashes[algo] = algo.New()
+			}
+		}
+	}
+
+	if len(msg.hashes) == 0 {
+		for _, algo := range allowedHashes {
+			msg.hashes[algo] = algo.New()
+		}
+	}
+	return nil
+}
+
+// readLine reads the next line without its line feed, or a fragment of it if
+// it doesn't fit in the buffer, in which case complete is false.
+// The line is only valid until the next read.
+func (msg *ClearTextMessageReader) readLine() (line []byte, complete bool, err error) {
+	line, err = msg.armored.ReadSlice('\n')
+	switch {
+	case errors.Is(err, bufio.ErrBufferFull):
+		return line, false, nil
+	case errors.Is(err, io.EOF):
+		return nil, false, errors.New("gopenpgp: unexpected end of cleartext message")
+	case err != nil:
+		return nil, false, errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+	}
+	return line[:len(line)-1], true, nil
+}
+
+// trimCR removes the carriage return ending a line.
+func trimCR(line []byte) []byte {
+	return bytes.TrimSuffix(line, []byte{'\r'})
+}
+
+// readFragment canonicalizes the next line of the text, or a fragment of it,
+// or reads the signature if the text is over.
+func (msg *ClearTextMessageReader) readFragment() error {
+	fragment, complete, err := msg.readLine()
+	if err != nil {
+		return err
+	}
+	if msg.lineStart {
+		if complete && string(trimCR(fragment)) == clearTextSignature {
+			return msg.readSignature()
+		}
+		// The last line ending isn't part of the text
+		if !msg.firstLine {
+			_, _ = msg.text.Write(crlf)
+		}
+		msg.firstLine = false
+		fragment = bytes.TrimPrefix(fragment, []byte("- "))
+	}
+
+	data := fragment
+	if len(msg.pending) > 0 {
+		data = append(msg.pending, fragment...)
+	}
+	if complete {
+		_, _ = msg.text.Write(bytes.TrimRight(trimCR(data), " \t"))
+		msg.pending = nil
+	} else {
+		// Keep the whitespace which may end the line
+		end := len(bytes.TrimRight(data, " \t\r"))
+		_, _ = msg.text.Write(data[:end])
+		msg.pending = clone(data[end:])
+	}
+	msg.lineStart = complete
+	return nil
+}
+
+// readSignature reads the armored signature following the text.
+func (msg *ClearTextMessageReader) readSignature() error {
+	msg.textDone = true
+	block, err := armor.Decode(io.MultiReader(strings.NewReader(clearTextSignature+"\n"), msg.armored))
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading cleartext signature")
+	}
+	if block.Type != constants.PGPSignatureHeader {
+		return errors.New("gopenpgp: invalid cleartext signature armor")
+	}
+	msg.signature, err = ioutil.ReadAll(block.Body)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading cleartext signature")
+	}
+	return nil
+}
+
+// verifySignatureHashes verifies the signature packets with the hashes of the
+// signed data, like verifySignature: hashes for binary signatures and
+// textHashes for text signatures, by hash algorithm. If the signers of all the
+// valid signatures are rejected by policy, the error of the last one is
+// returned.
+func verifySignatureHashes(
+	entities openpgp.EntityList,
+	hashes, textHashes map[crypto.Hash]hash.Hash,
+	signature []byte,
+	verifyTime int64,
+	policy signerPolicy,
+) error {
+	_, err := verifySignatureHashesResult(entities, hashes, textHashes, signature, verifyTime, policy)
+	return err
+}
+
+// verifySignatureHashesResult verifies the signature packets like
+// verifySignatureHashes, and returns the first valid signature.
+func verifySignatureHashesResult(
+	entities openpgp.EntityList,
+	hashes, textHashes map[crypto.Hash]hash.Hash,
+	signature []byte,
+	verifyTime int64,
+	policy signerPolicy,
+) (*VerificationResult, error) {
+	var cause error = errors.New("gopenpgp: no signature made by a key of the keyring")
+	var policyErr error
+	verifyTime = ResolveVerifyTime(verifyTime)
+	reportSignatureDeprecations(entities, signature)
+	packets := packet.NewReader(bytes.NewReader(signature))
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, newDetachedSignatureFailed(signature, errors.Wrap(err, "gopenpgp: error in reading signature packet"))
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || !isMessageSignature(sig) || sig.IssuerKeyId == nil {
+			continue
+		}
+		h, ok := hashes[sig.Hash]
+		if sig.SigType == packet.SigTypeText {
+			h, ok = textHashes[sig.Hash]
+		}
+		if !ok {
+			cause = errors.New("gopenpgp: the signature hash is not allowed or not announced")
+			continue
+		}
+		for _, key := range entities.KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign) {
+			hashed, err := cloneHash(h, sig.Hash)
+			if err != nil {
+				return nil, newDetachedSignatureFailed(signature, err)
+			}
+			if err := key.PublicKey.VerifySignature(hashed, sig); err != nil {
+				cause = err
+				continue
+			}
+			if verifyTime != 0 && (!isSignatureTimeValid(sig, verifyTime) || !isKeyTimeValid(key, verifyTime)) {
+				cause = errors.New("gopenpgp: signature or key expired")
+				continue
+			}
+			if err := policy.rejectSigner(key.Entity, key.PublicKey); err != nil {
+				policyErr = err
+				continue
+			}
+			if err, rejected := policy.rejectSignatureAge(sig, verifyTime); rejected {
+				policyErr = err
+				continue
+			}
+			return newVerificationResult(key, sig), nil
+		}
+	}
+	if policyErr != nil {
+		return nil, withDetachedSignatureDetails(policyErr, signature)
+	}
+	return nil, newDetachedSignatureFailed(signature, cause)
+}
+
+// cloneHash copies the state of h, as the signature trailer is written to the
+// hash when verifying a signature.
+func cloneHash(h hash.Hash, algo crypto.Hash) (hash.Hash, error) {
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.New("gopenpgp: unable to copy hash state")
+	}
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy hash state")
+	}
+	hashed := algo.New()
+	if err := hashed.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to copy hash state")
+	}
+	return hashed, nil
+}
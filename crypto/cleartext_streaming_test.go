@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signClearText(t *testing.T, text string) string {
+	return signClearTextWithKeyRing(t, keyRingTestPrivate, text)
+}
+
+func signClearTextWithKeyRing(t *testing.T, keyRing *KeyRing, text string) string {
+	message := NewPlainMessageFromString(text)
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	armoredSignature, err := signature.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring signature, got:", err)
+	}
+
+	lines := strings.Split(message.GetString(), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "-") {
+			lines[i] = "- " + line
+		}
+	}
+	return "-----BEGIN PGP SIGNED MESSAGE-----\r\nHash: SHA512\r\n\r\n" +
+		strings.Join(lines, "\r\n") + "\r\n" + armoredSignature
+}
+
+func TestClearTextMessageReader(t *testing.T) {
+	// The long line is split by the reader buffer inside its trailing whitespace
+	longLine := strings.Repeat("x", 4090) + strings.Repeat(" ", 20)
+	text := "- dash-escaped \n-----BEGIN PGP SIGNATURE-----\n" + longLine + "\nlast line\t \n"
+	armored := signClearText(t, text)
+
+	expected, err := NewClearTextMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext message, got:", err)
+	}
+
+	reader, err := NewClearTextMessageReader(iotest.OneByteReader(strings.NewReader(armored)), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	assert.Error(t, reader.VerifySignature())
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	assert.Exactly(t, expected.GetBinary(), data)
+	if err := reader.VerifySignature(); err != nil {
+		t.Fatal("Expected no error while verifying cleartext message, got:", err)
+	}
+	signature, err := reader.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	assert.Exactly(t, expected.GetBinarySignature(), signature.GetBinary())
+}
+
+func TestClearTextMessageReaderTampered(t *testing.T) {
+	armored := signClearText(t, "pay 10 EUR\n")
+	tampered := strings.Replace(armored, "pay 10 EUR", "pay 1000 EUR", 1)
+
+	reader, err := NewClearTextMessageReader(strings.NewReader(tampered), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	assert.Error(t, reader.VerifySignature())
+
+	// Truncated before the signature
+	truncated := armored[:strings.Index(armored, "-----BEGIN PGP SIGNATURE-----")]
+	reader, err = NewClearTextMessageReader(strings.NewReader(truncated), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	_, err = ioutil.ReadAll(reader)
+	assert.Error(t, err)
+	assert.Error(t, reader.VerifySignature())
+
+	_, err = NewClearTextMessageReader(strings.NewReader("not a cleartext message\n"), keyRingTestPublic, GetUnixTime())
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// VerificationResult describes the valid signature of a verified message.
+type VerificationResult struct {
+	// SignerKeyID is the hex key ID of the signing key or subkey
+	SignerKeyID string
+	// SignerFingerprint is the hex fingerprint of the primary key of the signer
+	SignerFingerprint string
+	// CreationTime is the unix creation time of the signature
+	CreationTime int64
+}
+
+func newVerificationResult(key openpgp.Key, sig *packet.Signature) *VerificationResult {
+	return &VerificationResult{
+		SignerKeyID:       keyIDToHex(key.PublicKey.KeyId),
+		SignerFingerprint: hex.EncodeToString(key.Entity.PrimaryKey.Fingerprint),
+		CreationTime:      sig.CreationTime.Unix(),
+	}
+}
+
+// VerifyOnly verifies the signature of the cleartext message with
+// verifyKeyRing at verifyTime, like KeyRing.VerifyDetached, hashing the text
+// in place instead of building a PlainMessage from it.
+// Returns the signer of the first valid signature, or a
+// SignatureVerificationError if the verification fails.
+func (msg *ClearTextMessage) VerifyOnly(verifyKeyRing *KeyRing, verifyTime int64) (*VerificationResult, error) {
+	return msg.VerifyOnlyWithOptions(verifyKeyRing, verifyTime, nil)
+}
+
+// VerifyOnlyWithOptions verifies the signature of the cleartext message like
+// VerifyOnly, with the checks of options, see VerifyOptions.
+func (msg *ClearTextMessage) VerifyOnlyWithOptions(
+	verifyKeyRing *KeyRing, verifyTime int64, options *VerifyOptions,
+) (*VerificationResult, error) {
+	if verifyKeyRing == nil {
+		return nil, errors.New("gopenpgp: no verification key ring provided")
+	}
+	policy, err := options.getSignerPolicy()
+	if err != nil {
+		return nil, err
+	}
+	// The text is canonical, so that its text and binary hashes are the same
+	hashes := make(map[crypto.Hash]hash.Hash)
+	packets := packet.NewReader(bytes.NewReader(msg.Signature))
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, newDetachedSignatureFailed(msg.Signature, errors.Wrap(err, "gopenpgp: error in reading signature packet"))
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || hashes[sig.Hash] != nil || !isAllowedHash(sig.Hash) {
+			continue
+		}
+		h := sig.Hash.New()
+		_, _ = h.Write(msg.Data)
+		hashes[sig.Hash] = h
+	}
+	return verifySignatureHashesResult(
+		verifyKeyRing.getEntities(), hashes, hashes, msg.Signature, verifyTime, policy,
+	)
+}
+
+// VerifyOnly reads the rest of the message without returning its text, and
+// verifies its signature like VerifySignature.
+// Returns the signer of the first valid signature, or a
+// SignatureVerificationError if the verification fails.
+func (msg *ClearTextMessageReader) VerifyOnly() (*VerificationResult, error) {
+	if msg.verifyKeyRing == nil {
+		return nil, errors.New("gopenpgp: no verify keyring was provided before reading")
+	}
+	for !msg.textDone && msg.err == nil {
+		msg.err = msg.readFragment()
+		msg.out.Reset()
+	}
+	if msg.err != nil {
+		return nil, msg.err
+	}
+	msg.readAll = true
+	return msg.verify()
+}
+
+func isAllowedHash(algo crypto.Hash) bool {
+	for _, allowed := range allowedHashes {
+		if algo == allowed {
+			return algo.Available()
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertVerificationResult(t *testing.T, result *VerificationResult) {
+	signingKey, err := keyRingTestPublic.GetKey(0)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	assert.Exactly(t, signingKey.GetFingerprint(), result.SignerFingerprint)
+	assert.Exactly(t, GetUnixTime(), result.CreationTime)
+	assert.NotEmpty(t, result.SignerKeyID)
+}
+
+func TestClearTextMessageVerifyOnly(t *testing.T) {
+	armored := signClearText(t, "- dash-escaped \nsigned text\t \n")
+	message, err := NewClearTextMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext message, got:", err)
+	}
+	result, err := message.VerifyOnly(keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying cleartext message, got:", err)
+	}
+	assertVerificationResult(t, result)
+
+	tampered, err := NewClearTextMessageFromArmored(strings.Replace(armored, "signed text", "forged text", 1))
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext message, got:", err)
+	}
+	result, err = tampered.VerifyOnly(keyRingTestPublic, GetUnixTime())
+	var sigErr SignatureVerificationError
+	assert.True(t, errors.As(err, &sigErr), err)
+	assert.Nil(t, result)
+}
+
+func TestClearTextMessageReaderVerifyOnly(t *testing.T) {
+	armored := signClearText(t, strings.Repeat("streamed signed text \n", 1000))
+	reader, err := NewClearTextMessageReader(strings.NewReader(armored), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	result, err := reader.VerifyOnly()
+	if err != nil {
+		t.Fatal("Expected no error while verifying cleartext message, got:", err)
+	}
+	assertVerificationResult(t, result)
+
+	tampered := strings.Replace(armored, "streamed", "tampered", 1)
+	reader, err = NewClearTextMessageReader(strings.NewReader(tampered), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	_, err = reader.VerifyOnly()
+	assert.Error(t, err)
+}
+
+// 10 MB signed document
+func benchmarkClearTextMessage(b *testing.B) string {
+	text := strings.Repeat(strings.Repeat("x", 99)+"\n", 100000)
+	message := NewPlainMessageFromString(text)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		b.Fatal("Expected no error while signing, got:", err)
+	}
+	armored, err := NewClearTextMessage(message.GetBinary(), signature.GetBinary()).GetArmored()
+	if err != nil {
+		b.Fatal("Expected no error while armoring, got:", err)
+	}
+	return armored
+}
+
+func BenchmarkClearTextMessageVerifyDetached(b *testing.B) {
+	armored := benchmarkClearTextMessage(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		message, _ := NewClearTextMessageFromArmored(armored)
+		plainMessage := NewPlainMessageFromString(message.GetString())
+		if err := keyRingTestPublic.VerifyDetached(plainMessage, NewPGPSignature(message.GetBinarySignature()), 0); err != nil {
+			b.Fatal("Expected no error while verifying, got:", err)
+		}
+	}
+}
+
+func BenchmarkClearTextMessageVerifyOnly(b *testing.B) {
+	armored := benchmarkClearTextMessage(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		message, _ := NewClearTextMessageFromArmored(armored)
+		if _, err := message.VerifyOnly(keyRingTestPublic, 0); err != nil {
+			b.Fatal("Expected no error while verifying, got:", err)
+		}
+	}
+}
+
+func BenchmarkClearTextMessageReaderVerifyOnly(b *testing.B) {
+	armored := benchmarkClearTextMessage(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader, _ := NewClearTextMessageReader(strings.NewReader(armored), keyRingTestPublic, 0)
+		if _, err := reader.VerifyOnly(); err != nil {
+			b.Fatal("Expected no error while verifying, got:", err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// CompressionOptions selects the compression of the data before encryption,
+// see KeyRing.EncryptWithCompressionOptions and
+// SessionKey.EncryptAndSignWithCompression.
+// The data is signed before compression.
+type CompressionOptions struct {
+	// One of constants.COMPRESSION_NONE, COMPRESSION_ZIP or COMPRESSION_ZLIB.
+	Algorithm int
+	// From 1 (fastest) to 9 (smallest), or 0 for
+	// constants.DefaultCompressionLevel.
+	Level int
+}
+
+// NewCompressionOptions returns the options compressing with the given
+// algorithm and level, see CompressionOptions.
+func NewCompressionOptions(algorithm, level int) *CompressionOptions {
+	return &CompressionOptions{Algorithm: algorithm, Level: level}
+}
+
+// NewCompressionOptionsWithAlgorithm returns the options compressing with the
+// given typed algorithm and level, see CompressionOptions.
+func NewCompressionOptionsWithAlgorithm(algorithm constants.Compression, level int) *CompressionOptions {
+	return NewCompressionOptions(int(algorithm), level)
+}
+
+// DefaultCompressionOptions returns the compression options of
+// SessionKey.EncryptWithCompression: constants.DefaultCompression, at
+// constants.DefaultCompressionLevel.
+func DefaultCompressionOptions() *CompressionOptions {
+	return NewCompressionOptions(constants.DefaultCompression, constants.DefaultCompressionLevel)
+}
+
+// applyTo sets the compression of config, or returns an error if the options
+// are invalid. Nil options disable compression.
+func (options *CompressionOptions) applyTo(config *packet.Config) error {
+	if options == nil {
+		return nil
+	}
+	switch options.Algorithm {
+	case constants.COMPRESSION_NONE, constants.COMPRESSION_ZIP, constants.COMPRESSION_ZLIB:
+	default:
+		return errors.Errorf("gopenpgp: unsupported compression algorithm %d", options.Algorithm)
+	}
+	level := options.Level
+	if level == 0 {
+		level = constants.DefaultCompressionLevel
+	}
+	if level < 1 || level > 9 {
+		return errors.Errorf("gopenpgp: invalid compression level %d", options.Level)
+	}
+	config.DefaultCompressionAlgo = packet.CompressionAlgo(options.Algorithm)
+	config.CompressionConfig = &packet.CompressionConfig{Level: level}
+	return nil
+}
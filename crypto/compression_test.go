@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestKeyRingEncryptWithCompressionOptions(t *testing.T) {
+	message := NewPlainMessageFromString(strings.Repeat("compressible line\n", 1000))
+
+	uncompressed, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	compressed, err := keyRingTestPublic.EncryptWithCompressionOptions(
+		message, keyRingTestPrivate, NewCompressionOptions(constants.COMPRESSION_ZLIB, 9),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with compression, got:", err)
+	}
+	assert.Less(t, len(compressed.GetBinary()), len(uncompressed.GetBinary())/10)
+
+	decrypted, err := keyRingTestPrivate.Decrypt(compressed, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	assert.Exactly(t, message.GetBinary(), decrypted.GetBinary())
+
+	_, err = keyRingTestPublic.EncryptWithCompressionOptions(message, nil, NewCompressionOptions(3, 0))
+	assert.Error(t, err)
+	_, err = keyRingTestPublic.EncryptWithCompressionOptions(message, nil, NewCompressionOptions(constants.COMPRESSION_ZIP, 10))
+	assert.Error(t, err)
+}
+
+func TestSessionKeyEncryptAndSignWithCompression(t *testing.T) {
+	message := NewPlainMessage([]byte(strings.Repeat("compressible data ", 1000)))
+
+	uncompressed, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	for _, compression := range []*CompressionOptions{nil, NewCompressionOptions(constants.COMPRESSION_ZIP, 1)} {
+		compressed, err := testSessionKey.EncryptAndSignWithCompression(message, keyRingTestPrivate, compression)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting with compression, got:", err)
+		}
+		assert.Less(t, len(compressed), len(uncompressed)/10)
+
+		decrypted, err := testSessionKey.DecryptAndVerify(compressed, keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting and verifying, got:", err)
+		}
+		assert.Exactly(t, message.GetBinary(), decrypted.GetBinary())
+	}
+}
@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/subtle"
+	goerrors "errors"
+	"hash"
+
+	"github.com/pkg/errors"
+)
+
+// ErrContentHashMismatch is returned when the decrypted data doesn't have the
+// expected digest, see ExpectedHash.
+var ErrContentHashMismatch = goerrors.New("gopenpgp: the decrypted data doesn't match the expected hash")
+
+// ExpectedHash is the digest that the decrypted data must have, e.g. the hash
+// of a block listed in a manifest, checked while decrypting, see
+// KeyRing.DecryptWithExpectedHash and PlainMessageReader.SetExpectedHash.
+type ExpectedHash struct {
+	Hash   crypto.Hash
+	Digest []byte
+}
+
+// NewExpectedHash returns the expected digest of the data with the hash
+// algorithm whose crypto.Hash identifier is hash, e.g. 5 for crypto.SHA256.
+func NewExpectedHash(hash int, digest []byte) (*ExpectedHash, error) {
+	expected := &ExpectedHash{Hash: crypto.Hash(hash), Digest: clone(digest)}
+	if err := expected.validate(); err != nil {
+		return nil, err
+	}
+	return expected, nil
+}
+
+// validate checks that the hash is available and that the digest has its size.
+func (expected *ExpectedHash) validate() error {
+	if !expected.Hash.Available() {
+		return errors.Errorf("gopenpgp: unsupported hash %d", expected.Hash)
+	}
+	if len(expected.Digest) != expected.Hash.Size() {
+		return errors.New("gopenpgp: the expected digest doesn't have the size of the hash")
+	}
+	return nil
+}
+
+// newHash returns a hash of the data to compare to the expected digest with
+// matches.
+func (expected *ExpectedHash) newHash() (hash.Hash, error) {
+	if err := expected.validate(); err != nil {
+		return nil, err
+	}
+	return expected.Hash.New(), nil
+}
+
+// matches returns ErrContentHashMismatch if the data hashed with h doesn't
+// have the expected digest.
+func (expected *ExpectedHash) matches(h hash.Hash) error {
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected.Digest) != 1 {
+		return ErrContentHashMismatch
+	}
+	return nil
+}
+
+// check returns ErrContentHashMismatch if data doesn't have the expected
+// digest.
+func (expected *ExpectedHash) check(data []byte) error {
+	h, err := expected.newHash()
+	if err != nil {
+		return err
+	}
+	_, _ = h.Write(data)
+	return expected.matches(h)
+}
+
+// DecryptWithExpectedHash decrypts a message like Decrypt, and fails with
+// ErrContentHashMismatch if the decrypted data doesn't have the expected
+// digest, without returning it.
+func (keyRing *KeyRing) DecryptWithExpectedHash(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64, expected *ExpectedHash,
+) (*PlainMessage, error) {
+	plainMessage, err := keyRing.Decrypt(message, verifyKey, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := expected.check(plainMessage.GetBinary()); err != nil {
+		return nil, err
+	}
+	return plainMessage, nil
+}
+
+// DecryptAndVerifyWithExpectedHash decrypts data packets like DecryptAndVerify,
+// and fails with ErrContentHashMismatch if the decrypted data doesn't have the
+// expected digest, without returning it.
+func (sk *SessionKey) DecryptAndVerifyWithExpectedHash(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, expected *ExpectedHash,
+) (*PlainMessage, error) {
+	plainMessage, err := sk.DecryptAndVerify(dataPacket, verifyKeyRing, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := expected.check(plainMessage.GetBinary()); err != nil {
+		return nil, err
+	}
+	return plainMessage, nil
+}
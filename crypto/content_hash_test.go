@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptWithExpectedHash(t *testing.T) {
+	data := []byte("block listed in a manifest")
+	digest := sha256.Sum256(data)
+	expected, err := NewExpectedHash(int(crypto.SHA256), digest[:])
+	if err != nil {
+		t.Fatal("Expected no error while creating expected hash, got:", err)
+	}
+	wrongDigest := sha256.Sum256([]byte("another block"))
+	wrong, err := NewExpectedHash(int(crypto.SHA256), wrongDigest[:])
+	if err != nil {
+		t.Fatal("Expected no error while creating expected hash, got:", err)
+	}
+
+	pgpMessage, err := keyRingTestPublic.Encrypt(NewPlainMessage(data), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.DecryptWithExpectedHash(pgpMessage, keyRingTestPublic, GetUnixTime(), expected)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted.GetBinary())
+	decrypted, err = keyRingTestPrivate.DecryptWithExpectedHash(pgpMessage, nil, 0, wrong)
+	assert.True(t, errors.Is(err, ErrContentHashMismatch))
+	assert.Nil(t, decrypted)
+
+	dataPacket, err := testSessionKey.Encrypt(NewPlainMessage(data))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with session key, got:", err)
+	}
+	decrypted, err = testSessionKey.DecryptAndVerifyWithExpectedHash(dataPacket, nil, 0, expected)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, data, decrypted.GetBinary())
+	_, err = testSessionKey.DecryptAndVerifyWithExpectedHash(dataPacket, nil, 0, wrong)
+	assert.True(t, errors.Is(err, ErrContentHashMismatch))
+
+	_, err = NewExpectedHash(int(crypto.SHA512), digest[:])
+	assert.Error(t, err)
+	_, err = NewExpectedHash(0, digest[:])
+	assert.Error(t, err)
+}
+
+func TestDecryptStreamWithExpectedHash(t *testing.T) {
+	data := bytes.Repeat([]byte("streamed block "), 10000)
+	digest := sha512.Sum512(data)
+	expected, err := NewExpectedHash(int(crypto.SHA512), digest[:])
+	if err != nil {
+		t.Fatal("Expected no error while creating expected hash, got:", err)
+	}
+	pgpMessage, err := keyRingTestPublic.Encrypt(NewPlainMessage(data), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(pgpMessage.GetBinary()), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	if err := reader.SetExpectedHash(expected); err != nil {
+		t.Fatal("Expected no error while setting expected hash, got:", err)
+	}
+	decrypted, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.Exactly(t, data, decrypted)
+	assert.Error(t, reader.SetExpectedHash(expected))
+
+	// Mismatch reported by the last Read
+	data[0] = 'S'
+	split, err := pgpMessage.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	reader, err = sessionKey.DecryptStream(bytes.NewReader(split.GetBinaryDataPacket()), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	digest = sha512.Sum512(data)
+	if err := reader.SetExpectedHash(&ExpectedHash{Hash: crypto.SHA512, Digest: digest[:]}); err != nil {
+		t.Fatal("Expected no error while setting expected hash, got:", err)
+	}
+	_, err = ioutil.ReadAll(reader)
+	assert.True(t, errors.Is(err, ErrContentHashMismatch))
+}
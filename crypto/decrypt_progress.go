@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+)
+
+// ProgressCallback receives the progress of the decryption of a buffered
+// message: current is the number of bytes of the encrypted message read so
+// far, and total the length of the message up to the end of its encrypted
+// data packet, including its partial body chunks, or -1 if it can't be
+// determined, i.e. if the data packet has an indeterminate length or is
+// truncated.
+// It is called synchronously, never after the decryption function returns,
+// and doesn't receive any plaintext.
+type ProgressCallback func(current, total int64)
+
+// progressInterval is the minimal number of bytes read between two calls of a
+// ProgressCallback, except for the last one.
+const progressInterval = 1 << 20
+
+const (
+	packetTagSymmetricallyEncryptedIntegrity = 18
+	packetTagAEADEncrypted                   = 20
+)
+
+// progressReader reads an encrypted message, reporting the number of bytes
+// read to a ProgressCallback.
+type progressReader struct {
+	in       io.Reader
+	progress ProgressCallback
+	current  int64
+	total    int64
+	reported int64
+}
+
+// newProgressReader returns a reader of data, reporting its progress if
+// progress isn't nil.
+func newProgressReader(data []byte, progress ProgressCallback) io.Reader {
+	if progress == nil {
+		return bytes.NewReader(data)
+	}
+	return &progressReader{
+		in:       bytes.NewReader(data),
+		progress: progress,
+		total:    getDataPacketEnd(data),
+	}
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.in.Read(b)
+	r.current += int64(n)
+	if r.total >= 0 && r.current > r.total {
+		// Trailing data after the data packet
+		r.current = r.total
+	}
+	if r.current-r.reported >= progressInterval {
+		r.reported = r.current
+		r.progress(r.current, r.total)
+	}
+	return n, err
+}
+
+// reportProgressDone reports the end of the decryption if r is a
+// progressReader, unless its last report was already complete.
+func reportProgressDone(r io.Reader) {
+	progress, ok := r.(*progressReader)
+	if !ok {
+		return
+	}
+	if progress.total >= 0 {
+		progress.current = progress.total
+	}
+	if progress.reported != progress.current || progress.current == 0 {
+		progress.reported = progress.current
+		progress.progress(progress.current, progress.total)
+	}
+}
+
+// getDataPacketEnd returns the offset of the end of the encrypted data packet
+// of data, after the key, marker and padding packets, or -1 if it can't be
+// determined.
+func getDataPacketEnd(data []byte) int64 {
+	for offset := 0; offset < len(data); {
+		tag, _, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return -1
+		}
+		length, ok := getPacketLengthWithChunks(data[offset:])
+		if !ok {
+			return -1
+		}
+		offset += length
+		switch tag {
+		case packetTagSymmetricallyEncrypted, packetTagSymmetricallyEncryptedIntegrity, packetTagAEADEncrypted:
+			return int64(offset)
+		}
+	}
+	return -1
+}
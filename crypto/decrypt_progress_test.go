@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type progressReport struct {
+	current, total int64
+}
+
+func TestDecryptWithProgress(t *testing.T) {
+	data := make([]byte, 3*progressInterval+1234)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal("Expected no error while generating data, got:", err)
+	}
+	message := NewPlainMessage(data)
+	pgpMessage, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	var reports []progressReport
+	decrypted, err := keyRingTestPrivate.DecryptWithProgress(
+		pgpMessage, keyRingTestPublic, GetUnixTime(),
+		func(current, total int64) { reports = append(reports, progressReport{current, total}) },
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted.GetBinary())
+	total := int64(len(pgpMessage.GetBinary()))
+	checkProgressReports(t, reports, total)
+	assert.Exactly(t, progressReport{total, total}, reports[len(reports)-1])
+
+	// Without progress, with the same result
+	decrypted, err = keyRingTestPrivate.Decrypt(pgpMessage, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted.GetBinary())
+}
+
+func TestSessionKeyDecryptWithProgress(t *testing.T) {
+	data := make([]byte, 2*progressInterval)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal("Expected no error while generating data, got:", err)
+	}
+	dataPacket, err := testSessionKey.Encrypt(NewPlainMessage(data))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	var reports []progressReport
+	progress := func(current, total int64) { reports = append(reports, progressReport{current, total}) }
+	decrypted, err := testSessionKey.DecryptAndVerifyWithProgress(dataPacket, nil, 0, progress)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted.GetBinary())
+	checkProgressReports(t, reports, int64(len(dataPacket)))
+
+	// A truncated data packet has no total, and isn't reported as complete
+	reports = nil
+	truncated := dataPacket[:len(dataPacket)/2]
+	_, err = testSessionKey.DecryptAndVerifyWithProgress(truncated, nil, 0, progress)
+	assert.Error(t, err)
+	for _, report := range reports {
+		assert.Exactly(t, int64(-1), report.total)
+		assert.LessOrEqual(t, report.current, int64(len(truncated)))
+	}
+}
+
+func TestGetDataPacketEnd(t *testing.T) {
+	pgpMessage, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("hello"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	data := pgpMessage.GetBinary()
+	assert.Exactly(t, int64(len(data)), getDataPacketEnd(data))
+	// Trailing data isn't counted
+	assert.Exactly(t, int64(len(data)), getDataPacketEnd(append(clone(data), 0xc0|packetTagMarker, 3, 'P', 'G', 'P')))
+	// Old format packets with an indeterminate length
+	assert.Exactly(t, int64(-1), getDataPacketEnd([]byte{0x80 | packetTagSymmetricallyEncrypted<<2 | 3, 1, 2, 3}))
+	assert.Exactly(t, int64(-1), getDataPacketEnd(data[:len(data)-1]))
+}
+
+// checkProgressReports checks that the progress increases, with the total of
+// the message, at most once per progressInterval bytes except the last time.
+func checkProgressReports(t *testing.T, reports []progressReport, total int64) {
+	assert.GreaterOrEqual(t, len(reports), 2)
+	var previous int64
+	for i, report := range reports {
+		assert.Exactly(t, total, report.total)
+		if i < len(reports)-1 {
+			assert.GreaterOrEqual(t, report.current-previous, int64(progressInterval))
+		} else {
+			assert.Greater(t, report.current, previous)
+		}
+		previous = report.current
+	}
+}
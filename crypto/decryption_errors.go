@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"bufio"
+	goerrors "errors"
+	"fmt"
+	"io"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/pkg/errors"
+)
+
+// ErrTruncatedMessage is matched by TruncatedMessageError with errors.Is.
+var ErrTruncatedMessage = goerrors.New("gopenpgp: truncated message")
+
+// ErrIntegrityCheckFailed is returned when the MDC or the AEAD authentication
+// tag of a complete data packet doesn't match its content: the message was
+// corrupted or tampered with, or decrypted with the wrong session key.
+var ErrIntegrityCheckFailed = goerrors.New("gopenpgp: integrity check failed, the message may have been tampered with")
+
+// ErrNoIntegrityProtection is returned when decrypting a data packet without
+// MDC, which predates the integrity protection and is not supported.
+var ErrNoIntegrityProtection = goerrors.New("gopenpgp: the data packet has no integrity protection")
+
+// TruncatedMessageError is returned when the data packet ends before its
+// announced length, e.g. because of an interrupted download, rather than
+// being corrupted.
+type TruncatedMessageError struct {
+	// Offset is the number of bytes of the input read when the truncation was
+	// detected
+	Offset int64
+}
+
+// Error is the base method for all errors.
+func (e TruncatedMessageError) Error() string {
+	return fmt.Sprintf("gopenpgp: message truncated after %d bytes", e.Offset)
+}
+
+// Is matches ErrTruncatedMessage.
+func (e TruncatedMessageError) Is(target error) bool {
+	return target == ErrTruncatedMessage
+}
+
+const packetTagSymmetricallyEncrypted = 9
+
+// dataPacketErrors classifies the errors of the decryption of a data packet,
+// by tracking how its input is read.
+type dataPacketErrors struct {
+	input    countingReader
+	buffered *bufio.Reader
+	// Whether the parser of the packets read the end of the input
+	parserEOF bool
+	truncated bool
+	// Error returned by the reader of the content of the data packet
+	ciphertextErr error
+	authFailed    bool
+}
+
+// newDataPacketErrors returns the tracker of the reading of r, and the
+// buffered reader to parse the packets from, through parserInput.
+func newDataPacketErrors(r io.Reader) *dataPacketErrors {
+	d := &dataPacketErrors{input: countingReader{r: r}}
+	d.buffered = bufio.NewReader(&d.input)
+	return d
+}
+
+// checkProtection returns ErrNoIntegrityProtection if the next packet is a
+// data packet without MDC.
+func (d *dataPacketErrors) checkProtection() error {
+	if peekPacketTag(d.buffered) == packetTagSymmetricallyEncrypted {
+		return ErrNoIntegrityProtection
+	}
+	return nil
+}
+
+// parserInput returns the reader of the input for the packet parser.
+func (d *dataPacketErrors) parserInput() io.Reader {
+	return readerFunc(func(b []byte) (int, error) {
+		n, err := d.buffered.Read(b)
+		if goerrors.Is(err, io.EOF) {
+			d.parserEOF = true
+		}
+		return n, err
+	})
+}
+
+// ciphertext wraps the reader of the content of the data packet, to detect
+// when it is cut short: either in the middle of a chunk, or at the end of a
+// partial length chunk, when the end of the input is reached.
+func (d *dataPacketErrors) ciphertext(r io.Reader) io.Reader {
+	return readerFunc(func(b []byte) (int, error) {
+		n, err := r.Read(b)
+		if goerrors.Is(err, io.ErrUnexpectedEOF) || (goerrors.Is(err, io.EOF) && d.parserEOF) {
+			d.truncated = true
+		}
+		if err != nil && !goerrors.Is(err, io.EOF) {
+			d.ciphertextErr = err
+		}
+		return n, err
+	})
+}
+
+// plaintext wraps the decrypted data, to detect the authentication failures
+// of AEAD chunks: the errors which don't come from reading the ciphertext.
+func (d *dataPacketErrors) plaintext(r io.ReadCloser) io.ReadCloser {
+	return &plaintextReader{ReadCloser: r, d: d}
+}
+
+// classify returns the error to report for err, which happened while
+// decrypting the data packet.
+func (d *dataPacketErrors) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if d.truncated {
+		return TruncatedMessageError{Offset: d.input.n - int64(d.buffered.Buffered())}
+	}
+	if d.authFailed || goerrors.Is(err, pgpErrors.ErrMDCHashMismatch) || goerrors.Is(err, pgpErrors.ErrMDCMissing) {
+		return errors.Wrap(ErrIntegrityCheckFailed, err.Error())
+	}
+	return err
+}
+
+// body wraps the reader of the message body, to check the integrity of
+// decrypted once read entirely, and classify the errors.
+func (d *dataPacketErrors) body(body io.Reader, decrypted io.ReadCloser) io.Reader {
+	checked := &integrityCheckReader{body: body, decrypted: decrypted}
+	return readerFunc(func(b []byte) (int, error) {
+		n, err := checked.Read(b)
+		if err != nil && !goerrors.Is(err, io.EOF) {
+			err = d.classify(err)
+		}
+		return n, err
+	})
+}
+
+type plaintextReader struct {
+	io.ReadCloser
+	d *dataPacketErrors
+}
+
+func (r *plaintextReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err != nil && !goerrors.Is(err, io.EOF) && r.d.ciphertextErr == nil {
+		r.d.authFailed = true
+	}
+	return n, err
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// readerFunc implements io.Reader with a function.
+type readerFunc func(b []byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
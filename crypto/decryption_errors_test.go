@@ -0,0 +1,112 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// decryptBothWays decrypts the data packet with the session key, buffered and
+// streaming, and returns both errors.
+func decryptBothWays(sessionKey *SessionKey, dataPacket []byte) (bufferedErr, streamingErr error) {
+	_, bufferedErr = sessionKey.Decrypt(dataPacket)
+	reader, streamingErr := sessionKey.DecryptStream(bytes.NewReader(dataPacket), nil, 0)
+	if streamingErr == nil {
+		_, streamingErr = ioutil.ReadAll(reader)
+	}
+	return bufferedErr, streamingErr
+}
+
+func assertTruncated(t *testing.T, err error, offset int) {
+	var truncatedErr TruncatedMessageError
+	if !errors.As(err, &truncatedErr) {
+		t.Fatal("Expected a TruncatedMessageError, got:", err)
+	}
+	assert.Exactly(t, int64(offset), truncatedErr.Offset)
+	assert.True(t, errors.Is(err, ErrTruncatedMessage))
+	assert.False(t, errors.Is(err, ErrIntegrityCheckFailed))
+}
+
+func TestDecryptTruncatedMessage(t *testing.T) {
+	message := NewPlainMessageFromString(strings.Repeat("interrupted download ", 500))
+	var encrypted bytes.Buffer
+	writer, err := testSessionKey.EncryptStream(&encrypted, nil, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	for _, line := range strings.SplitAfter(message.GetString(), " ") {
+		if _, err := writer.Write([]byte(line)); err != nil {
+			t.Fatal("Expected no error while writing, got:", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	dataPacket := encrypted.Bytes()
+	chunkLength := 1 << (dataPacket[1] & 0x1f)
+
+	for _, cut := range []int{
+		1,                   // In the header
+		len(dataPacket) / 2, // In a chunk
+		2 + chunkLength,     // At the end of the first partial length chunk
+		len(dataPacket) - 1, // In the MDC
+	} {
+		bufferedErr, streamingErr := decryptBothWays(testSessionKey, dataPacket[:cut])
+		assertTruncated(t, bufferedErr, cut)
+		assertTruncated(t, streamingErr, cut)
+	}
+}
+
+func TestDecryptTamperedMessage(t *testing.T) {
+	dataPacket, err := testSessionKey.Encrypt(NewPlainMessageFromString(strings.Repeat("tampered ", 100)))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	for _, position := range []int{30, len(dataPacket) / 2, len(dataPacket) - 1} {
+		tampered := clone(dataPacket)
+		tampered[position] ^= 1
+		bufferedErr, streamingErr := decryptBothWays(testSessionKey, tampered)
+		for _, err := range []error{bufferedErr, streamingErr} {
+			assert.True(t, errors.Is(err, ErrIntegrityCheckFailed), err)
+			assert.False(t, errors.Is(err, ErrTruncatedMessage))
+		}
+	}
+}
+
+func TestDecryptTamperedAEADMessage(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_protectionAEAD", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, dataPacket := splitProtectionTestMessage(t, message)
+	sessionKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+
+	tampered := clone(dataPacket)
+	tampered[len(tampered)-20] ^= 1
+	bufferedErr, streamingErr := decryptBothWays(sessionKey, tampered)
+	assert.True(t, errors.Is(bufferedErr, ErrIntegrityCheckFailed), bufferedErr)
+	assert.True(t, errors.Is(streamingErr, ErrIntegrityCheckFailed), streamingErr)
+
+	bufferedErr, streamingErr = decryptBothWays(sessionKey, dataPacket[:len(dataPacket)-5])
+	assertTruncated(t, bufferedErr, len(dataPacket)-5)
+	assertTruncated(t, streamingErr, len(dataPacket)-5)
+}
+
+func TestDecryptMessageWithoutMDC(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_protectionLegacy", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, dataPacket := splitProtectionTestMessage(t, message)
+	sessionKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+
+	bufferedErr, streamingErr := decryptBothWays(sessionKey, dataPacket)
+	assert.True(t, errors.Is(bufferedErr, ErrNoIntegrityProtection), bufferedErr)
+	assert.True(t, errors.Is(streamingErr, ErrNoIntegrityProtection), streamingErr)
+}
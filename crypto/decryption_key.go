@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// IsDecryptionKeyExpired returns true if the message was decrypted by
+// KeyRing.Decrypt with a key which is expired or revoked at the decryption
+// time, e.g. so that the caller re-encrypts it to a valid key.
+// Decryption accepts any key held by the keyring, while encryption only uses
+// valid keys.
+func (msg *PlainMessage) IsDecryptionKeyExpired() bool {
+	return msg.decryptionKeyExpired
+}
+
+// IsDecryptionKeyExpired returns true if the message was decrypted with a key
+// which is expired or revoked at the decryption time,
+// see PlainMessage.IsDecryptionKeyExpired.
+func (msg *PlainMessageReader) IsDecryptionKeyExpired() bool {
+	return msg.decryptionKeyExpired
+}
+
+// isDecryptionKeyValid checks that the decryption key and its primary key are
+// neither expired nor revoked at now.
+func isDecryptionKeyValid(key openpgp.Key, now time.Time) bool {
+	entity := key.Entity
+	if len(entity.Revocations) != 0 {
+		return false
+	}
+	identity := entity.PrimaryIdentity()
+	if identity == nil || entity.PrimaryKey.KeyExpired(identity.SelfSignature, now) {
+		return false
+	}
+	if key.PublicKey == entity.PrimaryKey {
+		return true
+	}
+	return key.SelfSignature.SigType != packet.SigTypeSubkeyRevocation &&
+		!key.PublicKey.KeyExpired(key.SelfSignature, now)
+}
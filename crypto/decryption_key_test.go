@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptWithExpiredOrRevokedSubkey(t *testing.T) {
+	decryptTime := GetUnixTime() + 3600
+	var tests = []struct {
+		name       string
+		invalidate func(sig *packet.Signature)
+	}{
+		{"expired", func(sig *packet.Signature) {
+			lifetime := uint32(60)
+			sig.KeyLifetimeSecs = &lifetime
+		}},
+		{"revoked", func(sig *packet.Signature) {
+			sig.SigType = packet.SigTypeSubkeyRevocation
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+			if err != nil {
+				t.Fatal("Expected no error while generating key, got:", err)
+			}
+			keyRing, err := NewKeyRing(key)
+			if err != nil {
+				t.Fatal("Expected no error while building keyring, got:", err)
+			}
+			encrypted, err := keyRing.Encrypt(NewPlainMessageFromString("before the subkey expired"), nil)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting, got:", err)
+			}
+
+			decrypted, err := keyRing.Decrypt(encrypted, nil, decryptTime)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assert.False(t, decrypted.IsDecryptionKeyExpired())
+
+			test.invalidate(key.entity.Subkeys[0].Sig)
+			assert.Exactly(t, 1, keyRing.CountDecryptionEntities())
+			_, err = key.GetEncryptionKey(decryptTime)
+			assert.Error(t, err)
+
+			decrypted, err = keyRing.Decrypt(encrypted, nil, decryptTime)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting with invalid subkey, got:", err)
+			}
+			assert.Exactly(t, "before the subkey expired", decrypted.GetString())
+			assert.True(t, decrypted.IsDecryptionKeyExpired())
+
+			reader, err := keyRing.DecryptStream(encrypted.NewReader(), nil, decryptTime)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting stream with invalid subkey, got:", err)
+			}
+			assert.True(t, reader.IsDecryptionKeyExpired())
+		})
+	}
+}
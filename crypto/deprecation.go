@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// deprecationQueueSize is the number of events waiting for the observer
+// beyond which new events are dropped.
+const deprecationQueueSize = 64
+
+// DeprecationEvent describes deprecated cryptographic material encountered by
+// an operation, see SetDeprecationObserver. It never contains plaintext nor
+// secret material.
+type DeprecationEvent struct {
+	// One of the constants.DEPRECATION_OPERATION_* values
+	Operation string
+	// The deprecated algorithm: a hash, e.g. constants.SHA1, a cipher, e.g.
+	// constants.CAST5, or a key algorithm, e.g. "rsa" or "dsa"
+	Algorithm string
+	// The size in bits of a weak key, 0 for hashes and ciphers
+	KeyBits int
+	// The hex SHA-256 digest of the fingerprint of the primary key of the key
+	// involved, empty if unknown
+	KeyFingerprintHash string
+}
+
+// DeprecationObserver is notified of the deprecated cryptographic material
+// encountered when parsing keys, verifying signatures and decrypting
+// messages: MD5, SHA-1 and RIPEMD-160 signatures, CAST5 and 3DES encrypted
+// data, RSA keys of less than 2048 bits, DSA and ElGamal keys.
+type DeprecationObserver interface {
+	OnDeprecation(event *DeprecationEvent)
+}
+
+// SetDeprecationObserver sets the observer notified of the deprecated material
+// encountered by all the operations, or removes it if nil.
+// The observer is called from a single goroutine, in order, and never blocks
+// the operations: the events are dropped while too many of them are waiting
+// for a slow observer.
+func SetDeprecationObserver(observer DeprecationObserver) {
+	pgp.deprecationLock.Lock()
+	defer pgp.deprecationLock.Unlock()
+
+	if previous := getDeprecationNotifier(); previous != nil {
+		close(previous.stop)
+	}
+	var notifier *deprecationNotifier
+	if observer != nil {
+		notifier = &deprecationNotifier{
+			events: make(chan *DeprecationEvent, deprecationQueueSize),
+			stop:   make(chan struct{}),
+		}
+		go notifier.run(observer)
+	}
+	pgp.deprecationNotifier.Store(notifier)
+}
+
+// deprecationNotifier queues the events for the observer.
+type deprecationNotifier struct {
+	events chan *DeprecationEvent
+	stop   chan struct{}
+}
+
+func (notifier *deprecationNotifier) run(observer DeprecationObserver) {
+	for {
+		select {
+		case event := <-notifier.events:
+			observer.OnDeprecation(event)
+		case <-notifier.stop:
+			return
+		}
+	}
+}
+
+// notify queues the event, or drops it if the queue is full.
+func (notifier *deprecationNotifier) notify(event *DeprecationEvent) {
+	select {
+	case notifier.events <- event:
+	default:
+	}
+}
+
+// getDeprecationNotifier returns the notifier of the observer, nil if none is
+// set, in which case nothing else must be done to report deprecations.
+func getDeprecationNotifier() *deprecationNotifier {
+	notifier, _ := pgp.deprecationNotifier.Load().(*deprecationNotifier)
+	return notifier
+}
+
+// reportKeyDeprecations reports the weak keys of the entity and its
+// self-signatures made with deprecated hashes.
+func reportKeyDeprecations(entity *openpgp.Entity) {
+	notifier := getDeprecationNotifier()
+	if notifier == nil {
+		return
+	}
+	operation := constants.DEPRECATION_OPERATION_KEY_PARSING
+	notifier.reportKey(operation, entity, entity.PrimaryKey)
+	for _, identity := range entity.Identities {
+		if identity.SelfSignature != nil {
+			notifier.reportHash(operation, entity, identity.SelfSignature.Hash)
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		notifier.reportKey(operation, entity, subkey.PublicKey)
+		if subkey.Sig != nil {
+			notifier.reportHash(operation, entity, subkey.Sig.Hash)
+		}
+	}
+}
+
+// reportSignatureDeprecations reports the signature packets of signature made
+// with deprecated hashes, and the weak keys of entities which issued them.
+func reportSignatureDeprecations(entities openpgp.EntityList, signature []byte) {
+	notifier := getDeprecationNotifier()
+	if notifier == nil {
+		return
+	}
+	packets := packet.NewReader(bytes.NewReader(signature))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok {
+			continue
+		}
+		var signer *openpgp.Entity
+		if sig.IssuerKeyId != nil {
+			for _, key := range entities.KeysById(*sig.IssuerKeyId) {
+				signer = key.Entity
+				notifier.reportKey(constants.DEPRECATION_OPERATION_VERIFICATION, key.Entity, key.PublicKey)
+			}
+		}
+		notifier.reportHash(constants.DEPRECATION_OPERATION_VERIFICATION, signer, sig.Hash)
+	}
+}
+
+// reportMessageSignatureDeprecations reports the deprecated hash and weak key
+// of the signature verified while reading a message.
+func reportMessageSignatureDeprecations(md *openpgp.MessageDetails) {
+	notifier := getDeprecationNotifier()
+	if notifier == nil || md.Signature == nil {
+		return
+	}
+	var signer *openpgp.Entity
+	if md.SignedBy != nil {
+		signer = md.SignedBy.Entity
+		notifier.reportKey(constants.DEPRECATION_OPERATION_VERIFICATION, signer, md.SignedBy.PublicKey)
+	}
+	notifier.reportHash(constants.DEPRECATION_OPERATION_VERIFICATION, signer, md.Signature.Hash)
+}
+
+// reportDecryptionDeprecations reports a deprecated cipher of decrypted data,
+// and the weak key which decrypted its session key, if any.
+func reportDecryptionDeprecations(cipherFunc packet.CipherFunction, decryptionKey *openpgp.Key) {
+	notifier := getDeprecationNotifier()
+	if notifier == nil {
+		return
+	}
+	var entity *openpgp.Entity
+	if decryptionKey != nil {
+		entity = decryptionKey.Entity
+		notifier.reportKey(constants.DEPRECATION_OPERATION_DECRYPTION, entity, decryptionKey.PublicKey)
+	}
+	if cipherFunc == packet.CipherCAST5 || cipherFunc == packet.Cipher3DES {
+		notifier.notify(&DeprecationEvent{
+			Operation:          constants.DEPRECATION_OPERATION_DECRYPTION,
+			Algorithm:          getCipherName(cipherFunc),
+			KeyFingerprintHash: getFingerprintHash(entity),
+		})
+	}
+}
+
+func (notifier *deprecationNotifier) reportKey(operation string, entity *openpgp.Entity, key *packet.PublicKey) {
+	if !isWeakSigningKey(key) && key.PubKeyAlgo != packet.PubKeyAlgoElGamal {
+		return
+	}
+	bitLength, _ := key.BitLength()
+	notifier.notify(&DeprecationEvent{
+		Operation:          operation,
+		Algorithm:          getAlgorithmName(key.PubKeyAlgo),
+		KeyBits:            int(bitLength),
+		KeyFingerprintHash: getFingerprintHash(entity),
+	})
+}
+
+func (notifier *deprecationNotifier) reportHash(operation string, entity *openpgp.Entity, hash crypto.Hash) {
+	var name string
+	switch hash {
+	case crypto.MD5:
+		name = constants.MD5
+	case crypto.SHA1:
+		name = constants.SHA1
+	case crypto.RIPEMD160:
+		name = constants.RIPEMD160
+	default:
+		return
+	}
+	notifier.notify(&DeprecationEvent{
+		Operation:          operation,
+		Algorithm:          name,
+		KeyFingerprintHash: getFingerprintHash(entity),
+	})
+}
+
+// getFingerprintHash returns the hex SHA-256 digest of the fingerprint of the
+// primary key of entity, or an empty string if it is nil.
+func getFingerprintHash(entity *openpgp.Entity) string {
+	if entity == nil {
+		return ""
+	}
+	digest := sha256.Sum256(entity.PrimaryKey.Fingerprint)
+	return hex.EncodeToString(digest[:])
+}
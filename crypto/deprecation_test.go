@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+type deprecationRecorder struct {
+	events chan *DeprecationEvent
+}
+
+func (recorder *deprecationRecorder) OnDeprecation(event *DeprecationEvent) {
+	recorder.events <- event
+}
+
+func (recorder *deprecationRecorder) expect(t *testing.T, expected *DeprecationEvent) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-recorder.events:
+			if *event == *expected {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected deprecation event:", *expected)
+		}
+	}
+}
+
+func TestDeprecationObserver(t *testing.T) {
+	recorder := &deprecationRecorder{events: make(chan *DeprecationEvent, deprecationQueueSize)}
+	SetDeprecationObserver(recorder)
+	defer SetDeprecationObserver(nil)
+
+	// RSA 1024 key
+	armored, err := keyTestRSA.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	if _, err = NewKeyFromArmored(armored); err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	rsaFingerprintHash := getFingerprintHash(keyTestRSA.entity)
+	recorder.expect(t, &DeprecationEvent{
+		Operation:          constants.DEPRECATION_OPERATION_KEY_PARSING,
+		Algorithm:          "rsa",
+		KeyBits:            1024,
+		KeyFingerprintHash: rsaFingerprintHash,
+	})
+
+	// SHA-1 signature
+	message := NewPlainMessageFromString("signed with SHA-1")
+	var signature bytes.Buffer
+	config := &packet.Config{DefaultHash: crypto.SHA1}
+	if err := openpgp.DetachSign(&signature, keyTestEC.entity, message.NewReader(), config); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Error(t, keyRing.VerifyDetached(message, NewPGPSignature(signature.Bytes()), GetUnixTime()))
+	recorder.expect(t, &DeprecationEvent{
+		Operation:          constants.DEPRECATION_OPERATION_VERIFICATION,
+		Algorithm:          constants.SHA1,
+		KeyFingerprintHash: getFingerprintHash(keyTestEC.entity),
+	})
+
+	// CAST5 data
+	sessionKey := NewSessionKeyFromToken(bytes.Repeat([]byte{1}, 16), constants.CAST5)
+	dataPacket, err := sessionKey.Encrypt(NewPlainMessageFromString("encrypted with CAST5"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = sessionKey.Decrypt(dataPacket); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	recorder.expect(t, &DeprecationEvent{
+		Operation: constants.DEPRECATION_OPERATION_DECRYPTION,
+		Algorithm: constants.CAST5,
+	})
+
+	// RSA 1024 decryption key
+	rsaKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	encrypted, err := rsaKeyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = rsaKeyRing.Decrypt(encrypted, nil, 0); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	recorder.expect(t, &DeprecationEvent{
+		Operation:          constants.DEPRECATION_OPERATION_DECRYPTION,
+		Algorithm:          "rsa",
+		KeyBits:            1024,
+		KeyFingerprintHash: rsaFingerprintHash,
+	})
+}
+
+type blockingObserver struct {
+	release chan struct{}
+}
+
+func (observer *blockingObserver) OnDeprecation(event *DeprecationEvent) {
+	<-observer.release
+}
+
+func TestDeprecationObserverNonBlocking(t *testing.T) {
+	observer := &blockingObserver{release: make(chan struct{})}
+	SetDeprecationObserver(observer)
+	defer SetDeprecationObserver(nil)
+	defer close(observer.release)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*deprecationQueueSize; i++ {
+			reportKeyDeprecations(keyTestRSA.entity)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the events to be dropped while the observer is blocked")
+	}
+
+	SetDeprecationObserver(nil)
+	assert.Nil(t, getDeprecationNotifier())
+}
@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	goerrors "errors"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+)
+
+// ErrInvalidECDHEphemeralKey is matched by InvalidECDHEphemeralKeyError with
+// errors.Is.
+var ErrInvalidECDHEphemeralKey = goerrors.New("gopenpgp: invalid ECDH ephemeral key")
+
+// InvalidECDHEphemeralKeyError is returned when the ephemeral public point of
+// an ECDH encrypted session key packet isn't a valid point of the curve of
+// the recipient key, e.g. a point of low order, which would make the shared
+// secret independent of the recipient key, or a point off the curve.
+// The session key is not decrypted with such a point.
+type InvalidECDHEphemeralKeyError struct {
+	// KeyID is the hex key ID of the recipient key
+	KeyID string
+	// Reason describes why the point is invalid
+	Reason string
+}
+
+// Error is the base method for all errors.
+func (e InvalidECDHEphemeralKeyError) Error() string {
+	return "gopenpgp: invalid ECDH ephemeral key for " + e.KeyID + ": " + e.Reason
+}
+
+// Is matches ErrInvalidECDHEphemeralKey.
+func (e InvalidECDHEphemeralKeyError) Is(target error) bool {
+	return target == ErrInvalidECDHEphemeralKey
+}
+
+// ValidateECDHEphemeralKey checks that the ephemeral public point of an ECDH
+// key packet is a valid point of the curve of the matching encryption key of
+// key, before decrypting it outside of the library, see GetKeyPackets. It
+// returns an InvalidECDHEphemeralKeyError if the point is invalid, and nil
+// for the other algorithms.
+func (keyPacket *EncryptedKeyPacket) ValidateECDHEphemeralKey(key *Key) error {
+	if keyPacket.Algorithm != packet.PubKeyAlgoECDH {
+		return nil
+	}
+	if len(keyPacket.EncryptedMPIs) == 0 {
+		return errors.New("gopenpgp: missing ECDH ephemeral key")
+	}
+	hidden := keyPacket.KeyID == keyIDToHex(0)
+	var validationErr error
+	for _, pub := range getKeyPublicKeys(key) {
+		if pub.PubKeyAlgo != packet.PubKeyAlgoECDH || (!hidden && keyIDToHex(pub.KeyId) != keyPacket.KeyID) {
+			continue
+		}
+		err := validateECDHEphemeralKey(pub, keyPacket.EncryptedMPIs[0])
+		if err == nil {
+			return nil
+		}
+		validationErr = err
+	}
+	if validationErr == nil {
+		return errors.New("gopenpgp: no matching ECDH key for the key packet")
+	}
+	return validationErr
+}
+
+// getKeyPublicKeys returns the public keys of the primary key and subkeys of
+// key.
+func getKeyPublicKeys(key *Key) []*packet.PublicKey {
+	publicKeys := []*packet.PublicKey{key.entity.PrimaryKey}
+	for _, subkey := range key.entity.Subkeys {
+		publicKeys = append(publicKeys, subkey.PublicKey)
+	}
+	return publicKeys
+}
+
+// checkECDHEphemeralKey checks the ephemeral public point of ek if it is an
+// ECDH key packet decrypted with priv.
+func checkECDHEphemeralKey(ek *packet.EncryptedKey, priv *packet.PrivateKey) error {
+	if ek.Algo != packet.PubKeyAlgoECDH || priv.PubKeyAlgo != packet.PubKeyAlgoECDH {
+		return nil
+	}
+	// go-crypto doesn't expose the encrypted values of the packet
+	var serialized bytes.Buffer
+	if err := ek.Serialize(&serialized); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to serialize key packet")
+	}
+	keyPacket, err := parseEncryptedKeyPacket(getRawPacketBody(serialized.Bytes()))
+	if err != nil {
+		return err
+	}
+	return validateECDHEphemeralKey(&priv.PublicKey, keyPacket.EncryptedMPIs[0])
+}
+
+// curveTypeCurve25519 is the value of ecc.Curve25519, which go-crypto
+// doesn't export.
+const curveTypeCurve25519 = 2
+
+// x25519CheckScalar is the scalar multiplied with X25519 ephemeral points to
+// detect the points of low order. The clamped scalar is a multiple of the
+// cofactor, so the product is zero only for them.
+var x25519CheckScalar = []byte{
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+}
+
+// validateECDHEphemeralKey checks that point, an ephemeral public point in
+// its OpenPGP encoding, is a valid point of the curve of pub, and not the
+// identity nor another point of low order.
+func validateECDHEphemeralKey(pub *packet.PublicKey, point []byte) error {
+	invalid := func(reason string) error {
+		return InvalidECDHEphemeralKeyError{KeyID: keyIDToHex(pub.KeyId), Reason: reason}
+	}
+	ecdhKey, ok := pub.PublicKey.(*ecdh.PublicKey)
+	if !ok {
+		return errors.New("gopenpgp: unsupported ECDH key")
+	}
+
+	if ecdhKey.CurveType == curveTypeCurve25519 {
+		// The point is prefixed with 0x40, see RFC 4880bis, section 13.2
+		if len(point) != 33 {
+			return invalid("wrong length")
+		}
+		if point[0] != 0x40 {
+			return invalid("wrong prefix")
+		}
+		if _, err := curve25519.X25519(x25519CheckScalar, point[1:]); err != nil {
+			return invalid("point of low order")
+		}
+		return nil
+	}
+
+	byteLen := (ecdhKey.Curve.Params().BitSize + 7) / 8
+	if len(point) != 1+2*byteLen {
+		return invalid("wrong length")
+	}
+	if point[0] != 4 {
+		return invalid("not an uncompressed point")
+	}
+	if isZero(point[1:]) {
+		return invalid("point at infinity")
+	}
+	// Unmarshal checks that the point is on the curve
+	if x, _ := elliptic.Unmarshal(ecdhKey.Curve, point); x == nil {
+		return invalid("point not on the curve")
+	}
+	return nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
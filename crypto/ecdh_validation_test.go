@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+// getTestP256Key returns a copy of keyTestEC whose encryption subkey is
+// replaced by a P-256 ECDH key.
+func getTestP256Key(t *testing.T) *Key {
+	key, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	subkey := &key.entity.Subkeys[0]
+	kdf := subkey.PublicKey.PublicKey.(*ecdh.PublicKey).KDF
+	priv, err := ecdh.GenerateKey(elliptic.P256(), kdf, rand.Reader)
+	if err != nil {
+		t.Fatal("Expected no error while generating P-256 key, got:", err)
+	}
+	subkey.PrivateKey = packet.NewECDHPrivateKey(subkey.PublicKey.CreationTime, priv)
+	subkey.PublicKey = &subkey.PrivateKey.PublicKey
+	return key
+}
+
+// encryptTestECDHKeyPacket returns a key packet of the session key encrypted
+// to the encryption subkey of key.
+func encryptTestECDHKeyPacket(t *testing.T, key *Key) []byte {
+	var keyPacket bytes.Buffer
+	err := packet.SerializeEncryptedKey(&keyPacket, key.entity.Subkeys[0].PublicKey, packet.CipherAES256, testSessionKey.Key, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	return keyPacket.Bytes()
+}
+
+// replaceEphemeralKey returns the ECDH key packet with its ephemeral point
+// replaced by point.
+func replaceEphemeralKey(t *testing.T, keyPacket []byte, point []byte) []byte {
+	parsed, err := parseEncryptedKeyPacket(getRawPacketBody(keyPacket))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key packet, got:", err)
+	}
+	body := []byte{3, 0, 0, 0, 0, 0, 0, 0, 0, byte(packet.PubKeyAlgoECDH)}
+	copy(body[1:9], getRawPacketBody(keyPacket)[1:9])
+	body = append(body, 0, 0)
+	binary.BigEndian.PutUint16(body[len(body)-2:], uint16(8*len(point)))
+	body = append(body, point...)
+	wrapped := parsed.EncryptedMPIs[1]
+	body = append(body, byte(len(wrapped)))
+	body = append(body, wrapped...)
+	return append([]byte{0xc1, byte(len(body))}, body...)
+}
+
+func TestECDHEphemeralKeyValidation(t *testing.T) {
+	p256Key := getTestP256Key(t)
+	p256Point := elliptic.Marshal(elliptic.P256(), elliptic.P256().Params().Gx, elliptic.P256().Params().Gy)
+	offCurvePoint := clone(p256Point)
+	offCurvePoint[len(offCurvePoint)-1] ^= 1
+	compressedPoint := elliptic.MarshalCompressed(elliptic.P256(), elliptic.P256().Params().Gx, elliptic.P256().Params().Gy)
+	lowOrderPoint := append([]byte{0x40, 1}, make([]byte, 31)...)
+
+	tests := []struct {
+		name  string
+		key   *Key
+		point []byte
+	}{
+		{"x25519 wrong length", keyTestEC, append([]byte{0x40}, make([]byte, 31)...)},
+		{"x25519 wrong prefix", keyTestEC, append([]byte{0x04}, p256Point[1:33]...)},
+		{"x25519 all zero", keyTestEC, append([]byte{0x40}, make([]byte, 32)...)},
+		{"x25519 low order", keyTestEC, lowOrderPoint},
+		{"p256 wrong length", p256Key, p256Point[:33]},
+		{"p256 all zero", p256Key, append([]byte{0x04}, make([]byte, 64)...)},
+		{"p256 off curve", p256Key, offCurvePoint},
+		{"p256 compressed", p256Key, compressedPoint},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyRing, err := NewKeyRing(test.key)
+			if err != nil {
+				t.Fatal("Expected no error while building keyring, got:", err)
+			}
+			keyPacket := replaceEphemeralKey(t, encryptTestECDHKeyPacket(t, test.key), test.point)
+
+			_, err = keyRing.DecryptSessionKey(keyPacket)
+			assert.True(t, errors.Is(err, ErrInvalidECDHEphemeralKey), err)
+			var invalidErr InvalidECDHEphemeralKeyError
+			assert.True(t, errors.As(err, &invalidErr))
+			assert.Exactly(t, keyIDToHex(test.key.entity.Subkeys[0].PublicKey.KeyId), invalidErr.KeyID)
+
+			dataPacket, err := testSessionKey.Encrypt(NewPlainMessageFromString("hello"))
+			if err != nil {
+				t.Fatal("Expected no error while encrypting data, got:", err)
+			}
+			_, err = keyRing.Decrypt(NewPGPSplitMessage(keyPacket, dataPacket).GetPGPMessage(), nil, 0)
+			assert.True(t, errors.Is(err, ErrInvalidECDHEphemeralKey), err)
+
+			keyPackets, err := NewPGPSplitMessage(keyPacket, nil).GetKeyPackets()
+			if err != nil {
+				t.Fatal("Expected no error while getting key packets, got:", err)
+			}
+			err = keyPackets[0].ValidateECDHEphemeralKey(test.key)
+			assert.True(t, errors.Is(err, ErrInvalidECDHEphemeralKey), err)
+		})
+	}
+}
+
+func TestECDHEphemeralKeyValidationValid(t *testing.T) {
+	for _, key := range []*Key{keyTestEC, getTestP256Key(t)} {
+		keyRing, err := NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		keyPacket := encryptTestECDHKeyPacket(t, key)
+
+		sessionKey, err := keyRing.DecryptSessionKey(keyPacket)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting session key, got:", err)
+		}
+		assert.Exactly(t, testSessionKey.Key, sessionKey.Key)
+
+		keyPackets, err := NewPGPSplitMessage(keyPacket, nil).GetKeyPackets()
+		if err != nil {
+			t.Fatal("Expected no error while getting key packets, got:", err)
+		}
+		assert.NoError(t, keyPackets[0].ValidateECDHEphemeralKey(key))
+	}
+
+	// The point is checked against the curve of the key
+	keyPackets, err := NewPGPSplitMessage(encryptTestECDHKeyPacket(t, keyTestEC), nil).GetKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while getting key packets, got:", err)
+	}
+	assert.Error(t, keyPackets[0].ValidateECDHEphemeralKey(keyTestRSA))
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	goerrors "errors"
+	"strings"
+
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// GetEmbeddedVerificationKey returns the public key embedded in the decrypted
+// message which verified its signature, or nil, see
+// VerifyOptions.AllowEmbeddedKeyVerification.
+func (msg *PlainMessage) GetEmbeddedVerificationKey() *Key {
+	return msg.embeddedVerificationKey
+}
+
+// verifyWithEmbeddedKey decrypts message again to verify its signature with
+// the key embedded in it, after the verification of plainMessage failed with
+// verifyErr, and returns the result of the verification.
+func (keyRing *KeyRing) verifyWithEmbeddedKey(
+	message *PGPMessage, plainMessage *PlainMessage, verifyErr error, verifyTime int64, policy signerPolicy,
+) (*PlainMessage, error) {
+	embeddedKey := message.getEmbeddedKey()
+	if embeddedKey == nil {
+		return plainMessage, verifyErr
+	}
+	embeddedKeyRing, err := NewKeyRing(embeddedKey)
+	if err != nil {
+		return plainMessage, verifyErr
+	}
+	_, err = asymmetricDecrypt(message.NewReader(), keyRing, embeddedKeyRing, verifyTime, policy)
+	if err != nil {
+		var sigErr SignatureVerificationError
+		if goerrors.As(err, &sigErr) {
+			return plainMessage, err
+		}
+		return plainMessage, verifyErr
+	}
+	embeddedErr := newSignatureVerifiedWithEmbeddedKey()
+	var sigErr SignatureVerificationError
+	if goerrors.As(verifyErr, &sigErr) {
+		embeddedErr.SignerKeyID = sigErr.SignerKeyID
+		embeddedErr.CreationTime = sigErr.CreationTime
+	}
+	plainMessage.embeddedVerificationKey = embeddedKey
+	return plainMessage, embeddedErr
+}
+
+// getEmbeddedKey returns the public key embedded in the message, or nil.
+func (msg *PGPMessage) getEmbeddedKey() *Key {
+	var key *Key
+	var err error
+	if msg.armoredEmbeddedKey != "" {
+		key, err = NewKeyFromArmored(msg.armoredEmbeddedKey)
+	} else if keyPackets := getTrailingKeyPackets(msg.GetBinary()); keyPackets != nil {
+		key, err = NewKey(keyPackets)
+	}
+	if err != nil || key == nil || key.IsPrivate() {
+		return nil
+	}
+	return key
+}
+
+// getArmoredEmbeddedKey returns the armored public key following the first
+// armored block, or an empty string.
+func getArmoredEmbeddedKey(armored string) string {
+	begin := "-----BEGIN " + constants.PublicKeyHeader + "-----"
+	offset := strings.Index(armored, "-----END ")
+	if offset < 0 {
+		return ""
+	}
+	start := strings.Index(armored[offset:], begin)
+	if start < 0 {
+		return ""
+	}
+	return armored[offset+start:]
+}
+
+// getTrailingKeyPackets returns the packets of data from the first public key
+// packet following the packets of the message, or nil.
+func getTrailingKeyPackets(data []byte) []byte {
+	for offset := 0; offset < len(data); {
+		tag, _, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil
+		}
+		if tag == packetTagPublicKey {
+			return data[offset:]
+		}
+		length, ok := getPacketLengthWithChunks(data[offset:])
+		if !ok {
+			return nil
+		}
+		offset += length
+	}
+	return nil
+}
+
+// getPacketLengthWithChunks returns the length of the first packet of data,
+// including its header and the headers of its partial body chunks, if it
+// doesn't have an indeterminate length and isn't truncated.
+func getPacketLengthWithChunks(data []byte) (int, bool) {
+	if data[0]&0x40 == 0 || !(data[1] >= 224 && data[1] < 255) {
+		_, length, err := readPacketHeader(data)
+		if err != nil || !hasDefiniteLength(data) || length > len(data) {
+			return 0, false
+		}
+		return length, true
+	}
+	for offset := 1; offset < len(data); {
+		b := data[offset]
+		var length int
+		switch {
+		case b < 192:
+			length = 1 + int(b)
+		case b < 224:
+			if len(data)-offset < 2 {
+				return 0, false
+			}
+			length = 2 + (int(b)-192)<<8 + int(data[offset+1]) + 192
+		case b < 255:
+			// A partial body chunk, followed by another length
+			offset += 1 + 1<<(b&0x1f)
+			continue
+		default:
+			if len(data)-offset < 5 {
+				return 0, false
+			}
+			var err error
+			if length, err = readFourOctetLength(data[offset+1:], 5); err != nil {
+				return 0, false
+			}
+		}
+		if length > len(data)-offset {
+			return 0, false
+		}
+		return offset + length, true
+	}
+	return 0, false
+}
+
+// isSignatureNoVerifier returns whether err is a SignatureVerificationError
+// with the constants.SIGNATURE_NO_VERIFIER status.
+func isSignatureNoVerifier(err error) bool {
+	var sigErr SignatureVerificationError
+	return goerrors.As(err, &sigErr) && sigErr.Status == constants.SIGNATURE_NO_VERIFIER
+}
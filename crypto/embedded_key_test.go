@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func assertSignatureStatus(t *testing.T, status int, err error) {
+	var sigErr SignatureVerificationError
+	if !errors.As(err, &sigErr) {
+		t.Fatal("Expected a SignatureVerificationError, got:", err)
+	}
+	assert.Exactly(t, status, sigErr.Status)
+}
+
+func encryptWithEmbeddedKey(t *testing.T) (*PGPMessage, []byte) {
+	message := NewPlainMessageFromString("signed with an embedded key")
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	signingKey, err := keyRingTestPublic.GetKey(0)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	publicKey, err := signingKey.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	return encrypted, publicKey
+}
+
+func TestDecryptWithEmbeddedKeyPackets(t *testing.T) {
+	encrypted, publicKey := encryptWithEmbeddedKey(t)
+	withKey := NewPGPMessage(append(encrypted.GetBinary(), publicKey...))
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building key ring, got:", err)
+	}
+
+	// Disabled by default
+	decrypted, err := keyRingTestPrivate.Decrypt(withKey, otherKeyRing, GetUnixTime())
+	assertSignatureStatus(t, constants.SIGNATURE_NO_VERIFIER, err)
+	assert.Nil(t, decrypted.GetEmbeddedVerificationKey())
+
+	options := &VerifyOptions{AllowEmbeddedKeyVerification: true}
+	decrypted, err = keyRingTestPrivate.DecryptWithOptions(withKey, otherKeyRing, GetUnixTime(), options)
+	assertSignatureStatus(t, constants.SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY, err)
+	assert.Exactly(t, "signed with an embedded key", decrypted.GetString())
+	embeddedKey := decrypted.GetEmbeddedVerificationKey()
+	if embeddedKey == nil {
+		t.Fatal("Expected the embedded key")
+	}
+	signingKey, _ := keyRingTestPublic.GetKey(0)
+	assert.Exactly(t, signingKey.GetFingerprint(), embeddedKey.GetFingerprint())
+
+	// Only for the call
+	_, err = keyRingTestPrivate.Decrypt(withKey, otherKeyRing, GetUnixTime())
+	assertSignatureStatus(t, constants.SIGNATURE_NO_VERIFIER, err)
+
+	// The keys of the keyring are used first
+	decrypted, err = keyRingTestPrivate.DecryptWithOptions(withKey, keyRingTestPublic, GetUnixTime(), options)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.GetEmbeddedVerificationKey())
+
+	// Without embedded key
+	_, err = keyRingTestPrivate.DecryptWithOptions(encrypted, otherKeyRing, GetUnixTime(), options)
+	assertSignatureStatus(t, constants.SIGNATURE_NO_VERIFIER, err)
+
+	// After a data packet with partial lengths
+	var streamed bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&streamed, nil, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := writer.Write(bytes.Repeat([]byte("streamed "), 100)); err != nil {
+			t.Fatal("Expected no error while writing stream, got:", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing stream, got:", err)
+	}
+	withKey = NewPGPMessage(append(streamed.Bytes(), publicKey...))
+	decrypted, err = keyRingTestPrivate.DecryptWithOptions(withKey, otherKeyRing, GetUnixTime(), options)
+	assertSignatureStatus(t, constants.SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY, err)
+	assert.NotNil(t, decrypted.GetEmbeddedVerificationKey())
+}
+
+func TestDecryptWithEmbeddedArmoredKey(t *testing.T) {
+	encrypted, publicKey := encryptWithEmbeddedKey(t)
+	armoredMessage, err := encrypted.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring message, got:", err)
+	}
+	key, err := NewKey(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	armoredPublicKey, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	withKey, err := NewPGPMessageFromArmored(armoredMessage + "\n" + armoredPublicKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+
+	empty, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating key ring, got:", err)
+	}
+	options := &VerifyOptions{AllowEmbeddedKeyVerification: true}
+	decrypted, err := keyRingTestPrivate.DecryptWithOptions(withKey, empty, GetUnixTime(), options)
+	assertSignatureStatus(t, constants.SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY, err)
+	assert.NotNil(t, decrypted.GetEmbeddedVerificationKey())
+
+	// An embedded key which didn't make the signature
+	otherKey, err := keyTestRSA.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	withOtherKey, err := NewPGPMessageFromArmored(armoredMessage + "\n" + otherKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.DecryptWithOptions(withOtherKey, empty, GetUnixTime(), options)
+	assertSignatureStatus(t, constants.SIGNATURE_NO_VERIFIER, err)
+	assert.Nil(t, decrypted.GetEmbeddedVerificationKey())
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"encoding/json"
+	goerrors "errors"
+
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// ExplicitVerifyMessageJSONVersion is the version of the JSON encoding of the
+// results of decryption with explicit verification, incremented whenever its
+// fields change.
+const ExplicitVerifyMessageJSONVersion = 1
+
+// ExplicitVerifyMessage is a decrypted message with the outcome of the
+// verification of its signatures.
+type ExplicitVerifyMessage struct {
+	Message *PlainMessage
+	// SignatureVerificationError is nil if the signature is valid.
+	SignatureVerificationError *SignatureVerificationError
+}
+
+// newExplicitVerifyMessage splits the result of a decryption with verification,
+// returning the error only if it is not a SignatureVerificationError.
+func newExplicitVerifyMessage(message *PlainMessage, err error) (*ExplicitVerifyMessage, error) {
+	if err == nil {
+		return &ExplicitVerifyMessage{Message: message}, nil
+	}
+	verificationError := &SignatureVerificationError{}
+	if !goerrors.As(err, verificationError) {
+		return nil, err
+	}
+	return &ExplicitVerifyMessage{
+		Message:                    message,
+		SignatureVerificationError: verificationError,
+	}, nil
+}
+
+// explicitVerifyMessageJSON is the JSON encoding of an ExplicitVerifyMessage,
+// whose field names must not change without incrementing
+// ExplicitVerifyMessageJSONVersion.
+type explicitVerifyMessageJSON struct {
+	Version int `json:"version"`
+	// Base64 encoded
+	Data     string `json:"data"`
+	IsBinary bool   `json:"is_binary"`
+	Filename string `json:"filename"`
+	// Unix timestamp, 0 if unset
+	ModificationTime int64 `json:"modification_time"`
+	SignatureStatus  int   `json:"signature_status"`
+	// Hex key ID, empty if unknown
+	SignerKeyID string `json:"signer_key_id"`
+}
+
+// MarshalJSON encodes the result with stable snake_case fields: version,
+// data (base64), is_binary, filename, modification_time, signature_status
+// and signer_key_id, see ExplicitVerifyMessageJSONVersion.
+func (msg *ExplicitVerifyMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newExplicitVerifyMessageJSON(msg.Message, msg.SignatureVerificationError))
+}
+
+func newExplicitVerifyMessageJSON(message *PlainMessage, verificationError *SignatureVerificationError) *explicitVerifyMessageJSON {
+	encoded := &explicitVerifyMessageJSON{
+		Version:         ExplicitVerifyMessageJSONVersion,
+		SignatureStatus: constants.SIGNATURE_OK,
+	}
+	if message != nil {
+		encoded.Data = message.GetBase64()
+		encoded.IsBinary = message.IsBinary()
+		encoded.Filename = message.Filename
+		encoded.ModificationTime = int64(message.Time)
+		encoded.SignerKeyID = message.GetHexSignerKeyID()
+	}
+	if verificationError != nil {
+		encoded.SignatureStatus = verificationError.Status
+		if verificationError.SignerKeyID != "" {
+			encoded.SignerKeyID = verificationError.SignerKeyID
+		}
+	}
+	return encoded
+}
@@ -0,0 +1,33 @@
+package crypto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplicitVerifyMessageJSON(t *testing.T) {
+	message := NewPlainMessageFromString("json bridged")
+	message.Time = 1600000000
+	dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	signerKeyID := keyRingTestPrivate.GetKeys()[0].GetHexKeyID()
+
+	explicitVerify, err := testSessionKey.DecryptAndVerifyExplicit(dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, signerKeyID, explicitVerify.Message.GetHexSignerKeyID())
+	encoded, err := json.Marshal(explicitVerify)
+	if err != nil {
+		t.Fatal("Expected no error while encoding, got:", err)
+	}
+	assert.Exactly(t,
+		`{"version":1,"data":"anNvbiBicmlkZ2Vk","is_binary":false,"filename":"",`+
+			`"modification_time":1600000000,"signature_status":0,"signer_key_id":"`+signerKeyID+`"}`,
+		string(encoded),
+	)
+}
@@ -1,14 +1,46 @@
 // Package crypto provides a high-level API for common OpenPGP functionality.
 package crypto
 
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
 // GopenPGP is used as a "namespace" for many of the functions in this package.
 // It is a struct that keeps track of time skew between server and client.
 type GopenPGP struct {
+	// Guards the settings below, up to the deprecation observer
+	lock             sync.RWMutex
 	latestServerTime int64
+	// Advances the latest server time, unless timeFrozen is set
+	serverTime *ServerTimeProvider
+	timeFrozen bool
+	// Replaces the server time if set, see SetTimeProvider
+	timeProvider     TimeProvider
 	generationOffset int64
+	maxPacketNesting int
+	// Limits of the packets parsed in messages, see SetMaxPacketCounts
+	maxESKPackets int
+	maxPackets    int
+	// Maximum size of buffered plaintexts, see SetMaxPlaintextSize
+	maxPlaintextSize int64
+	// Size of the plaintext chunks, see SetPacketChunkSize
+	packetChunkSize int
+	// Protection of the secret keys locked by Key.Lock, see SetKeyProtection
+	keyProtection *S2KConfig
+	// Whether verification rejects weak signing keys by default, see
+	// SetRejectWeakSigningKeys
+	rejectWeakSigningKeys bool
+	// Source of randomness replacing crypto/rand in tests, see setRandomSource
+	random io.Reader
+	// Holds the *deprecationNotifier of the DeprecationObserver, replaced
+	// under deprecationLock, see SetDeprecationObserver
+	deprecationNotifier atomic.Value
+	deprecationLock     sync.Mutex
 }
 
-var pgp = GopenPGP{}
+var pgp = GopenPGP{serverTime: NewServerTimeProvider()}
 
 // clone returns a clone of the byte slice. Internal function used to make sure
 // we don't retain a reference to external data.
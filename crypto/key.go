@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"strconv"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/yougroupteam/gopenpgp/v2/armor"
 	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
 
 	openpgp "github.com/ProtonMail/go-crypto/openpgp"
 	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
@@ -23,6 +25,8 @@ import (
 type Key struct {
 	// PGP entities in this keyring.
 	entity *openpgp.Entity
+	// Packets which aren't parsed, see KeyImportOptions
+	preserved *preservedPackets
 }
 
 // --- Create Key object
@@ -59,6 +63,25 @@ func NewKeyFromArmored(armored string) (key *Key, err error) {
 	return NewKeyFromArmoredReader(strings.NewReader(armored))
 }
 
+// NewKeysFromArmored creates a key for each of the keys in an armored string,
+// e.g. a key ring file exported with several keys.
+func NewKeysFromArmored(armored string) (keys []*Key, err error) {
+	entities, err := readArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading key ring")
+	}
+
+	if len(entities) == 0 {
+		return nil, errors.New("gopenpgp: the key does not contain any entity")
+	}
+
+	for _, entity := range entities {
+		reportKeyDeprecations(entity)
+		keys = append(keys, &Key{entity: entity})
+	}
+	return keys, nil
+}
+
 func NewKeyFromEntity(entity *openpgp.Entity) (*Key, error) {
 	if entity == nil {
 		return nil, errors.New("gopenpgp: nil entity provided")
@@ -82,20 +105,98 @@ func GenerateKey(name, email string, keyType string, bits int) (*Key, error) {
 	return generateKey(name, email, keyType, bits, nil, nil, nil, nil)
 }
 
+// KeyGenerationOptions selects the algorithms of the primary key and of the
+// encryption subkey of a generated key.
+type KeyGenerationOptions struct {
+	// PrimaryKeyType is the type of the primary signing key:
+	// "rsa", "ed25519", or "x25519" (same as "ed25519").
+	PrimaryKeyType string
+	// PrimaryKeyBits is the RSA bitsize of the primary key, unused otherwise.
+	PrimaryKeyBits int
+	// SubkeyType is the type of the encryption subkey:
+	// "rsa", "cv25519", or "x25519" (same as "cv25519").
+	// If empty, the subkey matches PrimaryKeyType, "x25519" for an "ed25519"
+	// primary key, as in GenerateKey.
+	SubkeyType string
+	// SubkeyBits is the RSA bitsize of the subkey, if 0 PrimaryKeyBits is used.
+	SubkeyBits int
+}
+
+// GenerateKeyWithOptions generates a key whose primary key and encryption
+// subkey algorithms are selected independently,
+// e.g. an Ed25519 primary key with a RSA encryption subkey.
+func GenerateKeyWithOptions(name, email string, options *KeyGenerationOptions) (*Key, error) {
+	if options == nil {
+		return nil, errors.New("gopenpgp: no key generation options provided")
+	}
+	primaryAlgo, err := getPrimaryKeyAlgorithm(options.PrimaryKeyType)
+	if err != nil {
+		return nil, err
+	}
+	// As in GenerateKey, an EdDSA primary key gets an ECDH subkey by default
+	subkeyAlgo := packet.PubKeyAlgoRSA
+	if primaryAlgo == packet.PubKeyAlgoEdDSA {
+		subkeyAlgo = packet.PubKeyAlgoECDH
+	}
+	if options.SubkeyType != "" {
+		if subkeyAlgo, err = getSubkeyAlgorithm(options.SubkeyType); err != nil {
+			return nil, err
+		}
+	}
+	subkeyBits := options.SubkeyBits
+	if subkeyBits == 0 {
+		subkeyBits = options.PrimaryKeyBits
+	}
+
+	if len(email) == 0 {
+		return nil, errors.New("gopenpgp: invalid email format")
+	}
+	if len(name) == 0 {
+		return nil, errors.New("gopenpgp: invalid name format")
+	}
+
+	// The entity is built without the default subkey of openpgp.NewEntity,
+	// which would otherwise be generated and thrown away
+	entity, err := newPrimaryEntity(name, "", email, getKeyGenerationConfig(primaryAlgo, options.PrimaryKeyBits))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopengpp: error in encoding new entity")
+	}
+	if err = entity.AddEncryptionSubkey(getKeyGenerationConfig(subkeyAlgo, subkeyBits)); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in generating encryption subkey")
+	}
+	return NewKeyFromEntity(entity)
+}
+
 // --- Operate on key
 
 // Copy creates a deep copy of the key.
+// The copy does not share any packet or secret material with the original key.
 func (key *Key) Copy() (*Key, error) {
 	serialized, err := key.Serialize()
 	if err != nil {
 		return nil, err
 	}
+	// The serialization may contain unencrypted secret material
+	defer clearMem(serialized)
 
-	return NewKey(serialized)
+	copied, err := NewKeyFromReader(bytes.NewReader(serialized))
+	if err != nil {
+		return nil, err
+	}
+	copied.preserved = key.preserved
+	return copied, nil
 }
 
 // Lock locks a copy of the key.
+// The decrypted private parameters of the copy are wiped once it is encrypted,
+// the receiver is left untouched.
+// The secret material is encrypted with the configuration set by
+// SetKeyProtection, AES-256 and the iterated and salted S2K by default.
 func (key *Key) Lock(passphrase []byte) (*Key, error) {
+	if config := getKeyProtection(); passphrase != nil && config != nil {
+		return key.LockWithS2KConfig(passphrase, config)
+	}
+
 	unlocked, err := key.IsUnlocked()
 	if err != nil {
 		return nil, err
@@ -114,6 +215,10 @@ func (key *Key) Lock(passphrase []byte) (*Key, error) {
 		return lockedKey, nil
 	}
 
+	// Encryption drops the references to the decrypted parameters
+	// without wiping them, so we keep track of them here.
+	defer clearPrivateParams(lockedKey.getPrivateParams())
+
 	err = lockedKey.entity.PrivateKey.Encrypt(passphrase)
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in locking key")
@@ -139,7 +244,19 @@ func (key *Key) Lock(passphrase []byte) (*Key, error) {
 }
 
 // Unlock unlocks a copy of the key.
+// The secret packets are deep-copied before decryption, so that no decrypted
+// material is reachable from the receiver.
 func (key *Key) Unlock(passphrase []byte) (*Key, error) {
+	return key.unlock(passphrase, nil)
+}
+
+// UnlockWithCache unlocks a copy of the key like Unlock, reusing the keys
+// derived from the passphrase stored in cache, and storing the new ones.
+func (key *Key) UnlockWithCache(passphrase []byte, cache *UnlockCache) (*Key, error) {
+	return key.unlock(passphrase, cache)
+}
+
+func (key *Key) unlock(passphrase []byte, cache *UnlockCache) (*Key, error) {
 	isLocked, err := key.IsLocked()
 	if err != nil {
 		return nil, err
@@ -157,14 +274,17 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 		return nil, err
 	}
 
-	err = unlockedKey.entity.PrivateKey.Decrypt(passphrase)
-	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in unlocking key")
+	if !unlockedKey.entity.PrivateKey.Dummy() {
+		err = decryptPrivateKey(unlockedKey.entity.PrivateKey, passphrase, cache)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in unlocking key")
+		}
 	}
 
 	for _, sub := range unlockedKey.entity.Subkeys {
 		if sub.PrivateKey != nil && !sub.PrivateKey.Dummy() {
-			if err := sub.PrivateKey.Decrypt(passphrase); err != nil {
+			if err := decryptPrivateKey(sub.PrivateKey, passphrase, cache); err != nil {
+				unlockedKey.clearPrivateWithSubkeys()
 				return nil, errors.Wrap(err, "gopenpgp: error in unlocking sub key")
 			}
 		}
@@ -172,9 +292,11 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 
 	isUnlocked, err := unlockedKey.IsUnlocked()
 	if err != nil {
+		unlockedKey.clearPrivateWithSubkeys()
 		return nil, err
 	}
 	if !isUnlocked {
+		unlockedKey.clearPrivateWithSubkeys()
 		return nil, errors.New("gopenpgp: unable to unlock key")
 	}
 
@@ -183,7 +305,18 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 
 // --- Export key
 
+// Serialize returns the binary key, with the packets preserved at import, see
+// KeyImportOptions.
 func (key *Key) Serialize() ([]byte, error) {
+	serialized, err := key.serializeEntity()
+	if err != nil || key.preserved == nil {
+		return serialized, err
+	}
+	return key.preserved.restore(serialized)
+}
+
+// serializeEntity serializes the packets of the key which are parsed.
+func (key *Key) serializeEntity() ([]byte, error) {
 	var buffer bytes.Buffer
 	var err error
 
@@ -225,6 +358,21 @@ func (key *Key) ArmorWithCustomHeaders(comment, version string) (string, error)
 	return armor.ArmorWithTypeAndCustomHeaders(serialized, constants.PrivateKeyHeader, version, comment)
 }
 
+// ArmorWithCustomHeadersAndOptions returns the armored key as a string, with
+// the given headers and armor options. Empty parameters are omitted from the headers.
+func (key *Key) ArmorWithCustomHeadersAndOptions(comment, version string, options *armor.Options) (string, error) {
+	serialized, err := key.Serialize()
+	if err != nil {
+		return "", err
+	}
+
+	if key.IsPrivate() {
+		return armor.ArmorWithTypeAndOptions(serialized, constants.PrivateKeyHeader, version, comment, options)
+	}
+
+	return armor.ArmorWithTypeAndOptions(serialized, constants.PublicKeyHeader, version, comment, options)
+}
+
 // GetArmoredPublicKey returns the armored public keys from this keyring.
 func (key *Key) GetArmoredPublicKey() (s string, err error) {
 	serialized, err := key.GetPublicKey()
@@ -246,6 +394,17 @@ func (key *Key) GetArmoredPublicKeyWithCustomHeaders(comment, version string) (s
 	return armor.ArmorWithTypeAndCustomHeaders(serialized, constants.PublicKeyHeader, version, comment)
 }
 
+// GetArmoredPublicKeyWithCustomHeadersAndOptions returns the armored public key as a string,
+// with the given headers and armor options. Empty parameters are omitted from the headers.
+func (key *Key) GetArmoredPublicKeyWithCustomHeadersAndOptions(comment, version string, options *armor.Options) (string, error) {
+	serialized, err := key.GetPublicKey()
+	if err != nil {
+		return "", err
+	}
+
+	return armor.ArmorWithTypeAndOptions(serialized, constants.PublicKeyHeader, version, comment, options)
+}
+
 // GetPublicKey returns the unarmored public keys from this keyring.
 func (key *Key) GetPublicKey() (b []byte, err error) {
 	var outBuf bytes.Buffer
@@ -266,17 +425,34 @@ func (key *Key) CanVerify() bool {
 
 // CanEncrypt returns true if any of the subkeys can be used for encryption.
 func (key *Key) CanEncrypt() bool {
-	_, canEncrypt := key.entity.EncryptionKey(getNow())
+	_, canEncrypt := getEncryptionKey(key.entity, getNow())
 	return canEncrypt
 }
 
+// GetPrimaryKeyAlgorithm returns the algorithm of the primary key,
+// e.g. "rsa" or "eddsa".
+func (key *Key) GetPrimaryKeyAlgorithm() string {
+	return getAlgorithmName(key.entity.PrimaryKey.PubKeyAlgo)
+}
+
+// GetEncryptionKeyAlgorithm returns the algorithm of the (sub)key used for
+// encryption, e.g. "rsa" or "ecdh".
+func (key *Key) GetEncryptionKeyAlgorithm() (string, error) {
+	encryptionKey, ok := getEncryptionKey(key.entity, getNow())
+	if !ok {
+		return "", errors.New("gopenpgp: no valid encryption key")
+	}
+	return getAlgorithmName(encryptionKey.PublicKey.PubKeyAlgo), nil
+}
+
 // IsExpired checks whether the key is expired.
 func (key *Key) IsExpired() bool {
 	_, ok := key.entity.EncryptionKey(getNow())
 	return !ok
 }
 
-// IsPrivate returns true if the key is private.
+// IsPrivate returns true if the key is private, even if its secret material
+// is stubbed, see HasAnySecretMaterial and HasCompleteSecretMaterial.
 func (key *Key) IsPrivate() bool {
 	return key.entity.PrivateKey != nil
 }
@@ -369,19 +545,20 @@ func (key *Key) GetEntity() *openpgp.Entity {
 	return key.entity
 }
 
-// ToPublic returns the corresponding public key of the given private key.
+// ToPublic returns the corresponding public key of the given private key,
+// which doesn't need to be unlocked. The public key is parsed from the
+// serialized public packets, so it shares nothing with the private key, and
+// the secret material is never copied.
 func (key *Key) ToPublic() (publicKey *Key, err error) {
 	if !key.IsPrivate() {
 		return nil, errors.New("gopenpgp: key is already public")
 	}
 
-	publicKey, err = key.Copy()
+	serialized, err := key.GetPublicKey()
 	if err != nil {
 		return nil, err
 	}
-
-	publicKey.ClearPrivateParams()
-	return
+	return NewKeyFromReader(bytes.NewReader(serialized))
 }
 
 // --- Internal methods
@@ -402,7 +579,7 @@ func (key *Key) readFrom(r io.Reader, armored bool) error {
 	var err error
 	var entities openpgp.EntityList
 	if armored {
-		entities, err = openpgp.ReadArmoredKeyRing(r)
+		entities, err = readArmoredKeyRing(r)
 	} else {
 		entities, err = openpgp.ReadKeyRing(r)
 	}
@@ -419,9 +596,31 @@ func (key *Key) readFrom(r io.Reader, armored bool) error {
 	}
 
 	key.entity = entities[0]
+	reportKeyDeprecations(key.entity)
 	return nil
 }
 
+// readArmoredKeyRing reads the keys of the first armored block of r.
+// Private key blocks are unarmored in constant time, as they may hold
+// unencrypted secret material.
+func readArmoredKeyRing(r io.Reader) (openpgp.EntityList, error) {
+	armored, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(armored, []byte("-----BEGIN "+constants.PrivateKeyHeader+"-----")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(armored))
+	}
+	block, err := internal.UnarmorConstantTime(string(armored))
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != constants.PrivateKeyHeader && block.Type != constants.PublicKeyHeader {
+		return nil, errors.New("gopenpgp: invalid armor type for a key: " + block.Type)
+	}
+	return openpgp.ReadKeyRing(block.Body)
+}
+
 func generateKey(
 	name, email string,
 	keyType string,
@@ -438,18 +637,11 @@ func generateKey(
 
 	comments := ""
 
-	cfg := &packet.Config{
-		Algorithm:              packet.PubKeyAlgoRSA,
-		RSABits:                bits,
-		Time:                   getKeyGenerationTimeGenerator(),
-		DefaultHash:            crypto.SHA256,
-		DefaultCipher:          packet.CipherAES256,
-		DefaultCompressionAlgo: packet.CompressionZLIB,
-	}
-
-	if keyType == "x25519" {
-		cfg.Algorithm = packet.PubKeyAlgoEdDSA
+	algo := packet.PubKeyAlgoRSA
+	if keyType == "x25519" || keyType == "ed25519" {
+		algo = packet.PubKeyAlgoEdDSA
 	}
+	cfg := getKeyGenerationConfig(algo, bits)
 
 	if prime1 != nil && prime2 != nil && prime3 != nil && prime4 != nil {
 		var bigPrimes [4]*big.Int
@@ -477,6 +669,64 @@ func generateKey(
 	return NewKeyFromEntity(newEntity)
 }
 
+func getKeyGenerationConfig(algo packet.PublicKeyAlgorithm, bits int) *packet.Config {
+	return &packet.Config{
+		Rand:                   getRandom(),
+		Algorithm:              algo,
+		RSABits:                bits,
+		Time:                   getKeyGenerationTimeGenerator(),
+		DefaultHash:            crypto.SHA256,
+		DefaultCipher:          packet.CipherAES256,
+		DefaultCompressionAlgo: packet.CompressionZLIB,
+	}
+}
+
+// getPrimaryKeyAlgorithm returns the algorithm of a primary key type,
+// which must be able to sign.
+func getPrimaryKeyAlgorithm(keyType string) (packet.PublicKeyAlgorithm, error) {
+	switch keyType {
+	case "rsa":
+		return packet.PubKeyAlgoRSA, nil
+	case "x25519", "ed25519":
+		return packet.PubKeyAlgoEdDSA, nil
+	case "cv25519":
+		return 0, errors.New("gopenpgp: key type cv25519 is encryption-only and can't be used for the primary key")
+	}
+	return 0, errors.Errorf("gopenpgp: unsupported primary key type %s", keyType)
+}
+
+// getSubkeyAlgorithm returns the algorithm of an encryption subkey type.
+func getSubkeyAlgorithm(keyType string) (packet.PublicKeyAlgorithm, error) {
+	switch keyType {
+	case "rsa":
+		return packet.PubKeyAlgoRSA, nil
+	case "x25519", "cv25519":
+		return packet.PubKeyAlgoECDH, nil
+	case "ed25519":
+		return 0, errors.New("gopenpgp: key type ed25519 is signing-only and can't be used for the encryption subkey")
+	}
+	return 0, errors.Errorf("gopenpgp: unsupported subkey type %s", keyType)
+}
+
+// getAlgorithmName returns a readable name for a public key algorithm.
+func getAlgorithmName(algo packet.PublicKeyAlgorithm) string {
+	switch algo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		return "rsa"
+	case packet.PubKeyAlgoElGamal:
+		return "elgamal"
+	case packet.PubKeyAlgoDSA:
+		return "dsa"
+	case packet.PubKeyAlgoECDH:
+		return "ecdh"
+	case packet.PubKeyAlgoECDSA:
+		return "ecdsa"
+	case packet.PubKeyAlgoEdDSA:
+		return "eddsa"
+	}
+	return "unknown"
+}
+
 // keyIDToHex casts a keyID to hex with the correct padding.
 func keyIDToHex(keyID uint64) string {
 	return fmt.Sprintf("%016v", strconv.FormatUint(keyID, 16))
@@ -12,11 +12,16 @@ import (
 	"github.com/ProtonMail/go-crypto/openpgp/elgamal"
 )
 
+// Clear wipes the session key material in place.
 func (sk *SessionKey) Clear() (ok bool) {
 	clearMem(sk.Key)
 	return true
 }
 
+// ClearPrivateParams wipes all the decrypted private parameters of the key and
+// its subkeys in place, and removes the private key packets.
+// The key can then only be used as a public key.
+// Returns true if any private parameter was wiped.
 func (key *Key) ClearPrivateParams() (ok bool) {
 	num := key.clearPrivateWithSubkeys()
 	key.entity.PrivateKey = nil
@@ -28,6 +33,8 @@ func (key *Key) ClearPrivateParams() (ok bool) {
 	return num > 0
 }
 
+// clearPrivateWithSubkeys wipes the decrypted private parameters of the key and
+// its subkeys, and returns the number of wiped keys.
 func (key *Key) clearPrivateWithSubkeys() (num int) {
 	num = 0
 	if key.entity.PrivateKey != nil {
@@ -47,6 +54,27 @@ func (key *Key) clearPrivateWithSubkeys() (num int) {
 	return num
 }
 
+// getPrivateParams returns the decrypted private parameters of the key and its
+// subkeys.
+func (key *Key) getPrivateParams() (params []interface{}) {
+	if key.entity.PrivateKey != nil && key.entity.PrivateKey.PrivateKey != nil {
+		params = append(params, key.entity.PrivateKey.PrivateKey)
+	}
+	for _, sub := range key.entity.Subkeys {
+		if sub.PrivateKey != nil && sub.PrivateKey.PrivateKey != nil {
+			params = append(params, sub.PrivateKey.PrivateKey)
+		}
+	}
+	return params
+}
+
+// clearPrivateParams wipes the given private parameters.
+func clearPrivateParams(params []interface{}) {
+	for _, param := range params {
+		_ = clearPrivateKey(param)
+	}
+}
+
 func clearPrivateKey(privateKey interface{}) error {
 	switch priv := privateKey.(type) {
 	case *rsa.PrivateKey:
@@ -66,11 +94,13 @@ func clearPrivateKey(privateKey interface{}) error {
 	}
 }
 
+// clearBigInt wipes the words of n, then sets it to 0 without reallocating.
 func clearBigInt(n *big.Int) {
 	w := n.Bits()
 	for k := range w {
 		w[k] = 0x00
 	}
+	n.SetBits(w[:0])
 }
 
 func clearMem(w []byte) {
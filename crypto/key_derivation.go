@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// symmetricKeyDerivationInfo is the HKDF info prefix of DeriveSymmetricKey,
+// which versions the derivation: the keys derived with a given version must
+// never change, a new derivation needs a new version.
+const symmetricKeyDerivationInfo = "gopenpgp-derive-symmetric-key-v1"
+
+// DeriveSymmetricKey derives a symmetric key of size bytes from an unlocked
+// private key and a context string, which separates the keys of different
+// uses. The derivation (v1) is HKDF-SHA256 (RFC 5869) with:
+//   - IKM: the secret key material of the primary key, as encoded in an
+//     unencrypted version 4 secret key packet (RFC 4880 section 5.5.3),
+//     between the string-to-key usage octet and the checksum;
+//   - salt: the fingerprint of the primary key (20 octets);
+//   - info: "gopenpgp-derive-symmetric-key-v1", a zero octet, and the
+//     UTF-8 context.
+//
+// The result only depends on the primary key, not on its passphrase nor on
+// its subkeys. Locked keys, public keys and keys whose primary secret key is
+// a stub return an error.
+func DeriveSymmetricKey(key *Key, context string, size int) ([]byte, error) {
+	if context == "" {
+		return nil, errors.New("gopenpgp: the key derivation context can't be empty")
+	}
+	if size <= 0 || size > 255*sha256.Size {
+		return nil, errors.Errorf("gopenpgp: invalid derived key size %d", size)
+	}
+	if key == nil || !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: a private key is required to derive a symmetric key")
+	}
+	privateKey := key.entity.PrivateKey
+	if privateKey.Dummy() {
+		return nil, errors.New("gopenpgp: the primary secret key is a stub")
+	}
+	if privateKey.Encrypted {
+		return nil, errors.New("gopenpgp: the key must be unlocked to derive a symmetric key")
+	}
+
+	secret, err := getSecretKeyMaterial(key)
+	if err != nil {
+		return nil, err
+	}
+	defer clearMem(secret)
+
+	info := append([]byte(symmetricKeyDerivationInfo), 0)
+	info = append(info, context...)
+	derived := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, privateKey.Fingerprint, info), derived); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in deriving symmetric key")
+	}
+	return derived, nil
+}
+
+// getSecretKeyMaterial returns the secret key material of the unlocked
+// primary key, as serialized in its secret key packet.
+func getSecretKeyMaterial(key *Key) ([]byte, error) {
+	privateKey := key.entity.PrivateKey
+	if privateKey.Version != 4 {
+		return nil, errors.New("gopenpgp: only version 4 keys are supported")
+	}
+
+	var privateBuf, publicBuf bytes.Buffer
+	if err := privateKey.Serialize(&privateBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing private key")
+	}
+	defer clearMem(privateBuf.Bytes())
+	if err := privateKey.PublicKey.Serialize(&publicBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing public key")
+	}
+	privateBody, publicBody := getPacketBody(privateBuf.Bytes()), getPacketBody(publicBuf.Bytes())
+	if len(privateBody) <= len(publicBody) || privateBody[len(publicBody)] != 0 {
+		return nil, errors.New("gopenpgp: unexpected secret key packet")
+	}
+
+	// Secret material followed by its SHA-1 or two-octet checksum
+	material := privateBody[len(publicBody)+1:]
+	if n := len(material) - sha1.Size; n > 0 {
+		if sum := sha1.Sum(material[:n]); bytes.Equal(sum[:], material[n:]) {
+			return clone(material[:n]), nil
+		}
+	}
+	if n := len(material) - 2; n > 0 {
+		var sum uint16
+		for _, b := range material[:n] {
+			sum += uint16(b)
+		}
+		if material[n] == byte(sum>>8) && material[n+1] == byte(sum) {
+			return clone(material[:n]), nil
+		}
+	}
+	return nil, errors.New("gopenpgp: invalid secret key checksum")
+}
@@ -0,0 +1,82 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors of DeriveSymmetricKey v1, which other implementations must match.
+var symmetricKeyDerivationVectors = []struct {
+	keyFile, context string
+	size             int
+	derived          string
+}{
+	// RSA key, whose secret material is protected with a SHA-1 checksum
+	{"keyring_privateKey", "drive-share-key", 32, "956efa01c877c031e002c743e8b8fb067d91d024ac6dad584b11da998f955a5b"},
+	{"keyring_privateKey", "calendar", 16, "3ff970f6cab0ba54ecefce42119b1f5f"},
+	// Unprotected ECDSA P-256 key, with a two-octet checksum
+	{"key_ecdsaP256", "drive-share-key", 32, "b737cd9f5f219c254ef9c2fd32d398577f9435cbd86ace5089abe5bfca25f60e"},
+	{"key_ecdsaP256", "calendar", 16, "5a3b9ed5a63e40dccacc6432b6767bda"},
+}
+
+func TestDeriveSymmetricKeyVectors(t *testing.T) {
+	for _, vector := range symmetricKeyDerivationVectors {
+		key, err := NewKeyFromArmored(readTestFile(vector.keyFile, false))
+		if err != nil {
+			t.Fatal("Expected no error while parsing key, got:", err)
+		}
+		if locked, _ := key.IsLocked(); locked {
+			if key, err = key.Unlock(testMailboxPassword); err != nil {
+				t.Fatal("Expected no error while unlocking key, got:", err)
+			}
+		}
+
+		derived, err := DeriveSymmetricKey(key, vector.context, vector.size)
+		if err != nil {
+			t.Fatal("Expected no error while deriving key, got:", err)
+		}
+		assert.Exactly(t, vector.derived, hex.EncodeToString(derived))
+	}
+}
+
+func TestDeriveSymmetricKeyErrors(t *testing.T) {
+	// Independent of the passphrase
+	relocked, err := keyTestRSA.Lock([]byte("another passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	unlocked, err := relocked.Unlock([]byte("another passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	expected, err := DeriveSymmetricKey(keyTestRSA, "context", 32)
+	if err != nil {
+		t.Fatal("Expected no error while deriving key, got:", err)
+	}
+	derived, err := DeriveSymmetricKey(unlocked, "context", 32)
+	if err != nil {
+		t.Fatal("Expected no error while deriving key, got:", err)
+	}
+	assert.Exactly(t, expected, derived)
+
+	_, err = DeriveSymmetricKey(relocked, "context", 32)
+	assert.Error(t, err)
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = DeriveSymmetricKey(publicKey, "context", 32)
+	assert.Error(t, err)
+	stub, err := NewKeyFromArmored(readTestFile("key_stubPrimary", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	_, err = DeriveSymmetricKey(stub, "context", 32)
+	assert.Error(t, err)
+	_, err = DeriveSymmetricKey(keyTestRSA, "", 32)
+	assert.Error(t, err)
+	_, err = DeriveSymmetricKey(keyTestRSA, "context", 0)
+	assert.Error(t, err)
+}
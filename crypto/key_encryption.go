@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// GetEncryptionKey returns the hex key ID of the (sub)key which messages
+// encrypted to the key at unixTime are encrypted to, see getEncryptionKey.
+func (key *Key) GetEncryptionKey(unixTime int64) (string, error) {
+	encryptionKey, ok := getEncryptionKey(key.entity, time.Unix(unixTime, 0))
+	if !ok {
+		return "", errors.New("gopenpgp: no valid encryption key")
+	}
+	return keyIDToHex(encryptionKey.PublicKey.KeyId), nil
+}
+
+// getEncryptionKey selects the key to encrypt to like
+// openpgp.Entity.EncryptionKey: the newest valid subkey flagged for
+// encrypting communications, or else the primary key. Unlike the library, the
+// primary key is only used if it can encrypt, and if its flags allow it when
+// it has any: e.g. a signing primary key with no flags, whose subkeys are all
+// authentication-only, has no encryption key.
+func getEncryptionKey(entity *openpgp.Entity, now time.Time) (openpgp.Key, bool) {
+	identity := entity.PrimaryIdentity()
+	if identity == nil || entity.PrimaryKey.KeyExpired(identity.SelfSignature, now) {
+		return openpgp.Key{}, false
+	}
+
+	candidate := -1
+	var maxTime time.Time
+	for i, subkey := range entity.Subkeys {
+		if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation ||
+			!subkey.Sig.FlagsValid || !subkey.Sig.FlagEncryptCommunications ||
+			!subkey.PublicKey.PubKeyAlgo.CanEncrypt() ||
+			subkey.PublicKey.KeyExpired(subkey.Sig, now) {
+			continue
+		}
+		if candidate == -1 || subkey.Sig.CreationTime.After(maxTime) {
+			candidate = i
+			maxTime = subkey.Sig.CreationTime
+		}
+	}
+	if candidate != -1 {
+		subkey := entity.Subkeys[candidate]
+		return openpgp.Key{
+			Entity:        entity,
+			PublicKey:     subkey.PublicKey,
+			PrivateKey:    subkey.PrivateKey,
+			SelfSignature: subkey.Sig,
+		}, true
+	}
+
+	selfSignature := identity.SelfSignature
+	if entity.PrimaryKey.PubKeyAlgo.CanEncrypt() &&
+		(!selfSignature.FlagsValid || selfSignature.FlagEncryptCommunications) {
+		return openpgp.Key{
+			Entity:        entity,
+			PublicKey:     entity.PrimaryKey,
+			PrivateKey:    entity.PrivateKey,
+			SelfSignature: selfSignature,
+		}, true
+	}
+	return openpgp.Key{}, false
+}
+
+// withEncryptionKeyOnly returns a shallow copy of entity, whose only subkey is
+// the one selected by getEncryptionKey, so that the library encrypts to it.
+func withEncryptionKeyOnly(entity *openpgp.Entity, now time.Time) (*openpgp.Entity, bool) {
+	encryptionKey, ok := getEncryptionKey(entity, now)
+	if !ok {
+		return nil, false
+	}
+	restricted := *entity
+	restricted.Subkeys = nil
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey == encryptionKey.PublicKey {
+			restricted.Subkeys = []openpgp.Subkey{subkey}
+		}
+	}
+	return &restricted, true
+}
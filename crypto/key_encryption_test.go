@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEncryptionKeyWithUsageSubkeys(t *testing.T) {
+	// Certify-only primary key, with signing, encryption, authentication and
+	// signing+authentication subkeys, created in this order
+	key, err := NewKeyFromArmored(readTestFile("key_usageSubkeys", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+
+	encryptionKeyID, err := key.GetEncryptionKey(GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while selecting encryption key, got:", err)
+	}
+	assert.Exactly(t, "12b99520314bae65", encryptionKeyID)
+	algorithm, err := key.GetEncryptionKeyAlgorithm()
+	if err != nil {
+		t.Fatal("Expected no error while getting encryption key algorithm, got:", err)
+	}
+	assert.Exactly(t, "ecdh", algorithm)
+
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	encrypted, err := keyRing.Encrypt(NewPlainMessageFromString("to the encryption subkey"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	keyIDs, ok := encrypted.GetHexEncryptionKeyIDs()
+	assert.True(t, ok)
+	assert.Exactly(t, []string{encryptionKeyID}, keyIDs)
+
+	// Before the encryption subkey was created
+	_, err = key.GetEncryptionKey(1546300900)
+	assert.Error(t, err)
+}
+
+func TestGetEncryptionKeyWithoutFlags(t *testing.T) {
+	// The library uses a primary key without flags even if it can't encrypt
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	key.entity.Subkeys = nil
+	key.entity.PrimaryIdentity().SelfSignature.FlagsValid = false
+
+	_, err = key.GetEncryptionKey(GetUnixTime())
+	assert.Error(t, err)
+	assert.False(t, key.CanEncrypt())
+
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = keyRing.Encrypt(NewPlainMessageFromString("no encryption key"), nil)
+	var missingErr *MissingEncryptionKeyError
+	if !errors.As(err, &missingErr) {
+		t.Fatal("Expected a MissingEncryptionKeyError, got:", err)
+	}
+}
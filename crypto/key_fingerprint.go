@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatFingerprint formats the hex fingerprint fp like GnuPG displays it:
+//   - v4 fingerprints, of 20 bytes, as 10 uppercase groups of 4 characters,
+//     with a double space in the middle, e.g. "6E8B A229 ... DF21 DF24";
+//   - v5 fingerprints, of 32 bytes, truncated to their first 25 bytes, as 10
+//     uppercase groups of 5 characters, e.g. "19347 BC987 ... 3EA4C".
+//
+// Other inputs are returned in uppercase, without grouping.
+func FormatFingerprint(fp string) string {
+	fp = strings.ToUpper(strings.Join(strings.Fields(fp), ""))
+	var groupSize int
+	switch len(fp) {
+	case 40:
+		groupSize = 4
+	case 64, 50:
+		fp = fp[:50]
+		groupSize = 5
+	default:
+		return fp
+	}
+
+	var formatted strings.Builder
+	for i := 0; i < len(fp); i += groupSize {
+		switch {
+		case groupSize == 4 && i == 20:
+			formatted.WriteString("  ")
+		case i > 0:
+			formatted.WriteByte(' ')
+		}
+		formatted.WriteString(fp[i : i+groupSize])
+	}
+	return formatted.String()
+}
+
+// GetFormattedFingerprint returns the fingerprint of the key formatted like
+// GnuPG displays it, see FormatFingerprint.
+func (key *Key) GetFormattedFingerprint() string {
+	return FormatFingerprint(key.GetFingerprint())
+}
+
+// GetLongKeyID returns the 64-bit key ID of the key as 16 uppercase hex
+// characters, like GnuPG displays it.
+func (key *Key) GetLongKeyID() string {
+	return fmt.Sprintf("%016X", key.GetKeyID())
+}
+
+// GetShortKeyID returns the last 8 characters of the long key ID.
+// Short key IDs are easily forged and collide: they should only be displayed
+// along with the fingerprint, never used to identify a key.
+func (key *Key) GetShortKeyID() string {
+	return key.GetLongKeyID()[8:]
+}
@@ -0,0 +1,41 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormattedFingerprint(t *testing.T) {
+	// Compared with the output of gpg --fingerprint --keyid-format long
+	for _, test := range []struct {
+		file        string
+		fingerprint string
+		longKeyID   string
+	}{
+		{"keyring_publicKey", "6E8B A229 B0CC CAF6 962F  9795 3EB6 259E DF21 DF24", "3EB6259EDF21DF24"},
+		{"key_usageSubkeys", "6ED7 90A9 C921 0D8B 0AF0  33FA 9BB6 1674 94F7 795D", "9BB6167494F7795D"},
+		{"key_ecdsaP256", "A27A FED5 BBF0 8D06 AAED  2EAA 3530 93AE AC6E 72D6", "353093AEAC6E72D6"},
+	} {
+		key, err := NewKeyFromArmored(readTestFile(test.file, false))
+		if err != nil {
+			t.Fatal("Expected no error while reading key, got:", err)
+		}
+		assert.Exactly(t, test.fingerprint, key.GetFormattedFingerprint())
+		assert.Exactly(t, test.longKeyID, key.GetLongKeyID())
+		assert.Exactly(t, test.longKeyID[8:], key.GetShortKeyID())
+	}
+}
+
+func TestFormatFingerprint(t *testing.T) {
+	// v5 fingerprints are truncated, see GnuPG's format_hexfingerprint
+	assert.Exactly(t,
+		"19347 BC987 24640 25F99 DF3EC 2E000 0ED98 84892 E1F7B 3EA4C",
+		FormatFingerprint("19347bc9872464025f99df3ec2e0000ed9884892e1f7b3ea4c94009159569b54"),
+	)
+	assert.Exactly(t,
+		"6E8B A229 B0CC CAF6 962F  9795 3EB6 259E DF21 DF24",
+		FormatFingerprint("6E8B A229 B0CC CAF6 962F  9795 3EB6 259E DF21 DF24"),
+	)
+	assert.Exactly(t, "ABCDEF", FormatFingerprint("abcdef"))
+}
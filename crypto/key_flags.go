@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	keyServerPreferencesSubpacket = 23
+	keyFlagsSubpacket             = 27
+
+	keyFlagAuthenticate   = 0x20
+	keyServerPrefNoModify = 0x80
+)
+
+// KeyFlags contains the usages of a key or subkey,
+// as set in its newest valid self-signature.
+type KeyFlags struct {
+	KeyID                    string
+	CanCertify               bool
+	CanSign                  bool
+	CanEncryptCommunications bool
+	CanEncryptStorage        bool
+	CanAuthenticate          bool
+}
+
+// KeyServerPreferences contains the key server preferences of a key,
+// as set in its newest valid self-signature.
+type KeyServerPreferences struct {
+	// NoModify asks key servers to only accept updates from the key holder.
+	NoModify bool
+}
+
+// GetKeyFlags returns the flags of the primary key followed by the flags of
+// each subkey.
+func (key *Key) GetKeyFlags() []*KeyFlags {
+	flags := []*KeyFlags{newKeyFlags(key.entity.PrimaryKey, key.getNewestSelfSignature())}
+	for _, subkey := range key.entity.Subkeys {
+		flags = append(flags, newKeyFlags(subkey.PublicKey, subkey.Sig))
+	}
+	return flags
+}
+
+// GetKeyServerPreferences returns the key server preferences of the key.
+func (key *Key) GetKeyServerPreferences() *KeyServerPreferences {
+	preferences := &KeyServerPreferences{}
+	subpacket := getHashedSubpacket(key.getNewestSelfSignature(), keyServerPreferencesSubpacket)
+	if len(subpacket) > 0 {
+		preferences.NoModify = subpacket[0]&keyServerPrefNoModify != 0
+	}
+	return preferences
+}
+
+// getNewestSelfSignature returns the newest valid self-signature over the
+// user IDs of the key.
+func (key *Key) getNewestSelfSignature() (selfSignature *packet.Signature) {
+	for _, identity := range key.entity.Identities {
+		if identity.SelfSignature == nil {
+			continue
+		}
+		if selfSignature == nil || identity.SelfSignature.CreationTime.After(selfSignature.CreationTime) {
+			selfSignature = identity.SelfSignature
+		}
+	}
+	return selfSignature
+}
+
+func newKeyFlags(publicKey *packet.PublicKey, sig *packet.Signature) *KeyFlags {
+	flags := &KeyFlags{KeyID: keyIDToHex(publicKey.KeyId)}
+	if sig == nil || !sig.FlagsValid {
+		return flags
+	}
+	flags.CanCertify = sig.FlagCertify
+	flags.CanSign = sig.FlagSign
+	flags.CanEncryptCommunications = sig.FlagEncryptCommunications
+	flags.CanEncryptStorage = sig.FlagEncryptStorage
+	subpacket := getHashedSubpacket(sig, keyFlagsSubpacket)
+	if len(subpacket) > 0 {
+		flags.CanAuthenticate = subpacket[0]&keyFlagAuthenticate != 0
+	}
+	return flags
+}
+
+// getHashedSubpacket returns the content of the first hashed subpacket of the
+// given type, which the library does not expose, or nil if it is missing.
+func getHashedSubpacket(sig *packet.Signature, subpacketType byte) []byte {
+	if sig == nil || len(sig.HashSuffix) < 6 {
+		return nil
+	}
+	// The hash suffix starts with the version, type, algorithms,
+	// and the length of the hashed subpackets.
+	length := int(sig.HashSuffix[4])<<8 | int(sig.HashSuffix[5])
+	if len(sig.HashSuffix) < 6+length {
+		return nil
+	}
+	subpackets := sig.HashSuffix[6 : 6+length]
+	for len(subpackets) > 0 {
+		var subpacketLength int
+		switch {
+		case subpackets[0] < 192:
+			subpacketLength = int(subpackets[0])
+			subpackets = subpackets[1:]
+		case subpackets[0] < 255:
+			if len(subpackets) < 2 {
+				return nil
+			}
+			subpacketLength = int(subpackets[0]-192)<<8 + int(subpackets[1]) + 192
+			subpackets = subpackets[2:]
+		default:
+			if len(subpackets) < 5 {
+				return nil
+			}
+			var err error
+			if subpacketLength, err = readFourOctetLength(subpackets[1:], 0); err != nil {
+				return nil
+			}
+			subpackets = subpackets[5:]
+		}
+		if subpacketLength == 0 || subpacketLength > len(subpackets) {
+			return nil
+		}
+		if subpackets[0]&0x7f == subpacketType {
+			return subpackets[1:subpacketLength]
+		}
+		subpackets = subpackets[subpacketLength:]
+	}
+	return nil
+}
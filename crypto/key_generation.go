@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/rsa"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+// newPrimaryEntity returns an entity with a primary key of the algorithm of
+// config and a self-signed user ID, like openpgp.NewEntity, but without the
+// encryption subkey which openpgp.NewEntity generates with the algorithm of
+// the primary key. The caller adds the subkeys.
+func newPrimaryEntity(name, comment, email string, config *packet.Config) (*openpgp.Entity, error) {
+	uid := packet.NewUserId(name, comment, email)
+	if uid == nil {
+		return nil, errors.New("gopenpgp: user id field contained invalid characters")
+	}
+
+	var signer crypto.Signer
+	switch config.PublicKeyAlgorithm() {
+	case packet.PubKeyAlgoRSA:
+		bits := config.RSAModulusBits()
+		if bits < 1024 {
+			return nil, errors.New("gopenpgp: bits must be >= 1024")
+		}
+		rsaKey, err := rsa.GenerateKey(config.Random(), bits)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in generating primary key")
+		}
+		signer = rsaKey
+	case packet.PubKeyAlgoEdDSA:
+		_, edKey, err := ed25519.GenerateKey(config.Random())
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in generating primary key")
+		}
+		signer = &edKey
+	default:
+		return nil, errors.New("gopenpgp: unsupported primary key algorithm")
+	}
+
+	creationTime := config.Now()
+	keyLifetimeSecs := config.KeyLifetime()
+	primary := packet.NewSignerPrivateKey(creationTime, signer)
+
+	hashID, ok := s2k.HashToHashId(config.Hash())
+	if !ok {
+		return nil, errors.New("gopenpgp: unsupported hash function")
+	}
+	isPrimaryID := true
+	selfSignature := &packet.Signature{
+		Version:           primary.PublicKey.Version,
+		SigType:           packet.SigTypePositiveCert,
+		PubKeyAlgo:        primary.PublicKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      creationTime,
+		KeyLifetimeSecs:   &keyLifetimeSecs,
+		IssuerKeyId:       &primary.PublicKey.KeyId,
+		IssuerFingerprint: primary.PublicKey.Fingerprint,
+		IsPrimaryId:       &isPrimaryID,
+		FlagsValid:        true,
+		FlagSign:          true,
+		FlagCertify:       true,
+		MDC:               true,
+		// The same preferences as openpgp.NewEntity
+		PreferredHash:        []uint8{hashID},
+		PreferredSymmetric:   []uint8{uint8(config.Cipher())},
+		PreferredCompression: []uint8{uint8(packet.CompressionNone)},
+		PreferredAEAD:        []uint8{uint8(packet.AEADModeEAX)},
+	}
+	if config.Hash() != crypto.SHA256 {
+		sha256ID, _ := s2k.HashToHashId(crypto.SHA256)
+		selfSignature.PreferredHash = append(selfSignature.PreferredHash, sha256ID)
+	}
+	if config.Cipher() != packet.CipherAES128 {
+		selfSignature.PreferredSymmetric = append(selfSignature.PreferredSymmetric, uint8(packet.CipherAES128))
+	}
+	if config.Compression() != packet.CompressionNone {
+		selfSignature.PreferredCompression = append(selfSignature.PreferredCompression, uint8(config.Compression()))
+	}
+
+	if err := selfSignature.SignUserId(uid.Id, &primary.PublicKey, primary, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing user id")
+	}
+
+	return &openpgp.Entity{
+		PrimaryKey: &primary.PublicKey,
+		PrivateKey: primary,
+		Identities: map[string]*openpgp.Identity{
+			uid.Id: {
+				Name:          uid.Id,
+				UserId:        uid,
+				SelfSignature: selfSignature,
+				Signatures:    []*packet.Signature{selfSignature},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,157 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+)
+
+// KeyMismatchError describes how a key differs from another copy of the same
+// certificate, see Key.CheckIntegrityAgainst.
+type KeyMismatchError struct {
+	// Hex fingerprints of the primary keys, set if they differ.
+	PrimaryFingerprint, OtherPrimaryFingerprint string
+	// Hex fingerprints of the subkeys which are only in the key, or only in
+	// the other key.
+	SubkeysOnlyInKey, SubkeysOnlyInOther []string
+	// User IDs which are only in the key, or only in the other key.
+	UserIDsOnlyInKey, UserIDsOnlyInOther []string
+	// User IDs and hex fingerprints of the subkeys in both keys, whose
+	// self-signatures differ.
+	SignatureMismatches []string
+}
+
+func (err *KeyMismatchError) Error() string {
+	var details []string
+	if err.PrimaryFingerprint != "" {
+		details = append(details, "primary key "+err.PrimaryFingerprint+" instead of "+err.OtherPrimaryFingerprint)
+	}
+	describe := func(what string, items []string) {
+		if len(items) > 0 {
+			details = append(details, what+" "+strings.Join(items, ", "))
+		}
+	}
+	describe("subkeys only in the key:", err.SubkeysOnlyInKey)
+	describe("subkeys only in the other key:", err.SubkeysOnlyInOther)
+	describe("user IDs only in the key:", err.UserIDsOnlyInKey)
+	describe("user IDs only in the other key:", err.UserIDsOnlyInOther)
+	describe("different self-signatures of", err.SignatureMismatches)
+	return "gopenpgp: the keys don't match: " + strings.Join(details, "; ")
+}
+
+// isEmpty returns whether no difference was found.
+func (err *KeyMismatchError) isEmpty() bool {
+	return err.PrimaryFingerprint == "" &&
+		len(err.SubkeysOnlyInKey) == 0 && len(err.SubkeysOnlyInOther) == 0 &&
+		len(err.UserIDsOnlyInKey) == 0 && len(err.UserIDsOnlyInOther) == 0 &&
+		len(err.SignatureMismatches) == 0
+}
+
+// EqualsPublicOf returns whether the key and other have the same primary key
+// and the same subkeys, e.g. a private key and its public key. The user IDs
+// and signatures are not compared, see CheckIntegrityAgainst.
+func (key *Key) EqualsPublicOf(other *Key) bool {
+	if key == nil || other == nil || key.entity == nil || other.entity == nil {
+		return false
+	}
+	mismatch := &KeyMismatchError{}
+	compareKeyMaterial(key.entity, other.entity, mismatch)
+	return mismatch.isEmpty()
+}
+
+// CheckIntegrityAgainst checks that public is a copy of the key, e.g. the
+// public key of a private key restored from a backup: besides the primary key
+// and subkeys, the user IDs and the self-signatures of the user IDs and
+// subkeys must match, so that a public key whose self-signatures were swapped
+// or forged is detected. Returns a *KeyMismatchError describing the
+// differences if they don't match.
+func (key *Key) CheckIntegrityAgainst(public *Key) error {
+	if key == nil || public == nil || key.entity == nil || public.entity == nil {
+		return errors.New("gopenpgp: cannot compare empty keys")
+	}
+	mismatch := &KeyMismatchError{}
+	if compareKeyMaterial(key.entity, public.entity, mismatch) {
+		compareSelfSignatures(key.entity, public.entity, mismatch)
+	}
+	if mismatch.isEmpty() {
+		return nil
+	}
+	return mismatch
+}
+
+// compareKeyMaterial reports the differences between the primary keys and the
+// subkeys of the entities, and returns whether the primary keys match.
+func compareKeyMaterial(entity, other *openpgp.Entity, mismatch *KeyMismatchError) bool {
+	if !bytes.Equal(entity.PrimaryKey.Fingerprint, other.PrimaryKey.Fingerprint) {
+		mismatch.PrimaryFingerprint = hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+		mismatch.OtherPrimaryFingerprint = hex.EncodeToString(other.PrimaryKey.Fingerprint)
+		return false
+	}
+	subkeys, otherSubkeys := getSubkeysByFingerprint(entity), getSubkeysByFingerprint(other)
+	mismatch.SubkeysOnlyInKey = getMissingKeys(subkeys, otherSubkeys)
+	mismatch.SubkeysOnlyInOther = getMissingKeys(otherSubkeys, subkeys)
+	return true
+}
+
+// compareSelfSignatures reports the differences between the user IDs of the
+// entities, and between the self-signatures of the user IDs and subkeys which
+// both have. The hashed parts of the signatures are compared.
+func compareSelfSignatures(entity, other *openpgp.Entity, mismatch *KeyMismatchError) {
+	for name, identity := range entity.Identities {
+		otherIdentity, ok := other.Identities[name]
+		if !ok {
+			mismatch.UserIDsOnlyInKey = append(mismatch.UserIDsOnlyInKey, name)
+			continue
+		}
+		if !bytes.Equal(identity.SelfSignature.HashSuffix, otherIdentity.SelfSignature.HashSuffix) {
+			mismatch.SignatureMismatches = append(mismatch.SignatureMismatches, name)
+		}
+	}
+	for name := range other.Identities {
+		if _, ok := entity.Identities[name]; !ok {
+			mismatch.UserIDsOnlyInOther = append(mismatch.UserIDsOnlyInOther, name)
+		}
+	}
+
+	otherSubkeys := getSubkeysByFingerprint(other)
+	var subkeyMismatches []string
+	for fingerprint, subkey := range getSubkeysByFingerprint(entity) {
+		otherSubkey, ok := otherSubkeys[fingerprint]
+		if ok && !bytes.Equal(subkey.Sig.HashSuffix, otherSubkey.Sig.HashSuffix) {
+			subkeyMismatches = append(subkeyMismatches, fingerprint)
+		}
+	}
+
+	sort.Strings(mismatch.UserIDsOnlyInKey)
+	sort.Strings(mismatch.UserIDsOnlyInOther)
+	sort.Strings(mismatch.SignatureMismatches)
+	sort.Strings(subkeyMismatches)
+	mismatch.SignatureMismatches = append(mismatch.SignatureMismatches, subkeyMismatches...)
+}
+
+// getSubkeysByFingerprint maps the hex fingerprints of the subkeys of entity
+// to the subkeys.
+func getSubkeysByFingerprint(entity *openpgp.Entity) map[string]*openpgp.Subkey {
+	subkeys := make(map[string]*openpgp.Subkey, len(entity.Subkeys))
+	for i := range entity.Subkeys {
+		subkeys[hex.EncodeToString(entity.Subkeys[i].PublicKey.Fingerprint)] = &entity.Subkeys[i]
+	}
+	return subkeys
+}
+
+// getMissingKeys returns the sorted fingerprints of keys which are not in
+// others.
+func getMissingKeys(keys, others map[string]*openpgp.Subkey) []string {
+	var missing []string
+	for fingerprint := range keys {
+		if _, ok := others[fingerprint]; !ok {
+			missing = append(missing, fingerprint)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
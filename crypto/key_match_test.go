@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyEqualsPublicOf(t *testing.T) {
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	assert.True(t, keyTestRSA.EqualsPublicOf(publicKey))
+	assert.True(t, publicKey.EqualsPublicOf(keyTestRSA))
+	assert.False(t, keyTestRSA.EqualsPublicOf(keyTestEC))
+	assert.False(t, keyTestRSA.EqualsPublicOf(nil))
+	if err := keyTestRSA.CheckIntegrityAgainst(publicKey); err != nil {
+		t.Fatal("Expected no error while checking key integrity, got:", err)
+	}
+
+	var mismatch *KeyMismatchError
+	if !errors.As(keyTestRSA.CheckIntegrityAgainst(keyTestEC), &mismatch) {
+		t.Fatal("Expected a KeyMismatchError")
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), mismatch.OtherPrimaryFingerprint)
+
+	// Public key without its subkey
+	publicKey.entity.Subkeys = nil
+	assert.False(t, keyTestRSA.EqualsPublicOf(publicKey))
+	if !errors.As(keyTestRSA.CheckIntegrityAgainst(publicKey), &mismatch) {
+		t.Fatal("Expected a KeyMismatchError")
+	}
+	assert.Len(t, mismatch.SubkeysOnlyInKey, 1)
+	assert.Empty(t, mismatch.SubkeysOnlyInOther)
+	assert.Empty(t, mismatch.PrimaryFingerprint)
+}
+
+func TestKeyCheckIntegrityAgainstResignedKey(t *testing.T) {
+	resigned, err := keyTestRSA.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	entity := resigned.entity
+	for name, identity := range entity.Identities {
+		sig := &packet.Signature{
+			Version:      4,
+			SigType:      packet.SigTypePositiveCert,
+			PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+			Hash:         crypto.SHA256,
+			CreationTime: time.Unix(testTime+1, 0),
+			IssuerKeyId:  &entity.PrimaryKey.KeyId,
+			FlagsValid:   true,
+			FlagSign:     true,
+			FlagCertify:  true,
+		}
+		if err := sig.SignUserId(name, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+			t.Fatal("Expected no error while signing user ID, got:", err)
+		}
+		identity.SelfSignature = sig
+		identity.Signatures = []*packet.Signature{sig}
+	}
+	publicKey, err := resigned.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	// Same key material, different self-signatures
+	assert.True(t, keyTestRSA.EqualsPublicOf(publicKey))
+	var mismatch *KeyMismatchError
+	if !errors.As(keyTestRSA.CheckIntegrityAgainst(publicKey), &mismatch) {
+		t.Fatal("Expected a KeyMismatchError")
+	}
+	assert.Len(t, mismatch.SignatureMismatches, len(entity.Identities))
+	assert.Empty(t, mismatch.SubkeysOnlyInKey)
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+)
+
+const (
+	packetTagSecretKey = 5
+	packetTagPublicKey = 6
+)
+
+// KeyMetadata summarizes a key for display. CanEncrypt and CanVerify use the
+// same checks as encryption and signature verification, at the current time.
+type KeyMetadata struct {
+	Fingerprint string
+	KeyID       string
+	Algorithm   string
+	// CreationTime and ExpirationTime are unix timestamps,
+	// ExpirationTime is 0 if the key does not expire.
+	CreationTime   int64
+	ExpirationTime int64
+	IsRevoked      bool
+	CanEncrypt     bool
+	CanVerify      bool
+	IsPrivate      bool
+	UserIDs        []string
+	// Error is set, and the other fields are empty, if the key can't be parsed.
+	Error string
+}
+
+// GetMetadata returns the metadata of the key.
+func (key *Key) GetMetadata() *KeyMetadata {
+	primaryKey := key.entity.PrimaryKey
+	metadata := &KeyMetadata{
+		Fingerprint:  key.GetFingerprint(),
+		KeyID:        key.GetHexKeyID(),
+		Algorithm:    key.GetPrimaryKeyAlgorithm(),
+		CreationTime: primaryKey.CreationTime.Unix(),
+		IsRevoked:    len(key.entity.Revocations) > 0,
+		CanEncrypt:   key.CanEncrypt(),
+		CanVerify:    key.CanVerify(),
+		IsPrivate:    key.IsPrivate(),
+	}
+
+	if identity := key.entity.PrimaryIdentity(); identity != nil {
+		lifetime := identity.SelfSignature.KeyLifetimeSecs
+		if lifetime != nil && *lifetime != 0 {
+			metadata.ExpirationTime = metadata.CreationTime + int64(*lifetime)
+		}
+	}
+	for _, identity := range key.entity.Identities {
+		metadata.UserIDs = append(metadata.UserIDs, identity.Name)
+	}
+	return metadata
+}
+
+// GetKeyMetadataList returns the metadata of each key of the keyring.
+func (keyRing *KeyRing) GetKeyMetadataList() []*KeyMetadata {
+	var list []*KeyMetadata
+	for _, key := range keyRing.GetKeys() {
+		list = append(list, key.GetMetadata())
+	}
+	return list
+}
+
+// GetKeyMetadataListFromArmored returns the metadata of each key of an armored
+// key ring. Each key is parsed separately: a key which can't be parsed has an
+// entry with the Error field set, instead of being skipped.
+func GetKeyMetadataListFromArmored(armored string) ([]*KeyMetadata, error) {
+	data, err := armor.Unarmor(armored)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := splitKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*KeyMetadata
+	for _, binKey := range keys {
+		key, err := NewKey(binKey)
+		if err != nil {
+			list = append(list, &KeyMetadata{Error: err.Error()})
+			continue
+		}
+		list = append(list, key.GetMetadata())
+	}
+	return list, nil
+}
+
+// splitKeys splits a binary key ring at each primary key packet.
+func splitKeys(data []byte) (keys [][]byte, err error) {
+	start, offset := 0, 0
+	for offset < len(data) {
+		tag, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil, err
+		}
+		if length > len(data)-offset {
+			return nil, errors.New("gopenpgp: truncated key packet")
+		}
+		if (tag == packetTagPublicKey || tag == packetTagSecretKey) && offset > start {
+			keys = append(keys, data[start:offset])
+			start = offset
+		}
+		offset += length
+	}
+	if offset > start {
+		keys = append(keys, data[start:offset])
+	}
+	return keys, nil
+}
@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestGetKeyMetadataList(t *testing.T) {
+	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor expired key:", err)
+	}
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.AddKey(expiredKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	list := keyRing.GetKeyMetadataList()
+	assert.Len(t, list, 2)
+
+	assert.Exactly(t, keyTestEC.GetFingerprint(), list[0].Fingerprint)
+	assert.Exactly(t, keyTestEC.GetHexKeyID(), list[0].KeyID)
+	assert.Exactly(t, "eddsa", list[0].Algorithm)
+	assert.Exactly(t, []string{keyTestName + " <" + keyTestDomain + ">"}, list[0].UserIDs)
+	assert.True(t, list[0].CanEncrypt)
+	assert.True(t, list[0].CanVerify)
+	assert.True(t, list[0].IsPrivate)
+	assert.Exactly(t, int64(0), list[0].ExpirationTime)
+	assert.Empty(t, list[0].Error)
+
+	// The expired key is not usable, as for encryption
+	assert.False(t, list[1].CanEncrypt)
+	expiredKeyRing, _ := NewKeyRing(expiredKey)
+	_, err = expiredKeyRing.Encrypt(NewPlainMessageFromString("message"), nil)
+	assert.Error(t, err)
+}
+
+func TestGetKeyMetadataListFromArmored(t *testing.T) {
+	publicKey, err := keyTestRSA.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	// Public key packet with an unknown algorithm
+	unsupportedKey := []byte{0xc6, 0x06, 0x04, 0x5c, 0xd9, 0x6b, 0x63, 0x63}
+	armored, err := armor.ArmorWithType(
+		bytes.Join([][]byte{publicKey, unsupportedKey, publicKey}, nil),
+		constants.PublicKeyHeader,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+
+	list, err := GetKeyMetadataListFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading metadata, got:", err)
+	}
+	assert.Len(t, list, 3)
+	assert.Exactly(t, keyTestRSA.GetFingerprint(), list[0].Fingerprint)
+	assert.False(t, list[0].IsPrivate)
+	assert.NotEmpty(t, list[1].Error)
+	assert.Empty(t, list[1].Fingerprint)
+	assert.Exactly(t, keyTestRSA.GetFingerprint(), list[2].Fingerprint)
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// sigTypeCertificationRevocation is the type of user ID revocations, which the
+// library does not define.
+const sigTypeCertificationRevocation packet.SignatureType = 0x30
+
+// GetArmoredPublicKeyMinimal returns the armored public key, reduced to the
+// signatures needed for it to be valid, see GetPublicKeyMinimal.
+func (key *Key) GetArmoredPublicKeyMinimal() (string, error) {
+	serialized, err := key.GetPublicKeyMinimal()
+	if err != nil {
+		return "", err
+	}
+
+	return armor.ArmorWithType(serialized, constants.PublicKeyHeader)
+}
+
+// GetPublicKeyMinimal returns the unarmored public key, with only the newest
+// valid self-signature of each user ID and subkey, and the revocations issued
+// by the key itself. Third-party certifications and superseded
+// self-signatures are removed, e.g. before uploading the key to a key server.
+func (key *Key) GetPublicKeyMinimal() ([]byte, error) {
+	var outBuf bytes.Buffer
+	if err := key.serializeMinimal(&outBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing minimal public key")
+	}
+
+	return outBuf.Bytes(), nil
+}
+
+func (key *Key) serializeMinimal(w io.Writer) error {
+	entity := key.entity
+	if err := entity.PrimaryKey.Serialize(w); err != nil {
+		return err
+	}
+	for _, revocation := range entity.Revocations {
+		if err := revocation.Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(entity.Identities))
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		identity := entity.Identities[name]
+		if err := identity.UserId.Serialize(w); err != nil {
+			return err
+		}
+		if err := identity.SelfSignature.Serialize(w); err != nil {
+			return err
+		}
+		for _, sig := range identity.Signatures {
+			if sig.SigType != sigTypeCertificationRevocation || !sig.CheckKeyIdOrFingerprint(entity.PrimaryKey) {
+				continue
+			}
+			if entity.PrimaryKey.VerifyUserIdSignature(name, entity.PrimaryKey, sig) != nil {
+				continue
+			}
+			if err := sig.Serialize(w); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The library keeps the newest binding signature of each subkey,
+	// or its revocation.
+	for _, subkey := range entity.Subkeys {
+		if err := subkey.PublicKey.Serialize(w); err != nil {
+			return err
+		}
+		if err := subkey.Sig.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
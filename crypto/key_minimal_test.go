@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetArmoredPublicKeyMinimal(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_certified", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring key, got:", err)
+	}
+
+	full, err := key.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	minimal, err := key.GetPublicKeyMinimal()
+	if err != nil {
+		t.Fatal("Expected no error while serializing minimal key, got:", err)
+	}
+	assert.Less(t, len(minimal)*4, len(full))
+
+	// Newest self-signatures of both user IDs, revocation of the second one,
+	// newest binding of the subkey.
+	var sigTypes []packet.SignatureType
+	packets := packet.NewReader(bytes.NewReader(minimal))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			break
+		}
+		if sig, ok := p.(*packet.Signature); ok {
+			assert.Exactly(t, key.entity.PrimaryKey.KeyId, *sig.IssuerKeyId)
+			sigTypes = append(sigTypes, sig.SigType)
+		}
+	}
+	assert.Exactly(t, []packet.SignatureType{
+		packet.SigTypePositiveCert,
+		packet.SigTypePositiveCert,
+		sigTypeCertificationRevocation,
+		packet.SigTypeSubkeyBinding,
+	}, sigTypes)
+
+	armored, err := key.GetArmoredPublicKeyMinimal()
+	if err != nil {
+		t.Fatal("Expected no error while armoring minimal key, got:", err)
+	}
+	minimalKey, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing minimal key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), minimalKey.GetFingerprint())
+	assert.True(t, minimalKey.CanEncrypt())
+	assert.True(t, minimalKey.CanVerify())
+}
+
+func TestGetPublicKeyMinimalRevoked(t *testing.T) {
+	key, err := keyTestRSA.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	if err = key.entity.RevokeKey(packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+
+	minimal, err := key.GetPublicKeyMinimal()
+	if err != nil {
+		t.Fatal("Expected no error while serializing minimal key, got:", err)
+	}
+	minimalKey, err := NewKey(minimal)
+	if err != nil {
+		t.Fatal("Expected no error while parsing minimal key, got:", err)
+	}
+	assert.Len(t, minimalKey.entity.Revocations, 1)
+}
@@ -0,0 +1,218 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// errInvalidDecryptedSessionKey is the only error returned for invalid
+// decrypted session key data, whatever the check which failed.
+var errInvalidDecryptedSessionKey = errors.New("gopenpgp: invalid decrypted session key")
+
+// EncryptedKeyPacket is a public key encrypted session key packet, with its
+// raw encrypted values, to decrypt the session key outside of the library,
+// e.g. in an HSM, see NewSessionKeyFromDecryptedMPIs.
+type EncryptedKeyPacket struct {
+	// KeyID is the hex key ID of the recipient, all zeros if hidden
+	KeyID string
+	// Algorithm is the public key algorithm of the recipient key
+	Algorithm packet.PublicKeyAlgorithm
+	// EncryptedMPIs are the values of the encrypted session key, without
+	// their length prefix: m^e mod n for RSA, g^k mod p and m * y^k mod p for
+	// ElGamal, the ephemeral public point and the wrapped key for ECDH.
+	// Leading zero bytes are stripped, the RSA value may need to be padded to
+	// the size of the modulus. It is nil if the algorithm is unsupported.
+	EncryptedMPIs [][]byte
+}
+
+// GetKeyPackets returns the public key encrypted session key packets of the
+// key packets of the message, ignoring the password encrypted ones.
+func (msg *PGPSplitMessage) GetKeyPackets() ([]*EncryptedKeyPacket, error) {
+	data := msg.GetBinaryKeyPacket()
+	var keyPackets []*EncryptedKeyPacket
+	for offset := 0; offset < len(data); {
+		tag, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil, err
+		}
+		if length > len(data)-offset {
+			return nil, errors.New("gopenpgp: truncated key packet")
+		}
+		raw := data[offset : offset+length]
+		offset += length
+		if tag != packetTagEncryptedKey {
+			continue
+		}
+		keyPacket, err := parseEncryptedKeyPacket(getRawPacketBody(raw))
+		if err != nil {
+			return nil, err
+		}
+		keyPackets = append(keyPackets, keyPacket)
+	}
+	return keyPackets, nil
+}
+
+// NewSessionKeyFromDecryptedMPIs returns the session key from the decryption
+// of the EncryptedMPIs of an EncryptedKeyPacket with algorithm algo, checking
+// its OpenPGP encoding, see RFC 4880, section 5.1, and RFC 6637, section 8.
+// For RSA and ElGamal, decrypted is the raw decrypted value, i.e. the
+// EME-PKCS1-v1_5 encoded session key, with or without its leading zero.
+// For ECDH, decrypted is the output of the AES key unwrap of the wrapped key
+// with the KEK derived from the shared point, i.e. the session key with its
+// PKCS5 padding.
+// The checks of the padding, the cipher and the checksum don't branch on the
+// decrypted data, and all fail with the same error, so that the result
+// doesn't give an oracle on the padding to Bleichenbacher-style attacks.
+func NewSessionKeyFromDecryptedMPIs(algo packet.PublicKeyAlgorithm, decrypted []byte) (*SessionKey, error) {
+	var start, end, valid int
+	switch algo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoElGamal:
+		start, end, valid = parseEMEPKCS1v15(decrypted)
+	case packet.PubKeyAlgoECDH:
+		start, end, valid = parsePKCS5Padding(decrypted)
+	default:
+		return nil, errors.New("gopenpgp: unsupported public key algorithm")
+	}
+	cipher, keyLength, checksumValid := parseSessionKeyMaterial(decrypted, start, end)
+	valid &= checksumValid
+	valid &= isValidCipherKeyLength(cipher, keyLength)
+	if valid != 1 {
+		return nil, errInvalidDecryptedSessionKey
+	}
+	return &SessionKey{
+		Key:  clone(decrypted[start+1 : end-2]),
+		Algo: getAlgo(packet.CipherFunction(cipher)),
+	}, nil
+}
+
+// parseEncryptedKeyPacket parses the body of a version 3 public key encrypted
+// session key packet.
+func parseEncryptedKeyPacket(body []byte) (*EncryptedKeyPacket, error) {
+	if len(body) < 10 || body[0] != 3 {
+		return nil, errors.New("gopenpgp: unsupported key packet")
+	}
+	keyPacket := &EncryptedKeyPacket{
+		KeyID:     keyIDToHex(binary.BigEndian.Uint64(body[1:9])),
+		Algorithm: packet.PublicKeyAlgorithm(body[9]),
+	}
+	fields := body[10:]
+	var count int
+	switch keyPacket.Algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly:
+		count = 1
+	case packet.PubKeyAlgoElGamal, packet.PubKeyAlgoECDH:
+		count = 2
+	default:
+		return keyPacket, nil
+	}
+	for i := 0; i < count; i++ {
+		var value []byte
+		var ok bool
+		if keyPacket.Algorithm == packet.PubKeyAlgoECDH && i == 1 {
+			// The wrapped key has a one-byte length
+			value, fields, ok = readLengthPrefixed(fields)
+		} else {
+			value, fields, ok = readMPI(fields)
+		}
+		if !ok {
+			return nil, errors.New("gopenpgp: truncated key packet")
+		}
+		keyPacket.EncryptedMPIs = append(keyPacket.EncryptedMPIs, clone(value))
+	}
+	return keyPacket, nil
+}
+
+// readMPI returns the value of the MPI at the start of data, and the rest.
+func readMPI(data []byte) (value, rest []byte, ok bool) {
+	if len(data) < 2 {
+		return nil, nil, false
+	}
+	length := ((int(data[0])<<8 | int(data[1])) + 7) / 8
+	if len(data)-2 < length {
+		return nil, nil, false
+	}
+	return data[2 : 2+length], data[2+length:], true
+}
+
+func readLengthPrefixed(data []byte) (value, rest []byte, ok bool) {
+	if len(data) < 1 || len(data)-1 < int(data[0]) {
+		return nil, nil, false
+	}
+	return data[1 : 1+int(data[0])], data[1+int(data[0]):], true
+}
+
+// parseEMEPKCS1v15 returns the bounds of the message encoded in em, 0x00 ||
+// 0x02 || PS || 0x00 || M with at least 8 non-zero padding bytes in PS, where
+// the leading zero is optional, and 1 if the encoding is valid, 0 otherwise.
+// Like rsa.DecryptPKCS1v15SessionKey, it doesn't branch on the content of em.
+func parseEMEPKCS1v15(em []byte) (start, end, valid int) {
+	if len(em) < 11 {
+		return 0, 0, 0
+	}
+	// Skip the leading zero if there is one
+	offset := subtle.ConstantTimeByteEq(em[0], 0)
+	second := subtle.ConstantTimeSelect(offset, int(em[1]), int(em[0]))
+	valid = subtle.ConstantTimeEq(int32(second), 2)
+
+	// The separator is the first zero after the padding start
+	separator, found := 0, 0
+	for i := 1; i < len(em); i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(offset+1, i)
+		isSeparator := inPadding & subtle.ConstantTimeByteEq(em[i], 0) & ^found & 1
+		separator = subtle.ConstantTimeSelect(isSeparator, i, separator)
+		found |= isSeparator
+	}
+	valid &= found
+	valid &= subtle.ConstantTimeLessOrEq(offset+1+8, separator)
+	return separator + 1, len(em), valid
+}
+
+// parsePKCS5Padding returns the bounds of the message padded in data with
+// 1 to 8 bytes with the value of the padding length, see RFC 6637, section 8,
+// and 1 if the padding is valid, 0 otherwise, without branching on data.
+func parsePKCS5Padding(data []byte) (start, end, valid int) {
+	if len(data) < 8 || len(data)%8 != 0 {
+		return 0, 0, 0
+	}
+	padding := int(data[len(data)-1])
+	valid = subtle.ConstantTimeLessOrEq(1, padding) & subtle.ConstantTimeLessOrEq(padding, 8)
+	for i := 1; i <= 8; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, padding)
+		isPadding := subtle.ConstantTimeByteEq(data[len(data)-i], byte(padding))
+		valid &= ^inPadding | isPadding
+	}
+	end = subtle.ConstantTimeSelect(valid, len(data)-padding, len(data))
+	return 0, end, valid & 1
+}
+
+// parseSessionKeyMaterial reads the session key material data[start:end],
+// the cipher byte, the key and its two-byte checksum, and returns the cipher,
+// the length of the key and 1 if the checksum is valid, 0 otherwise, without
+// branching on data nor on the bounds.
+func parseSessionKeyMaterial(data []byte, start, end int) (cipher byte, keyLength int, valid int) {
+	keyLength = end - start - 3
+	var checksum, expected uint16
+	for i, b := range data {
+		isCipher := subtle.ConstantTimeEq(int32(i), int32(start))
+		inKey := subtle.ConstantTimeLessOrEq(start+1, i) & subtle.ConstantTimeLessOrEq(i+3, end)
+		isChecksumHigh := subtle.ConstantTimeEq(int32(i), int32(end-2))
+		isChecksumLow := subtle.ConstantTimeEq(int32(i), int32(end-1))
+		cipher |= b & byte(-isCipher)
+		checksum += uint16(b) & uint16(-inKey)
+		expected |= uint16(b)<<8&uint16(-isChecksumHigh) | uint16(b)&uint16(-isChecksumLow)
+	}
+	return cipher, keyLength, subtle.ConstantTimeEq(int32(checksum), int32(expected))
+}
+
+// isValidCipherKeyLength returns 1 if cipher is a supported cipher with keys
+// of keyLength bytes, 0 otherwise, without branching on them.
+func isValidCipherKeyLength(cipher byte, keyLength int) int {
+	valid := 0
+	for _, cf := range symKeyAlgos {
+		valid |= subtle.ConstantTimeByteEq(cipher, byte(cf)) & subtle.ConstantTimeEq(int32(keyLength), int32(cf.KeySize()))
+	}
+	return valid
+}
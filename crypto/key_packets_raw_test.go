@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+// rawDecryptTestKeyPacket decrypts the RSA key packet without unpadding, as
+// an HSM would.
+func rawDecryptTestKeyPacket(t *testing.T, keyPacket *EncryptedKeyPacket) []byte {
+	for _, entity := range keyRingTestPrivate.getEntities() {
+		for _, subkey := range entity.Subkeys {
+			if keyIDToHex(subkey.PrivateKey.KeyId) != keyPacket.KeyID {
+				continue
+			}
+			priv := subkey.PrivateKey.PrivateKey.(*rsa.PrivateKey)
+			c := new(big.Int).SetBytes(keyPacket.EncryptedMPIs[0])
+			em := new(big.Int).Exp(c, priv.D, priv.N).Bytes()
+			// Left-pad to the size of the modulus
+			return append(make([]byte, priv.Size()-len(em)), em...)
+		}
+	}
+	t.Fatal("Expected a private key for key packet", keyPacket.KeyID)
+	return nil
+}
+
+func TestNewSessionKeyFromDecryptedMPIs(t *testing.T) {
+	sessionKey, err := GenerateSessionKeyAlgo("aes128")
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	keyPackets, err := NewPGPSplitMessage(keyPacket, nil).GetKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while parsing key packets, got:", err)
+	}
+	assert.Len(t, keyPackets, 1)
+	assert.Exactly(t, packet.PubKeyAlgoRSA, keyPackets[0].Algorithm)
+	assert.Len(t, keyPackets[0].EncryptedMPIs, 1)
+
+	em := rawDecryptTestKeyPacket(t, keyPackets[0])
+	assert.Exactly(t, byte(0), em[0])
+	decrypted, err := NewSessionKeyFromDecryptedMPIs(keyPackets[0].Algorithm, em)
+	if err != nil {
+		t.Fatal("Expected no error while unpadding session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey, decrypted)
+
+	// The leading zero is optional
+	decrypted, err = NewSessionKeyFromDecryptedMPIs(keyPackets[0].Algorithm, em[1:])
+	if err != nil {
+		t.Fatal("Expected no error while unpadding session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey, decrypted)
+
+	// All the invalid encodings fail with the same error
+	separator := len(em) - 1 - 3 - len(sessionKey.Key)
+	for _, tamper := range []func(em []byte){
+		func(em []byte) { em[1] = 1 },
+		func(em []byte) { em[separator] = 1 },
+		func(em []byte) { em[5] = 0 },
+		func(em []byte) { em[separator+1] = byte(packet.CipherAES256) },
+		func(em []byte) { em[len(em)-1]++ },
+		func(em []byte) { em[separator+2]++ },
+	} {
+		tampered := clone(em)
+		tamper(tampered)
+		_, err = NewSessionKeyFromDecryptedMPIs(keyPackets[0].Algorithm, tampered)
+		assert.Exactly(t, errInvalidDecryptedSessionKey, err)
+	}
+}
+
+func TestNewSessionKeyFromDecryptedMPIsECDH(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	checksum := 0
+	for _, b := range key {
+		checksum += int(b)
+	}
+	material := append([]byte{byte(packet.CipherAES256)}, key...)
+	material = append(material, byte(checksum>>8), byte(checksum))
+	// 35 bytes, padded to 40
+	padded := append(material, 5, 5, 5, 5, 5)
+
+	decrypted, err := NewSessionKeyFromDecryptedMPIs(packet.PubKeyAlgoECDH, padded)
+	if err != nil {
+		t.Fatal("Expected no error while unpadding session key, got:", err)
+	}
+	assert.Exactly(t, &SessionKey{Key: key, Algo: "aes256"}, decrypted)
+
+	for _, tamper := range []func(padded []byte){
+		func(padded []byte) { padded[len(padded)-2] = 4 },
+		func(padded []byte) { padded[len(padded)-1] = 9 },
+		func(padded []byte) { padded[len(padded)-1] = 0 },
+		func(padded []byte) { padded[1]++ },
+	} {
+		tampered := clone(padded)
+		tamper(tampered)
+		_, err = NewSessionKeyFromDecryptedMPIs(packet.PubKeyAlgoECDH, tampered)
+		assert.Exactly(t, errInvalidDecryptedSessionKey, err)
+	}
+}
+
+func TestGetKeyPacketsECDH(t *testing.T) {
+	key, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	ecKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building key ring, got:", err)
+	}
+	if err := keyRing.AddKey(ecKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	keyPacket, err := keyRing.EncryptSessionKey(testSessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	password, err := EncryptSessionKeyWithPassword(testSessionKey, []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	keyPackets, err := NewPGPSplitMessage(append(keyPacket, password...), nil).GetKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while parsing key packets, got:", err)
+	}
+	assert.Len(t, keyPackets, 2)
+	assert.Exactly(t, packet.PubKeyAlgoECDH, keyPackets[1].Algorithm)
+	assert.Len(t, keyPackets[1].EncryptedMPIs, 2)
+	// The wrapped key of a 32-byte session key
+	assert.Len(t, keyPackets[1].EncryptedMPIs[1], 48)
+}
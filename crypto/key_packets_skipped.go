@@ -0,0 +1,128 @@
+package crypto
+
+import (
+	"bufio"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// SkippedKeyPacket describes an encrypted session key packet skipped while
+// looking for a session key to decrypt.
+type SkippedKeyPacket struct {
+	// KeyID is the hex key ID of the recipient, empty if the packet couldn't
+	// be parsed
+	KeyID string
+	// Algorithm is the name of the public key algorithm of the packet, empty
+	// if the packet couldn't be parsed
+	Algorithm string
+	// Reason is the reason why the packet was skipped
+	Reason string
+}
+
+// String returns a description of the skipped packet.
+func (s SkippedKeyPacket) String() string {
+	if s.KeyID == "" {
+		return "invalid key packet: " + s.Reason
+	}
+	return fmt.Sprintf("key packet for %s (%s): %s", s.KeyID, s.Algorithm, s.Reason)
+}
+
+// SessionKeyDecryptionError is returned when no encrypted session key packet
+// can be decrypted, and lists the skipped packets.
+// It matches the library error openpgp/errors.ErrKeyIncorrect with errors.Is.
+type SessionKeyDecryptionError struct {
+	Skipped []SkippedKeyPacket
+}
+
+// Error is the base method for all errors.
+func (e SessionKeyDecryptionError) Error() string {
+	skipped := make([]string, len(e.Skipped))
+	for i, s := range e.Skipped {
+		skipped[i] = s.String()
+	}
+	return "gopenpgp: unable to decrypt session key: no valid decryption key, skipped: " + strings.Join(skipped, "; ")
+}
+
+// Is matches openpgp/errors.ErrKeyIncorrect.
+func (e SessionKeyDecryptionError) Is(target error) bool {
+	return target == pgpErrors.ErrKeyIncorrect
+}
+
+// keyPacketSkipper records the encrypted session key packets skipped while
+// looking for a session key to decrypt.
+type keyPacketSkipper struct {
+	skipped []SkippedKeyPacket
+	// invalidKey is the first InvalidECDHEphemeralKeyError of the packets
+	invalidKey error
+	// unsupportedSKESK is the first UnsupportedSKESKVersionError of the
+	// packets
+	unsupportedSKESK error
+}
+
+// next returns the next packet of packets, which reads from buffered.
+// The encrypted session key packets which can't be parsed, e.g. with an
+// unsupported version, are recorded and skipped.
+func (s *keyPacketSkipper) next(packets *packetReader, buffered *bufio.Reader) (packet.Packet, error) {
+	for {
+		tag := peekPacketTag(buffered)
+		skeskVersion := peekSKESKVersion(buffered)
+		p, err := packets.Next()
+		if err == nil || goerrors.Is(err, io.EOF) ||
+			(tag != packetTagEncryptedKey && tag != packetTagSymmetricKeyEncrypted) {
+			return p, err
+		}
+		if err := packets.counter.add(true); err != nil {
+			return nil, err
+		}
+		if tag == packetTagSymmetricKeyEncrypted && skeskVersion != 4 && skeskVersion != 5 && s.unsupportedSKESK == nil {
+			s.unsupportedSKESK = UnsupportedSKESKVersionError{Version: skeskVersion}
+		}
+		s.skipped = append(s.skipped, SkippedKeyPacket{Reason: err.Error()})
+	}
+}
+
+// skip records that the encrypted session key packet ek couldn't be decrypted
+// because of reason.
+func (s *keyPacketSkipper) skip(ek *packet.EncryptedKey, reason string) {
+	s.skipped = append(s.skipped, SkippedKeyPacket{
+		KeyID:     keyIDToHex(ek.KeyId),
+		Algorithm: getAlgorithmName(ek.Algo),
+		Reason:    reason,
+	})
+}
+
+// skipInvalid records that the ECDH encrypted session key packet ek wasn't
+// decrypted because its ephemeral public point is invalid.
+func (s *keyPacketSkipper) skipInvalid(ek *packet.EncryptedKey, err error) {
+	if s.invalidKey == nil {
+		s.invalidKey = err
+	}
+	s.skip(ek, err.Error())
+}
+
+// err returns the error to report when no session key could be decrypted,
+// the invalid ECDH ephemeral key if there is one.
+func (s *keyPacketSkipper) err() error {
+	if s.invalidKey != nil {
+		return s.invalidKey
+	}
+	if len(s.skipped) == 0 {
+		return pgpErrors.ErrKeyIncorrect
+	}
+	return SessionKeyDecryptionError{Skipped: s.skipped}
+}
+
+// peekPacketTag returns the tag of the next packet read from r, or 0 if it
+// can't be read.
+func peekPacketTag(r *bufio.Reader) byte {
+	header, _ := r.Peek(1)
+	if len(header) != 1 || header[0]&0x80 == 0 {
+		return 0
+	}
+	return getPacketTag(header[0])
+}
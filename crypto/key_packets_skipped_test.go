@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// Encrypted session key packets which can't be parsed: an unsupported version,
+// and an RSA packet with a truncated MPI.
+var unparsableKeyPackets = []byte{
+	0xc1, 0x0d, 0x09, 1, 2, 3, 4, 5, 6, 7, 8, 0x01, 0x00, 0x01, 0xff,
+	0xc1, 0x0d, 0x03, 1, 2, 3, 4, 5, 6, 7, 8, 0x01, 0x08, 0x00, 0xff,
+}
+
+func TestDecryptElGamalRecipientFirst(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_elGamalRecipientFirst", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "addressed to an ElGamal key first", decrypted.GetString())
+
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKeyFromMessage(message)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	_, dataPacket := splitTestKeyPackets(t, message)
+	if _, err := sessionKey.Decrypt(dataPacket); err != nil {
+		t.Fatal("Expected no error while decrypting with the session key, got:", err)
+	}
+}
+
+func splitTestKeyPackets(t *testing.T, message *PGPMessage) (keyPackets, dataPacket []byte) {
+	keyPackets, dataPacket, err := splitKeyPackets(message.GetBinary())
+	if err != nil {
+		t.Fatal("Expected no error while splitting the key packets, got:", err)
+	}
+	return keyPackets, dataPacket
+}
+
+func TestDecryptSkippingUnparsableKeyPackets(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_elGamalRecipientFirst", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	keyPackets, dataPacket := splitTestKeyPackets(t, message)
+	withUnparsable := append(clone(unparsableKeyPackets), keyPackets...)
+
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(withUnparsable)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	if _, err := sessionKey.Decrypt(dataPacket); err != nil {
+		t.Fatal("Expected no error while decrypting with the session key, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(NewPGPMessage(append(withUnparsable, dataPacket...)), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "addressed to an ElGamal key first", decrypted.GetString())
+}
+
+func TestDecryptSkippedKeyPacketsError(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_elGamalRecipientFirst", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	keyPackets, dataPacket := splitTestKeyPackets(t, message)
+	// Only keep the ElGamal packet
+	_, elGamalLength, err := readPacketHeader(keyPackets)
+	if err != nil {
+		t.Fatal("Expected no error while reading packet header, got:", err)
+	}
+	withoutRecipient := append(clone(unparsableKeyPackets), keyPackets[:elGamalLength]...)
+
+	_, err = keyRingTestPrivate.DecryptSessionKey(withoutRecipient)
+	assertSkippedKeyPackets(t, err)
+
+	_, err = keyRingTestPrivate.Decrypt(NewPGPMessage(append(withoutRecipient, dataPacket...)), nil, 0)
+	assertSkippedKeyPackets(t, err)
+}
+
+func assertSkippedKeyPackets(t *testing.T, err error) {
+	var skippedErr SessionKeyDecryptionError
+	if !errors.As(err, &skippedErr) {
+		t.Fatal("Expected a SessionKeyDecryptionError, got:", err)
+	}
+	assert.True(t, errors.Is(err, pgpErrors.ErrKeyIncorrect))
+	assert.Len(t, skippedErr.Skipped, 3)
+	assert.Empty(t, skippedErr.Skipped[0].KeyID)
+	assert.Contains(t, skippedErr.Skipped[0].Reason, "unknown EncryptedKey version 9")
+	assert.Empty(t, skippedErr.Skipped[1].KeyID)
+	assert.Exactly(t, SkippedKeyPacket{
+		KeyID:     "c5ef84d129523ad9",
+		Algorithm: "elgamal",
+		Reason:    "no matching decryption key",
+	}, skippedErr.Skipped[2])
+	assert.Contains(t, err.Error(), "key packet for c5ef84d129523ad9 (elgamal): no matching decryption key")
+}
@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+)
+
+// KeyPreview describes a key parsed for display before it is imported, e.g.
+// in a confirmation dialog. It is not a Key and can't be used for encryption
+// nor signatures: the self-signatures of the key aren't verified, and the key
+// may be rejected by NewKey.
+type KeyPreview struct {
+	Fingerprint string
+	KeyID       string
+	Algorithm   string
+	// CreationTime is a unix timestamp
+	CreationTime int64
+	IsPrivate    bool
+	UserIDs      []string
+	// Errors lists the parts of the key which couldn't be parsed, the fields
+	// which could be parsed are still set.
+	Errors []string
+}
+
+// PreviewKey parses the first key of the armored or binary data just enough
+// to return its fingerprint, algorithm, creation time and user IDs, without
+// validating it, see KeyPreview. It only returns an error if the data doesn't
+// start with a key.
+func PreviewKey(data []byte) (*KeyPreview, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		var err error
+		if data, err = armor.Unarmor(string(data)); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to unarmor key")
+		}
+	}
+
+	tag, _, err := readPacketHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag != packetTagPublicKey && tag != packetTagSecretKey {
+		return nil, errors.New("gopenpgp: the data doesn't start with a key")
+	}
+	preview := &KeyPreview{IsPrivate: tag == packetTagSecretKey}
+	for offset := 0; offset < len(data); {
+		tag, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			preview.addError(err)
+			break
+		}
+		if length > len(data)-offset {
+			preview.addError(errors.New("gopenpgp: truncated key packet"))
+			break
+		}
+		raw := data[offset : offset+length]
+		if offset > 0 && (tag == packetTagPublicKey || tag == packetTagSecretKey) {
+			// The next key
+			break
+		}
+		offset += length
+
+		switch tag {
+		case packetTagPublicKey, packetTagSecretKey:
+			preview.parsePrimaryKey(tag, getRawPacketBody(raw))
+		case packetTagUserID:
+			preview.UserIDs = append(preview.UserIDs, string(getRawPacketBody(raw)))
+		}
+	}
+	return preview, nil
+}
+
+// parsePrimaryKey sets the fields of the primary key packet, whose public key
+// fields start its body.
+func (preview *KeyPreview) parsePrimaryKey(tag byte, body []byte) {
+	if len(body) < 6 {
+		preview.addError(errors.New("gopenpgp: truncated key packet"))
+		return
+	}
+	preview.CreationTime = int64(binary.BigEndian.Uint32(body[1:5]))
+	preview.Algorithm = getAlgorithmName(packet.PublicKeyAlgorithm(body[5]))
+
+	// Parse the body as a public key packet, ignoring the secret key fields
+	publicKeyPacket := append(serializePacketHeader(packetTagPublicKey, len(body)), body...)
+	p, err := packet.Read(bytes.NewReader(publicKeyPacket))
+	if err == nil {
+		publicKey, ok := p.(*packet.PublicKey)
+		if !ok {
+			err = errors.New("gopenpgp: invalid public key packet")
+		} else {
+			preview.Fingerprint = hex.EncodeToString(publicKey.Fingerprint)
+			preview.KeyID = keyIDToHex(publicKey.KeyId)
+			return
+		}
+	}
+	preview.addError(errors.Wrap(err, "gopenpgp: unable to parse key"))
+
+	// The version 4 fingerprint only depends on the public key packet, see
+	// RFC 4880, section 12.2
+	if body[0] == 4 && tag == packetTagPublicKey {
+		h := sha1.New() //nolint:gosec
+		_, _ = h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+		_, _ = h.Write(body)
+		fingerprint := h.Sum(nil)
+		preview.Fingerprint = hex.EncodeToString(fingerprint)
+		preview.KeyID = keyIDToHex(binary.BigEndian.Uint64(fingerprint[12:20]))
+	}
+}
+
+func (preview *KeyPreview) addError(err error) {
+	preview.Errors = append(preview.Errors, err.Error())
+}
+
+// serializePacketHeader returns the new format header of a packet with tag
+// and a body of length bytes.
+func serializePacketHeader(tag byte, length int) []byte {
+	header := []byte{0xc0 | tag}
+	switch {
+	case length < 192:
+		return append(header, byte(length))
+	case length < 8384:
+		length -= 192
+		return append(header, byte(length>>8)+192, byte(length))
+	}
+	return append(header, 0xff, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewKey(t *testing.T) {
+	for _, armored := range []string{keyTestArmoredRSA, keyTestArmoredEC, readTestFile("keyring_publicKey", false)} {
+		key, err := NewKeyFromArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring key, got:", err)
+		}
+		var userIDs []string
+		for name := range key.entity.Identities {
+			userIDs = append(userIDs, name)
+		}
+		binary, err := key.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+
+		for _, data := range [][]byte{[]byte(armored), binary} {
+			preview, err := PreviewKey(data)
+			if err != nil {
+				t.Fatal("Expected no error while previewing key, got:", err)
+			}
+			assert.Exactly(t, key.GetFingerprint(), preview.Fingerprint)
+			assert.Exactly(t, key.GetHexKeyID(), preview.KeyID)
+			assert.Exactly(t, key.GetPrimaryKeyAlgorithm(), preview.Algorithm)
+			assert.Exactly(t, key.entity.PrimaryKey.CreationTime.Unix(), preview.CreationTime)
+			assert.Exactly(t, key.IsPrivate(), preview.IsPrivate)
+			assert.ElementsMatch(t, userIDs, preview.UserIDs)
+			assert.Empty(t, preview.Errors)
+		}
+	}
+}
+
+func TestPreviewKeyCorrupt(t *testing.T) {
+	publicKey, err := keyTestRSA.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	preview, err := PreviewKey(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while previewing key, got:", err)
+	}
+
+	// Unknown algorithm: the fingerprint is still computed
+	unknownAlgorithm := clone(publicKey)
+	unknownAlgorithm[len(publicKey)-len(getRawPacketBody(publicKey))+5] = 99
+	corrupt, err := PreviewKey(unknownAlgorithm)
+	if err != nil {
+		t.Fatal("Expected no error while previewing corrupt key, got:", err)
+	}
+	assert.Exactly(t, "unknown", corrupt.Algorithm)
+	assert.Len(t, corrupt.Fingerprint, 40)
+	assert.NotEqual(t, preview.Fingerprint, corrupt.Fingerprint)
+	assert.Exactly(t, preview.CreationTime, corrupt.CreationTime)
+	assert.Exactly(t, preview.UserIDs, corrupt.UserIDs)
+	assert.NotEmpty(t, corrupt.Errors)
+
+	// Truncated key: the primary key and user IDs are still parsed
+	corrupt, err = PreviewKey(publicKey[:len(publicKey)-10])
+	if err != nil {
+		t.Fatal("Expected no error while previewing truncated key, got:", err)
+	}
+	assert.Exactly(t, preview.Fingerprint, corrupt.Fingerprint)
+	assert.Exactly(t, preview.UserIDs, corrupt.UserIDs)
+	assert.NotEmpty(t, corrupt.Errors)
+
+	_, err = PreviewKey(nil)
+	assert.Error(t, err)
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("hello"))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	_, err = PreviewKey(signature.GetBinary())
+	assert.Error(t, err)
+}
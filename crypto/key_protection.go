@@ -0,0 +1,404 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// S2K usage bytes of the secret key material, besides s2kUsageSHA1 and
+// s2kUsageChecksum.
+const (
+	s2kUsageNone = 0
+	// AEAD protection (RFC 4880bis), which isn't supported
+	s2kUsageAEAD = 253
+)
+
+// S2K specifier types (RFC 4880, section 3.7.1).
+const (
+	s2kTypeSimple   = 0
+	s2kTypeSalted   = 1
+	s2kTypeIterated = 3
+	// Argon2 (RFC 4880bis), which isn't supported
+	s2kTypeArgon2   = 4
+	s2kTypeGNUDummy = 101
+)
+
+// KeyProtectionScheme describes how the secret material of a key packet is
+// protected, see Key.GetProtectionScheme.
+type KeyProtectionScheme struct {
+	// KeyID is the hex key ID of the primary key or subkey.
+	KeyID string
+	// Cipher is the name of the cipher encrypting the secret material, empty
+	// if it isn't encrypted.
+	Cipher string
+	// S2KType is one of the constants.S2K_TYPE_* values.
+	S2KType string
+	// Hash is the name of the hash of the S2K, empty if there is none.
+	Hash string
+	// Count is the number of hashed bytes of the iterated S2K, 0 otherwise.
+	Count int
+	// IsDeprecated is true if the secret material is encrypted with a
+	// deprecated cipher (CAST5, 3DES, ...), a simple or salted S2K, or
+	// without a SHA-1 checksum. Such a key can be locked again with modern
+	// parameters with Key.UpgradeProtection.
+	IsDeprecated bool
+}
+
+// SetKeyProtection sets the configuration used by Key.Lock, and so by the
+// functions locking generated keys or changing passphrases, to encrypt the
+// secret key material. If config is nil, the default, AES-256 and the
+// iterated and salted S2K with SHA-256 are used.
+func SetKeyProtection(config *S2KConfig) error {
+	if config != nil {
+		if _, err := config.getKeyProtectionCipher(); err != nil {
+			return err
+		}
+		if _, _, err := config.getHashAndCount(); err != nil {
+			return err
+		}
+	}
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.keyProtection = config
+	return nil
+}
+
+func getKeyProtection() *S2KConfig {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+	return pgp.keyProtection
+}
+
+// LockWithS2KConfig locks a copy of the key like Lock, encrypting the secret
+// key material with the cipher, hash and count of config, or with the
+// configuration set by SetKeyProtection if config is nil.
+func (key *Key) LockWithS2KConfig(passphrase []byte, config *S2KConfig) (*Key, error) {
+	if config == nil {
+		return key.Lock(passphrase)
+	}
+	if passphrase == nil {
+		return nil, errors.New("gopenpgp: a passphrase is required to lock the key")
+	}
+	cf, err := config.getKeyProtectionCipher()
+	if err != nil {
+		return nil, err
+	}
+	hash, count, err := config.getHashAndCount()
+	if err != nil {
+		return nil, err
+	}
+	s2kConfig := &s2k.Config{S2KMode: s2kTypeIterated, Hash: hash, S2KCount: count}
+
+	unlocked, err := key.IsUnlocked()
+	if err != nil {
+		return nil, err
+	}
+	if !unlocked {
+		return nil, errors.New("gopenpgp: key is not unlocked")
+	}
+
+	lockedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	defer clearPrivateParams(lockedKey.getPrivateParams())
+
+	entity := lockedKey.entity
+	if entity.PrivateKey != nil && !entity.PrivateKey.Dummy() {
+		if entity.PrivateKey, err = encryptPrivateKey(entity.PrivateKey, passphrase, cf, s2kConfig); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in locking key")
+		}
+		entity.PrimaryKey = &entity.PrivateKey.PublicKey
+	}
+	for i, sub := range entity.Subkeys {
+		if sub.PrivateKey == nil || sub.PrivateKey.Dummy() {
+			continue
+		}
+		if entity.Subkeys[i].PrivateKey, err = encryptPrivateKey(sub.PrivateKey, passphrase, cf, s2kConfig); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in locking sub key")
+		}
+		entity.Subkeys[i].PublicKey = &entity.Subkeys[i].PrivateKey.PublicKey
+	}
+
+	locked, err := lockedKey.IsLocked()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.New("gopenpgp: unable to lock key")
+	}
+	return lockedKey, nil
+}
+
+// UpgradeProtection unlocks a copy of the locked key with passphrase, and
+// locks it again with the same passphrase and the configuration set by
+// SetKeyProtection, e.g. for a key protected with CAST5.
+func (key *Key) UpgradeProtection(passphrase []byte) (*Key, error) {
+	unlocked, err := key.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer unlocked.ClearPrivateParams()
+	return unlocked.Lock(passphrase)
+}
+
+// GetProtectionScheme returns the protection of the secret material of each
+// secret key packet of the key, primary key first.
+func (key *Key) GetProtectionScheme() ([]*KeyProtectionScheme, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: the key has no secret key packet")
+	}
+	var schemes []*KeyProtectionScheme
+	privateKeys := []*packet.PrivateKey{key.entity.PrivateKey}
+	for _, sub := range key.entity.Subkeys {
+		if sub.PrivateKey != nil {
+			privateKeys = append(privateKeys, sub.PrivateKey)
+		}
+	}
+	for _, pk := range privateKeys {
+		scheme, err := getProtectionScheme(pk)
+		if err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, scheme)
+	}
+	return schemes, nil
+}
+
+// getKeyProtectionCipher returns the cipher of the configuration to lock keys.
+func (s2kConfig *S2KConfig) getKeyProtectionCipher() (packet.CipherFunction, error) {
+	name := s2kConfig.Cipher
+	if name == "" {
+		name = constants.AES256
+	}
+	cf, ok := symKeyAlgos[name]
+	if !ok {
+		return 0, errors.New("gopenpgp: unsupported cipher function: " + name)
+	}
+	if isDeprecatedCipher(cf) {
+		return 0, errors.New("gopenpgp: deprecated cipher function for key protection: " + name)
+	}
+	return cf, nil
+}
+
+func isDeprecatedCipher(cf packet.CipherFunction) bool {
+	return cf != packet.CipherAES128 && cf != packet.CipherAES192 && cf != packet.CipherAES256
+}
+
+// getSecretKeyFields returns the fields of the serialized secret key packet
+// of pk following the public key fields, starting with the S2K usage byte.
+func getSecretKeyFields(pk *packet.PrivateKey) ([]byte, error) {
+	if pk.Version != 4 {
+		return nil, errors.New("gopenpgp: unsupported secret key packet version")
+	}
+	var secret, public bytes.Buffer
+	if err := pk.Serialize(&secret); err != nil {
+		return nil, err
+	}
+	if err := pk.PublicKey.Serialize(&public); err != nil {
+		return nil, err
+	}
+	secretBody, publicBody := getPacketBody(secret.Bytes()), getPacketBody(public.Bytes())
+	if len(secretBody) <= len(publicBody) {
+		return nil, errors.New("gopenpgp: invalid secret key packet")
+	}
+	return secretBody[len(publicBody):], nil
+}
+
+// getProtectionScheme parses the S2K fields of the secret key packet of pk.
+func getProtectionScheme(pk *packet.PrivateKey) (*KeyProtectionScheme, error) {
+	fields, err := getSecretKeyFields(pk)
+	if err != nil {
+		return nil, err
+	}
+	scheme := &KeyProtectionScheme{KeyID: keyIDToHex(pk.KeyId)}
+	switch fields[0] {
+	case s2kUsageNone:
+		scheme.S2KType = constants.S2K_TYPE_NONE
+		return scheme, nil
+	case s2kUsageAEAD:
+		return nil, errors.New("gopenpgp: AEAD protected secret keys are not supported")
+	case s2kUsageSHA1, s2kUsageChecksum:
+		if len(fields) < 4 {
+			return nil, errors.New("gopenpgp: invalid secret key packet S2K")
+		}
+		scheme.IsDeprecated = fields[0] == s2kUsageChecksum
+		scheme.Cipher = getCipherName(packet.CipherFunction(fields[1]))
+		scheme.IsDeprecated = scheme.IsDeprecated || isDeprecatedCipher(packet.CipherFunction(fields[1]))
+		if hash, ok := s2k.HashIdToHash(fields[3]); ok {
+			scheme.Hash = getS2KHashName(hash)
+		}
+		switch fields[2] {
+		case s2kTypeSimple:
+			scheme.S2KType = constants.S2K_TYPE_SIMPLE
+			scheme.IsDeprecated = true
+		case s2kTypeSalted:
+			scheme.S2KType = constants.S2K_TYPE_SALTED
+			scheme.IsDeprecated = true
+		case s2kTypeIterated:
+			if len(fields) < 13 {
+				return nil, errors.New("gopenpgp: invalid secret key packet S2K")
+			}
+			scheme.S2KType = constants.S2K_TYPE_ITERATED
+			c := fields[12]
+			scheme.Count = (16 + int(c&15)) << (uint32(c>>4) + 6)
+		case s2kTypeGNUDummy:
+			scheme = &KeyProtectionScheme{KeyID: scheme.KeyID, S2KType: constants.S2K_TYPE_GNU_DUMMY}
+		case s2kTypeArgon2:
+			return nil, errors.New("gopenpgp: the Argon2 S2K is not supported")
+		default:
+			return nil, errors.Errorf("gopenpgp: unknown S2K type %d", fields[2])
+		}
+	default:
+		// The usage byte is the cipher, with the simple S2K with MD5
+		scheme.S2KType = constants.S2K_TYPE_LEGACY
+		scheme.Cipher = getCipherName(packet.CipherFunction(fields[0]))
+		scheme.Hash = constants.MD5
+		scheme.IsDeprecated = true
+	}
+	return scheme, nil
+}
+
+// getS2KHashName returns the name of an S2K hash.
+func getS2KHashName(hash crypto.Hash) string {
+	for name, h := range s2kHashes {
+		if h == hash {
+			return name
+		}
+	}
+	switch hash {
+	case crypto.MD5:
+		return constants.MD5
+	case crypto.SHA1:
+		return constants.SHA1
+	case crypto.RIPEMD160:
+		return constants.RIPEMD160
+	}
+	return hash.String()
+}
+
+// getUnencryptedMaterial returns the unencrypted secret material, without its
+// checksum. The library keeps the SHA-1 checksum of a key which was encrypted
+// with one once it is decrypted, instead of the 2-byte checksum of RFC 4880,
+// section 5.5.3.
+func getUnencryptedMaterial(data []byte) ([]byte, error) {
+	if len(data) > sha1.Size {
+		material := data[:len(data)-sha1.Size]
+		checksum := sha1.Sum(material) //nolint:gosec
+		if bytes.Equal(checksum[:], data[len(material):]) {
+			return material, nil
+		}
+	}
+	if len(data) > 2 {
+		material := data[:len(data)-2]
+		var checksum uint16
+		for _, b := range material {
+			checksum += uint16(b)
+		}
+		if data[len(material)] == byte(checksum>>8) && data[len(material)+1] == byte(checksum) {
+			return material, nil
+		}
+	}
+	return nil, errors.New("gopenpgp: invalid secret key material checksum")
+}
+
+// encryptPrivateKey returns the secret key packet of the unlocked pk, with its
+// secret material encrypted with cf in CFB mode and the iterated and salted
+// S2K, and a SHA-1 checksum, as GnuPG does. The other S2K types, e.g. Argon2,
+// are rejected, since s2k.Generate would silently write an iterated S2K.
+// The AEAD protection of RFC 4880bis isn't written either.
+func encryptPrivateKey(
+	pk *packet.PrivateKey, passphrase []byte, cf packet.CipherFunction, s2kConfig *s2k.Config,
+) (*packet.PrivateKey, error) {
+	switch s2kConfig.S2KMode {
+	case s2kTypeIterated:
+	case s2kTypeArgon2:
+		return nil, errors.New("gopenpgp: the Argon2 S2K is not supported for key protection")
+	default:
+		return nil, errors.Errorf("gopenpgp: unsupported S2K type %d for key protection", s2kConfig.S2KMode)
+	}
+	fields, err := getSecretKeyFields(pk)
+	if err != nil {
+		return nil, err
+	}
+	defer clearMem(fields)
+	if fields[0] != s2kUsageNone {
+		return nil, errors.New("gopenpgp: the key packet is not unlocked")
+	}
+	material, err := getUnencryptedMaterial(fields[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	random := getRandom()
+	if random == nil {
+		random = rand.Reader
+	}
+	params, err := s2k.Generate(random, s2kConfig)
+	if err != nil {
+		return nil, err
+	}
+	deriveKey, err := params.Function()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, cf.KeySize())
+	defer clearMem(key)
+	deriveKey(key, passphrase)
+	block, err := newBlockCipher(cf, key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, block.BlockSize())
+	if _, err := io.ReadFull(random, iv); err != nil {
+		return nil, err
+	}
+
+	checksum := sha1.Sum(material) //nolint:gosec
+	encrypted := make([]byte, len(material)+len(checksum))
+	stream := cipher.NewCFBEncrypter(block, iv)
+	stream.XORKeyStream(encrypted, material)
+	stream.XORKeyStream(encrypted[len(material):], checksum[:])
+
+	var body bytes.Buffer
+	if err := pk.PublicKey.Serialize(&body); err != nil {
+		return nil, err
+	}
+	publicBody := getPacketBody(body.Bytes())
+	body.Reset()
+	body.Write(publicBody)
+	body.Write([]byte{s2kUsageSHA1, byte(cf)})
+	if err := params.Serialize(&body); err != nil {
+		return nil, err
+	}
+	body.Write(iv)
+	body.Write(encrypted)
+
+	tag := byte(packetTagSecretKey)
+	if pk.IsSubkey {
+		tag = packetTagSecretSubkey
+	}
+	length := body.Len()
+	serialized := append(
+		[]byte{0xc0 | tag, 0xff, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)},
+		body.Bytes()...,
+	)
+	p, err := packet.Read(bytes.NewReader(serialized))
+	if err != nil {
+		return nil, err
+	}
+	locked, ok := p.(*packet.PrivateKey)
+	if !ok || !locked.Encrypted {
+		return nil, errors.New("gopenpgp: unable to encrypt secret key packet")
+	}
+	return locked, nil
+}
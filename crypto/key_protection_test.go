@@ -0,0 +1,230 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	mathrand "math/rand"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// Passphrase of the key_protection* fixtures
+const protectionFixturePassphrase = "gopenpgp fixture"
+
+func assertProtectionScheme(t *testing.T, key *Key, expected KeyProtectionScheme) {
+	schemes, err := key.GetProtectionScheme()
+	if err != nil {
+		t.Fatal("Expected no error while getting the protection scheme, got:", err)
+	}
+	assert.Len(t, schemes, 2)
+	for _, scheme := range schemes {
+		expected.KeyID = scheme.KeyID
+		assert.Exactly(t, expected, *scheme)
+	}
+}
+
+func TestKeyLockWithS2KConfig(t *testing.T) {
+	assertProtectionScheme(t, keyTestEC, KeyProtectionScheme{S2KType: constants.S2K_TYPE_NONE})
+
+	locked, err := keyTestEC.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assertProtectionScheme(t, locked, KeyProtectionScheme{
+		Cipher:  constants.AES256,
+		S2KType: constants.S2K_TYPE_ITERATED,
+		Hash:    constants.SHA256,
+		Count:   65536,
+	})
+
+	locked, err = keyTestEC.LockWithS2KConfig(keyTestPassphrase, &S2KConfig{
+		Cipher: constants.AES128,
+		Hash:   constants.SHA512,
+		Count:  1 << 20,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assertProtectionScheme(t, locked, KeyProtectionScheme{
+		Cipher:  constants.AES128,
+		S2KType: constants.S2K_TYPE_ITERATED,
+		Hash:    constants.SHA512,
+		Count:   1 << 20,
+	})
+	testLockUnlockKey(t, mustArmor(t, locked), keyTestPassphrase)
+
+	_, err = keyTestEC.LockWithS2KConfig(keyTestPassphrase, &S2KConfig{Cipher: constants.CAST5})
+	assert.Error(t, err)
+	_, err = keyTestEC.LockWithS2KConfig(keyTestPassphrase, &S2KConfig{Cipher: constants.TripleDES})
+	assert.Error(t, err)
+}
+
+func TestSetKeyProtection(t *testing.T) {
+	assert.Error(t, SetKeyProtection(&S2KConfig{Cipher: constants.CAST5}))
+	assert.Error(t, SetKeyProtection(&S2KConfig{Count: 1}))
+
+	if err := SetKeyProtection(&S2KConfig{Cipher: constants.AES192}); err != nil {
+		t.Fatal("Expected no error while setting the key protection, got:", err)
+	}
+	defer func() { _ = SetKeyProtection(nil) }()
+
+	locked, err := keyTestRSA.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assertProtectionScheme(t, locked, KeyProtectionScheme{
+		Cipher:  constants.AES192,
+		S2KType: constants.S2K_TYPE_ITERATED,
+		Hash:    constants.SHA256,
+		Count:   S2KDefaultCount,
+	})
+}
+
+func TestUpgradeDeprecatedKeyProtection(t *testing.T) {
+	// Lock a copy of the key with CAST5, which the API refuses
+	deprecated, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	config := &s2k.Config{S2KMode: s2kTypeIterated, Hash: crypto.SHA256, S2KCount: 65536}
+	entity := deprecated.entity
+	if entity.PrivateKey, err = encryptPrivateKey(entity.PrivateKey, keyTestPassphrase, packet.CipherCAST5, config); err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	entity.PrimaryKey = &entity.PrivateKey.PublicKey
+	sub := entity.Subkeys[0].PrivateKey
+	if entity.Subkeys[0].PrivateKey, err = encryptPrivateKey(sub, keyTestPassphrase, packet.CipherCAST5, config); err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	entity.Subkeys[0].PublicKey = &entity.Subkeys[0].PrivateKey.PublicKey
+
+	assertProtectionScheme(t, deprecated, KeyProtectionScheme{
+		Cipher:       constants.CAST5,
+		S2KType:      constants.S2K_TYPE_ITERATED,
+		Hash:         constants.SHA256,
+		Count:        65536,
+		IsDeprecated: true,
+	})
+
+	_, err = deprecated.UpgradeProtection([]byte("wrong passphrase"))
+	assert.Error(t, err)
+
+	upgraded, err := deprecated.UpgradeProtection(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while upgrading key protection, got:", err)
+	}
+	assertProtectionScheme(t, upgraded, KeyProtectionScheme{
+		Cipher:  constants.AES256,
+		S2KType: constants.S2K_TYPE_ITERATED,
+		Hash:    constants.SHA256,
+		Count:   65536,
+	})
+	testLockUnlockKey(t, mustArmor(t, upgraded), keyTestPassphrase)
+}
+
+// TestEncryptPrivateKeyGnuPG checks the secret key packets written by
+// encryptPrivateKey against the ones of GnuPG, which protects the exported
+// keys with AES-128 and the iterated and salted S2K with SHA-1.
+func TestEncryptPrivateKeyGnuPG(t *testing.T) {
+	locked, err := NewKeyFromArmored(readTestFile("key_protectionGnuPG", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	unlocked, err := locked.Unlock([]byte(protectionFixturePassphrase))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	config := &s2k.Config{S2KMode: s2kTypeIterated, Hash: crypto.SHA1, S2KCount: 65011712}
+
+	lockedKeys := []*packet.PrivateKey{locked.entity.PrivateKey, locked.entity.Subkeys[0].PrivateKey}
+	unlockedKeys := []*packet.PrivateKey{unlocked.entity.PrivateKey, unlocked.entity.Subkeys[0].PrivateKey}
+	for i, lockedKey := range lockedKeys {
+		expected, err := getSecretKeyFields(lockedKey)
+		if err != nil {
+			t.Fatal("Expected no error while reading secret key fields, got:", err)
+		}
+		// Usage, cipher, S2K type, hash, salt, count and IV
+		assert.Exactly(t, []byte{s2kUsageSHA1, byte(packet.CipherAES128), s2kTypeIterated, 2}, expected[:4])
+		assert.Exactly(t, byte(255), expected[12])
+		random := append(append([]byte{}, expected[4:12]...), expected[13:29]...)
+
+		setRandomSource(bytes.NewReader(random))
+		encrypted, err := encryptPrivateKey(
+			unlockedKeys[i], []byte(protectionFixturePassphrase), packet.CipherAES128, config,
+		)
+		setRandomSource(nil)
+		if err != nil {
+			t.Fatal("Expected no error while locking key, got:", err)
+		}
+		fields, err := getSecretKeyFields(encrypted)
+		if err != nil {
+			t.Fatal("Expected no error while reading secret key fields, got:", err)
+		}
+		assert.Exactly(t, expected, fields)
+	}
+}
+
+// TestLockWithS2KConfigFixtures checks the keys locked with each cipher and
+// hash against fixtures which were locked with the same random source, and
+// checked with GnuPG: it imports them, rejects a wrong passphrase, and signs
+// and decrypts with them.
+func TestLockWithS2KConfigFixtures(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_protectionNone", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	for _, test := range []struct {
+		fixture string
+		config  *S2KConfig
+	}{
+		{"key_protectionAES128SHA256", &S2KConfig{Cipher: constants.AES128, Hash: constants.SHA256, Count: 65536}},
+		{"key_protectionAES192SHA384", &S2KConfig{Cipher: constants.AES192, Hash: constants.SHA384, Count: 65536}},
+		{"key_protectionAES256SHA512", &S2KConfig{Cipher: constants.AES256, Hash: constants.SHA512, Count: 65536}},
+	} {
+		setRandomSource(mathrand.New(mathrand.NewSource(42))) //nolint:gosec
+		locked, err := key.LockWithS2KConfig([]byte(protectionFixturePassphrase), test.config)
+		setRandomSource(nil)
+		if err != nil {
+			t.Fatal("Expected no error while locking key, got:", err)
+		}
+		assertProtectionScheme(t, locked, KeyProtectionScheme{
+			Cipher:  test.config.Cipher,
+			S2KType: constants.S2K_TYPE_ITERATED,
+			Hash:    test.config.Hash,
+			Count:   test.config.Count,
+		})
+
+		expected, err := NewKeyFromArmored(readTestFile(test.fixture, false))
+		if err != nil {
+			t.Fatal("Expected no error while reading key, got:", err)
+		}
+		expectedBinary, err := expected.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		lockedBinary, err := locked.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		assert.Exactly(t, expectedBinary, lockedBinary, test.fixture)
+	}
+
+	// The S2K types which s2k.Generate doesn't write are rejected
+	for _, mode := range []uint8{s2kTypeSimple, s2kTypeSalted, s2kTypeArgon2} {
+		config := &s2k.Config{S2KMode: mode, Hash: crypto.SHA256}
+		_, err = encryptPrivateKey(key.entity.PrivateKey, []byte(protectionFixturePassphrase), packet.CipherAES256, config)
+		assert.Error(t, err)
+	}
+}
+
+func mustArmor(t *testing.T, key *Key) string {
+	armored, err := key.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	return armored
+}
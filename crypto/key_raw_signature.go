@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// RawSignature is a signature made directly with the primary key material,
+// see Key.SignRaw. It is NOT an OpenPGP signature.
+type RawSignature struct {
+	// Algorithm is one of the constants.RAW_SIGNATURE_* values.
+	Algorithm string
+	Signature []byte
+}
+
+// SignRaw signs data with the secret material of the primary key, without any
+// OpenPGP framing, for protocols which reuse the key of the user, e.g.
+// device attestation. The result is NOT an OpenPGP signature: it can't be
+// verified as one, and an OpenPGP signature can't be verified with VerifyRaw.
+// The algorithm depends on the type of the key, and the signature encoding is:
+//   - Ed25519 (EdDSA keys): the raw 64-byte signature of pure Ed25519 over
+//     data, not the prehashed Ed25519ph variant;
+//   - RSA: RSASSA-PSS with SHA-256 and a salt of 32 bytes, of the size of the
+//     modulus;
+//   - ECDSA: the ASN.1 DER encoding of r and s, over the SHA-256 digest of data.
+//
+// The key must be unlocked, and the secret material of its primary key must
+// not be a stub.
+func (key *Key) SignRaw(data []byte) (*RawSignature, error) {
+	pk := key.entity.PrivateKey
+	if pk == nil {
+		return nil, errors.New("gopenpgp: cannot sign with a public key")
+	}
+	if pk.Dummy() {
+		return nil, errors.New("gopenpgp: cannot sign, the secret material of the primary key is missing (stub key)")
+	}
+	if pk.Encrypted {
+		return nil, errors.New("gopenpgp: cannot sign with a locked key")
+	}
+
+	random := getRandom()
+	if random == nil {
+		random = rand.Reader
+	}
+	switch priv := pk.PrivateKey.(type) {
+	case *ed25519.PrivateKey:
+		return &RawSignature{
+			Algorithm: constants.RAW_SIGNATURE_ED25519,
+			Signature: ed25519.Sign(*priv, data),
+		}, nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		signature, err := rsa.SignPSS(random, priv, crypto.SHA256, digest[:], &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in signing")
+		}
+		return &RawSignature{Algorithm: constants.RAW_SIGNATURE_RSA_PSS_SHA256, Signature: signature}, nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		signature, err := ecdsa.SignASN1(random, priv, digest[:])
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in signing")
+		}
+		return &RawSignature{Algorithm: constants.RAW_SIGNATURE_ECDSA_SHA256, Signature: signature}, nil
+	}
+	return nil, errors.New("gopenpgp: raw signatures are not supported for key algorithm " + key.GetPrimaryKeyAlgorithm())
+}
+
+// VerifyRaw verifies a raw signature of data, made by SignRaw with the
+// primary key, whose encoding depends on the type of the key, see SignRaw.
+// OpenPGP signatures can't be verified with this function.
+func (key *Key) VerifyRaw(data, signature []byte) error {
+	var valid bool
+	switch pub := key.entity.PrimaryKey.PublicKey.(type) {
+	case *ed25519.PublicKey:
+		valid = ed25519.Verify(*pub, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = rsa.VerifyPSS(pub, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		}) == nil
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = ecdsa.VerifyASN1(pub, digest[:], signature)
+	default:
+		return errors.New("gopenpgp: raw signatures are not supported for key algorithm " + key.GetPrimaryKeyAlgorithm())
+	}
+	if !valid {
+		return errors.New("gopenpgp: invalid raw signature")
+	}
+	return nil
+}
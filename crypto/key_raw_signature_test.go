@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+var rawSignatureData = []byte("raw signature data")
+
+func TestRawSignature(t *testing.T) {
+	ecdsaKey, err := NewKeyFromArmored(readTestFile("key_ecdsaP256", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+
+	for _, test := range []struct {
+		key       *Key
+		algorithm string
+		size      int
+	}{
+		{keyTestEC, constants.RAW_SIGNATURE_ED25519, 64},
+		{keyTestRSA, constants.RAW_SIGNATURE_RSA_PSS_SHA256, 128},
+		{ecdsaKey, constants.RAW_SIGNATURE_ECDSA_SHA256, 0},
+	} {
+		signature, err := test.key.SignRaw(rawSignatureData)
+		if err != nil {
+			t.Fatal("Expected no error while signing raw data, got:", err)
+		}
+		assert.Exactly(t, test.algorithm, signature.Algorithm)
+		if test.size > 0 {
+			assert.Len(t, signature.Signature, test.size)
+		} else {
+			// DER SEQUENCE
+			assert.Exactly(t, byte(0x30), signature.Signature[0])
+		}
+
+		publicKey, err := test.key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		assert.NoError(t, publicKey.VerifyRaw(rawSignatureData, signature.Signature))
+		assert.Error(t, publicKey.VerifyRaw([]byte("other data"), signature.Signature))
+	}
+}
+
+func TestRawSignatureNotOpenPGP(t *testing.T) {
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessage(rawSignatureData))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.Error(t, keyRingTestPrivate.GetKeys()[0].VerifyRaw(rawSignatureData, signature.GetBinary()))
+}
+
+func TestRawSignatureLockedKey(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(keyTestArmoredEC)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	_, err = lockedKey.SignRaw(rawSignatureData)
+	assert.EqualError(t, err, "gopenpgp: cannot sign with a locked key")
+
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = publicKey.SignRaw(rawSignatureData)
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// GetSecretKeyPresence returns the presence of the secret material of the key
+// and its subkeys, indexed by their hex fingerprints, with the values
+// constants.SECRET_KEY_ABSENT, SECRET_KEY_STUB and SECRET_KEY_PRESENT.
+// Keys exported with gpg --export-secret-subkeys have a stub primary key.
+func (key *Key) GetSecretKeyPresence() map[string]int {
+	presence := make(map[string]int, len(key.entity.Subkeys)+1)
+	presence[hex.EncodeToString(key.entity.PrimaryKey.Fingerprint)] = getSecretKeyPresence(key.entity.PrivateKey)
+	for _, sub := range key.entity.Subkeys {
+		presence[hex.EncodeToString(sub.PublicKey.Fingerprint)] = getSecretKeyPresence(sub.PrivateKey)
+	}
+	return presence
+}
+
+// HasAnySecretMaterial returns true if the key or one of its subkeys has
+// secret material which isn't a stub.
+func (key *Key) HasAnySecretMaterial() bool {
+	for _, presence := range key.GetSecretKeyPresence() {
+		if presence == constants.SECRET_KEY_PRESENT {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCompleteSecretMaterial returns true if the key and all its subkeys have
+// secret material which isn't a stub.
+func (key *Key) HasCompleteSecretMaterial() bool {
+	for _, presence := range key.GetSecretKeyPresence() {
+		if presence != constants.SECRET_KEY_PRESENT {
+			return false
+		}
+	}
+	return true
+}
+
+func getSecretKeyPresence(pk *packet.PrivateKey) int {
+	switch {
+	case pk == nil:
+		return constants.SECRET_KEY_ABSENT
+	case pk.Dummy():
+		return constants.SECRET_KEY_STUB
+	default:
+		return constants.SECRET_KEY_PRESENT
+	}
+}
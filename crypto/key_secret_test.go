@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+const stubKeyPassphrase = "stubpass"
+
+func TestSecretKeyPresenceStubPrimary(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_stubPrimary", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+
+	assert.Exactly(t, map[string]int{
+		"1e1014f6ff4523db96ce87ed913337d8b0c10aa9": constants.SECRET_KEY_STUB,
+		"fcb271c2b0ef89ffbd90cd08acce32a3b0bae573": constants.SECRET_KEY_PRESENT,
+		"f5d26a28c0ed7bd194b5109550491a040fde298f": constants.SECRET_KEY_PRESENT,
+	}, key.GetSecretKeyPresence())
+	assert.True(t, key.IsPrivate())
+	assert.True(t, key.HasAnySecretMaterial())
+	assert.False(t, key.HasCompleteSecretMaterial())
+
+	unlockedKey, err := key.Unlock([]byte(stubKeyPassphrase))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking stub key, got:", err)
+	}
+	keyRing, err := NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	// The signing subkey has secret material
+	message := NewPlainMessageFromString("stub key message")
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing with subkey, got:", err)
+	}
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+}
+
+func TestSecretKeyPresenceStubSigning(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_stubSigning", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+
+	assert.Exactly(t, map[string]int{
+		"1e1014f6ff4523db96ce87ed913337d8b0c10aa9": constants.SECRET_KEY_STUB,
+		"f5d26a28c0ed7bd194b5109550491a040fde298f": constants.SECRET_KEY_PRESENT,
+	}, key.GetSecretKeyPresence())
+	assert.True(t, key.HasAnySecretMaterial())
+	assert.False(t, key.HasCompleteSecretMaterial())
+
+	unlockedKey, err := key.Unlock([]byte(stubKeyPassphrase))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking stub key, got:", err)
+	}
+	keyRing, err := NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	_, err = keyRing.SignDetached(NewPlainMessageFromString("stub key message"))
+	assert.EqualError(t, err, "gopenpgp: cannot sign message, the secret material of the signing key is missing (stub key)")
+
+	// The encryption subkey can still decrypt
+	encrypted, err := keyRing.Encrypt(NewPlainMessageFromString("stub key message"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRing.Decrypt(encrypted, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with stub key, got:", err)
+	}
+	assert.Exactly(t, "stub key message", decrypted.GetString())
+}
+
+func TestSecretKeyPresencePublicKey(t *testing.T) {
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	for _, presence := range publicKey.GetSecretKeyPresence() {
+		assert.Exactly(t, constants.SECRET_KEY_ABSENT, presence)
+	}
+	assert.False(t, publicKey.HasAnySecretMaterial())
+	assert.True(t, keyTestEC.HasCompleteSecretMaterial())
+}
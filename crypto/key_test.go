@@ -1,15 +1,18 @@
 package crypto
 
 import (
+	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/base64"
 	"io/ioutil"
+	"math/big"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 
 	"github.com/stretchr/testify/assert"
@@ -221,6 +224,36 @@ func TestIsExpired(t *testing.T) {
 	assert.Exactly(t, true, futureKey.IsExpired())
 }
 
+func TestKeyFlags(t *testing.T) {
+	// The user ID has an older self-signature with the flags certify and sign,
+	// and a newer one with the flags certify, sign and authenticate.
+	key, err := NewKeyFromArmored(readTestFile("key_conflictingFlags", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	flags := key.GetKeyFlags()
+	assert.Len(t, flags, 2)
+	assert.Exactly(t, &KeyFlags{
+		KeyID:           key.GetHexKeyID(),
+		CanCertify:      true,
+		CanSign:         true,
+		CanAuthenticate: true,
+	}, flags[0])
+	assert.Exactly(t, &KeyFlags{
+		KeyID:                    keyIDToHex(key.entity.Subkeys[0].PublicKey.KeyId),
+		CanEncryptCommunications: true,
+		CanEncryptStorage:        true,
+	}, flags[1])
+	assert.True(t, key.GetKeyServerPreferences().NoModify)
+
+	flags = keyTestEC.GetKeyFlags()
+	assert.True(t, flags[0].CanSign)
+	assert.False(t, flags[0].CanEncryptStorage)
+	assert.True(t, flags[1].CanEncryptStorage)
+	assert.False(t, keyTestEC.GetKeyServerPreferences().NoModify)
+}
+
 func TestGenerateKeyWithPrimes(t *testing.T) {
 	prime1, _ := base64.StdEncoding.DecodeString(
 		"/thF8zjjk6fFx/y9NId35NFx8JTA7jvHEl+gI0dp9dIl9trmeZb+ESZ8f7bNXUmTI8j271kyenlrVJiqwqk80Q==")
@@ -242,6 +275,99 @@ func TestGenerateKeyWithPrimes(t *testing.T) {
 	assert.Exactly(t, prime2, pk.Primes[1].Bytes())
 }
 
+func TestGenerateKeyWithOptions(t *testing.T) {
+	key, err := GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{
+		PrimaryKeyType: "ed25519",
+		SubkeyType:     "rsa",
+		SubkeyBits:     1024,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.Exactly(t, "eddsa", key.GetPrimaryKeyAlgorithm())
+	encryptionAlgorithm, err := key.GetEncryptionKeyAlgorithm()
+	if err != nil {
+		t.Fatal("Expected no error while getting encryption algorithm, got:", err)
+	}
+	assert.Exactly(t, "rsa", encryptionAlgorithm)
+	assert.Len(t, key.entity.Subkeys, 1)
+
+	// The subkey binding signature must survive a round trip
+	serialized, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	parsedKey, err := NewKey(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	assert.True(t, parsedKey.CanEncrypt())
+	assert.True(t, parsedKey.CanVerify())
+
+	keyRing, err := NewKeyRing(parsedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	ciphertext, err := keyRing.Encrypt(NewPlainMessageFromString("hello"), keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRing.Decrypt(ciphertext, keyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	key, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{PrimaryKeyType: "x25519"})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	encryptionAlgorithm, _ = key.GetEncryptionKeyAlgorithm()
+	assert.Exactly(t, "ecdh", encryptionAlgorithm)
+
+	key, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{PrimaryKeyType: "ed25519"})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.Exactly(t, "eddsa", key.GetPrimaryKeyAlgorithm())
+	encryptionAlgorithm, _ = key.GetEncryptionKeyAlgorithm()
+	assert.Exactly(t, "ecdh", encryptionAlgorithm)
+
+	// AddEncryptionSubkey maps EdDSA to ECDH, the subkey must not follow
+	// the primary key algorithm
+	key, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{
+		PrimaryKeyType: "rsa", PrimaryKeyBits: 1024, SubkeyType: "x25519",
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.Exactly(t, packet.PubKeyAlgoRSA, key.entity.PrimaryKey.PubKeyAlgo)
+	assert.Len(t, key.entity.Subkeys, 1)
+	assert.Exactly(t, packet.PubKeyAlgoECDH, key.entity.Subkeys[0].PublicKey.PubKeyAlgo)
+	assert.True(t, key.CanEncrypt())
+	assert.True(t, key.CanVerify())
+
+	key, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{
+		PrimaryKeyType: "rsa", PrimaryKeyBits: 1024, SubkeyBits: 2048,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.Exactly(t, packet.PubKeyAlgoRSA, key.entity.Subkeys[0].PublicKey.PubKeyAlgo)
+	subkeyBits, err := key.entity.Subkeys[0].PublicKey.BitLength()
+	if err != nil {
+		t.Fatal("Expected no error while getting the subkey size, got:", err)
+	}
+	assert.Exactly(t, uint16(2048), subkeyBits)
+
+	_, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{PrimaryKeyType: "cv25519"})
+	assert.Error(t, err)
+	_, err = GenerateKeyWithOptions(keyTestName, keyTestDomain, &KeyGenerationOptions{
+		PrimaryKeyType: "rsa", PrimaryKeyBits: 1024, SubkeyType: "ed25519",
+	})
+	assert.Error(t, err)
+}
+
 func TestFailCheckIntegrity25519(t *testing.T) {
 	failCheckIntegrity(t, "x25519", 0)
 }
@@ -435,3 +561,102 @@ func TestKeyCompression(t *testing.T) {
 		keyTestEC.entity.PrimaryIdentity().SelfSignature.PreferredCompression,
 	)
 }
+
+func TestLockClearsUnlockedMaterial(t *testing.T) {
+	unlockedKey, err := keyTestRSA.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy key:", err)
+	}
+
+	lockedKey, err := unlockedKey.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Cannot lock key:", err)
+	}
+
+	// No decrypted material must be reachable from the locked key, which
+	// can't sign
+	assert.Empty(t, lockedKey.getPrivateParams())
+	lockedKeyRing := &KeyRing{}
+	lockedKeyRing.appendKey(lockedKey)
+	_, err = lockedKeyRing.SignDetached(NewPlainMessageFromString("locked"))
+	assert.Error(t, err)
+
+	// The receiver must still be usable
+	unlocked, err := unlockedKey.IsUnlocked()
+	if err != nil {
+		t.Fatal("Cannot check if key is unlocked:", err)
+	}
+	assert.True(t, unlocked)
+	assert.NotZero(t, unlockedKey.entity.PrivateKey.PrivateKey.(*rsa.PrivateKey).D.Sign())
+
+	relockedKey, err := lockedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+	assert.Exactly(t, unlockedKey.GetFingerprint(), relockedKey.GetFingerprint())
+
+	// Lock wipes the parameters of its copy as below once it is encrypted
+	copiedKey, err := unlockedKey.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy key:", err)
+	}
+	params, words := getRSAPrivateParams(copiedKey)
+	clearPrivateParams(copiedKey.getPrivateParams())
+	assertBigIntsCleared(t, params, words)
+
+	copiedKey, err = unlockedKey.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy key:", err)
+	}
+	params, words = getRSAPrivateParams(copiedKey)
+	assert.True(t, copiedKey.ClearPrivateParams())
+	assertBigIntsCleared(t, params, words)
+}
+
+// getRSAPrivateParams returns the private parameters of the RSA key and
+// subkeys of key, and their words, which are wiped in place.
+func getRSAPrivateParams(key *Key) (params []*big.Int, words [][]big.Word) {
+	for _, priv := range key.getPrivateParams() {
+		rsaPriv := priv.(*rsa.PrivateKey)
+		params = append(params, rsaPriv.D, rsaPriv.Precomputed.Dp, rsaPriv.Precomputed.Dq, rsaPriv.Precomputed.Qinv)
+		params = append(params, rsaPriv.Primes...)
+	}
+	for _, param := range params {
+		words = append(words, param.Bits())
+	}
+	return params, words
+}
+
+func assertBigIntsCleared(t *testing.T, params []*big.Int, words [][]big.Word) {
+	assert.NotEmpty(t, params)
+	for i, param := range params {
+		assert.Zero(t, param.Sign())
+		assert.NotEmpty(t, words[i])
+		assert.Exactly(t, make([]big.Word, len(words[i])), words[i])
+	}
+}
+
+func TestUnlockDoesNotMutateReceiver(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(keyTestArmoredEC)
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	unlockedKey, err := lockedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+
+	assert.True(t, lockedKey.entity.PrivateKey.Encrypted)
+	assert.Nil(t, lockedKey.entity.PrivateKey.PrivateKey)
+	assert.True(t, lockedKey.entity.Subkeys[0].PrivateKey.Encrypted)
+	assert.Nil(t, lockedKey.entity.Subkeys[0].PrivateKey.PrivateKey)
+
+	primary := unlockedKey.entity.PrivateKey.PrivateKey.(*ed25519.PrivateKey)
+	sub := unlockedKey.entity.Subkeys[0].PrivateKey.PrivateKey.(*ecdh.PrivateKey)
+
+	assert.True(t, unlockedKey.ClearPrivateParams())
+	assertEdDSACleared(t, primary)
+	assertECDHCleared(t, sub)
+	assert.False(t, unlockedKey.IsPrivate())
+}
@@ -0,0 +1,213 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+)
+
+// Tags of the packets of a key which are parsed, in addition to the key
+// packets.
+const (
+	packetTagUserID        = 13
+	packetTagPublicSubkey  = 14
+	packetTagUserAttribute = 17
+)
+
+// KeyImportOptions are the options to import a key, see NewKeyWithOptions.
+type KeyImportOptions struct {
+	// PreserveUnknownPackets keeps the packets of the key which aren't parsed,
+	// e.g. the trust packets exported by GnuPG, as opaque data: Serialize and
+	// Armor write them back after the packet they followed. They are never
+	// used by the cryptographic operations, nor exported with the public key.
+	PreserveUnknownPackets bool
+}
+
+// preservedPackets holds the unknown packets of an imported key.
+type preservedPackets struct {
+	// The key as imported, and the sorted packets of its serialization
+	original   []byte
+	serialized [][]byte
+	unknown    []anchoredPacket
+}
+
+// anchoredPacket is an unknown packet, with the anchor of the packet it
+// follows, see getPacketAnchor.
+type anchoredPacket struct {
+	anchor string
+	data   []byte
+}
+
+// NewKeyWithOptions creates a new key from the first key in the unarmored
+// binary data, like NewKey, with the import options.
+func NewKeyWithOptions(binKeys []byte, options *KeyImportOptions) (*Key, error) {
+	key, err := NewKey(binKeys)
+	if err != nil {
+		return nil, err
+	}
+	if options != nil && options.PreserveUnknownPackets {
+		if key.preserved, err = newPreservedPackets(key, binKeys); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// NewKeyFromArmoredWithOptions creates a new key from the first key in an
+// armored string, like NewKeyFromArmored, with the import options.
+func NewKeyFromArmoredWithOptions(armored string, options *KeyImportOptions) (*Key, error) {
+	binKeys, err := armor.Unarmor(armored)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyWithOptions(binKeys, options)
+}
+
+// newPreservedPackets records the packets of data, the binary key, which
+// aren't parsed into key, or returns nil if there is none.
+func newPreservedPackets(key *Key, data []byte) (*preservedPackets, error) {
+	packets, err := splitPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	preserved := &preservedPackets{original: clone(data)}
+	anchor := ""
+	for _, raw := range packets {
+		if isKeyPacketTag(raw) {
+			anchor = getPacketAnchor(raw)
+			continue
+		}
+		preserved.unknown = append(preserved.unknown, anchoredPacket{anchor: anchor, data: clone(raw)})
+	}
+	if len(preserved.unknown) == 0 {
+		return nil, nil
+	}
+
+	serialized, err := key.serializeEntity()
+	if err != nil {
+		return nil, err
+	}
+	if preserved.serialized, err = splitPackets(serialized); err != nil {
+		return nil, err
+	}
+	sortPackets(preserved.serialized)
+	return preserved, nil
+}
+
+// restore returns the serialization of the key with the unknown packets:
+// the key as imported if serialized has the same packets as at import, or
+// serialized with the unknown packets inserted after the packets with the
+// same anchor otherwise.
+func (preserved *preservedPackets) restore(serialized []byte) ([]byte, error) {
+	packets, err := splitPackets(serialized)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([][]byte(nil), packets...)
+	sortPackets(sorted)
+	if equalPackets(sorted, preserved.serialized) {
+		return clone(preserved.original), nil
+	}
+
+	var restored bytes.Buffer
+	preserved.writeAnchored(&restored, "")
+	for _, raw := range packets {
+		restored.Write(raw)
+		preserved.writeAnchored(&restored, getPacketAnchor(raw))
+	}
+	return restored.Bytes(), nil
+}
+
+func (preserved *preservedPackets) writeAnchored(w *bytes.Buffer, anchor string) {
+	for _, unknown := range preserved.unknown {
+		if unknown.anchor == anchor {
+			w.Write(unknown.data)
+		}
+	}
+}
+
+// isKeyPacketTag returns whether the raw packet is parsed into keys.
+func isKeyPacketTag(raw []byte) bool {
+	tag, _, _ := readPacketHeader(raw)
+	switch tag {
+	case packetTagSignature, packetTagSecretKey, packetTagPublicKey, packetTagSecretSubkey,
+		packetTagUserID, packetTagPublicSubkey, packetTagUserAttribute:
+		return true
+	}
+	return false
+}
+
+// getPacketAnchor identifies the raw packet of a key: by the fingerprint for
+// key packets, which doesn't change when the key is locked or unlocked, and
+// by the tag and the body for the other packets.
+func getPacketAnchor(raw []byte) string {
+	tag, _, _ := readPacketHeader(raw)
+	switch tag {
+	case packetTagSecretKey, packetTagPublicKey, packetTagSecretSubkey, packetTagPublicSubkey:
+		// Parse the public key fields which start the body
+		body := getRawPacketBody(raw)
+		public := make([]byte, 0, len(body)+6)
+		length := len(body)
+		public = append(public, 0xc0|packetTagPublicKey, 0xff,
+			byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+		public = append(public, body...)
+		defer clearMem(public)
+		if p, err := packet.Read(bytes.NewReader(public)); err == nil {
+			if pk, ok := p.(*packet.PublicKey); ok {
+				return "key:" + hex.EncodeToString(pk.Fingerprint)
+			}
+		}
+	}
+	// The header of the serialized packet may be in another format
+	return string(tag) + string(getRawPacketBody(raw))
+}
+
+// getRawPacketBody returns the body of a packet with a definite length, or
+// nil if its header is invalid.
+func getRawPacketBody(raw []byte) []byte {
+	header, err := parsePacketHeader(raw)
+	if err != nil {
+		return nil
+	}
+	return raw[header.headerLength:]
+}
+
+// splitPackets splits binary data into its packets, which must have definite
+// lengths.
+func splitPackets(data []byte) ([][]byte, error) {
+	var packets [][]byte
+	for offset := 0; offset < len(data); {
+		_, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil, err
+		}
+		if length > len(data)-offset {
+			return nil, errors.New("gopenpgp: truncated packet")
+		}
+		packets = append(packets, data[offset:offset+length])
+		offset += length
+	}
+	return packets, nil
+}
+
+func sortPackets(packets [][]byte) {
+	sort.Slice(packets, func(i, j int) bool {
+		return bytes.Compare(packets[i], packets[j]) < 0
+	})
+}
+
+func equalPackets(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+)
+
+const packetTagTrust = 12
+
+func countTrustPackets(t *testing.T, data []byte) int {
+	packets, err := splitPackets(data)
+	if err != nil {
+		t.Fatal("Expected no error while splitting packets, got:", err)
+	}
+	count := 0
+	for _, raw := range packets {
+		if tag, _, _ := readPacketHeader(raw); tag == packetTagTrust {
+			count++
+		}
+	}
+	return count
+}
+
+func TestKeyPreserveUnknownPacketsRoundTrip(t *testing.T) {
+	armored := readTestFile("key_gnupgTrustPackets", false)
+	original, err := armor.Unarmor(armored)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring key, got:", err)
+	}
+	assert.Exactly(t, 6, countTrustPackets(t, original))
+
+	key, err := NewKeyFromArmoredWithOptions(armored, &KeyImportOptions{PreserveUnknownPackets: true})
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	serialized, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	assert.Exactly(t, original, serialized)
+
+	rearmored, err := key.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	reimported, err := armor.Unarmor(rearmored)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring key, got:", err)
+	}
+	assert.Exactly(t, original, reimported)
+
+	copied, err := key.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	serialized, err = copied.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	assert.Exactly(t, original, serialized)
+
+	// The public key doesn't carry the local trust data
+	public, err := key.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing public key, got:", err)
+	}
+	assert.Zero(t, countTrustPackets(t, public))
+}
+
+func TestKeyPreserveUnknownPacketsModified(t *testing.T) {
+	key, err := NewKeyFromArmoredWithOptions(
+		readTestFile("key_gnupgTrustPackets", false), &KeyImportOptions{PreserveUnknownPackets: true},
+	)
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	locked, err := key.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	serialized, err := locked.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	assert.Exactly(t, 6, countTrustPackets(t, serialized))
+
+	// The trust packet of the primary key still follows it
+	packets, err := splitPackets(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while splitting packets, got:", err)
+	}
+	tag, _, _ := readPacketHeader(packets[1])
+	assert.Exactly(t, byte(packetTagTrust), tag)
+
+	relocked, err := NewKey(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	unlocked, err := relocked.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), unlocked.GetFingerprint())
+}
+
+func TestKeyDropUnknownPacketsByDefault(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_gnupgTrustPackets", false))
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	serialized, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	assert.Zero(t, countTrustPackets(t, serialized))
+
+	withoutUnknown, err := NewKeyWithOptions(serialized, &KeyImportOptions{PreserveUnknownPackets: true})
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	assert.Nil(t, withoutUnknown.preserved)
+}
@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"bytes"
+	"sync"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -10,12 +11,28 @@ import (
 )
 
 // KeyRing contains multiple private and public keys.
+// A keyring is safe for concurrent use, e.g. decrypting in several goroutines
+// while keys are added: the operations use the keys of the keyring at the
+// time they start. The keys themselves are shared with the Key objects
+// returned by GetKeys and GetKey, and must not be modified concurrently, e.g.
+// unlocked or cleared.
 type KeyRing struct {
-	// PGP entities in this keyring.
+	// Guards entities and the options, not FirstKeyID
+	lock sync.RWMutex
+
+	// PGP entities in this keyring, never modified in place.
 	entities openpgp.EntityList
 
 	// FirstKeyID as obtained from API to match salt
 	FirstKeyID string
+
+	// Optional cache for the results of signature verifications
+	verificationCache VerificationCache
+
+	// Whether encryption skips the keys without encryption key
+	allowPartialRecipients bool
+	// Whether encryption uses AEAD if all the recipients support it
+	enableModernCrypto bool
 }
 
 // Identity contains the name and the email of a key holder.
@@ -37,6 +54,9 @@ func NewKeyRing(key *Key) (*KeyRing, error) {
 }
 
 // AddKey adds the given key to the keyring.
+// If the keyring already has a key with the same fingerprint, they are merged
+// instead: the copy with secret material is kept, e.g. a private key added
+// after its public key, with the signatures of both copies.
 func (keyRing *KeyRing) AddKey(key *Key) error {
 	if key.IsPrivate() {
 		unlocked, err := key.IsUnlocked()
@@ -45,7 +65,7 @@ func (keyRing *KeyRing) AddKey(key *Key) error {
 		}
 	}
 
-	keyRing.appendKey(key)
+	keyRing.addOrMergeKey(key)
 	return nil
 }
 
@@ -53,34 +73,52 @@ func (keyRing *KeyRing) AddKey(key *Key) error {
 
 // GetKeys returns openpgp keys contained in this KeyRing.
 func (keyRing *KeyRing) GetKeys() []*Key {
-	keys := make([]*Key, keyRing.CountEntities())
-	for i, entity := range keyRing.entities {
-		keys[i] = &Key{entity}
+	entities := keyRing.getEntities()
+	keys := make([]*Key, len(entities))
+	for i, entity := range entities {
+		keys[i] = &Key{entity: entity}
 	}
 	return keys
 }
 
 // GetKey returns the n-th openpgp key contained in this KeyRing.
 func (keyRing *KeyRing) GetKey(n int) (*Key, error) {
-	if n >= keyRing.CountEntities() {
+	entities := keyRing.getEntities()
+	if n >= len(entities) {
 		return nil, errors.New("gopenpgp: out of bound when fetching key")
 	}
-	return &Key{keyRing.entities[n]}, nil
+	return &Key{entity: entities[n]}, nil
 }
 
 // getSigningEntity returns first private unlocked signing entity from keyring.
 func (keyRing *KeyRing) getSigningEntity() (*openpgp.Entity, error) {
+	entities := keyRing.getEntities()
+	if len(entities) == 0 {
+		return nil, errors.New("gopenpgp: cannot sign message, the signing key ring is empty")
+	}
 	var signEntity *openpgp.Entity
+	stubbed := false
 
-	for _, e := range keyRing.entities {
+	for _, e := range entities {
 		// Entity.PrivateKey must be a signing key
 		if e.PrivateKey != nil {
 			if !e.PrivateKey.Encrypted {
+				// Skip the keys whose signing key is a stub
+				if e.PrimaryIdentity() != nil {
+					signingKey, ok := e.SigningKey(getNow())
+					if ok && signingKey.PrivateKey != nil && signingKey.PrivateKey.Dummy() {
+						stubbed = true
+						continue
+					}
+				}
 				signEntity = e
 				break
 			}
 		}
 	}
+	if signEntity == nil && stubbed {
+		return nil, errors.New("gopenpgp: cannot sign message, the secret material of the signing key is missing (stub key)")
+	}
 	if signEntity == nil {
 		return nil, errors.New("gopenpgp: cannot sign message, unable to unlock signer key")
 	}
@@ -92,18 +130,20 @@ func (keyRing *KeyRing) getSigningEntity() (*openpgp.Entity, error) {
 
 // CountEntities returns the number of entities in the keyring.
 func (keyRing *KeyRing) CountEntities() int {
-	return len(keyRing.entities)
+	return len(keyRing.getEntities())
 }
 
-// CountDecryptionEntities returns the number of entities in the keyring.
+// CountDecryptionEntities returns the number of keys of the keyring which can
+// decrypt messages, including expired and revoked ones, see
+// PlainMessage.IsDecryptionKeyExpired.
 func (keyRing *KeyRing) CountDecryptionEntities() int {
-	return len(keyRing.entities.DecryptionKeys())
+	return len(keyRing.getEntities().DecryptionKeys())
 }
 
 // GetIdentities returns the list of identities associated with this key ring.
 func (keyRing *KeyRing) GetIdentities() []*Identity {
 	var identities []*Identity
-	for _, e := range keyRing.entities {
+	for _, e := range keyRing.getEntities() {
 		for _, id := range e.Identities {
 			identities = append(identities, &Identity{
 				Name:  id.UserId.Name,
@@ -138,8 +178,9 @@ func (keyRing *KeyRing) CanEncrypt() bool {
 
 // GetKeyIDs returns array of IDs of keys in this KeyRing.
 func (keyRing *KeyRing) GetKeyIDs() []uint64 {
-	var res = make([]uint64, len(keyRing.entities))
-	for id, e := range keyRing.entities {
+	entities := keyRing.getEntities()
+	var res = make([]uint64, len(entities))
+	for id, e := range entities {
 		res[id] = e.PrimaryKey.KeyId
 	}
 	return res
@@ -158,7 +199,7 @@ func FilterExpiredKeys(contactKeys []*KeyRing) (filteredKeys []*KeyRing, err err
 	for _, contactKeyRing := range contactKeys {
 		keyRingHasUnexpiredEntity := false
 		keyRingHasTotallyExpiredEntity := false
-		for _, entity := range contactKeyRing.entities {
+		for _, entity := range contactKeyRing.getEntities() {
 			hasExpired := false
 			hasUnexpired := false
 			for _, subkey := range entity.Subkeys {
@@ -195,11 +236,12 @@ func FilterExpiredKeys(contactKeys []*KeyRing) (filteredKeys []*KeyRing, err err
 
 // FirstKey returns a KeyRing with only the first key of the original one.
 func (keyRing *KeyRing) FirstKey() (*KeyRing, error) {
-	if len(keyRing.entities) == 0 {
+	entities := keyRing.getEntities()
+	if len(entities) == 0 {
 		return nil, errors.New("gopenpgp: No key available in this keyring")
 	}
 	newKeyRing := &KeyRing{}
-	newKeyRing.entities = keyRing.entities[:1]
+	newKeyRing.entities = entities[:1]
 
 	return newKeyRing.Copy()
 }
@@ -208,8 +250,15 @@ func (keyRing *KeyRing) FirstKey() (*KeyRing, error) {
 func (keyRing *KeyRing) Copy() (*KeyRing, error) {
 	newKeyRing := &KeyRing{}
 
-	entities := make([]*openpgp.Entity, len(keyRing.entities))
-	for id, entity := range keyRing.entities {
+	keyRing.lock.RLock()
+	verificationCache := keyRing.verificationCache
+	allowPartialRecipients := keyRing.allowPartialRecipients
+	enableModernCrypto := keyRing.enableModernCrypto
+	keyRing.lock.RUnlock()
+
+	oldEntities := keyRing.getEntities()
+	entities := make([]*openpgp.Entity, len(oldEntities))
+	for id, entity := range oldEntities {
 		var buffer bytes.Buffer
 		var err error
 
@@ -232,10 +281,45 @@ func (keyRing *KeyRing) Copy() (*KeyRing, error) {
 	}
 	newKeyRing.entities = entities
 	newKeyRing.FirstKeyID = keyRing.FirstKeyID
+	newKeyRing.verificationCache = verificationCache
+	newKeyRing.allowPartialRecipients = allowPartialRecipients
+	newKeyRing.enableModernCrypto = enableModernCrypto
 
 	return newKeyRing, nil
 }
 
+// ToPublic returns a keyring with the public keys of the keys of the keyring,
+// in the same order, see Key.ToPublic. The public keys of the keyring are
+// copied. The options of the keyring are kept.
+func (keyRing *KeyRing) ToPublic() (*KeyRing, error) {
+	keyRing.lock.RLock()
+	verificationCache := keyRing.verificationCache
+	allowPartialRecipients := keyRing.allowPartialRecipients
+	enableModernCrypto := keyRing.enableModernCrypto
+	keyRing.lock.RUnlock()
+
+	oldEntities := keyRing.getEntities()
+	entities := make(openpgp.EntityList, len(oldEntities))
+	for i, entity := range oldEntities {
+		serialized, err := (&Key{entity: entity}).GetPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		entities[i], err = openpgp.ReadEntity(packet.NewReader(bytes.NewReader(serialized)))
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to read public key")
+		}
+	}
+
+	return &KeyRing{
+		entities:               entities,
+		FirstKeyID:             keyRing.FirstKeyID,
+		verificationCache:      verificationCache,
+		allowPartialRecipients: allowPartialRecipients,
+		enableModernCrypto:     enableModernCrypto,
+	}, nil
+}
+
 func (keyRing *KeyRing) ClearPrivateParams() {
 	for _, key := range keyRing.GetKeys() {
 		key.ClearPrivateParams()
@@ -246,5 +330,20 @@ func (keyRing *KeyRing) ClearPrivateParams() {
 
 // appendKey appends a key to the keyring.
 func (keyRing *KeyRing) appendKey(key *Key) {
-	keyRing.entities = append(keyRing.entities, key.entity)
+	keyRing.lock.Lock()
+	defer keyRing.lock.Unlock()
+	// Copy the entities, which may be used by concurrent operations
+	n := len(keyRing.entities)
+	keyRing.entities = append(keyRing.entities[:n:n], key.entity)
+}
+
+// getEntities returns the entities of the keyring, none if keyRing is nil.
+// They must not be modified in place, but can be appended to.
+func (keyRing *KeyRing) getEntities() openpgp.EntityList {
+	if keyRing == nil {
+		return nil
+	}
+	keyRing.lock.RLock()
+	defer keyRing.lock.RUnlock()
+	return keyRing.entities[:len(keyRing.entities):len(keyRing.entities)]
 }
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// KeyRingBuilder builds a KeyRing from several keys, validating each of them.
+// By default, the first invalid key fails the build, and duplicate keys are
+// added again, like successive calls to KeyRing.AddKey.
+type KeyRingBuilder struct {
+	skipInvalid    bool
+	deduplicate    bool
+	requirePrivate bool
+
+	keys []*Key
+	// Parsing errors of the keys added with AddArmoredKey, by index
+	parseErrors map[int]error
+}
+
+// KeyRingReport describes which keys were added to a keyring by
+// KeyRingBuilder.Build.
+type KeyRingReport struct {
+	// Fingerprints of the keys added to the keyring, in order.
+	Accepted []string
+	// Fingerprints of the keys skipped because a key with the same
+	// fingerprint was added before, see KeyRingBuilder.DeduplicateKeys.
+	Duplicates []string
+	// Keys which are invalid, see KeyRingBuilder.SkipInvalidKeys.
+	Rejected []*RejectedKey
+}
+
+// RejectedKey is a key which KeyRingBuilder didn't add to the keyring.
+type RejectedKey struct {
+	// Index of the key, in the order in which it was added to the builder.
+	Index int
+	// Fingerprint is empty if the key can't be parsed.
+	Fingerprint string
+	Error       string
+}
+
+// NewKeyRingFromKeys creates a new KeyRing with the given keys, and fails if
+// one of them can't be added, see KeyRing.AddKey.
+func NewKeyRingFromKeys(keys []*Key) (*KeyRing, error) {
+	builder := NewKeyRingBuilder()
+	for _, key := range keys {
+		builder.AddKey(key)
+	}
+	keyRing, _, err := builder.Build()
+	return keyRing, err
+}
+
+// NewKeyRingBuilder returns a builder of an empty keyring.
+func NewKeyRingBuilder() *KeyRingBuilder {
+	return &KeyRingBuilder{parseErrors: make(map[int]error)}
+}
+
+// SkipInvalidKeys sets whether the invalid keys are left out of the keyring
+// and reported, instead of failing the build.
+func (builder *KeyRingBuilder) SkipInvalidKeys(skip bool) *KeyRingBuilder {
+	builder.skipInvalid = skip
+	return builder
+}
+
+// DeduplicateKeys sets whether the keys whose fingerprint was already added
+// are left out of the keyring.
+func (builder *KeyRingBuilder) DeduplicateKeys(deduplicate bool) *KeyRingBuilder {
+	builder.deduplicate = deduplicate
+	return builder
+}
+
+// RequirePrivateKeys sets whether public keys are invalid.
+func (builder *KeyRingBuilder) RequirePrivateKeys(require bool) *KeyRingBuilder {
+	builder.requirePrivate = require
+	return builder
+}
+
+// AddKey adds a key to the keyring. Private keys must be unlocked.
+func (builder *KeyRingBuilder) AddKey(key *Key) *KeyRingBuilder {
+	builder.keys = append(builder.keys, key)
+	return builder
+}
+
+// AddArmoredKey parses an armored key and adds it to the keyring. A key which
+// can't be parsed is invalid.
+func (builder *KeyRingBuilder) AddArmoredKey(armored string) *KeyRingBuilder {
+	key, err := NewKeyFromArmored(armored)
+	if err != nil {
+		builder.parseErrors[len(builder.keys)] = err
+	}
+	return builder.AddKey(key)
+}
+
+// Build creates the keyring, and reports which keys were added.
+// If invalid keys are skipped, an error is only returned if every key is
+// invalid, with the report. Otherwise, the error of the first invalid key is
+// returned, without keyring nor report.
+func (builder *KeyRingBuilder) Build() (*KeyRing, *KeyRingReport, error) {
+	keyRing := &KeyRing{}
+	report := &KeyRingReport{}
+	added := make(map[string]bool, len(builder.keys))
+
+	for i, key := range builder.keys {
+		if err := builder.validateKey(i, key); err != nil {
+			if !builder.skipInvalid {
+				return nil, nil, errors.Wrap(err, fmt.Sprintf("gopenpgp: unable to add key %d to the keyring", i))
+			}
+			rejected := &RejectedKey{Index: i, Error: err.Error()}
+			if key != nil && key.entity != nil {
+				rejected.Fingerprint = key.GetFingerprint()
+			}
+			report.Rejected = append(report.Rejected, rejected)
+			continue
+		}
+
+		fingerprint := key.GetFingerprint()
+		if builder.deduplicate && added[fingerprint] {
+			report.Duplicates = append(report.Duplicates, fingerprint)
+			continue
+		}
+		added[fingerprint] = true
+		keyRing.appendKey(key)
+		report.Accepted = append(report.Accepted, fingerprint)
+	}
+
+	if len(report.Accepted) == 0 && len(report.Rejected) > 0 {
+		return nil, report, errors.New("gopenpgp: unable to build keyring, every key is invalid")
+	}
+	return keyRing, report, nil
+}
+
+// validateKey returns why the i-th key can't be added to the keyring.
+func (builder *KeyRingBuilder) validateKey(i int, key *Key) error {
+	if err, ok := builder.parseErrors[i]; ok {
+		return err
+	}
+	if key == nil || key.entity == nil {
+		return errors.New("gopenpgp: key is empty")
+	}
+	if builder.requirePrivate && !key.IsPrivate() {
+		return errors.New("gopenpgp: key is not private")
+	}
+	if key.IsPrivate() {
+		unlocked, err := key.IsUnlocked()
+		if err != nil || !unlocked {
+			return errors.New("gopenpgp: unable to add locked key to a keyring")
+		}
+	}
+	return nil
+}
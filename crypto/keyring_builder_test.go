@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const corruptArmoredKey = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+xjMEXCqtgBYJKwYBBAHaRw8BAQdA
+-----END PGP PUBLIC KEY BLOCK-----`
+
+func TestNewKeyRingFromKeys(t *testing.T) {
+	keyRing, err := NewKeyRingFromKeys([]*Key{keyTestRSA, keyTestEC})
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Exactly(t, 2, keyRing.CountEntities())
+	assert.Exactly(t, keyTestEC.GetFingerprint(), keyRing.GetKeys()[1].GetFingerprint())
+
+	lockedKey, err := NewKeyFromArmored(keyTestArmoredEC)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	_, err = NewKeyRingFromKeys([]*Key{keyTestRSA, lockedKey})
+	assert.Error(t, err)
+	_, err = NewKeyRingFromKeys([]*Key{keyTestRSA, nil})
+	assert.Error(t, err)
+}
+
+func TestKeyRingBuilderSkipInvalidKeys(t *testing.T) {
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	keyRing, report, err := NewKeyRingBuilder().
+		SkipInvalidKeys(true).
+		DeduplicateKeys(true).
+		AddKey(keyTestEC).
+		AddArmoredKey(corruptArmoredKey).
+		AddKey(keyTestEC).
+		AddArmoredKey(keyTestArmoredRSA).
+		AddKey(publicKey).
+		Build()
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	assert.Exactly(t, 2, keyRing.CountEntities())
+	assert.Exactly(t, []string{keyTestEC.GetFingerprint(), keyTestRSA.GetFingerprint()}, report.Accepted)
+	assert.Exactly(t, []string{keyTestEC.GetFingerprint()}, report.Duplicates)
+	assert.Len(t, report.Rejected, 2)
+	assert.Exactly(t, 1, report.Rejected[0].Index)
+	assert.Exactly(t, "", report.Rejected[0].Fingerprint)
+	assert.Exactly(t, 3, report.Rejected[1].Index)
+	assert.Exactly(t, keyTestRSA.GetFingerprint(), report.Rejected[1].Fingerprint)
+	assert.Exactly(t, "gopenpgp: unable to add locked key to a keyring", report.Rejected[1].Error)
+}
+
+func TestKeyRingBuilderFailFast(t *testing.T) {
+	keyRing, report, err := NewKeyRingBuilder().
+		AddKey(keyTestEC).
+		AddArmoredKey(corruptArmoredKey).
+		AddKey(keyTestRSA).
+		Build()
+	assert.Error(t, err)
+	assert.Nil(t, keyRing)
+	assert.Nil(t, report)
+}
+
+func TestKeyRingBuilderRequirePrivateKeys(t *testing.T) {
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	_, _, err = NewKeyRingBuilder().RequirePrivateKeys(true).AddKey(keyTestEC).AddKey(publicKey).Build()
+	assert.Error(t, err)
+
+	_, report, err := NewKeyRingBuilder().RequirePrivateKeys(true).SkipInvalidKeys(true).AddKey(publicKey).Build()
+	assert.Error(t, err)
+	assert.Len(t, report.Rejected, 1)
+}
@@ -0,0 +1,116 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func assertNoVerifier(t *testing.T, err error) {
+	var sigErr SignatureVerificationError
+	if !errors.As(err, &sigErr) {
+		t.Fatal("Expected a SignatureVerificationError, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, sigErr.Status)
+}
+
+func TestDecryptAndVerifyEmptyKeyRing(t *testing.T) {
+	empty, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating key ring, got:", err)
+	}
+	message := NewPlainMessageFromString("signed and encrypted")
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	split, err := encrypted.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, empty, GetUnixTime())
+	assertNoVerifier(t, err)
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+	decrypted, err = keyRingTestPrivate.Decrypt(encrypted, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting without verification, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	decrypted, err = sessionKey.DecryptAndVerify(split.GetBinaryDataPacket(), empty, GetUnixTime())
+	assertNoVerifier(t, err)
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+	decrypted, err = sessionKey.DecryptAndVerify(split.GetBinaryDataPacket(), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting without verification, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(encrypted.GetBinary()), empty, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading stream, got:", err)
+	}
+	assert.Exactly(t, message.GetBinary(), data)
+	assertNoVerifier(t, reader.VerifySignature())
+
+	reader, err = sessionKey.DecryptStream(bytes.NewReader(split.GetBinaryDataPacket()), empty, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	data, err = ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading stream, got:", err)
+	}
+	assert.Exactly(t, message.GetBinary(), data)
+	assertNoVerifier(t, reader.VerifySignature())
+}
+
+func TestEmptyKeyRingSafe(t *testing.T) {
+	empty, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating key ring, got:", err)
+	}
+	message := NewPlainMessageFromString("plain text")
+
+	assert.Zero(t, empty.CountEntities())
+	_, err = empty.SignDetached(message)
+	assert.Error(t, err)
+	_, err = empty.SignDetachedStream(bytes.NewReader(message.GetBinary()))
+	assert.Error(t, err)
+	_, err = empty.Encrypt(message, nil)
+	assert.Error(t, err)
+	_, err = empty.EncryptSessionKey(testSessionKey)
+	assert.Error(t, err)
+	_, err = empty.GetKey(0)
+	assert.Error(t, err)
+
+	for _, signKeyRing := range []*KeyRing{empty, nil} {
+		_, err = testSessionKey.EncryptAndSign(message, signKeyRing)
+		assert.Error(t, err)
+
+		// A nil or empty signing key ring doesn't sign
+		encrypted, err := keyRingTestPublic.Encrypt(message, signKeyRing)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		_, err = keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+		var sigErr SignatureVerificationError
+		if !errors.As(err, &sigErr) {
+			t.Fatal("Expected a SignatureVerificationError, got:", err)
+		}
+		assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, sigErr.Status)
+	}
+}
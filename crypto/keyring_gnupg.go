@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Blob types of a GnuPG keybox file.
+const (
+	keyboxBlobEmpty   = 0
+	keyboxBlobHeader  = 1
+	keyboxBlobOpenPGP = 2
+)
+
+// keyboxMagic identifies the header blob of a keybox file.
+var keyboxMagic = []byte("KBXf")
+
+// NewKeyRingFromGnuPGFile reads the public keys of a GnuPG public keyring,
+// either a keybox (pubring.kbx) or a legacy keyring (pubring.gpg).
+// Keys which can't be parsed, private keys, and the X.509 certificates of a
+// keybox are skipped, and counted in skipped.
+func NewKeyRingFromGnuPGFile(reader Reader) (keyRing *KeyRing, skipped int, err error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "gopenpgp: error in reading GnuPG keyring")
+	}
+
+	var keys [][]byte
+	if len(data) >= 12 && data[4] == keyboxBlobHeader && bytes.Equal(data[8:12], keyboxMagic) {
+		keys, skipped, err = splitKeybox(data)
+	} else {
+		keys, err = splitKeys(data)
+	}
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "gopenpgp: error in reading GnuPG keyring")
+	}
+
+	keyRing = &KeyRing{}
+	for _, binKey := range keys {
+		key, err := NewKey(binKey)
+		if err != nil || key.IsPrivate() {
+			skipped++
+			continue
+		}
+		keyRing.appendKey(key)
+	}
+	return keyRing, skipped, nil
+}
+
+// splitKeybox returns the OpenPGP keyblocks of a keybox file, and the number
+// of other blobs, e.g. X.509 certificates.
+func splitKeybox(data []byte) (keys [][]byte, skipped int, err error) {
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return nil, 0, errors.New("gopenpgp: truncated keybox blob")
+		}
+		length := binary.BigEndian.Uint32(data)
+		if length < 5 || uint64(length) > uint64(len(data)) {
+			return nil, 0, errors.New("gopenpgp: invalid keybox blob length")
+		}
+		blob := data[:length]
+		data = data[length:]
+
+		switch blob[4] {
+		case keyboxBlobEmpty, keyboxBlobHeader:
+		case keyboxBlobOpenPGP:
+			// The blob starts with its length, type, version, flags,
+			// and the offset and length of the keyblock
+			if len(blob) < 16 {
+				return nil, 0, errors.New("gopenpgp: truncated keybox blob")
+			}
+			offset := uint64(binary.BigEndian.Uint32(blob[8:]))
+			keyLength := uint64(binary.BigEndian.Uint32(blob[12:]))
+			if offset+keyLength > uint64(len(blob)) {
+				skipped++
+				continue
+			}
+			keys = append(keys, blob[offset:offset+keyLength])
+		default:
+			skipped++
+		}
+	}
+	return keys, skipped, nil
+}
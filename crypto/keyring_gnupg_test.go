@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The files were written by GnuPG 2.2, with the key of testdata/keyring_publicKey
+// and an Ed25519 key, and an X.509 certificate in the keybox.
+func TestNewKeyRingFromGnuPGFile(t *testing.T) {
+	for _, test := range []struct {
+		file    string
+		skipped int
+	}{
+		{file: "gnupg_pubring.kbx", skipped: 1},
+		{file: "gnupg_pubring.gpg", skipped: 0},
+	} {
+		file, err := os.Open("testdata/" + test.file)
+		if err != nil {
+			t.Fatal("Expected no error while opening the GnuPG file, got:", err)
+		}
+		keyRing, skipped, err := NewKeyRingFromGnuPGFile(file)
+		_ = file.Close()
+		if err != nil {
+			t.Fatal("Expected no error while reading the GnuPG file, got:", err)
+		}
+
+		assert.Exactly(t, test.skipped, skipped)
+		keys := keyRing.GetKeys()
+		assert.Len(t, keys, 2)
+		assert.Exactly(t, "3eb6259edf21df24", keys[0].GetHexKeyID())
+		assert.Exactly(t, "46beba3eb9e2a95a", keys[1].GetHexKeyID())
+	}
+}
+
+func TestNewKeyRingFromGnuPGFileSkipsPrivateKeys(t *testing.T) {
+	privateKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+	serialized, err := privateKey.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing private key, got:", err)
+	}
+
+	data := append([]byte(readTestFile("gnupg_pubring.gpg", false)), serialized...)
+	keyRing, skipped, err := NewKeyRingFromGnuPGFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Expected no error while reading the GnuPG file, got:", err)
+	}
+	assert.Exactly(t, 1, skipped)
+	assert.Exactly(t, 2, keyRing.CountEntities())
+}
+
+func TestNewKeyRingFromGnuPGFileInvalid(t *testing.T) {
+	data := readTestFile("gnupg_pubring.kbx", false)
+	_, _, err := NewKeyRingFromGnuPGFile(bytes.NewReader([]byte(data[:len(data)-1])))
+	assert.Error(t, err)
+}
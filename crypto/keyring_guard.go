@@ -0,0 +1,236 @@
+package crypto
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PassphraseProvider returns the passphrase of the keys of a KeyRingGuard
+// when they need to be unlocked. The returned passphrase is wiped after use.
+type PassphraseProvider func() ([]byte, error)
+
+// KeyRingGuard holds locked private keys, which it unlocks on demand with the
+// passphrase of a PassphraseProvider, and locks again after they have been
+// idle for a timeout: the unlocked keys are then wiped from memory.
+// It is safe for concurrent use: the keys are never locked during an
+// operation, the timeout expires once the operations in flight complete.
+type KeyRingGuard struct {
+	keys       []*Key
+	timeout    time.Duration
+	passphrase PassphraseProvider
+	clock      guardClock
+
+	// lock is held for reading by the operations using the unlocked keys,
+	// and for writing to unlock and lock them.
+	lock     sync.RWMutex
+	unlocked *KeyRing
+	// generation identifies the unlocking which started timer
+	generation int
+	timer      guardTimer
+
+	useLock sync.Mutex
+	lastUse time.Time
+}
+
+// NewKeyRingGuard returns a guard of the locked private keys, unlocking them
+// with the passphrase returned by passphrase, and locking them after they
+// have been idle for timeout, or only with KeyRingGuard.Lock if timeout is 0.
+// The keys are copied, and initially locked.
+func NewKeyRingGuard(timeout time.Duration, passphrase PassphraseProvider, lockedKeys ...*Key) (*KeyRingGuard, error) {
+	if len(lockedKeys) == 0 {
+		return nil, errors.New("gopenpgp: a key ring guard needs at least one key")
+	}
+	if passphrase == nil {
+		return nil, errors.New("gopenpgp: a key ring guard needs a passphrase provider")
+	}
+	keys := make([]*Key, len(lockedKeys))
+	for i, key := range lockedKeys {
+		locked, err := key.IsLocked()
+		if err != nil || !locked {
+			return nil, errors.New("gopenpgp: the keys of a key ring guard must be locked private keys")
+		}
+		if keys[i], err = key.Copy(); err != nil {
+			return nil, err
+		}
+	}
+	return &KeyRingGuard{
+		keys:       keys,
+		timeout:    timeout,
+		passphrase: passphrase,
+		clock:      systemClock{},
+	}, nil
+}
+
+// Unlock unlocks the keys, if they are locked, with the passphrase of the
+// PassphraseProvider. The other functions unlock them on demand.
+func (guard *KeyRingGuard) Unlock() error {
+	guard.lock.Lock()
+	defer guard.lock.Unlock()
+	if guard.unlocked != nil {
+		guard.touch()
+		return nil
+	}
+
+	passphrase, err := guard.passphrase()
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to get the passphrase of the key ring guard")
+	}
+	defer clearMem(passphrase)
+	keyRing := &KeyRing{}
+	for _, key := range guard.keys {
+		unlocked, err := key.Unlock(passphrase)
+		if err != nil {
+			keyRing.ClearPrivateParams()
+			return err
+		}
+		keyRing.appendKey(unlocked)
+	}
+
+	guard.unlocked = keyRing
+	guard.generation++
+	guard.touch()
+	if guard.timeout > 0 {
+		generation := guard.generation
+		guard.timer = guard.clock.AfterFunc(guard.timeout, func() { guard.checkIdle(generation) })
+	}
+	return nil
+}
+
+// Lock wipes the unlocked keys, once the operations in flight complete.
+func (guard *KeyRingGuard) Lock() {
+	guard.lock.Lock()
+	defer guard.lock.Unlock()
+	guard.relock()
+}
+
+// IsUnlocked returns whether the keys are currently unlocked.
+func (guard *KeyRingGuard) IsUnlocked() bool {
+	guard.lock.RLock()
+	defer guard.lock.RUnlock()
+	return guard.unlocked != nil
+}
+
+// Decrypt decrypts and verifies a message with the keys, like
+// KeyRing.Decrypt.
+func (guard *KeyRingGuard) Decrypt(message *PGPMessage, verifyKey *KeyRing, verifyTime int64) (*PlainMessage, error) {
+	var plainMessage *PlainMessage
+	err := guard.use(func(keyRing *KeyRing) (err error) {
+		plainMessage, err = keyRing.Decrypt(message, verifyKey, verifyTime)
+		return err
+	})
+	return plainMessage, err
+}
+
+// Encrypt encrypts a message to publicKey, or to the keys if publicKey is nil,
+// and signs it with the keys, like publicKey.Encrypt(message, keys).
+func (guard *KeyRingGuard) Encrypt(message *PlainMessage, publicKey *KeyRing) (*PGPMessage, error) {
+	var pgpMessage *PGPMessage
+	err := guard.use(func(keyRing *KeyRing) (err error) {
+		if publicKey == nil {
+			publicKey = keyRing
+		}
+		pgpMessage, err = publicKey.Encrypt(message, keyRing)
+		return err
+	})
+	return pgpMessage, err
+}
+
+// SignDetached signs a message with the keys, like KeyRing.SignDetached.
+func (guard *KeyRingGuard) SignDetached(message *PlainMessage) (*PGPSignature, error) {
+	var signature *PGPSignature
+	err := guard.use(func(keyRing *KeyRing) (err error) {
+		signature, err = keyRing.SignDetached(message)
+		return err
+	})
+	return signature, err
+}
+
+// GetUnlockedKeysSnapshot returns a copy of the unlocked keys, unlocking them
+// if needed. The copy isn't locked by the guard: the caller must wipe it with
+// KeyRing.ClearPrivateParams once done.
+func (guard *KeyRingGuard) GetUnlockedKeysSnapshot() (*KeyRing, error) {
+	var snapshot *KeyRing
+	err := guard.use(func(keyRing *KeyRing) (err error) {
+		snapshot, err = keyRing.Copy()
+		return err
+	})
+	return snapshot, err
+}
+
+// use calls f with the unlocked keys, unlocking them if needed. They aren't
+// locked until f returns.
+func (guard *KeyRingGuard) use(f func(keyRing *KeyRing) error) error {
+	for {
+		guard.lock.RLock()
+		if guard.unlocked != nil {
+			break
+		}
+		guard.lock.RUnlock()
+		if err := guard.Unlock(); err != nil {
+			return err
+		}
+	}
+	defer guard.lock.RUnlock()
+	guard.touch()
+	defer guard.touch()
+	return f(guard.unlocked)
+}
+
+// touch records a use of the unlocked keys.
+func (guard *KeyRingGuard) touch() {
+	guard.useLock.Lock()
+	defer guard.useLock.Unlock()
+	guard.lastUse = guard.clock.Now()
+}
+
+// checkIdle locks the keys unlocked by the given generation if they have been
+// idle for the timeout, or checks again when they may have been.
+func (guard *KeyRingGuard) checkIdle(generation int) {
+	guard.lock.Lock()
+	defer guard.lock.Unlock()
+	if guard.unlocked == nil || guard.generation != generation {
+		return
+	}
+	guard.useLock.Lock()
+	idle := guard.clock.Now().Sub(guard.lastUse)
+	guard.useLock.Unlock()
+	if idle < guard.timeout {
+		guard.timer = guard.clock.AfterFunc(guard.timeout-idle, func() { guard.checkIdle(generation) })
+		return
+	}
+	guard.relock()
+}
+
+// relock wipes the unlocked keys, with the lock held for writing.
+func (guard *KeyRingGuard) relock() {
+	if guard.timer != nil {
+		guard.timer.Stop()
+		guard.timer = nil
+	}
+	if guard.unlocked != nil {
+		guard.unlocked.ClearPrivateParams()
+		guard.unlocked = nil
+	}
+}
+
+// guardClock is the time source of a KeyRingGuard, replaced in tests.
+type guardClock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) guardTimer
+}
+
+type guardTimer interface {
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) guardTimer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,246 @@
+package crypto
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) AfterFunc(d time.Duration, f func()) guardTimer {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	timer := &fakeTimer{clock: clock, at: clock.now.Add(d), f: f}
+	clock.timers = append(clock.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward by d, and calls the functions of the timers
+// which expire.
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.lock.Lock()
+	clock.now = clock.now.Add(d)
+	var expired []*fakeTimer
+	for _, timer := range clock.timers {
+		if !timer.stopped && !timer.at.After(clock.now) {
+			timer.stopped = true
+			expired = append(expired, timer)
+		}
+	}
+	clock.lock.Unlock()
+	for _, timer := range expired {
+		timer.f()
+	}
+}
+
+func (timer *fakeTimer) Stop() bool {
+	timer.clock.lock.Lock()
+	defer timer.clock.lock.Unlock()
+	wasActive := !timer.stopped
+	timer.stopped = true
+	return wasActive
+}
+
+// lockTestKey returns a copy of key locked with keyTestPassphrase.
+func lockTestKey(t *testing.T, key *Key) *Key {
+	locked, err := key.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	return locked
+}
+
+func newTestKeyRingGuard(t *testing.T, timeout time.Duration) (*KeyRingGuard, *fakeClock, *int) {
+	unlocks := 0
+	guard, err := NewKeyRingGuard(timeout, func() ([]byte, error) {
+		unlocks++
+		return clone(keyTestPassphrase), nil
+	}, lockTestKey(t, keyTestRSA), lockTestKey(t, keyTestEC))
+	if err != nil {
+		t.Fatal("Expected no error while creating the key ring guard, got:", err)
+	}
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	guard.clock = clock
+	return guard, clock, &unlocks
+}
+
+func TestKeyRingGuardRelocksWhenIdle(t *testing.T) {
+	guard, clock, unlocks := newTestKeyRingGuard(t, time.Minute)
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	message, err := publicKeyRing.Encrypt(NewPlainMessageFromString("guarded"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	// The keys are unlocked on demand
+	assert.False(t, guard.IsUnlocked())
+	decrypted, err := guard.Decrypt(message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "guarded", decrypted.GetString())
+	assert.True(t, guard.IsUnlocked())
+	assert.Exactly(t, 1, *unlocks)
+
+	// Each use postpones the timeout
+	clock.Advance(40 * time.Second)
+	signature, err := guard.SignDetached(NewPlainMessageFromString("guarded"))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.NoError(t, publicKeyRing.VerifyDetached(NewPlainMessageFromString("guarded"), signature, GetUnixTime()))
+	clock.Advance(40 * time.Second)
+	assert.True(t, guard.IsUnlocked())
+	clock.Advance(20 * time.Second)
+	assert.False(t, guard.IsUnlocked())
+
+	// And unlocked again
+	encrypted, err := guard.Encrypt(NewPlainMessageFromString("guarded"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, 2, *unlocks)
+	decrypted, err = guard.Decrypt(encrypted, publicKeyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "guarded", decrypted.GetString())
+	assert.Exactly(t, 2, *unlocks)
+
+	// Explicit locking and unlocking
+	guard.Lock()
+	assert.False(t, guard.IsUnlocked())
+	clock.Advance(time.Hour)
+	assert.NoError(t, guard.Unlock())
+	assert.True(t, guard.IsUnlocked())
+	assert.Exactly(t, 3, *unlocks)
+	clock.Advance(time.Minute)
+	assert.False(t, guard.IsUnlocked())
+}
+
+// firingClock is a fakeClock signaling on fired when a timer expires, before
+// calling its function.
+type firingClock struct {
+	*fakeClock
+	fired chan struct{}
+}
+
+func (clock *firingClock) AfterFunc(d time.Duration, f func()) guardTimer {
+	return clock.fakeClock.AfterFunc(d, func() {
+		select {
+		case clock.fired <- struct{}{}:
+		default:
+		}
+		f()
+	})
+}
+
+func TestKeyRingGuardInFlightOperation(t *testing.T) {
+	guard, fake, _ := newTestKeyRingGuard(t, time.Minute)
+	clock := &firingClock{fakeClock: fake, fired: make(chan struct{}, 1)}
+	guard.clock = clock
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- guard.use(func(keyRing *KeyRing) error {
+			close(started)
+			<-release
+			// The keys can't be locked while the read lock is held
+			if guard.unlocked != keyRing {
+				return errors.New("the keys were locked during the operation")
+			}
+			_, err := keyRing.SignDetached(NewPlainMessageFromString("in flight"))
+			return err
+		})
+	}()
+	<-started
+
+	// The timeout expires during the operation
+	advanced := make(chan struct{})
+	go func() {
+		clock.Advance(2 * time.Minute)
+		close(advanced)
+	}()
+	<-clock.fired
+	close(release)
+	assert.NoError(t, <-done)
+	<-advanced
+
+	// The keys were in use until the end of the operation
+	assert.True(t, guard.IsUnlocked())
+	clock.Advance(time.Minute)
+	assert.False(t, guard.IsUnlocked())
+}
+
+func TestKeyRingGuardSnapshot(t *testing.T) {
+	guard, _, _ := newTestKeyRingGuard(t, 0)
+	snapshot, err := guard.GetUnlockedKeysSnapshot()
+	if err != nil {
+		t.Fatal("Expected no error while getting the snapshot, got:", err)
+	}
+	defer snapshot.ClearPrivateParams()
+	assert.Exactly(t, 2, snapshot.CountDecryptionEntities())
+
+	// The snapshot isn't locked with the guard
+	guard.Lock()
+	_, err = snapshot.SignDetached(NewPlainMessageFromString("snapshot"))
+	assert.NoError(t, err)
+}
+
+func TestKeyRingGuardErrors(t *testing.T) {
+	lockedKey := lockTestKey(t, keyTestRSA)
+	provider := func() ([]byte, error) { return []byte("wrong"), nil }
+	_, err := NewKeyRingGuard(time.Minute, provider, keyTestRSA)
+	assert.EqualError(t, err, "gopenpgp: the keys of a key ring guard must be locked private keys")
+	_, err = NewKeyRingGuard(time.Minute, provider)
+	assert.Error(t, err)
+	_, err = NewKeyRingGuard(time.Minute, nil, lockedKey)
+	assert.Error(t, err)
+
+	guard, err := NewKeyRingGuard(time.Minute, provider, lockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while creating the key ring guard, got:", err)
+	}
+	_, err = guard.SignDetached(NewPlainMessageFromString("wrong passphrase"))
+	assert.Error(t, err)
+	assert.False(t, guard.IsUnlocked())
+
+	errProvider := errors.New("cancelled")
+	guard, err = NewKeyRingGuard(time.Minute, func() ([]byte, error) { return nil, errProvider }, lockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while creating the key ring guard, got:", err)
+	}
+	err = guard.Unlock()
+	assert.True(t, errors.Is(err, errProvider), err)
+	assert.False(t, guard.IsUnlocked())
+}
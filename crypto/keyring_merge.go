@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// addOrMergeKey adds the key to the keyring, or merges it with the key of the
+// keyring with the same fingerprint, see AddKey.
+func (keyRing *KeyRing) addOrMergeKey(key *Key) {
+	keyRing.lock.Lock()
+	defer keyRing.lock.Unlock()
+	// Copy the entities, which may be used by concurrent operations
+	n := len(keyRing.entities)
+	for i, entity := range keyRing.entities {
+		if !bytes.Equal(entity.PrimaryKey.Fingerprint, key.entity.PrimaryKey.Fingerprint) {
+			continue
+		}
+		entities := make(openpgp.EntityList, n)
+		copy(entities, keyRing.entities)
+		if entity.PrivateKey == nil && key.entity.PrivateKey != nil {
+			entities[i] = mergeEntities(key.entity, entity)
+		} else {
+			entities[i] = mergeEntities(entity, key.entity)
+		}
+		keyRing.entities = entities
+		return
+	}
+	keyRing.entities = append(keyRing.entities[:n:n], key.entity)
+}
+
+// mergeEntities returns a new entity with the keys of preferred, and the
+// signatures of both entities, which have the same primary key. The latest
+// self-signatures are kept.
+func mergeEntities(preferred, other *openpgp.Entity) *openpgp.Entity {
+	merged := *preferred
+	merged.Revocations = mergeSignatures(preferred.Revocations, other.Revocations)
+
+	merged.Identities = make(map[string]*openpgp.Identity, len(preferred.Identities))
+	for name, identity := range preferred.Identities {
+		mergedIdentity := *identity
+		merged.Identities[name] = &mergedIdentity
+	}
+	for name, identity := range other.Identities {
+		mergedIdentity, ok := merged.Identities[name]
+		if !ok {
+			copied := *identity
+			merged.Identities[name] = &copied
+			continue
+		}
+		mergedIdentity.SelfSignature = latestSignature(mergedIdentity.SelfSignature, identity.SelfSignature)
+		mergedIdentity.Signatures = mergeSignatures(mergedIdentity.Signatures, identity.Signatures)
+	}
+
+	merged.Subkeys = append([]openpgp.Subkey(nil), preferred.Subkeys...)
+	for _, subkey := range other.Subkeys {
+		found := false
+		for i := range merged.Subkeys {
+			mergedSubkey := &merged.Subkeys[i]
+			if !bytes.Equal(mergedSubkey.PublicKey.Fingerprint, subkey.PublicKey.Fingerprint) {
+				continue
+			}
+			found = true
+			if mergedSubkey.PrivateKey == nil && subkey.PrivateKey != nil {
+				mergedSubkey.PublicKey = subkey.PublicKey
+				mergedSubkey.PrivateKey = subkey.PrivateKey
+			}
+			mergedSubkey.Sig = latestSignature(mergedSubkey.Sig, subkey.Sig)
+			break
+		}
+		if !found {
+			merged.Subkeys = append(merged.Subkeys, subkey)
+		}
+	}
+	return &merged
+}
+
+// mergeSignatures returns the signatures of a followed by those of b which
+// aren't in a.
+func mergeSignatures(a, b []*packet.Signature) []*packet.Signature {
+	merged := a[:len(a):len(a)]
+	for _, sig := range b {
+		duplicate := false
+		for _, existing := range a {
+			if isSameSignature(existing, sig) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, sig)
+		}
+	}
+	return merged
+}
+
+// latestSignature returns the most recent of the signatures, a if they have
+// the same creation time.
+func latestSignature(a, b *packet.Signature) *packet.Signature {
+	if a == nil || (b != nil && b.CreationTime.After(a.CreationTime)) {
+		return b
+	}
+	return a
+}
+
+// isSameSignature returns whether the signatures have the same serialization.
+func isSameSignature(a, b *packet.Signature) bool {
+	if a == b {
+		return true
+	}
+	var serializedA, serializedB bytes.Buffer
+	if a.Serialize(&serializedA) != nil || b.Serialize(&serializedB) != nil {
+		return false
+	}
+	return bytes.Equal(serializedA.Bytes(), serializedB.Bytes())
+}
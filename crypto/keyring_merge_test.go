@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRingAddKeyMergesDuplicates(t *testing.T) {
+	privateKey := keyRingTestPrivate.GetKeys()[0]
+	publicKey, err := privateKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	// The public copy, e.g. from the contacts, has a certification which the
+	// private copy doesn't have
+	identity := publicKey.entity.PrimaryIdentity().Name
+	privateSignatures := len(privateKey.entity.Identities[identity].Signatures)
+	if err := publicKey.entity.SignIdentity(identity, keyTestEC.entity, nil); err != nil {
+		t.Fatal("Expected no error while certifying the key, got:", err)
+	}
+	message, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("duplicate"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	for name, keys := range map[string][]*Key{
+		"public first":  {publicKey, privateKey},
+		"private first": {privateKey, publicKey},
+	} {
+		keyRing, err := NewKeyRing(nil)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		for _, key := range keys {
+			if err := keyRing.AddKey(key); err != nil {
+				t.Fatal("Expected no error while adding key, got:", err)
+			}
+		}
+
+		assert.Exactly(t, 1, keyRing.CountEntities(), name)
+		assert.Exactly(t, 1, keyRing.CountDecryptionEntities(), name)
+		merged := keyRing.GetKeys()[0]
+		assert.True(t, merged.IsPrivate(), name)
+		assert.Len(t, merged.entity.Identities[identity].Signatures, privateSignatures+1, name)
+
+		decrypted, err := keyRing.Decrypt(message, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, "duplicate", decrypted.GetString(), name)
+	}
+
+	// The added keys aren't modified
+	assert.Len(t, privateKey.entity.Identities[identity].Signatures, privateSignatures)
+	assert.Len(t, publicKey.entity.Identities[identity].Signatures, privateSignatures+1)
+
+	// Adding the same key again doesn't duplicate its signatures
+	keyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := keyRing.AddKey(publicKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	assert.Exactly(t, 1, keyRing.CountEntities())
+	assert.Exactly(t, 0, keyRing.CountDecryptionEntities())
+	assert.Len(t, keyRing.GetKeys()[0].entity.Identities[identity].Signatures, privateSignatures+1)
+}
@@ -3,6 +3,7 @@ package crypto
 import (
 	"bytes"
 	"crypto"
+	goerrors "errors"
 	"io"
 	"io/ioutil"
 	"time"
@@ -17,8 +18,9 @@ import (
 // If an unlocked private key is also provided it will also sign the message.
 // * message    : The plaintext input as a PlainMessage.
 // * privateKey : (optional) an unlocked private keyring to include signature in the message.
+// The message isn't signed if privateKey is nil or empty.
 func (keyRing *KeyRing) Encrypt(message *PlainMessage, privateKey *KeyRing) (*PGPMessage, error) {
-	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator()}
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator(), Rand: getRandom()}
 	encrypted, err := asymmetricEncrypt(message, keyRing, privateKey, config)
 	if err != nil {
 		return nil, err
@@ -28,16 +30,18 @@ func (keyRing *KeyRing) Encrypt(message *PlainMessage, privateKey *KeyRing) (*PG
 }
 
 // EncryptWithCompression encrypts with compression support a PlainMessage to PGPMessage using public/private keys.
-// * message : The plain data as a PlainMessage.
-// * privateKey : (optional) an unlocked private keyring to include signature in the message.
-// * output  : The encrypted data as PGPMessage.
-// * cipherFunction : The type of cipher
-// * compressionAlgo  : algo used for compression.
+//   - message : The plain data as a PlainMessage.
+//   - privateKey : (optional) an unlocked private keyring to include signature in the message,
+//     the message isn't signed if it is nil or empty.
+//   - output  : The encrypted data as PGPMessage.
+//   - cipherFunction : The type of cipher
+//   - compressionAlgo  : algo used for compression.
 func (keyRing *KeyRing) EncryptWithCompression(message *PlainMessage,
 	privateKey *KeyRing,
 	cipherFunction packet.CipherFunction,
 	compressionAlgo packet.CompressionAlgo) (*PGPMessage, error) {
 	config := &packet.Config{
+		Rand:                   getRandom(),
 		DefaultCipher:          cipherFunction,
 		Time:                   getTimeGenerator(),
 		DefaultCompressionAlgo: compressionAlgo,
@@ -52,27 +56,87 @@ func (keyRing *KeyRing) EncryptWithCompression(message *PlainMessage,
 	return NewPGPMessage(encrypted), nil
 }
 
+// EncryptWithCompressionOptions encrypts a PlainMessage like Encrypt,
+// compressing it with the given options, after signing it if privateKey is
+// not nil. The compression is skipped if a recipient key doesn't support it.
+func (keyRing *KeyRing) EncryptWithCompressionOptions(
+	message *PlainMessage,
+	privateKey *KeyRing,
+	compression *CompressionOptions,
+) (*PGPMessage, error) {
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator(), Rand: getRandom()}
+	if err := compression.applyTo(config); err != nil {
+		return nil, err
+	}
+	encrypted, err := asymmetricEncrypt(message, keyRing, privateKey, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPGPMessage(encrypted), nil
+}
+
 // Decrypt decrypts encrypted string using pgp keys, returning a PlainMessage
 // * message    : The encrypted input as a PGPMessage
 // * verifyKey  : Public key for signature verification (optional)
 // * verifyTime : Time at verification (necessary only if verifyKey is not nil)
 //
 // When verifyKey is not provided, then verifyTime should be zero, and
-// signature verification will be ignored.
+// signature verification will be ignored. An empty verifyKey doesn't disable
+// the verification: the message is returned with a SignatureVerificationError
+// with status constants.SIGNATURE_NO_VERIFIER if it is signed, see also
+// VerifyOptions.AllowEmbeddedKeyVerification.
 func (keyRing *KeyRing) Decrypt(
 	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
 ) (*PlainMessage, error) {
-	return asymmetricDecrypt(message.NewReader(), keyRing, verifyKey, verifyTime)
+	return keyRing.decrypt(message, verifyKey, verifyTime, nil, nil)
+}
+
+// DecryptWithOptions decrypts and verifies a message like Decrypt, with the
+// checks of options, see VerifyOptions.
+func (keyRing *KeyRing) DecryptWithOptions(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64, options *VerifyOptions,
+) (*PlainMessage, error) {
+	return keyRing.decrypt(message, verifyKey, verifyTime, nil, options)
+}
+
+// DecryptWithProgress decrypts and verifies a message like Decrypt, reporting
+// the progress of the decryption to progress, see ProgressCallback.
+func (keyRing *KeyRing) DecryptWithProgress(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64, progress ProgressCallback,
+) (*PlainMessage, error) {
+	return keyRing.decrypt(message, verifyKey, verifyTime, progress, nil)
+}
+
+func (keyRing *KeyRing) decrypt(
+	message *PGPMessage, verifyKey *KeyRing, verifyTime int64, progress ProgressCallback, options *VerifyOptions,
+) (*PlainMessage, error) {
+	policy, err := options.getSignerPolicy()
+	if err != nil {
+		return nil, err
+	}
+	verifyTime = ResolveVerifyTime(verifyTime)
+	plainMessage, err := asymmetricDecrypt(
+		newProgressReader(message.GetBinary(), progress), keyRing, verifyKey, verifyTime, policy,
+	)
+	if verifyKey != nil && policy.allowEmbeddedKey && isSignatureNoVerifier(err) {
+		return keyRing.verifyWithEmbeddedKey(message, plainMessage, err, verifyTime, policy)
+	}
+	return plainMessage, err
 }
 
 // SignDetached generates and returns a PGPSignature for a given PlainMessage.
+// The signature is a binary signature, whatever the TextType of the message.
 func (keyRing *KeyRing) SignDetached(message *PlainMessage) (*PGPSignature, error) {
+	if err := checkPlaintextSize(message); err != nil {
+		return nil, err
+	}
 	signEntity, err := keyRing.getSigningEntity()
 	if err != nil {
 		return nil, err
 	}
 
-	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator()}
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator(), Rand: getRandom()}
 	var outBuf bytes.Buffer
 	// sign bin
 	if err := openpgp.DetachSign(&outBuf, signEntity, message.NewReader(), config); err != nil {
@@ -84,17 +148,42 @@ func (keyRing *KeyRing) SignDetached(message *PlainMessage) (*PGPSignature, erro
 
 // VerifyDetached verifies a PlainMessage with a detached PGPSignature
 // and returns a SignatureVerificationError if fails.
+// The message is hashed as binary data or as canonical text depending on the
+// type of the signature packet, not on its TextType, see
+// VerifyDetachedWithMode.
+// If a VerificationCache is set on the keyring, cached results are returned
+// when available.
 func (keyRing *KeyRing) VerifyDetached(message *PlainMessage, signature *PGPSignature, verifyTime int64) error {
-	return verifySignature(
-		keyRing.entities,
-		message.NewReader(),
-		signature.GetBinary(),
-		verifyTime,
-	)
+	return keyRing.VerifyDetachedWithOptions(message, signature, verifyTime, nil)
+}
+
+// VerifyDetachedWithOptions verifies a PlainMessage with a detached
+// PGPSignature like VerifyDetached, with the checks of options, see
+// VerifyOptions.
+func (keyRing *KeyRing) VerifyDetachedWithOptions(
+	message *PlainMessage, signature *PGPSignature, verifyTime int64, options *VerifyOptions,
+) error {
+	policy, err := options.getSignerPolicy()
+	if err != nil {
+		return err
+	}
+	verifyTime = ResolveVerifyTime(verifyTime)
+	return keyRing.verifyCached(signature.GetBinary(), message.GetBinary(), verifyTime, policy, func() error {
+		return verifySignature(
+			keyRing.getEntities(),
+			message.NewReader(),
+			signature.GetBinary(),
+			verifyTime,
+			policy,
+		)
+	})
 }
 
 // SignDetachedEncrypted generates and returns a PGPMessage
 // containing an encrypted detached signature for a given PlainMessage.
+// The encrypted message is a binary literal data packet containing the
+// unarmored signature packet, so that the signature never leaves the library
+// unencrypted.
 func (keyRing *KeyRing) SignDetachedEncrypted(message *PlainMessage, encryptionKeyRing *KeyRing) (encryptedSignature *PGPMessage, err error) {
 	if encryptionKeyRing == nil {
 		return nil, errors.New("gopenpgp: no encryption key ring provided")
@@ -111,6 +200,7 @@ func (keyRing *KeyRing) SignDetachedEncrypted(message *PlainMessage, encryptionK
 // VerifyDetachedEncrypted verifies a PlainMessage
 // with a PGPMessage containing an encrypted detached signature
 // and returns a SignatureVerificationError if fails.
+// The encrypted signature must be framed as in SignDetachedEncrypted.
 func (keyRing *KeyRing) VerifyDetachedEncrypted(message *PlainMessage, encryptedSignature *PGPMessage, decryptionKeyRing *KeyRing, verifyTime int64) error {
 	if decryptionKeyRing == nil {
 		return errors.New("gopenpgp: no decryption key ring provided")
@@ -123,6 +213,103 @@ func (keyRing *KeyRing) VerifyDetachedEncrypted(message *PlainMessage, encrypted
 	return keyRing.VerifyDetached(message, signature, verifyTime)
 }
 
+// SignDetachedEncryptedWithSessionKey generates a detached signature for a
+// given PlainMessage and encrypts it with the session key.
+// Returns the symmetrically encrypted data packet, framed as in
+// SignDetachedEncrypted, to be used in the split key workflow.
+func (keyRing *KeyRing) SignDetachedEncryptedWithSessionKey(message *PlainMessage, sessionKey *SessionKey) (encryptedSignature []byte, err error) {
+	if sessionKey == nil {
+		return nil, errors.New("gopenpgp: no session key provided")
+	}
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		return nil, err
+	}
+	plainMessage := NewPlainMessage(signature.GetBinary())
+	return sessionKey.Encrypt(plainMessage)
+}
+
+// VerifyDetachedEncryptedWithSessionKey verifies a PlainMessage with a
+// detached signature encrypted with the session key in a data packet,
+// as generated by SignDetachedEncryptedWithSessionKey,
+// and returns a SignatureVerificationError if fails.
+func (keyRing *KeyRing) VerifyDetachedEncryptedWithSessionKey(message *PlainMessage, encryptedSignature []byte, sessionKey *SessionKey, verifyTime int64) error {
+	if sessionKey == nil {
+		return errors.New("gopenpgp: no session key provided")
+	}
+	plainMessage, err := sessionKey.Decrypt(encryptedSignature)
+	if err != nil {
+		return err
+	}
+	signature := NewPGPSignature(plainMessage.GetBinary())
+	return keyRing.VerifyDetached(message, signature, verifyTime)
+}
+
+// EncryptSignedDetached encrypts a PlainMessage to the key ring, and signs it
+// with signKeyRing in a detached signature, which is encrypted separately
+// with the same session key, so that decrypting the key packet of the message
+// grants access to both.
+// The encrypted signature is a data packet only, without key packet, framed
+// as in SignDetachedEncryptedWithSessionKey: it can be joined with the key
+// packet of the message to form a complete PGPMessage.
+func (keyRing *KeyRing) EncryptSignedDetached(
+	message *PlainMessage, signKeyRing *KeyRing,
+) (encryptedMessage *PGPSplitMessage, encryptedSignature *PGPMessage, err error) {
+	if signKeyRing == nil {
+		return nil, nil, errors.New("gopenpgp: no signing key ring provided")
+	}
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sessionKey.Clear()
+
+	keyPacket, err := keyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataPacket, err := sessionKey.Encrypt(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	signaturePacket, err := signKeyRing.SignDetachedEncryptedWithSessionKey(message, sessionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewPGPSplitMessage(keyPacket, dataPacket), NewPGPMessage(signaturePacket), nil
+}
+
+// DecryptSignedDetached decrypts a message and its encrypted detached
+// signature, as generated by EncryptSignedDetached, with the session key
+// decrypted from the key packet of the message, and verifies the signature
+// with verifyKeyRing.
+// If the signature doesn't verify, the message is returned along with a
+// SignatureVerificationError.
+func (keyRing *KeyRing) DecryptSignedDetached(
+	encryptedMessage *PGPSplitMessage, encryptedSignature *PGPMessage,
+	verifyKeyRing *KeyRing, verifyTime int64,
+) (*PlainMessage, error) {
+	if verifyKeyRing == nil {
+		return nil, errors.New("gopenpgp: no verification key ring provided")
+	}
+	sessionKey, err := keyRing.DecryptSessionKey(encryptedMessage.GetBinaryKeyPacket())
+	if err != nil {
+		return nil, err
+	}
+	defer sessionKey.Clear()
+
+	message, err := sessionKey.Decrypt(encryptedMessage.GetBinaryDataPacket())
+	if err != nil {
+		return nil, err
+	}
+	err = verifyKeyRing.VerifyDetachedEncryptedWithSessionKey(message, encryptedSignature.GetBinary(), sessionKey, verifyTime)
+	var sigErr SignatureVerificationError
+	if err != nil && !goerrors.As(err, &sigErr) {
+		return nil, err
+	}
+	return message, err
+}
+
 // ------ INTERNAL FUNCTIONS -------
 
 // Core for encryption+signature (non-streaming) functions.
@@ -131,9 +318,19 @@ func asymmetricEncrypt(
 	publicKey, privateKey *KeyRing,
 	config *packet.Config,
 ) ([]byte, error) {
+	if err := checkPlaintextSize(plainMessage); err != nil {
+		return nil, err
+	}
+	plainMessage, err := plainMessage.getSignableMessage()
+	if err != nil {
+		return nil, err
+	}
+	if plainMessage.GetFormat() == constants.LITERAL_FORMAT_UTF8 {
+		return asymmetricEncryptWithSessionKey(plainMessage, publicKey, privateKey, config)
+	}
+
 	var outBuf bytes.Buffer
 	var encryptWriter io.WriteCloser
-	var err error
 
 	hints := &openpgp.FileHints{
 		IsBinary: plainMessage.IsBinary(),
@@ -159,6 +356,52 @@ func asymmetricEncrypt(
 	return outBuf.Bytes(), nil
 }
 
+// asymmetricEncryptWithSessionKey encrypts the message with a new session key,
+// for the literal data formats which the library can't write. The cipher,
+// AEAD and compression are negotiated with the recipients as by the library.
+func asymmetricEncryptWithSessionKey(
+	plainMessage *PlainMessage,
+	publicKey, privateKey *KeyRing,
+	config *packet.Config,
+) ([]byte, error) {
+	var signEntity *openpgp.Entity
+	if privateKey != nil && len(privateKey.getEntities()) > 0 {
+		var err error
+		signEntity, err = privateKey.getSigningEntity()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recipients, err := publicKey.getEncryptionEntities(config)
+	if err != nil {
+		return nil, err
+	}
+	cipher, aead, compression, err := negotiateEncryption(recipients, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in encrypting asymmetrically")
+	}
+	encryptionConfig := *config
+	encryptionConfig.DefaultCipher = cipher
+	encryptionConfig.DefaultCompressionAlgo = compression
+
+	sk, err := GenerateSessionKeyAlgo(getAlgo(cipher))
+	if err != nil {
+		return nil, err
+	}
+	defer sk.Clear()
+
+	keyPacket, err := encryptSessionKeyTo(recipients, sk, cipher)
+	if err != nil {
+		return nil, err
+	}
+	dataPacket, err := encryptWithSessionKey(plainMessage, sk, signEntity, &encryptionConfig, aead)
+	if err != nil {
+		return nil, err
+	}
+	return append(keyPacket, dataPacket...), nil
+}
+
 // Core for encryption+signature (all) functions.
 func asymmetricEncryptStream(
 	hints *openpgp.FileHints,
@@ -169,7 +412,7 @@ func asymmetricEncryptStream(
 ) (encryptWriter io.WriteCloser, err error) {
 	var signEntity *openpgp.Entity
 
-	if privateKey != nil && len(privateKey.entities) > 0 {
+	if privateKey != nil && len(privateKey.getEntities()) > 0 {
 		var err error
 		signEntity, err = privateKey.getSigningEntity()
 		if err != nil {
@@ -177,10 +420,15 @@ func asymmetricEncryptStream(
 		}
 	}
 
+	recipients, err := publicKey.getEncryptionEntities(config)
+	if err != nil {
+		return nil, err
+	}
+
 	if hints.IsBinary {
-		encryptWriter, err = openpgp.EncryptSplit(keyPacketWriter, dataPacketWriter, publicKey.entities, signEntity, hints, config)
+		encryptWriter, err = openpgp.EncryptSplit(keyPacketWriter, dataPacketWriter, recipients, signEntity, hints, config)
 	} else {
-		encryptWriter, err = openpgp.EncryptTextSplit(keyPacketWriter, dataPacketWriter, publicKey.entities, signEntity, hints, config)
+		encryptWriter, err = openpgp.EncryptTextSplit(keyPacketWriter, dataPacketWriter, recipients, signEntity, hints, config)
 	}
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in encrypting asymmetrically")
@@ -190,9 +438,9 @@ func asymmetricEncryptStream(
 
 // Core for decryption+verification (non streaming) functions.
 func asymmetricDecrypt(
-	encryptedIO io.Reader, privateKey *KeyRing, verifyKey *KeyRing, verifyTime int64,
+	encryptedIO io.Reader, privateKey *KeyRing, verifyKey *KeyRing, verifyTime int64, policy signerPolicy,
 ) (message *PlainMessage, err error) {
-	messageDetails, err := asymmetricDecryptStream(
+	messageDetails, encryption, err := asymmetricDecryptStream(
 		encryptedIO,
 		privateKey,
 		verifyKey,
@@ -206,36 +454,48 @@ func asymmetricDecrypt(
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
 	}
+	reportProgressDone(encryptedIO)
 
 	if verifyKey != nil {
 		processSignatureExpiration(messageDetails, verifyTime)
-		err = verifyDetailsSignature(messageDetails, verifyKey)
+		err = verifyDetailsSignature(messageDetails, verifyKey, policy)
+		if !messageDetails.IsSigned {
+			err = verifyPrefixedSignatures(encryption.prefixedSignatures, body, verifyKey, verifyTime, policy)
+		}
 	}
 
 	return &PlainMessage{
-		Data:     body,
-		TextType: !messageDetails.LiteralData.IsBinary,
-		Filename: messageDetails.LiteralData.FileName,
-		Time:     messageDetails.LiteralData.Time,
+		Data:                 body,
+		TextType:             !messageDetails.LiteralData.IsBinary,
+		Filename:             messageDetails.LiteralData.FileName,
+		Time:                 messageDetails.LiteralData.Time,
+		format:               getLiteralFormat(messageDetails.LiteralData.Format),
+		cipher:               getDecryptedCipherName(encryption.cipherFunc),
+		protection:           encryption.protection,
+		decryptionKeyExpired: encryption.decryptionKeyExpired,
+		signerKeyID:          messageDetails.SignedByKeyId,
 	}, err
 }
 
 // Core for decryption+verification (all) functions.
+// Returns how the message is encrypted.
 func asymmetricDecryptStream(
 	encryptedIO io.Reader,
 	privateKey *KeyRing,
 	verifyKey *KeyRing,
 	verifyTime int64,
-) (messageDetails *openpgp.MessageDetails, err error) {
-	privKeyEntries := privateKey.entities
+) (messageDetails *openpgp.MessageDetails, encryption *encryptionDetails, err error) {
+	privKeyEntries := privateKey.getEntities()
 	var additionalEntries openpgp.EntityList
 
 	if verifyKey != nil {
-		additionalEntries = verifyKey.entities
+		additionalEntries = verifyKey.getEntities()
 	}
 
 	if additionalEntries != nil {
-		privKeyEntries = append(privKeyEntries, additionalEntries...)
+		// Limit the capacity so that appending never writes to the array of
+		// the keyring, which may be used concurrently.
+		privKeyEntries = append(privKeyEntries[:len(privKeyEntries):len(privKeyEntries)], additionalEntries...)
 	}
 
 	config := &packet.Config{
@@ -252,9 +512,9 @@ func asymmetricDecryptStream(
 		},
 	}
 
-	messageDetails, err = openpgp.ReadMessage(encryptedIO, privKeyEntries, nil, config)
+	messageDetails, encryption, err = readMessage(encryptedIO, privKeyEntries, nil, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+		return nil, nil, errors.Wrap(err, "gopenpgp: error in reading message")
 	}
-	return messageDetails, err
+	return messageDetails, encryption, err
 }
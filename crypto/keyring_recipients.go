@@ -0,0 +1,304 @@
+package crypto
+
+import (
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// encryptionCandidateCiphers are the ciphers which the library may choose for
+// the session key of a message, in its order of preference.
+var encryptionCandidateCiphers = []packet.CipherFunction{
+	packet.CipherAES128,
+	packet.CipherAES256,
+	packet.CipherCAST5,
+}
+
+// EncryptPerRecipient encrypts a PlainMessage to the keys of the keyring, like
+// Encrypt, and returns the messages indexed by the hex fingerprint of each
+// recipient's primary key.
+// If the recipients share a cipher, a single message is encrypted, and every
+// fingerprint maps to it. Otherwise, the recipients are split in groups which
+// share a cipher, e.g. keys on smartcards only supporting AES-128 and keys
+// only accepting AES-256, and a message is encrypted for each group.
+// Encrypting several messages multiplies the size of the payload, and reveals
+// to anyone who sees them how the recipients are grouped, while each message
+// only lists the recipients of its group: callers must opt in by using this
+// function instead of Encrypt.
+// If privateKey is not nil, each message is signed, and the encryption fails
+// if the message of any group can't be encrypted. Otherwise, the messages
+// which could be encrypted are returned with an error listing the
+// fingerprints of the other recipients.
+func (keyRing *KeyRing) EncryptPerRecipient(
+	message *PlainMessage, privateKey *KeyRing,
+) (map[string]*PGPMessage, error) {
+	recipients := keyRing.getEntities()
+	if len(recipients) == 0 {
+		return nil, errors.New("gopenpgp: no encryption recipient provided")
+	}
+
+	messages := make(map[string]*PGPMessage, len(recipients))
+	var failed []string
+	var lastErr error
+	for _, group := range groupRecipientsByCipher(recipients) {
+		groupKeyRing := &KeyRing{entities: group}
+		groupKeyRing.SetEnableModernCrypto(keyRing.isModernCryptoEnabled())
+		encrypted, err := groupKeyRing.Encrypt(message, privateKey)
+		if err != nil {
+			if privateKey != nil {
+				return nil, errors.Wrap(err, "gopenpgp: unable to encrypt signed message to every recipient")
+			}
+			for _, entity := range group {
+				failed = append(failed, (&Key{entity: entity}).GetFingerprint())
+			}
+			lastErr = err
+			continue
+		}
+		for _, entity := range group {
+			messages[(&Key{entity: entity}).GetFingerprint()] = encrypted
+		}
+	}
+
+	if len(failed) > 0 {
+		return messages, errors.Wrap(lastErr, "gopenpgp: unable to encrypt message to "+strings.Join(failed, ", "))
+	}
+	return messages, nil
+}
+
+// groupRecipientsByCipher splits the recipients in groups which share at least
+// one cipher, in the order of the recipients. All the recipients are in the
+// first group if they share a cipher.
+func groupRecipientsByCipher(recipients openpgp.EntityList) []openpgp.EntityList {
+	var groups []openpgp.EntityList
+	var groupCiphers [][]packet.CipherFunction
+AddRecipient:
+	for _, entity := range recipients {
+		ciphers := getRecipientCiphers(entity)
+		if len(ciphers) > 0 {
+			for i := range groups {
+				if common := intersectCiphers(groupCiphers[i], ciphers); len(common) > 0 {
+					groups[i] = append(groups[i], entity)
+					groupCiphers[i] = common
+					continue AddRecipient
+				}
+			}
+		}
+		groups = append(groups, openpgp.EntityList{entity})
+		groupCiphers = append(groupCiphers, ciphers)
+	}
+	return groups
+}
+
+// getRecipientCiphers returns the candidate ciphers accepted by the recipient,
+// as negotiated by the library.
+func getRecipientCiphers(entity *openpgp.Entity) []packet.CipherFunction {
+	var preferred []uint8
+	if identity := entity.PrimaryIdentity(); identity != nil && identity.SelfSignature != nil {
+		preferred = identity.SelfSignature.PreferredSymmetric
+	}
+	if len(preferred) == 0 {
+		// Every implementation supports the first candidate
+		return encryptionCandidateCiphers[:1]
+	}
+	var ciphers []packet.CipherFunction
+	for _, cipher := range preferred {
+		ciphers = append(ciphers, packet.CipherFunction(cipher))
+	}
+	return intersectCiphers(encryptionCandidateCiphers, ciphers)
+}
+
+// intersectCiphers returns the ciphers of a which are also in b.
+func intersectCiphers(a, b []packet.CipherFunction) (intersection []packet.CipherFunction) {
+	for _, cipherA := range a {
+		for _, cipherB := range b {
+			if cipherA == cipherB {
+				intersection = append(intersection, cipherA)
+				break
+			}
+		}
+	}
+	return intersection
+}
+
+// MissingEncryptionKeyError is returned when encrypting to a keyring with keys
+// which have no valid encryption key, e.g. sign-only keys or expired keys,
+// unless partial recipients are allowed, see
+// KeyRing.SetAllowPartialRecipients.
+type MissingEncryptionKeyError struct {
+	// Hex fingerprints of the primary keys to which nothing was encrypted.
+	Fingerprints []string
+}
+
+func (err *MissingEncryptionKeyError) Error() string {
+	return "gopenpgp: no valid encryption key for " + strings.Join(err.Fingerprints, ", ")
+}
+
+// SetAllowPartialRecipients sets whether encrypting to the keyring skips the
+// keys which have no valid encryption key, instead of failing with a
+// MissingEncryptionKeyError. Encrypting still fails if no key of the keyring
+// can be encrypted to.
+func (keyRing *KeyRing) SetAllowPartialRecipients(allow bool) {
+	keyRing.lock.Lock()
+	defer keyRing.lock.Unlock()
+	keyRing.allowPartialRecipients = allow
+}
+
+// SetEnableModernCrypto sets whether encrypting to the keyring uses an AEAD
+// encrypted data packet, as drafted for RFC 4880bis, when every recipient
+// advertises AEAD support in its features. As the OpenPGP library doesn't
+// negotiate the AEAD parameters, it is only used if the recipients also
+// accept the cipher of the message, AES-256 unless another one is given, and
+// the EAX mode. Otherwise, the data is encrypted in a symmetrically encrypted
+// integrity protected data packet, see PGPMessage.GetProtection.
+// The library doesn't support the version 2 SEIPD packets and version 6 key
+// packets of RFC 9580 yet: they are neither produced nor decrypted.
+// AEAD encrypted messages are decrypted whatever the option.
+func (keyRing *KeyRing) SetEnableModernCrypto(enable bool) {
+	keyRing.lock.Lock()
+	defer keyRing.lock.Unlock()
+	keyRing.enableModernCrypto = enable
+}
+
+// getEncryptionEntities returns the entities of the keyring which have a valid
+// encryption key, restricted to it by withEncryptionKeyOnly, or a
+// MissingEncryptionKeyError, see SetAllowPartialRecipients.
+// Their AEAD support is hidden from the library, unless modern crypto is
+// enabled and AEAD can be used with the encryption config, see
+// SetEnableModernCrypto.
+func (keyRing *KeyRing) getEncryptionEntities(config *packet.Config) (openpgp.EntityList, error) {
+	keyRing.lock.RLock()
+	allowPartialRecipients := keyRing.allowPartialRecipients
+	enableModernCrypto := keyRing.enableModernCrypto
+	keyRing.lock.RUnlock()
+
+	all := keyRing.getEntities()
+	entities := make(openpgp.EntityList, 0, len(all))
+	var missing []string
+	for _, entity := range all {
+		if restricted, ok := withEncryptionKeyOnly(entity, getNow()); ok {
+			entities = append(entities, restricted)
+		} else {
+			missing = append(missing, (&Key{entity: entity}).GetFingerprint())
+		}
+	}
+	if len(missing) > 0 && (!allowPartialRecipients || len(entities) == 0) {
+		return nil, &MissingEncryptionKeyError{Fingerprints: missing}
+	}
+	if !enableModernCrypto || !canUseAEAD(entities, config) {
+		for _, entity := range entities {
+			hideAEADSupport(entity)
+		}
+	}
+	return entities, nil
+}
+
+// canUseAEAD returns whether the library encrypts to the recipients with AEAD
+// parameters they all accept: the library only checks that they support AEAD,
+// and uses the cipher and the AEAD mode of the config.
+func canUseAEAD(recipients openpgp.EntityList, config *packet.Config) bool {
+	for _, entity := range recipients {
+		identity := entity.PrimaryIdentity()
+		if identity == nil || identity.SelfSignature == nil || !identity.SelfSignature.AEAD {
+			return false
+		}
+		if len(intersectCiphers(getRecipientCiphers(entity), []packet.CipherFunction{config.Cipher()})) == 0 {
+			return false
+		}
+		modes := identity.SelfSignature.PreferredAEAD
+		if len(modes) == 0 {
+			// The library assumes that every recipient supports EAX
+			modes = []uint8{uint8(packet.AEADModeEAX)}
+		}
+		accepted := false
+		for _, mode := range modes {
+			accepted = accepted || packet.AEADMode(mode) == config.AEAD().Mode()
+		}
+		if !accepted {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncryption returns the cipher, the use of AEAD and the compression
+// which the library negotiates when encrypting to the recipients returned by
+// getEncryptionEntities with the config, see openpgp.EncryptSplit, for the
+// messages which it can't encrypt itself.
+func negotiateEncryption(
+	recipients openpgp.EntityList, config *packet.Config,
+) (cipher packet.CipherFunction, aead bool, compression packet.CompressionAlgo, err error) {
+	if len(recipients) == 0 {
+		return 0, false, 0, errors.New("gopenpgp: no encryption recipient provided")
+	}
+	ciphers := encryptionCandidateCiphers
+	aead = true
+	compression = config.Compression()
+	for _, entity := range recipients {
+		ciphers = intersectCiphers(ciphers, getRecipientCiphers(entity))
+		var preferredCompression []uint8
+		if identity := entity.PrimaryIdentity(); identity != nil && identity.SelfSignature != nil {
+			aead = aead && identity.SelfSignature.AEAD
+			preferredCompression = identity.SelfSignature.PreferredCompression
+		} else {
+			aead = false
+		}
+		if !acceptsCompression(preferredCompression, compression) {
+			compression = packet.CompressionNone
+		}
+	}
+	if len(ciphers) == 0 {
+		return 0, false, 0, errors.New("gopenpgp: the recipients share no common cipher")
+	}
+
+	// The cipher of the config is used if the recipients accept it
+	cipher = ciphers[0]
+	if len(intersectCiphers(ciphers, []packet.CipherFunction{config.Cipher()})) > 0 {
+		cipher = config.Cipher()
+	}
+	return cipher, aead, compression, nil
+}
+
+// acceptsCompression returns whether a recipient with the preferred
+// compression algorithms accepts the given one, no compression if they are
+// unspecified.
+func acceptsCompression(preferred []uint8, compression packet.CompressionAlgo) bool {
+	if compression == packet.CompressionNone {
+		return true
+	}
+	if compression != packet.CompressionZIP && compression != packet.CompressionZLIB {
+		// Not a candidate of the library
+		return false
+	}
+	for _, algo := range preferred {
+		if packet.CompressionAlgo(algo) == compression {
+			return true
+		}
+	}
+	return false
+}
+
+// isModernCryptoEnabled returns whether encryption may use AEAD, see
+// SetEnableModernCrypto.
+func (keyRing *KeyRing) isModernCryptoEnabled() bool {
+	keyRing.lock.RLock()
+	defer keyRing.lock.RUnlock()
+	return keyRing.enableModernCrypto
+}
+
+// hideAEADSupport clears the AEAD feature flag of the self-signatures of the
+// shallow copy of an entity, without modifying the original entity.
+func hideAEADSupport(entity *openpgp.Entity) {
+	identities := make(map[string]*openpgp.Identity, len(entity.Identities))
+	for name, identity := range entity.Identities {
+		identityCopy := *identity
+		if identity.SelfSignature != nil {
+			selfSignature := *identity.SelfSignature
+			selfSignature.AEAD = false
+			identityCopy.SelfSignature = &selfSignature
+		}
+		identities[name] = &identityCopy
+	}
+	entity.Identities = identities
+}
@@ -0,0 +1,263 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func generateKeyWithCiphers(t *testing.T, ciphers ...packet.CipherFunction) *Key {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	preferred := make([]uint8, len(ciphers))
+	for i, cipher := range ciphers {
+		preferred[i] = uint8(cipher)
+	}
+	for _, identity := range key.entity.Identities {
+		identity.SelfSignature.PreferredSymmetric = preferred
+	}
+	return key
+}
+
+func TestEncryptPerRecipient(t *testing.T) {
+	smartcardKey := generateKeyWithCiphers(t, packet.CipherAES128)
+	strongKey := generateKeyWithCiphers(t, packet.CipherAES256)
+	otherStrongKey := generateKeyWithCiphers(t, packet.CipherAES256, packet.CipherAES128)
+
+	recipients, err := NewKeyRing(smartcardKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, key := range []*Key{strongKey, otherStrongKey} {
+		if err := recipients.AddKey(key); err != nil {
+			t.Fatal("Expected no error while adding key, got:", err)
+		}
+	}
+
+	_, err = recipients.Encrypt(NewPlainMessageFromString(signedPlainText), nil)
+	assert.Error(t, err)
+
+	messages, err := recipients.EncryptPerRecipient(NewPlainMessageFromString(signedPlainText), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting per recipient, got:", err)
+	}
+	assert.Len(t, messages, 3)
+	assert.True(t, messages[smartcardKey.GetFingerprint()] == messages[otherStrongKey.GetFingerprint()])
+	assert.False(t, messages[smartcardKey.GetFingerprint()] == messages[strongKey.GetFingerprint()])
+
+	expectedCiphers := map[*Key]string{
+		smartcardKey:   "aes128",
+		strongKey:      "aes256",
+		otherStrongKey: "aes128",
+	}
+	for key, expectedCipher := range expectedCiphers {
+		decryptionKeyRing, err := NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		decrypted, err := decryptionKeyRing.Decrypt(messages[key.GetFingerprint()], nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting message, got:", err)
+		}
+		assert.Exactly(t, signedPlainText, decrypted.GetString())
+		assert.Exactly(t, expectedCipher, decrypted.GetCipher())
+	}
+}
+
+func TestEncryptPerRecipientSingleMessage(t *testing.T) {
+	key := generateKeyWithCiphers(t, packet.CipherAES256, packet.CipherAES128)
+	otherKey := generateKeyWithCiphers(t, packet.CipherAES128, packet.CipherAES256)
+
+	recipients, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := recipients.AddKey(otherKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	messages, err := recipients.EncryptPerRecipient(NewPlainMessageFromString(signedPlainText), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting per recipient, got:", err)
+	}
+	assert.Len(t, messages, 2)
+	assert.True(t, messages[key.GetFingerprint()] == messages[otherKey.GetFingerprint()])
+
+	decryptionKeyRing, err := NewKeyRing(otherKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	decrypted, err := decryptionKeyRing.Decrypt(messages[otherKey.GetFingerprint()], keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying message, got:", err)
+	}
+	assert.Exactly(t, signedPlainText, decrypted.GetString())
+}
+
+func TestEncryptPerRecipientSignedFailure(t *testing.T) {
+	key := generateKeyWithCiphers(t, packet.CipherAES256)
+	noCommonCipherKey := generateKeyWithCiphers(t, packet.CipherAES192)
+
+	recipients, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := recipients.AddKey(noCommonCipherKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	messages, err := recipients.EncryptPerRecipient(NewPlainMessageFromString(signedPlainText), keyRingTestPrivate)
+	assert.Error(t, err)
+	assert.Nil(t, messages)
+
+	messages, err = recipients.EncryptPerRecipient(NewPlainMessageFromString(signedPlainText), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), noCommonCipherKey.GetFingerprint())
+	assert.Len(t, messages, 1)
+	assert.NotNil(t, messages[key.GetFingerprint()])
+}
+
+// newSignOnlyKeyRing returns a keyring with an encryption key and a sign-only
+// key, and the fingerprint of the sign-only key.
+func newSignOnlyKeyRing(t *testing.T) (*KeyRing, string) {
+	signOnlyKey, err := NewKeyFromArmored(readTestFile("key_ecdsaP256", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	signOnlyKey, err = signOnlyKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	keyRing, err := NewKeyRingFromKeys([]*Key{keyTestRSA, signOnlyKey})
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	return keyRing, signOnlyKey.GetFingerprint()
+}
+
+func TestEncryptMissingEncryptionKey(t *testing.T) {
+	keyRing, fingerprint := newSignOnlyKeyRing(t)
+
+	var missingErr *MissingEncryptionKeyError
+	_, err := keyRing.Encrypt(NewPlainMessageFromString("message"), nil)
+	if !errors.As(err, &missingErr) {
+		t.Fatal("Expected a MissingEncryptionKeyError, got:", err)
+	}
+	assert.Exactly(t, []string{fingerprint}, missingErr.Fingerprints)
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	_, err = keyRing.EncryptSessionKey(sessionKey)
+	if !errors.As(err, &missingErr) {
+		t.Fatal("Expected a MissingEncryptionKeyError, got:", err)
+	}
+
+	_, err = keyRing.NewManualAttachmentProcessor(1024, "attachment", make([]byte, 1024))
+	assert.True(t, errors.As(err, &missingErr))
+}
+
+func TestEncryptAllowPartialRecipients(t *testing.T) {
+	keyRing, _ := newSignOnlyKeyRing(t)
+	keyRing.SetAllowPartialRecipients(true)
+
+	encrypted, err := keyRing.Encrypt(NewPlainMessageFromString("message"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting to partial recipients, got:", err)
+	}
+	keyIDs, ok := encrypted.GetEncryptionKeyIDs()
+	assert.True(t, ok)
+	assert.Exactly(t, []uint64{keyTestRSA.entity.Subkeys[0].PublicKey.KeyId}, keyIDs)
+
+	signOnlyKeyRing, err := NewKeyRing(keyRing.GetKeys()[1])
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	signOnlyKeyRing.SetAllowPartialRecipients(true)
+	_, err = signOnlyKeyRing.Encrypt(NewPlainMessageFromString("message"), nil)
+	var missingErr *MissingEncryptionKeyError
+	assert.True(t, errors.As(err, &missingErr))
+}
+
+func generateKeyWithAEAD(t *testing.T) *Key {
+	key := generateKeyWithCiphers(t, packet.CipherAES256)
+	for _, identity := range key.entity.Identities {
+		identity.SelfSignature.AEAD = true
+		identity.SelfSignature.PreferredAEAD = []uint8{uint8(packet.AEADModeOCB), uint8(packet.AEADModeEAX)}
+	}
+	return key
+}
+
+func TestEncryptWithModernCrypto(t *testing.T) {
+	aeadKey := generateKeyWithAEAD(t)
+	recipients, err := NewKeyRing(aeadKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	for _, test := range []struct {
+		enable     bool
+		protection *Protection
+	}{
+		{false, &Protection{Level: constants.PROTECTION_LEVEL_MDC}},
+		{true, &Protection{Level: constants.PROTECTION_LEVEL_AEAD, AEADMode: constants.AEAD_MODE_EAX, AEADChunkSize: 262144}},
+	} {
+		recipients.SetEnableModernCrypto(test.enable)
+		encrypted, err := recipients.Encrypt(NewPlainMessageFromString(signedPlainText), nil)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		protection, err := encrypted.GetProtection()
+		if err != nil {
+			t.Fatal("Expected no error while reading protection, got:", err)
+		}
+		assert.Exactly(t, test.protection, protection)
+
+		decrypted, err := recipients.Decrypt(encrypted, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, signedPlainText, decrypted.GetString())
+		assert.Exactly(t, test.protection, decrypted.GetProtection())
+	}
+
+	// Falls back to MDC if a recipient doesn't support the AEAD mode
+	ocbKey := generateKeyWithAEAD(t)
+	ocbKey.entity.PrimaryIdentity().SelfSignature.PreferredAEAD = []uint8{uint8(packet.AEADModeOCB)}
+	ocbRecipients, err := NewKeyRing(ocbKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	ocbRecipients.SetEnableModernCrypto(true)
+	encrypted, err := ocbRecipients.Encrypt(NewPlainMessageFromString(signedPlainText), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	protection, err := encrypted.GetProtection()
+	if err != nil {
+		t.Fatal("Expected no error while reading protection, got:", err)
+	}
+	assert.Exactly(t, constants.PROTECTION_LEVEL_MDC, protection.Level)
+
+	// Falls back to MDC if a recipient doesn't support AEAD
+	if err := recipients.AddKey(generateKeyWithCiphers(t, packet.CipherAES256)); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	encrypted, err = recipients.Encrypt(NewPlainMessageFromString(signedPlainText), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	protection, err = encrypted.GetProtection()
+	if err != nil {
+		t.Fatal("Expected no error while reading protection, got:", err)
+	}
+	assert.Exactly(t, constants.PROTECTION_LEVEL_MDC, protection.Level)
+	// The key itself isn't modified
+	assert.True(t, aeadKey.entity.PrimaryIdentity().SelfSignature.AEAD)
+}
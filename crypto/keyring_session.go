@@ -1,46 +1,53 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
-	"strconv"
+	goerrors "errors"
 
 	"github.com/pkg/errors"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 )
 
+// ErrSessionKeyDecryption is returned by AddRecipientToMessage when the
+// session key can't be decrypted with the given keyring.
+var ErrSessionKeyDecryption = goerrors.New("gopenpgp: cannot decrypt session key with the provided private key")
+
+// ErrSessionKeyEncryption is returned by AddRecipientToMessage when the
+// session key can't be encrypted to the additional keyring.
+var ErrSessionKeyEncryption = goerrors.New("gopenpgp: cannot encrypt session key to the additional key")
+
+// ErrPasswordRequired is returned by DecryptSessionKeyFromMessage when the
+// session key of the message is only encrypted with passwords.
+var ErrPasswordRequired = goerrors.New("gopenpgp: password required to decrypt the session key")
+
 // DecryptSessionKey returns the decrypted session key from one or multiple binary encrypted session key packets.
 func (keyRing *KeyRing) DecryptSessionKey(keyPacket []byte) (*SessionKey, error) {
 	var p packet.Packet
-	var ek *packet.EncryptedKey
-
 	var err error
 	var hasPacket = false
-	var decryptErr error
 
-	keyReader := bytes.NewReader(keyPacket)
-	packets := packet.NewReader(keyReader)
+	keyReader := bufio.NewReader(bytes.NewReader(keyPacket))
+	packets := newPacketReader(keyReader)
+	var skipper keyPacketSkipper
 
 Loop:
 	for {
-		if p, err = packets.Next(); err != nil {
+		p, err = skipper.next(packets, keyReader)
+		if errors.Is(err, ErrTooManyPackets) {
+			return nil, err
+		}
+		if err != nil {
 			break
 		}
 
 		switch p := p.(type) {
 		case *packet.EncryptedKey:
 			hasPacket = true
-			ek = p
-
-			for _, key := range keyRing.entities.DecryptionKeys() {
-				priv := key.PrivateKey
-				if priv.Encrypted {
-					continue
-				}
-
-				if decryptErr = ek.Decrypt(priv, nil); decryptErr == nil {
-					break Loop
-				}
+			if keyRing.decryptEncryptedKey(p, &skipper) {
+				return newSessionKeyFromEncrypted(p)
 			}
 
 		case *packet.SymmetricallyEncrypted,
@@ -54,36 +61,70 @@ Loop:
 		}
 	}
 
-	if !hasPacket {
+	if !hasPacket && len(skipper.skipped) == 0 {
 		return nil, errors.Wrap(err, "gopenpgp: couldn't find a session key packet")
 	}
+	return nil, skipper.err()
+}
 
-	if decryptErr != nil {
-		return nil, errors.Wrap(decryptErr, "gopenpgp: error in decrypting")
+// decryptEncryptedKey decrypts ek with the first unlocked decryption key of
+// keyRing which can decrypt it, or records why it is skipped.
+func (keyRing *KeyRing) decryptEncryptedKey(ek *packet.EncryptedKey, skipper *keyPacketSkipper) bool {
+	reason := "no matching decryption key"
+	var invalidKey error
+	for _, key := range keyRing.getEntities().DecryptionKeys() {
+		priv := key.PrivateKey
+		matching := ek.KeyId == 0 || ek.KeyId == priv.KeyId
+		if priv.Encrypted {
+			if matching {
+				reason = "the decryption key is locked"
+			}
+			continue
+		}
+		if err := checkECDHEphemeralKey(ek, priv); err != nil {
+			if matching {
+				invalidKey = err
+			}
+			continue
+		}
+		err := ek.Decrypt(priv, nil)
+		if err == nil {
+			return true
+		}
+		if matching {
+			reason = err.Error()
+		}
 	}
-
-	if ek == nil || ek.Key == nil {
-		return nil, errors.New("gopenpgp: unable to decrypt session key: no valid decryption key")
+	if invalidKey != nil {
+		skipper.skipInvalid(ek, invalidKey)
+		return false
 	}
-
-	return newSessionKeyFromEncrypted(ek)
+	skipper.skip(ek, reason)
+	return false
 }
 
 // EncryptSessionKey encrypts the session key with the unarmored
 // publicKey and returns a binary public-key encrypted session key packet.
 func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
-	outbuf := &bytes.Buffer{}
 	cf, err := sk.GetCipherFunc()
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt session key")
 	}
 
-	pubKeys := make([]*packet.PublicKey, 0, len(keyRing.entities))
-	for _, e := range keyRing.entities {
-		encryptionKey, ok := e.EncryptionKey(getNow())
-		if !ok {
-			return nil, errors.New("gopenpgp: encryption key is unavailable for key id " + strconv.FormatUint(e.PrimaryKey.KeyId, 16))
-		}
+	recipients, err := keyRing.getEncryptionEntities(nil)
+	if err != nil {
+		return nil, err
+	}
+	return encryptSessionKeyTo(recipients, sk, cf)
+}
+
+// encryptSessionKeyTo encrypts the session key to the encryption keys of the
+// recipients, and returns the public-key encrypted session key packets.
+func encryptSessionKeyTo(recipients openpgp.EntityList, sk *SessionKey, cf packet.CipherFunction) ([]byte, error) {
+	outbuf := &bytes.Buffer{}
+	pubKeys := make([]*packet.PublicKey, 0, len(recipients))
+	for _, e := range recipients {
+		encryptionKey, _ := getEncryptionKey(e, getNow())
 		pubKeys = append(pubKeys, encryptionKey.PublicKey)
 	}
 	if len(pubKeys) == 0 {
@@ -91,9 +132,120 @@ func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
 	}
 
 	for _, pub := range pubKeys {
-		if err := packet.SerializeEncryptedKey(outbuf, pub, cf, sk.Key, nil); err != nil {
+		if err := packet.SerializeEncryptedKey(outbuf, pub, cf, sk.Key, &packet.Config{Rand: getRandom()}); err != nil {
 			return nil, errors.Wrap(err, "gopenpgp: cannot set key")
 		}
 	}
 	return outbuf.Bytes(), nil
 }
+
+// AddRecipientToMessage decrypts the session key of the message with the
+// keyring and returns a copy of the message where the session key is
+// additionally encrypted to additionalKeyRing. The message data is not
+// decrypted. Errors wrap either ErrSessionKeyDecryption or
+// ErrSessionKeyEncryption.
+func (keyRing *KeyRing) AddRecipientToMessage(message *PGPMessage, additionalKeyRing *KeyRing) (*PGPMessage, error) {
+	keyPackets, dataPackets, err := splitKeyPackets(message.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	sessionKey, err := keyRing.DecryptSessionKey(keyPackets)
+	if err != nil {
+		return nil, errors.Wrap(ErrSessionKeyDecryption, err.Error())
+	}
+	defer sessionKey.Clear()
+
+	additionalKeyPacket, err := additionalKeyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		return nil, errors.Wrap(ErrSessionKeyEncryption, err.Error())
+	}
+
+	data := make([]byte, 0, len(keyPackets)+len(additionalKeyPacket)+len(dataPackets))
+	data = append(data, keyPackets...)
+	data = append(data, additionalKeyPacket...)
+	data = append(data, dataPackets...)
+	return &PGPMessage{Data: data}, nil
+}
+
+// DecryptSessionKeyFromMessage returns the decrypted session key of a full
+// message, found in its public-key encrypted session key packets.
+// The message data is not decrypted. If the session key is only encrypted
+// with passwords, ErrPasswordRequired is returned.
+func (keyRing *KeyRing) DecryptSessionKeyFromMessage(message *PGPMessage) (*SessionKey, error) {
+	keyPackets, _, err := splitKeyPackets(message.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	hasEncryptedKey := false
+	for offset := 0; offset < len(keyPackets); {
+		tag, length, err := readPacketHeader(keyPackets[offset:])
+		if err != nil {
+			return nil, err
+		}
+		if tag == packetTagEncryptedKey {
+			hasEncryptedKey = true
+			break
+		}
+		offset += length
+	}
+	if !hasEncryptedKey {
+		return nil, ErrPasswordRequired
+	}
+
+	return keyRing.DecryptSessionKey(keyPackets)
+}
+
+// SplitKeyPackets splits the leading encrypted session key packets of the
+// message from its data packet, without parsing the packets. If the message
+// only contains a data packet, the key packet of the split message is empty.
+func (msg *PGPMessage) SplitKeyPackets() (*PGPSplitMessage, error) {
+	tag, _, err := readPacketHeader(msg.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if tag != packetTagEncryptedKey && tag != packetTagSymmetricKeyEncrypted {
+		return NewPGPSplitMessage(nil, msg.GetBinary()), nil
+	}
+	keyPackets, dataPacket, err := splitKeyPackets(msg.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if len(dataPacket) == 0 {
+		return nil, errors.New("gopenpgp: the message does not contain a data packet")
+	}
+	return NewPGPSplitMessage(keyPackets, dataPacket), nil
+}
+
+// splitKeyPackets splits the leading encrypted session key packets of a
+// binary message from the rest of the message, without parsing the packets.
+func splitKeyPackets(data []byte) (keyPackets, rest []byte, err error) {
+	offset := 0
+	var counter packetCounter
+	for offset < len(data) {
+		tag, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag != packetTagEncryptedKey && tag != packetTagSymmetricKeyEncrypted {
+			break
+		}
+		if err := counter.add(true); err != nil {
+			return nil, nil, err
+		}
+		if length > len(data)-offset {
+			return nil, nil, errors.New("gopenpgp: truncated key packet")
+		}
+		offset += length
+	}
+	if offset == 0 {
+		return nil, nil, errors.New("gopenpgp: packets don't include an encrypted key packet")
+	}
+	return data[:offset], data[offset:], nil
+}
+
+const (
+	packetTagEncryptedKey          = 1
+	packetTagSymmetricKeyEncrypted = 3
+)
@@ -3,8 +3,8 @@ package crypto
 import (
 	"bytes"
 	"crypto"
+	"hash"
 	"io"
-	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
@@ -27,7 +27,8 @@ type WriteCloser interface {
 type PlainMessageMetadata struct {
 	IsBinary bool
 	Filename string
-	ModTime  int64
+	// ModTime is the modification time as a unix timestamp, or 0 if unset.
+	ModTime int64
 }
 
 func NewPlainMessageMetadata(isBinary bool, filename string, modTime int64) *PlainMessageMetadata {
@@ -42,7 +43,7 @@ func (keyRing *KeyRing) EncryptStream(
 	plainMessageMetadata *PlainMessageMetadata,
 	signKeyRing *KeyRing,
 ) (plainMessageWriter WriteCloser, err error) {
-	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator()}
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator(), Rand: getRandom()}
 
 	if plainMessageMetadata == nil {
 		// Use sensible default metadata
@@ -53,10 +54,15 @@ func (keyRing *KeyRing) EncryptStream(
 		}
 	}
 
+	modTime, err := checkModTime(plainMessageMetadata.ModTime)
+	if err != nil {
+		return nil, err
+	}
+
 	hints := &openpgp.FileHints{
 		FileName: plainMessageMetadata.Filename,
 		IsBinary: plainMessageMetadata.IsBinary,
-		ModTime:  time.Unix(plainMessageMetadata.ModTime, 0),
+		ModTime:  getModTime(modTime),
 	}
 
 	plainMessageWriter, err = asymmetricEncryptStream(hints, pgpMessageWriter, pgpMessageWriter, keyRing, signKeyRing, config)
@@ -107,7 +113,7 @@ func (keyRing *KeyRing) EncryptSplitStream(
 	plainMessageMetadata *PlainMessageMetadata,
 	signKeyRing *KeyRing,
 ) (*EncryptSplitResult, error) {
-	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator()}
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Time: getTimeGenerator(), Rand: getRandom()}
 
 	if plainMessageMetadata == nil {
 		// Use sensible default metadata
@@ -118,10 +124,15 @@ func (keyRing *KeyRing) EncryptSplitStream(
 		}
 	}
 
+	modTime, err := checkModTime(plainMessageMetadata.ModTime)
+	if err != nil {
+		return nil, err
+	}
+
 	hints := &openpgp.FileHints{
 		FileName: plainMessageMetadata.Filename,
 		IsBinary: plainMessageMetadata.IsBinary,
-		ModTime:  time.Unix(plainMessageMetadata.ModTime, 0),
+		ModTime:  getModTime(modTime),
 	}
 
 	var keyPacketBuf bytes.Buffer
@@ -142,6 +153,13 @@ type PlainMessageReader struct {
 	verifyKeyRing *KeyRing
 	verifyTime    int64
 	readAll       bool
+	protection    *Protection
+	// Whether the message was decrypted with an expired or revoked key
+	decryptionKeyExpired bool
+	// Set with SetExpectedHash
+	expectedHash *ExpectedHash
+	contentHash  hash.Hash
+	readStarted  bool
 }
 
 // GetMetadata returns the metadata of the decrypted message.
@@ -155,14 +173,40 @@ func (msg *PlainMessageReader) GetMetadata() *PlainMessageMetadata {
 
 // Read is used to access the message decrypted data.
 // Makes PlainMessageReader implement the Reader interface.
+// If an expected hash is set, the last Read returns ErrContentHashMismatch
+// instead of io.EOF if the data doesn't match it.
 func (msg *PlainMessageReader) Read(b []byte) (n int, err error) {
+	msg.readStarted = true
 	n, err = msg.details.UnverifiedBody.Read(b)
+	if msg.contentHash != nil {
+		_, _ = msg.contentHash.Write(b[:n])
+	}
 	if errors.Is(err, io.EOF) {
 		msg.readAll = true
+		if msg.contentHash != nil {
+			if hashErr := msg.expectedHash.matches(msg.contentHash); hashErr != nil {
+				err = hashErr
+			}
+		}
 	}
 	return
 }
 
+// SetExpectedHash sets the digest that the decrypted data must have, checked
+// while it is read, see Read. It must be called before reading.
+func (msg *PlainMessageReader) SetExpectedHash(expected *ExpectedHash) error {
+	if msg.readStarted {
+		return errors.New("gopenpgp: the expected hash must be set before reading")
+	}
+	contentHash, err := expected.newHash()
+	if err != nil {
+		return err
+	}
+	msg.expectedHash = expected
+	msg.contentHash = contentHash
+	return nil
+}
+
 // VerifySignature is used to verify that the signature is valid.
 // This method needs to be called once all the data has been read.
 // It will return an error if the signature is invalid
@@ -173,7 +217,7 @@ func (msg *PlainMessageReader) VerifySignature() (err error) {
 	}
 	if msg.verifyKeyRing != nil {
 		processSignatureExpiration(msg.details, msg.verifyTime)
-		err = verifyDetailsSignature(msg.details, msg.verifyKeyRing)
+		err = verifyDetailsSignature(msg.details, msg.verifyKeyRing, getDefaultSignerPolicy())
 	} else {
 		err = errors.New("gopenpgp: no verify keyring was provided before decryption")
 	}
@@ -184,13 +228,15 @@ func (msg *PlainMessageReader) VerifySignature() (err error) {
 // It takes a reader for the message data
 // and returns a PlainMessageReader for the plaintext data.
 // If verifyKeyRing is not nil, PlainMessageReader.VerifySignature() will
-// verify the embedded signature with the given key ring and verification time.
+// verify the embedded signature with the given key ring and verification time,
+// and report constants.SIGNATURE_NO_VERIFIER if the key ring is empty.
 func (keyRing *KeyRing) DecryptStream(
 	message Reader,
 	verifyKeyRing *KeyRing,
 	verifyTime int64,
 ) (plainMessage *PlainMessageReader, err error) {
-	messageDetails, err := asymmetricDecryptStream(
+	verifyTime = ResolveVerifyTime(verifyTime)
+	messageDetails, encryption, err := asymmetricDecryptStream(
 		message,
 		keyRing,
 		verifyKeyRing,
@@ -201,10 +247,11 @@ func (keyRing *KeyRing) DecryptStream(
 	}
 
 	return &PlainMessageReader{
-		messageDetails,
-		verifyKeyRing,
-		verifyTime,
-		false,
+		details:              messageDetails,
+		verifyKeyRing:        verifyKeyRing,
+		verifyTime:           verifyTime,
+		protection:           encryption.protection,
+		decryptionKeyExpired: encryption.decryptionKeyExpired,
 	}, err
 }
 
@@ -236,7 +283,7 @@ func (keyRing *KeyRing) SignDetachedStream(message Reader) (*PGPSignature, error
 		return nil, err
 	}
 
-	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator()}
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator(), Rand: getRandom()}
 	var outBuf bytes.Buffer
 	// sign bin
 	if err := openpgp.DetachSign(&outBuf, signEntity, message, config); err != nil {
@@ -254,10 +301,11 @@ func (keyRing *KeyRing) VerifyDetachedStream(
 	verifyTime int64,
 ) error {
 	return verifySignature(
-		keyRing.entities,
+		keyRing.getEntities(),
 		message,
 		signature.GetBinary(),
 		verifyTime,
+		getDefaultSignerPolicy(),
 	)
 }
 
@@ -4,11 +4,13 @@ import (
 	"crypto/ed25519"
 	"crypto/rsa"
 	"errors"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
 	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
@@ -147,6 +149,64 @@ func TestMultipleKeyRing(t *testing.T) {
 	assert.Exactly(t, 1, singleKeyRing.CountDecryptionEntities())
 }
 
+func TestKeyRingConcurrentUse(t *testing.T) {
+	keyRing, err := NewKeyRing(keyRingTestPrivate.GetKeys()[0])
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	encrypted, err := keyRing.Encrypt(NewPlainMessageFromString("concurrent"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	const additions = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if _, err := keyRing.Decrypt(encrypted, nil, 0); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < additions; i++ {
+			if err := keyRing.AddKey(keyTestRSA); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < additions; i++ {
+			keys := keyRing.GetKeys()
+			if len(keys) < keyRing.CountEntities()-additions {
+				errs <- errors.New("keyring shrunk")
+				return
+			}
+			keys[0] = nil
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("Expected no error while using the keyring concurrently, got:", err)
+	}
+
+	// Adding the same key again merges it
+	assert.Exactly(t, 2, keyRing.CountEntities())
+	// The keys returned don't alias the keyring
+	assert.NotNil(t, keyRing.GetKeys()[0])
+}
+
 func TestClearPrivateKey(t *testing.T) {
 	keyRingCopy, err := keyRingTestMultiple.Copy()
 	if err != nil {
@@ -225,6 +285,39 @@ func TestEncryptedDetachedSignature(t *testing.T) {
 	}
 }
 
+func TestEncryptedDetachedSignatureWithSessionKey(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	message := NewPlainMessageFromString("Hello World!")
+	encSign, err := keyRingTestPrivate.SignDetachedEncryptedWithSessionKey(message, sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encryptedSigning, got:", err)
+	}
+	err = keyRingTestPublic.VerifyDetachedEncryptedWithSessionKey(message, encSign, sessionKey, 0)
+	if err != nil {
+		t.Fatal("Expected no error while verifying encSignature, got:", err)
+	}
+
+	// The data packet can be joined with a key packet and verified with VerifyDetachedEncrypted
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	encMessage := NewPGPSplitMessage(keyPacket, encSign).GetPGPMessage()
+	err = keyRingTestPublic.VerifyDetachedEncrypted(message, encMessage, keyRingTestPrivate, 0)
+	if err != nil {
+		t.Fatal("Expected no error while verifying encSignature, got:", err)
+	}
+
+	message2 := NewPlainMessageFromString("Bye!")
+	err = keyRingTestPublic.VerifyDetachedEncryptedWithSessionKey(message2, encSign, sessionKey, 0)
+	if err == nil {
+		t.Fatal("Expected an error while verifying bad encSignature, got nil")
+	}
+}
+
 func TestKeyringCapabilities(t *testing.T) {
 	assert.True(t, keyRingTestPrivate.CanVerify())
 	assert.True(t, keyRingTestPrivate.CanEncrypt())
@@ -273,3 +366,190 @@ func TestVerificationTime(t *testing.T) {
 		t.Fatalf("Got an error while decrypting %v", err)
 	}
 }
+
+func TestAddRecipientToMessage(t *testing.T) {
+	message := NewPlainMessageFromString("plain text")
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	additionalKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = additionalKeyRing.Decrypt(ciphertext, nil, 0)
+	assert.Error(t, err)
+
+	shared, err := keyRingTestPrivate.AddRecipientToMessage(ciphertext, additionalKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while adding recipient, got:", err)
+	}
+	for _, keyRing := range []*KeyRing{keyRingTestPrivate, additionalKeyRing} {
+		decrypted, err := keyRing.Decrypt(shared, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+
+	_, err = additionalKeyRing.AddRecipientToMessage(ciphertext, keyRingTestPublic)
+	assert.True(t, errors.Is(err, ErrSessionKeyDecryption))
+	_, err = keyRingTestPrivate.AddRecipientToMessage(ciphertext, &KeyRing{})
+	assert.True(t, errors.Is(err, ErrSessionKeyEncryption))
+}
+
+func TestDecryptSessionKeyFromMessage(t *testing.T) {
+	message, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("session key message"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKeyFromMessage(message)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	split, err := message.SeparateKeyAndData(len(message.GetBinary()), -1)
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	decrypted, err := sessionKey.Decrypt(split.GetBinaryDataPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, "session key message", decrypted.GetString())
+
+	passwordMessage, err := EncryptMessageWithPassword(NewPlainMessageFromString("session key message"), []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with password, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptSessionKeyFromMessage(passwordMessage)
+	assert.True(t, errors.Is(err, ErrPasswordRequired))
+
+	_, err = keyRingTestPrivate.DecryptSessionKeyFromMessage(NewPGPMessage(split.GetBinaryDataPacket()))
+	assert.Error(t, err)
+}
+
+func TestSplitKeyPackets(t *testing.T) {
+	message, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("split message"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	split, err := message.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	assert.NotEmpty(t, split.GetBinaryKeyPacket())
+	assert.Exactly(t, message.GetBinary(), split.GetPGPMessage().GetBinary())
+
+	dataOnly, err := NewPGPMessage(split.GetBinaryDataPacket()).SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting data packet, got:", err)
+	}
+	assert.Empty(t, dataOnly.GetBinaryKeyPacket())
+	assert.Exactly(t, split.GetBinaryDataPacket(), dataOnly.GetBinaryDataPacket())
+}
+
+// getPacketBodies returns the tags and bodies of the packets of data, which
+// may use old or new format headers.
+func getPacketBodies(t *testing.T, data []byte) (tags []byte, bodies [][]byte) {
+	for len(data) > 0 {
+		tag, length, err := readPacketHeader(data)
+		if err != nil || length > len(data) {
+			t.Fatal("Expected no error while reading packet header, got:", err)
+		}
+		body := getPacketBody(data[:length])
+		if data[0]&0x40 == 0 {
+			// Old format packet
+			body = data[1+1<<(data[0]&0x03) : length]
+		}
+		tags = append(tags, tag)
+		bodies = append(bodies, body)
+		data = data[length:]
+	}
+	return tags, bodies
+}
+
+func TestKeyRingToPublic(t *testing.T) {
+	keyRing, err := NewKeyRingFromKeys([]*Key{keyTestEC, keyRingTestPrivate.GetKeys()[0]})
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	publicKeyRing, err := keyRing.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public keyring, got:", err)
+	}
+	assert.Exactly(t, 2, publicKeyRing.CountEntities())
+	assert.Exactly(t, 0, publicKeyRing.CountDecryptionEntities())
+	for i, publicKey := range publicKeyRing.GetKeys() {
+		key := keyRing.GetKeys()[i]
+		assert.Exactly(t, key.GetFingerprint(), publicKey.GetFingerprint())
+		assert.False(t, publicKey.IsPrivate())
+		assert.True(t, key.IsPrivate())
+		assert.False(t, key.entity == publicKey.entity)
+		assert.False(t, key.entity.PrimaryKey == publicKey.entity.PrimaryKey)
+	}
+
+	// Same packets as exported by GnuPG, which writes old format headers
+	serialized, err := publicKeyRing.GetKeys()[1].Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing public key, got:", err)
+	}
+	exported, err := armor.Unarmor(readTestFile("keyring_publicKeyGnuPG", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring exported key, got:", err)
+	}
+	tags, bodies := getPacketBodies(t, serialized)
+	exportedTags, exportedBodies := getPacketBodies(t, exported)
+	assert.Exactly(t, exportedTags, tags)
+	assert.Exactly(t, exportedBodies, bodies)
+}
+
+func TestEncryptSignedDetached(t *testing.T) {
+	message := NewPlainMessageFromString("Hello World!")
+	encMessage, encSignature, err := keyRingTestPublic.EncryptSignedDetached(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with detached signature, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.DecryptSignedDetached(encMessage, encSignature, keyRingTestPublic, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with detached signature, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	// The signature shares the session key of the message, without key packet
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(encMessage.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	err = keyRingTestPublic.VerifyDetachedEncryptedWithSessionKey(message, encSignature.GetBinary(), sessionKey, 0)
+	if err != nil {
+		t.Fatal("Expected no error while verifying encSignature, got:", err)
+	}
+	joined := NewPGPSplitMessage(encMessage.GetBinaryKeyPacket(), encSignature.GetBinary()).GetPGPMessage()
+	err = keyRingTestPublic.VerifyDetachedEncrypted(message, joined, keyRingTestPrivate, 0)
+	if err != nil {
+		t.Fatal("Expected no error while verifying encSignature, got:", err)
+	}
+
+	// A signature of another message doesn't verify
+	_, otherSignature, err := keyRingTestPublic.EncryptSignedDetached(NewPlainMessageFromString("Bye!"), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with detached signature, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.DecryptSignedDetached(encMessage, otherSignature, keyRingTestPublic, 0)
+	assert.Error(t, err)
+	assert.Nil(t, decrypted)
+
+	// A signature encrypted with the session key which doesn't verify
+	otherSig, err := keyRingTestPrivate.SignDetachedEncryptedWithSessionKey(NewPlainMessageFromString("Bye!"), sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encryptedSigning, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.DecryptSignedDetached(encMessage, NewPGPMessage(otherSig), keyRingTestPublic, 0)
+	var sigErr SignatureVerificationError
+	assert.True(t, errors.As(err, &sigErr), err)
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+}
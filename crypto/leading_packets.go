@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"bufio"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	packetTagMarker  = 10
+	packetTagPadding = 21
+)
+
+// maxLeadingPackets bounds the number of marker and padding packets skipped
+// before a data packet.
+const maxLeadingPackets = 8
+
+// skipLeadingPackets discards the marker packets, written first by older
+// implementations, and the padding packets preceding the data packet read
+// from r, so that the next packet read is the data packet.
+func skipLeadingPackets(r *bufio.Reader) error {
+	for i := 0; i < maxLeadingPackets; i++ {
+		// The longest header with a definite length has 6 bytes
+		header, _ := r.Peek(6)
+		tag, length, err := readPacketHeader(header)
+		if err != nil || (tag != packetTagMarker && tag != packetTagPadding) {
+			// Any invalid packet is reported when reading the data packet
+			return nil
+		}
+		if !hasDefiniteLength(header) {
+			return errors.New("gopenpgp: invalid length of leading packet")
+		}
+		if _, err := r.Discard(length); err != nil {
+			return errors.Wrap(err, "gopenpgp: unable to read leading packet")
+		}
+	}
+	return errors.New("gopenpgp: too many packets before the data packet")
+}
+
+// hasDefiniteLength returns whether the packet header at the start of data
+// gives the length of the packet, rather than a partial or an indeterminate
+// length.
+func hasDefiniteLength(data []byte) bool {
+	if data[0]&0x40 == 0 {
+		return data[0]&0x03 != 3
+	}
+	return data[1] < 224 || data[1] == 255
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+var markerPacket = []byte{0xa8, 0x03, 'P', 'G', 'P'}
+
+func TestSessionKeyDecryptLeadingPackets(t *testing.T) {
+	sessionKey := NewSessionKeyFromToken([]byte("leading packets session key 32b!"), constants.AES256)
+
+	for file, plaintext := range map[string]string{
+		"sessionkey_markerPacket":  "leading marker packet",
+		"sessionkey_paddingPacket": "trailing padding packet",
+	} {
+		message, err := NewPGPMessageFromArmored(readTestFile(file, false))
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring, got:", err)
+		}
+		decrypted, err := sessionKey.Decrypt(message.GetBinary())
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, plaintext, decrypted.GetString())
+
+		reader, err := sessionKey.DecryptStream(bytes.NewReader(message.GetBinary()), nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting the stream, got:", err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal("Expected no error when reading the stream, got:", err)
+		}
+		assert.Exactly(t, plaintext, string(data))
+	}
+}
+
+func TestSessionKeyDecryptLeadingPacketsProtection(t *testing.T) {
+	sessionKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+	message, err := NewPGPMessageFromArmored(readTestFile("message_protectionAEAD", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, dataPacket := splitProtectionTestMessage(t, message)
+
+	// A padding packet and a marker packet before the data packet
+	padding := []byte{0xd5, 0x04, 0x00, 0x01, 0x02, 0x03}
+	leading := append(append(append([]byte{}, padding...), markerPacket...), dataPacket...)
+	decrypted, err := sessionKey.Decrypt(leading)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, "AEAD protection", decrypted.GetString())
+	assert.Exactly(t, &Protection{
+		Level:         constants.PROTECTION_LEVEL_AEAD,
+		AEADMode:      constants.AEAD_MODE_OCB,
+		AEADChunkSize: 4096,
+	}, decrypted.GetProtection())
+
+	tooMany := append(bytes.Repeat(markerPacket, maxLeadingPackets+1), dataPacket...)
+	_, err = sessionKey.Decrypt(tooMany)
+	assert.Error(t, err)
+
+	_, err = sessionKey.Decrypt(markerPacket)
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+const packetTagLiteralData = 11
+
+// getLiteralFormat returns the format of decrypted literal data to be stored
+// in a PlainMessage, 0 if it is derived from the binary flag.
+func getLiteralFormat(format uint8) byte {
+	if string(format) == constants.LITERAL_FORMAT_UTF8 {
+		return format
+	}
+	return 0
+}
+
+// getFormatFromBinary returns the format of literal data from its binary flag.
+func getFormatFromBinary(isBinary bool) string {
+	if isBinary {
+		return constants.LITERAL_FORMAT_BINARY
+	}
+	return constants.LITERAL_FORMAT_TEXT
+}
+
+// withLiteralFormat wraps w, to which the library writes a literal data packet
+// with the binary or text format, so that the packet has the given format.
+func withLiteralFormat(w io.WriteCloser, format string) io.WriteCloser {
+	if format != constants.LITERAL_FORMAT_UTF8 {
+		return w
+	}
+	return &literalFormatWriter{w: w, format: format[0]}
+}
+
+// literalFormatWriter replaces the format byte of the first literal data
+// packet written to it. The packets before it, e.g. one-pass signatures,
+// must have a new format header with a fixed length, as written by the
+// library.
+type literalFormatWriter struct {
+	w      io.WriteCloser
+	format byte
+
+	done bool
+	// The header being parsed, the number of body bytes left to skip,
+	// and whether the next byte is the format of the literal data
+	header    []byte
+	skip      int64
+	patchNext bool
+}
+
+func (l *literalFormatWriter) Write(p []byte) (n int, err error) {
+	for i := 0; i < len(p) && !l.done; i++ {
+		switch {
+		case l.patchNext:
+			p = append([]byte{}, p...)
+			p[i] = l.format
+			l.done = true
+		case l.skip > 0:
+			skip := int64(len(p) - i)
+			if skip > l.skip {
+				skip = l.skip
+			}
+			l.skip -= skip
+			i += int(skip) - 1
+		default:
+			l.header = append(l.header, p[i])
+			if l.header[0]&0xc0 != 0xc0 {
+				// Not a new format header, leave the data untouched
+				l.done = true
+				break
+			}
+			if len(l.header) == 1 {
+				break
+			}
+			length, complete := parseNewLength(l.header[1:])
+			switch {
+			case !complete:
+			case l.header[0]&0x3f == packetTagLiteralData:
+				l.patchNext = true
+			case length < 0:
+				// Partial length before the literal data
+				l.done = true
+			default:
+				l.header = l.header[:0]
+				l.skip = length
+			}
+		}
+	}
+	return l.w.Write(p)
+}
+
+func (l *literalFormatWriter) Close() error {
+	return l.w.Close()
+}
+
+// parseNewLength parses the length of a new format packet header, -1 for a
+// partial length.
+func parseNewLength(b []byte) (length int64, complete bool) {
+	switch {
+	case b[0] < 192:
+		return int64(b[0]), true
+	case b[0] < 224:
+		if len(b) < 2 {
+			return 0, false
+		}
+		return (int64(b[0])-192)<<8 + int64(b[1]) + 192, true
+	case b[0] < 255:
+		return -1, true
+	default:
+		if len(b) < 5 {
+			return 0, false
+		}
+		return int64(binary.BigEndian.Uint32(b[1:])), true
+	}
+}
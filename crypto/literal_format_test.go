@@ -0,0 +1,202 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestPlainMessageFormat(t *testing.T) {
+	assert.Exactly(t, constants.LITERAL_FORMAT_BINARY, NewPlainMessage([]byte("data")).GetFormat())
+	assert.Exactly(t, constants.LITERAL_FORMAT_TEXT, NewPlainMessageFromString("text").GetFormat())
+
+	message := NewPlainMessage([]byte("data"))
+	if err := message.SetFormat(constants.LITERAL_FORMAT_UTF8); err != nil {
+		t.Fatal("Expected no error while setting format, got:", err)
+	}
+	assert.True(t, message.IsText())
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, message.GetFormat())
+
+	// TextType takes precedence if it doesn't match the format anymore
+	message.TextType = false
+	assert.Exactly(t, constants.LITERAL_FORMAT_BINARY, message.GetFormat())
+
+	assert.Error(t, message.SetFormat("x"))
+	assert.Error(t, message.SetFormat(""))
+}
+
+func TestLiteralFormatUTF8RoundTrip(t *testing.T) {
+	message := NewPlainMessageFromString("utf-8 text: éè")
+	message.Filename = "utf8.txt"
+	if err := message.SetFormat(constants.LITERAL_FORMAT_UTF8); err != nil {
+		t.Fatal("Expected no error while setting format, got:", err)
+	}
+
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+	assert.Exactly(t, "utf8.txt", decrypted.GetFilename())
+
+	// Re-encrypting the decrypted message keeps the format
+	encrypted, err = keyRingTestPublic.EncryptWithCompression(decrypted, nil, packet.CipherAES128, packet.CompressionZLIB)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(encrypted, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+
+	dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with session key, got:", err)
+	}
+	decrypted, err = testSessionKey.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+
+	encrypted, err = EncryptMessageWithPassword(message, testSymmetricKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with password, got:", err)
+	}
+	decrypted, err = DecryptMessageWithPassword(encrypted, testSymmetricKey)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with password, got:", err)
+	}
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+}
+
+func TestLiteralFormatUTF8Negotiation(t *testing.T) {
+	message := NewPlainMessageFromString("utf-8 text: éè")
+	if err := message.SetFormat(constants.LITERAL_FORMAT_UTF8); err != nil {
+		t.Fatal("Expected no error while setting format, got:", err)
+	}
+
+	// The recipient doesn't accept AES-256, the cipher of Encrypt
+	recipients, err := NewKeyRing(generateKeyWithCiphers(t, packet.CipherAES128))
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	encrypted, err := recipients.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	split, err := encrypted.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting, got:", err)
+	}
+	sk, err := recipients.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	assert.Exactly(t, constants.AES128, sk.Algo)
+	decrypted, err := recipients.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	// AEAD is used as for the other formats
+	aeadRecipients, err := NewKeyRing(generateKeyWithAEAD(t))
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, test := range []struct {
+		enable bool
+		level  int
+	}{
+		{false, constants.PROTECTION_LEVEL_MDC},
+		{true, constants.PROTECTION_LEVEL_AEAD},
+	} {
+		aeadRecipients.SetEnableModernCrypto(test.enable)
+		encrypted, err := aeadRecipients.Encrypt(message, nil)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		protection, err := encrypted.GetProtection()
+		if err != nil {
+			t.Fatal("Expected no error while reading protection, got:", err)
+		}
+		assert.Exactly(t, test.level, protection.Level)
+
+		decrypted, err := aeadRecipients.Decrypt(encrypted, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, constants.LITERAL_FORMAT_UTF8, decrypted.GetFormat())
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+}
+
+func TestLiteralFormatTextSignatures(t *testing.T) {
+	// A text signature is valid for both the 't' and 'u' formats
+	message := NewPlainMessageFromString("signed\ntext")
+	if err := message.SetFormat(constants.LITERAL_FORMAT_UTF8); err != nil {
+		t.Fatal("Expected no error while setting format, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	textMessage := NewPlainMessageFromString("signed\ntext")
+	if err = keyRingTestPublic.VerifyDetached(textMessage, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+}
+
+func TestLiteralFormatWriter(t *testing.T) {
+	// A one-pass signature packet, then a literal data packet written byte by byte
+	var serialized bytes.Buffer
+	ops := &packet.OnePassSignature{SigType: packet.SigTypeText, Hash: crypto.SHA256, PubKeyAlgo: packet.PubKeyAlgoRSA, IsLast: true}
+	if err := ops.Serialize(&serialized); err != nil {
+		t.Fatal("Expected no error while serializing, got:", err)
+	}
+	literal, err := packet.SerializeLiteral(nopWriteCloser{&serialized}, false, "name", 0)
+	if err != nil {
+		t.Fatal("Expected no error while serializing, got:", err)
+	}
+	_, _ = literal.Write([]byte("data"))
+	_ = literal.Close()
+
+	var patched bytes.Buffer
+	w := withLiteralFormat(nopWriteCloser{&patched}, constants.LITERAL_FORMAT_UTF8)
+	for _, b := range serialized.Bytes() {
+		_, _ = w.Write([]byte{b})
+	}
+
+	packets := packet.NewReader(&patched)
+	_, err = packets.Next()
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	p, err := packets.Next()
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	literalData, ok := p.(*packet.LiteralData)
+	assert.True(t, ok)
+	assert.Exactly(t, uint8('u'), literalData.Format)
+	assert.Exactly(t, "name", literalData.FileName)
+}
+
+type nopWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (nopWriteCloser) Close() error { return nil }
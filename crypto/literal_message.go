@@ -0,0 +1,234 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	goerrors "errors"
+	"hash"
+	"io"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// ErrUnexpectedPacket is returned when decrypting a message whose decrypted
+// packets aren't a single literal data packet, possibly compressed and
+// signed, e.g. a message with a signed and an unsigned literal data packet,
+// whose signature status could otherwise be attributed to the wrong data.
+var ErrUnexpectedPacket = goerrors.New("gopenpgp: unexpected packet in message")
+
+// readLiteralMessage reads the decrypted packets of a message like
+// openpgp.ReadMessage reads an unencrypted message, verifying its signatures
+// with keyring, but enforces that it has a single literal data packet: once
+// the body is read entirely, the following packets may only be signatures,
+// otherwise reading the body fails with ErrUnexpectedPacket.
+// Also returns the serialized signature packets found before the literal data,
+// which the library skips, see constants.SIGNATURE_FRAMING_PREFIXED.
+// Fails with ErrPacketNestingTooDeep if the compressed packets are nested
+// deeper than the limit, see SetMaxPacketNesting.
+func readLiteralMessage(
+	r io.Reader, keyring openpgp.KeyRing, config *packet.Config,
+) (md *openpgp.MessageDetails, prefixedSignatures [][]byte, err error) {
+	packets := packet.NewReader(r)
+	md = &openpgp.MessageDetails{}
+	body := &literalBodyReader{packets: packets, md: md, config: config}
+
+	var prevLast bool
+	maxDepth := getMaxPacketNesting()
+	depth := 0
+	for md.LiteralData == nil {
+		p, err := packets.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch p := p.(type) {
+		case *packet.Compressed:
+			depth++
+			if depth > maxDepth {
+				return nil, nil, ErrPacketNestingTooDeep
+			}
+			if err := packets.Push(p.Body); err != nil {
+				return nil, nil, err
+			}
+		case *packet.OnePassSignature:
+			if prevLast {
+				return nil, nil, pgpErrors.UnsupportedError("nested signature packets")
+			}
+			prevLast = p.IsLast
+
+			body.h, body.wrappedHash, err = hashForSignature(p.Hash, p.SigType)
+			if err != nil {
+				md.SignatureError = err
+			}
+			md.IsSigned = true
+			md.SignedByKeyId = p.KeyId
+			if keys := keyring.KeysByIdUsage(p.KeyId, packet.KeyFlagSign); len(keys) > 0 {
+				md.SignedBy = &keys[0]
+			}
+		case *packet.Signature:
+			var signature bytes.Buffer
+			if err := p.Serialize(&signature); err != nil {
+				return nil, nil, err
+			}
+			prefixedSignatures = append(prefixedSignatures, signature.Bytes())
+		case *packet.LiteralData:
+			md.LiteralData = p
+		case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted,
+			*packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+			return nil, nil, ErrUnexpectedPacket
+		}
+	}
+
+	// As in openpgp.ReadMessage, the signature isn't checked if its hash is
+	// unsupported, its error is reported instead
+	body.verify = md.IsSigned && md.SignatureError == nil
+	md.UnverifiedBody = body
+	return md, prefixedSignatures, nil
+}
+
+// literalBodyReader reads the body of the literal data packet of a message,
+// then checks the packets following it, and the signature if verify is set.
+type literalBodyReader struct {
+	packets        *packet.Reader
+	md             *openpgp.MessageDetails
+	config         *packet.Config
+	verify         bool
+	h, wrappedHash hash.Hash
+	done           bool
+	trailingErr    error
+}
+
+func (r *literalBodyReader) Read(b []byte) (int, error) {
+	if r.done {
+		return 0, r.endError()
+	}
+	n, err := r.md.LiteralData.Body.Read(b)
+	if r.verify && r.md.SignedBy != nil {
+		_, _ = r.wrappedHash.Write(b[:n])
+	}
+	if goerrors.Is(err, io.EOF) {
+		r.done = true
+		r.trailingErr = r.readTrailingPackets()
+		return n, r.endError()
+	}
+	if err != nil {
+		return n, pgpErrors.StructuralError("parsing error")
+	}
+	return n, nil
+}
+
+func (r *literalBodyReader) endError() error {
+	if r.trailingErr != nil {
+		return r.trailingErr
+	}
+	return io.EOF
+}
+
+// readTrailingPackets reads the packets following the literal data packet,
+// checking the signature if needed.
+func (r *literalBodyReader) readTrailingPackets() error {
+	for {
+		// As in openpgp.ReadMessage, errors end the packets: a corrupted
+		// message is reported by its integrity check
+		p, err := r.packets.Next()
+		if err != nil {
+			break
+		}
+		switch p := p.(type) {
+		case *packet.Signature:
+			if r.verify {
+				r.checkSignature(p)
+			}
+		case *packet.LiteralData, *packet.OnePassSignature, *packet.Compressed,
+			*packet.EncryptedKey, *packet.SymmetricKeyEncrypted,
+			*packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+			return ErrUnexpectedPacket
+		}
+	}
+
+	if r.verify && r.md.SignedBy != nil && r.md.Signature == nil {
+		if r.md.UnverifiedSignatures == nil {
+			r.md.SignatureError = pgpErrors.StructuralError("LiteralData not followed by signature")
+		} else {
+			r.md.SignatureError = pgpErrors.StructuralError("No matching signature found")
+		}
+	}
+	return nil
+}
+
+// checkSignature verifies sig if it was made by the signer of the one-pass
+// signature, or records it as unverified.
+func (r *literalBodyReader) checkSignature(sig *packet.Signature) {
+	md := r.md
+	if sig.Version == 5 && (sig.SigType == packet.SigTypeBinary || sig.SigType == packet.SigTypeText) {
+		sig.Metadata = md.LiteralData
+	}
+	if md.SignedBy == nil || sig.IssuerKeyId == nil || *sig.IssuerKeyId != md.SignedByKeyId {
+		md.UnverifiedSignatures = append(md.UnverifiedSignatures, sig)
+		return
+	}
+	md.Signature = sig
+	md.SignatureError = md.SignedBy.PublicKey.VerifySignature(r.h, sig)
+	if md.SignatureError == nil && sig.SigExpired(r.config.Now()) {
+		md.SignatureError = pgpErrors.ErrSignatureExpired
+	}
+}
+
+// verifyLiteralSignatures verifies the signatures of a message read by
+// readLiteralMessage without the keys of its signer, once its body has been
+// read entirely, with keyring, as readLiteralMessage would have.
+func verifyLiteralSignatures(
+	md *openpgp.MessageDetails, body []byte, keyring openpgp.KeyRing, config *packet.Config,
+) {
+	if !md.IsSigned || md.SignatureError != nil {
+		return
+	}
+	if keys := keyring.KeysByIdUsage(md.SignedByKeyId, packet.KeyFlagSign); len(keys) > 0 {
+		md.SignedBy = &keys[0]
+	}
+
+	r := &literalBodyReader{md: md, config: config, verify: true}
+	signatures := md.UnverifiedSignatures
+	md.UnverifiedSignatures = nil
+	for _, sig := range signatures {
+		if md.SignedBy != nil && sig.IssuerKeyId != nil && *sig.IssuerKeyId == md.SignedByKeyId {
+			var err error
+			r.h, r.wrappedHash, err = hashForSignature(sig.Hash, sig.SigType)
+			if err != nil {
+				md.SignatureError = err
+				return
+			}
+			_, _ = r.wrappedHash.Write(body)
+		}
+		r.checkSignature(sig)
+	}
+
+	if md.SignedBy != nil && md.Signature == nil {
+		if md.UnverifiedSignatures == nil {
+			md.SignatureError = pgpErrors.StructuralError("LiteralData not followed by signature")
+		} else {
+			md.SignatureError = pgpErrors.StructuralError("No matching signature found")
+		}
+	}
+}
+
+// hashForSignature returns the hash of the signature, and the hash to write
+// the message to, which canonicalizes the line endings of text signatures.
+func hashForSignature(hashID crypto.Hash, sigType packet.SignatureType) (hash.Hash, hash.Hash, error) {
+	if hashID == crypto.MD5 {
+		return nil, nil, pgpErrors.UnsupportedError("insecure hash algorithm: MD5")
+	}
+	if !hashID.Available() {
+		return nil, nil, pgpErrors.UnsupportedError("hash not available: " + strconv.Itoa(int(hashID)))
+	}
+	h := hashID.New()
+	switch sigType {
+	case packet.SigTypeBinary:
+		return h, h, nil
+	case packet.SigTypeText:
+		return h, openpgp.NewCanonicalTextHash(h), nil
+	}
+	return nil, nil, pgpErrors.UnsupportedError("unsupported signature type: " + strconv.Itoa(int(sigType)))
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+// serializeSignedLiteral returns the packets of a message with a literal data
+// packet signed by keyRingTestPrivate.
+func serializeSignedLiteral(t *testing.T, data string) []byte {
+	var b bytes.Buffer
+	config := &packet.Config{Time: getTimeGenerator()}
+	w, err := openpgp.Sign(&b, keyRingTestPrivate.getEntities()[0], &openpgp.FileHints{IsBinary: true}, config)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	_, _ = w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	return b.Bytes()
+}
+
+// serializeLiteral returns an unsigned literal data packet.
+func serializeLiteral(t *testing.T, data string) []byte {
+	var b bytes.Buffer
+	w, err := packet.SerializeLiteral(nopCloser{&b}, true, "", 0)
+	if err != nil {
+		t.Fatal("Expected no error while serializing literal data, got:", err)
+	}
+	_, _ = w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal("Expected no error while serializing literal data, got:", err)
+	}
+	return b.Bytes()
+}
+
+// serializeCompressed returns a compressed packet containing packets.
+func serializeCompressed(t *testing.T, packets []byte) []byte {
+	var b bytes.Buffer
+	w, err := packet.SerializeCompressed(nopCloser{&b}, packet.CompressionZLIB, nil)
+	if err != nil {
+		t.Fatal("Expected no error while compressing, got:", err)
+	}
+	_, _ = w.Write(packets)
+	if err := w.Close(); err != nil {
+		t.Fatal("Expected no error while compressing, got:", err)
+	}
+	return b.Bytes()
+}
+
+// encryptRawPackets returns a data packet encrypting the packets with
+// testSessionKey, whatever their structure.
+func encryptRawPackets(t *testing.T, packets []byte) []byte {
+	cipherFunc, err := testSessionKey.GetCipherFunc()
+	if err != nil {
+		t.Fatal("Expected no error while getting the cipher, got:", err)
+	}
+	var b bytes.Buffer
+	w, err := packet.SerializeSymmetricallyEncrypted(&b, cipherFunc, testSessionKey.Key, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, _ = w.Write(packets)
+	if err := w.Close(); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	return b.Bytes()
+}
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestDecryptMultipleLiteralPackets(t *testing.T) {
+	signed := serializeSignedLiteral(t, "signed data")
+	unsigned := serializeLiteral(t, "attacker data")
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(testSessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+
+	// The fixtures are valid once the extra literal data packet is removed
+	dataPacket := encryptRawPackets(t, signed)
+	decrypted, err := keyRingTestPrivate.Decrypt(NewPGPMessage(append(clone(keyPacket), dataPacket...)), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "signed data", decrypted.GetString())
+	decrypted, err = testSessionKey.Decrypt(encryptRawPackets(t, unsigned))
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "attacker data", decrypted.GetString())
+
+	for name, packets := range map[string][]byte{
+		"signed then unsigned":       append(clone(signed), unsigned...),
+		"unsigned then signed":       append(clone(unsigned), signed...),
+		"two unsigned":               append(clone(unsigned), unsigned...),
+		"compressed":                 serializeCompressed(t, append(clone(signed), unsigned...)),
+		"after compressed":           append(serializeCompressed(t, signed), unsigned...),
+		"compressed after signature": append(clone(signed), serializeCompressed(t, unsigned)...),
+	} {
+		dataPacket := encryptRawPackets(t, packets)
+		message := NewPGPMessage(append(clone(keyPacket), dataPacket...))
+
+		_, err := keyRingTestPrivate.Decrypt(message, keyRingTestPublic, GetUnixTime())
+		assert.True(t, errors.Is(err, ErrUnexpectedPacket), name, err)
+		_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+		assert.True(t, errors.Is(err, ErrUnexpectedPacket), name, err)
+
+		_, err = testSessionKey.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+		assert.True(t, errors.Is(err, ErrUnexpectedPacket), name, err)
+
+		reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(message.GetBinary()), keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while starting the decryption, got:", err)
+		}
+		_, err = ioutil.ReadAll(reader)
+		assert.True(t, errors.Is(err, ErrUnexpectedPacket), name, err)
+
+		reader, err = testSessionKey.DecryptStream(bytes.NewReader(dataPacket), nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while starting the decryption, got:", err)
+		}
+		_, err = ioutil.ReadAll(reader)
+		assert.True(t, errors.Is(err, ErrUnexpectedPacket), name, err)
+	}
+}
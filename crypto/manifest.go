@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// manifestMagic starts the canonical encoding of manifests, followed by the
+// version of the encoding.
+const manifestMagic = "gopenpgp-manifest\x00"
+
+// manifestVersion is the version of the canonical encoding of manifests.
+// The encoding of a version is frozen: signatures of manifests must stay valid.
+const manifestVersion = 1
+
+// ManifestEntry is an entry of a file manifest: the path of the file, its size
+// and the SHA-256 digest of its content.
+type ManifestEntry struct {
+	Path   string
+	Size   int64
+	SHA256 []byte
+}
+
+// BuildManifest returns the canonical encoding of the manifest listing
+// entries, in any order. The paths must be non-empty, valid UTF-8 and unique.
+//
+// The encoding, version 1, is:
+//   - the magic string "gopenpgp-manifest", a zero byte, and the version byte 1;
+//   - the number of entries, as a 4-byte big-endian integer;
+//   - the entries, sorted by the bytes of their paths, each as the length of
+//     the path as a 4-byte big-endian integer, the path, the size as an 8-byte
+//     big-endian integer, and the 32-byte SHA-256 digest.
+func BuildManifest(entries []ManifestEntry) ([]byte, error) {
+	sorted := make([]ManifestEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	var manifest bytes.Buffer
+	manifest.WriteString(manifestMagic)
+	manifest.WriteByte(manifestVersion)
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(sorted)))
+	manifest.Write(buf[:4])
+	for i, entry := range sorted {
+		if entry.Path == "" || !utf8.ValidString(entry.Path) {
+			return nil, errors.Errorf("gopenpgp: invalid manifest path %q", entry.Path)
+		}
+		if i > 0 && sorted[i-1].Path == entry.Path {
+			return nil, errors.Errorf("gopenpgp: duplicate manifest path %q", entry.Path)
+		}
+		if entry.Size < 0 {
+			return nil, errors.Errorf("gopenpgp: invalid size of manifest path %q", entry.Path)
+		}
+		if len(entry.SHA256) != sha256.Size {
+			return nil, errors.Errorf("gopenpgp: invalid SHA-256 digest of manifest path %q", entry.Path)
+		}
+		binary.BigEndian.PutUint32(buf[:4], uint32(len(entry.Path)))
+		manifest.Write(buf[:4])
+		manifest.WriteString(entry.Path)
+		binary.BigEndian.PutUint64(buf[:], uint64(entry.Size))
+		manifest.Write(buf[:])
+		manifest.Write(entry.SHA256)
+	}
+	return manifest.Bytes(), nil
+}
+
+// SignManifest signs the canonical encoding of the manifest listing entries,
+// see BuildManifest, with a detached signature.
+func SignManifest(keyRing *KeyRing, entries []ManifestEntry) (*PGPSignature, error) {
+	manifest, err := BuildManifest(entries)
+	if err != nil {
+		return nil, err
+	}
+	return keyRing.SignDetached(NewPlainMessage(manifest))
+}
+
+// VerifyManifest verifies the detached signature of the canonical encoding of
+// the manifest listing entries, see BuildManifest, with keyRing at verifyTime.
+// Returns a SignatureVerificationError if the verification fails.
+func VerifyManifest(keyRing *KeyRing, entries []ManifestEntry, signature *PGPSignature, verifyTime int64) error {
+	manifest, err := BuildManifest(entries)
+	if err != nil {
+		return err
+	}
+	return keyRing.VerifyDetached(NewPlainMessage(manifest), signature, verifyTime)
+}
@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testManifestEntries() []ManifestEntry {
+	digest := func(content string) []byte {
+		hash := sha256.Sum256([]byte(content))
+		return hash[:]
+	}
+	return []ManifestEntry{
+		{Path: "photos/2021/beach.jpg", Size: 2048576, SHA256: digest("beach")},
+		{Path: "documents/report.pdf", Size: 52311, SHA256: digest("report")},
+		{Path: "empty.txt", Size: 0, SHA256: digest("")},
+	}
+}
+
+func TestBuildManifestGolden(t *testing.T) {
+	entries := testManifestEntries()
+	manifest, err := BuildManifest(entries)
+	if err != nil {
+		t.Fatal("Expected no error while building manifest, got:", err)
+	}
+	assert.Exactly(t, readTestFile("manifest_canonical", true), hex.EncodeToString(manifest))
+	assert.Exactly(t, "photos/2021/beach.jpg", entries[0].Path)
+
+	// The order of the entries doesn't matter
+	reversed := []ManifestEntry{entries[2], entries[1], entries[0]}
+	reordered, err := BuildManifest(reversed)
+	if err != nil {
+		t.Fatal("Expected no error while building manifest, got:", err)
+	}
+	assert.Exactly(t, manifest, reordered)
+}
+
+func TestBuildManifestInvalid(t *testing.T) {
+	entries := testManifestEntries()
+	for _, invalid := range []ManifestEntry{
+		entries[1],
+		{Path: "", Size: 1, SHA256: entries[0].SHA256},
+		{Path: "\xff", Size: 1, SHA256: entries[0].SHA256},
+		{Path: "negative", Size: -1, SHA256: entries[0].SHA256},
+		{Path: "short", Size: 1, SHA256: entries[0].SHA256[:20]},
+	} {
+		_, err := BuildManifest(append(testManifestEntries(), invalid))
+		assert.Error(t, err, invalid.Path)
+	}
+}
+
+func TestSignVerifyManifest(t *testing.T) {
+	signature, err := SignManifest(keyRingTestPrivate, testManifestEntries())
+	if err != nil {
+		t.Fatal("Expected no error while signing manifest, got:", err)
+	}
+	entries := testManifestEntries()
+	if err := VerifyManifest(keyRingTestPublic, entries, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying manifest, got:", err)
+	}
+
+	entries[2].Size = 1
+	err = VerifyManifest(keyRingTestPublic, entries, signature, GetUnixTime())
+	var sigErr SignatureVerificationError
+	assert.True(t, errors.As(err, &sigErr), err)
+}
+
+func TestVerifyManifestGoldenSignature(t *testing.T) {
+	signature, err := NewPGPSignatureFromArmored(readTestFile("manifest_signature", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring signature, got:", err)
+	}
+	if err := VerifyManifest(keyRingTestPublic, testManifestEntries(), signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying manifest, got:", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	goerrors "errors"
 	"io"
 	"io/ioutil"
+	"math"
 	"regexp"
 	"runtime"
 	"strings"
@@ -27,16 +28,37 @@ type PlainMessage struct {
 	Data []byte
 	// If the content is text or binary
 	TextType bool
-	// The file's latest modification time
+	// The file's latest modification time, as a unix timestamp, or 0 if unset
 	Time uint32
 	// The encrypted message's filename
 	Filename string
+	// The cipher of the decrypted message
+	cipher string
+	// The format of the literal data if not derived from TextType, i.e. 'u'
+	format byte
+	// The protection of the decrypted message
+	protection *Protection
+	// Whether the message was decrypted with an expired or revoked key
+	decryptionKeyExpired bool
+	// The key ID of the signer announced by the decrypted message, 0 if unknown
+	signerKeyID uint64
+	// Whether the message is signed and encrypted as binary data if its text
+	// data is invalid
+	binaryIfInvalidText bool
+	// The key embedded in the decrypted message which verified its signature
+	embeddedVerificationKey *Key
+	// The packet which decrypted the message with a password
+	skesk *SKESKDetails
 }
 
 // PGPMessage stores a PGP-encrypted message.
 type PGPMessage struct {
 	// The content of the message
 	Data []byte
+	// The headers of the armor the message was parsed from, nil if not armored
+	armorHeaders []armor.Header
+	// The armored public key following the message, empty if none
+	armoredEmbeddedKey string
 }
 
 // PGPSignature stores a PGP-encoded detached signature.
@@ -108,6 +130,8 @@ func NewPGPMessage(data []byte) *PGPMessage {
 }
 
 // NewPGPMessageFromArmored generates a new PGPMessage from an armored string ready for decryption.
+// Only the first armored block is read, see NewPGPMessagesFromArmored and
+// NewPGPMessageFromArmoredStrict.
 func NewPGPMessageFromArmored(armored string) (*PGPMessage, error) {
 	encryptedIO, err := internal.Unarmor(armored)
 	if err != nil {
@@ -119,8 +143,15 @@ func NewPGPMessageFromArmored(armored string) (*PGPMessage, error) {
 		return nil, errors.Wrap(err, "gopenpgp: error in reading armored message")
 	}
 
+	headers, err := armor.ReadHeaders(armored)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading armor headers")
+	}
+
 	return &PGPMessage{
-		Data: message,
+		Data:               message,
+		armorHeaders:       headers,
+		armoredEmbeddedKey: getArmoredEmbeddedKey(armored),
 	}, nil
 }
 
@@ -227,9 +258,81 @@ func (msg *PlainMessage) IsBinary() bool {
 	return !msg.TextType
 }
 
+// GetFormat returns the format of the literal data of the message, one of
+// constants.LITERAL_FORMAT_BINARY, LITERAL_FORMAT_TEXT or LITERAL_FORMAT_UTF8.
+// Unless set with SetFormat or by decryption, it is derived from TextType.
+func (msg *PlainMessage) GetFormat() string {
+	switch {
+	case msg.TextType && msg.format != 0:
+		return string(msg.format)
+	case msg.TextType:
+		return constants.LITERAL_FORMAT_TEXT
+	default:
+		return constants.LITERAL_FORMAT_BINARY
+	}
+}
+
+// GetHexSignerKeyID returns the hex key ID of the signer announced by the
+// one-pass signature of a decrypted message, as set by KeyRing.Decrypt and the
+// SessionKey decryption functions, or an empty string if unknown.
+// It doesn't tell whether the signature is valid.
+func (msg *PlainMessage) GetHexSignerKeyID() string {
+	if msg.signerKeyID == 0 {
+		return ""
+	}
+	return keyIDToHex(msg.signerKeyID)
+}
+
+// SetFormat sets the format of the literal data of the message, written back
+// on encryption, and TextType accordingly.
+func (msg *PlainMessage) SetFormat(format string) error {
+	switch format {
+	case constants.LITERAL_FORMAT_BINARY, constants.LITERAL_FORMAT_TEXT, constants.LITERAL_FORMAT_UTF8:
+	default:
+		return errors.Errorf("gopenpgp: invalid literal data format %q", format)
+	}
+	msg.format = getLiteralFormat(format[0])
+	msg.TextType = format != constants.LITERAL_FORMAT_BINARY
+	return nil
+}
+
+// SetTime sets the modification time of the file, truncated to the second.
+// The zero time.Time unsets it. It returns an error if the time cannot be
+// represented in a literal data packet, i.e. is before 1970 or after 2106.
+func (msg *PlainMessage) SetTime(modTime time.Time) error {
+	if modTime.IsZero() {
+		msg.Time = 0
+		return nil
+	}
+	rawTime, err := checkModTime(modTime.Unix())
+	if err != nil {
+		return err
+	}
+	msg.Time = rawTime
+	return nil
+}
+
 // getFormattedTime returns the message (latest modification) Time as time.Time.
 func (msg *PlainMessage) getFormattedTime() time.Time {
-	return time.Unix(int64(msg.Time), 0)
+	return getModTime(msg.Time)
+}
+
+// getModTime returns the modification time to be written in a literal data
+// packet, mapping an unset (0) time to the zero time.Time.
+func getModTime(modTime uint32) time.Time {
+	if modTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(modTime), 0)
+}
+
+// checkModTime returns the unix timestamp as the 32-bit unsigned integer
+// stored in literal data packets, or an error if it would overflow.
+func checkModTime(modTime int64) (uint32, error) {
+	if modTime < 0 || modTime > math.MaxUint32 {
+		return 0, errors.Errorf("gopenpgp: modification time %d out of range", modTime)
+	}
+	return uint32(modTime), nil
 }
 
 // GetBinary returns the unarmored binary content of the message as a []byte.
@@ -254,9 +357,45 @@ func (msg *PGPMessage) GetArmoredWithCustomHeaders(comment, version string) (str
 	return armor.ArmorWithTypeAndCustomHeaders(msg.Data, constants.PGPMessageHeader, version, comment)
 }
 
+// GetArmoredWithCustomHeadersAndOptions returns the armored message as a string, with
+// the given headers and armor options. Empty parameters are omitted from the headers.
+func (msg *PGPMessage) GetArmoredWithCustomHeadersAndOptions(comment, version string, options *armor.Options) (string, error) {
+	return armor.ArmorWithTypeAndOptions(msg.Data, constants.PGPMessageHeader, version, comment, options)
+}
+
+// GetArmoredWithOriginalHeaders returns the armored message as a string, with
+// the headers of the armor it was parsed from, in their original order,
+// or set with SetArmorHeaders. Other messages are armored as by GetArmored.
+func (msg *PGPMessage) GetArmoredWithOriginalHeaders() (string, error) {
+	if msg.armorHeaders == nil {
+		return msg.GetArmored()
+	}
+	return armor.ArmorWithTypeAndHeaders(msg.Data, constants.PGPMessageHeader, msg.armorHeaders)
+}
+
+// GetArmorHeaders returns the headers of the armor the message was parsed
+// from, nil if it was not armored.
+func (msg *PGPMessage) GetArmorHeaders() []armor.Header {
+	if msg.armorHeaders == nil {
+		return nil
+	}
+	return append([]armor.Header{}, msg.armorHeaders...)
+}
+
+// SetArmorHeaders sets the headers written by GetArmoredWithOriginalHeaders,
+// e.g. to keep the headers of a received message after adding a recipient.
+func (msg *PGPMessage) SetArmorHeaders(headers []armor.Header) {
+	if headers == nil {
+		msg.armorHeaders = nil
+		return
+	}
+	msg.armorHeaders = append([]armor.Header{}, headers...)
+}
+
 // GetEncryptionKeyIDs Returns the key IDs of the keys to which the session key is encrypted.
+// Returns false if the message has more packets than the limits, see SetMaxPacketCounts.
 func (msg *PGPMessage) GetEncryptionKeyIDs() ([]uint64, bool) {
-	packets := packet.NewReader(bytes.NewReader(msg.Data))
+	packets := newPacketReader(bytes.NewReader(msg.Data))
 	var err error
 	var ids []uint64
 	var encryptedKey *packet.EncryptedKey
@@ -266,6 +405,9 @@ Loop:
 		if p, err = packets.Next(); goerrors.Is(err, io.EOF) {
 			break
 		}
+		if goerrors.Is(err, ErrTooManyPackets) {
+			return nil, false
+		}
 		switch p := p.(type) {
 		case *packet.EncryptedKey:
 			encryptedKey = p
@@ -331,7 +473,7 @@ func (msg *PGPSplitMessage) GetPGPMessage() *PGPMessage {
 // * garbageCollector > 0 activates the garbage collector.
 func (msg *PGPMessage) SeparateKeyAndData(estimatedLength, garbageCollector int) (outSplit *PGPSplitMessage, err error) {
 	// For info on each, see: https://golang.org/pkg/runtime/#MemStats
-	packets := packet.NewReader(bytes.NewReader(msg.Data))
+	packets := newPacketReader(bytes.NewReader(msg.Data))
 	outSplit = &PGPSplitMessage{}
 	gcCounter := 0
 
@@ -343,6 +485,9 @@ func (msg *PGPMessage) SeparateKeyAndData(estimatedLength, garbageCollector int)
 			err = nil //nolint:wastedassign
 			break
 		}
+		if goerrors.Is(err, ErrTooManyPackets) {
+			return nil, err
+		}
 		switch p := p.(type) {
 		case *packet.EncryptedKey:
 			if encryptedKey != nil && encryptedKey.Key != nil {
@@ -432,6 +577,18 @@ func (msg *PGPSignature) GetArmored() (string, error) {
 	return armor.ArmorWithType(msg.Data, constants.PGPSignatureHeader)
 }
 
+// GetArmoredWithCustomHeaders returns the armored signature as a string, with
+// the given headers. Empty parameters are omitted from the headers.
+func (msg *PGPSignature) GetArmoredWithCustomHeaders(comment, version string) (string, error) {
+	return armor.ArmorWithTypeAndCustomHeaders(msg.Data, constants.PGPSignatureHeader, version, comment)
+}
+
+// GetArmoredWithCustomHeadersAndOptions returns the armored signature as a string, with
+// the given headers and armor options. Empty parameters are omitted from the headers.
+func (msg *PGPSignature) GetArmoredWithCustomHeadersAndOptions(comment, version string, options *armor.Options) (string, error) {
+	return armor.ArmorWithTypeAndOptions(msg.Data, constants.PGPSignatureHeader, version, comment, options)
+}
+
 // GetSignatureKeyIDs Returns the key IDs of the keys to which the (readable) signature packets are encrypted to.
 func (msg *PGPSignature) GetSignatureKeyIDs() ([]uint64, bool) {
 	return getSignatureKeyIDs(msg.Data)
@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// NewPGPMessageFromBase64 generates a new PGPMessage from the base64 encoding
+// of its binary data, e.g. as transported in JSON. Whitespace, including line
+// breaks, and missing or extra padding are tolerated.
+func NewPGPMessageFromBase64(encoded string) (*PGPMessage, error) {
+	encoded = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, encoded)
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in decoding base64 message")
+	}
+	return &PGPMessage{Data: data}, nil
+}
+
+// GetBase64 returns the base64 encoding of the binary data of the message,
+// with padding and without line breaks.
+func (msg *PGPMessage) GetBase64() string {
+	return base64.StdEncoding.EncodeToString(msg.Data)
+}
+
+// EncryptStreamBase64 is used to encrypt data as a Writer, like
+// EncryptStream, writing the base64 encoding of the encrypted message to
+// pgpMessageWriter on the fly, with padding and without line breaks, instead
+// of its binary data. The encoding is only complete once the returned
+// WriteCloser is closed.
+func (keyRing *KeyRing) EncryptStreamBase64(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+) (plainMessageWriter WriteCloser, err error) {
+	encoder := base64.NewEncoder(base64.StdEncoding, pgpMessageWriter)
+	encryptWriter, err := keyRing.EncryptStream(encoder, plainMessageMetadata, signKeyRing)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptAndEncodeWriteCloser{encryptWriter: encryptWriter, encoder: encoder}, nil
+}
+
+// EncryptStreamBase64 is used to encrypt data with the session key as a
+// Writer, like EncryptStream, writing the base64 encoding of the data packet
+// to dataPacketWriter on the fly, see KeyRing.EncryptStreamBase64.
+func (sk *SessionKey) EncryptStreamBase64(
+	dataPacketWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+) (plainMessageWriter WriteCloser, err error) {
+	encoder := base64.NewEncoder(base64.StdEncoding, dataPacketWriter)
+	encryptWriter, err := sk.EncryptStream(encoder, plainMessageMetadata, signKeyRing)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptAndEncodeWriteCloser{encryptWriter: encryptWriter, encoder: encoder}, nil
+}
+
+// encryptAndEncodeWriteCloser closes the base64 encoder of the encrypted data,
+// which flushes the last encoded block, after the encryption writer.
+type encryptAndEncodeWriteCloser struct {
+	encryptWriter WriteCloser
+	encoder       WriteCloser
+}
+
+func (w *encryptAndEncodeWriteCloser) Write(b []byte) (int, error) {
+	return w.encryptWriter.Write(b)
+}
+
+func (w *encryptAndEncodeWriteCloser) Close() error {
+	if err := w.encryptWriter.Close(); err != nil {
+		return err
+	}
+	if err := w.encoder.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in closing base64 encoder")
+	}
+	return nil
+}
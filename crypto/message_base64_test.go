@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPGPMessageBase64(t *testing.T) {
+	encrypted, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("transported as base64"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	encoded := encrypted.GetBase64()
+	assert.Exactly(t, base64.StdEncoding.EncodeToString(encrypted.GetBinary()), encoded)
+
+	var wrapped strings.Builder
+	for i := 0; i < len(encoded); i += 64 {
+		end := i + 64
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		wrapped.WriteString(" " + encoded[i:end] + "\r\n")
+	}
+	for _, variant := range []string{
+		encoded,
+		wrapped.String(),
+		strings.TrimRight(encoded, "="),
+		strings.TrimRight(encoded, "=") + "===",
+	} {
+		decoded, err := NewPGPMessageFromBase64(variant)
+		if err != nil {
+			t.Fatal("Expected no error while decoding base64 message, got:", err)
+		}
+		assert.Exactly(t, encrypted.GetBinary(), decoded.GetBinary())
+	}
+
+	_, err = NewPGPMessageFromBase64("not*base64")
+	assert.Error(t, err)
+}
+
+func TestEncryptStreamBase64(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("streamed as base64\n"), 1000)
+
+	var encoded bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStreamBase64(&encoded, nil, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		t.Fatal("Expected no error while writing plaintext, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing writer, got:", err)
+	}
+
+	encrypted, err := NewPGPMessageFromBase64(encoded.String())
+	if err != nil {
+		t.Fatal("Expected no error while decoding base64 message, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.GetBinary())
+
+	encoded.Reset()
+	writer, err = testSessionKey.EncryptStreamBase64(&encoded, nil, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream with session key, got:", err)
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		t.Fatal("Expected no error while writing plaintext, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing writer, got:", err)
+	}
+	dataPacket, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		t.Fatal("Expected no error while decoding data packet, got:", err)
+	}
+	decrypted, err = testSessionKey.Decrypt(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.GetBinary())
+}
+
+const benchmarkBase64MessageSize = 20 << 20
+
+// BenchmarkEncryptBase64Buffered encrypts 20 MB to a buffer, then encodes the
+// encrypted message in base64.
+func BenchmarkEncryptBase64Buffered(b *testing.B) {
+	plaintext := make([]byte, benchmarkBase64MessageSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var encrypted bytes.Buffer
+		writer, err := keyRingTestPublic.EncryptStream(&encrypted, nil, nil)
+		if err != nil {
+			b.Fatal("Expected no error while encrypting stream, got:", err)
+		}
+		if _, err := io.Copy(writer, bytes.NewReader(plaintext)); err != nil {
+			b.Fatal("Expected no error while writing plaintext, got:", err)
+		}
+		if err := writer.Close(); err != nil {
+			b.Fatal("Expected no error while closing writer, got:", err)
+		}
+		encoded := NewPGPMessage(encrypted.Bytes()).GetBase64()
+		if _, err := io.WriteString(ioutil.Discard, encoded); err != nil {
+			b.Fatal("Expected no error while writing base64 message, got:", err)
+		}
+	}
+}
+
+// BenchmarkEncryptStreamBase64 encrypts and encodes 20 MB on the fly.
+func BenchmarkEncryptStreamBase64(b *testing.B) {
+	plaintext := make([]byte, benchmarkBase64MessageSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		writer, err := keyRingTestPublic.EncryptStreamBase64(ioutil.Discard, nil, nil)
+		if err != nil {
+			b.Fatal("Expected no error while encrypting stream, got:", err)
+		}
+		if _, err := io.Copy(writer, bytes.NewReader(plaintext)); err != nil {
+			b.Fatal("Expected no error while writing plaintext, got:", err)
+		}
+		if err := writer.Close(); err != nil {
+			b.Fatal("Expected no error while closing writer, got:", err)
+		}
+	}
+}
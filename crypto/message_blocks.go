@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	goerrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+// ErrMultipleArmoredBlocks is returned by NewPGPMessageFromArmoredStrict when
+// the input contains more than one armored message.
+var ErrMultipleArmoredBlocks = goerrors.New("gopenpgp: the input contains several armored blocks")
+
+// ArmoredBlocksError is returned by NewPGPMessagesFromArmored and
+// NewPGPSignaturesFromArmored when some of the armored blocks are invalid,
+// along with the blocks which could be parsed.
+type ArmoredBlocksError struct {
+	// The number of armored blocks found
+	Count int
+	// The errors of the invalid blocks, by index of the block
+	Errors map[int]error
+}
+
+// Error is the base method for all errors.
+func (e ArmoredBlocksError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	causes := make([]string, len(indexes))
+	for i, index := range indexes {
+		causes[i] = fmt.Sprintf("block %d: %v", index, e.Errors[index])
+	}
+	return fmt.Sprintf(
+		"gopenpgp: %d of %d armored blocks are invalid: %s",
+		len(e.Errors), e.Count, strings.Join(causes, "; "),
+	)
+}
+
+// NewPGPMessagesFromArmored parses every armored PGP MESSAGE block of data, in
+// order, ignoring the text between them, e.g. in an email containing several
+// messages. If some blocks are invalid, their messages are nil and an
+// ArmoredBlocksError is returned with the other messages.
+func NewPGPMessagesFromArmored(data string) ([]*PGPMessage, error) {
+	blocks := internal.SplitArmoredBlocks(data, constants.PGPMessageHeader)
+	if len(blocks) == 0 {
+		return nil, errors.New("gopenpgp: no armored message found")
+	}
+	messages := make([]*PGPMessage, len(blocks))
+	blocksErr := ArmoredBlocksError{Count: len(blocks), Errors: make(map[int]error)}
+	for i, block := range blocks {
+		message, err := NewPGPMessageFromArmored(block)
+		if err != nil {
+			blocksErr.Errors[i] = err
+			continue
+		}
+		messages[i] = message
+	}
+	if len(blocksErr.Errors) != 0 {
+		return messages, blocksErr
+	}
+	return messages, nil
+}
+
+// NewPGPSignaturesFromArmored parses every armored PGP SIGNATURE block of
+// data, like NewPGPMessagesFromArmored.
+func NewPGPSignaturesFromArmored(data string) ([]*PGPSignature, error) {
+	blocks := internal.SplitArmoredBlocks(data, constants.PGPSignatureHeader)
+	if len(blocks) == 0 {
+		return nil, errors.New("gopenpgp: no armored signature found")
+	}
+	signatures := make([]*PGPSignature, len(blocks))
+	blocksErr := ArmoredBlocksError{Count: len(blocks), Errors: make(map[int]error)}
+	for i, block := range blocks {
+		signature, err := NewPGPSignatureFromArmored(block)
+		if err != nil {
+			blocksErr.Errors[i] = err
+			continue
+		}
+		signatures[i] = signature
+	}
+	if len(blocksErr.Errors) != 0 {
+		return signatures, blocksErr
+	}
+	return signatures, nil
+}
+
+// NewPGPMessageFromArmoredStrict generates a new PGPMessage from an armored
+// string like NewPGPMessageFromArmored, which ignores the blocks following the
+// first one, but returns ErrMultipleArmoredBlocks if there are several
+// armored messages.
+func NewPGPMessageFromArmoredStrict(armored string) (*PGPMessage, error) {
+	if len(internal.SplitArmoredBlocks(armored, constants.PGPMessageHeader)) > 1 {
+		return nil, ErrMultipleArmoredBlocks
+	}
+	return NewPGPMessageFromArmored(armored)
+}
@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPGPMessagesFromArmored(t *testing.T) {
+	var armored []string
+	var expected []*PGPMessage
+	for _, text := range []string{"first message", "second message"} {
+		encrypted, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString(text), nil)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		armoredMessage, err := encrypted.GetArmored()
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		armored = append(armored, armoredMessage)
+		expected = append(expected, encrypted)
+	}
+	email := "Hi,\n\n" + armored[0] + "\nand the other one:\n" + armored[1] + "\nBye\n"
+
+	messages, err := NewPGPMessagesFromArmored(email)
+	if err != nil {
+		t.Fatal("Expected no error while parsing messages, got:", err)
+	}
+	assert.Len(t, messages, 2)
+	for i, message := range messages {
+		assert.Exactly(t, expected[i].GetBinary(), message.GetBinary())
+	}
+
+	_, err = NewPGPMessageFromArmoredStrict(email)
+	assert.True(t, errors.Is(err, ErrMultipleArmoredBlocks))
+	message, err := NewPGPMessageFromArmoredStrict("Hi,\n" + armored[1])
+	if err != nil {
+		t.Fatal("Expected no error while parsing single message, got:", err)
+	}
+	assert.Exactly(t, expected[1].GetBinary(), message.GetBinary())
+
+	// Corrupt the body of the first block
+	lines := strings.Split(armored[0], "\n")
+	body := 1
+	for lines[body-1] != "" {
+		body++
+	}
+	lines[body] = "!" + lines[body][1:]
+	corrupt := strings.Join(lines, "\n") + "\n" + armored[1]
+	messages, err = NewPGPMessagesFromArmored(corrupt)
+	var blocksErr ArmoredBlocksError
+	if !errors.As(err, &blocksErr) {
+		t.Fatal("Expected an ArmoredBlocksError, got:", err)
+	}
+	assert.Exactly(t, 2, blocksErr.Count)
+	assert.Contains(t, blocksErr.Errors, 0)
+	assert.Len(t, blocksErr.Errors, 1)
+	assert.Nil(t, messages[0])
+	assert.Exactly(t, expected[1].GetBinary(), messages[1].GetBinary())
+
+	_, err = NewPGPMessagesFromArmored("no message here")
+	assert.Error(t, err)
+}
+
+func TestNewPGPSignaturesFromArmored(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+	var armored string
+	for _, keyRing := range []*KeyRing{keyRingTestPrivate, keyRingTestPrivate} {
+		signature, err := keyRing.SignDetached(message)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		armoredSignature, err := signature.GetArmored()
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		armored += "signature:\n" + armoredSignature + "\n"
+	}
+
+	signatures, err := NewPGPSignaturesFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signatures, got:", err)
+	}
+	assert.Len(t, signatures, 2)
+	for _, signature := range signatures {
+		assert.NoError(t, keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()))
+	}
+}
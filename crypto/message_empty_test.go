@@ -0,0 +1,262 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// emptyTestMessages returns empty messages, binary and text, with and without
+// a filename and a time.
+func emptyTestMessages() map[string]*PlainMessage {
+	return map[string]*PlainMessage{
+		"binary nil":     NewPlainMessage(nil),
+		"binary empty":   NewPlainMessage([]byte{}),
+		"text":           NewPlainMessageFromString(""),
+		"file":           NewPlainMessageFromFile(nil, "", 0),
+		"file with name": NewPlainMessageFromFile([]byte{}, "empty.txt", uint32(GetUnixTime())),
+	}
+}
+
+// assertEmptyMessage checks that decrypted has the data and metadata of the
+// empty message.
+func assertEmptyMessage(t *testing.T, message, decrypted *PlainMessage) {
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+	assert.Exactly(t, len(message.GetBinary()), len(decrypted.GetBinary()))
+	assert.Exactly(t, message.IsBinary(), decrypted.IsBinary())
+	assert.Exactly(t, message.Filename, decrypted.Filename)
+	if message.Time != 0 {
+		assert.Exactly(t, message.Time, decrypted.Time)
+	}
+}
+
+func TestEmptyMessageSignVerify(t *testing.T) {
+	for name, message := range emptyTestMessages() {
+		t.Run(name, func(t *testing.T) {
+			signature, err := keyRingTestPrivate.SignDetached(message)
+			if err != nil {
+				t.Fatal("Expected no error while signing, got:", err)
+			}
+			assert.NoError(t, keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()))
+			// The canonical text of the empty data is itself
+			for _, mode := range []int{constants.SIGNATURE_MODE_AUTO, constants.SIGNATURE_MODE_BINARY, constants.SIGNATURE_MODE_TEXT} {
+				assert.NoError(t, keyRingTestPublic.VerifyDetachedWithMode(message, signature, GetUnixTime(), mode))
+			}
+			assert.NoError(t, keyRingTestPublic.VerifyDetachedStream(bytes.NewReader(message.GetBinary()), signature, GetUnixTime()))
+
+			streamSignature, err := keyRingTestPrivate.SignDetachedStream(bytes.NewReader(message.GetBinary()))
+			if err != nil {
+				t.Fatal("Expected no error while signing stream, got:", err)
+			}
+			assert.NoError(t, keyRingTestPublic.VerifyDetached(message, streamSignature, GetUnixTime()))
+
+			digest, err := signature.ComputeDigest(message)
+			if err != nil {
+				t.Fatal("Expected no error while computing digest, got:", err)
+			}
+			assert.NoError(t, keyRingTestPublic.VerifyDetachedQuick(digest, signature, GetUnixTime()))
+
+			encryptedSignature, err := keyRingTestPrivate.SignDetachedEncrypted(message, keyRingTestPublic)
+			if err != nil {
+				t.Fatal("Expected no error while signing and encrypting, got:", err)
+			}
+			assert.NoError(t, keyRingTestPublic.VerifyDetachedEncrypted(message, encryptedSignature, keyRingTestPrivate, GetUnixTime()))
+
+			selfContained, err := keyRingTestPrivate.SignDetachedSelfContained(message)
+			if err != nil {
+				t.Fatal("Expected no error while signing self-contained, got:", err)
+			}
+			_, err = VerifySelfContained(message.GetBinary(), selfContained, GetUnixTime(), nil)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestEmptyMessageEncryptDecrypt(t *testing.T) {
+	for name, message := range emptyTestMessages() {
+		t.Run(name, func(t *testing.T) {
+			ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting, got:", err)
+			}
+			armored, err := ciphertext.GetArmored()
+			if err != nil {
+				t.Fatal("Expected no error while armoring, got:", err)
+			}
+			unarmored, err := NewPGPMessageFromArmored(armored)
+			if err != nil {
+				t.Fatal("Expected no error while unarmoring, got:", err)
+			}
+			decrypted, err := keyRingTestPrivate.Decrypt(unarmored, keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assertEmptyMessage(t, message, decrypted)
+
+			reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(ciphertext.GetBinary()), keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting stream, got:", err)
+			}
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatal("Expected no error while reading, got:", err)
+			}
+			assert.Exactly(t, message.GetString(), string(data))
+			assert.NoError(t, reader.VerifySignature())
+			assert.Exactly(t, message.Filename, reader.GetMetadata().Filename)
+
+			var streamed bytes.Buffer
+			writer, err := keyRingTestPublic.EncryptStream(&streamed, NewPlainMessageMetadata(
+				message.IsBinary(), message.Filename, int64(message.Time),
+			), keyRingTestPrivate)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting stream, got:", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal("Expected no error while closing stream, got:", err)
+			}
+			decrypted, err = keyRingTestPrivate.Decrypt(NewPGPMessage(streamed.Bytes()), keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting streamed message, got:", err)
+			}
+			assertEmptyMessage(t, message, decrypted)
+		})
+	}
+}
+
+func TestEmptyMessageSplitStream(t *testing.T) {
+	var dataPacket bytes.Buffer
+	result, err := keyRingTestPublic.EncryptSplitStream(&dataPacket, NewPlainMessageMetadata(true, "", 0), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if err := result.Close(); err != nil {
+		t.Fatal("Expected no error while closing stream, got:", err)
+	}
+	keyPacket, err := result.GetKeyPacket()
+	if err != nil {
+		t.Fatal("Expected no error while getting key packet, got:", err)
+	}
+
+	reader, err := keyRingTestPrivate.DecryptSplitStream(keyPacket, &dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.Empty(t, data)
+	assert.NoError(t, reader.VerifySignature())
+}
+
+func TestEmptyMessageSessionKey(t *testing.T) {
+	for name, message := range emptyTestMessages() {
+		t.Run(name, func(t *testing.T) {
+			dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting, got:", err)
+			}
+			decrypted, err := testSessionKey.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assertEmptyMessage(t, message, decrypted)
+
+			reader, err := testSessionKey.DecryptStream(bytes.NewReader(dataPacket), keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting stream, got:", err)
+			}
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatal("Expected no error while reading, got:", err)
+			}
+			assert.Exactly(t, message.GetString(), string(data))
+			assert.NoError(t, reader.VerifySignature())
+
+			var streamed bytes.Buffer
+			writer, err := testSessionKey.EncryptStream(&streamed, NewPlainMessageMetadata(
+				message.IsBinary(), message.Filename, int64(message.Time),
+			), keyRingTestPrivate)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting stream, got:", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal("Expected no error while closing stream, got:", err)
+			}
+			decrypted, err = testSessionKey.DecryptAndVerify(streamed.Bytes(), keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting streamed message, got:", err)
+			}
+			assertEmptyMessage(t, message, decrypted)
+		})
+	}
+}
+
+func TestEmptyMessageCleartext(t *testing.T) {
+	message := NewPlainMessageFromString("")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	armored, err := NewClearTextMessage(message.GetBinary(), signature.GetBinary()).GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+
+	parsed, err := NewClearTextMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing, got:", err)
+	}
+	assert.Exactly(t, "", parsed.GetString())
+	_, err = parsed.VerifyOnly(keyRingTestPublic, GetUnixTime())
+	assert.NoError(t, err)
+
+	reader, err := NewClearTextMessageReader(strings.NewReader(armored), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.Empty(t, data)
+	assert.NoError(t, reader.VerifySignature())
+}
+
+func TestEmptyAttachment(t *testing.T) {
+	processor, err := keyRingTestPublic.NewLowMemoryAttachmentProcessor(0, "")
+	if err != nil {
+		t.Fatal("Expected no error while creating processor, got:", err)
+	}
+	split, err := processor.Finish()
+	if err != nil {
+		t.Fatal("Expected no error while finishing, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.DecryptAttachment(split)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Empty(t, decrypted.GetBinary())
+	assert.Exactly(t, "", decrypted.Filename)
+
+	dataBuffer := make([]byte, 1024)
+	manual, err := keyRingTestPublic.NewManualAttachmentProcessor(0, "", dataBuffer)
+	if err != nil {
+		t.Fatal("Expected no error while creating processor, got:", err)
+	}
+	if err := manual.Finish(); err != nil {
+		t.Fatal("Expected no error while finishing, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.DecryptAttachment(
+		NewPGPSplitMessage(manual.GetKeyPacket(), dataBuffer[:manual.GetDataLength()]),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Empty(t, decrypted.GetBinary())
+}
@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"bytes"
+	goerrors "errors"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// ErrEmptyMessage is returned by NewPGPMessageAuto when the input is empty.
+var ErrEmptyMessage = goerrors.New("gopenpgp: empty message")
+
+// ErrUnknownMessageFormat is returned by NewPGPMessageAuto when the input
+// is neither an armored message, a binary message nor a cleartext message.
+var ErrUnknownMessageFormat = goerrors.New("gopenpgp: unable to detect message format")
+
+const (
+	armorBeginPrefix     = "-----BEGIN PGP "
+	armorEndPrefix       = "-----END PGP "
+	clearTextBeginHeader = "-----BEGIN PGP SIGNED MESSAGE-----"
+)
+
+// DetectedPGPMessage holds a message whose framing was detected by
+// NewPGPMessageAuto. Depending on the format, either the PGPMessage or the
+// ClearTextMessage is set.
+type DetectedPGPMessage struct {
+	// One of constants.MESSAGE_FORMAT_BINARY, MESSAGE_FORMAT_ARMORED, MESSAGE_FORMAT_CLEARTEXT
+	Format           int
+	PGPMessage       *PGPMessage
+	ClearTextMessage *ClearTextMessage
+}
+
+// NewPGPMessageAuto detects whether data is an armored PGP message, a binary
+// PGP message, or a cleartext signed message, and parses it accordingly.
+// Returns ErrEmptyMessage for empty input and ErrUnknownMessageFormat when
+// the format can't be determined.
+func NewPGPMessageAuto(data []byte) (*DetectedPGPMessage, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyMessage
+	}
+
+	switch {
+	case IsClearTextMessage(data):
+		clearText, err := NewClearTextMessageFromArmored(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return &DetectedPGPMessage{
+			Format:           constants.MESSAGE_FORMAT_CLEARTEXT,
+			ClearTextMessage: clearText,
+		}, nil
+	case IsArmored(data):
+		if !IsPGPMessage(string(bytes.TrimSpace(data))) {
+			return nil, errors.Wrap(ErrUnknownMessageFormat, "gopenpgp: armored data is not a message")
+		}
+		message, err := NewPGPMessageFromArmored(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return &DetectedPGPMessage{
+			Format:     constants.MESSAGE_FORMAT_ARMORED,
+			PGPMessage: message,
+		}, nil
+	case IsBinaryPGP(data):
+		return &DetectedPGPMessage{
+			Format:     constants.MESSAGE_FORMAT_BINARY,
+			PGPMessage: NewPGPMessage(data),
+		}, nil
+	}
+
+	return nil, ErrUnknownMessageFormat
+}
+
+// GetFormat returns the detected format of the message.
+func (msg *DetectedPGPMessage) GetFormat() int {
+	return msg.Format
+}
+
+// IsArmored returns true if data looks like an ASCII armored block,
+// ignoring leading whitespace. Cleartext signed messages are not considered
+// armored, see IsClearTextMessage. The data is not fully parsed.
+func IsArmored(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte(armorBeginPrefix)) &&
+		!bytes.HasPrefix(trimmed, []byte(clearTextBeginHeader)) &&
+		bytes.Contains(trimmed, []byte(armorEndPrefix))
+}
+
+// IsClearTextMessage returns true if data looks like a cleartext signed
+// message, ignoring leading whitespace. The data is not fully parsed.
+func IsClearTextMessage(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte(clearTextBeginHeader))
+}
+
+// IsBinaryPGP returns true if data starts with a valid OpenPGP packet header
+// (RFC 4880, section 4.2). Only the first packet header is inspected.
+func IsBinaryPGP(data []byte) bool {
+	header, err := parsePacketHeader(data)
+	if err != nil {
+		return false
+	}
+
+	// Reserved and undefined packet tags
+	switch tag := header.tag; {
+	case tag == 0, tag == 15, tag == 16, tag > 21 && tag < 60:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestNewPGPMessageAutoArmored(t *testing.T) {
+	armored := readTestFile("message_signed", false)
+
+	detected, err := NewPGPMessageAuto([]byte(armored))
+	if err != nil {
+		t.Fatal("Expected no error while detecting armored message, got:", err)
+	}
+
+	expected, err := NewPGPMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring message, got:", err)
+	}
+
+	assert.Exactly(t, constants.MESSAGE_FORMAT_ARMORED, detected.GetFormat())
+	assert.Exactly(t, expected.GetBinary(), detected.PGPMessage.GetBinary())
+	assert.Nil(t, detected.ClearTextMessage)
+}
+
+func TestNewPGPMessageAutoBinary(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring message, got:", err)
+	}
+
+	assert.True(t, IsBinaryPGP(message.GetBinary()))
+	assert.False(t, IsArmored(message.GetBinary()))
+
+	detected, err := NewPGPMessageAuto(message.GetBinary())
+	if err != nil {
+		t.Fatal("Expected no error while detecting binary message, got:", err)
+	}
+
+	assert.Exactly(t, constants.MESSAGE_FORMAT_BINARY, detected.GetFormat())
+	assert.Exactly(t, message.GetBinary(), detected.PGPMessage.GetBinary())
+}
+
+func TestNewPGPMessageAutoClearText(t *testing.T) {
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString(signedPlainText))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	armored, err := NewClearTextMessage([]byte(signedPlainText), signature.GetBinary()).GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring cleartext message, got:", err)
+	}
+
+	assert.False(t, IsArmored([]byte(armored)))
+	assert.True(t, IsClearTextMessage([]byte(armored)))
+
+	detected, err := NewPGPMessageAuto([]byte(armored))
+	if err != nil {
+		t.Fatal("Expected no error while detecting cleartext message, got:", err)
+	}
+
+	assert.Exactly(t, constants.MESSAGE_FORMAT_CLEARTEXT, detected.GetFormat())
+	assert.Exactly(t, signature.GetBinary(), detected.ClearTextMessage.GetBinarySignature())
+	assert.Nil(t, detected.PGPMessage)
+}
+
+func TestNewPGPMessageAutoErrors(t *testing.T) {
+	_, err := NewPGPMessageAuto(nil)
+	assert.True(t, errors.Is(err, ErrEmptyMessage))
+
+	_, err = NewPGPMessageAuto([]byte(" \n"))
+	assert.True(t, errors.Is(err, ErrEmptyMessage))
+
+	_, err = NewPGPMessageAuto([]byte("Hello, world"))
+	assert.True(t, errors.Is(err, ErrUnknownMessageFormat))
+
+	_, err = NewPGPMessageAuto([]byte(readTestFile("keyring_publicKey", false)))
+	assert.True(t, errors.Is(err, ErrUnknownMessageFormat))
+}
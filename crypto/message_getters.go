@@ -3,12 +3,36 @@
 
 package crypto
 
+import "time"
+
 // GetFilename returns the file name of the message as a string.
 func (msg *PlainMessage) GetFilename() string {
 	return msg.Filename
 }
 
+// GetCipher returns the cipher of the decrypted message, e.g. "aes256",
+// as set by KeyRing.Decrypt and the SessionKey decryption functions,
+// or an empty string.
+func (msg *PlainMessage) GetCipher() string {
+	return msg.cipher
+}
+
 // GetTime returns the modification time of a file (if provided in the ciphertext).
+// A time of 0 means the time is unset, see HasTime and GetModTime.
+// It keeps returning the raw uint32 for compatibility with existing callers.
 func (msg *PlainMessage) GetTime() uint32 {
 	return msg.Time
 }
+
+// HasTime returns whether the modification time of the file was provided in
+// the ciphertext, i.e. is not 0.
+func (msg *PlainMessage) HasTime() bool {
+	return msg.Time != 0
+}
+
+// GetModTime returns the modification time of the file, and whether it was
+// provided in the ciphertext. If it is unset, the time is the zero time.Time
+// rather than the Unix epoch.
+func (msg *PlainMessage) GetModTime() (time.Time, bool) {
+	return getModTime(msg.Time), msg.HasTime()
+}
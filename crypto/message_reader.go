@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// NewPlainMessageFromReader reads a new PlainMessage ready for encryption,
+// signature, or verification from r, with the filename and the current time
+// as modification time.
+// The message is text if its data is text according to IsTextData, binary
+// otherwise, see NewPlainMessageFromReaderWithType to set it explicitly.
+// If maxSize is positive and r has more than maxSize bytes, it returns a
+// PlaintextTooLargeError, matching ErrPlaintextTooLarge.
+func NewPlainMessageFromReader(r io.Reader, filename string, maxSize int64) (*PlainMessage, error) {
+	data, err := readPlainData(r, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return newPlainMessageFromData(data, filename, !IsTextData(data)), nil
+}
+
+// NewPlainMessageFromReaderWithType reads a new PlainMessage like
+// NewPlainMessageFromReader, as binary data if isBinary is true, text
+// otherwise, instead of guessing it from the data.
+func NewPlainMessageFromReaderWithType(
+	r io.Reader, filename string, maxSize int64, isBinary bool,
+) (*PlainMessage, error) {
+	data, err := readPlainData(r, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return newPlainMessageFromData(data, filename, isBinary), nil
+}
+
+// IsTextData returns whether data is considered text by
+// NewPlainMessageFromReader, which then signs it in text mode: data is text
+// if it is valid UTF-8 and has no control character other than tab, line
+// feed, form feed and carriage return, i.e. no byte in 0x00-0x08, 0x0b,
+// 0x0e-0x1f nor 0x7f. Empty data is text.
+// The heuristic doesn't depend on the platform and won't change, since it
+// determines the signatures of the messages.
+func IsTextData(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, b := range data {
+		switch {
+		case b == '\t', b == '\n', b == '\f', b == '\r':
+		case b < 0x20, b == 0x7f:
+			return false
+		}
+	}
+	return true
+}
+
+// readPlainData reads all of r, or returns a PlaintextTooLargeError if it has
+// more than maxSize bytes and maxSize is positive.
+func readPlainData(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		data, err := ioutil.ReadAll(r)
+		return data, errors.Wrap(err, "gopenpgp: error in reading plain message")
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading plain message")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, PlaintextTooLargeError{Limit: maxSize, Size: int64(len(data))}
+	}
+	return data, nil
+}
+
+func newPlainMessageFromData(data []byte, filename string, isBinary bool) *PlainMessage {
+	return &PlainMessage{
+		Data:     data,
+		TextType: !isBinary,
+		Filename: filename,
+		Time:     uint32(GetUnixTime()),
+	}
+}
@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPlainMessageFromReader(t *testing.T) {
+	text := "Hello\tWorld!\r\nBonjour à tous\n"
+	message, err := NewPlainMessageFromReader(strings.NewReader(text), "hello.txt", 1024)
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	assert.True(t, message.IsText())
+	assert.Exactly(t, []byte(text), message.GetBinary())
+	assert.Exactly(t, "hello.txt", message.Filename)
+	assert.Exactly(t, uint32(GetUnixTime()), message.Time)
+
+	binary := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	message, err = NewPlainMessageFromReader(bytes.NewReader(binary), "image.png", 0)
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	assert.True(t, message.IsBinary())
+	assert.Exactly(t, binary, message.GetBinary())
+
+	message, err = NewPlainMessageFromReaderWithType(strings.NewReader(text), "hello.bin", 0, true)
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	assert.True(t, message.IsBinary())
+
+	// The cap is inclusive
+	_, err = NewPlainMessageFromReader(strings.NewReader(text), "", int64(len(text)))
+	assert.NoError(t, err)
+	_, err = NewPlainMessageFromReader(strings.NewReader(text), "", int64(len(text)-1))
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge), err)
+}
+
+func TestIsTextData(t *testing.T) {
+	assert.True(t, IsTextData(nil))
+	assert.True(t, IsTextData([]byte("plain ASCII\f\r\n")))
+	assert.True(t, IsTextData([]byte("UTF-8: 日本語, emoji: 🔑")))
+	assert.False(t, IsTextData([]byte("NUL\x00")))
+	assert.False(t, IsTextData([]byte("escape \x1b[0m")))
+	assert.False(t, IsTextData([]byte("vertical tab\v")))
+	assert.False(t, IsTextData([]byte("delete\x7f")))
+	assert.False(t, IsTextData([]byte("latin-1 \xe9")))
+	// A truncated multi-byte sequence
+	assert.False(t, IsTextData([]byte("日本語")[:4]))
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
 func TestTextMessageEncryptionWithPassword(t *testing.T) {
@@ -68,6 +71,12 @@ func TestBinaryMessageEncryptionWithPassword(t *testing.T) {
 	if err != nil {
 		t.Fatal("Expected no error when decrypting, got:", err)
 	}
+	assert.Exactly(t, &SKESKDetails{
+		Version:         4,
+		Algorithm:       constants.AES256,
+		PacketAlgorithm: constants.AES256,
+	}, decrypted.GetSKESKDetails())
+	decrypted.skesk = nil
 	assert.Exactly(t, message, decrypted)
 }
 
@@ -172,6 +181,53 @@ func TestBinaryMessageEncryption(t *testing.T) {
 	assert.Exactly(t, message.GetString(), decrypted.GetString())
 }
 
+func TestMessageModificationTime(t *testing.T) {
+	message := NewPlainMessageFromFile([]byte("data"), "file.txt", 0)
+	assert.False(t, message.HasTime())
+
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.False(t, decrypted.HasTime())
+	assert.Exactly(t, uint32(0), decrypted.GetTime())
+	modTime, ok := decrypted.GetModTime()
+	assert.False(t, ok)
+	assert.True(t, modTime.IsZero())
+
+	modTime = time.Unix(1600000000, 500)
+	if err = message.SetTime(modTime); err != nil {
+		t.Fatal("Expected no error when setting time, got:", err)
+	}
+	ciphertext, err = keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.True(t, decrypted.HasTime())
+	assert.Exactly(t, uint32(1600000000), decrypted.GetTime())
+	decryptedTime, ok := decrypted.GetModTime()
+	assert.True(t, ok)
+	assert.Exactly(t, int64(1600000000), decryptedTime.Unix())
+
+	assert.Error(t, message.SetTime(time.Unix(1<<32, 0)))
+	assert.Error(t, message.SetTime(time.Unix(-1, 0)))
+	assert.Exactly(t, uint32(1600000000), message.GetTime())
+	assert.NoError(t, message.SetTime(time.Time{}))
+	assert.Exactly(t, uint32(0), message.GetTime())
+
+	var buf bytes.Buffer
+	_, err = keyRingTestPublic.EncryptStream(&buf, NewPlainMessageMetadata(true, "", 1<<32), nil)
+	assert.Error(t, err)
+}
+
 func TestIssue11(t *testing.T) {
 	var issue11Password = []byte("1234")
 
@@ -387,3 +443,82 @@ func TestMessageGetArmoredWithEmptyHeaders(t *testing.T) {
 	assert.NotContains(t, armored, "Version")
 	assert.NotContains(t, armored, "Comment")
 }
+
+func TestMessageArmorHeadersRoundTrip(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	assert.Nil(t, ciphertext.GetArmorHeaders())
+
+	headers := []armor.Header{
+		{Key: "Version", Value: "Other client 1.0"},
+		{Key: "Comment", Value: "First comment"},
+		{Key: "Comment", Value: "Second comment"},
+		{Key: "Charset", Value: "UTF-8"},
+	}
+	armored, err := armor.ArmorWithTypeAndHeaders(ciphertext.GetBinary(), constants.PGPMessageHeader, headers)
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+
+	decoded, err := NewPGPMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	assert.Exactly(t, headers, decoded.GetArmorHeaders())
+
+	rearmored, err := decoded.GetArmoredWithOriginalHeaders()
+	if err != nil {
+		t.Fatal("Expected no error when re-armoring, got:", err)
+	}
+	assert.Exactly(t, armored, rearmored)
+
+	rebuilt := NewPGPMessage(decoded.GetBinary())
+	rebuilt.SetArmorHeaders(decoded.GetArmorHeaders())
+	rearmored, err = rebuilt.GetArmoredWithOriginalHeaders()
+	if err != nil {
+		t.Fatal("Expected no error when re-armoring, got:", err)
+	}
+	assert.Exactly(t, armored, rearmored)
+}
+
+func TestMessageGetArmoredWithOptions(t *testing.T) {
+	var message = NewPlainMessageFromString("plain text")
+
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	for _, options := range []*armor.Options{
+		armor.NewOptions(76, true),
+		armor.NewOptions(76, false),
+		armor.NewOptions(0, false),
+	} {
+		armored, err := ciphertext.GetArmoredWithCustomHeadersAndOptions("", "", options)
+		if err != nil {
+			t.Fatal("Could not armor the ciphertext:", err)
+		}
+
+		lines := strings.Split(armored, "\n")
+		lineLength := options.LineLength
+		if lineLength == 0 {
+			lineLength = armor.DefaultLineLength
+		}
+		assert.Len(t, lines[2], lineLength)
+		checksumLine := lines[len(lines)-2]
+		assert.Exactly(t, !options.OmitChecksum, strings.HasPrefix(checksumLine, "="))
+
+		decoded, err := NewPGPMessageFromArmored(armored)
+		if err != nil {
+			t.Fatal("Could not unarmor the ciphertext:", err)
+		}
+		assert.Exactly(t, ciphertext.GetBinary(), decoded.GetBinary())
+	}
+
+	_, err = ciphertext.GetArmoredWithCustomHeadersAndOptions("", "", armor.NewOptions(77, true))
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"bytes"
+	goerrors "errors"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// ErrInvalidTextData is returned when signing or encrypting a text message
+// whose data contains a NUL byte, which other implementations reject in text
+// documents, see PlainMessage.SetBinaryIfInvalidText.
+var ErrInvalidTextData = goerrors.New("gopenpgp: text data contains a NUL byte")
+
+// NewPlainMessageFromStringStrict generates a new text PlainMessage like
+// NewPlainMessageFromString, or returns an error wrapping ErrInvalidTextData
+// if the text contains a NUL byte.
+func NewPlainMessageFromStringStrict(text string) (*PlainMessage, error) {
+	message := NewPlainMessageFromString(text)
+	if err := message.validateText(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// SetBinaryIfInvalidText sets whether the message is signed and encrypted as
+// binary data if it is a text message whose data contains a NUL byte, instead
+// of failing with ErrInvalidTextData. The message itself is not modified.
+func (msg *PlainMessage) SetBinaryIfInvalidText(enable bool) {
+	msg.binaryIfInvalidText = enable
+}
+
+// validateText returns an error wrapping ErrInvalidTextData, with the offset
+// of the first NUL byte, if msg is a text message containing one.
+func (msg *PlainMessage) validateText() error {
+	if msg.IsBinary() {
+		return nil
+	}
+	if offset := bytes.IndexByte(msg.Data, 0); offset >= 0 {
+		return errors.Wrapf(ErrInvalidTextData, "gopenpgp: NUL byte at offset %d", offset)
+	}
+	return nil
+}
+
+// getSignableMessage returns the message to sign or encrypt: msg, or a binary
+// copy of it if its text data is invalid and SetBinaryIfInvalidText is set.
+func (msg *PlainMessage) getSignableMessage() (*PlainMessage, error) {
+	err := msg.validateText()
+	if err == nil {
+		return msg, nil
+	}
+	if !msg.binaryIfInvalidText {
+		return nil, err
+	}
+	binary := *msg
+	binary.TextType = false
+	binary.format = constants.LITERAL_FORMAT_BINARY[0]
+	return &binary, nil
+}
@@ -0,0 +1,47 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestNewPlainMessageFromStringStrict(t *testing.T) {
+	message, err := NewPlainMessageFromStringStrict("valid text\n")
+	if err != nil {
+		t.Fatal("Expected no error while creating text message, got:", err)
+	}
+	assert.True(t, message.IsText())
+
+	_, err = NewPlainMessageFromStringStrict("text\x00with NUL")
+	assert.True(t, errors.Is(err, ErrInvalidTextData))
+	assert.True(t, strings.Contains(err.Error(), "offset 4"))
+}
+
+func TestEncryptTextWithNUL(t *testing.T) {
+	message := NewPlainMessageFromString("text\x00with NUL")
+
+	_, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	assert.True(t, errors.Is(err, ErrInvalidTextData))
+	_, err = testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	assert.True(t, errors.Is(err, ErrInvalidTextData))
+	_, err = testSessionKey.EncryptAndSignWithSignatureFraming(message, keyRingTestPrivate, constants.SIGNATURE_FRAMING_PREFIXED)
+	assert.True(t, errors.Is(err, ErrInvalidTextData))
+
+	message.SetBinaryIfInvalidText(true)
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting as binary, got:", err)
+	}
+	assert.True(t, message.IsText())
+
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.True(t, decrypted.IsBinary())
+	assert.Exactly(t, message.GetBinary(), decrypted.GetBinary())
+}
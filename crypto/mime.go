@@ -70,10 +70,10 @@ func parseMIME(
 
 	var pgpKering openpgp.KeyRing
 	if verifierKey != nil {
-		pgpKering = verifierKey.entities
+		pgpKering = verifierKey.getEntities()
 	}
 
-	signatureCollector := newSignatureCollector(mimeVisitor, pgpKering, config)
+	signatureCollector := newSignatureCollector(mimeVisitor, pgpKering, getDefaultSignerPolicy(), config)
 
 	err = gomime.VisitAll(bytes.NewReader(mmBodyData), h, signatureCollector)
 	if err == nil && verifierKey != nil {
@@ -0,0 +1,67 @@
+package crypto
+
+import "io"
+
+// SetPacketChunkSize sets the size of the chunks in which the encryption with
+// a session key writes the plaintext to the packets, in both the buffered
+// functions, such as SessionKey.Encrypt, and the streaming ones, such as
+// SessionKey.EncryptStream. Whatever the writes of the caller, messages of up
+// to size bytes are then written with definite-length packets, and larger
+// ones with partial-length packets, so that both produce the same packet
+// structure.
+// If size is 0, the default, the buffered functions write a single
+// definite-length packet, and the streaming functions write partial-length
+// packets depending on the writes of the caller.
+func SetPacketChunkSize(size int) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.packetChunkSize = size
+}
+
+// withPacketChunks wraps w to write in chunks of the size set by
+// SetPacketChunkSize, if any.
+func withPacketChunks(w io.WriteCloser) io.WriteCloser {
+	pgp.lock.RLock()
+	size := pgp.packetChunkSize
+	pgp.lock.RUnlock()
+	if size <= 0 {
+		return w
+	}
+	return &chunkedWriteCloser{w: w, size: size}
+}
+
+// chunkedWriteCloser forwards the data written to w in writes of exactly size
+// bytes, and the remaining data when closed.
+type chunkedWriteCloser struct {
+	w    io.WriteCloser
+	size int
+	buf  []byte
+}
+
+func (c *chunkedWriteCloser) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(c.buf)+len(b) >= c.size {
+		fill := c.size - len(c.buf)
+		chunk := b[:fill]
+		if len(c.buf) > 0 {
+			chunk = append(c.buf, chunk...)
+		}
+		b = b[fill:]
+		if _, err := c.w.Write(chunk); err != nil {
+			return n - len(b), err
+		}
+		c.buf = c.buf[:0]
+	}
+	c.buf = append(c.buf, b...)
+	return n, nil
+}
+
+func (c *chunkedWriteCloser) Close() error {
+	if len(c.buf) > 0 {
+		if _, err := c.w.Write(c.buf); err != nil {
+			return err
+		}
+		c.buf = nil
+	}
+	return c.w.Close()
+}
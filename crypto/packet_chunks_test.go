@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptWithPacketChunks(t *testing.T, message *PlainMessage, streaming bool) []byte {
+	setRandomSource(mathrand.New(mathrand.NewSource(42)))
+	defer setRandomSource(nil)
+
+	if !streaming {
+		encrypted, err := testSessionKey.Encrypt(message)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		return encrypted
+	}
+
+	var encrypted bytes.Buffer
+	writer, err := testSessionKey.EncryptStream(&encrypted, &PlainMessageMetadata{
+		IsBinary: message.IsBinary(),
+		Filename: message.Filename,
+		ModTime:  int64(message.Time),
+	}, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	// Small writes of odd sizes
+	data := message.GetBinary()
+	for len(data) > 0 {
+		n := 37
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := writer.Write(data[:n]); err != nil {
+			t.Fatal("Expected no error while writing, got:", err)
+		}
+		data = data[n:]
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	return encrypted.Bytes()
+}
+
+// dumpPacketLengths returns the lengths of the chunks of the body of the first
+// packet of a new format message, prefixed by "partial" or "definite".
+func dumpPacketLengths(t *testing.T, data []byte) (dump []string) {
+	if len(data) < 2 || data[0]&0xc0 != 0xc0 {
+		t.Fatal("Expected a new format packet")
+	}
+	data = data[1:]
+	for {
+		var length, headerLength int
+		partial := false
+		switch {
+		case data[0] < 192:
+			length, headerLength = int(data[0]), 1
+		case data[0] < 224:
+			length, headerLength = (int(data[0])-192)<<8+int(data[1])+192, 2
+		case data[0] == 255:
+			length, headerLength = int(data[1])<<24|int(data[2])<<16|int(data[3])<<8|int(data[4]), 5
+		default:
+			length, headerLength, partial = 1<<(data[0]&0x1f), 1, true
+		}
+		if !partial {
+			return append(dump, fmt.Sprint("definite ", length))
+		}
+		dump = append(dump, fmt.Sprint("partial ", length))
+		data = data[headerLength+length:]
+	}
+}
+
+func TestPacketChunkSize(t *testing.T) {
+	SetPacketChunkSize(1024)
+	defer SetPacketChunkSize(0)
+
+	for _, size := range []int{100, 1024, 5000} {
+		message := NewPlainMessageFromFile([]byte(strings.Repeat("x", size)), "chunks.txt", 1600000000)
+		buffered := encryptWithPacketChunks(t, message, false)
+		streamed := encryptWithPacketChunks(t, message, true)
+		assert.Exactly(t, buffered, streamed)
+		dump := dumpPacketLengths(t, buffered)
+		assert.Exactly(t, dump, dumpPacketLengths(t, streamed))
+		if size < 512 {
+			assert.Exactly(t, []string{"definite 159"}, dump)
+		} else {
+			// Whole chunks are written at once
+			assert.Exactly(t, "partial 1024", dump[0])
+		}
+
+		for _, encrypted := range [][]byte{buffered, streamed} {
+			decrypted, err := testSessionKey.Decrypt(encrypted)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assert.Exactly(t, message.GetBinary(), decrypted.GetBinary())
+		}
+	}
+}
+
+func TestPacketChunkSizeUnset(t *testing.T) {
+	message := NewPlainMessageFromFile([]byte(strings.Repeat("x", 5000)), "chunks.txt", 1600000000)
+	buffered := encryptWithPacketChunks(t, message, false)
+	streamed := encryptWithPacketChunks(t, message, true)
+	// The chunks depend on the writes of the caller
+	assert.NotEqual(t, dumpPacketLengths(t, buffered), dumpPacketLengths(t, streamed))
+
+	for _, encrypted := range [][]byte{buffered, streamed} {
+		decrypted, err := testSessionKey.Decrypt(encrypted)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetBinary(), decrypted.GetBinary())
+	}
+}
@@ -0,0 +1,116 @@
+package crypto
+
+import (
+	"github.com/pkg/errors"
+)
+
+const packetTagCompressed = 8
+
+// packetHeader is the header of an OpenPGP packet, see RFC 4880, section 4.2.
+type packetHeader struct {
+	tag byte
+	// Length of the header itself
+	headerLength int
+	// Length of the header and the body, or -1 if the body has partial
+	// lengths or an indeterminate length, which isn't known from the header
+	length int
+}
+
+// getPacketTag returns the tag encoded in the first byte of a packet header,
+// in the old or the new format.
+func getPacketTag(first byte) byte {
+	if first&0x40 == 0 {
+		// Old format packet
+		return (first & 0x3f) >> 2
+	}
+	return first & 0x3f
+}
+
+// parsePacketHeader parses the header of the packet at the start of data,
+// which must be complete. Partial body lengths are only accepted for the data
+// packets.
+func parsePacketHeader(data []byte) (*packetHeader, error) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return nil, errors.New("gopenpgp: invalid packet header")
+	}
+	header := &packetHeader{tag: getPacketTag(data[0])}
+	var err error
+	if data[0]&0x40 == 0 {
+		// Old format packet
+		switch data[0] & 0x03 {
+		case 0:
+			header.headerLength = 2
+			header.length = 2 + int(data[1])
+		case 1:
+			header.headerLength = 3
+			if len(data) >= 3 {
+				header.length = 3 + (int(data[1])<<8 | int(data[2]))
+			}
+		case 2:
+			header.headerLength = 5
+			if len(data) >= 5 {
+				header.length, err = readFourOctetLength(data[1:], 5)
+			}
+		default:
+			// Indeterminate length
+			header.headerLength = 1
+			header.length = -1
+		}
+	} else {
+		// New format packet
+		switch {
+		case data[1] < 192:
+			header.headerLength = 2
+			header.length = 2 + int(data[1])
+		case data[1] < 224:
+			header.headerLength = 3
+			if len(data) >= 3 {
+				header.length = 3 + (int(data[1])-192)<<8 + int(data[2]) + 192
+			}
+		case data[1] == 255:
+			header.headerLength = 6
+			if len(data) >= 6 {
+				header.length, err = readFourOctetLength(data[2:], 6)
+			}
+		default:
+			// Partial body lengths are only allowed for data packets
+			if !isDataPacketTag(header.tag) {
+				return nil, errors.New("gopenpgp: partial body length in a non-data packet")
+			}
+			header.headerLength = 2
+			header.length = -1
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < header.headerLength {
+		return nil, errors.New("gopenpgp: invalid packet header")
+	}
+	return header, nil
+}
+
+// isDataPacketTag returns whether packets with the tag may have partial body
+// lengths, see RFC 4880, section 4.2.2.4.
+func isDataPacketTag(tag byte) bool {
+	switch tag {
+	case packetTagCompressed, packetTagSymmetricallyEncrypted, packetTagLiteralData,
+		packetTagSymmetricallyEncryptedIntegrity, packetTagAEADEncrypted:
+		return true
+	}
+	return false
+}
+
+// readPacketHeader returns the tag and the total length of the first packet,
+// which is the length of data if the packet has partial body lengths or an
+// indeterminate length.
+func readPacketHeader(data []byte) (tag byte, length int, err error) {
+	header, err := parsePacketHeader(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	if header.length < 0 {
+		return header.tag, len(data), nil
+	}
+	return header.tag, header.length, nil
+}
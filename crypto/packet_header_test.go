@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePacketHeader(t *testing.T) {
+	for _, test := range []struct {
+		data         []byte
+		tag          byte
+		headerLength int
+		length       int
+	}{
+		// Old format, one, two and four-octet lengths, and indeterminate
+		{[]byte{0x88, 0x03}, 2, 2, 5},
+		{[]byte{0x89, 0x01, 0x00}, 2, 3, 259},
+		{[]byte{0x8a, 0x00, 0x00, 0x01, 0x00}, 2, 5, 261},
+		{[]byte{0xa3, 0x01}, 8, 1, -1},
+		// New format, one, two and five-octet lengths
+		{[]byte{0xc2, 0x03}, 2, 2, 5},
+		{[]byte{0xc2, 0xc0, 0x00}, 2, 3, 195},
+		{[]byte{0xc2, 0xff, 0x00, 0x00, 0x01, 0x00}, 2, 6, 262},
+		// New format, partial body length of a literal data packet
+		{[]byte{0xcb, 0xe0}, 11, 2, -1},
+	} {
+		header, err := parsePacketHeader(test.data)
+		if err != nil {
+			t.Fatalf("Expected no error while parsing header %x, got: %v", test.data, err)
+		}
+		assert.Exactly(t, &packetHeader{tag: test.tag, headerLength: test.headerLength, length: test.length}, header)
+		assert.True(t, IsBinaryPGP(test.data))
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{0x41, 0x42},
+		// Truncated headers
+		{0x89, 0x01},
+		{0x8a, 0x00, 0x00, 0x01},
+		{0xc2, 0xc0},
+		{0xc2, 0xff, 0x00, 0x00, 0x01},
+		// Partial body length of a signature packet
+		{0xc2, 0xe0},
+	} {
+		_, err := parsePacketHeader(data)
+		assert.Error(t, err)
+		_, _, err = readPacketHeader(data)
+		assert.Error(t, err)
+		assert.False(t, IsBinaryPGP(data))
+	}
+}
@@ -0,0 +1,21 @@
+package crypto
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// maxInt is the largest int, which has 32 bits on 32-bit platforms.
+const maxInt = int(^uint(0) >> 1)
+
+// readFourOctetLength returns the four-octet length at the start of b, see
+// RFC 4880, section 4.2.2.3, plus the length of the header, or an error if
+// the sum doesn't fit in an int, i.e. if it exceeds 2 GiB on 32-bit platforms.
+func readFourOctetLength(b []byte, headerLength int) (int, error) {
+	length := int64(binary.BigEndian.Uint32(b)) + int64(headerLength)
+	if length > int64(maxInt) {
+		return 0, errors.New("gopenpgp: packet length exceeds the maximum size on this platform")
+	}
+	return int(length), nil
+}
@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPacketHeaderFourOctetLength(t *testing.T) {
+	header := []byte{0xc2, 0xff, 0xff, 0xff, 0xff, 0xff}
+	_, length, err := readPacketHeader(header)
+	if int64(maxInt) > 0xffffffff {
+		if err != nil {
+			t.Fatal("Expected no error while reading packet header, got:", err)
+		}
+		assert.Exactly(t, int64(6+0xffffffff), int64(length))
+	} else {
+		// The length doesn't fit in an int on 32-bit platforms
+		assert.Error(t, err)
+	}
+
+	// The packet is truncated, the offset must not overflow
+	_, err = splitPackets(header)
+	assert.Error(t, err)
+	_, err = splitKeys(header)
+	assert.Error(t, err)
+}
+
+func TestLargeMessageRoundTrip(t *testing.T) {
+	// 10 MB
+	message := NewPlainMessage(bytes.Repeat([]byte("0123456789"), 1<<20))
+
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.True(t, bytes.Equal(message.GetBinary(), decrypted.GetBinary()))
+
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	if err := keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	goerrors "errors"
+	"io"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	// DefaultMaxESKPackets is the default maximum number of encrypted session
+	// key packets in a message.
+	DefaultMaxESKPackets = 512
+	// DefaultMaxPackets is the default maximum number of packets read when
+	// parsing the packets of a message.
+	DefaultMaxPackets = 16 << 10
+)
+
+// ErrTooManyPackets is returned when parsing a message with more encrypted
+// session key packets or more packets than the limits, see
+// SetMaxPacketCounts.
+var ErrTooManyPackets = goerrors.New("gopenpgp: too many packets in message")
+
+// SetMaxPacketCounts sets the maximum number of encrypted session key packets
+// and of packets accepted when parsing messages, splitting them, decrypting
+// their session key, or listing their encryption key IDs. If a limit is 0,
+// DefaultMaxESKPackets or DefaultMaxPackets is used.
+func SetMaxPacketCounts(maxESKPackets, maxPackets int) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.maxESKPackets = maxESKPackets
+	pgp.maxPackets = maxPackets
+}
+
+func getMaxESKPackets() int {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+	if pgp.maxESKPackets == 0 {
+		return DefaultMaxESKPackets
+	}
+	return pgp.maxESKPackets
+}
+
+func getMaxPackets() int {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+	if pgp.maxPackets == 0 {
+		return DefaultMaxPackets
+	}
+	return pgp.maxPackets
+}
+
+// packetCounter counts the packets read from a message against the limits.
+type packetCounter struct {
+	eskPackets int
+	packets    int
+}
+
+// add counts a packet, which is an encrypted session key packet if esk is
+// set, and returns ErrTooManyPackets if it exceeds a limit.
+func (counter *packetCounter) add(esk bool) error {
+	counter.packets++
+	if esk {
+		counter.eskPackets++
+	}
+	if counter.eskPackets > getMaxESKPackets() || counter.packets > getMaxPackets() {
+		return ErrTooManyPackets
+	}
+	return nil
+}
+
+// addPacket counts the parsed packet p, see add.
+func (counter *packetCounter) addPacket(p packet.Packet) error {
+	switch p.(type) {
+	case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted:
+		return counter.add(true)
+	}
+	return counter.add(false)
+}
+
+// packetReader reads the packets of a message like packet.Reader, counting
+// them against the limits, including the packets of unknown types which
+// packet.Reader skips.
+type packetReader struct {
+	r       io.Reader
+	counter packetCounter
+}
+
+func newPacketReader(r io.Reader) *packetReader {
+	return &packetReader{r: r}
+}
+
+// Next returns the next packet of a known type, or io.EOF at the end of the
+// packets.
+func (reader *packetReader) Next() (packet.Packet, error) {
+	for {
+		p, err := packet.Read(reader.r)
+		if _, ok := err.(pgpErrors.UnknownPacketTypeError); ok {
+			if err := reader.counter.add(false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := reader.counter.addPacket(p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+}
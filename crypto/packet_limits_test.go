@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketCountLimits(t *testing.T) {
+	// 513 password-encrypted session key packets before the data packet
+	message, err := NewPGPMessageFromArmored(readTestFile("message_tooManyESKPackets", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	password := []byte("too many packets")
+
+	_, err = DecryptMessageWithPassword(message, password)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = message.SplitKeyPackets()
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = message.SeparateKeyAndData(1024, 0)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = DecryptSessionKeyWithPassword(message.GetBinary(), password)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = keyRingTestPrivate.DecryptSessionKey(message.GetBinary())
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, ok := message.GetEncryptionKeyIDs()
+	assert.False(t, ok)
+
+	SetMaxPacketCounts(1024, 0)
+	defer SetMaxPacketCounts(0, 0)
+
+	decrypted, err := DecryptMessageWithPassword(message, password)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "behind many key packets", decrypted.GetString())
+
+	split, err := message.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error while splitting, got:", err)
+	}
+	_, err = DecryptSessionKeyWithPassword(split.GetBinaryKeyPacket(), password)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+
+	SetMaxPacketCounts(1024, 100)
+	_, err = DecryptMessageWithPassword(message, password)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+}
+
+func TestPacketCountLimitsUnknownPackets(t *testing.T) {
+	encrypted, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("behind unknown packets"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	// Empty packets of the private tag 60, which the library skips
+	unknown := bytes.Repeat([]byte{0xc0 | 60, 0x00}, DefaultMaxPackets)
+	message := NewPGPMessage(append(unknown, encrypted.GetBinary()...))
+
+	_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = keyRingTestPrivate.DecryptSessionKey(message.GetBinary())
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, err = message.SeparateKeyAndData(1024, 0)
+	assert.True(t, errors.Is(err, ErrTooManyPackets))
+	_, ok := message.GetEncryptionKeyIDs()
+	assert.False(t, ok)
+
+	SetMaxPacketCounts(0, 2*DefaultMaxPackets)
+	defer SetMaxPacketCounts(0, 0)
+
+	decrypted, err := keyRingTestPrivate.Decrypt(message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "behind unknown packets", decrypted.GetString())
+}
+
+func BenchmarkDecryptTooManyESKPackets(b *testing.B) {
+	// A million password-encrypted session key packets, of which only the
+	// first ones are parsed
+	skesk := []byte{0xc3, 0x04, 0x04, 0x09, 0x00, 0x08}
+	message := NewPGPMessage(bytes.Repeat(skesk, 1<<20))
+	password := []byte("too many packets")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecryptMessageWithPassword(message, password); !errors.Is(err, ErrTooManyPackets) {
+			b.Fatal("Expected ErrTooManyPackets, got:", err)
+		}
+	}
+}
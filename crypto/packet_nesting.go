@@ -0,0 +1,240 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	goerrors "errors"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// DefaultMaxPacketNesting is the default maximum number of nested compressed
+// packets in a message. Legitimate messages use at most one level.
+const DefaultMaxPacketNesting = 4
+
+// ErrPacketNestingTooDeep is returned when decrypting a message whose
+// compressed packets are nested deeper than the limit, see SetMaxPacketNesting.
+var ErrPacketNestingTooDeep = goerrors.New("gopenpgp: packet nesting too deep")
+
+// SetMaxPacketNesting sets the maximum number of nested compressed packets
+// accepted when decrypting messages. If depth is 0, DefaultMaxPacketNesting
+// is used.
+func SetMaxPacketNesting(depth int) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.maxPacketNesting = depth
+}
+
+func getMaxPacketNesting() int {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+	if pgp.maxPacketNesting == 0 {
+		return DefaultMaxPacketNesting
+	}
+	return pgp.maxPacketNesting
+}
+
+// readMessage reads a message like openpgp.ReadMessage, decrypting it with
+// keyring or password, but limits the nesting of the packets of the decrypted
+// data, see readLiteralMessage. Returns how the message is encrypted, and its
+// prefixed signatures.
+func readMessage(
+	r io.Reader, keyring openpgp.EntityList, password []byte, config *packet.Config,
+) (*openpgp.MessageDetails, *encryptionDetails, error) {
+	buffered := bufio.NewReader(r)
+	recorder := &recordingReader{in: buffered, recording: true}
+	packets := newPacketReader(recorder)
+
+	var encryptedKeys []*packet.EncryptedKey
+	var symKeys []*packet.SymmetricKeyEncrypted
+	var edp packet.EncryptedDataPacket
+
+	var edpStart int
+	var skipper keyPacketSkipper
+
+ParsePackets:
+	for {
+		edpStart = recorder.recorded.Len()
+		p, err := skipper.next(packets, buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			encryptedKeys = append(encryptedKeys, p)
+		case *packet.SymmetricKeyEncrypted:
+			symKeys = append(symKeys, p)
+		case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
+			edp = p.(packet.EncryptedDataPacket)
+			break ParsePackets
+		case *packet.Compressed, *packet.LiteralData, *packet.OnePassSignature:
+			if len(encryptedKeys) != 0 || len(symKeys) != 0 || len(skipper.skipped) != 0 {
+				return nil, nil, pgpErrors.StructuralError("key material not followed by encrypted message")
+			}
+			// The message isn't encrypted: parse it again from the start
+			plaintext := io.MultiReader(&recorder.recorded, buffered)
+			md, prefixedSignatures, err := readLiteralMessage(plaintext, keyring, config)
+			return md, &encryptionDetails{
+				protection:         getProtection(nil, nil),
+				prefixedSignatures: prefixedSignatures,
+			}, err
+		}
+	}
+	recorder.recording = false
+	details := &encryptionDetails{protection: getProtection(edp, recorder.recorded.Bytes()[edpStart:])}
+
+	decrypted, cipherFunc, decryptionKey, err := decryptDataPacket(edp, encryptedKeys, symKeys, keyring, password, config, &skipper, details)
+	if err != nil {
+		return nil, nil, err
+	}
+	details.cipherFunc = cipherFunc
+	reportDecryptionDeprecations(cipherFunc, decryptionKey)
+	details.decryptionKeyExpired = decryptionKey != nil && !isDecryptionKeyValid(*decryptionKey, config.Now())
+	md, prefixedSignatures, err := readLiteralMessage(decrypted, keyring, config)
+	if goerrors.Is(err, ErrUnexpectedPacket) || goerrors.Is(err, ErrPacketNestingTooDeep) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, pgpErrors.StructuralError("parsing error")
+	}
+	details.prefixedSignatures = prefixedSignatures
+	md.IsEncrypted = true
+	md.IsSymmetricallyEncrypted = len(symKeys) != 0
+	if decryptionKey != nil {
+		md.DecryptedWith = *decryptionKey
+	}
+	for _, ek := range encryptedKeys {
+		md.EncryptedToKeyIds = append(md.EncryptedToKeyIds, ek.KeyId)
+	}
+	md.UnverifiedBody = &integrityCheckReader{body: md.UnverifiedBody, decrypted: decrypted}
+	return md, details, nil
+}
+
+// decryptDataPacket decrypts the encrypted data packet with the first session
+// key which can be decrypted with the unlocked keys of keyring or password,
+// and returns the cipher of the session key, and the key which decrypted it if
+// any. Any key held by keyring is tried, even if it is expired or revoked.
+// The encrypted session key packets which can't be decrypted are recorded by
+// skipper, and the symmetric key encrypted session key packet which decrypted
+// the session key with password, if any, is set on details.
+func decryptDataPacket(
+	edp packet.EncryptedDataPacket,
+	encryptedKeys []*packet.EncryptedKey,
+	symKeys []*packet.SymmetricKeyEncrypted,
+	keyring openpgp.EntityList,
+	password []byte,
+	config *packet.Config,
+	skipper *keyPacketSkipper,
+	details *encryptionDetails,
+) (io.ReadCloser, packet.CipherFunction, *openpgp.Key, error) {
+	for _, ek := range encryptedKeys {
+		var keys []openpgp.Key
+		if ek.KeyId == 0 {
+			keys = keyring.DecryptionKeys()
+		} else {
+			keys = keyring.KeysById(ek.KeyId)
+		}
+		reason := "no matching decryption key"
+		var invalidKey error
+		for _, key := range keys {
+			if key.PrivateKey == nil {
+				continue
+			}
+			if key.PrivateKey.Encrypted {
+				reason = "the decryption key is locked"
+				continue
+			}
+			if len(ek.Key) == 0 {
+				if err := checkECDHEphemeralKey(ek, key.PrivateKey); err != nil {
+					invalidKey = err
+					continue
+				}
+				if err := ek.Decrypt(key.PrivateKey, config); err != nil {
+					reason = err.Error()
+					continue
+				}
+			}
+			if se, ok := edp.(*packet.SymmetricallyEncrypted); ok {
+				if err := checkDataPacketCipher(se, ek.CipherFunc, ek.Key); err != nil {
+					return nil, 0, nil, err
+				}
+			}
+			decrypted, err := edp.Decrypt(ek.CipherFunc, ek.Key)
+			if err != nil && !goerrors.Is(err, pgpErrors.ErrKeyIncorrect) {
+				return nil, 0, nil, err
+			}
+			if decrypted != nil {
+				decryptionKey := key
+				return decrypted, ek.CipherFunc, &decryptionKey, nil
+			}
+			reason = "the session key doesn't decrypt the data packet"
+		}
+		if invalidKey != nil {
+			skipper.skipInvalid(ek, invalidKey)
+			continue
+		}
+		skipper.skip(ek, reason)
+	}
+
+	if password != nil {
+		for _, symKey := range symKeys {
+			key, cipherFunc, skesk, err := decryptSKESK(symKey, password)
+			if err != nil {
+				continue
+			}
+			decrypted, err := edp.Decrypt(cipherFunc, key)
+			if err != nil && !goerrors.Is(err, pgpErrors.ErrKeyIncorrect) {
+				return nil, 0, nil, err
+			}
+			if decrypted != nil {
+				details.skesk = skesk
+				return decrypted, cipherFunc, nil, nil
+			}
+		}
+		if len(symKeys) == 0 && skipper.unsupportedSKESK != nil {
+			return nil, 0, nil, skipper.unsupportedSKESK
+		}
+	}
+	return nil, 0, nil, skipper.err()
+}
+
+// recordingReader records the data read until recording is stopped.
+type recordingReader struct {
+	in        io.Reader
+	recorded  bytes.Buffer
+	recording bool
+}
+
+func (r *recordingReader) Read(b []byte) (n int, err error) {
+	n, err = r.in.Read(b)
+	if r.recording {
+		_, _ = r.recorded.Write(b[:n])
+	}
+	return n, err
+}
+
+// integrityCheckReader checks the integrity of the decrypted data, by closing
+// it, once the message body has been read.
+type integrityCheckReader struct {
+	body      io.Reader
+	decrypted io.ReadCloser
+	closed    bool
+	closeErr  error
+}
+
+func (r *integrityCheckReader) Read(b []byte) (n int, err error) {
+	n, err = r.body.Read(b)
+	if goerrors.Is(err, io.EOF) {
+		if !r.closed {
+			r.closed = true
+			r.closeErr = r.decrypted.Close()
+		}
+		if r.closeErr != nil {
+			return n, r.closeErr
+		}
+	}
+	return n, err
+}
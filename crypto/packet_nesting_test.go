@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketNestingLimit(t *testing.T) {
+	// 10 nested compressed packets
+	testPacketNestingLimit(t, "message_nestedCompression")
+	// The same packets, following a private packet of 20000 bytes
+	testPacketNestingLimit(t, "message_nestedCompressionLargePrefix")
+}
+
+func testPacketNestingLimit(t *testing.T, fixture string) {
+	message, err := NewPGPMessageFromArmored(readTestFile(fixture, false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	split, err := message.SeparateKeyAndData(1024, 0)
+	if err != nil {
+		t.Fatal("Expected no error while splitting, got:", err)
+	}
+	sk, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+
+	_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+	assert.True(t, errors.Is(err, ErrPacketNestingTooDeep))
+	_, err = keyRingTestPrivate.DecryptStream(message.NewReader(), nil, 0)
+	assert.True(t, errors.Is(err, ErrPacketNestingTooDeep))
+	_, err = keyRingTestPrivate.DecryptAttachment(split)
+	assert.True(t, errors.Is(err, ErrPacketNestingTooDeep))
+	_, err = sk.Decrypt(split.GetBinaryDataPacket())
+	assert.True(t, errors.Is(err, ErrPacketNestingTooDeep))
+
+	password := []byte("password")
+	passwordKeyPacket, err := EncryptSessionKeyWithPassword(sk, password)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	passwordMessage := NewPGPSplitMessage(passwordKeyPacket, split.GetBinaryDataPacket()).GetPGPMessage()
+	_, err = DecryptMessageWithPassword(passwordMessage, password)
+	assert.True(t, errors.Is(err, ErrPacketNestingTooDeep))
+
+	SetMaxPacketNesting(10)
+	defer SetMaxPacketNesting(0)
+
+	decrypted, err := keyRingTestPrivate.Decrypt(message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "nested message", decrypted.GetString())
+
+	reader, err := keyRingTestPrivate.DecryptStream(message.NewReader(), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading stream, got:", err)
+	}
+	assert.Exactly(t, "nested message", string(data))
+
+	decrypted, err = sk.Decrypt(split.GetBinaryDataPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, "nested message", decrypted.GetString())
+
+	decrypted, err = DecryptMessageWithPassword(passwordMessage, password)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with password, got:", err)
+	}
+	assert.Exactly(t, "nested message", decrypted.GetString())
+}
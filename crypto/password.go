@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 
@@ -8,6 +9,7 @@ import (
 	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
 // EncryptMessageWithPassword encrypts a PlainMessage to PGPMessage with a
@@ -28,21 +30,29 @@ func EncryptMessageWithPassword(message *PlainMessage, password []byte) (*PGPMes
 // * encrypted: The encrypted data as PGPMessage.
 // * password: A password that will be derived into an encryption key.
 // * output: The decrypted data as PlainMessage.
+// The version and cipher of the packet which decrypted the message are
+// returned by PlainMessage.GetSKESKDetails. Returns an
+// UnsupportedSKESKVersionError if the packets all have an unsupported version.
 func DecryptMessageWithPassword(message *PGPMessage, password []byte) (*PlainMessage, error) {
 	return passwordDecrypt(message.NewReader(), password)
 }
 
 // DecryptSessionKeyWithPassword decrypts the binary symmetrically encrypted
 // session key packet and returns the session key.
+// Returns an UnsupportedSKESKVersionError if the packets all have an
+// unsupported version.
 func DecryptSessionKeyWithPassword(keyPacket, password []byte) (*SessionKey, error) {
-	keyReader := bytes.NewReader(keyPacket)
-	packets := packet.NewReader(keyReader)
+	keyReader := bufio.NewReader(bytes.NewReader(keyPacket))
+	packets := newPacketReader(keyReader)
 
 	var symKeys []*packet.SymmetricKeyEncrypted
+	var skipper keyPacketSkipper
 	for {
-		var p packet.Packet
-		var err error
-		if p, err = packets.Next(); err != nil {
+		p, err := skipper.next(packets, keyReader)
+		if errors.Is(err, ErrTooManyPackets) {
+			return nil, err
+		}
+		if err != nil {
 			break
 		}
 
@@ -50,11 +60,14 @@ func DecryptSessionKeyWithPassword(keyPacket, password []byte) (*SessionKey, err
 			symKeys = append(symKeys, p)
 		}
 	}
+	if len(symKeys) == 0 && skipper.unsupportedSKESK != nil {
+		return nil, skipper.unsupportedSKESK
+	}
 
 	// Try the symmetric passphrase first
 	if len(symKeys) != 0 && password != nil {
 		for _, s := range symKeys {
-			key, cipherFunc, err := s.Decrypt(password)
+			key, cipherFunc, _, err := decryptSKESK(s, password)
 			if err == nil {
 				sk := &SessionKey{
 					Key:  key,
@@ -92,6 +105,7 @@ func EncryptSessionKeyWithPassword(sk *SessionKey, password []byte) ([]byte, err
 	}
 
 	config := &packet.Config{
+		Rand:          getRandom(),
 		DefaultCipher: cf,
 	}
 
@@ -105,13 +119,22 @@ func EncryptSessionKeyWithPassword(sk *SessionKey, password []byte) ([]byte, err
 // ----- INTERNAL FUNCTIONS ------
 
 func passwordEncrypt(message *PlainMessage, password []byte) ([]byte, error) {
+	if err := checkPlaintextSize(message); err != nil {
+		return nil, err
+	}
 	var outBuf bytes.Buffer
 
 	config := &packet.Config{
+		Rand:          getRandom(),
 		DefaultCipher: packet.CipherAES256,
 		Time:          getTimeGenerator(),
 	}
 
+	if message.GetFormat() == constants.LITERAL_FORMAT_UTF8 {
+		// The library can't write this format, see passwordEncryptWithSessionKey
+		return passwordEncryptWithSessionKey(message, password, config)
+	}
+
 	hints := &openpgp.FileHints{
 		IsBinary: message.IsBinary(),
 		FileName: message.Filename,
@@ -135,25 +158,38 @@ func passwordEncrypt(message *PlainMessage, password []byte) ([]byte, error) {
 	return outBuf.Bytes(), nil
 }
 
-func passwordDecrypt(encryptedIO io.Reader, password []byte) (*PlainMessage, error) {
-	firstTimeCalled := true
-	var prompt = func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
-		if firstTimeCalled {
-			firstTimeCalled = false
-			return password, nil
-		}
-		// Re-prompt still occurs if SKESK pasrsing fails (i.e. when decrypted cipher algo is invalid).
-		// For most (but not all) cases, inputting a wrong passwords is expected to trigger this error.
-		return nil, errors.New("gopenpgp: wrong password in symmetric decryption")
+// passwordEncryptWithSessionKey encrypts the message with a new session key,
+// for the literal data formats which the library can't write.
+func passwordEncryptWithSessionKey(message *PlainMessage, password []byte, config *packet.Config) ([]byte, error) {
+	sk, err := GenerateSessionKeyAlgo(getAlgo(config.Cipher()))
+	if err != nil {
+		return nil, err
 	}
+	defer sk.Clear()
 
+	keyPacket, err := EncryptSessionKeyWithPassword(sk, password)
+	if err != nil {
+		return nil, err
+	}
+	dataPacket, err := encryptWithSessionKey(message, sk, nil, config, false)
+	if err != nil {
+		return nil, err
+	}
+	return append(keyPacket, dataPacket...), nil
+}
+
+func passwordDecrypt(encryptedIO io.Reader, password []byte) (*PlainMessage, error) {
 	config := &packet.Config{
 		Time: getTimeGenerator(),
 	}
 
 	var emptyKeyRing openpgp.EntityList
-	md, err := openpgp.ReadMessage(encryptedIO, emptyKeyRing, prompt, config)
+	md, details, err := readMessage(encryptedIO, emptyKeyRing, password, config)
 	if err != nil {
+		if errors.Is(err, ErrPacketNestingTooDeep) || errors.Is(err, ErrTooManyPackets) ||
+			errors.Is(err, ErrUnsupportedSKESKVersion) {
+			return nil, err
+		}
 		// Parsing errors when reading the message are most likely caused by incorrect password, but we cannot know for sure
 		return nil, errors.New("gopenpgp: error in reading password protected message: wrong password or malformed message")
 	}
@@ -175,5 +211,7 @@ func passwordDecrypt(encryptedIO io.Reader, password []byte) (*PlainMessage, err
 		TextType: !md.LiteralData.IsBinary,
 		Filename: md.LiteralData.FileName,
 		Time:     md.LiteralData.Time,
+		format:   getLiteralFormat(md.LiteralData.Format),
+		skesk:    details.skesk,
 	}, nil
 }
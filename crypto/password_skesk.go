@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bufio"
+	goerrors "errors"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// ErrUnsupportedSKESKVersion is matched by UnsupportedSKESKVersionError with
+// errors.Is.
+var ErrUnsupportedSKESKVersion = goerrors.New("gopenpgp: unsupported symmetric key encrypted session key version")
+
+// UnsupportedSKESKVersionError is returned when a message can't be decrypted
+// with a password because its symmetric key encrypted session key packets
+// (SKESK) all have an unsupported version, e.g. the version 6 of RFC 9580.
+// Only the versions 4 and 5 are supported.
+type UnsupportedSKESKVersionError struct {
+	Version int
+}
+
+// Error is the base method for all errors.
+func (e UnsupportedSKESKVersionError) Error() string {
+	return ErrUnsupportedSKESKVersion.Error() + " " + strconv.Itoa(e.Version)
+}
+
+// Is matches ErrUnsupportedSKESKVersion.
+func (e UnsupportedSKESKVersionError) Is(target error) bool {
+	return target == ErrUnsupportedSKESKVersion
+}
+
+// SKESKDetails describes the symmetric key encrypted session key packet which
+// decrypted a message with a password, see PlainMessage.GetSKESKDetails.
+type SKESKDetails struct {
+	// Version is the version of the packet, 4 or 5
+	Version int
+	// Algorithm is the cipher of the session key, e.g. constants.AES256
+	Algorithm string
+	// PacketAlgorithm is the cipher of the packet, which encrypts the session
+	// key. A version 4 packet either derives the session key from the
+	// password for this cipher, or encrypts a session key for another one.
+	PacketAlgorithm string
+	// AEADMode is the AEAD mode of a version 5 packet, e.g.
+	// constants.AEAD_MODE_OCB, empty for version 4
+	AEADMode string
+}
+
+// GetSKESKDetails returns the symmetric key encrypted session key packet which
+// decrypted the message, as set by DecryptMessageWithPassword, nil otherwise.
+func (msg *PlainMessage) GetSKESKDetails() *SKESKDetails {
+	if msg.skesk == nil {
+		return nil
+	}
+	details := *msg.skesk
+	return &details
+}
+
+// decryptSKESK decrypts the session key of ske with password, and returns it
+// with its cipher and the details of the packet.
+func decryptSKESK(ske *packet.SymmetricKeyEncrypted, password []byte) ([]byte, packet.CipherFunction, *SKESKDetails, error) {
+	key, cipherFunc, err := ske.Decrypt(password)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	details := &SKESKDetails{
+		Version:         ske.Version,
+		PacketAlgorithm: getAlgo(ske.CipherFunc),
+	}
+	if ske.Version == 5 {
+		// The library doesn't return the cipher of version 5 packets, which
+		// is the cipher of the packet
+		cipherFunc = ske.CipherFunc
+		details.AEADMode = getAEADModeName(ske.Mode)
+	}
+	details.Algorithm = getAlgo(cipherFunc)
+	return key, cipherFunc, details, nil
+}
+
+// peekSKESKVersion returns the version of the symmetric key encrypted session
+// key packet read next from r, or 0 if it can't be read.
+func peekSKESKVersion(r *bufio.Reader) int {
+	// The longest header, and the version
+	data, _ := r.Peek(7)
+	if peekPacketTag(r) != packetTagSymmetricKeyEncrypted || len(data) < 2 {
+		return 0
+	}
+	body := getRawPacketBody(data)
+	if len(body) == 0 {
+		return 0
+	}
+	return int(body[0])
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// The password of the messages encrypted by GnuPG 2.2
+var testGnuPGPassword = []byte("password")
+
+// unsupportedSKESK is a version 6 symmetric key encrypted session key packet.
+var unsupportedSKESK = []byte{
+	0xc3, 0x10, 6, 12, 9, 2, 4, 3, 2, 1, 2, 3, 4, 5, 6, 7, 8, 0xff,
+}
+
+func TestDecryptMessageWithPasswordGnuPG(t *testing.T) {
+	for _, file := range []string{
+		// The session key is derived from the password
+		"message_passwordGnuPG",
+		// The session key is encrypted in the packet, and to a key
+		"message_passwordGnuPGEncryptedKey",
+	} {
+		message, err := NewPGPMessageFromArmored(readTestFile(file, false))
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring, got:", err)
+		}
+		decrypted, err := DecryptMessageWithPassword(message, testGnuPGPassword)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting "+file+", got:", err)
+		}
+		assert.Exactly(t, "hello from gnupg", decrypted.GetString())
+		assert.Exactly(t, &SKESKDetails{
+			Version:         4,
+			Algorithm:       constants.AES256,
+			PacketAlgorithm: constants.AES256,
+		}, decrypted.GetSKESKDetails(), file)
+
+		keyPackets, dataPacket := splitTestKeyPackets(t, message)
+		sessionKey, err := DecryptSessionKeyWithPassword(keyPackets, testGnuPGPassword)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting session key, got:", err)
+		}
+		assert.Exactly(t, constants.AES256, sessionKey.Algo)
+		decrypted, err = sessionKey.Decrypt(dataPacket)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting with session key, got:", err)
+		}
+		assert.Exactly(t, "hello from gnupg", decrypted.GetString())
+	}
+
+	// The session key encrypted with the password is the one encrypted to the key
+	message, err := NewPGPMessageFromArmored(readTestFile("message_passwordGnuPGEncryptedKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	keyPackets, _ := splitTestKeyPackets(t, message)
+	passwordSessionKey, err := DecryptSessionKeyWithPassword(keyPackets, testGnuPGPassword)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting session key, got:", err)
+	}
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(keyPackets)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting session key with key, got:", err)
+	}
+	assert.Exactly(t, sessionKey, passwordSessionKey)
+}
+
+func TestDecryptMessageWithPasswordV5(t *testing.T) {
+	for _, mode := range []packet.AEADMode{packet.AEADModeEAX, packet.AEADModeOCB} {
+		var encrypted bytes.Buffer
+		config := &packet.Config{
+			DefaultCipher: packet.CipherAES128,
+			AEADConfig:    &packet.AEADConfig{DefaultMode: mode},
+			Time:          getTimeGenerator(),
+		}
+		w, err := openpgp.SymmetricallyEncrypt(&encrypted, testGnuPGPassword, nil, config)
+		if err != nil {
+			t.Fatal("Expected no error when encrypting, got:", err)
+		}
+		if _, err := w.Write([]byte("hello from v5")); err != nil {
+			t.Fatal("Expected no error when writing, got:", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal("Expected no error when closing, got:", err)
+		}
+		message := NewPGPMessage(encrypted.Bytes())
+
+		decrypted, err := DecryptMessageWithPassword(message, testGnuPGPassword)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, "hello from v5", decrypted.GetString())
+		assert.Exactly(t, &SKESKDetails{
+			Version:         5,
+			Algorithm:       constants.AES128,
+			PacketAlgorithm: constants.AES128,
+			AEADMode:        getAEADModeName(mode),
+		}, decrypted.GetSKESKDetails())
+
+		// The cipher of the session key is the cipher of the packet
+		keyPackets, dataPacket := splitTestKeyPackets(t, message)
+		sessionKey, err := DecryptSessionKeyWithPassword(keyPackets, testGnuPGPassword)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting session key, got:", err)
+		}
+		assert.Exactly(t, constants.AES128, sessionKey.Algo)
+		decrypted, err = sessionKey.Decrypt(dataPacket)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting with session key, got:", err)
+		}
+		assert.Exactly(t, "hello from v5", decrypted.GetString())
+	}
+}
+
+func TestDecryptMessageWithPasswordUnsupportedVersion(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_passwordGnuPG", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	keyPackets, dataPacket := splitTestKeyPackets(t, message)
+
+	onlyUnsupported := append(clone(unsupportedSKESK), dataPacket...)
+	_, err = DecryptMessageWithPassword(NewPGPMessage(onlyUnsupported), testGnuPGPassword)
+	assert.True(t, errors.Is(err, ErrUnsupportedSKESKVersion), err)
+	var versionErr UnsupportedSKESKVersionError
+	assert.True(t, errors.As(err, &versionErr))
+	assert.Exactly(t, 6, versionErr.Version)
+	_, err = DecryptSessionKeyWithPassword(unsupportedSKESK, testGnuPGPassword)
+	assert.True(t, errors.Is(err, ErrUnsupportedSKESKVersion), err)
+
+	// The unsupported packets are skipped
+	withUnsupported := append(append(clone(unsupportedSKESK), keyPackets...), dataPacket...)
+	decrypted, err := DecryptMessageWithPassword(NewPGPMessage(withUnsupported), testGnuPGPassword)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello from gnupg", decrypted.GetString())
+	_, err = DecryptSessionKeyWithPassword(withUnsupported[:len(unsupportedSKESK)+len(keyPackets)], testGnuPGPassword)
+	assert.NoError(t, err)
+
+	// A wrong password isn't reported as an unsupported version
+	_, err = DecryptMessageWithPassword(NewPGPMessage(withUnsupported), []byte("wrong"))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUnsupportedSKESKVersion))
+}
@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	goerrors "errors"
+	"fmt"
+)
+
+// ErrPlaintextTooLarge is matched by PlaintextTooLargeError with errors.Is.
+var ErrPlaintextTooLarge = goerrors.New("gopenpgp: plaintext too large")
+
+// PlaintextTooLargeError is returned when encrypting or signing a PlainMessage
+// larger than the maximum, see SetMaxPlaintextSize.
+type PlaintextTooLargeError struct {
+	Limit int64
+	Size  int64
+}
+
+// Error is the base method for all errors.
+func (e PlaintextTooLargeError) Error() string {
+	return fmt.Sprintf(
+		"gopenpgp: plaintext of %d bytes exceeds the maximum of %d bytes, use the streaming API to process it",
+		e.Size, e.Limit,
+	)
+}
+
+// Is matches ErrPlaintextTooLarge.
+func (e PlaintextTooLargeError) Is(target error) bool {
+	return target == ErrPlaintextTooLarge
+}
+
+// SetMaxPlaintextSize sets the maximum size in bytes of the PlainMessage
+// encrypted or signed by the buffered functions, such as KeyRing.Encrypt,
+// SessionKey.Encrypt or KeyRing.SignDetached, which otherwise return a
+// PlaintextTooLargeError. The streaming functions are not limited.
+// If size is 0, the default, the size is not limited.
+func SetMaxPlaintextSize(size int64) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.maxPlaintextSize = size
+}
+
+// checkPlaintextSize returns a PlaintextTooLargeError if the message exceeds
+// the maximum size.
+func checkPlaintextSize(message *PlainMessage) error {
+	size := int64(len(message.GetBinary()))
+	pgp.lock.RLock()
+	maxSize := pgp.maxPlaintextSize
+	pgp.lock.RUnlock()
+	if maxSize > 0 && size > maxSize {
+		return PlaintextTooLargeError{Limit: maxSize, Size: size}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxPlaintextSize(t *testing.T) {
+	SetMaxPlaintextSize(16)
+	defer SetMaxPlaintextSize(0)
+
+	small := NewPlainMessage(bytes.Repeat([]byte{'a'}, 16))
+	large := NewPlainMessage(bytes.Repeat([]byte{'a'}, 17))
+
+	_, err := keyRingTestPublic.Encrypt(large, nil)
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	var sizeErr PlaintextTooLargeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal("Expected a PlaintextTooLargeError, got:", err)
+	}
+	assert.Exactly(t, PlaintextTooLargeError{Limit: 16, Size: 17}, sizeErr)
+
+	_, err = keyRingTestPublic.Encrypt(large, keyRingTestPrivate)
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	_, err = keyRingTestPrivate.SignDetached(large)
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	_, err = keyRingTestPublic.EncryptAttachment(large, "")
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	_, err = testSessionKey.Encrypt(large)
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	_, err = testSessionKey.EncryptAndSign(large, keyRingTestPrivate)
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+	_, err = EncryptMessageWithPassword(large, []byte("password"))
+	assert.True(t, errors.Is(err, ErrPlaintextTooLarge))
+
+	if _, err := keyRingTestPublic.Encrypt(small, nil); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err := testSessionKey.Encrypt(small); err != nil {
+		t.Fatal("Expected no error while encrypting with session key, got:", err)
+	}
+
+	// The streaming functions are not limited
+	var encrypted bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&encrypted, nil, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if _, err := writer.Write(large.GetBinary()); err != nil {
+		t.Fatal("Expected no error while writing stream, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing stream, got:", err)
+	}
+	reader, err := keyRingTestPrivate.DecryptStream(&encrypted, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading stream, got:", err)
+	}
+	assert.Exactly(t, large.GetBinary(), data)
+}
@@ -0,0 +1,133 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// maxAEADChunkSizeByte is the largest chunk size byte of an AEAD encrypted
+// data packet whose chunk size fits in an int64.
+const maxAEADChunkSizeByte = 56
+
+// Protection describes the data packet of an encrypted message.
+type Protection struct {
+	// One of constants.PROTECTION_LEVEL_NONE, PROTECTION_LEVEL_MDC or
+	// PROTECTION_LEVEL_AEAD
+	Level int
+	// The AEAD mode, e.g. constants.AEAD_MODE_OCB, empty without AEAD
+	AEADMode string
+	// The size of the AEAD chunks in bytes, 0 without AEAD
+	AEADChunkSize int64
+}
+
+// GetProtection returns how the data of the message was protected, as set by
+// KeyRing.Decrypt and the SessionKey decryption functions.
+func (msg *PlainMessage) GetProtection() *Protection {
+	if msg.protection == nil {
+		return &Protection{Level: constants.PROTECTION_LEVEL_NONE}
+	}
+	protection := *msg.protection
+	return &protection
+}
+
+// GetProtection returns how the data of the message is protected.
+func (msg *PlainMessageReader) GetProtection() *Protection {
+	protection := *msg.protection
+	return &protection
+}
+
+// GetProtection returns how the data of the encrypted message is protected,
+// e.g. to check whether encrypting it used AEAD, see
+// KeyRing.SetEnableModernCrypto.
+func (msg *PGPMessage) GetProtection() (*Protection, error) {
+	_, dataPacket, err := splitKeyPackets(msg.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	p, err := packet.Read(bytes.NewReader(dataPacket))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read data packet")
+	}
+	edp, ok := p.(packet.EncryptedDataPacket)
+	if !ok {
+		return nil, errors.New("gopenpgp: no encrypted data packet after the key packets")
+	}
+	return getProtection(edp, dataPacket), nil
+}
+
+// encryptionDetails describes the encrypted data packet of a decrypted message.
+type encryptionDetails struct {
+	// The cipher of the data packet, 0 if the message isn't encrypted
+	cipherFunc packet.CipherFunction
+	protection *Protection
+	// Whether the session key was decrypted with an expired or revoked key
+	decryptionKeyExpired bool
+	// The packet which decrypted the session key with a password, if any
+	skesk *SKESKDetails
+	// The signature packets before the literal data, see readLiteralMessage
+	prefixedSignatures [][]byte
+}
+
+// getProtection returns the protection of the encrypted data packet edp,
+// given the bytes read to parse it, starting with its header.
+func getProtection(edp packet.EncryptedDataPacket, header []byte) *Protection {
+	switch edp.(type) {
+	case *packet.SymmetricallyEncrypted:
+		// The library rejects data packets without MDC when parsing them
+		return &Protection{Level: constants.PROTECTION_LEVEL_MDC}
+	case *packet.AEADEncrypted:
+		protection := &Protection{Level: constants.PROTECTION_LEVEL_AEAD}
+		// The library doesn't expose the AEAD parameters: read them from
+		// the version, cipher, mode and chunk size bytes after the header.
+		body := getPacketBody(header)
+		if len(body) >= 4 {
+			protection.AEADMode = getAEADModeName(packet.AEADMode(body[2]))
+			if body[3] <= maxAEADChunkSizeByte {
+				protection.AEADChunkSize = int64(1) << (body[3] + 6)
+			}
+		}
+		return protection
+	}
+	return &Protection{Level: constants.PROTECTION_LEVEL_NONE}
+}
+
+// getPacketBody returns the start of the body of the new format packet at the
+// start of data, or nil if its header is incomplete.
+func getPacketBody(data []byte) []byte {
+	if len(data) < 2 || data[0]&0xc0 != 0xc0 {
+		return nil
+	}
+	var headerLength int
+	switch {
+	case data[1] < 192:
+		headerLength = 2
+	case data[1] < 224:
+		headerLength = 3
+	case data[1] < 255:
+		// Partial length
+		headerLength = 2
+	default:
+		headerLength = 6
+	}
+	if len(data) < headerLength {
+		return nil
+	}
+	return data[headerLength:]
+}
+
+// getAEADModeName returns the name of an AEAD mode, or its ID if it is unknown.
+func getAEADModeName(mode packet.AEADMode) string {
+	switch mode {
+	case packet.AEADModeEAX:
+		return constants.AEAD_MODE_EAX
+	case packet.AEADModeOCB:
+		return constants.AEAD_MODE_OCB
+	case packet.AEADModeExperimentalGCM:
+		return constants.AEAD_MODE_GCM
+	}
+	return fmt.Sprintf("mode %d", mode)
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestMessageProtection(t *testing.T) {
+	sessionKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+
+	for _, test := range []struct {
+		file       string
+		plaintext  string
+		protection *Protection
+	}{
+		{
+			file:       "message_protectionMDC",
+			plaintext:  "MDC protection",
+			protection: &Protection{Level: constants.PROTECTION_LEVEL_MDC},
+		},
+		{
+			file:      "message_protectionAEAD",
+			plaintext: "AEAD protection",
+			protection: &Protection{
+				Level:         constants.PROTECTION_LEVEL_AEAD,
+				AEADMode:      constants.AEAD_MODE_OCB,
+				AEADChunkSize: 4096,
+			},
+		},
+	} {
+		message, err := NewPGPMessageFromArmored(readTestFile(test.file, false))
+		if err != nil {
+			t.Fatal("Expected no error when unarmoring, got:", err)
+		}
+
+		decrypted, err := keyRingTestPrivate.Decrypt(message, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, test.plaintext, decrypted.GetString())
+		assert.Exactly(t, test.protection, decrypted.GetProtection())
+
+		keyPacket, dataPacket := splitProtectionTestMessage(t, message)
+
+		reader, err := keyRingTestPrivate.DecryptSplitStream(keyPacket, bytes.NewReader(dataPacket), nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting the stream, got:", err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal("Expected no error when reading the stream, got:", err)
+		}
+		assert.Exactly(t, test.plaintext, string(data))
+		assert.Exactly(t, test.protection, reader.GetProtection())
+
+		decrypted, err = sessionKey.Decrypt(dataPacket)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting with the session key, got:", err)
+		}
+		assert.Exactly(t, test.plaintext, decrypted.GetString())
+		assert.Exactly(t, test.protection, decrypted.GetProtection())
+	}
+}
+
+func TestMessageProtectionLegacyRejected(t *testing.T) {
+	message, err := NewPGPMessageFromArmored(readTestFile("message_protectionLegacy", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	_, err = keyRingTestPrivate.Decrypt(message, nil, 0)
+	assert.Error(t, err)
+
+	_, dataPacket := splitProtectionTestMessage(t, message)
+	sessionKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+	_, err = sessionKey.Decrypt(dataPacket)
+	assert.Error(t, err)
+}
+
+func TestMessageProtectionNotEncrypted(t *testing.T) {
+	message := NewPlainMessageFromString("plain text")
+	assert.Exactly(t, constants.PROTECTION_LEVEL_NONE, message.GetProtection().Level)
+}
+
+// splitProtectionTestMessage splits the key packet from the data packet,
+// which SeparateKeyAndData only supports for SEIPD packets.
+func splitProtectionTestMessage(t *testing.T, message *PGPMessage) (keyPacket, dataPacket []byte) {
+	data := message.GetBinary()
+	reader := bytes.NewReader(data)
+	if _, err := packet.Read(reader); err != nil {
+		t.Fatal("Expected no error when reading the key packet, got:", err)
+	}
+	split := len(data) - reader.Len()
+	return data[:split], data[split:]
+}
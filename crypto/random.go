@@ -0,0 +1,22 @@
+package crypto
+
+import (
+	"io"
+)
+
+// getRandom returns the source of randomness of the encryption, signing and
+// key generation functions, nil for crypto/rand.Reader.
+func getRandom() io.Reader {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+	return pgp.random
+}
+
+// setRandomSource replaces the source of randomness, in tests producing
+// reproducible output. It must never be used outside of tests: with a
+// predictable source, session keys and signatures are predictable.
+func setRandomSource(random io.Reader) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.random = random
+}
@@ -0,0 +1,21 @@
+//go:build gopenpgp_deterministic
+// +build gopenpgp_deterministic
+
+package crypto
+
+import (
+	"io"
+)
+
+// SetRandomSource replaces the source of all the randomness of the
+// encryption, signing and key generation functions, e.g. the session keys,
+// initialization vectors, ephemeral keys and signature nonces, so that golden
+// files can be compared byte for byte. A nil source restores crypto/rand.
+// It is only built with the gopenpgp_deterministic build tag, and must never
+// be used in production: with a predictable source, the encryption and the
+// keys are broken.
+// RSA encryption and ECDSA signatures may still be nondeterministic, since the
+// standard library randomly reads an extra byte from the source.
+func SetRandomSource(random io.Reader) {
+	setRandomSource(random)
+}
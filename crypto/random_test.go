@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	mathrand "math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptAndSignWithRandom(t *testing.T, keyRing *KeyRing, fixed bool) []byte {
+	if fixed {
+		setRandomSource(mathrand.New(mathrand.NewSource(42))) //nolint:gosec
+		defer setRandomSource(nil)
+	}
+
+	message := NewPlainMessage([]byte("reproducible message"))
+	message.Time = 1557754627
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := keyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	dataPacket, err := sessionKey.EncryptAndSign(message, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting and signing, got:", err)
+	}
+	encrypted, err := keyRing.Encrypt(message, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting and signing, got:", err)
+	}
+
+	output := append(keyPacket, dataPacket...)
+	return append(output, encrypted.GetBinary()...)
+}
+
+func TestEncryptAndSignWithFixedRandom(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_stubPrimary", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	unlockedKey, err := key.Unlock([]byte(stubKeyPassphrase))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	keyRing, err := NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	first := encryptAndSignWithRandom(t, keyRing, true)
+	second := encryptAndSignWithRandom(t, keyRing, true)
+	assert.Exactly(t, first, second)
+
+	// The output is the same on every platform
+	digest := sha256.Sum256(first)
+	assert.Exactly(t, "1a04724548a60d09460cfb1e2df6bbd2c2eb71af34b5ff24683e265ba7151250", hex.EncodeToString(digest[:]))
+
+	// The default source is random again
+	assert.NotEqual(t, first, encryptAndSignWithRandom(t, keyRing, false))
+}
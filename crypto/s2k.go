@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"crypto"
+
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+const (
+	// S2KSaltLength is the length of the salt of the iterated and salted S2K.
+	S2KSaltLength = 8
+	// S2KMinCount and S2KMaxCount bound the number of hashed bytes.
+	S2KMinCount = 65536
+	S2KMaxCount = 65011712
+	// S2KDefaultCount is the number of hashed bytes used for password
+	// encrypted messages.
+	S2KDefaultCount = 16777216
+)
+
+var s2kHashes = map[string]crypto.Hash{
+	constants.SHA256: crypto.SHA256,
+	constants.SHA384: crypto.SHA384,
+	constants.SHA512: crypto.SHA512,
+}
+
+// S2KConfig configures the iterated and salted S2K (RFC 4880, section 3.7.1.3).
+type S2KConfig struct {
+	// Hash is the name of the hash function, constants.SHA256 if empty.
+	Hash string
+	// Count is the number of hashed bytes, between S2KMinCount and S2KMaxCount.
+	// It is rounded up to the next value encodable in a packet.
+	// If 0, S2KDefaultCount is used.
+	Count int
+	// Cipher is the name of the cipher encrypting the secret key material
+	// when locking keys, see Key.LockWithS2KConfig, constants.AES256 if empty.
+	// It is ignored when deriving session keys.
+	Cipher string
+}
+
+// SetCipher sets the Cipher of the configuration from a typed cipher.
+func (s2kConfig *S2KConfig) SetCipher(cipher constants.Cipher) {
+	s2kConfig.Cipher = cipher.String()
+}
+
+// GenerateS2KSalt generates a random salt for DeriveSessionKeyFromPassword.
+func GenerateS2KSalt() ([]byte, error) {
+	return RandomToken(S2KSaltLength)
+}
+
+// DeriveSessionKeyFromPassword derives a session key for the algorithm algo
+// from a password, with the iterated and salted S2K used by password encrypted
+// messages. The same password, salt and configuration always derive the same key.
+// If s2kConfig is nil, the default configuration is used.
+func DeriveSessionKeyFromPassword(password, salt []byte, s2kConfig *S2KConfig, algo string) (*SessionKey, error) {
+	cipher, ok := constants.ParseCipher(algo)
+	if !ok {
+		return nil, errors.New("gopenpgp: unsupported cipher function: " + algo)
+	}
+	sk, err := DeriveSessionKeyFromPasswordWithCipher(password, salt, s2kConfig, cipher)
+	if err != nil {
+		return nil, err
+	}
+	sk.Algo = algo
+	return sk, nil
+}
+
+// DeriveSessionKeyFromPasswordWithCipher derives a session key for the cipher
+// from a password, like DeriveSessionKeyFromPassword.
+func DeriveSessionKeyFromPasswordWithCipher(
+	password, salt []byte, s2kConfig *S2KConfig, cipher constants.Cipher,
+) (*SessionKey, error) {
+	algo := cipher.String()
+	if len(password) == 0 {
+		return nil, errors.New("gopenpgp: password can't be empty")
+	}
+	if len(salt) != S2KSaltLength {
+		return nil, errors.Errorf("gopenpgp: the S2K salt must be %d bytes long, got %d", S2KSaltLength, len(salt))
+	}
+	cf, ok := symKeyAlgos[algo]
+	if !ok {
+		return nil, errors.New("gopenpgp: unsupported cipher function: " + algo)
+	}
+	hash, count, err := s2kConfig.getHashAndCount()
+	if err != nil {
+		return nil, err
+	}
+	// Round the count as it would be encoded in a packet
+	encodedCount := (&s2k.Config{S2KCount: count}).EncodedCount()
+	count = (16 + int(encodedCount&15)) << (uint32(encodedCount>>4) + 6)
+
+	key := make([]byte, cf.KeySize())
+	s2k.Iterated(key, hash.New(), password, salt, count)
+	return &SessionKey{
+		Key:  key,
+		Algo: algo,
+	}, nil
+}
+
+// getHashAndCount returns the hash and the count of the configuration, with
+// their default values if unset. A nil configuration has the default values.
+func (s2kConfig *S2KConfig) getHashAndCount() (crypto.Hash, int, error) {
+	if s2kConfig == nil {
+		s2kConfig = &S2KConfig{}
+	}
+
+	hashName := s2kConfig.Hash
+	if hashName == "" {
+		hashName = constants.SHA256
+	}
+	hash, ok := s2kHashes[hashName]
+	if !ok || !hash.Available() {
+		return 0, 0, errors.New("gopenpgp: unsupported S2K hash function: " + hashName)
+	}
+
+	count := s2kConfig.Count
+	if count == 0 {
+		count = S2KDefaultCount
+	}
+	if count < S2KMinCount || count > S2KMaxCount {
+		return 0, 0, errors.Errorf("gopenpgp: the S2K count must be between %d and %d, got %d", S2KMinCount, S2KMaxCount, count)
+	}
+	return hash, count, nil
+}
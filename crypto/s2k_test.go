@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestDeriveSessionKeyFromPassword(t *testing.T) {
+	// Vectors from the session keys of messages encrypted by GnuPG
+	var vectors = []struct {
+		salt   string
+		config *S2KConfig
+		algo   string
+		key    string
+	}{
+		{"8C237360FC1B2B6D", &S2KConfig{Hash: constants.SHA256, Count: 65536}, constants.AES256,
+			"9B8747178C6D91554ACF40FA502C01086937788C3186C012110ACA2D7C28D6C4"},
+		{"7CAA9B5A80B38CAE", &S2KConfig{Hash: constants.SHA512, Count: 1015808}, constants.AES128,
+			"2DE51230F733D0B6556F2794EE19B1E8"},
+	}
+
+	for _, vector := range vectors {
+		salt, _ := hex.DecodeString(vector.salt)
+		sk, err := DeriveSessionKeyFromPassword([]byte("correct horse"), salt, vector.config, vector.algo)
+		if err != nil {
+			t.Fatal("Expected no error while deriving session key, got:", err)
+		}
+		assert.Exactly(t, strings.ToLower(vector.key), hex.EncodeToString(sk.Key))
+		assert.Exactly(t, vector.algo, sk.Algo)
+	}
+
+	salt, err := GenerateS2KSalt()
+	if err != nil {
+		t.Fatal("Expected no error while generating salt, got:", err)
+	}
+	first, err := DeriveSessionKeyFromPassword([]byte("password"), salt, nil, constants.AES256)
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	second, err := DeriveSessionKeyFromPassword([]byte("password"), salt, &S2KConfig{Count: S2KDefaultCount}, constants.AES256)
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, first.Key, second.Key)
+
+	_, err = DeriveSessionKeyFromPassword([]byte("password"), salt[:4], nil, constants.AES256)
+	assert.Error(t, err)
+	_, err = DeriveSessionKeyFromPassword([]byte("password"), salt, &S2KConfig{Count: 1024}, constants.AES256)
+	assert.Error(t, err)
+	_, err = DeriveSessionKeyFromPassword([]byte("password"), salt, &S2KConfig{Hash: "md5"}, constants.AES256)
+	assert.Error(t, err)
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/yougroupteam/gopenpgp/v2/constants"
@@ -41,6 +40,16 @@ func (sk *SessionKey) GetCipherFunc() (packet.CipherFunction, error) {
 	return cf, nil
 }
 
+// GetCipher returns the cipher of the SessionKey, the typed alternative to
+// its Algo.
+func (sk *SessionKey) GetCipher() (constants.Cipher, error) {
+	cf, err := sk.GetCipherFunc()
+	if err != nil {
+		return 0, err
+	}
+	return constants.Cipher(cf), nil
+}
+
 // GetBase64Key returns the session key as base64 encoded string.
 func (sk *SessionKey) GetBase64Key() string {
 	return base64.StdEncoding.EncodeToString(sk.Key)
@@ -48,7 +57,7 @@ func (sk *SessionKey) GetBase64Key() string {
 
 // RandomToken generates a random token with the specified key size.
 func RandomToken(size int) ([]byte, error) {
-	config := &packet.Config{DefaultCipher: packet.CipherAES256}
+	config := &packet.Config{DefaultCipher: packet.CipherAES256, Rand: getRandom()}
 	symKey := make([]byte, size)
 	if _, err := io.ReadFull(config.Random(), symKey); err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in generating random token")
@@ -59,20 +68,33 @@ func RandomToken(size int) ([]byte, error) {
 // GenerateSessionKeyAlgo generates a random key of the correct length for the
 // specified algorithm.
 func GenerateSessionKeyAlgo(algo string) (sk *SessionKey, err error) {
-	cf, ok := symKeyAlgos[algo]
+	cipher, ok := constants.ParseCipher(algo)
 	if !ok {
 		return nil, errors.New("gopenpgp: unknown symmetric key generation algorithm")
 	}
-	r, err := RandomToken(cf.KeySize())
+	sk, err = GenerateSessionKeyWithCipher(cipher)
 	if err != nil {
 		return nil, err
 	}
+	sk.Algo = algo
+	return sk, nil
+}
 
-	sk = &SessionKey{
-		Key:  r,
-		Algo: algo,
+// GenerateSessionKeyWithCipher generates a random key of the correct length
+// for the cipher, like GenerateSessionKeyAlgo.
+func GenerateSessionKeyWithCipher(cipher constants.Cipher) (*SessionKey, error) {
+	cf, ok := symKeyAlgos[cipher.String()]
+	if !ok {
+		return nil, errors.New("gopenpgp: unknown symmetric key generation algorithm")
 	}
-	return sk, nil
+	r, err := RandomToken(cf.KeySize())
+	if err != nil {
+		return nil, err
+	}
+	return &SessionKey{
+		Key:  r,
+		Algo: cipher.String(),
+	}, nil
 }
 
 // GenerateSessionKey generates a random key for the default cipher.
@@ -87,6 +109,12 @@ func NewSessionKeyFromToken(token []byte, algo string) *SessionKey {
 	}
 }
 
+// NewSessionKeyFromTokenWithCipher creates a SessionKey from a key and its
+// cipher, like NewSessionKeyFromToken.
+func NewSessionKeyFromTokenWithCipher(token []byte, cipher constants.Cipher) *SessionKey {
+	return NewSessionKeyFromToken(token, cipher.String())
+}
+
 func newSessionKeyFromEncrypted(ek *packet.EncryptedKey) (*SessionKey, error) {
 	var algo string
 	for k, v := range symKeyAlgos {
@@ -121,11 +149,12 @@ func (sk *SessionKey) Encrypt(message *PlainMessage) ([]byte, error) {
 	}
 
 	config := &packet.Config{
+		Rand:          getRandom(),
 		Time:          getTimeGenerator(),
 		DefaultCipher: dc,
 	}
 
-	return encryptWithSessionKey(message, sk, nil, config)
+	return encryptWithSessionKey(message, sk, nil, config, false)
 }
 
 // EncryptAndSign encrypts a PlainMessage to PGPMessage with a SessionKey and signs it with a Private key.
@@ -139,6 +168,7 @@ func (sk *SessionKey) EncryptAndSign(message *PlainMessage, signKeyRing *KeyRing
 	}
 
 	config := &packet.Config{
+		Rand:          getRandom(),
 		Time:          getTimeGenerator(),
 		DefaultCipher: dc,
 	}
@@ -148,7 +178,7 @@ func (sk *SessionKey) EncryptAndSign(message *PlainMessage, signKeyRing *KeyRing
 		return nil, errors.Wrap(err, "gopenpgp: unable to sign")
 	}
 
-	return encryptWithSessionKey(message, sk, signEntity, config)
+	return encryptWithSessionKey(message, sk, signEntity, config, false)
 }
 
 // EncryptWithCompression encrypts with compression support a PlainMessage to PGPMessage with a SessionKey.
@@ -161,26 +191,76 @@ func (sk *SessionKey) EncryptWithCompression(message *PlainMessage) ([]byte, err
 	}
 
 	config := &packet.Config{
+		Rand:                   getRandom(),
 		Time:                   getTimeGenerator(),
 		DefaultCipher:          dc,
 		DefaultCompressionAlgo: constants.DefaultCompression,
 		CompressionConfig:      &packet.CompressionConfig{Level: constants.DefaultCompressionLevel},
 	}
 
-	return encryptWithSessionKey(message, sk, nil, config)
+	return encryptWithSessionKey(message, sk, nil, config, false)
 }
 
-func encryptWithSessionKey(message *PlainMessage, sk *SessionKey, signEntity *openpgp.Entity, config *packet.Config) ([]byte, error) {
+// EncryptAndSignWithCompression encrypts a PlainMessage with a SessionKey and
+// signs it with a Private key, like EncryptAndSign, compressing the signed
+// message with the given options, or DefaultCompressionOptions if nil.
+// * message : The plain data as a PlainMessage.
+// * signKeyRing: The KeyRing to sign the message
+// * output  : The encrypted data as PGPMessage.
+func (sk *SessionKey) EncryptAndSignWithCompression(
+	message *PlainMessage,
+	signKeyRing *KeyRing,
+	compression *CompressionOptions,
+) ([]byte, error) {
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt with session key")
+	}
+
+	config := &packet.Config{
+		Rand:          getRandom(),
+		Time:          getTimeGenerator(),
+		DefaultCipher: dc,
+	}
+	if compression == nil {
+		compression = DefaultCompressionOptions()
+	}
+	if err := compression.applyTo(config); err != nil {
+		return nil, err
+	}
+
+	signEntity, err := signKeyRing.getSigningEntity()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to sign")
+	}
+
+	return encryptWithSessionKey(message, sk, signEntity, config, false)
+}
+
+// encryptWithSessionKey encrypts and signs the message with the session key,
+// in an AEAD encrypted data packet if aead is set, otherwise in a
+// symmetrically encrypted integrity protected data packet.
+func encryptWithSessionKey(
+	message *PlainMessage, sk *SessionKey, signEntity *openpgp.Entity, config *packet.Config, aead bool,
+) ([]byte, error) {
+	if err := checkPlaintextSize(message); err != nil {
+		return nil, err
+	}
+	message, err := message.getSignableMessage()
+	if err != nil {
+		return nil, err
+	}
 	var encBuf = new(bytes.Buffer)
 
 	encryptWriter, signWriter, err := encryptStreamWithSessionKey(
-		message.IsBinary(),
+		message.GetFormat(),
 		message.Filename,
 		message.Time,
 		encBuf,
 		sk,
 		signEntity,
 		config,
+		aead,
 	)
 	if err != nil {
 		return nil, err
@@ -208,15 +288,20 @@ func encryptWithSessionKey(message *PlainMessage, sk *SessionKey, signEntity *op
 }
 
 func encryptStreamWithSessionKey(
-	isBinary bool,
+	format string,
 	filename string,
 	modTime uint32,
 	dataPacketWriter io.Writer,
 	sk *SessionKey,
 	signEntity *openpgp.Entity,
 	config *packet.Config,
+	aead bool,
 ) (encryptWriter, signWriter io.WriteCloser, err error) {
-	encryptWriter, err = packet.SerializeSymmetricallyEncrypted(dataPacketWriter, config.Cipher(), sk.Key, config)
+	if aead {
+		encryptWriter, err = packet.SerializeAEADEncrypted(dataPacketWriter, sk.Key, config.Cipher(), config.AEAD().Mode(), config)
+	} else {
+		encryptWriter, err = packet.SerializeSymmetricallyEncrypted(dataPacketWriter, config.Cipher(), sk.Key, config)
+	}
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "gopenpgp: unable to encrypt")
 	}
@@ -228,11 +313,14 @@ func encryptStreamWithSessionKey(
 		}
 	}
 
+	isBinary := format == constants.LITERAL_FORMAT_BINARY
+	encryptWriter = withLiteralFormat(encryptWriter, format)
+
 	if signEntity != nil {
 		hints := &openpgp.FileHints{
 			IsBinary: isBinary,
 			FileName: filename,
-			ModTime:  time.Unix(int64(modTime), 0),
+			ModTime:  getModTime(modTime),
 		}
 
 		signWriter, err = openpgp.Sign(encryptWriter, signEntity, hints, config)
@@ -250,6 +338,11 @@ func encryptStreamWithSessionKey(
 			return nil, nil, errors.Wrap(err, "gopenpgp: unable to serialize")
 		}
 	}
+	if signWriter != nil {
+		signWriter = withPacketChunks(signWriter)
+	} else {
+		encryptWriter = withPacketChunks(encryptWriter)
+	}
 	return encryptWriter, signWriter, nil
 }
 
@@ -265,59 +358,196 @@ func (sk *SessionKey) Decrypt(dataPacket []byte) (*PlainMessage, error) {
 // * verifyKeyRing: KeyRing with verification public keys
 // * verifyTime: when should the signature be valid, as timestamp. If 0 time verification is disabled.
 // * output: PlainMessage.
+// If verifyKeyRing is nil, the verification is disabled; if it is empty, the
+// message is returned with a SignatureVerificationError with status
+// constants.SIGNATURE_NO_VERIFIER if it is signed.
+// If a VerificationCache is set on verifyKeyRing, cached results are returned when available.
 func (sk *SessionKey) DecryptAndVerify(dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64) (*PlainMessage, error) {
-	var messageReader = bytes.NewReader(dataPacket)
+	return sk.DecryptAndVerifyWithProgress(dataPacket, verifyKeyRing, verifyTime, nil)
+}
+
+// DecryptAndVerifyWithOptions decrypts and verifies a data packet like
+// DecryptAndVerify, with the checks of options, see VerifyOptions.
+func (sk *SessionKey) DecryptAndVerifyWithOptions(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, options *VerifyOptions,
+) (*PlainMessage, error) {
+	return sk.decryptAndVerifyWithOptions(dataPacket, verifyKeyRing, verifyTime, nil, options)
+}
 
-	md, err := decryptStreamWithSessionKey(sk, messageReader, verifyKeyRing)
+// DecryptAndVerifyWithProgress decrypts and verifies a data packet like
+// DecryptAndVerify, reporting the progress of the decryption to progress, see
+// ProgressCallback.
+func (sk *SessionKey) DecryptAndVerifyWithProgress(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, progress ProgressCallback,
+) (*PlainMessage, error) {
+	return sk.decryptAndVerifyWithOptions(dataPacket, verifyKeyRing, verifyTime, progress, nil)
+}
+
+func (sk *SessionKey) decryptAndVerifyWithOptions(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, progress ProgressCallback, options *VerifyOptions,
+) (*PlainMessage, error) {
+	policy, err := options.getSignerPolicy()
 	if err != nil {
 		return nil, err
 	}
-	messageBuf := new(bytes.Buffer)
-	_, err = messageBuf.ReadFrom(md.UnverifiedBody)
+	verifyTime = ResolveVerifyTime(verifyTime)
+	if verifyKeyRing != nil && verifyKeyRing.getVerificationCache() != nil {
+		return sk.decryptAndVerifyCached(dataPacket, verifyKeyRing, verifyTime, progress, policy)
+	}
+	return sk.decryptAndVerify(dataPacket, verifyKeyRing, verifyTime, progress, policy)
+}
+
+func (sk *SessionKey) decryptAndVerify(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, progress ProgressCallback, policy signerPolicy,
+) (*PlainMessage, error) {
+	md, message, prefixedSignatures, err := sk.decryptToPlainMessage(dataPacket, verifyKeyRing, progress)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+		return nil, err
 	}
 
 	if verifyKeyRing != nil {
 		processSignatureExpiration(md, verifyTime)
-		err = verifyDetailsSignature(md, verifyKeyRing)
+		err = verifyDetailsSignature(md, verifyKeyRing, policy)
+		if !md.IsSigned {
+			err = verifyPrefixedSignatures(prefixedSignatures, message.GetBinary(), verifyKeyRing, verifyTime, policy)
+		}
 	}
 
-	return &PlainMessage{
-		Data:     messageBuf.Bytes(),
-		TextType: !md.LiteralData.IsBinary,
-		Filename: md.LiteralData.FileName,
-		Time:     md.LiteralData.Time,
-	}, err
+	return message, err
+}
+
+// DecryptAndVerifyExplicit decrypts pgp data packets using directly a session
+// key and verifies embedded signatures, like DecryptAndVerify. The returned
+// error is only set if the decryption fails: the outcome of the verification
+// is returned in the result, along with the message.
+func (sk *SessionKey) DecryptAndVerifyExplicit(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	message, err := sk.DecryptAndVerify(dataPacket, verifyKeyRing, verifyTime)
+	return newExplicitVerifyMessage(message, err)
+}
+
+// decryptToPlainMessage decrypts the data packet and reads the whole literal
+// data, checking the embedded signatures against verifyKeyRing if not nil.
+// The signatures are verified by the caller, from the returned details and
+// prefixed signatures.
+// The progress of the decryption is reported to progress if not nil.
+func (sk *SessionKey) decryptToPlainMessage(
+	dataPacket []byte, verifyKeyRing *KeyRing, progress ProgressCallback,
+) (*openpgp.MessageDetails, *PlainMessage, [][]byte, error) {
+	reader := newProgressReader(dataPacket, progress)
+	md, details, err := decryptStreamWithSessionKey(sk, reader, verifyKeyRing)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	messageBuf := new(bytes.Buffer)
+	_, err = messageBuf.ReadFrom(md.UnverifiedBody)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+	}
+	reportProgressDone(reader)
+
+	return md, &PlainMessage{
+		Data:        messageBuf.Bytes(),
+		TextType:    !md.LiteralData.IsBinary,
+		Filename:    md.LiteralData.FileName,
+		Time:        md.LiteralData.Time,
+		format:      getLiteralFormat(md.LiteralData.Format),
+		cipher:      sk.Algo,
+		protection:  details.protection,
+		signerKeyID: md.SignedByKeyId,
+	}, details.prefixedSignatures, nil
 }
 
-func decryptStreamWithSessionKey(sk *SessionKey, messageReader io.Reader, verifyKeyRing *KeyRing) (*openpgp.MessageDetails, error) {
+// decryptAndVerifyCached decrypts the data packet without verifying the embedded
+// signatures, then looks up the verification result in the cache of verifyKeyRing.
+// On a cache miss, the signatures are verified against the decrypted data.
+func (sk *SessionKey) decryptAndVerifyCached(
+	dataPacket []byte, verifyKeyRing *KeyRing, verifyTime int64, progress ProgressCallback, policy signerPolicy,
+) (*PlainMessage, error) {
+	md, message, prefixedSignatures, err := sk.decryptToPlainMessage(dataPacket, nil, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if !md.IsSigned {
+		return message, verifyPrefixedSignatures(prefixedSignatures, message.GetBinary(), verifyKeyRing, verifyTime, policy)
+	}
+
+	var signatures bytes.Buffer
+	for _, sig := range md.UnverifiedSignatures {
+		if err := sig.Serialize(&signatures); err != nil {
+			// The message was decrypted, only the signature is unusable
+			return message, newSignatureFailed()
+		}
+	}
+
+	err = verifyKeyRing.verifyCached(signatures.Bytes(), message.GetBinary(), verifyTime, policy, func() error {
+		config := &packet.Config{
+			Time: getTimeGenerator(),
+		}
+		verifyLiteralSignatures(md, message.GetBinary(), verifyKeyRing.getEntities(), config)
+		processSignatureExpiration(md, verifyTime)
+		return verifyDetailsSignature(md, verifyKeyRing, policy)
+	})
+	return message, err
+}
+
+// decryptStreamWithSessionKey decrypts the data packet read from
+// messageReader, and returns its details, protection and prefixed signatures.
+func decryptStreamWithSessionKey(
+	sk *SessionKey, messageReader io.Reader, verifyKeyRing *KeyRing,
+) (*openpgp.MessageDetails, *encryptionDetails, error) {
 	var decrypted io.ReadCloser
 	var keyring openpgp.EntityList
 
-	// Read symmetrically encrypted data packet
-	packets := packet.NewReader(messageReader)
-	p, err := packets.Next()
+	// Read symmetrically encrypted data packet, after any marker or padding
+	dataPacketErrors := newDataPacketErrors(messageReader)
+	if err := skipLeadingPackets(dataPacketErrors.buffered); err != nil {
+		return nil, nil, err
+	}
+	if err := dataPacketErrors.checkProtection(); err != nil {
+		return nil, nil, err
+	}
+	recorder := &recordingReader{in: dataPacketErrors.parserInput(), recording: true}
+	p, err := newPacketReader(recorder).Next()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		dataPacketErrors.truncated = true
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to read symmetric packet")
+		return nil, nil, errors.Wrap(dataPacketErrors.classify(err), "gopenpgp: unable to read symmetric packet")
 	}
+	recorder.recording = false
 
 	// Decrypt data packet
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gopenpgp: unable to decrypt with session key")
+	}
 	switch p := p.(type) {
 	case *packet.SymmetricallyEncrypted:
-		dc, err := sk.GetCipherFunc()
+		if err := checkDataPacketCipher(p, dc, sk.Key); err != nil {
+			return nil, nil, err
+		}
+
+		p.Contents = dataPacketErrors.ciphertext(p.Contents)
+		decrypted, err = p.Decrypt(dc, sk.Key)
 		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt with session key")
+			return nil, nil, errors.Wrap(dataPacketErrors.classify(err), "gopenpgp: unable to decrypt symmetric packet")
 		}
 
+	case *packet.AEADEncrypted:
+		p.Contents = dataPacketErrors.ciphertext(p.Contents)
 		decrypted, err = p.Decrypt(dc, sk.Key)
 		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt symmetric packet")
+			return nil, nil, errors.Wrap(dataPacketErrors.classify(err), "gopenpgp: unable to decrypt AEAD packet")
 		}
 
 	default:
-		return nil, errors.New("gopenpgp: invalid packet type")
+		return nil, nil, errors.New("gopenpgp: invalid packet type")
 	}
+	protection := getProtection(p.(packet.EncryptedDataPacket), recorder.recorded.Bytes())
+	reportDecryptionDeprecations(dc, nil)
 
 	config := &packet.Config{
 		Time: getTimeGenerator(),
@@ -325,17 +555,31 @@ func decryptStreamWithSessionKey(sk *SessionKey, messageReader io.Reader, verify
 
 	// Push decrypted packet as literal packet and use openpgp's reader
 	if verifyKeyRing != nil {
-		keyring = verifyKeyRing.entities
+		keyring = verifyKeyRing.getEntities()
 	} else {
 		keyring = openpgp.EntityList{}
 	}
 
-	md, err := openpgp.ReadMessage(decrypted, keyring, nil, config)
+	decrypted = dataPacketErrors.plaintext(decrypted)
+	md, prefixedSignatures, err := readLiteralMessage(decrypted, keyring, config)
+	if errors.Is(err, ErrPacketNestingTooDeep) {
+		return nil, nil, err
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to decode symmetric packet")
+		// The decrypted data may be corrupted, check the integrity of the
+		// whole packet
+		if closeErr := decrypted.Close(); closeErr != nil {
+			err = closeErr
+		}
+		return nil, nil, errors.Wrap(dataPacketErrors.classify(err), "gopenpgp: unable to decode symmetric packet")
 	}
+	md.UnverifiedBody = dataPacketErrors.body(md.UnverifiedBody, decrypted)
 
-	return md, nil
+	return md, &encryptionDetails{
+		cipherFunc:         dc,
+		protection:         protection,
+		prefixedSignatures: prefixedSignatures,
+	}, nil
 }
 
 func (sk *SessionKey) checkSize() error {
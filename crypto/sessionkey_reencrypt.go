@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// Reencrypt decrypts the data packet with the session key, and encrypts its
+// content with newKey, without exposing the plaintext.
+// The decrypted packets, e.g. the literal data and the signatures, are copied
+// bit for bit, so that the embedded signatures still verify.
+// The new data packet is integrity protected with a modification detection
+// code, whether the original one is or uses AEAD.
+func (sk *SessionKey) Reencrypt(dataPacket []byte, newKey *SessionKey) ([]byte, error) {
+	var outBuf bytes.Buffer
+	if err := sk.ReencryptStream(bytes.NewReader(dataPacket), newKey, &outBuf); err != nil {
+		return nil, err
+	}
+	return outBuf.Bytes(), nil
+}
+
+// ReencryptStream reads a data packet from dataPacketReader, and writes it to
+// dataPacketWriter re-encrypted with newKey, like Reencrypt.
+// The integrity of the original data is only checked once it is entirely
+// read: if an error is returned, the data written must be discarded.
+func (sk *SessionKey) ReencryptStream(dataPacketReader Reader, newKey *SessionKey, dataPacketWriter Writer) error {
+	p, err := packet.Read(dataPacketReader)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to read data packet")
+	}
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to decrypt with session key")
+	}
+
+	var decrypted io.ReadCloser
+	switch p := p.(type) {
+	case *packet.SymmetricallyEncrypted:
+		if err := checkDataPacketCipher(p, dc, sk.Key); err != nil {
+			return err
+		}
+		decrypted, err = p.Decrypt(dc, sk.Key)
+	case *packet.AEADEncrypted:
+		decrypted, err = p.Decrypt(dc, sk.Key)
+	default:
+		return errors.New("gopenpgp: invalid packet type")
+	}
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to decrypt data packet")
+	}
+
+	if err := newKey.checkSize(); err != nil {
+		_ = decrypted.Close()
+		return errors.Wrap(err, "gopenpgp: unable to encrypt with new session key")
+	}
+	newCipher, err := newKey.GetCipherFunc()
+	if err != nil {
+		_ = decrypted.Close()
+		return errors.Wrap(err, "gopenpgp: unable to encrypt with new session key")
+	}
+	config := &packet.Config{Time: getTimeGenerator(), DefaultCipher: newCipher, Rand: getRandom()}
+	encryptWriter, err := packet.SerializeSymmetricallyEncrypted(dataPacketWriter, newCipher, newKey.Key, config)
+	if err != nil {
+		_ = decrypted.Close()
+		return errors.Wrap(err, "gopenpgp: unable to encrypt")
+	}
+
+	if _, err := io.Copy(encryptWriter, decrypted); err != nil {
+		_ = decrypted.Close()
+		return errors.Wrap(err, "gopenpgp: error in re-encrypting data packet")
+	}
+	// Check the integrity of the original data before finishing the new packet
+	if err := decrypted.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in re-encrypting data packet")
+	}
+	if err := encryptWriter.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in closing encryption writer")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// decryptDataPacketContent returns the decrypted packets of a data packet.
+func decryptDataPacketContent(t *testing.T, sk *SessionKey, dataPacket []byte) []byte {
+	p, err := packet.Read(bytes.NewReader(dataPacket))
+	if err != nil {
+		t.Fatal("Expected no error while reading data packet, got:", err)
+	}
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		t.Fatal("Expected no error while getting cipher, got:", err)
+	}
+	decrypted, err := p.(packet.EncryptedDataPacket).Decrypt(dc, sk.Key)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting data packet, got:", err)
+	}
+	content, err := ioutil.ReadAll(decrypted)
+	if err != nil {
+		t.Fatal("Expected no error while reading decrypted data, got:", err)
+	}
+	if err := decrypted.Close(); err != nil {
+		t.Fatal("Expected no error while checking decrypted data, got:", err)
+	}
+	return content
+}
+
+func TestSessionKeyReencrypt(t *testing.T) {
+	oldKey, err := GenerateSessionKeyAlgo(constants.AES128)
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	newKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+
+	message := NewPlainMessageFromString("re-encrypted message")
+	dataPacket, err := oldKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting and signing, got:", err)
+	}
+
+	reencrypted, err := oldKey.Reencrypt(dataPacket, newKey)
+	if err != nil {
+		t.Fatal("Expected no error while re-encrypting, got:", err)
+	}
+
+	// The content, including the signature, is unchanged
+	assert.Exactly(t, decryptDataPacketContent(t, oldKey, dataPacket), decryptDataPacketContent(t, newKey, reencrypted))
+
+	decrypted, err := newKey.DecryptAndVerify(reencrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	_, err = oldKey.Decrypt(reencrypted)
+	assert.Error(t, err)
+}
+
+func TestSessionKeyReencryptStreamAEAD(t *testing.T) {
+	oldKey := NewSessionKeyFromToken([]byte("protection level session key 32b"), constants.AES256)
+	newKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+
+	message, err := NewPGPMessageFromArmored(readTestFile("message_protectionAEAD", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, dataPacket := splitProtectionTestMessage(t, message)
+
+	var reencrypted bytes.Buffer
+	if err := oldKey.ReencryptStream(bytes.NewReader(dataPacket), newKey, &reencrypted); err != nil {
+		t.Fatal("Expected no error while re-encrypting, got:", err)
+	}
+
+	decrypted, err := newKey.Decrypt(reencrypted.Bytes())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "AEAD protection", decrypted.GetString())
+	assert.Exactly(t, constants.PROTECTION_LEVEL_MDC, decrypted.GetProtection().Level)
+}
+
+func TestSessionKeyReencryptTampered(t *testing.T) {
+	newKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	dataPacket, err := testSessionKey.Encrypt(NewPlainMessageFromString("re-encrypted message"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	dataPacket[len(dataPacket)-1] ^= 1
+
+	_, err = testSessionKey.Reencrypt(dataPacket, newKey)
+	assert.Error(t, err)
+}
@@ -36,6 +36,7 @@ func (sk *SessionKey) EncryptStream(
 	}
 
 	config := &packet.Config{
+		Rand:          getRandom(),
 		Time:          getTimeGenerator(),
 		DefaultCipher: dc,
 	}
@@ -56,14 +57,20 @@ func (sk *SessionKey) EncryptStream(
 		}
 	}
 
+	modTime, err := checkModTime(plainMessageMetadata.ModTime)
+	if err != nil {
+		return nil, err
+	}
+
 	encryptWriter, signWriter, err := encryptStreamWithSessionKey(
-		plainMessageMetadata.IsBinary,
+		getFormatFromBinary(plainMessageMetadata.IsBinary),
 		plainMessageMetadata.Filename,
-		uint32(plainMessageMetadata.ModTime),
+		modTime,
 		dataPacketWriter,
 		sk,
 		signEntity,
 		config,
+		false,
 	)
 
 	if err != nil {
@@ -81,13 +88,15 @@ func (sk *SessionKey) EncryptStream(
 // It takes a reader for the data packet
 // and returns a PlainMessageReader for the plaintext data.
 // If verifyKeyRing is not nil, PlainMessageReader.VerifySignature() will
-// verify the embedded signature with the given key ring and verification time.
+// verify the embedded signature with the given key ring and verification time,
+// and report constants.SIGNATURE_NO_VERIFIER if the key ring is empty.
 func (sk *SessionKey) DecryptStream(
 	dataPacketReader Reader,
 	verifyKeyRing *KeyRing,
 	verifyTime int64,
 ) (plainMessage *PlainMessageReader, err error) {
-	messageDetails, err := decryptStreamWithSessionKey(
+	verifyTime = ResolveVerifyTime(verifyTime)
+	messageDetails, details, err := decryptStreamWithSessionKey(
 		sk,
 		dataPacketReader,
 		verifyKeyRing,
@@ -97,9 +106,9 @@ func (sk *SessionKey) DecryptStream(
 	}
 
 	return &PlainMessageReader{
-		messageDetails,
-		verifyKeyRing,
-		verifyTime,
-		false,
+		details:       messageDetails,
+		verifyKeyRing: verifyKeyRing,
+		verifyTime:    verifyTime,
+		protection:    details.protection,
 	}, err
 }
@@ -324,3 +324,115 @@ func TestAsymmetricKeyPacketDecryptionFailure(t *testing.T) {
 	_, err = ukr.DecryptSessionKey(keyPacket)
 	assert.Error(t, err, "gopenpgp: unable to decrypt session key")
 }
+
+func TestDataPacketDecryptAndVerifyExplicit(t *testing.T) {
+	message := NewPlainMessageFromFile([]byte("explicit verification"), "explicit.txt", 1600000000)
+	dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting and signing, got:", err)
+	}
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Unable to generate EC keyring, got:", err)
+	}
+
+	result, err := testSessionKey.DecryptAndVerifyExplicit(dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, result.SignatureVerificationError)
+	assert.Exactly(t, message.GetString(), result.Message.GetString())
+
+	cachedKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Unable to generate EC keyring, got:", err)
+	}
+	cachedKeyRing.SetVerificationCache(newTestVerificationCache())
+
+	for _, verifyKeyRing := range []*KeyRing{ecKeyRing, cachedKeyRing} {
+		result, err = testSessionKey.DecryptAndVerifyExplicit(dataPacket, verifyKeyRing, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.NotNil(t, result.SignatureVerificationError)
+		assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, result.SignatureVerificationError.Status)
+		assert.Exactly(t, message.GetString(), result.Message.GetString())
+		assert.Exactly(t, "explicit.txt", result.Message.GetFilename())
+		assert.Exactly(t, uint32(1600000000), result.Message.GetTime())
+	}
+
+	wrongKey := &SessionKey{Key: []byte("wrong session key, 32 bytes long"), Algo: constants.AES256}
+	_, err = wrongKey.DecryptAndVerifyExplicit(dataPacket, keyRingTestPublic, GetUnixTime())
+	assert.Error(t, err)
+}
+
+func TestSessionKeyTypedCipher(t *testing.T) {
+	for _, cipher := range []constants.Cipher{
+		constants.CIPHER_3DES, constants.CIPHER_CAST5,
+		constants.CIPHER_AES128, constants.CIPHER_AES192, constants.CIPHER_AES256,
+	} {
+		parsed, ok := constants.ParseCipher(cipher.String())
+		assert.True(t, ok)
+		assert.Exactly(t, cipher, parsed)
+
+		sk, err := GenerateSessionKeyWithCipher(cipher)
+		if err != nil {
+			t.Fatal("Expected no error while generating session key, got:", err)
+		}
+		assert.Exactly(t, cipher.String(), sk.Algo)
+		typed, err := sk.GetCipher()
+		if err != nil {
+			t.Fatal("Expected no error while getting cipher, got:", err)
+		}
+		assert.Exactly(t, cipher, typed)
+		assert.Exactly(t, sk, NewSessionKeyFromTokenWithCipher(sk.Key, cipher))
+	}
+	parsed, ok := constants.ParseCipher(constants.TripleDES)
+	assert.True(t, ok)
+	assert.Exactly(t, constants.CIPHER_3DES, parsed)
+
+	// The string-based functions keep their errors
+	_, err := GenerateSessionKeyAlgo("aes257")
+	assert.EqualError(t, err, "gopenpgp: unknown symmetric key generation algorithm")
+	_, err = GenerateSessionKeyWithCipher(constants.Cipher(42))
+	assert.EqualError(t, err, "gopenpgp: unknown symmetric key generation algorithm")
+	_, err = DeriveSessionKeyFromPassword([]byte("password"), make([]byte, S2KSaltLength), nil, "aes257")
+	assert.EqualError(t, err, "gopenpgp: unsupported cipher function: aes257")
+	sk, err := GenerateSessionKeyAlgo(constants.TripleDES)
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	assert.Exactly(t, constants.TripleDES, sk.Algo)
+
+	derived, err := DeriveSessionKeyFromPasswordWithCipher(
+		[]byte("password"), make([]byte, S2KSaltLength), nil, constants.CIPHER_AES128,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	fromName, err := DeriveSessionKeyFromPassword([]byte("password"), make([]byte, S2KSaltLength), nil, constants.AES128)
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, fromName, derived)
+}
+
+func TestTypedCompression(t *testing.T) {
+	for _, compression := range []constants.Compression{
+		constants.COMPRESSION_NONE, constants.COMPRESSION_ZIP, constants.COMPRESSION_ZLIB,
+	} {
+		parsed, ok := constants.ParseCompression(compression.String())
+		assert.True(t, ok)
+		assert.Exactly(t, compression, parsed)
+	}
+	_, ok := constants.ParseCompression("bzip2")
+	assert.False(t, ok)
+	assert.Exactly(t,
+		NewCompressionOptions(constants.COMPRESSION_ZIP, 9),
+		NewCompressionOptionsWithAlgorithm(constants.COMPRESSION_ZIP, 9),
+	)
+
+	s2kConfig := &S2KConfig{}
+	s2kConfig.SetCipher(constants.CIPHER_AES128)
+	assert.Exactly(t, constants.AES128, s2kConfig.Cipher)
+}
@@ -3,10 +3,10 @@ package crypto
 import (
 	"bytes"
 	"crypto"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -29,6 +29,22 @@ var allowedHashes = []crypto.Hash{
 type SignatureVerificationError struct {
 	Status  int
 	Message string
+	// The underlying error, if any
+	Cause error
+	// The hex key ID of the signer, empty if unknown
+	SignerKeyID string
+	// The creation time of the signature as a unix timestamp, 0 if unknown
+	CreationTime int64
+}
+
+// signatureVerificationErrorJSON is the JSON encoding of a
+// SignatureVerificationError, whose field names must not change.
+type signatureVerificationErrorJSON struct {
+	Status       int    `json:"status"`
+	Message      string `json:"message"`
+	Cause        string `json:"cause"`
+	SignerKeyID  string `json:"signerKeyID"`
+	CreationTime int64  `json:"creationTime"`
 }
 
 // Error is the base method for all errors.
@@ -36,6 +52,45 @@ func (e SignatureVerificationError) Error() string {
 	return fmt.Sprintf("Signature Verification Error: %v", e.Message)
 }
 
+// Unwrap returns the underlying error, if any.
+func (e SignatureVerificationError) Unwrap() error {
+	return e.Cause
+}
+
+// MarshalJSON encodes the error as a JSON object with the status, message,
+// cause, signerKeyID and creationTime fields, the cause being its message.
+func (e SignatureVerificationError) MarshalJSON() ([]byte, error) {
+	encoded := signatureVerificationErrorJSON{
+		Status:       e.Status,
+		Message:      e.Message,
+		SignerKeyID:  e.SignerKeyID,
+		CreationTime: e.CreationTime,
+	}
+	if e.Cause != nil {
+		encoded.Cause = e.Cause.Error()
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes an error encoded by MarshalJSON, ignoring unknown
+// fields. The cause is restored as an error with the same message.
+func (e *SignatureVerificationError) UnmarshalJSON(data []byte) error {
+	var decoded signatureVerificationErrorJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = SignatureVerificationError{
+		Status:       decoded.Status,
+		Message:      decoded.Message,
+		SignerKeyID:  decoded.SignerKeyID,
+		CreationTime: decoded.CreationTime,
+	}
+	if decoded.Cause != "" {
+		e.Cause = errors.New(decoded.Cause)
+	}
+	return nil
+}
+
 // ------------------
 // Internal functions
 // ------------------
@@ -58,6 +113,42 @@ func newSignatureInsecure() SignatureVerificationError {
 	}
 }
 
+// newSignatureWeakKey creates a new SignatureVerificationError, type
+// SignatureWeakKey.
+func newSignatureWeakKey() SignatureVerificationError {
+	return SignatureVerificationError{
+		Status:  constants.SIGNATURE_WEAK_KEY,
+		Message: "Weak signing key",
+	}
+}
+
+// newSignatureWrongSigner creates a new SignatureVerificationError, type
+// SignatureWrongSigner.
+func newSignatureWrongSigner() SignatureVerificationError {
+	return SignatureVerificationError{
+		Status:  constants.SIGNATURE_WRONG_SIGNER,
+		Message: "Signature made by a key which is not a required signer",
+	}
+}
+
+// newSignatureVerifiedWithEmbeddedKey creates a new
+// SignatureVerificationError, type SignatureVerifiedWithEmbeddedKey.
+func newSignatureVerifiedWithEmbeddedKey() SignatureVerificationError {
+	return SignatureVerificationError{
+		Status:  constants.SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY,
+		Message: "Signature verified with the key embedded in the message only",
+	}
+}
+
+// newSignatureTooOld creates a new SignatureVerificationError, type
+// SignatureTooOld.
+func newSignatureTooOld() SignatureVerificationError {
+	return SignatureVerificationError{
+		Status:  constants.SIGNATURE_TOO_OLD,
+		Message: "Signature older than the maximum signature age",
+	}
+}
+
 // newSignatureNotSigned creates a new SignatureVerificationError, type
 // SignatureNotSigned.
 func newSignatureNotSigned() SignatureVerificationError {
@@ -82,80 +173,180 @@ func processSignatureExpiration(md *openpgp.MessageDetails, verifyTime int64) {
 	if !errors.Is(md.SignatureError, pgpErrors.ErrSignatureExpired) {
 		return
 	}
+	verifyTime = ResolveVerifyTime(verifyTime)
 	if verifyTime == 0 {
 		// verifyTime = 0: time check disabled, everything is okay
 		md.SignatureError = nil
 		return
 	}
-	created := md.Signature.CreationTime.Unix()
-	expires := int64(math.MaxInt64)
-	if md.Signature.SigLifetimeSecs != nil {
-		expires = int64(*md.Signature.SigLifetimeSecs) + created
-	}
-	if created-internal.CreationTimeOffset <= verifyTime && verifyTime <= expires {
+	if md.Signature != nil && isSignatureTimeValid(md.Signature, verifyTime) {
 		md.SignatureError = nil
 	}
 }
 
-// verifyDetailsSignature verifies signature from message details.
-func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing) error {
+// isSignatureTimeValid checks the creation and expiration times of a signature
+// at verifyTime. The signature may be created up to CreationTimeOffset after
+// verifyTime, to compensate clock skew, but its expiration is never extended.
+func isSignatureTimeValid(sig *packet.Signature, verifyTime int64) bool {
+	created := sig.CreationTime.Unix()
+	if verifyTime < created-internal.CreationTimeOffset {
+		return false
+	}
+	if sig.SigLifetimeSecs == nil || *sig.SigLifetimeSecs == 0 {
+		return true
+	}
+	return verifyTime <= created+int64(*sig.SigLifetimeSecs)
+}
+
+// isKeyTimeValid checks the creation and expiration times of a signing key at
+// verifyTime, with the same clock skew compensation as isSignatureTimeValid.
+func isKeyTimeValid(key openpgp.Key, verifyTime int64) bool {
+	created := key.PublicKey.CreationTime.Unix()
+	if verifyTime < created-internal.CreationTimeOffset {
+		return false
+	}
+	if verifyTime < created {
+		verifyTime = created
+	}
+	return !key.PublicKey.KeyExpired(key.SelfSignature, time.Unix(verifyTime, 0))
+}
+
+// verifyDetailsSignature verifies signature from message details, and checks
+// its signer with policy.
+func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing, policy signerPolicy) error {
 	if !md.IsSigned {
 		return newSignatureNotSigned()
 	}
+	reportMessageSignatureDeprecations(md)
 	if md.SignedBy == nil ||
-		len(verifierKey.entities) == 0 ||
-		len(verifierKey.entities.KeysById(md.SignedByKeyId)) == 0 {
-		return newSignatureNoVerifier()
+		len(verifierKey.getEntities()) == 0 ||
+		len(verifierKey.getEntities().KeysById(md.SignedByKeyId)) == 0 {
+		return withSignatureDetails(newSignatureNoVerifier(), md)
 	}
 	if md.SignatureError != nil {
-		return newSignatureFailed()
+		failed := newSignatureFailed()
+		failed.Cause = md.SignatureError
+		return withSignatureDetails(failed, md)
 	}
 	if md.Signature == nil ||
 		md.Signature.Hash < allowedHashes[0] ||
 		md.Signature.Hash > allowedHashes[len(allowedHashes)-1] {
-		return newSignatureInsecure()
+		return withSignatureDetails(newSignatureInsecure(), md)
+	}
+	if err := policy.rejectSigner(md.SignedBy.Entity, md.SignedBy.PublicKey); err != nil {
+		return withSignatureDetails(err, md)
 	}
 	return nil
 }
 
-// verifySignature verifies if a signature is valid with the entity list.
-func verifySignature(pubKeyEntries openpgp.EntityList, origText io.Reader, signature []byte, verifyTime int64) error {
-	config := &packet.Config{}
-	if verifyTime == 0 {
-		config.Time = func() time.Time {
-			return time.Unix(0, 0)
-		}
-	} else {
-		config.Time = func() time.Time {
-			return time.Unix(verifyTime+internal.CreationTimeOffset, 0)
-		}
+// withSignatureDetails sets the signer and the creation time of the signature
+// of the message on err, if it is a SignatureVerificationError.
+func withSignatureDetails(err error, md *openpgp.MessageDetails) error {
+	var verificationErr SignatureVerificationError
+	if !errors.As(err, &verificationErr) {
+		return err
 	}
-	signatureReader := bytes.NewReader(signature)
-
-	signer, err := openpgp.CheckDetachedSignatureAndHash(pubKeyEntries, origText, signatureReader, allowedHashes, config)
+	if md.SignedByKeyId != 0 {
+		verificationErr.SignerKeyID = keyIDToHex(md.SignedByKeyId)
+	}
+	if md.Signature != nil {
+		verificationErr.CreationTime = md.Signature.CreationTime.Unix()
+	}
+	return verificationErr
+}
 
-	if errors.Is(err, pgpErrors.ErrSignatureExpired) && signer != nil && verifyTime > 0 {
-		// if verifyTime = 0: time check disabled, everything is okay
-		// Maybe the creation time offset pushed it over the edge
-		// Retry with the actual verification time
-		config.Time = func() time.Time {
+// verifySignature verifies if a signature is valid with the entity list, and
+// checks its signer with policy.
+func verifySignature(
+	pubKeyEntries openpgp.EntityList, origText io.Reader, signature []byte, verifyTime int64, policy signerPolicy,
+) error {
+	verifyTime = ResolveVerifyTime(verifyTime)
+	config := &packet.Config{
+		Time: func() time.Time {
 			return time.Unix(verifyTime, 0)
-		}
+		},
+	}
 
-		_, err = signatureReader.Seek(0, io.SeekStart)
-		if err != nil {
-			return newSignatureFailed()
+	reportSignatureDeprecations(pubKeyEntries, signature)
+	signer, err := openpgp.CheckDetachedSignatureAndHash(pubKeyEntries, origText, bytes.NewReader(signature), allowedHashes, config)
+	if errors.Is(err, pgpErrors.ErrSignatureExpired) && signer != nil {
+		// The library rejects the signatures created after verifyTime, and
+		// doesn't check the key in that case: check both with a margin
+		err = checkDetachedSignatureTime(signer, signature, verifyTime)
+	}
+
+	if signer == nil || err != nil {
+		return newDetachedSignatureFailed(signature, err)
+	}
+
+	if err := policy.rejectDetachedSigner(signer, signature); err != nil {
+		return withDetachedSignatureDetails(err, signature)
+	}
+	if sig, ok := getDetachedSignaturePacket(signer, signature); ok {
+		if err, rejected := policy.rejectSignatureAge(sig, verifyTime); rejected {
+			return withDetachedSignatureDetails(err, signature)
 		}
+	}
+	return nil
+}
 
-		signer, err = openpgp.CheckDetachedSignatureAndHash(pubKeyEntries, origText, signatureReader, allowedHashes, config)
+// checkDetachedSignatureTime checks the times of the signature packet which
+// the library verified with the keys of signer, i.e. the first one issued by
+// one of its signing keys, and of the key, see isSignatureTimeValid.
+func checkDetachedSignatureTime(signer *openpgp.Entity, signature []byte, verifyTime int64) error {
+	if verifyTime == 0 {
+		// verifyTime = 0: time check disabled, everything is okay
+		return nil
+	}
+	packets := packet.NewReader(bytes.NewReader(signature))
+	for {
+		p, err := packets.Next()
 		if err != nil {
-			return newSignatureFailed()
+			return pgpErrors.ErrSignatureExpired
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.IssuerKeyId == nil {
+			continue
+		}
+		keys := openpgp.EntityList{signer}.KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+		if len(keys) == 0 {
+			continue
 		}
+		if !isSignatureTimeValid(sig, verifyTime) {
+			return pgpErrors.ErrSignatureExpired
+		}
+		for _, key := range keys {
+			if isKeyTimeValid(key, verifyTime) {
+				return nil
+			}
+		}
+		return pgpErrors.ErrKeyExpired
 	}
+}
 
-	if signer == nil {
-		return newSignatureFailed()
-	}
+// newDetachedSignatureFailed returns the error for a failed detached
+// signature, with the signer and the creation time of its first packet.
+func newDetachedSignatureFailed(signature []byte, cause error) error {
+	err := newSignatureFailed()
+	err.Cause = cause
+	return withDetachedSignatureDetails(err, signature)
+}
 
-	return nil
+// withDetachedSignatureDetails sets the signer and the creation time of the
+// first packet of a detached signature on err, if it is a
+// SignatureVerificationError.
+func withDetachedSignatureDetails(err error, signature []byte) error {
+	var verificationErr SignatureVerificationError
+	if !errors.As(err, &verificationErr) {
+		return err
+	}
+	if p, readErr := packet.Read(bytes.NewReader(signature)); readErr == nil {
+		if sig, ok := p.(*packet.Signature); ok {
+			if sig.IssuerKeyId != nil {
+				verificationErr.SignerKeyID = keyIDToHex(*sig.IssuerKeyId)
+			}
+			verificationErr.CreationTime = sig.CreationTime.Unix()
+		}
+	}
+	return verificationErr
 }
@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"bytes"
+	goerrors "errors"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// ErrNotBinarySignature is returned when verifying a signature over raw bytes,
+// e.g. a ciphertext, with a signature which is not a binary signature.
+var ErrNotBinarySignature = goerrors.New("gopenpgp: only binary signatures can be verified over raw bytes")
+
+// VerifyDetachedOverBytes verifies a detached signature over raw data, e.g. a
+// ciphertext, which is never canonicalized as text: text signatures are
+// rejected with ErrNotBinarySignature.
+// Otherwise returns a SignatureVerificationError if the verification fails,
+// see VerifyDetached.
+func (keyRing *KeyRing) VerifyDetachedOverBytes(data []byte, signature *PGPSignature, verifyTime int64) error {
+	if err := checkBinarySignatures(signature); err != nil {
+		return err
+	}
+	policy := getDefaultSignerPolicy()
+	return keyRing.verifyCached(signature.GetBinary(), data, verifyTime, policy, func() error {
+		return verifySignature(keyRing.getEntities(), bytes.NewReader(data), signature.GetBinary(), verifyTime, policy)
+	})
+}
+
+// VerifyCiphertextSignature verifies a detached signature over the binary
+// content of the message, see KeyRing.VerifyDetachedOverBytes.
+func (msg *PGPMessage) VerifyCiphertextSignature(keyRing *KeyRing, signature *PGPSignature, verifyTime int64) error {
+	return keyRing.VerifyDetachedOverBytes(msg.GetBinary(), signature, verifyTime)
+}
+
+// checkBinarySignatures checks that all the signature packets are binary
+// signatures.
+func checkBinarySignatures(signature *PGPSignature) error {
+	packets := packet.NewReader(bytes.NewReader(signature.GetBinary()))
+	for {
+		p, err := packets.Next()
+		if goerrors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in reading signature packets")
+		}
+		if sig, ok := p.(*packet.Signature); ok && sig.SigType != packet.SigTypeBinary {
+			return ErrNotBinarySignature
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCiphertextSignature(t *testing.T) {
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("stored message"), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessage(ciphertext.GetBinary()))
+	if err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	assert.NoError(t, ciphertext.VerifyCiphertextSignature(keyRingTestPublic, signature, GetUnixTime()))
+
+	tampered := NewPGPMessage(append(clone(ciphertext.GetBinary()), 0))
+	err = tampered.VerifyCiphertextSignature(keyRingTestPublic, signature, GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+}
+
+func TestVerifyDetachedOverBytesRejectsTextSignature(t *testing.T) {
+	data := []byte("line\r\nline \n")
+	signEntity, err := keyRingTestPrivate.getSigningEntity()
+	if err != nil {
+		t.Fatal("Expected no error when getting the signing entity, got:", err)
+	}
+	var signature bytes.Buffer
+	config := &packet.Config{Time: getTimeGenerator()}
+	if err := openpgp.DetachSignText(&signature, signEntity, bytes.NewReader(data), config); err != nil {
+		t.Fatal("Expected no error when signing, got:", err)
+	}
+	textSignature := NewPGPSignature(signature.Bytes())
+	// The text signature is valid over the canonicalized data
+	assert.NoError(t, keyRingTestPublic.VerifyDetached(&PlainMessage{Data: data, TextType: true}, textSignature, GetUnixTime()))
+
+	err = keyRingTestPublic.VerifyDetachedOverBytes(data, textSignature, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrNotBinarySignature))
+}
@@ -11,24 +11,27 @@ import (
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	gomime "github.com/ProtonMail/go-mime"
 	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
 )
 
 // SignatureCollector structure.
 type SignatureCollector struct {
 	config    *packet.Config
 	keyring   openpgp.KeyRing
+	policy    signerPolicy
 	target    gomime.VisitAcceptor
 	signature string
 	verified  error
 }
 
 func newSignatureCollector(
-	targetAcceptor gomime.VisitAcceptor, keyring openpgp.KeyRing, config *packet.Config,
+	targetAcceptor gomime.VisitAcceptor, keyring openpgp.KeyRing, policy signerPolicy, config *packet.Config,
 ) *SignatureCollector {
 	return &SignatureCollector{
 		target:  targetAcceptor,
 		config:  config,
 		keyring: keyring,
+		policy:  policy,
 	}
 }
 
@@ -97,12 +100,11 @@ func (sc *SignatureCollector) Accept(
 	str, _ := ioutil.ReadAll(rawBody)
 	rawBody = bytes.NewReader(str)
 	if sc.keyring != nil {
-		_, err = openpgp.CheckArmoredDetachedSignature(sc.keyring, rawBody, bytes.NewReader(buffer), sc.config)
-
+		signer, err := openpgp.CheckArmoredDetachedSignature(sc.keyring, rawBody, bytes.NewReader(buffer), sc.config)
 		if err != nil {
 			sc.verified = newSignatureFailed()
 		} else {
-			sc.verified = nil
+			sc.verified = sc.rejectSigner(signer)
 		}
 	} else {
 		sc.verified = newSignatureNoVerifier()
@@ -111,6 +113,16 @@ func (sc *SignatureCollector) Accept(
 	return nil
 }
 
+// rejectSigner returns the error for the key of signer which verified the
+// collected signature if the policy rejects it, or nil.
+func (sc SignatureCollector) rejectSigner(signer *openpgp.Entity) error {
+	signature, err := armor.Unarmor(sc.signature)
+	if err != nil {
+		return nil
+	}
+	return sc.policy.rejectDetachedSigner(signer, signature)
+}
+
 // GetSignature collected by Accept.
 func (sc SignatureCollector) GetSignature() string {
 	return sc.signature
@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"hash"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+const packetTagSignature = 2
+
+// GetSignatureType returns the type of the first signature packet, e.g.
+// constants.SIGNATURE_TYPE_BINARY, or SIGNATURE_TYPE_THIRD_PARTY_CONFIRMATION
+// for a signature made by SignOverSignature, which doesn't sign a message and
+// is rejected by the message verification functions.
+func (msg *PGPSignature) GetSignatureType() (int, error) {
+	sig, err := msg.getSignaturePacket()
+	if err != nil {
+		return 0, err
+	}
+	return int(sig.SigType), nil
+}
+
+// SignOverSignature makes a third-party confirmation signature (type 0x50,
+// RFC 4880 section 5.2.1) of the signature packet of original, e.g. for a
+// notary countersigning an already signed document. The confirmation only
+// covers the original signature packet, and thus the digest of the document
+// it signs: it doesn't state that the original signature is valid.
+// The unhashed subpackets of the original signature are not covered.
+func (keyRing *KeyRing) SignOverSignature(original *PGPSignature) (*PGPSignature, error) {
+	body, err := getConfirmedSignatureBody(original)
+	if err != nil {
+		return nil, err
+	}
+	signEntity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return nil, err
+	}
+	signingKey, ok := signEntity.SigningKey(getNow())
+	if !ok {
+		return nil, errors.New("gopenpgp: no valid signing keys")
+	}
+
+	sig := &packet.Signature{
+		Version:      4,
+		SigType:      packet.SignatureType(constants.SIGNATURE_TYPE_THIRD_PARTY_CONFIRMATION),
+		PubKeyAlgo:   signingKey.PrivateKey.PubKeyAlgo,
+		Hash:         crypto.SHA512,
+		CreationTime: getNow(),
+		IssuerKeyId:  &signingKey.PrivateKey.KeyId,
+	}
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator(), Rand: getRandom()}
+	if err := sig.Sign(hashConfirmedSignature(sig.Hash.New(), body), signingKey.PrivateKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	var outBuf bytes.Buffer
+	if err := sig.Serialize(&outBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing signature")
+	}
+	return NewPGPSignature(outBuf.Bytes()), nil
+}
+
+// VerifySignatureOverSignature verifies a third-party confirmation signature
+// of original, made by SignOverSignature with a key of the keyring, at
+// verifyTime, or without checking the times if it is 0.
+// The original signature itself is not verified.
+// Returns a SignatureVerificationError if the verification fails.
+func (keyRing *KeyRing) VerifySignatureOverSignature(original, confirmation *PGPSignature, verifyTime int64) error {
+	body, err := getConfirmedSignatureBody(original)
+	if err != nil {
+		return err
+	}
+	sig, err := confirmation.getSignaturePacket()
+	if err != nil {
+		return err
+	}
+	reportSignatureDeprecations(keyRing.getEntities(), confirmation.GetBinary())
+	verifyTime = ResolveVerifyTime(verifyTime)
+	if int(sig.SigType) != constants.SIGNATURE_TYPE_THIRD_PARTY_CONFIRMATION {
+		return errors.New("gopenpgp: not a third-party confirmation signature")
+	}
+	if sig.Hash < allowedHashes[0] || sig.Hash > allowedHashes[len(allowedHashes)-1] {
+		return newSignatureInsecure()
+	}
+	if sig.IssuerKeyId == nil {
+		return newSignatureNoVerifier()
+	}
+	keys := keyRing.getEntities().KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+	if len(keys) == 0 {
+		return newSignatureNoVerifier()
+	}
+
+	for _, key := range keys {
+		if key.PublicKey.VerifySignature(hashConfirmedSignature(sig.Hash.New(), body), sig) != nil {
+			continue
+		}
+		if verifyTime != 0 && (!isSignatureTimeValid(sig, verifyTime) || !isKeyTimeValid(key, verifyTime)) {
+			return newDetachedSignatureFailed(confirmation.GetBinary(), errors.New("gopenpgp: signature or key expired"))
+		}
+		if err := getDefaultSignerPolicy().rejectSigner(key.Entity, key.PublicKey); err != nil {
+			return withDetachedSignatureDetails(err, confirmation.GetBinary())
+		}
+		return nil
+	}
+	return newDetachedSignatureFailed(confirmation.GetBinary(), errors.New("gopenpgp: invalid signature"))
+}
+
+// getConfirmedSignatureBody returns the body of the single signature packet
+// of original, with an empty unhashed area, as hashed by a third-party
+// confirmation signature.
+func getConfirmedSignatureBody(original *PGPSignature) ([]byte, error) {
+	data := original.GetBinary()
+	tag, length, err := readPacketHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag != packetTagSignature || length != len(data) {
+		return nil, errors.New("gopenpgp: the original signature must be a single signature packet")
+	}
+	if data[0]&0x40 == 0 && data[0]&0x03 == 3 || data[0]&0x40 != 0 && data[1] >= 224 && data[1] < 255 {
+		return nil, errors.New("gopenpgp: the original signature has an indeterminate length")
+	}
+	var headerLength int
+	switch {
+	case data[0]&0x40 == 0:
+		// Old format packet
+		headerLength = 1 + 1<<(data[0]&0x03)
+	case data[1] < 192:
+		headerLength = 2
+	case data[1] < 224:
+		headerLength = 3
+	default:
+		headerLength = 6
+	}
+	body := data[headerLength:]
+
+	// Version 4: version, type, algorithms and hashed area length
+	if len(body) < 6 || body[0] != 4 {
+		return nil, errors.New("gopenpgp: only version 4 signatures can be confirmed")
+	}
+	hashedEnd := 6 + int(binary.BigEndian.Uint16(body[4:6]))
+	if len(body) < hashedEnd+2 {
+		return nil, errors.New("gopenpgp: truncated signature packet")
+	}
+	unhashedEnd := hashedEnd + 2 + int(binary.BigEndian.Uint16(body[hashedEnd:hashedEnd+2]))
+	if len(body) < unhashedEnd {
+		return nil, errors.New("gopenpgp: truncated signature packet")
+	}
+	normalized := make([]byte, 0, len(body))
+	normalized = append(normalized, body[:hashedEnd]...)
+	normalized = append(normalized, 0, 0)
+	return append(normalized, body[unhashedEnd:]...), nil
+}
+
+// hashConfirmedSignature writes the signature packet body to h, with the
+// header of RFC 4880 section 5.2.4: 0x88 followed by its four-octet length.
+func hashConfirmedSignature(h hash.Hash, body []byte) hash.Hash {
+	var header [5]byte
+	header[0] = 0x88
+	binary.BigEndian.PutUint32(header[1:], uint32(len(body)))
+	_, _ = h.Write(header[:])
+	_, _ = h.Write(body)
+	return h
+}
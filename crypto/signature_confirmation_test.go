@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestSignOverSignature(t *testing.T) {
+	message := NewPlainMessageFromString("document to notarize")
+	original, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	notaryKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	confirmation, err := notaryKeyRing.SignOverSignature(original)
+	if err != nil {
+		t.Fatal("Expected no error while confirming signature, got:", err)
+	}
+
+	sigType, err := confirmation.GetSignatureType()
+	if err != nil {
+		t.Fatal("Expected no error while reading signature type, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_TYPE_THIRD_PARTY_CONFIRMATION, sigType)
+	sigType, err = original.GetSignatureType()
+	if err != nil {
+		t.Fatal("Expected no error while reading signature type, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_TYPE_BINARY, sigType)
+
+	if err := notaryKeyRing.VerifySignatureOverSignature(original, confirmation, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying confirmation, got:", err)
+	}
+	assert.Error(t, keyRingTestPublic.VerifySignatureOverSignature(original, confirmation, GetUnixTime()))
+
+	// The confirmation doesn't cover another signature of the same document
+	otherSignature, err := notaryKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.Error(t, notaryKeyRing.VerifySignatureOverSignature(otherSignature, confirmation, GetUnixTime()))
+
+	// The confirmation isn't a signature of the document
+	assert.Error(t, notaryKeyRing.VerifyDetached(message, confirmation, GetUnixTime()))
+	_, err = confirmation.ComputeDigest(message)
+	assert.Error(t, err)
+}
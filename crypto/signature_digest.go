@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	"hash"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// GetHashPrefix returns the 16-bit prefix of the signed digest, stored in the
+// first signature packet.
+// Matching the prefix is NOT a security check: it can only be used to cheaply
+// reject mismatching signatures, see VerifyDetachedQuick.
+func (msg *PGPSignature) GetHashPrefix() (prefix [2]byte, err error) {
+	sig, err := msg.getSignaturePacket()
+	if err != nil {
+		return prefix, err
+	}
+	return sig.HashTag, nil
+}
+
+// ComputeDigest returns the digest of the message as signed by the first
+// signature packet, i.e. the hash of the message followed by the signature
+// trailer, to be used with VerifyDetachedQuick.
+func (msg *PGPSignature) ComputeDigest(message *PlainMessage) ([]byte, error) {
+	sig, err := msg.getSignaturePacket()
+	if err != nil {
+		return nil, err
+	}
+	if !isMessageSignature(sig) {
+		return nil, errors.New("gopenpgp: the signature doesn't sign a message")
+	}
+	if !sig.Hash.Available() {
+		return nil, errors.New("gopenpgp: unsupported signature hash")
+	}
+
+	h := sig.Hash.New()
+	if sig.SigType == packet.SigTypeText {
+		h = openpgp.NewCanonicalTextHash(h)
+	}
+	_, _ = h.Write(message.GetBinary())
+	if sig.Version == 5 {
+		sig.AddMetadataToHashSuffix()
+	}
+	_, _ = h.Write(sig.HashSuffix)
+	return h.Sum(nil), nil
+}
+
+// VerifyDetachedQuick checks a detached signature against the digest of the
+// signed data, as returned by ComputeDigest.
+// If only the 2-byte hash prefix is given, the check only compares it to the
+// hash prefix stored in the signature: this is NOT a security check,
+// and is only meant to reject mismatching signatures before loading the data.
+// If the full digest is given, the signature is verified with the signing
+// keys of the keyring at verifyTime, like VerifyDetached: revoked keys and
+// keys without the signing flag are ignored, and the signature and key times
+// are checked unless verifyTime is 0.
+// Returns a SignatureVerificationError if the check fails.
+func (keyRing *KeyRing) VerifyDetachedQuick(digest []byte, signature *PGPSignature, verifyTime int64) error {
+	sig, err := signature.getSignaturePacket()
+	if err != nil {
+		return err
+	}
+	if !isMessageSignature(sig) {
+		return errors.New("gopenpgp: the signature doesn't sign a message")
+	}
+	if len(digest) != 2 && len(digest) != sig.Hash.Size() {
+		return errors.New("gopenpgp: the digest must be the hash prefix or the full digest")
+	}
+	if digest[0] != sig.HashTag[0] || digest[1] != sig.HashTag[1] {
+		return newSignatureFailed()
+	}
+	if len(digest) == 2 {
+		return nil
+	}
+	verifyTime = ResolveVerifyTime(verifyTime)
+
+	reportSignatureDeprecations(keyRing.getEntities(), signature.GetBinary())
+
+	if sig.Hash < allowedHashes[0] || sig.Hash > allowedHashes[len(allowedHashes)-1] {
+		return newSignatureInsecure()
+	}
+	if sig.IssuerKeyId == nil {
+		return newSignatureNoVerifier()
+	}
+	keys := keyRing.getEntities().KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+	if len(keys) == 0 {
+		return newSignatureNoVerifier()
+	}
+	for _, key := range keys {
+		if key.PublicKey.VerifySignature(&precomputedHash{digest: digest}, sig) != nil {
+			continue
+		}
+		if verifyTime != 0 && !isSignatureTimeValid(sig, verifyTime) {
+			return newDetachedSignatureFailed(signature.GetBinary(), pgpErrors.ErrSignatureExpired)
+		}
+		if verifyTime != 0 && !isKeyTimeValid(key, verifyTime) {
+			return newDetachedSignatureFailed(signature.GetBinary(), pgpErrors.ErrKeyExpired)
+		}
+		if err := getDefaultSignerPolicy().rejectSigner(key.Entity, key.PublicKey); err != nil {
+			return withDetachedSignatureDetails(err, signature.GetBinary())
+		}
+		return nil
+	}
+	return newSignatureFailed()
+}
+
+// getSignaturePacket parses the first signature packet.
+func (msg *PGPSignature) getSignaturePacket() (*packet.Signature, error) {
+	p, err := packet.Read(bytes.NewReader(msg.Data))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading signature packet")
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, errors.New("gopenpgp: the first packet is not a signature")
+	}
+	return sig, nil
+}
+
+// isMessageSignature returns whether sig signs a message, unlike e.g. a
+// third-party confirmation signature.
+func isMessageSignature(sig *packet.Signature) bool {
+	return sig.SigType == packet.SigTypeBinary || sig.SigType == packet.SigTypeText
+}
+
+// precomputedHash is a hash.Hash returning a digest computed beforehand,
+// the data written to it is ignored.
+type precomputedHash struct {
+	digest []byte
+}
+
+var _ hash.Hash = &precomputedHash{}
+
+func (h *precomputedHash) Write(b []byte) (int, error) { return len(b), nil }
+func (h *precomputedHash) Sum(b []byte) []byte         { return append(b, h.digest...) }
+func (h *precomputedHash) Reset()                      {}
+func (h *precomputedHash) Size() int                   { return len(h.digest) }
+func (h *precomputedHash) BlockSize() int              { return 1 }
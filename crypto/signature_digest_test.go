@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestVerifyDetachedQuick(t *testing.T) {
+	for _, message := range []*PlainMessage{
+		NewPlainMessageFromString(signedPlainText),
+		NewPlainMessage([]byte(signedPlainText)),
+	} {
+		signature, err := keyRingTestPrivate.SignDetached(message)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+
+		prefix, err := signature.GetHashPrefix()
+		if err != nil {
+			t.Fatal("Expected no error while reading hash prefix, got:", err)
+		}
+		digest, err := signature.ComputeDigest(message)
+		if err != nil {
+			t.Fatal("Expected no error while computing digest, got:", err)
+		}
+		assert.Exactly(t, prefix[:], digest[:2])
+
+		assert.NoError(t, keyRingTestPublic.VerifyDetachedQuick(prefix[:], signature, testTime))
+		assert.NoError(t, keyRingTestPublic.VerifyDetachedQuick(digest, signature, testTime))
+
+		// A digest with a matching prefix is still rejected
+		digest[len(digest)-1] ^= 1
+		err = keyRingTestPublic.VerifyDetachedQuick(digest, signature, testTime)
+		var verificationError SignatureVerificationError
+		assert.True(t, errors.As(err, &verificationError))
+		assert.Exactly(t, constants.SIGNATURE_FAILED, verificationError.Status)
+
+		// The key must be valid at the verification time
+		digest[len(digest)-1] ^= 1
+		err = keyRingTestPublic.VerifyDetachedQuick(digest, signature, 1)
+		assert.True(t, errors.As(err, &verificationError))
+		assert.Exactly(t, constants.SIGNATURE_FAILED, verificationError.Status)
+		assert.NoError(t, keyRingTestPublic.VerifyDetachedQuick(digest, signature, 0))
+
+		wrongPrefix := []byte{prefix[0] ^ 1, prefix[1]}
+		assert.Error(t, keyRingTestPublic.VerifyDetachedQuick(wrongPrefix, signature, testTime))
+	}
+}
@@ -0,0 +1,144 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	goerrors "errors"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// EncryptWithSignatureFraming encrypts a PlainMessage and signs it with
+// privateKey, using the given signature framing:
+// * constants.SIGNATURE_FRAMING_ONE_PASS: the default, as in Encrypt.
+// * constants.SIGNATURE_FRAMING_PREFIXED: a signature packet placed before the
+// literal data, for implementations which can't handle one-pass signatures.
+// The prefixed framing requires the whole message before writing the signature,
+// hence it is not available for streaming encryption.
+func (keyRing *KeyRing) EncryptWithSignatureFraming(message *PlainMessage, privateKey *KeyRing, framing int) (*PGPMessage, error) {
+	switch framing {
+	case constants.SIGNATURE_FRAMING_ONE_PASS:
+		return keyRing.Encrypt(message, privateKey)
+	case constants.SIGNATURE_FRAMING_PREFIXED:
+	default:
+		return nil, errors.Errorf("gopenpgp: unknown signature framing %d", framing)
+	}
+
+	sk, err := GenerateSessionKeyAlgo(constants.AES256)
+	if err != nil {
+		return nil, err
+	}
+	defer sk.Clear()
+
+	keyPacket, err := keyRing.EncryptSessionKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	dataPacket, err := sk.encryptWithPrefixedSignature(message, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewPGPSplitMessage(keyPacket, dataPacket).GetPGPMessage(), nil
+}
+
+// EncryptAndSignWithSignatureFraming encrypts a PlainMessage with a SessionKey
+// and signs it with signKeyRing, using the given signature framing,
+// see KeyRing.EncryptWithSignatureFraming.
+func (sk *SessionKey) EncryptAndSignWithSignatureFraming(message *PlainMessage, signKeyRing *KeyRing, framing int) ([]byte, error) {
+	switch framing {
+	case constants.SIGNATURE_FRAMING_ONE_PASS:
+		return sk.EncryptAndSign(message, signKeyRing)
+	case constants.SIGNATURE_FRAMING_PREFIXED:
+		return sk.encryptWithPrefixedSignature(message, signKeyRing)
+	}
+	return nil, errors.Errorf("gopenpgp: unknown signature framing %d", framing)
+}
+
+// encryptWithPrefixedSignature encrypts the message with the session key,
+// after a detached signature of it.
+func (sk *SessionKey) encryptWithPrefixedSignature(message *PlainMessage, signKeyRing *KeyRing) ([]byte, error) {
+	if signKeyRing == nil {
+		return nil, errors.New("gopenpgp: no signing key ring provided")
+	}
+	message, err := message.getSignableMessage()
+	if err != nil {
+		return nil, err
+	}
+	signEntity, err := signKeyRing.getSigningEntity()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to sign")
+	}
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt with session key")
+	}
+	config := &packet.Config{
+		Rand:          getRandom(),
+		Time:          getTimeGenerator(),
+		DefaultCipher: dc,
+		DefaultHash:   crypto.SHA512,
+	}
+
+	var signature bytes.Buffer
+	if message.IsBinary() {
+		err = openpgp.DetachSign(&signature, signEntity, message.NewReader(), config)
+	} else {
+		err = openpgp.DetachSignText(&signature, signEntity, message.NewReader(), config)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	var encBuf bytes.Buffer
+	encryptWriter, err := packet.SerializeSymmetricallyEncrypted(&encBuf, config.Cipher(), sk.Key, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt")
+	}
+	if _, err = encryptWriter.Write(signature.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in writing signature")
+	}
+	literalWriter, err := packet.SerializeLiteral(
+		withLiteralFormat(encryptWriter, message.GetFormat()), message.IsBinary(), message.Filename, message.Time,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to serialize")
+	}
+	if _, err = literalWriter.Write(message.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in writing message")
+	}
+	if err = literalWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in closing encryption writer")
+	}
+	return encBuf.Bytes(), nil
+}
+
+// verifyPrefixedSignatures verifies the signature packets placed before the
+// literal data of a message, see readLiteralMessage, and checks their signer
+// with policy. Returns a SignatureVerificationError if none is valid.
+func verifyPrefixedSignatures(
+	signatures [][]byte, body []byte, verifyKeyRing *KeyRing, verifyTime int64, policy signerPolicy,
+) error {
+	if len(signatures) == 0 {
+		return newSignatureNotSigned()
+	}
+
+	var err error
+	for _, signature := range signatures {
+		err = verifySignature(
+			verifyKeyRing.getEntities(), bytes.NewReader(body), signature, verifyTime, policy,
+		)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// isSignatureNotSigned checks whether err reports a message without signature.
+func isSignatureNotSigned(err error) bool {
+	var sigErr SignatureVerificationError
+	return goerrors.As(err, &sigErr) && sigErr.Status == constants.SIGNATURE_NOT_SIGNED
+}
@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestEncryptWithSignatureFraming(t *testing.T) {
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	for _, framing := range []int{constants.SIGNATURE_FRAMING_ONE_PASS, constants.SIGNATURE_FRAMING_PREFIXED} {
+		for _, message := range []*PlainMessage{
+			NewPlainMessageFromString(signedPlainText),
+			NewPlainMessage([]byte(signedPlainText)),
+		} {
+			encrypted, err := keyRingTestPublic.EncryptWithSignatureFraming(message, keyRingTestPrivate, framing)
+			if err != nil {
+				t.Fatal("Expected no error while encrypting, got:", err)
+			}
+
+			split, err := encrypted.SeparateKeyAndData(1024, 0)
+			if err != nil {
+				t.Fatal("Expected no error while splitting, got:", err)
+			}
+			sk, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting session key, got:", err)
+			}
+			_, details, err := decryptStreamWithSessionKey(sk, bytes.NewReader(split.GetBinaryDataPacket()), nil)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assert.Exactly(t, framing == constants.SIGNATURE_FRAMING_PREFIXED, len(details.prefixedSignatures) == 1)
+
+			decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting, got:", err)
+			}
+			assert.Exactly(t, message.GetString(), decrypted.GetString())
+			assert.Exactly(t, message.IsBinary(), decrypted.IsBinary())
+
+			decrypted, err = sk.DecryptAndVerify(split.GetBinaryDataPacket(), keyRingTestPublic, GetUnixTime())
+			if err != nil {
+				t.Fatal("Expected no error while decrypting with session key, got:", err)
+			}
+			assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+			_, err = keyRingTestPrivate.Decrypt(encrypted, otherKeyRing, GetUnixTime())
+			var verificationError SignatureVerificationError
+			assert.True(t, errors.As(err, &verificationError))
+			assert.NotEqual(t, constants.SIGNATURE_NOT_SIGNED, verificationError.Status)
+		}
+	}
+
+	_, err = keyRingTestPublic.EncryptWithSignatureFraming(NewPlainMessageFromString(signedPlainText), keyRingTestPrivate, 2)
+	assert.Error(t, err)
+}
+
+func TestSessionKeyEncryptAndSignWithSignatureFraming(t *testing.T) {
+	sk, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	message := NewPlainMessageFromString(signedPlainText)
+
+	for _, framing := range []int{constants.SIGNATURE_FRAMING_ONE_PASS, constants.SIGNATURE_FRAMING_PREFIXED} {
+		dataPacket, err := sk.EncryptAndSignWithSignatureFraming(message, keyRingTestPrivate, framing)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+
+		decrypted, err := sk.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+
+	dataPacket, err := sk.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = sk.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+	var verificationError SignatureVerificationError
+	assert.True(t, errors.As(err, &verificationError))
+	assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, verificationError.Status)
+}
+
+func TestDecryptAEADWithPrefixedSignature(t *testing.T) {
+	sk, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	message := NewPlainMessage([]byte(signedPlainText))
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sk)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		data   []byte
+		status int
+	}{
+		{"valid", message.GetBinary(), constants.SIGNATURE_OK},
+		{"tampered", []byte("tampered"), constants.SIGNATURE_FAILED},
+	} {
+		var dataPacket bytes.Buffer
+		encryptWriter, err := packet.SerializeAEADEncrypted(
+			&dataPacket, sk.Key, packet.CipherAES256, packet.AEADModeEAX, nil,
+		)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		if _, err = encryptWriter.Write(signature.GetBinary()); err != nil {
+			t.Fatal("Expected no error while writing signature, got:", err)
+		}
+		literalWriter, err := packet.SerializeLiteral(encryptWriter, true, "", 0)
+		if err != nil {
+			t.Fatal("Expected no error while serializing, got:", err)
+		}
+		if _, err = literalWriter.Write(test.data); err != nil {
+			t.Fatal("Expected no error while writing message, got:", err)
+		}
+		if err = literalWriter.Close(); err != nil {
+			t.Fatal("Expected no error while closing, got:", err)
+		}
+
+		decrypted, err := sk.DecryptAndVerify(dataPacket.Bytes(), keyRingTestPublic, GetUnixTime())
+		assert.Exactly(t, test.status, getSignatureStatus(t, err), test.name)
+		assert.Exactly(t, test.data, decrypted.GetBinary())
+
+		encrypted := NewPGPSplitMessage(keyPacket, dataPacket.Bytes()).GetPGPMessage()
+		decrypted, err = keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+		assert.Exactly(t, test.status, getSignatureStatus(t, err), test.name)
+		assert.Exactly(t, test.data, decrypted.GetBinary())
+	}
+}
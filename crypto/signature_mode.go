@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"crypto"
+	"hash"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// VerifyDetachedWithMode verifies a PlainMessage with a detached PGPSignature
+// like VerifyDetached, hashing the message as given by mode:
+//   - constants.SIGNATURE_MODE_AUTO hashes it as binary data or as canonical
+//     text, with CRLF line endings, depending on the type of the signature
+//     packet, as VerifyDetached does;
+//   - SIGNATURE_MODE_BINARY and SIGNATURE_MODE_TEXT force either hashing, for
+//     signers which mislabel the type of their signatures.
+//
+// In any case, the TextType of the message is not taken into account.
+// Returns a SignatureVerificationError if the verification fails.
+func (keyRing *KeyRing) VerifyDetachedWithMode(message *PlainMessage, signature *PGPSignature, verifyTime int64, mode int) error {
+	hashes := make(map[crypto.Hash]hash.Hash, len(allowedHashes))
+	textHashes := make(map[crypto.Hash]hash.Hash, len(allowedHashes))
+	writers := make([]io.Writer, 0, 2*len(allowedHashes))
+	for _, algo := range allowedHashes {
+		binaryHash, textHash := algo.New(), algo.New()
+		writers = append(writers, binaryHash, openpgp.NewCanonicalTextHash(textHash))
+		switch mode {
+		case constants.SIGNATURE_MODE_AUTO:
+			hashes[algo], textHashes[algo] = binaryHash, textHash
+		case constants.SIGNATURE_MODE_BINARY:
+			hashes[algo], textHashes[algo] = binaryHash, binaryHash
+		case constants.SIGNATURE_MODE_TEXT:
+			hashes[algo], textHashes[algo] = textHash, textHash
+		default:
+			return errors.New("gopenpgp: unknown signature mode")
+		}
+	}
+	_, _ = io.MultiWriter(writers...).Write(message.GetBinary())
+
+	return verifySignatureHashes(
+		keyRing.getEntities(), hashes, textHashes, signature.GetBinary(), verifyTime, getDefaultSignerPolicy(),
+	)
+}
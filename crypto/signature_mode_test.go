@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func TestVerifyDetachedWithModeTextSignature(t *testing.T) {
+	data := []byte("trailing space \nsecond line")
+	signEntity, err := keyRingTestPrivate.getSigningEntity()
+	if err != nil {
+		t.Fatal("Expected no error while getting signing entity, got:", err)
+	}
+	var signature bytes.Buffer
+	config := &packet.Config{DefaultHash: crypto.SHA512, Time: getTimeGenerator()}
+	if err := openpgp.DetachSignText(&signature, signEntity, bytes.NewReader(data), config); err != nil {
+		t.Fatal("Expected no error while signing text, got:", err)
+	}
+	textSignature := NewPGPSignature(signature.Bytes())
+
+	// The trailing whitespace is trimmed from text messages
+	assert.Error(t, keyRingTestPublic.VerifyDetached(NewPlainMessageFromString(string(data)), textSignature, GetUnixTime()))
+
+	binaryMessage := NewPlainMessage(data)
+	if err := keyRingTestPublic.VerifyDetachedWithMode(binaryMessage, textSignature, GetUnixTime(), constants.SIGNATURE_MODE_AUTO); err != nil {
+		t.Fatal("Expected no error while verifying text signature, got:", err)
+	}
+	if err := keyRingTestPublic.VerifyDetachedWithMode(binaryMessage, textSignature, GetUnixTime(), constants.SIGNATURE_MODE_TEXT); err != nil {
+		t.Fatal("Expected no error while verifying text signature in text mode, got:", err)
+	}
+	assert.Error(t, keyRingTestPublic.VerifyDetachedWithMode(binaryMessage, textSignature, GetUnixTime(), constants.SIGNATURE_MODE_BINARY))
+	assert.Error(t, keyRingTestPublic.VerifyDetachedWithMode(binaryMessage, textSignature, GetUnixTime(), 3))
+}
+
+func TestVerifyDetachedWithModeBinarySignature(t *testing.T) {
+	// Binary signature of text with CRLF line endings
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessage([]byte("first line\r\nsecond line")))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	message := NewPlainMessage([]byte("first line\nsecond line"))
+	assert.Error(t, keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Error(t, keyRingTestPublic.VerifyDetachedWithMode(message, signature, GetUnixTime(), constants.SIGNATURE_MODE_AUTO))
+	assert.Error(t, keyRingTestPublic.VerifyDetachedWithMode(message, signature, GetUnixTime(), constants.SIGNATURE_MODE_BINARY))
+	if err := keyRingTestPublic.VerifyDetachedWithMode(message, signature, GetUnixTime(), constants.SIGNATURE_MODE_TEXT); err != nil {
+		t.Fatal("Expected no error while verifying binary signature in text mode, got:", err)
+	}
+}
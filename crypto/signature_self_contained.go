@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// SignerTrustPolicy decides whether the key embedded in a self-contained
+// signature is trusted, see VerifySelfContained.
+type SignerTrustPolicy interface {
+	IsTrusted(fingerprint string) bool
+}
+
+// SignDetachedSelfContained signs a PlainMessage like SignDetached, and
+// returns a self-contained armored signature: a single PGP SIGNATURE block
+// containing the signature packet followed by the transferable public key of
+// the signer, so that the signature can be verified with
+// VerifySelfContained without any other artifact.
+func (keyRing *KeyRing) SignDetachedSelfContained(message *PlainMessage) (string, error) {
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		return "", err
+	}
+	signEntity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := (&Key{entity: signEntity}).GetPublicKey()
+	if err != nil {
+		return "", err
+	}
+	return armor.ArmorWithType(append(signature.GetBinary(), publicKey...), constants.PGPSignatureHeader)
+}
+
+// VerifySelfContained verifies data with a self-contained armored signature,
+// produced by SignDetachedSelfContained, with the public key it embeds, and
+// returns this key so that the caller can pin it.
+// The key is returned as soon as it could be read, even if the verification
+// fails. The embedded key proves nothing by itself: if trustPolicy is not
+// nil, the verification fails with the constants.SIGNATURE_WRONG_SIGNER
+// status unless it trusts the fingerprint of the key, otherwise the trust
+// decision is left to the caller.
+// Returns a SignatureVerificationError if the verification fails.
+func VerifySelfContained(data []byte, armoredBlock string, verifyTime int64, trustPolicy SignerTrustPolicy) (*Key, error) {
+	signature, err := NewPGPSignatureFromArmored(armoredBlock)
+	if err != nil {
+		return nil, err
+	}
+	signaturePackets, keyPackets, err := splitSelfContainedSignature(signature.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	key, err := NewKey(keyPackets)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading embedded signer key")
+	}
+	if key.IsPrivate() {
+		return nil, errors.New("gopenpgp: the embedded signer key is a private key")
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := keyRing.VerifyDetached(NewPlainMessage(data), NewPGPSignature(signaturePackets), verifyTime); err != nil {
+		return key, err
+	}
+	if trustPolicy != nil && !trustPolicy.IsTrusted(key.GetFingerprint()) {
+		verifyErr := newSignatureWrongSigner()
+		verifyErr.Message = "Signature made by an untrusted key"
+		return key, withDetachedSignatureDetails(verifyErr, signaturePackets)
+	}
+	return key, nil
+}
+
+// splitSelfContainedSignature splits the leading signature packets of a
+// self-contained signature from the key packets following them.
+func splitSelfContainedSignature(data []byte) (signature, key []byte, err error) {
+	offset := 0
+	for offset < len(data) {
+		tag, length, err := readPacketHeader(data[offset:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag != packetTagSignature {
+			break
+		}
+		if length > len(data)-offset {
+			return nil, nil, errors.New("gopenpgp: truncated signature packet")
+		}
+		offset += length
+	}
+	if offset == 0 {
+		return nil, nil, errors.New("gopenpgp: no signature packet in self-contained signature")
+	}
+	if offset == len(data) {
+		return nil, nil, errors.New("gopenpgp: no signer key in self-contained signature")
+	}
+	return data[:offset], data[offset:], nil
+}
@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+type trustedFingerprints []string
+
+func (trusted trustedFingerprints) IsTrusted(fingerprint string) bool {
+	for _, trustedFingerprint := range trusted {
+		if trustedFingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSelfContainedSignature(t *testing.T) {
+	data := []byte("verified without a keyring")
+	armored, err := keyRingTestPrivate.SignDetachedSelfContained(NewPlainMessage(data))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	fingerprint := keyRingTestPublic.GetKeys()[0].GetFingerprint()
+
+	key, err := VerifySelfContained(data, armored, GetUnixTime(), nil)
+	if err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	assert.Exactly(t, fingerprint, key.GetFingerprint())
+	assert.False(t, key.IsPrivate())
+
+	_, err = VerifySelfContained(data, armored, GetUnixTime(), trustedFingerprints{fingerprint})
+	if err != nil {
+		t.Fatal("Expected no error while verifying with trusted key, got:", err)
+	}
+
+	// The key is returned for the caller to inspect when verification fails
+	key, err = VerifySelfContained(data, armored, GetUnixTime(), trustedFingerprints{})
+	assert.Exactly(t, constants.SIGNATURE_WRONG_SIGNER, getSignatureStatus(t, err))
+	assert.Exactly(t, fingerprint, key.GetFingerprint())
+
+	key, err = VerifySelfContained([]byte("tampered"), armored, GetUnixTime(), trustedFingerprints{fingerprint})
+	assert.Exactly(t, constants.SIGNATURE_FAILED, getSignatureStatus(t, err))
+	assert.NotNil(t, key)
+}
+
+func TestSelfContainedSignatureWithoutKey(t *testing.T) {
+	data := []byte("no embedded key")
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessage(data))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	armored, err := signature.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring signature, got:", err)
+	}
+	_, err = VerifySelfContained(data, armored, GetUnixTime(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// minRSASigningKeyBits is the size under which RSA signing keys are weak.
+const minRSASigningKeyBits = 2048
+
+// SetRejectWeakSigningKeys sets whether signature verification rejects the
+// signatures made by weak keys, i.e. RSA keys of less than 2048 bits and DSA
+// keys, with the constants.SIGNATURE_WEAK_KEY status. They are accepted by
+// default. A verification can override it, see VerifyOptions.
+func SetRejectWeakSigningKeys(reject bool) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.rejectWeakSigningKeys = reject
+}
+
+// rejectsWeakSigningKeys returns whether a verification with the given
+// constants.WEAK_SIGNING_KEYS_* policy rejects weak signing keys.
+func rejectsWeakSigningKeys(policy int) (bool, error) {
+	switch policy {
+	case constants.WEAK_SIGNING_KEYS_ALLOW:
+		return false, nil
+	case constants.WEAK_SIGNING_KEYS_REJECT:
+		return true, nil
+	case constants.WEAK_SIGNING_KEYS_DEFAULT:
+		pgp.lock.RLock()
+		defer pgp.lock.RUnlock()
+		return pgp.rejectWeakSigningKeys, nil
+	}
+	return false, errors.New("gopenpgp: unknown weak signing key policy")
+}
+
+// isWeakSigningKey returns true for RSA keys of less than 2048 bits and for DSA
+// keys.
+func isWeakSigningKey(key *packet.PublicKey) bool {
+	switch key.PubKeyAlgo {
+	case packet.PubKeyAlgoDSA:
+		return true
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+		bitLength, err := key.BitLength()
+		return err != nil || bitLength < minRSASigningKeyBits
+	}
+	return false
+}
+
+// getDetachedSigningKey returns the signing key of signer which issued the
+// first of the signature packets issued by its keys, as the library verifies
+// them, see checkDetachedSignatureTime.
+func getDetachedSigningKey(signer *openpgp.Entity, signature []byte) (*packet.PublicKey, bool) {
+	key, _, ok := getDetachedSignature(signer, signature)
+	return key, ok
+}
+
+// getDetachedSignaturePacket returns the signature packet which the library
+// verified with the keys of signer, see getDetachedSigningKey.
+func getDetachedSignaturePacket(signer *openpgp.Entity, signature []byte) (*packet.Signature, bool) {
+	_, sig, ok := getDetachedSignature(signer, signature)
+	return sig, ok
+}
+
+func getDetachedSignature(signer *openpgp.Entity, signature []byte) (*packet.PublicKey, *packet.Signature, bool) {
+	packets := packet.NewReader(bytes.NewReader(signature))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return nil, nil, false
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.IssuerKeyId == nil {
+			continue
+		}
+		keys := openpgp.EntityList{signer}.KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+		if len(keys) != 0 {
+			return keys[0].PublicKey, sig, true
+		}
+	}
+}
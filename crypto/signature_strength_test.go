@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+func getSignatureStatus(t *testing.T, err error) int {
+	if err == nil {
+		return constants.SIGNATURE_OK
+	}
+	var verificationError SignatureVerificationError
+	if !errors.As(err, &verificationError) {
+		t.Fatal("Expected a SignatureVerificationError, got:", err)
+	}
+	return verificationError.Status
+}
+
+// getVerificationStatuses signs a message with signKeyRing, and returns the
+// statuses of its verification with verifyKeyRing and options as a detached,
+// embedded, cleartext and session key signature, and of the verification of
+// the cleartext signature with a reader, which only follows the default.
+func getVerificationStatuses(t *testing.T, signKeyRing, verifyKeyRing *KeyRing, options *VerifyOptions) map[string]int {
+	message := NewPlainMessageFromString("signed by a weak key")
+	statuses := make(map[string]int)
+
+	signature, err := signKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	statuses["detached"] = getSignatureStatus(t, verifyKeyRing.VerifyDetachedWithOptions(message, signature, GetUnixTime(), options))
+
+	encrypted, err := keyRingTestPublic.Encrypt(message, signKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptWithOptions(encrypted, verifyKeyRing, GetUnixTime(), options)
+	statuses["embedded"] = getSignatureStatus(t, err)
+
+	armored := signClearTextWithKeyRing(t, signKeyRing, message.GetString())
+	clearText, err := NewClearTextMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext message, got:", err)
+	}
+	_, err = clearText.VerifyOnlyWithOptions(verifyKeyRing, GetUnixTime(), options)
+	statuses["cleartext"] = getSignatureStatus(t, err)
+	reader, err := NewClearTextMessageReader(strings.NewReader(armored), verifyKeyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext header, got:", err)
+	}
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	if options == nil {
+		statuses["cleartext reader"] = getSignatureStatus(t, reader.VerifySignature())
+	}
+
+	dataPacket, err := testSessionKey.EncryptAndSign(message, signKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with session key, got:", err)
+	}
+	_, err = testSessionKey.DecryptAndVerifyWithOptions(dataPacket, verifyKeyRing, GetUnixTime(), options)
+	statuses["session key"] = getSignatureStatus(t, err)
+
+	return statuses
+}
+
+func TestWeakSigningKeyPolicy(t *testing.T) {
+	dsaKey, err := NewKeyFromArmored(readTestFile("key_dsa", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	var tests = []struct {
+		name string
+		key  *Key
+		weak bool
+	}{
+		{"rsa1024", keyTestRSA, true},
+		{"dsa", dsaKey, true},
+		{"x25519", keyTestEC, false},
+	}
+	defer SetRejectWeakSigningKeys(false)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyRing, err := NewKeyRing(test.key)
+			if err != nil {
+				t.Fatal("Expected no error while building keyring, got:", err)
+			}
+			assert.Exactly(t, test.weak, isWeakSigningKey(test.key.entity.PrimaryKey))
+			weakStatus := constants.SIGNATURE_OK
+			if test.weak {
+				weakStatus = constants.SIGNATURE_WEAK_KEY
+			}
+
+			for _, status := range getVerificationStatuses(t, keyRing, keyRing, nil) {
+				assert.Exactly(t, constants.SIGNATURE_OK, status)
+			}
+
+			reject := &VerifyOptions{WeakSigningKeyPolicy: constants.WEAK_SIGNING_KEYS_REJECT}
+			for path, status := range getVerificationStatuses(t, keyRing, keyRing, reject) {
+				assert.Exactly(t, weakStatus, status, path)
+			}
+			// The options only apply to their call
+			for path, status := range getVerificationStatuses(t, keyRing, keyRing, nil) {
+				assert.Exactly(t, constants.SIGNATURE_OK, status, path)
+			}
+
+			// A weak key doesn't make an invalid signature valid
+			signature, err := keyRing.SignDetached(NewPlainMessageFromString("signed"))
+			if err != nil {
+				t.Fatal("Expected no error while signing, got:", err)
+			}
+			err = keyRing.VerifyDetachedWithOptions(NewPlainMessageFromString("tampered"), signature, GetUnixTime(), reject)
+			assert.Exactly(t, constants.SIGNATURE_FAILED, getSignatureStatus(t, err))
+
+			// The options override the default
+			SetRejectWeakSigningKeys(true)
+			allow := &VerifyOptions{WeakSigningKeyPolicy: constants.WEAK_SIGNING_KEYS_ALLOW}
+			for path, status := range getVerificationStatuses(t, keyRing, keyRing, allow) {
+				assert.Exactly(t, constants.SIGNATURE_OK, status, path)
+			}
+			for path, status := range getVerificationStatuses(t, keyRing, keyRing, nil) {
+				assert.Exactly(t, weakStatus, status, path)
+			}
+			SetRejectWeakSigningKeys(false)
+		})
+	}
+
+	invalid := &VerifyOptions{WeakSigningKeyPolicy: 3}
+	err = keyRingTestPublic.VerifyDetachedWithOptions(NewPlainMessageFromString("signed"), &PGPSignature{}, 0, invalid)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &SignatureVerificationError{}))
+}
@@ -1,10 +1,17 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto"
+	"encoding/json"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/stretchr/testify/assert"
 	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
@@ -73,3 +80,223 @@ func TestVerifyBinDetachedSig(t *testing.T) {
 		t.Fatal("Cannot verify binary signature:", verificationError)
 	}
 }
+
+func TestSignatureVerificationErrorJSON(t *testing.T) {
+	statuses := []int{
+		constants.SIGNATURE_OK,
+		constants.SIGNATURE_NOT_SIGNED,
+		constants.SIGNATURE_NO_VERIFIER,
+		constants.SIGNATURE_FAILED,
+	}
+	for _, status := range statuses {
+		original := SignatureVerificationError{
+			Status:       status,
+			Message:      "Invalid signature",
+			Cause:        errors.New("openpgp: invalid signature: hash tag doesn't match"),
+			SignerKeyID:  "4b5a2e31d3b53cb1",
+			CreationTime: testTime,
+		}
+		encoded, err := json.Marshal(original)
+		if err != nil {
+			t.Fatal("Expected no error while encoding verification error, got:", err)
+		}
+
+		var decoded SignatureVerificationError
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal("Expected no error while decoding verification error, got:", err)
+		}
+		assert.Exactly(t, original.Status, decoded.Status)
+		assert.Exactly(t, original.Message, decoded.Message)
+		assert.Exactly(t, original.Cause.Error(), decoded.Cause.Error())
+		assert.Exactly(t, original.SignerKeyID, decoded.SignerKeyID)
+		assert.Exactly(t, original.CreationTime, decoded.CreationTime)
+	}
+
+	encoded, err := json.Marshal(SignatureVerificationError{Status: constants.SIGNATURE_NOT_SIGNED, Message: "Missing signature"})
+	if err != nil {
+		t.Fatal("Expected no error while encoding verification error, got:", err)
+	}
+	assert.JSONEq(
+		t,
+		`{"status":1,"message":"Missing signature","cause":"","signerKeyID":"","creationTime":0}`,
+		string(encoded),
+	)
+
+	var decoded SignatureVerificationError
+	err = json.Unmarshal([]byte(`{"status":3,"message":"Invalid signature","futureField":[1,2]}`), &decoded)
+	if err != nil {
+		t.Fatal("Expected no error while decoding verification error with unknown fields, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_FAILED, decoded.Status)
+	assert.Nil(t, decoded.Cause)
+}
+
+func TestSignatureVerificationErrorDetails(t *testing.T) {
+	fakeMessage := NewPlainMessageFromString("wrong text")
+	verificationError := keyRingTestPublic.VerifyDetached(fakeMessage, textSignature, testTime)
+
+	err := &SignatureVerificationError{}
+	if !errors.As(verificationError, err) {
+		t.Fatal("Expected a signature verification error, got:", verificationError)
+	}
+	assert.Exactly(t, keyRingTestPublic.GetKeys()[0].GetHexKeyID(), err.SignerKeyID)
+	assert.NotZero(t, err.CreationTime)
+	assert.NotNil(t, err.Cause)
+}
+
+func TestVerifyRotatedSigningSubkey(t *testing.T) {
+	// The signing subkey was bound on 2019-01-01 and signed the message on
+	// 2019-02-01. On 2019-06-01, its binding signature was replaced, setting
+	// an expiration, and a new signing subkey was added.
+	key, err := NewKeyFromArmored(readTestFile("key_rotatedSigningSubkey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	signature, err := NewPGPSignatureFromArmored(readTestFile("signature_rotatedSigningSubkey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	message, err := NewPGPMessageFromArmored(readTestFile("message_rotatedSigningSubkey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing message, got:", err)
+	}
+
+	signatureTime := int64(1548979200)
+	bindingTime := int64(1559347200)
+	for _, verifyTime := range []int64{signatureTime, signatureTime + 3600, bindingTime - 1, bindingTime + 3600} {
+		err := keyRing.VerifyDetached(NewPlainMessage([]byte("rotation\n")), signature, verifyTime)
+		assert.NoError(t, err, "detached signature verified at %d", verifyTime)
+
+		decrypted, err := keyRing.Decrypt(message, keyRing, verifyTime)
+		assert.NoError(t, err, "inline signature verified at %d", verifyTime)
+		assert.Exactly(t, "rotation\n", decrypted.GetString())
+	}
+}
+
+// signDetachedAt returns a detached signature of data created at the given
+// time, expiring after lifetime seconds if it isn't 0.
+func signDetachedAt(t *testing.T, data []byte, created int64, lifetime uint32) *PGPSignature {
+	var signature bytes.Buffer
+	config := &packet.Config{
+		DefaultHash:     crypto.SHA256,
+		Time:            func() time.Time { return time.Unix(created, 0) },
+		SigLifetimeSecs: lifetime,
+	}
+	if err := openpgp.DetachSign(&signature, keyRingTestPrivate.entities[0], bytes.NewReader(data), config); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	return NewPGPSignature(signature.Bytes())
+}
+
+func TestVerifySignatureTimes(t *testing.T) {
+	data := []byte("signature times")
+	const hour = 60 * 60
+	for _, test := range []struct {
+		name       string
+		created    int64
+		lifetime   uint32
+		verifyTime int64
+		valid      bool
+	}{
+		{"valid", testTime - hour, 0, testTime, true},
+		{"future within skew", testTime + hour, 0, testTime, true},
+		{"future beyond skew", testTime + 3*24*hour, 0, testTime, false},
+		{"expired", testTime - hour, 5 * 60, testTime, false},
+		{"not yet expired", testTime - hour, 2 * hour, testTime, true},
+		{"future within skew, not yet expired", testTime + hour, 5 * 60, testTime, true},
+		{"future within skew, expired", testTime + hour, 5 * 60, testTime + 2*hour, false},
+		{"expired, time check disabled", testTime - hour, 5 * 60, 0, true},
+		{"valid now", testTime - hour, 0, constants.VERIFY_TIME_NOW, true},
+		{"expired now", testTime - hour, 5 * 60, constants.VERIFY_TIME_NOW, false},
+	} {
+		signature := signDetachedAt(t, data, test.created, test.lifetime)
+		err := keyRingTestPublic.VerifyDetached(NewPlainMessage(data), signature, test.verifyTime)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+
+		md := &openpgp.MessageDetails{SignatureError: pgpErrors.ErrSignatureExpired}
+		md.Signature, err = signature.getSignaturePacket()
+		if err != nil {
+			t.Fatal("Expected no error while parsing signature, got:", err)
+		}
+		processSignatureExpiration(md, test.verifyTime)
+		assert.Exactly(t, test.valid, md.SignatureError == nil, test.name)
+	}
+}
+
+// newLargeVerificationKeyRing returns a keyring of size public keys, the last
+// one matching signer.
+func newLargeVerificationKeyRing(tb testing.TB, size int) (keyRing, signer *KeyRing) {
+	keyRing = &KeyRing{}
+	for i := 0; i < size; i++ {
+		key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+		if err != nil {
+			tb.Fatal("Expected no error while generating key, got:", err)
+		}
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			tb.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		keyRing.appendKey(publicKey)
+		if i == size-1 {
+			if signer, err = NewKeyRing(key); err != nil {
+				tb.Fatal("Expected no error while building keyring, got:", err)
+			}
+		}
+	}
+	return keyRing, signer
+}
+
+func TestVerifyDetachedIssuer(t *testing.T) {
+	keyRing, signer := newLargeVerificationKeyRing(t, 5)
+	message := NewPlainMessage([]byte("issuer"))
+	signature, err := signer.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, testTime))
+
+	// A lying issuer fingerprint points to another key of the keyring
+	data := signature.GetBinary()
+	signerFingerprint := signer.entities[0].PrimaryKey.Fingerprint
+	otherFingerprint := keyRing.entities[0].PrimaryKey.Fingerprint
+	i := bytes.Index(data, signerFingerprint)
+	if i < 0 {
+		t.Fatal("Expected an issuer fingerprint in the signature")
+	}
+	copy(data[i:], otherFingerprint)
+	assert.Error(t, keyRing.VerifyDetached(message, NewPGPSignature(data), testTime))
+}
+
+func BenchmarkVerifyDetachedSingleKey(b *testing.B) {
+	benchmarkVerifyDetached(b, 1)
+}
+
+// BenchmarkVerifyDetachedLargeKeyRing runs as fast as with a single key: the
+// library only verifies with the keys matching the issuer of the signature.
+func BenchmarkVerifyDetachedLargeKeyRing(b *testing.B) {
+	benchmarkVerifyDetached(b, 50)
+}
+
+func benchmarkVerifyDetached(b *testing.B, size int) {
+	keyRing, signer := newLargeVerificationKeyRing(b, size)
+	message := NewPlainMessage([]byte("benchmark"))
+	signature, err := signer.SignDetached(message)
+	if err != nil {
+		b.Fatal("Expected no error while signing, got:", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := keyRing.VerifyDetached(message, signature, testTime); err != nil {
+			b.Fatal("Expected no error while verifying, got:", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// signerPolicy holds the checks applied by a verification keyring to the key
+// which made an otherwise valid signature.
+type signerPolicy struct {
+	rejectWeakKeys bool
+	// Fingerprints of the accepted signers, any key if empty
+	requiredSigners [][]byte
+	// Maximum age in seconds of the signatures, unbounded if 0
+	maxSignatureAge int64
+	// Whether decryption falls back to the key embedded in the message
+	allowEmbeddedKey bool
+}
+
+// parseRequiredSigners decodes the hex fingerprints of
+// VerifyOptions.RequiredSigners.
+func parseRequiredSigners(fingerprints []string) ([][]byte, error) {
+	var requiredSigners [][]byte
+	for _, fingerprint := range fingerprints {
+		// Also accept the formatted fingerprints, see FormatFingerprint
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(fingerprint), ""))
+		if err != nil || len(decoded) == 0 {
+			return nil, errors.Errorf("gopenpgp: invalid fingerprint %q", fingerprint)
+		}
+		requiredSigners = append(requiredSigners, decoded)
+	}
+	return requiredSigners, nil
+}
+
+// rejectSigner checks the key of entity which made a valid signature, and
+// returns the SignatureVerificationError to report if the policy rejects it,
+// nil otherwise.
+func (policy signerPolicy) rejectSigner(entity *openpgp.Entity, key *packet.PublicKey) error {
+	if !policy.isRequiredSigner(entity, key) {
+		return newSignatureWrongSigner()
+	}
+	if policy.rejectWeakKeys && isWeakSigningKey(key) {
+		return newSignatureWeakKey()
+	}
+	return nil
+}
+
+func (policy signerPolicy) isRequiredSigner(entity *openpgp.Entity, key *packet.PublicKey) bool {
+	if len(policy.requiredSigners) == 0 {
+		return true
+	}
+	for _, fingerprint := range policy.requiredSigners {
+		if bytes.Equal(fingerprint, key.Fingerprint) ||
+			(entity != nil && bytes.Equal(fingerprint, entity.PrimaryKey.Fingerprint)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectDetachedSigner checks the key of signer which verified the detached
+// signature, see rejectSigner.
+func (policy signerPolicy) rejectDetachedSigner(signer *openpgp.Entity, signature []byte) error {
+	key, ok := getDetachedSigningKey(signer, signature)
+	if !ok {
+		key = signer.PrimaryKey
+	}
+	return policy.rejectSigner(signer, key)
+}
+
+// rejectSignatureAge checks the creation time of a valid signature at
+// verifyTime, and returns the error to report if it is too old.
+func (policy signerPolicy) rejectSignatureAge(sig *packet.Signature, verifyTime int64) (SignatureVerificationError, bool) {
+	if policy.maxSignatureAge == 0 || verifyTime == 0 {
+		return SignatureVerificationError{}, false
+	}
+	if sig.CreationTime.Unix() < verifyTime-policy.maxSignatureAge {
+		return newSignatureTooOld(), true
+	}
+	return SignatureVerificationError{}, false
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+func TestRequiredSigners(t *testing.T) {
+	// Signs with its signing subkey
+	stubKey, err := NewKeyFromArmored(readTestFile("key_stubPrimary", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	unlockedKey, err := stubKey.Unlock([]byte(stubKeyPassphrase))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	signKeyRing, err := NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	var signingSubkey, encryptionSubkey string
+	for _, subkey := range stubKey.entity.Subkeys {
+		if subkey.Sig.FlagSign {
+			signingSubkey = hex.EncodeToString(subkey.PublicKey.Fingerprint)
+		} else {
+			encryptionSubkey = hex.EncodeToString(subkey.PublicKey.Fingerprint)
+		}
+	}
+
+	// A ring of two keys
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	publicStubKey, err := stubKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	if err := verifyKeyRing.AddKey(publicStubKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		signers []string
+		status  int
+	}{
+		{"any signer", nil, constants.SIGNATURE_OK},
+		{"primary key", []string{stubKey.GetFingerprint()}, constants.SIGNATURE_OK},
+		{"formatted primary key", []string{stubKey.GetFormattedFingerprint()}, constants.SIGNATURE_OK},
+		{"signing subkey", []string{signingSubkey}, constants.SIGNATURE_OK},
+		{"one of several", []string{keyTestRSA.GetFingerprint(), signingSubkey}, constants.SIGNATURE_OK},
+		{"wrong key", []string{keyRingTestPublic.GetKeys()[0].GetFingerprint()}, constants.SIGNATURE_WRONG_SIGNER},
+		{"encryption subkey", []string{encryptionSubkey}, constants.SIGNATURE_WRONG_SIGNER},
+	} {
+		options := &VerifyOptions{RequiredSigners: test.signers}
+		for path, status := range getVerificationStatuses(t, signKeyRing, verifyKeyRing, options) {
+			assert.Exactly(t, test.status, status, test.name+": "+path)
+		}
+	}
+
+	// An invalid signature by the wrong signer fails
+	options := &VerifyOptions{RequiredSigners: []string{stubKey.GetFingerprint()}}
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("signed"))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	err = verifyKeyRing.VerifyDetachedWithOptions(NewPlainMessageFromString("tampered"), signature, GetUnixTime(), options)
+	assert.Exactly(t, constants.SIGNATURE_FAILED, getSignatureStatus(t, err))
+
+	// The required signers only apply to the call
+	err = verifyKeyRing.VerifyDetached(NewPlainMessageFromString("signed"), signature, GetUnixTime())
+	assert.NoError(t, err)
+
+	options = &VerifyOptions{RequiredSigners: []string{"not a fingerprint"}}
+	err = verifyKeyRing.VerifyDetachedWithOptions(NewPlainMessageFromString("signed"), signature, GetUnixTime(), options)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &SignatureVerificationError{}))
+}
+
+func TestMaxSignatureAge(t *testing.T) {
+	const maxAge = 600
+	message := NewPlainMessageFromString("attestation")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	sig, err := signature.getSignaturePacket()
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	created := sig.CreationTime.Unix()
+	cleartext, err := NewClearTextMessageFromArmored(signClearTextWithKeyRing(t, keyRingTestPrivate, message.GetString()))
+	if err != nil {
+		t.Fatal("Expected no error while parsing cleartext message, got:", err)
+	}
+
+	options := &VerifyOptions{MaxSignatureAge: maxAge}
+	cachedKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	cachedKeyRing.SetVerificationCache(newTestVerificationCache())
+
+	for _, test := range []struct {
+		name       string
+		verifyTime int64
+		status     int
+	}{
+		{"just created", created, constants.SIGNATURE_OK},
+		{"max age", created + maxAge, constants.SIGNATURE_OK},
+		{"too old", created + maxAge + 1, constants.SIGNATURE_TOO_OLD},
+		// The clock skew margin only applies to the future
+		{"created in the future", created - internal.CreationTimeOffset, constants.SIGNATURE_OK},
+		{"too far in the future", created - internal.CreationTimeOffset - 1, constants.SIGNATURE_FAILED},
+		{"time checks disabled", constants.VERIFY_TIME_DISABLED, constants.SIGNATURE_OK},
+	} {
+		statuses := map[string]int{
+			"detached": getSignatureStatus(t, keyRingTestPublic.VerifyDetachedWithOptions(
+				message, signature, test.verifyTime, options,
+			)),
+			"cached": getSignatureStatus(t, cachedKeyRing.VerifyDetachedWithOptions(
+				message, signature, test.verifyTime, options,
+			)),
+		}
+		_, err := cleartext.VerifyOnlyWithOptions(keyRingTestPublic, test.verifyTime, options)
+		statuses["cleartext"] = getSignatureStatus(t, err)
+		for path, status := range statuses {
+			assert.Exactly(t, test.status, status, test.name+": "+path)
+		}
+	}
+
+	// The maximum age only applies to the call
+	assert.NoError(t, keyRingTestPublic.VerifyDetached(message, signature, created+maxAge+1))
+	assert.NoError(t, cachedKeyRing.VerifyDetached(message, signature, created+maxAge+1))
+
+	options = &VerifyOptions{MaxSignatureAge: -1}
+	err = keyRingTestPublic.VerifyDetachedWithOptions(message, signature, created, options)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &SignatureVerificationError{}))
+}
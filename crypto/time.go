@@ -1,40 +1,158 @@
 package crypto
 
 import (
+	"sync"
 	"time"
+
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
-// UpdateTime updates cached time.
+// TimeProvider provides the current time, e.g. the server time, see
+// SetTimeProvider.
+type TimeProvider interface {
+	GetUnixTime() int64
+}
+
+// ServerTimeProvider is a TimeProvider tracking the server time: the time
+// elapsed since the last update is measured with the monotonic clock, so that
+// the time doesn't stop between updates nor follows changes of the system
+// clock. It is safe for concurrent use.
+type ServerTimeProvider struct {
+	mutex      sync.Mutex
+	serverTime int64
+	// Local time of the last update, with its monotonic clock reading
+	updatedAt time.Time
+	clock     timeClock
+}
+
+// timeClock is the time source of a ServerTimeProvider, replaced in tests.
+type timeClock interface {
+	Now() time.Time
+}
+
+// UpdateTime updates the cached server time, which then advances with the
+// monotonic clock until the next update, unless SetTimeFrozen is set. Updates
+// to an earlier time than the latest one are ignored.
 func UpdateTime(newTime int64) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
 	if newTime > pgp.latestServerTime {
 		pgp.latestServerTime = newTime
+		pgp.serverTime.UpdateTime(newTime)
 	}
 }
 
+// SetTimeFrozen sets whether the time set with UpdateTime stays the same until
+// the next update, instead of advancing with the monotonic clock, which is
+// the default.
+func SetTimeFrozen(frozen bool) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.timeFrozen = frozen
+}
+
 // SetKeyGenerationOffset updates the offset when generating keys.
 func SetKeyGenerationOffset(offset int64) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
 	pgp.generationOffset = offset
 }
 
-// GetUnixTime gets latest cached time.
+// NewServerTimeProvider returns a ServerTimeProvider, which provides the local
+// time until it is updated.
+func NewServerTimeProvider() *ServerTimeProvider {
+	return &ServerTimeProvider{clock: systemClock{}}
+}
+
+// UpdateTime sets the current server time. Unlike the package UpdateTime, the
+// time can go backwards.
+func (provider *ServerTimeProvider) UpdateTime(serverTime int64) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	provider.serverTime = serverTime
+	provider.updatedAt = provider.now()
+}
+
+// GetUnixTime returns the last server time plus the time elapsed since, or
+// the local time if the server time was never set.
+func (provider *ServerTimeProvider) GetUnixTime() int64 {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	if provider.updatedAt.IsZero() {
+		return provider.now().Unix()
+	}
+	return provider.serverTime + int64(provider.now().Sub(provider.updatedAt)/time.Second)
+}
+
+func (provider *ServerTimeProvider) now() time.Time {
+	if provider.clock == nil {
+		return time.Now()
+	}
+	return provider.clock.Now()
+}
+
+// SetTimeProvider sets the provider of the current time, used for signing,
+// key generation, and by the verification functions with
+// constants.VERIFY_TIME_NOW. It replaces the server time set with UpdateTime;
+// nil restores it.
+func SetTimeProvider(provider TimeProvider) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+	pgp.timeProvider = provider
+}
+
+// GetTimeWith returns the current time of provider, or the time of GetTime if
+// provider is nil.
+func GetTimeWith(provider TimeProvider) time.Time {
+	if provider == nil {
+		return GetTime()
+	}
+	return time.Unix(provider.GetUnixTime(), 0)
+}
+
+// ResolveVerifyTime returns the verification time to pass to the verification
+// functions: the current time if verifyTime is constants.VERIFY_TIME_NOW,
+// otherwise verifyTime, where constants.VERIFY_TIME_DISABLED disables the time
+// checks. The verification functions also resolve constants.VERIFY_TIME_NOW
+// themselves.
+func ResolveVerifyTime(verifyTime int64) int64 {
+	if verifyTime == constants.VERIFY_TIME_NOW {
+		return GetUnixTime()
+	}
+	return verifyTime
+}
+
+// GetUnixTime gets the current time, see UpdateTime and SetTimeProvider.
 func GetUnixTime() int64 {
 	return getNow().Unix()
 }
 
-// GetTime gets latest cached time.
+// GetTime gets the current time, see UpdateTime and SetTimeProvider.
 func GetTime() time.Time {
 	return getNow()
 }
 
 // ----- INTERNAL FUNCTIONS -----
 
-// getNow returns the latest server time.
+// getNow returns the time of the time provider, or the server time, see
+// UpdateTime.
 func getNow() time.Time {
-	if pgp.latestServerTime == 0 {
+	pgp.lock.RLock()
+	provider, latestServerTime, frozen := pgp.timeProvider, pgp.latestServerTime, pgp.timeFrozen
+	serverTime := pgp.serverTime
+	pgp.lock.RUnlock()
+
+	if provider != nil {
+		return time.Unix(provider.GetUnixTime(), 0)
+	}
+	if latestServerTime == 0 {
 		return time.Now()
 	}
+	if frozen {
+		return time.Unix(latestServerTime, 0)
+	}
 
-	return time.Unix(pgp.latestServerTime, 0)
+	return time.Unix(serverTime.GetUnixTime(), 0)
 }
 
 // getTimeGenerator Returns a time generator function.
@@ -44,11 +162,10 @@ func getTimeGenerator() func() time.Time {
 
 // getNowKeyGenerationOffset returns the current time with the key generation offset.
 func getNowKeyGenerationOffset() time.Time {
-	if pgp.latestServerTime == 0 {
-		return time.Unix(time.Now().Unix()+pgp.generationOffset, 0)
-	}
-
-	return time.Unix(pgp.latestServerTime+pgp.generationOffset, 0)
+	pgp.lock.RLock()
+	offset := pgp.generationOffset
+	pgp.lock.RUnlock()
+	return time.Unix(getNow().Unix()+offset, 0)
 }
 
 // getKeyGenerationTimeGenerator Returns a time generator function with the key generation offset.
@@ -1,17 +1,78 @@
 package crypto
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 )
 
 func TestTime(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	defaultServerTime := pgp.serverTime
+	pgp.serverTime = &ServerTimeProvider{clock: clock}
+	defer func() { pgp.serverTime = defaultServerTime }()
+
+	SetTimeFrozen(false)
+	defer SetTimeFrozen(true)
+	defer func() { pgp.latestServerTime = testTime }()
 	UpdateTime(1571072494)
-	time.Sleep(1 * time.Second)
-	now := GetUnixTime()
+	clock.Advance(time.Second)
+	assert.Exactly(t, int64(1571072495), GetUnixTime()) // Latest server time, advanced
+
+	// Earlier server times are ignored
+	UpdateTime(1571072490)
+	assert.Exactly(t, int64(1571072495), GetUnixTime())
+
+	SetTimeFrozen(true)
+	assert.Exactly(t, int64(1571072494), GetUnixTime()) // Latest server time
+}
+
+func TestServerTimeProvider(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	provider := &ServerTimeProvider{clock: clock}
+	assert.Exactly(t, int64(1600000000), provider.GetUnixTime())
+
+	provider.UpdateTime(1571072494)
+	clock.Advance(time.Second)
+	assert.Exactly(t, int64(1571072495), provider.GetUnixTime())
+	assert.Exactly(t, int64(1571072495), GetTimeWith(provider).Unix())
+	assert.Exactly(t, GetUnixTime(), GetTimeWith(nil).Unix())
+
+	SetTimeProvider(provider)
+	defer SetTimeProvider(nil)
+	assert.Exactly(t, int64(1571072495), GetUnixTime())
+	assert.Exactly(t, int64(1571072495), ResolveVerifyTime(constants.VERIFY_TIME_NOW))
+	assert.Exactly(t, constants.VERIFY_TIME_DISABLED, ResolveVerifyTime(constants.VERIFY_TIME_DISABLED))
+	assert.Exactly(t, int64(testTime), ResolveVerifyTime(testTime))
+
+	// Unlike UpdateTime, the provider can go back in time
+	provider.UpdateTime(testTime)
+	assert.Exactly(t, int64(testTime), GetUnixTime())
+
+	// The system clock is used by default
+	assert.InDelta(t, time.Now().Unix(), NewServerTimeProvider().GetUnixTime(), 1)
+}
+
+func TestTimeConcurrentAccess(t *testing.T) {
+	defer func() {
+		pgp.latestServerTime = testTime
+		pgp.generationOffset = 0
+	}()
 
-	assert.Exactly(t, int64(1571072494), now) // Use latest server time
-	UpdateTime(testTime)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			UpdateTime(testTime + int64(i))
+			SetKeyGenerationOffset(int64(i))
+			_ = getTimeGenerator()()
+			_ = getKeyGenerationTimeGenerator()()
+		}(i)
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, GetUnixTime(), int64(testTime))
 }
@@ -0,0 +1,252 @@
+package crypto
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/pkg/errors"
+)
+
+// DefaultUnlockCacheSize is the default maximum number of derived keys stored
+// in an UnlockCache.
+const DefaultUnlockCacheSize = 64
+
+const (
+	packetTagSecretSubkey = 7
+
+	s2kUsageSHA1     = 254
+	s2kUsageChecksum = 255
+)
+
+// UnlockCache stores the keys derived from passphrases by the S2K functions
+// of locked private keys, so that unlocking keys with the same passphrase and
+// S2K parameters, e.g. the same keys at each login, only derives them once.
+// The passphrases are not stored: the entries are indexed by a keyed hash of
+// the passphrase and the S2K parameters, with a random key private to the
+// cache. It is safe for concurrent use.
+type UnlockCache struct {
+	lock       sync.Mutex
+	hmacKey    []byte
+	maxEntries int
+	entries    map[[sha256.Size]byte]*list.Element
+	// The entries, most recently used first
+	order  *list.List
+	hits   int
+	misses int
+}
+
+type unlockCacheEntry struct {
+	id  [sha256.Size]byte
+	key []byte
+}
+
+// NewUnlockCache returns an empty cache storing at most maxEntries derived
+// keys, or DefaultUnlockCacheSize if maxEntries is not positive.
+// The least recently used keys are evicted first.
+func NewUnlockCache(maxEntries int) (*UnlockCache, error) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultUnlockCacheSize
+	}
+	hmacKey := make([]byte, sha256.Size)
+	if _, err := rand.Read(hmacKey); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in generating the unlock cache key")
+	}
+	return &UnlockCache{
+		hmacKey:    hmacKey,
+		maxEntries: maxEntries,
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+		order:      list.New(),
+	}, nil
+}
+
+// GetHits returns the number of derived keys found in the cache.
+func (cache *UnlockCache) GetHits() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.hits
+}
+
+// GetMisses returns the number of keys derived because they were not found in
+// the cache.
+func (cache *UnlockCache) GetMisses() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.misses
+}
+
+// Clear wipes the derived keys stored in the cache, and empties it.
+func (cache *UnlockCache) Clear() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	for element := cache.order.Front(); element != nil; element = element.Next() {
+		clearMem(element.Value.(*unlockCacheEntry).key)
+	}
+	cache.entries = make(map[[sha256.Size]byte]*list.Element)
+	cache.order.Init()
+}
+
+// deriveKey returns the key of keySize bytes derived from passphrase by
+// s2kFunc, whose parameters are serialized in spec.
+func (cache *UnlockCache) deriveKey(passphrase, spec []byte, keySize int, s2kFunc func(out, in []byte)) []byte {
+	mac := hmac.New(sha256.New, cache.hmacKey)
+	// The lengths make the encoding unambiguous
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(passphrase)))
+	_, _ = mac.Write(length[:])
+	_, _ = mac.Write(passphrase)
+	binary.BigEndian.PutUint64(length[:], uint64(keySize))
+	_, _ = mac.Write(length[:])
+	_, _ = mac.Write(spec)
+	var id [sha256.Size]byte
+	copy(id[:], mac.Sum(nil))
+
+	cache.lock.Lock()
+	if element, ok := cache.entries[id]; ok {
+		cache.order.MoveToFront(element)
+		cache.hits++
+		key := clone(element.Value.(*unlockCacheEntry).key)
+		cache.lock.Unlock()
+		return key
+	}
+	cache.misses++
+	cache.lock.Unlock()
+
+	key := make([]byte, keySize)
+	s2kFunc(key, passphrase)
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if _, ok := cache.entries[id]; !ok {
+		cache.entries[id] = cache.order.PushFront(&unlockCacheEntry{id: id, key: clone(key)})
+		if cache.order.Len() > cache.maxEntries {
+			entry := cache.order.Remove(cache.order.Back()).(*unlockCacheEntry)
+			clearMem(entry.key)
+			delete(cache.entries, entry.id)
+		}
+	}
+	return key
+}
+
+// decryptPrivateKey decrypts pk in place with passphrase, like pk.Decrypt,
+// deriving the key which encrypts it with cache if it isn't nil.
+func decryptPrivateKey(pk *packet.PrivateKey, passphrase []byte, cache *UnlockCache) error {
+	if cache == nil || pk.Version != 4 || !pk.Encrypted || pk.Dummy() {
+		return pk.Decrypt(passphrase)
+	}
+
+	// The library doesn't expose the encryption parameters of the key,
+	// read them after the public key in its serialization.
+	var serialized, public bytes.Buffer
+	if err := pk.Serialize(&serialized); err != nil {
+		return err
+	}
+	if err := pk.PublicKey.Serialize(&public); err != nil {
+		return err
+	}
+	publicBody := getPacketBody(public.Bytes())
+	body := getPacketBody(serialized.Bytes())
+	if len(body) < len(publicBody)+2 {
+		return pgpErrors.StructuralError("truncated private key data")
+	}
+	s2kUsage := body[len(publicBody)]
+	if s2kUsage != s2kUsageSHA1 && s2kUsage != s2kUsageChecksum {
+		return pk.Decrypt(passphrase)
+	}
+	cf := packet.CipherFunction(body[len(publicBody)+1])
+	rest := body[len(publicBody)+2:]
+	reader := bytes.NewReader(rest)
+	params, err := s2k.ParseIntoParams(reader)
+	if err != nil {
+		return err
+	}
+	spec := rest[:len(rest)-reader.Len()]
+	rest = rest[len(spec):]
+	s2kFunc, err := params.Function()
+	if err != nil {
+		return err
+	}
+	if cf.KeySize() == 0 {
+		return pgpErrors.UnsupportedError("unsupported cipher in private key")
+	}
+
+	key := cache.deriveKey(passphrase, spec, cf.KeySize(), s2kFunc)
+	defer clearMem(key)
+	block, err := newBlockCipher(cf, key)
+	if err != nil {
+		return err
+	}
+	if len(rest) < block.BlockSize() {
+		return pgpErrors.StructuralError("truncated private key data")
+	}
+	iv, encrypted := rest[:block.BlockSize()], rest[block.BlockSize():]
+	data := make([]byte, len(encrypted))
+	defer clearMem(data)
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(data, encrypted)
+
+	var secret []byte
+	if s2kUsage == s2kUsageSHA1 {
+		if len(data) < sha1.Size {
+			return pgpErrors.StructuralError("truncated private key data")
+		}
+		secret = data[:len(data)-sha1.Size]
+		sum := sha1.Sum(secret) //nolint:gosec
+		if !hmac.Equal(sum[:], data[len(secret):]) {
+			return pgpErrors.StructuralError("private key checksum failure")
+		}
+	} else {
+		if len(data) < 2 {
+			return pgpErrors.StructuralError("truncated private key data")
+		}
+		secret = data[:len(data)-2]
+		sum := secretChecksum(secret)
+		if data[len(secret)] != byte(sum>>8) || data[len(secret)+1] != byte(sum) {
+			return pgpErrors.StructuralError("private key checksum failure")
+		}
+	}
+
+	// Parse the decrypted key from an unencrypted key packet
+	tag := byte(packetTagSecretKey)
+	if pk.IsSubkey {
+		tag = packetTagSecretSubkey
+	}
+	length := len(publicBody) + 1 + len(secret) + 2
+	var plaintext bytes.Buffer
+	defer func() { clearMem(plaintext.Bytes()) }()
+	plaintext.Grow(6 + length)
+	_, _ = plaintext.Write([]byte{0xc0 | tag, 0xff, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)})
+	_, _ = plaintext.Write(publicBody)
+	_ = plaintext.WriteByte(0)
+	_, _ = plaintext.Write(secret)
+	sum := secretChecksum(secret)
+	_, _ = plaintext.Write([]byte{byte(sum >> 8), byte(sum)})
+
+	p, err := packet.Read(bytes.NewReader(plaintext.Bytes()))
+	if err != nil {
+		return err
+	}
+	decrypted, ok := p.(*packet.PrivateKey)
+	if !ok {
+		return pgpErrors.StructuralError("unexpected packet in private key data")
+	}
+	*pk = *decrypted
+	return nil
+}
+
+// secretChecksum returns the checksum of the secret parameters of an
+// unencrypted private key packet.
+func secretChecksum(secret []byte) (sum uint16) {
+	for _, b := range secret {
+		sum += uint16(b)
+	}
+	return sum
+}
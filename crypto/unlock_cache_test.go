@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlockWithCache(t *testing.T) {
+	passphrase := []byte("mailbox passphrase")
+	lockedKeys := make([]*Key, 20)
+	for i := range lockedKeys {
+		key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		lockedKeys[i], err = key.Lock(passphrase)
+		if err != nil {
+			t.Fatal("Expected no error while locking key, got:", err)
+		}
+	}
+
+	cache, err := NewUnlockCache(0)
+	if err != nil {
+		t.Fatal("Expected no error while creating the cache, got:", err)
+	}
+
+	for round := 1; round <= 3; round++ {
+		for _, lockedKey := range lockedKeys {
+			unlocked, err := lockedKey.UnlockWithCache(passphrase, cache)
+			if err != nil {
+				t.Fatal("Expected no error while unlocking key, got:", err)
+			}
+			expected, err := lockedKey.Unlock(passphrase)
+			if err != nil {
+				t.Fatal("Expected no error while unlocking key, got:", err)
+			}
+			assert.Equal(t, expected.getPrivateParams(), unlocked.getPrivateParams())
+		}
+		// Each key has a primary key and a subkey, locked with distinct salts
+		assert.Exactly(t, 2*len(lockedKeys), cache.GetMisses())
+		assert.Exactly(t, 2*len(lockedKeys)*(round-1), cache.GetHits())
+	}
+
+	_, err = lockedKeys[0].UnlockWithCache([]byte("wrong passphrase"), cache)
+	assert.Error(t, err)
+
+	cache.Clear()
+	if _, err = lockedKeys[0].UnlockWithCache(passphrase, cache); err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	assert.Exactly(t, 2*len(lockedKeys)+3, cache.GetMisses())
+}
+
+func TestUnlockWithCacheEviction(t *testing.T) {
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring private key, got:", err)
+	}
+
+	cache, err := NewUnlockCache(1)
+	if err != nil {
+		t.Fatal("Expected no error while creating the cache, got:", err)
+	}
+	for i := 0; i < 2; i++ {
+		unlocked, err := lockedKey.UnlockWithCache(testMailboxPassword, cache)
+		if err != nil {
+			t.Fatal("Expected no error while unlocking key, got:", err)
+		}
+		isUnlocked, err := unlocked.IsUnlocked()
+		if err != nil {
+			t.Fatal("Expected no error while checking the key, got:", err)
+		}
+		assert.True(t, isUnlocked)
+
+		keyRing, err := NewKeyRing(unlocked)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		message := NewPlainMessageFromString("signed with a cached unlock")
+		signature, err := keyRing.SignDetached(message)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		assert.NoError(t, keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()))
+	}
+	// The primary key and the subkey evict each other
+	assert.Exactly(t, 0, cache.GetHits())
+	assert.Exactly(t, 4, cache.GetMisses())
+}
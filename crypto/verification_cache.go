@@ -0,0 +1,308 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	goerrors "errors"
+	"io"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+// VerificationCache stores the results of signature verifications, so that
+// the same signature over the same data doesn't have to be verified twice
+// with the same keyring. See KeyRing.SetVerificationCache.
+// Implementations are free to bound the number of entries (e.g. LRU), and
+// must be safe for concurrent use if the keyring is shared.
+type VerificationCache interface {
+	Get(key string) (entry *VerificationCacheEntry, ok bool)
+	Set(key string, entry *VerificationCacheEntry)
+}
+
+// VerificationCacheEntry is the cached result of a signature verification.
+type VerificationCacheEntry struct {
+	// Status is constants.SIGNATURE_OK, or the status of the returned SignatureVerificationError.
+	// The other fields of the error are kept as well, the error returned from
+	// the cache is the one returned by the verification.
+	Status       int
+	Message      string
+	Cause        error
+	SignerKeyID  string
+	CreationTime int64
+	// NotBefore and NotAfter bound the verification times for which the result
+	// holds, according to the signature and signing key creation and expiration
+	// times. Zero means unbounded.
+	NotBefore int64
+	NotAfter  int64
+}
+
+// SetVerificationCache sets the cache used to store the results of the
+// signature verifications done with this keyring, in VerifyDetached and
+// SessionKey.DecryptAndVerify. Negative results are cached as well.
+// A nil cache disables caching.
+func (keyRing *KeyRing) SetVerificationCache(cache VerificationCache) {
+	keyRing.lock.Lock()
+	defer keyRing.lock.Unlock()
+	keyRing.verificationCache = cache
+}
+
+func (keyRing *KeyRing) getVerificationCache() VerificationCache {
+	keyRing.lock.RLock()
+	defer keyRing.lock.RUnlock()
+	return keyRing.verificationCache
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// verifyCached returns the cached result of the verification of the signature
+// packets over data with policy if there is one, otherwise it calls verify and
+// caches the result.
+func (keyRing *KeyRing) verifyCached(
+	signature, data []byte, verifyTime int64, policy signerPolicy, verify func() error,
+) error {
+	verifyTime = ResolveVerifyTime(verifyTime)
+	cache := keyRing.getVerificationCache()
+	if cache == nil {
+		return verify()
+	}
+
+	key := keyRing.getVerificationCacheKey(signature, data, verifyTime, policy)
+	if entry, ok := cache.Get(key); ok && entry != nil && entry.isValidAt(verifyTime) {
+		return entry.getError()
+	}
+
+	err := verify()
+
+	var verificationError *SignatureVerificationError
+	if err != nil {
+		verificationError = &SignatureVerificationError{}
+		if !goerrors.As(err, verificationError) {
+			// Not a verification result, e.g. a parsing error
+			return err
+		}
+	}
+
+	notBefore, notAfter := keyRing.getVerificationWindow(signature, policy)
+	cache.Set(key, newVerificationCacheEntry(verificationError, notBefore, notAfter, verifyTime))
+
+	return err
+}
+
+// getVerificationCacheKey computes the cache key from the digest of the
+// signature packets, the digest of the data, the signer policy and the
+// digests of the keys of the keyring, see getEntityDigest.
+func (keyRing *KeyRing) getVerificationCacheKey(signature, data []byte, verifyTime int64, policy signerPolicy) string {
+	signatureDigest := sha256.Sum256(signature)
+	dataDigest := sha256.Sum256(data)
+
+	h := sha256.New()
+	_, _ = h.Write(signatureDigest[:])
+	_, _ = h.Write(dataDigest[:])
+
+	// verifyTime = 0 disables the time checks, the results are not interchangeable
+	if verifyTime == 0 {
+		_, _ = h.Write([]byte{0})
+	} else {
+		_, _ = h.Write([]byte{1})
+	}
+	// Nor are the results with different signer policies
+	if policy.rejectWeakKeys {
+		_, _ = h.Write([]byte{1})
+	} else {
+		_, _ = h.Write([]byte{0})
+	}
+	var count [4]byte
+	binary.BigEndian.PutUint32(count[:], uint32(len(policy.requiredSigners)))
+	_, _ = h.Write(count[:])
+	for _, fingerprint := range policy.requiredSigners {
+		_, _ = h.Write([]byte{byte(len(fingerprint))})
+		_, _ = h.Write(fingerprint)
+	}
+	// Keep the keys of the results without maximum age stable
+	if policy.maxSignatureAge != 0 {
+		var maxSignatureAge [8]byte
+		binary.BigEndian.PutUint64(maxSignatureAge[:], uint64(policy.maxSignatureAge))
+		_, _ = h.Write(maxSignatureAge[:])
+	}
+	// Nor with the embedded key fallback
+	if policy.allowEmbeddedKey {
+		_, _ = h.Write([]byte{1})
+	}
+
+	// The results depend on the state of the keys, e.g. a revocation or a new
+	// binding signature merged into the keyring, not only on their fingerprints
+	var entityDigests [][]byte
+	for _, entity := range keyRing.getEntities() {
+		entityDigests = append(entityDigests, getEntityDigest(entity))
+	}
+	sort.Slice(entityDigests, func(i, j int) bool {
+		return bytes.Compare(entityDigests[i], entityDigests[j]) < 0
+	})
+	for _, entityDigest := range entityDigests {
+		_, _ = h.Write(entityDigest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getEntityDigest returns the digest of the public keys of entity and of the
+// signatures over them: revocations, user ID certifications and subkey
+// bindings or revocations. The signatures are digested by their hashed data,
+// instead of being serialized, which would modify them.
+func getEntityDigest(entity *openpgp.Entity) []byte {
+	h := sha256.New()
+	writeCount := func(n int) {
+		var count [4]byte
+		binary.BigEndian.PutUint32(count[:], uint32(n))
+		_, _ = h.Write(count[:])
+	}
+	writeField := func(field []byte) {
+		writeCount(len(field))
+		_, _ = h.Write(field)
+	}
+	writeSignature := func(sig *packet.Signature) {
+		if sig == nil {
+			writeField(nil)
+			return
+		}
+		writeField(sig.HashSuffix)
+		writeField(sig.HashTag[:])
+	}
+
+	writeField(entity.PrimaryKey.Fingerprint)
+	writeCount(len(entity.Revocations))
+	for _, revocation := range entity.Revocations {
+		writeSignature(revocation)
+	}
+
+	names := make([]string, 0, len(entity.Identities))
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeCount(len(names))
+	for _, name := range names {
+		identity := entity.Identities[name]
+		writeField([]byte(name))
+		writeSignature(identity.SelfSignature)
+		writeCount(len(identity.Signatures))
+		for _, sig := range identity.Signatures {
+			writeSignature(sig)
+		}
+	}
+
+	writeCount(len(entity.Subkeys))
+	for _, subkey := range entity.Subkeys {
+		writeField(subkey.PublicKey.Fingerprint)
+		writeSignature(subkey.Sig)
+	}
+	return h.Sum(nil)
+}
+
+// getVerificationWindow returns the range of verification times in which the
+// signature packets and the keys that issued them are valid, and young enough
+// for policy. Zero means unbounded.
+func (keyRing *KeyRing) getVerificationWindow(signature []byte, policy signerPolicy) (notBefore, notAfter int64) {
+	lowerBound := func(t int64) {
+		if t > notBefore {
+			notBefore = t
+		}
+	}
+	upperBound := func(t int64) {
+		if notAfter == 0 || t < notAfter {
+			notAfter = t
+		}
+	}
+
+	packets := packet.NewReader(bytes.NewReader(signature))
+	for {
+		p, err := packets.Next()
+		if goerrors.Is(err, io.EOF) || err != nil {
+			break
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok {
+			continue
+		}
+
+		created := sig.CreationTime.Unix()
+		lowerBound(created - internal.CreationTimeOffset)
+		if sig.SigLifetimeSecs != nil && *sig.SigLifetimeSecs != 0 {
+			upperBound(created + int64(*sig.SigLifetimeSecs))
+		}
+		if policy.maxSignatureAge != 0 {
+			upperBound(created + policy.maxSignatureAge)
+		}
+
+		if sig.IssuerKeyId == nil {
+			continue
+		}
+		for _, key := range keyRing.getEntities().KeysById(*sig.IssuerKeyId) {
+			keyCreated := key.PublicKey.CreationTime.Unix()
+			lowerBound(keyCreated - internal.CreationTimeOffset)
+			if key.SelfSignature != nil && key.SelfSignature.KeyLifetimeSecs != nil && *key.SelfSignature.KeyLifetimeSecs != 0 {
+				upperBound(keyCreated + int64(*key.SelfSignature.KeyLifetimeSecs))
+			}
+		}
+	}
+	return notBefore, notAfter
+}
+
+// newVerificationCacheEntry creates a cache entry for a result computed at
+// verifyTime, given the validity window of the signature. A nil
+// verificationError is a successful verification.
+func newVerificationCacheEntry(
+	verificationError *SignatureVerificationError, notBefore, notAfter, verifyTime int64,
+) *VerificationCacheEntry {
+	entry := &VerificationCacheEntry{Status: constants.SIGNATURE_OK}
+	if verificationError != nil {
+		entry.Status = verificationError.Status
+		entry.Message = verificationError.Message
+		entry.Cause = verificationError.Cause
+		entry.SignerKeyID = verificationError.SignerKeyID
+		entry.CreationTime = verificationError.CreationTime
+	}
+
+	switch {
+	case verifyTime == 0:
+		// Time checks are disabled, the result always holds
+	case verifyTime < notBefore:
+		entry.NotAfter = notBefore - 1
+	case notAfter != 0 && verifyTime > notAfter:
+		entry.NotBefore = notAfter + 1
+	default:
+		entry.NotBefore = notBefore
+		entry.NotAfter = notAfter
+	}
+
+	return entry
+}
+
+// isValidAt checks whether the cached result holds at verifyTime.
+func (entry *VerificationCacheEntry) isValidAt(verifyTime int64) bool {
+	if verifyTime == 0 {
+		return true
+	}
+	return (entry.NotBefore == 0 || verifyTime >= entry.NotBefore) &&
+		(entry.NotAfter == 0 || verifyTime <= entry.NotAfter)
+}
+
+// getError returns the cached result as returned by the verification functions.
+func (entry *VerificationCacheEntry) getError() error {
+	if entry.Status == constants.SIGNATURE_OK {
+		return nil
+	}
+	return SignatureVerificationError{
+		Status:       entry.Status,
+		Message:      entry.Message,
+		Cause:        entry.Cause,
+		SignerKeyID:  entry.SignerKeyID,
+		CreationTime: entry.CreationTime,
+	}
+}
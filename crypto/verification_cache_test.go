@@ -0,0 +1,232 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+type testVerificationCache struct {
+	entries map[string]*VerificationCacheEntry
+	hits    int
+}
+
+func newTestVerificationCache() *testVerificationCache {
+	return &testVerificationCache{entries: make(map[string]*VerificationCacheEntry)}
+}
+
+func (c *testVerificationCache) Get(key string) (*VerificationCacheEntry, bool) {
+	entry, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return entry, ok
+}
+
+func (c *testVerificationCache) Set(key string, entry *VerificationCacheEntry) {
+	c.entries[key] = entry
+}
+
+func TestVerifyDetachedCached(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	cache := newTestVerificationCache()
+	verifyKeyRing.SetVerificationCache(cache)
+
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Len(t, cache.entries, 1)
+	assert.Exactly(t, 0, cache.hits)
+
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Exactly(t, 1, cache.hits)
+
+	// Negative results are cached with their status
+	wrongMessage := NewPlainMessageFromString("wrong text")
+	for i := 0; i < 2; i++ {
+		err = verifyKeyRing.VerifyDetached(wrongMessage, signature, GetUnixTime())
+		var verificationError SignatureVerificationError
+		assert.True(t, errors.As(err, &verificationError))
+		assert.Exactly(t, constants.SIGNATURE_FAILED, verificationError.Status)
+	}
+	assert.Len(t, cache.entries, 2)
+	assert.Exactly(t, 2, cache.hits)
+
+	// A different keyring doesn't share the entries
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while creating keyring, got:", err)
+	}
+	otherKeyRing.SetVerificationCache(cache)
+	assert.NotNil(t, otherKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Len(t, cache.entries, 3)
+}
+
+func TestVerifyDetachedCachedKeyState(t *testing.T) {
+	signKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while creating keyring, got:", err)
+	}
+	message := NewPlainMessageFromString(signedPlainText)
+	signature, err := signKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	verifyKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while creating keyring, got:", err)
+	}
+	cache := newTestVerificationCache()
+	verifyKeyRing.SetVerificationCache(cache)
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+
+	// The embedded key fallback is part of the key
+	policy := getDefaultSignerPolicy()
+	embeddedPolicy := policy
+	embeddedPolicy.allowEmbeddedKey = true
+	assert.NotEqual(t,
+		verifyKeyRing.getVerificationCacheKey(signature.GetBinary(), message.GetBinary(), GetUnixTime(), policy),
+		verifyKeyRing.getVerificationCacheKey(signature.GetBinary(), message.GetBinary(), GetUnixTime(), embeddedPolicy),
+	)
+
+	// Merging the revocation of the key into the keyring invalidates the result
+	revokedKey, err := keyTestRSA.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	if err = revokedKey.entity.RevokeKey(packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+	// Unlike ToPublic, keeps the revocation
+	serialized, err := revokedKey.GetPublicKeyMinimal()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	revokedPublicKey, err := NewKey(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	if err = verifyKeyRing.AddKey(revokedPublicKey); err != nil {
+		t.Fatal("Expected no error while merging key, got:", err)
+	}
+	assert.Len(t, verifyKeyRing.GetKeys(), 1)
+
+	err = verifyKeyRing.VerifyDetached(message, signature, GetUnixTime())
+	assert.Exactly(t, 0, cache.hits)
+	assert.Len(t, cache.entries, 2)
+	var verificationError SignatureVerificationError
+	assert.True(t, errors.As(err, &verificationError))
+	assert.NotEqual(t, constants.SIGNATURE_OK, verificationError.Status)
+}
+
+func TestDecryptAndVerifyCached(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+	dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	cache := newTestVerificationCache()
+	verifyKeyRing.SetVerificationCache(cache)
+
+	for i := 0; i < 2; i++ {
+		decrypted, err := testSessionKey.DecryptAndVerify(dataPacket, verifyKeyRing, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+	assert.Len(t, cache.entries, 1)
+	assert.Exactly(t, 1, cache.hits)
+
+	unsignedPacket, err := testSessionKey.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = testSessionKey.DecryptAndVerify(unsignedPacket, verifyKeyRing, GetUnixTime())
+	var verificationError SignatureVerificationError
+	assert.True(t, errors.As(err, &verificationError))
+	assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, verificationError.Status)
+}
+
+func TestDecryptAndVerifyCachedResults(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+	dataPacket, err := testSessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	otherKeyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	tests := []struct {
+		name       string
+		keyRing    *KeyRing
+		verifyTime int64
+	}{
+		{"valid", keyRingTestPublic, GetUnixTime()},
+		{"no verifier", otherKeyRing, GetUnixTime()},
+		{"not yet valid", keyRingTestPublic, 1},
+		{"time check disabled", keyRingTestPublic, 0},
+	}
+	for _, test := range tests {
+		_, expected := testSessionKey.DecryptAndVerify(dataPacket, test.keyRing, test.verifyTime)
+
+		verifyKeyRing, err := test.keyRing.Copy()
+		if err != nil {
+			t.Fatal("Expected no error while copying keyring, got:", err)
+		}
+		cache := newTestVerificationCache()
+		verifyKeyRing.SetVerificationCache(cache)
+		for i := 0; i < 2; i++ {
+			decrypted, err := testSessionKey.DecryptAndVerify(dataPacket, verifyKeyRing, test.verifyTime)
+			assert.Exactly(t, expected, err, test.name)
+			assert.Exactly(t, message.GetString(), decrypted.GetString(), test.name)
+		}
+		assert.Exactly(t, 1, cache.hits, test.name)
+	}
+}
+
+func TestVerificationCacheEntryWindow(t *testing.T) {
+	failed := newSignatureFailed()
+	entry := newVerificationCacheEntry(nil, 100, 200, 150)
+	assert.True(t, entry.isValidAt(100))
+	assert.True(t, entry.isValidAt(200))
+	assert.False(t, entry.isValidAt(99))
+	assert.False(t, entry.isValidAt(201))
+
+	expired := newVerificationCacheEntry(&failed, 100, 200, 250)
+	assert.True(t, expired.isValidAt(201))
+	assert.False(t, expired.isValidAt(200))
+
+	notYetValid := newVerificationCacheEntry(&failed, 100, 200, 50)
+	assert.True(t, notYetValid.isValidAt(99))
+	assert.False(t, notYetValid.isValidAt(100))
+
+	unbounded := newVerificationCacheEntry(nil, 100, 0, 150)
+	assert.True(t, unbounded.isValidAt(1<<40))
+}
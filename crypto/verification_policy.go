@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"bytes"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// VerificationPolicy specifies which keys must have signed a message.
+type VerificationPolicy struct {
+	// RequiredSigners are the hex fingerprints of the primary keys whose
+	// signatures are counted. If empty, all the keys of the keyring are counted.
+	RequiredSigners []string
+	// Threshold is the number of distinct signers required.
+	// If 0, all the required signers must have signed.
+	Threshold int
+	// VerifyOptions are the checks of each signature, see VerifyOptions.
+	VerifyOptions *VerifyOptions
+}
+
+// PolicyVerificationError is returned by VerifyDetachedWithPolicy when less
+// than the required number of distinct keys have validly signed the message.
+type PolicyVerificationError struct {
+	Threshold int
+	// Fingerprints of the signers with a valid signature
+	Verified []string
+	// Fingerprints of the required signers without a valid signature
+	Missing []string
+}
+
+// Error is the base method for all errors.
+func (e PolicyVerificationError) Error() string {
+	return fmt.Sprintf(
+		"Signature Verification Error: %d of %d required signatures, missing signers: %s",
+		len(e.Verified), e.Threshold, strings.Join(e.Missing, ", "),
+	)
+}
+
+// VerifyDetachedWithPolicy verifies a PlainMessage with detached signatures,
+// which may contain several signature packets, and checks that the keys of
+// keyRing which validly signed satisfy the policy.
+// Several signatures from the same key are counted once.
+// Returns a PolicyVerificationError if the policy is not satisfied.
+func VerifyDetachedWithPolicy(
+	message *PlainMessage, signatures *PGPSignature, keyRing *KeyRing, policy VerificationPolicy, verifyTime int64,
+) error {
+	if keyRing == nil {
+		return errors.New("gopenpgp: no verification key ring provided")
+	}
+
+	candidates, err := policy.getCandidates(keyRing)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return errors.New("gopenpgp: no signer available in the key ring")
+	}
+	threshold := policy.Threshold
+	if threshold == 0 {
+		threshold = len(candidates)
+	}
+	if threshold < 0 || threshold > len(candidates) {
+		return errors.Errorf(
+			"gopenpgp: threshold of %d signers is unsatisfiable with %d available keys",
+			policy.Threshold, len(candidates),
+		)
+	}
+
+	signerPolicy, err := policy.VerifyOptions.getSignerPolicy()
+	if err != nil {
+		return err
+	}
+	signers, err := getDetachedSigners(message, signatures, keyRing, verifyTime, signerPolicy)
+	if err != nil {
+		return err
+	}
+
+	var verified, missing []string
+	for _, fingerprint := range candidates {
+		if signers[fingerprint] {
+			verified = append(verified, fingerprint)
+		} else {
+			missing = append(missing, fingerprint)
+		}
+	}
+	if len(verified) < threshold {
+		return PolicyVerificationError{
+			Threshold: threshold,
+			Verified:  verified,
+			Missing:   missing,
+		}
+	}
+	return nil
+}
+
+// getCandidates returns the fingerprints of the keys of keyRing which count
+// towards the threshold.
+func (policy VerificationPolicy) getCandidates(keyRing *KeyRing) ([]string, error) {
+	var available []string
+	isAvailable := make(map[string]bool)
+	for _, key := range keyRing.GetKeys() {
+		available = append(available, key.GetFingerprint())
+		isAvailable[key.GetFingerprint()] = true
+	}
+	if len(policy.RequiredSigners) == 0 {
+		return available, nil
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, fingerprint := range policy.RequiredSigners {
+		fingerprint = strings.ToLower(fingerprint)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		if !isAvailable[fingerprint] {
+			return nil, errors.Errorf("gopenpgp: required signer %s is not in the key ring", fingerprint)
+		}
+		candidates = append(candidates, fingerprint)
+	}
+	return candidates, nil
+}
+
+// getDetachedSigners verifies each signature packet separately and returns
+// the set of the primary key fingerprints with a valid signature accepted by
+// signerPolicy.
+func getDetachedSigners(
+	message *PlainMessage, signatures *PGPSignature, keyRing *KeyRing, verifyTime int64, signerPolicy signerPolicy,
+) (map[string]bool, error) {
+	signers := make(map[string]bool)
+	packets := packet.NewReader(bytes.NewReader(signatures.GetBinary()))
+	for {
+		p, err := packets.Next()
+		if goerrors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in reading signature packets")
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.IssuerKeyId == nil {
+			continue
+		}
+		keys := keyRing.getEntities().KeysById(*sig.IssuerKeyId)
+		if len(keys) == 0 {
+			continue
+		}
+
+		var serialized bytes.Buffer
+		if err := sig.Serialize(&serialized); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in serializing signature")
+		}
+		// Several keys of the keyring may share the issuer key ID: try each
+		for _, key := range keys {
+			fingerprint := (&Key{entity: key.Entity}).GetFingerprint()
+			if signers[fingerprint] {
+				continue
+			}
+			signer := openpgp.EntityList{key.Entity}
+			if verifySignature(signer, message.NewReader(), serialized.Bytes(), verifyTime, signerPolicy) == nil {
+				signers[fingerprint] = true
+			}
+		}
+	}
+	return signers, nil
+}
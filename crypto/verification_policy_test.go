@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyDetachedWithPolicy(t *testing.T) {
+	message := NewPlainMessageFromString(signedPlainText)
+
+	rsaKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	verifyKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, key := range []*Key{keyTestEC, keyRingTestPublic.GetKeys()[0]} {
+		if err = verifyKeyRing.AddKey(key); err != nil {
+			t.Fatal("Expected no error while adding key, got:", err)
+		}
+	}
+
+	var signatures []byte
+	for _, signKeyRing := range []*KeyRing{rsaKeyRing, rsaKeyRing, ecKeyRing} {
+		signature, err := signKeyRing.SignDetached(message)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		signatures = append(signatures, signature.GetBinary()...)
+	}
+	signature := NewPGPSignature(signatures)
+
+	policy := VerificationPolicy{Threshold: 2}
+	assert.NoError(t, VerifyDetachedWithPolicy(message, signature, verifyKeyRing, policy, GetUnixTime()))
+
+	policy = VerificationPolicy{
+		RequiredSigners: []string{keyTestRSA.GetFingerprint(), keyTestEC.GetFingerprint()},
+	}
+	assert.NoError(t, VerifyDetachedWithPolicy(message, signature, verifyKeyRing, policy, GetUnixTime()))
+
+	// The duplicate signature by the RSA key is counted once
+	policy = VerificationPolicy{Threshold: 3}
+	err = VerifyDetachedWithPolicy(message, signature, verifyKeyRing, policy, GetUnixTime())
+	var policyError PolicyVerificationError
+	if !errors.As(err, &policyError) {
+		t.Fatal("Expected a PolicyVerificationError, got:", err)
+	}
+	assert.Len(t, policyError.Verified, 2)
+	assert.Exactly(t, []string{keyRingTestPublic.GetKeys()[0].GetFingerprint()}, policyError.Missing)
+
+	err = VerifyDetachedWithPolicy(NewPlainMessageFromString("wrong"), signature, verifyKeyRing, VerificationPolicy{Threshold: 1}, GetUnixTime())
+	assert.True(t, errors.As(err, &policyError))
+	assert.Len(t, policyError.Verified, 0)
+
+	// Unsatisfiable policies fail upfront
+	err = VerifyDetachedWithPolicy(message, signature, verifyKeyRing, VerificationPolicy{Threshold: 4}, GetUnixTime())
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &policyError))
+	policy = VerificationPolicy{RequiredSigners: []string{keyTestRSA.GetFingerprint()}, Threshold: 2}
+	assert.Error(t, VerifyDetachedWithPolicy(message, signature, verifyKeyRing, policy, GetUnixTime()))
+
+	// An empty keyring can't satisfy any policy
+	emptyKeyRing, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	err = VerifyDetachedWithPolicy(message, signature, emptyKeyRing, VerificationPolicy{}, GetUnixTime())
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &policyError))
+}
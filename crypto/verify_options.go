@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"github.com/pkg/errors"
+)
+
+// VerifyOptions holds the checks of a single verification, see
+// KeyRing.VerifyDetachedWithOptions, KeyRing.DecryptWithOptions,
+// SessionKey.DecryptAndVerifyWithOptions, ClearTextMessage.VerifyOnlyWithOptions
+// and VerificationPolicy. They only apply to that call, so that a keyring
+// shared between goroutines can be used with different options.
+// A nil *VerifyOptions follows the defaults.
+type VerifyOptions struct {
+	// WeakSigningKeyPolicy is one of the constants.WEAK_SIGNING_KEYS_*
+	// policies for the signatures made by weak keys. The default follows
+	// SetRejectWeakSigningKeys.
+	WeakSigningKeyPolicy int
+	// RequiredSigners restricts the accepted signers to the keys with the
+	// given hex fingerprints. A fingerprint matches the key which made the
+	// signature, or its primary key, so that either the fingerprint of a
+	// signing subkey or of the primary key can be required. The signatures
+	// made by the other keys of the keyring fail with the
+	// constants.SIGNATURE_WRONG_SIGNER status, once otherwise valid.
+	// If empty, all the keys are accepted.
+	RequiredSigners []string
+	// MaxSignatureAge restricts the accepted signatures to those created at
+	// most MaxSignatureAge seconds before the verification time, e.g. for
+	// short-lived attestation tokens. The older signatures fail with the
+	// constants.SIGNATURE_TOO_OLD status, once otherwise valid. It only bounds
+	// the creation time in the past: a signature created after the
+	// verification time is still accepted within the clock skew margin, and
+	// the age isn't checked if the time checks are disabled with
+	// constants.VERIFY_TIME_DISABLED. It applies to the detached and
+	// cleartext signatures, not to the signatures embedded in messages.
+	// If 0, the age isn't restricted.
+	MaxSignatureAge int64
+	// AllowEmbeddedKeyVerification sets whether KeyRing.DecryptWithOptions
+	// falls back to the public key embedded in the message if no key of the
+	// verification keyring made its signature, i.e. the verification fails
+	// with the constants.SIGNATURE_NO_VERIFIER status.
+	// The key is embedded in an armored PGP PUBLIC KEY BLOCK following the
+	// armored message, see NewPGPMessageFromArmored, or as key packets
+	// following the packets of the message. If the signature verifies with
+	// it, the message is returned with a SignatureVerificationError with the
+	// constants.SIGNATURE_VERIFIED_WITH_EMBEDDED_KEY status, never as
+	// verified, and the key is returned by
+	// PlainMessage.GetEmbeddedVerificationKey, so that the caller can decide
+	// to trust it on first use.
+	AllowEmbeddedKeyVerification bool
+}
+
+// getSignerPolicy returns the checks of the signers applied by a verification
+// with the options. Nil options follow the defaults.
+func (options *VerifyOptions) getSignerPolicy() (signerPolicy, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+	rejectWeakKeys, err := rejectsWeakSigningKeys(options.WeakSigningKeyPolicy)
+	if err != nil {
+		return signerPolicy{}, err
+	}
+	requiredSigners, err := parseRequiredSigners(options.RequiredSigners)
+	if err != nil {
+		return signerPolicy{}, err
+	}
+	if options.MaxSignatureAge < 0 {
+		return signerPolicy{}, errors.New("gopenpgp: negative maximum signature age")
+	}
+	return signerPolicy{
+		rejectWeakKeys:   rejectWeakKeys,
+		requiredSigners:  requiredSigners,
+		maxSignatureAge:  options.MaxSignatureAge,
+		allowEmbeddedKey: options.AllowEmbeddedKeyVerification,
+	}, nil
+}
+
+// getDefaultSignerPolicy returns the checks of the signers applied by the
+// verifications which don't take options.
+func getDefaultSignerPolicy() signerPolicy {
+	// Nil options are always valid
+	policy, _ := (*VerifyOptions)(nil).getSignerPolicy()
+	return policy
+}
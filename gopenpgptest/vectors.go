@@ -0,0 +1,165 @@
+package gopenpgptest
+
+import (
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+)
+
+// MessageVector is a message encrypted to the test keys.
+type MessageVector struct {
+	Name string
+	// Armored message
+	Message   string
+	Plaintext string
+	// Whether the message is signed by one of the test keys, and the time at
+	// which the signature can be verified
+	Signed     bool
+	VerifyTime int64
+}
+
+// SignatureVector is a detached signature made by one of the test keys.
+type SignatureVector struct {
+	Name string
+	Data []byte
+	// Whether it is a text signature, to verify against the canonicalized data
+	Text bool
+	// Armored signature
+	Signature  string
+	VerifyTime int64
+}
+
+// SessionKeyVector is a message encrypted with a password, and its session key.
+type SessionKeyVector struct {
+	Name string
+	// Base64 encoded password encrypted session key packet and data packet
+	KeyPacket  string
+	DataPacket string
+	Password   []byte
+	Algo       string
+	Key        []byte
+	Plaintext  string
+}
+
+// 2018-06-01T00:00:00Z
+const vectorTime = 1527811200
+
+// MessageVectors returns the message vectors, decrypted by the keyring of
+// GenerateTestKeyRing and verified by GenerateTestPublicKeyRing.
+func MessageVectors() []*MessageVector {
+	return []*MessageVector{
+		{
+			Name: "rsa-signed-sha256",
+			Message: `-----BEGIN PGP MESSAGE-----
+Version: OpenPGP.js v4.10.4
+Comment: https://openpgpjs.org
+
+wcBMA0fcZ7XLgmf2AQf/adxZ10qXLL0qVcYq7iDTpGNEKbR8UWcWqN6S6GnG
+XJwVretWIGLjJiPdh7irZcG993rCJr43P6uZg8AD/+7G/ceVFSRw2+dYHZmp
+bq3e89py+zGO/m3o+41RF+3ekH3LHZsb/x4Ob+ZZM3UqOX9cvFiKKN1MjyH2
+JkHWhQLtZVxDKwa77QBvs5fu8kzEdVFjA1RQhRbQpkuTMkfOEfcsuqHrWJrG
+T2eliNU5VzIBxfojLOSAF1ueMP3QMrB/vMimEnO140MJHf4mwj92+a9BxyXs
+UAbgKC79BsrF5CsND7+ANsoTJTv2eDxf6bVEfCk8HwT5X/C4hOgJ9ILOw8K2
+Y9LBeQH9RMVQej1Az9glJY7S3FlTL9IUndFPD5Mb/+zNjNv6Mg5UquxmeZZx
+S8RJDjAOTC2Rcv9jlELh6leS3meY+lpXar/ao+FL/1eB8zKkGsZqstfhmA14
+S5I5R4EgLmjkt+Ibg3Kj2sswWchB9eDdfvW/VwrhXU45Hv+EeKS/Pn+5qf05
+E5j7PQ3+EtdMTLIT3cSJe1a7zHlLR49hAzJ3Z5wPSmIzFYNE7RvnRA/7MMv/
+amlHAdIjDejDFIHR4LsT5e0GyMcAaDAOyGZRxM9fPo7PmZhz7mvoY4MGaBZ/
+icrv91IunxWo7QjLzubx4lVJM6xi2375Mntk4ZI6JNTblodOXEchTCmzNQxE
+jJkzLnlMU8/gTNLJh43pgy2F8+QVYjqZT61mnScBIUidQVNeqSOiHyLCsAgS
+Q2XAzIgf7HO+UBqHfrDG9wtNtUKVRrGhMwNiVXIO8VvdrKsYrWdmCgvKXZn8
+cdS9991BJQSavQRjf6rZRit+4iBzx3elYOtdN6zu3JrF1Mjm7pgDfij8Z1o1
+2hpUDNWPCa1idijPp9wvy03lXuVhB1U+rFb+ASslYoXyOASiZuKGAyxXaJwR
+5VOY1EKExsSzh74w2bvtFyO/BjF5OFqtL2j3CEu3CWPiYlFPZzF+/jf8v/QQ
+jfB1gs9u/Yz9ao9BU00eooLud3cS5mr8jeO/ugKh3rAqQEShsmoillyuNWlr
+Dusky1Y9txR4llJOdcdIyhMhnUdMd8yVF4FfhlGgVirY
+=oTYf
+-----END PGP MESSAGE-----`,
+			Plaintext:  `<div>jeej saas<br></div><div><br></div><div class="protonmail_signature_block"><div>Sent from <a href="https://protonmail.ch">ProtonMail</a>, encrypted email based in Switzerland.<br></div><div><br></div></div>`,
+			Signed:     true,
+			VerifyTime: 0,
+		},
+		{
+			Name: "ed25519-signed",
+			Message: `-----BEGIN PGP MESSAGE-----
+
+hF4DTAEc66FII24SAQdA7nmUDB0n6A4TEXXoLQoHnkkv4AXWUolh9wSzhuSs7Wsw
+SrW3S/CuljcsO2Le2IabuTxZZstwdZhfyQT2VnnjcAZ8q5hKU93H7DUFlopU+G/Y
+0sAkATu/6qoJqnI5txaK6B5Y6dMraDOd+PmHI6geDKvqsfeXrvs4RMHZ+702aQuH
+fqFCwT1FTJZyUNwgxYMXQ/7VMGf0tRCeVTSJpdsaGpkkCm+u4XPC08FqMJZfN+Be
+Y8N2Ek0g2YIMC2HMP5lP4GxYS9dMLWSnVESz32CLVgiy0FIDtnUfSVv3rG9fWLYD
+YoimLPYnIQWrcSbtRQLOO0EF/9Nkdt9lcg600zR+n4xnpi7VXvOXnGlpJFFYuj+z
+n0fIQ8YYHLc4Gi3qc58qICvXYBfPFKdJDURxvL9YiceGQ2kIp3zT
+=dQSR
+-----END PGP MESSAGE-----`,
+			Plaintext:  "Hello from the known-answer vectors!\n",
+			Signed:     true,
+			VerifyTime: vectorTime,
+		},
+	}
+}
+
+// SignatureVectors returns the detached signature vectors, verified by
+// GenerateTestPublicKeyRing.
+func SignatureVectors() []*SignatureVector {
+	return []*SignatureVector{
+		{
+			Name: "ed25519-binary",
+			Data: []byte("binary data\x00\x01\x02"),
+			Signature: `-----BEGIN PGP SIGNATURE-----
+
+iHUEABYIAB0WIQQBWALlZ8plqE+YwizvKThMhq/KSwUCWxCMgAAKCRDvKThMhq/K
+S9kiAP4gHx+irAAzphYjc6NT0gJE2g8F52xwPorWYw+VTCyOYwD/Q5Cx4BpXovXk
++VwEE6fTqgU6NRTt7VyxdXJRaaH5rwA=
+=gzLN
+-----END PGP SIGNATURE-----`,
+			VerifyTime: vectorTime,
+		},
+		{
+			// Signed with LF line endings, verified against CRLF
+			Name: "ed25519-text-canonicalization",
+			Data: []byte("first line\nsecond line\n"),
+			Text: true,
+			Signature: `-----BEGIN PGP SIGNATURE-----
+
+iHUEARYIAB0WIQQBWALlZ8plqE+YwizvKThMhq/KSwUCWxCMgAAKCRDvKThMhq/K
+S2V2AP9kLamG+B3oqYo0ZtJqscjbM7w6QWqxL0Z/P8jAX7TGqQEAqTL6Tw6XJVeO
+hj0dKcXRY8BFqqFG8vTsVx3XIC5JNQw=
+=ZEFt
+-----END PGP SIGNATURE-----`,
+			VerifyTime: vectorTime,
+		},
+		{
+			// Created 36 hours after the verification time, within the
+			// tolerated clock skew
+			Name: "ed25519-clock-skew",
+			Data: []byte("binary data\x00\x01\x02"),
+			Signature: `-----BEGIN PGP SIGNATURE-----
+
+iHUEABYIAB0WIQQBWALlZ8plqE+YwizvKThMhq/KSwUCWxKGwAAKCRDvKThMhq/K
+S2RwAQCQvRQMkx4Nt32caG1erY3Xl0Zgw4gAL1d5w1q6OUG6hQEA7iFsqJqOmjgD
+g+lWG9pju90ZqRV4gERzGqNTJjCUsQQ=
+=obCg
+-----END PGP SIGNATURE-----`,
+			VerifyTime: vectorTime,
+		},
+	}
+}
+
+// SessionKeyVectors returns the session key vectors.
+func SessionKeyVectors() []*SessionKeyVector {
+	return []*SessionKeyVector{
+		{
+			Name:       "aes256-password",
+			KeyPacket:  "jA0ECQMCBlXpOhUpXkr/",
+			DataPacket: "0mEBnkLeTHx/7oOBmMc6TDl6VyoYoXMCKn3vEpOAOf5ilwt0+vNIObGLieJwhyp2VYAhajWlRnT8S0rTfyu+jBC1u9ctg/FrgslwZVDp9drrAFqQcS597j8cQ92CRGXhH4wd",
+			Password:   []byte("vector password"),
+			Algo:       constants.AES256,
+			Key: []byte{
+				0xed, 0x1b, 0xf0, 0xb6, 0x1f, 0x1e, 0xdd, 0x66,
+				0xc3, 0xc7, 0x04, 0xf7, 0xf2, 0xd8, 0x8d, 0x32,
+				0xa6, 0x24, 0x1b, 0x7d, 0x50, 0x13, 0xab, 0x85,
+				0x40, 0x7a, 0x98, 0x0b, 0xb2, 0xe7, 0x41, 0x76,
+			},
+			Plaintext: "Symmetrically encrypted known answer\n",
+		},
+	}
+}
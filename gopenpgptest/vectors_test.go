@@ -0,0 +1,80 @@
+package gopenpgptest
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestMessageVectors(t *testing.T) {
+	privateKeyRing := GenerateTestKeyRing(t)
+	publicKeyRing := GenerateTestPublicKeyRing(t)
+	assert.Exactly(t, 2, privateKeyRing.CountEntities())
+
+	for _, vector := range MessageVectors() {
+		message, err := crypto.NewPGPMessageFromArmored(vector.Message)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring message "+vector.Name+", got:", err)
+		}
+		var verifyKeyRing *crypto.KeyRing
+		if vector.Signed {
+			verifyKeyRing = publicKeyRing
+		}
+		decrypted, err := privateKeyRing.Decrypt(message, verifyKeyRing, vector.VerifyTime)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting message "+vector.Name+", got:", err)
+		}
+		assert.Exactly(t, vector.Plaintext, decrypted.GetString(), vector.Name)
+	}
+}
+
+func TestSignatureVectors(t *testing.T) {
+	publicKeyRing := GenerateTestPublicKeyRing(t)
+
+	for _, vector := range SignatureVectors() {
+		signature, err := crypto.NewPGPSignatureFromArmored(vector.Signature)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring signature "+vector.Name+", got:", err)
+		}
+		message := crypto.NewPlainMessage(vector.Data)
+		if vector.Text {
+			message = crypto.NewPlainMessageFromString(string(vector.Data))
+		}
+		if err = publicKeyRing.VerifyDetached(message, signature, vector.VerifyTime); err != nil {
+			t.Fatal("Expected no error while verifying signature "+vector.Name+", got:", err)
+		}
+	}
+}
+
+func TestSessionKeyVectors(t *testing.T) {
+	for _, vector := range SessionKeyVectors() {
+		keyPacket, err := base64.StdEncoding.DecodeString(vector.KeyPacket)
+		if err != nil {
+			t.Fatal("Expected no error while decoding key packet "+vector.Name+", got:", err)
+		}
+		dataPacket, err := base64.StdEncoding.DecodeString(vector.DataPacket)
+		if err != nil {
+			t.Fatal("Expected no error while decoding data packet "+vector.Name+", got:", err)
+		}
+
+		sessionKey, err := crypto.DecryptSessionKeyWithPassword(keyPacket, vector.Password)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting session key "+vector.Name+", got:", err)
+		}
+		assert.Exactly(t, vector.Algo, sessionKey.Algo, vector.Name)
+		assert.Exactly(t, vector.Key, sessionKey.Key, vector.Name)
+
+		decrypted, err := crypto.NewSessionKeyFromToken(vector.Key, vector.Algo).Decrypt(dataPacket)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting message "+vector.Name+", got:", err)
+		}
+		assert.Exactly(t, vector.Plaintext, decrypted.GetString(), vector.Name)
+	}
+}
+
+func TestVectorsAreCopies(t *testing.T) {
+	MessageVectors()[0].Plaintext = "modified"
+	assert.NotEqual(t, "modified", MessageVectors()[0].Plaintext)
+}
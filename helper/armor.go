@@ -0,0 +1,23 @@
+package helper
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+// SanitizeArmor repairs an armored key or message copy-pasted from rich text,
+// e.g. HTML mail, see armor.SanitizeArmor, and fixes its framing variants,
+// see armor.GetFramingIssues.
+// Returns an error listing the repairs if it still can't be unarmored.
+func SanitizeArmor(input string) (string, error) {
+	sanitized, fixes := armor.SanitizeArmor(input)
+	normalized, issues := internal.NormalizeArmor(sanitized)
+	if _, _, err := armor.UnarmorLenient(normalized); err != nil {
+		fixes = append(fixes, issues...)
+		return "", errors.Wrap(err, "gopenpgp: unable to sanitize armor, repaired: "+strings.Join(fixes, ", "))
+	}
+	return normalized, nil
+}
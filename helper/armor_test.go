@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestSanitizeArmor(t *testing.T) {
+	expected, err := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when parsing key, got:", err)
+	}
+	for _, file := range []string{"armor_mangled_nbsp", "armor_mangled_wrapped", "armor_mangled_indented"} {
+		sanitized, err := SanitizeArmor(readTestFile(file, false))
+		if err != nil {
+			t.Fatal("Expected no error when sanitizing "+file+", got:", err)
+		}
+		key, err := crypto.NewKeyFromArmored(sanitized)
+		if err != nil {
+			t.Fatal("Expected no error when parsing sanitized "+file+", got:", err)
+		}
+		assert.Exactly(t, expected.GetFingerprint(), key.GetFingerprint(), file)
+	}
+
+	// Corrupted data can't be repaired
+	corrupted := strings.Replace(readTestFile("armor_mangled_nbsp", false), "xsBNBF", "xs!NBF", 1)
+	_, err = SanitizeArmor(corrupted)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "non-ASCII spaces")
+}
@@ -24,5 +24,7 @@ func readTestFile(name string, trimNewlines bool) string {
 var testMailboxPassword = []byte("apple")
 
 func init() {
+	// The fixtures are checked at testTime
+	crypto.SetTimeFrozen(true)
 	crypto.UpdateTime(testTime) // 2019-05-13T13:37:07+00:00
 }
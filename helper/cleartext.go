@@ -69,7 +69,7 @@ func VerifyCleartextMessage(keyRing *crypto.KeyRing, armored string, verifyTime
 
 	message := crypto.NewPlainMessageFromString(clearTextMessage.GetString())
 	signature := crypto.NewPGPSignature(clearTextMessage.GetBinarySignature())
-	err = keyRing.VerifyDetached(message, signature, verifyTime)
+	err = keyRing.VerifyDetached(message, signature, crypto.ResolveVerifyTime(verifyTime))
 	if err != nil {
 		return "", errors.Wrap(err, "gopengpp: unable to verify cleartext message")
 	}
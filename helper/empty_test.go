@@ -0,0 +1,92 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestEmptyMessageHelpers(t *testing.T) {
+	publicKey := readTestFile("keyring_publicKey", false)
+	privateKey := readTestFile("keyring_privateKey", false)
+
+	armored, err := EncryptMessageWithPassword(testMailboxPassword, "")
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with password, got:", err)
+	}
+	plaintext, err := DecryptMessageWithPassword(testMailboxPassword, armored)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with password, got:", err)
+	}
+	assert.Exactly(t, "", plaintext)
+
+	armored, err = EncryptSignMessageArmored(publicKey, privateKey, testMailboxPassword, "")
+	if err != nil {
+		t.Fatal("Expected no error while encrypting and signing, got:", err)
+	}
+	plaintext, err = DecryptVerifyMessageArmored(publicKey, privateKey, testMailboxPassword, armored)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	assert.Exactly(t, "", plaintext)
+
+	armored, err = EncryptBinaryMessageArmored(publicKey, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting binary, got:", err)
+	}
+	data, err := DecryptBinaryMessageArmored(privateKey, testMailboxPassword, armored)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting binary, got:", err)
+	}
+	assert.Empty(t, data)
+
+	ciphertext, encryptedSignature, err := EncryptSignArmoredDetached(publicKey, privateKey, testMailboxPassword, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with detached signature, got:", err)
+	}
+	data, err = DecryptVerifyArmoredDetached(publicKey, privateKey, testMailboxPassword, ciphertext, encryptedSignature)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with detached signature, got:", err)
+	}
+	assert.Empty(t, data)
+
+	keyPacket, dataPacket, signature, err := EncryptSignAttachment(publicKey, privateKey, testMailboxPassword, "", nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting attachment, got:", err)
+	}
+	armoredSignature, err := crypto.NewPGPSignature(signature).GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring signature, got:", err)
+	}
+	data, err = DecryptVerifyAttachment(publicKey, privateKey, testMailboxPassword, keyPacket, dataPacket, armoredSignature)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting attachment, got:", err)
+	}
+	assert.Empty(t, data)
+
+	cleartext, err := SignCleartextMessageArmored(privateKey, testMailboxPassword, "")
+	if err != nil {
+		t.Fatal("Expected no error while signing cleartext, got:", err)
+	}
+	plaintext, err = VerifyCleartextMessageArmored(publicKey, cleartext, constants.VERIFY_TIME_NOW)
+	if err != nil {
+		t.Fatal("Expected no error while verifying cleartext, got:", err)
+	}
+	assert.Exactly(t, "", plaintext)
+
+	handle, err := NewEncryptSignHandle(publicKey, privateKey, testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	armored, err = handle.Finish()
+	if err != nil {
+		t.Fatal("Expected no error while finishing encryption, got:", err)
+	}
+	plaintext, err = DecryptVerifyMessageArmored(publicKey, privateKey, testMailboxPassword, armored)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	assert.Exactly(t, "", plaintext)
+}
@@ -1,9 +1,18 @@
 // Package helper contains several functions with a simple interface to extend usability and compatibility with gomobile
+//
+// The verification times of the helper functions can be
+// constants.VERIFY_TIME_NOW, to verify at the time of the time provider, see
+// crypto.SetTimeProvider, or constants.VERIFY_TIME_DISABLED.
 package helper
 
 import (
+	"io/ioutil"
+
 	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 	"github.com/yougroupteam/gopenpgp/v2/crypto"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
 )
 
 // EncryptMessageWithPassword encrypts a string with a passphrase using AES256.
@@ -103,6 +112,14 @@ func DecryptMessageArmored(
 // plain data or an error on signature verification failure.
 func DecryptVerifyMessageArmored(
 	publicKey, privateKey string, passphrase []byte, ciphertext string,
+) (plaintext string, err error) {
+	return DecryptVerifyMessageArmoredWithTime(publicKey, privateKey, passphrase, ciphertext, constants.VERIFY_TIME_NOW)
+}
+
+// DecryptVerifyMessageArmoredWithTime is DecryptVerifyMessageArmored,
+// verifying the signature at verifyTime.
+func DecryptVerifyMessageArmoredWithTime(
+	publicKey, privateKey string, passphrase []byte, ciphertext string, verifyTime int64,
 ) (plaintext string, err error) {
 	var privateKeyObj, unlockedKeyObj *crypto.Key
 	var publicKeyRing, privateKeyRing *crypto.KeyRing
@@ -130,7 +147,7 @@ func DecryptVerifyMessageArmored(
 		return "", errors.Wrap(err, "gopenpgp: unable to unarmor ciphertext")
 	}
 
-	if message, err = privateKeyRing.Decrypt(pgpMessage, publicKeyRing, crypto.GetUnixTime()); err != nil {
+	if message, err = privateKeyRing.Decrypt(pgpMessage, publicKeyRing, crypto.ResolveVerifyTime(verifyTime)); err != nil {
 		return "", errors.Wrap(err, "gopenpgp: unable to decrypt message")
 	}
 
@@ -145,6 +162,19 @@ func DecryptVerifyAttachment(
 	publicKey, privateKey string,
 	passphrase, keyPacket, dataPacket []byte,
 	armoredSignature string,
+) (plainData []byte, err error) {
+	return DecryptVerifyAttachmentWithTime(
+		publicKey, privateKey, passphrase, keyPacket, dataPacket, armoredSignature, constants.VERIFY_TIME_NOW,
+	)
+}
+
+// DecryptVerifyAttachmentWithTime is DecryptVerifyAttachment, verifying the
+// signature at verifyTime.
+func DecryptVerifyAttachmentWithTime(
+	publicKey, privateKey string,
+	passphrase, keyPacket, dataPacket []byte,
+	armoredSignature string,
+	verifyTime int64,
 ) (plainData []byte, err error) {
 	// We decrypt the attachment
 	message, err := decryptAttachment(privateKey, passphrase, keyPacket, dataPacket)
@@ -154,7 +184,7 @@ func DecryptVerifyAttachment(
 
 	// We verify the signature
 	var check bool
-	if check, err = verifyDetachedArmored(publicKey, message, armoredSignature); err != nil {
+	if check, err = verifyDetachedArmored(publicKey, message, armoredSignature, verifyTime); err != nil {
 		return nil, err
 	}
 	if !check {
@@ -215,6 +245,20 @@ func DecryptVerifyArmoredDetached(
 	passphrase []byte,
 	ciphertextArmored string,
 	encryptedSignatureArmored string,
+) (plainData []byte, err error) {
+	return DecryptVerifyArmoredDetachedWithTime(
+		publicKey, privateKey, passphrase, ciphertextArmored, encryptedSignatureArmored, constants.VERIFY_TIME_NOW,
+	)
+}
+
+// DecryptVerifyArmoredDetachedWithTime is DecryptVerifyArmoredDetached,
+// verifying the signature at verifyTime.
+func DecryptVerifyArmoredDetachedWithTime(
+	publicKey, privateKey string,
+	passphrase []byte,
+	ciphertextArmored string,
+	encryptedSignatureArmored string,
+	verifyTime int64,
 ) (plainData []byte, err error) {
 	// Some type casting
 	ciphertext, err := crypto.NewPGPMessageFromArmored(ciphertextArmored)
@@ -223,7 +267,9 @@ func DecryptVerifyArmoredDetached(
 	}
 
 	// We decrypt and verify the encrypted signature
-	message, err := decryptVerifyObjDetached(publicKey, privateKey, passphrase, ciphertext, encryptedSignatureArmored)
+	message, err := decryptVerifyObjDetached(
+		publicKey, privateKey, passphrase, ciphertext, encryptedSignatureArmored, verifyTime,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +307,20 @@ func DecryptVerifyBinaryDetached(
 	passphrase []byte,
 	encryptedData []byte,
 	encryptedSignatureArmored string,
+) (plainData []byte, err error) {
+	return DecryptVerifyBinaryDetachedWithTime(
+		publicKey, privateKey, passphrase, encryptedData, encryptedSignatureArmored, constants.VERIFY_TIME_NOW,
+	)
+}
+
+// DecryptVerifyBinaryDetachedWithTime is DecryptVerifyBinaryDetached,
+// verifying the signature at verifyTime.
+func DecryptVerifyBinaryDetachedWithTime(
+	publicKey, privateKey string,
+	passphrase []byte,
+	encryptedData []byte,
+	encryptedSignatureArmored string,
+	verifyTime int64,
 ) (plainData []byte, err error) {
 	// Some type casting
 	ciphertext := crypto.NewPGPMessage(encryptedData)
@@ -269,7 +329,9 @@ func DecryptVerifyBinaryDetached(
 	}
 
 	// We decrypt and verify the encrypted signature
-	message, err := decryptVerifyObjDetached(publicKey, privateKey, passphrase, ciphertext, encryptedSignatureArmored)
+	message, err := decryptVerifyObjDetached(
+		publicKey, privateKey, passphrase, ciphertext, encryptedSignatureArmored, verifyTime,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -352,6 +414,96 @@ func DecryptSessionKey(
 	return sessionKey, nil
 }
 
+// GetSessionKeyFromMessage decrypts the session key of an armored message with
+// the given private key and its passphrase, and returns it base64 encoded
+// with the name of its algorithm, e.g. to decrypt the message with another
+// tool. The message data is not decrypted.
+func GetSessionKeyFromMessage(
+	armoredMessage string,
+	privateKey string,
+	passphrase []byte,
+) (base64Key, algo string, err error) {
+	message, err := crypto.NewPGPMessageFromArmored(armoredMessage)
+	if err != nil {
+		return "", "", errors.Wrap(err, "gopenpgp: unable to parse ciphertext")
+	}
+
+	privateKeyObj, err := crypto.NewKeyFromArmored(privateKey)
+	if err != nil {
+		return "", "", errors.Wrap(err, "gopenpgp: unable to parse the private key")
+	}
+	privateKeyUnlocked, err := privateKeyObj.Unlock(passphrase)
+	if err != nil {
+		return "", "", errors.Wrap(err, "gopenpgp: unable to unlock key")
+	}
+	defer privateKeyUnlocked.ClearPrivateParams()
+	privateKeyRing, err := crypto.NewKeyRing(privateKeyUnlocked)
+	if err != nil {
+		return "", "", errors.Wrap(err, "gopenpgp: unable to create the private key ring")
+	}
+
+	sessionKey, err := privateKeyRing.DecryptSessionKeyFromMessage(message)
+	if err != nil {
+		return "", "", errors.Wrap(err, "gopenpgp: unable to decrypt session key")
+	}
+	defer sessionKey.Clear()
+
+	return sessionKey.GetBase64Key(), sessionKey.Algo, nil
+}
+
+// EncryptPGPMessageToAdditionalKey decrypts the session key of an armored
+// message with the given private key and its passphrase, and returns the
+// armored message with the session key also encrypted to additionalPublicKey.
+// The message itself is never decrypted, and its Version and Comment armor
+// headers are preserved.
+// Errors wrap crypto.ErrSessionKeyDecryption or crypto.ErrSessionKeyEncryption.
+func EncryptPGPMessageToAdditionalKey(
+	armoredMessage string,
+	privateKey string,
+	passphrase []byte,
+	additionalPublicKey string,
+) (string, error) {
+	block, err := internal.Unarmor(armoredMessage)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to parse ciphertext")
+	}
+	data, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to parse ciphertext")
+	}
+
+	privateKeyObj, err := crypto.NewKeyFromArmored(privateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to parse the private key")
+	}
+	privateKeyUnlocked, err := privateKeyObj.Unlock(passphrase)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to unlock key")
+	}
+	defer privateKeyUnlocked.ClearPrivateParams()
+	privateKeyRing, err := crypto.NewKeyRing(privateKeyUnlocked)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to create the private key ring")
+	}
+
+	additionalKeyRing, err := createPublicKeyRing(additionalPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := privateKeyRing.AddRecipientToMessage(crypto.NewPGPMessage(data), additionalKeyRing)
+	if err != nil {
+		return "", err
+	}
+
+	return armor.ArmorWithTypeAndCustomHeaders(
+		message.GetBinary(),
+		constants.PGPMessageHeader,
+		block.Header["Version"],
+		block.Header["Comment"],
+	)
+}
+
 func encryptMessageArmored(key string, message *crypto.PlainMessage) (string, error) {
 	ciphertext, err := encryptMessage(key, message)
 	if err != nil {
@@ -441,7 +593,9 @@ func signDetached(privateKey string, passphrase []byte, message *crypto.PlainMes
 	return detachedSignature, nil
 }
 
-func verifyDetachedArmored(publicKey string, message *crypto.PlainMessage, armoredSignature string) (check bool, err error) {
+func verifyDetachedArmored(
+	publicKey string, message *crypto.PlainMessage, armoredSignature string, verifyTime int64,
+) (check bool, err error) {
 	var detachedSignature *crypto.PGPSignature
 
 	// We unarmor the signature
@@ -449,10 +603,12 @@ func verifyDetachedArmored(publicKey string, message *crypto.PlainMessage, armor
 		return false, errors.Wrap(err, "gopenpgp: unable to unarmor signature")
 	}
 	// we verify the signature
-	return verifyDetached(publicKey, message, detachedSignature)
+	return verifyDetached(publicKey, message, detachedSignature, verifyTime)
 }
 
-func verifyDetached(publicKey string, message *crypto.PlainMessage, detachedSignature *crypto.PGPSignature) (check bool, err error) {
+func verifyDetached(
+	publicKey string, message *crypto.PlainMessage, detachedSignature *crypto.PGPSignature, verifyTime int64,
+) (check bool, err error) {
 	var publicKeyRing *crypto.KeyRing
 
 	// We prepare the public key for signature verification
@@ -462,7 +618,7 @@ func verifyDetached(publicKey string, message *crypto.PlainMessage, detachedSign
 	}
 
 	// We verify the signature
-	if publicKeyRing.VerifyDetached(message, detachedSignature, crypto.GetUnixTime()) != nil {
+	if publicKeyRing.VerifyDetached(message, detachedSignature, crypto.ResolveVerifyTime(verifyTime)) != nil {
 		return false, nil
 	}
 	return true, nil
@@ -518,6 +674,12 @@ func createPublicKeyRing(publicKey string) (*crypto.KeyRing, error) {
 	return publicKeyRing, nil
 }
 
+func clone(src []byte) (dst []byte) {
+	dst = make([]byte, len(src))
+	copy(dst, src)
+	return
+}
+
 func encryptSignObjDetached(
 	publicKey, privateKey string,
 	passphrase []byte,
@@ -569,6 +731,7 @@ func decryptVerifyObjDetached(
 	passphrase []byte,
 	ciphertext *crypto.PGPMessage,
 	encryptedSignatureArmored string,
+	verifyTime int64,
 ) (message *crypto.PlainMessage, err error) {
 	// We decrypt the message
 	if message, err = decryptMessage(privateKey, passphrase, ciphertext); err != nil {
@@ -589,7 +752,7 @@ func decryptVerifyObjDetached(
 
 	// We verify the signature
 	var check bool
-	if check, err = verifyDetached(publicKey, message, detachedSignature); err != nil {
+	if check, err = verifyDetached(publicKey, message, detachedSignature, verifyTime); err != nil {
 		return nil, err
 	}
 	if !check {
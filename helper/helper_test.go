@@ -2,9 +2,12 @@ package helper
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 	"github.com/yougroupteam/gopenpgp/v2/crypto"
 )
 
@@ -50,6 +53,15 @@ func TestArmoredTextMessageEncryption(t *testing.T) {
 	assert.Exactly(t, plaintext, decrypted)
 }
 
+func TestArmoredTextMessageEncryptionSignOnlyKey(t *testing.T) {
+	_, err := EncryptMessageArmored(readTestFile("key_ecdsaP256", false), "Secret message")
+	var missingErr *crypto.MissingEncryptionKeyError
+	if !errors.As(err, &missingErr) {
+		t.Fatal("Expected a MissingEncryptionKeyError, got:", err)
+	}
+	assert.Len(t, missingErr.Fingerprints, 1)
+}
+
 func TestArmoredTextMessageEncryptionVerification(t *testing.T) {
 	var plaintext = "Secret message"
 
@@ -87,6 +99,42 @@ func TestArmoredTextMessageEncryptionVerification(t *testing.T) {
 	assert.Exactly(t, plaintext, decrypted)
 }
 
+func TestDecryptVerifyMessageArmoredWithTime(t *testing.T) {
+	armored, err := EncryptSignMessageArmored(
+		readTestFile("keyring_privateKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		"Secret message",
+	)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	// Signed after the verification time
+	_, err = DecryptVerifyMessageArmoredWithTime(
+		readTestFile("keyring_privateKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		armored,
+		1000,
+	)
+	assert.Error(t, err)
+
+	for _, verifyTime := range []int64{constants.VERIFY_TIME_NOW, constants.VERIFY_TIME_DISABLED} {
+		decrypted, err := DecryptVerifyMessageArmoredWithTime(
+			readTestFile("keyring_privateKey", false),
+			readTestFile("keyring_privateKey", false),
+			testMailboxPassword,
+			armored,
+			verifyTime,
+		)
+		if err != nil {
+			t.Fatal("Expected no error when decrypting, got:", err)
+		}
+		assert.Exactly(t, "Secret message", decrypted)
+	}
+}
+
 func TestAttachmentEncryptionVerification(t *testing.T) {
 	var attachment = []byte("Secret file\r\nRoot password:hunter2")
 
@@ -352,3 +400,78 @@ func TestEncryptSignBinaryDetached(t *testing.T) {
 		t.Fatal("Expected an error while decrypting and verifying with a wrong signature")
 	}
 }
+
+func TestEncryptPGPMessageToAdditionalKey(t *testing.T) {
+	var plaintext = "Secret message"
+	additionalPassphrase := []byte("banana")
+
+	armored, err := EncryptMessageArmored(readTestFile("keyring_publicKey", false), plaintext)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	additionalPrivateKey, err := GenerateKey("name", "name@example.com", additionalPassphrase, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error when generating key, got:", err)
+	}
+	additionalKey, err := crypto.NewKeyFromArmored(additionalPrivateKey)
+	if err != nil {
+		t.Fatal("Expected no error when parsing key, got:", err)
+	}
+	additionalPublicKey, err := additionalKey.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error when getting public key, got:", err)
+	}
+
+	shared, err := EncryptPGPMessageToAdditionalKey(
+		armored, readTestFile("keyring_privateKey", false), testMailboxPassword, additionalPublicKey,
+	)
+	if err != nil {
+		t.Fatal("Expected no error when adding recipient, got:", err)
+	}
+	assert.Contains(t, shared, "Version: "+constants.ArmorHeaderVersion)
+
+	decrypted, err := DecryptMessageArmored(additionalPrivateKey, additionalPassphrase, shared)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted)
+	decrypted, err = DecryptMessageArmored(readTestFile("keyring_privateKey", false), testMailboxPassword, shared)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted)
+
+	_, err = EncryptPGPMessageToAdditionalKey(armored, additionalPrivateKey, additionalPassphrase, additionalPublicKey)
+	assert.True(t, errors.Is(err, crypto.ErrSessionKeyDecryption))
+}
+
+func TestGetSessionKeyFromMessage(t *testing.T) {
+	armored, err := EncryptMessageArmored(readTestFile("keyring_publicKey", false), "Secret message")
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	base64Key, algo, err := GetSessionKeyFromMessage(armored, readTestFile("keyring_privateKey", false), testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error when getting session key, got:", err)
+	}
+	assert.Exactly(t, constants.AES256, algo)
+
+	key, err := base64.StdEncoding.DecodeString(base64Key)
+	if err != nil {
+		t.Fatal("Expected no error when decoding session key, got:", err)
+	}
+	message, err := crypto.NewPGPMessageFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error when parsing message, got:", err)
+	}
+	split, err := message.SeparateKeyAndData(len(message.GetBinary()), -1)
+	if err != nil {
+		t.Fatal("Expected no error when splitting message, got:", err)
+	}
+	decrypted, err := crypto.NewSessionKeyFromToken(key, algo).Decrypt(split.GetBinaryDataPacket())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, "Secret message", decrypted.GetString())
+}
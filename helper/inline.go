@@ -0,0 +1,133 @@
+package helper
+
+import (
+	goerrors "errors"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+var inlineBlockRegexp = regexp.MustCompile(
+	`(?ms)^-----BEGIN PGP MESSAGE-----\r?$.*?^-----END PGP MESSAGE-----\r?$` +
+		`|^-----BEGIN PGP SIGNED MESSAGE-----\r?$.*?^-----END PGP SIGNATURE-----\r?$`,
+)
+
+// InlineBlock is the result of processing an armored block of a PGP/Inline body.
+type InlineBlock struct {
+	// IsEncrypted is true for PGP messages and false for cleartext signed messages.
+	IsEncrypted bool
+	// SignatureStatus is one of the constants.SIGNATURE_* statuses.
+	SignatureStatus int
+	// Error is set if the block could not be processed, it is then left
+	// untouched in the body.
+	Error string
+}
+
+// InlineMessage is a PGP/Inline body with its armored blocks replaced by their
+// content, and the results of processing each block in order.
+type InlineMessage struct {
+	Body   string
+	Blocks []*InlineBlock
+}
+
+// CountBlocks returns the number of armored blocks found in the body.
+func (msg *InlineMessage) CountBlocks() int {
+	return len(msg.Blocks)
+}
+
+// GetBlock returns the result of processing the n-th armored block.
+func (msg *InlineMessage) GetBlock(n int) (*InlineBlock, error) {
+	if n < 0 || n >= len(msg.Blocks) {
+		return nil, errors.New("gopenpgp: out of bound when fetching block")
+	}
+	return msg.Blocks[n], nil
+}
+
+// ProcessInlineMessage finds the armored PGP messages and cleartext signed
+// messages within a PGP/Inline body, decrypts them with privateKeyRing,
+// and verifies them with publicKeyRing.
+// Each block is replaced in the body by its content, unless it can't be
+// processed: it is then left untouched and its error is reported.
+// If publicKeyRing is nil, the signatures are not verified and the status
+// is constants.SIGNATURE_NO_VERIFIER.
+func ProcessInlineMessage(
+	body string, privateKeyRing, publicKeyRing *crypto.KeyRing, verifyTime int64,
+) *InlineMessage {
+	message := &InlineMessage{}
+	verifyTime = crypto.ResolveVerifyTime(verifyTime)
+	message.Body = inlineBlockRegexp.ReplaceAllStringFunc(body, func(armored string) string {
+		var block *InlineBlock
+		var content string
+		var err error
+		if strings.HasPrefix(armored, "-----BEGIN PGP SIGNED MESSAGE-----") {
+			block, content, err = processInlineSignedBlock(armored, publicKeyRing, verifyTime)
+		} else {
+			block, content, err = processInlineEncryptedBlock(armored, privateKeyRing, publicKeyRing, verifyTime)
+		}
+		message.Blocks = append(message.Blocks, block)
+		if err != nil {
+			block.Error = err.Error()
+			return armored
+		}
+		return content
+	})
+	return message
+}
+
+func processInlineEncryptedBlock(
+	armored string, privateKeyRing, publicKeyRing *crypto.KeyRing, verifyTime int64,
+) (*InlineBlock, string, error) {
+	block := &InlineBlock{IsEncrypted: true, SignatureStatus: constants.SIGNATURE_NO_VERIFIER}
+	if privateKeyRing == nil {
+		return block, "", errors.New("gopenpgp: no private key ring provided")
+	}
+	pgpMessage, err := crypto.NewPGPMessageFromArmored(armored)
+	if err != nil {
+		return block, "", errors.Wrap(err, "gopenpgp: unable to unarmor message")
+	}
+
+	message, err := privateKeyRing.Decrypt(pgpMessage, publicKeyRing, verifyTime)
+	if publicKeyRing != nil {
+		block.SignatureStatus, err = getSignatureStatus(err)
+	}
+	if err != nil {
+		return block, "", errors.Wrap(err, "gopenpgp: unable to decrypt message")
+	}
+	return block, message.GetString(), nil
+}
+
+func processInlineSignedBlock(
+	armored string, publicKeyRing *crypto.KeyRing, verifyTime int64,
+) (*InlineBlock, string, error) {
+	block := &InlineBlock{SignatureStatus: constants.SIGNATURE_NO_VERIFIER}
+	clearTextMessage, err := crypto.NewClearTextMessageFromArmored(armored)
+	if err != nil {
+		return block, "", errors.Wrap(err, "gopenpgp: unable to unarmor cleartext message")
+	}
+
+	message := crypto.NewPlainMessageFromString(clearTextMessage.GetString())
+	if publicKeyRing != nil {
+		signature := crypto.NewPGPSignature(clearTextMessage.GetBinarySignature())
+		block.SignatureStatus, err = getSignatureStatus(publicKeyRing.VerifyDetached(message, signature, verifyTime))
+		if err != nil {
+			return block, "", errors.Wrap(err, "gopenpgp: unable to verify cleartext message")
+		}
+	}
+	return block, message.GetString(), nil
+}
+
+// getSignatureStatus returns the status of a signature verification,
+// and the error if it is not a signature verification error.
+func getSignatureStatus(err error) (int, error) {
+	if err == nil {
+		return constants.SIGNATURE_OK, nil
+	}
+	var sigErr crypto.SignatureVerificationError
+	if goerrors.As(err, &sigErr) {
+		return sigErr.Status, nil
+	}
+	return constants.SIGNATURE_FAILED, err
+}
@@ -0,0 +1,78 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestProcessInlineMessage(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing private key, got:", err)
+	}
+	unlockedKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking private key, got:", err)
+	}
+	privateKeyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building private keyring, got:", err)
+	}
+	publicKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing public key, got:", err)
+	}
+	publicKeyRing, err := crypto.NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building public keyring, got:", err)
+	}
+
+	signed, err := SignCleartextMessage(privateKeyRing, "signed text")
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	encrypted, err := publicKeyRing.Encrypt(crypto.NewPlainMessageFromString("secret text"), privateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	armored, err := encrypted.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	broken := "-----BEGIN PGP MESSAGE-----\n\nnot base64\n-----END PGP MESSAGE-----"
+
+	body := strings.Join([]string{"Hello,", signed, "and", armored, "then", broken, "Bye"}, "\n")
+	message := ProcessInlineMessage(body, privateKeyRing, publicKeyRing, crypto.GetUnixTime())
+
+	assert.Exactly(
+		t,
+		strings.Join([]string{"Hello,", "signed text", "and", "secret text", "then", broken, "Bye"}, "\n"),
+		message.Body,
+	)
+	assert.Exactly(t, 3, message.CountBlocks())
+
+	block, err := message.GetBlock(0)
+	if err != nil {
+		t.Fatal("Expected no error while getting block, got:", err)
+	}
+	assert.False(t, block.IsEncrypted)
+	assert.Exactly(t, constants.SIGNATURE_OK, block.SignatureStatus)
+	assert.Empty(t, block.Error)
+
+	assert.True(t, message.Blocks[1].IsEncrypted)
+	assert.Exactly(t, constants.SIGNATURE_OK, message.Blocks[1].SignatureStatus)
+	assert.Empty(t, message.Blocks[1].Error)
+
+	assert.NotEmpty(t, message.Blocks[2].Error)
+
+	// Without keys, the signed block is not verified and the encrypted block is left untouched
+	message = ProcessInlineMessage(body, nil, nil, crypto.GetUnixTime())
+	assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, message.Blocks[0].SignatureStatus)
+	assert.Empty(t, message.Blocks[0].Error)
+	assert.NotEmpty(t, message.Blocks[1].Error)
+	assert.Contains(t, message.Body, armored)
+}
@@ -0,0 +1,49 @@
+package helper
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+// SignedJSON holds the canonical form of a signed JSON value and its binary
+// detached signature.
+type SignedJSON struct {
+	Data      []byte
+	Signature []byte
+}
+
+// SignJSON canonicalizes the JSON value jsonBytes with the JSON
+// Canonicalization Scheme (RFC 8785), and signs the canonical form with a
+// binary detached signature, so that the signature doesn't depend on the
+// ordering of the members or on the whitespace.
+// Returns the canonical form, to be stored by the caller, and the signature.
+func SignJSON(keyRing *crypto.KeyRing, jsonBytes []byte) (*SignedJSON, error) {
+	canonical, err := canonicalizeJSON(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := keyRing.SignDetached(crypto.NewPlainMessage(canonical))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to sign JSON")
+	}
+	return &SignedJSON{Data: canonical, Signature: signature.GetBinary()}, nil
+}
+
+// VerifyJSON canonicalizes the JSON value jsonBytes like SignJSON, and
+// verifies the binary detached signature of the canonical form with keyRing
+// at verifyTime, see crypto.ResolveVerifyTime.
+// Returns the canonical form, or a crypto.SignatureVerificationError if the
+// verification fails.
+func VerifyJSON(keyRing *crypto.KeyRing, jsonBytes, signature []byte, verifyTime int64) ([]byte, error) {
+	canonical, err := canonicalizeJSON(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+	err = keyRing.VerifyDetached(
+		crypto.NewPlainMessage(canonical), crypto.NewPGPSignature(signature), crypto.ResolveVerifyTime(verifyTime),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return canonical, nil
+}
@@ -0,0 +1,196 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// canonicalizeJSON returns the canonical form of the JSON value in data, as
+// defined by the JSON Canonicalization Scheme (RFC 8785): object members
+// sorted by the UTF-16 code units of their names, without whitespace, with
+// the ECMAScript serialization of numbers and minimally escaped strings.
+// The value must be I-JSON: valid UTF-8, without duplicate member names, and
+// with numbers representable as IEEE 754 doubles.
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("gopenpgp: invalid UTF-8 in JSON")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var canonical bytes.Buffer
+	if err := writeCanonicalJSONValue(&canonical, decoder); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("gopenpgp: invalid data after JSON value")
+	}
+	return canonical.Bytes(), nil
+}
+
+// writeCanonicalJSONValue writes the canonical form of the next JSON value
+// read by decoder.
+func writeCanonicalJSONValue(w *bytes.Buffer, decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: invalid JSON")
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' {
+			return writeCanonicalJSONObject(w, decoder)
+		}
+		if token == '[' {
+			return writeCanonicalJSONArray(w, decoder)
+		}
+		return errors.New("gopenpgp: invalid JSON")
+	case string:
+		writeCanonicalJSONString(w, token)
+	case json.Number:
+		number, err := canonicalJSONNumber(token)
+		if err != nil {
+			return err
+		}
+		w.WriteString(number)
+	case bool:
+		w.WriteString(strconv.FormatBool(token))
+	case nil:
+		w.WriteString("null")
+	}
+	return nil
+}
+
+func writeCanonicalJSONArray(w *bytes.Buffer, decoder *json.Decoder) error {
+	w.WriteByte('[')
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		if err := writeCanonicalJSONValue(w, decoder); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return errors.Wrap(err, "gopenpgp: invalid JSON")
+	}
+	w.WriteByte(']')
+	return nil
+}
+
+type canonicalJSONMember struct {
+	name  []uint16
+	value []byte
+}
+
+func writeCanonicalJSONObject(w *bytes.Buffer, decoder *json.Decoder) error {
+	var members []canonicalJSONMember
+	names := make(map[string]bool)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: invalid JSON")
+		}
+		name := token.(string)
+		if names[name] {
+			return errors.New("gopenpgp: duplicate member name in JSON object")
+		}
+		names[name] = true
+
+		var value bytes.Buffer
+		writeCanonicalJSONString(&value, name)
+		value.WriteByte(':')
+		if err := writeCanonicalJSONValue(&value, decoder); err != nil {
+			return err
+		}
+		members = append(members, canonicalJSONMember{utf16.Encode([]rune(name)), value.Bytes()})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return errors.Wrap(err, "gopenpgp: invalid JSON")
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i].name, members[j].name
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	w.WriteByte('{')
+	for i, member := range members {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(member.value)
+	}
+	w.WriteByte('}')
+	return nil
+}
+
+// writeCanonicalJSONString writes s quoted, escaping only the quotation
+// mark, the reverse solidus and the control characters.
+func writeCanonicalJSONString(w *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	w.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			w.WriteByte('\\')
+			w.WriteRune(r)
+		case '\b':
+			w.WriteString(`\b`)
+		case '\f':
+			w.WriteString(`\f`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case '\t':
+			w.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				w.WriteString(`\u00`)
+				w.WriteByte(hex[r>>4])
+				w.WriteByte(hex[r&0xf])
+			} else {
+				w.WriteRune(r)
+			}
+		}
+	}
+	w.WriteByte('"')
+}
+
+// canonicalJSONNumber serializes number like ECMAScript's
+// Number.prototype.toString.
+func canonicalJSONNumber(number json.Number) (string, error) {
+	value, err := strconv.ParseFloat(string(number), 64)
+	if err != nil || math.IsInf(value, 0) {
+		return "", errors.New("gopenpgp: JSON number out of range")
+	}
+	if value == 0 {
+		// Including negative zero
+		return "0", nil
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	serialized := strconv.FormatFloat(value, format, -1, 64)
+	if format == 'e' {
+		// Remove the leading zero of two-digit exponents, e.g. 1e-07
+		n := len(serialized)
+		if n >= 4 && serialized[n-4] == 'e' && serialized[n-2] == '0' {
+			serialized = serialized[:n-2] + serialized[n-1:]
+		}
+	}
+	return serialized, nil
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	var vectors []struct {
+		Input  string `json:"input"`
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(readTestFile("json_canonicalization", false)), &vectors); err != nil {
+		t.Fatal("Expected no error while reading vectors, got:", err)
+	}
+	for _, vector := range vectors {
+		canonical, err := canonicalizeJSON([]byte(vector.Input))
+		if err != nil {
+			t.Fatal("Expected no error while canonicalizing JSON, got:", err)
+		}
+		assert.Exactly(t, vector.Output, string(canonical))
+	}
+
+	for _, invalid := range []string{
+		``,
+		`{"a":1,}`,
+		`{"a":1,"a":2}`,
+		`{"a":1} {}`,
+		`[1e400]`,
+		"\"\xff\"",
+	} {
+		_, err := canonicalizeJSON([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSignVerifyJSON(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading private key, got:", err)
+	}
+	unlockedKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking private key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	signed, err := SignJSON(keyRing, []byte(`{"name": "Alice", "emails": ["alice@example.com"]}`))
+	if err != nil {
+		t.Fatal("Expected no error while signing JSON, got:", err)
+	}
+	assert.Exactly(t, `{"emails":["alice@example.com"],"name":"Alice"}`, string(signed.Data))
+
+	// Serialized differently by another platform
+	reordered := []byte("{\n  \"emails\" : [ \"alice@example.com\" ],\n  \"name\" : \"\\u0041lice\"\n}")
+	canonical, err := VerifyJSON(keyRing, reordered, signed.Signature, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying JSON, got:", err)
+	}
+	assert.Exactly(t, signed.Data, canonical)
+
+	_, err = VerifyJSON(keyRing, []byte(`{"emails":[],"name":"Alice"}`), signed.Signature, crypto.GetUnixTime())
+	assert.Error(t, err)
+
+	_, err = SignJSON(keyRing, []byte(`{"name":"Alice","name":"Bob"}`))
+	assert.Error(t, err)
+}
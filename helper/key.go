@@ -2,37 +2,65 @@ package helper
 
 import (
 	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
 	"github.com/yougroupteam/gopenpgp/v2/crypto"
 )
 
 // UpdatePrivateKeyPassphrase decrypts the given armored privateKey with oldPassphrase,
 // re-encrypts it with newPassphrase, and returns the new armored key.
+// If privateKey contains several keys, all of them are re-encrypted.
+// The unlocked key material is wiped, and the new key is checked to unlock
+// with newPassphrase before being returned.
 func UpdatePrivateKeyPassphrase(
 	privateKey string,
 	oldPassphrase, newPassphrase []byte,
 ) (string, error) {
-	key, err := crypto.NewKeyFromArmored(privateKey)
+	keys, err := crypto.NewKeysFromArmored(privateKey)
 	if err != nil {
 		return "", errors.Wrap(err, "gopenpgp: unable to parse key")
 	}
 
+	var serialized []byte
+	for _, key := range keys {
+		locked, err := updateKeyPassphrase(key, oldPassphrase, newPassphrase)
+		if err != nil {
+			return "", err
+		}
+		binKey, err := locked.Serialize()
+		if err != nil {
+			return "", errors.Wrap(err, "gopenpgp: unable to serialize new key")
+		}
+		serialized = append(serialized, binKey...)
+	}
+
+	armored, err := armor.ArmorWithType(serialized, constants.PrivateKeyHeader)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to armor new key")
+	}
+
+	return armored, nil
+}
+
+func updateKeyPassphrase(key *crypto.Key, oldPassphrase, newPassphrase []byte) (*crypto.Key, error) {
 	unlocked, err := key.Unlock(oldPassphrase)
 	if err != nil {
-		return "", errors.Wrap(err, "gopenpgp: unable to unlock old key")
+		return nil, errors.Wrap(err, "gopenpgp: unable to unlock old key")
 	}
 	defer unlocked.ClearPrivateParams()
 
 	locked, err := unlocked.Lock(newPassphrase)
 	if err != nil {
-		return "", errors.Wrap(err, "gopenpgp: unable to lock new key")
+		return nil, errors.Wrap(err, "gopenpgp: unable to lock new key")
 	}
 
-	armored, err := locked.Armor()
+	check, err := locked.Unlock(newPassphrase)
 	if err != nil {
-		return "", errors.Wrap(err, "gopenpgp: unable to armor new key")
+		return nil, errors.Wrap(err, "gopenpgp: unable to unlock new key")
 	}
+	check.ClearPrivateParams()
 
-	return armored, nil
+	return locked, nil
 }
 
 // GenerateKey generates a key of the given keyType ("rsa" or "x25519"), encrypts it, and returns an armored string.
@@ -53,6 +81,44 @@ func GenerateKey(name, email string, passphrase []byte, keyType string, bits int
 	return locked.Armor()
 }
 
+// CheckPassphrase returns whether passphrase unlocks the given armored private
+// key, without keeping the unlocked key: its private parameters are wiped
+// right away. A wrong passphrase returns false and no error, an error is only
+// returned if armoredKey isn't a locked private key.
+func CheckPassphrase(armoredKey string, passphrase []byte) (bool, error) {
+	key, err := crypto.NewKeyFromArmored(armoredKey)
+	if err != nil {
+		return false, errors.Wrap(err, "gopenpgp: unable to parse key")
+	}
+	isLocked, err := key.IsLocked()
+	if err != nil {
+		return false, err
+	}
+	if !isLocked {
+		return false, errors.New("gopenpgp: key is not locked")
+	}
+
+	unlocked, err := key.Unlock(passphrase)
+	if err != nil {
+		return false, nil
+	}
+	unlocked.ClearPrivateParams()
+	return true, nil
+}
+
+// GetArmoredPublicKey returns the armored public key of the given armored
+// private key, which doesn't need to be unlocked.
+func GetArmoredPublicKey(armoredPrivateKey string) (string, error) {
+	key, err := crypto.NewKeyFromArmored(armoredPrivateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to parse key")
+	}
+	if !key.IsPrivate() {
+		return "", errors.New("gopenpgp: not a private key")
+	}
+	return key.GetArmoredPublicKey()
+}
+
 func GetSHA256Fingerprints(publicKey string) ([]string, error) {
 	key, err := crypto.NewKeyFromArmored(publicKey)
 	if err != nil {
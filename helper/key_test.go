@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/armor"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
 )
 
 func TestGetSHA256FingerprintsV4(t *testing.T) {
@@ -16,3 +19,90 @@ func TestGetSHA256FingerprintsV4(t *testing.T) {
 	assert.Exactly(t, "d9ac0b857da6d2c8be985b251a9e3db31e7a1d2d832d1f07ebe838a9edce9c24", sha256Fingerprints[0])
 	assert.Exactly(t, "203dfba1f8442c17e59214d9cd11985bfc5cc8721bb4a71740dd5507e58a1a0d", sha256Fingerprints[1])
 }
+
+func TestUpdatePrivateKeyPassphrase(t *testing.T) {
+	newPassphrase := []byte("banana")
+
+	generated, err := GenerateKey("name", "name@example.com", testMailboxPassword, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	binKey1, err := armor.Unarmor(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring key, got:", err)
+	}
+	binKey2, err := armor.Unarmor(generated)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring key, got:", err)
+	}
+	armoredKeys, err := armor.ArmorWithType(append(binKey1, binKey2...), constants.PrivateKeyHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring keys, got:", err)
+	}
+
+	_, err = UpdatePrivateKeyPassphrase(armoredKeys, newPassphrase, newPassphrase)
+	assert.Error(t, err)
+
+	updated, err := UpdatePrivateKeyPassphrase(armoredKeys, testMailboxPassword, newPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while updating passphrase, got:", err)
+	}
+
+	keys, err := crypto.NewKeysFromArmored(updated)
+	if err != nil {
+		t.Fatal("Expected no error while reading keys, got:", err)
+	}
+	assert.Len(t, keys, 2)
+	for _, key := range keys {
+		_, err = key.Unlock(testMailboxPassword)
+		assert.Error(t, err)
+
+		unlocked, err := key.Unlock(newPassphrase)
+		if err != nil {
+			t.Fatal("Expected no error while unlocking key, got:", err)
+		}
+		unlocked.ClearPrivateParams()
+	}
+}
+
+func TestCheckPassphrase(t *testing.T) {
+	privateKey := readTestFile("keyring_privateKey", false)
+
+	ok, err := CheckPassphrase(privateKey, testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while checking passphrase, got:", err)
+	}
+	assert.True(t, ok)
+
+	ok, err = CheckPassphrase(privateKey, []byte("wrong"))
+	if err != nil {
+		t.Fatal("Expected no error while checking wrong passphrase, got:", err)
+	}
+	assert.False(t, ok)
+
+	_, err = CheckPassphrase(readTestFile("keyring_publicKey", false), testMailboxPassword)
+	assert.Error(t, err)
+	_, err = CheckPassphrase("not a key", testMailboxPassword)
+	assert.Error(t, err)
+}
+
+func TestGetArmoredPublicKey(t *testing.T) {
+	armored, err := GetArmoredPublicKey(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	publicKey, err := crypto.NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading public key, got:", err)
+	}
+	assert.False(t, publicKey.IsPrivate())
+	expected, err := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading public key, got:", err)
+	}
+	assert.Exactly(t, expected.GetFingerprint(), publicKey.GetFingerprint())
+
+	_, err = GetArmoredPublicKey(readTestFile("keyring_publicKey", false))
+	assert.Error(t, err)
+}
@@ -1,67 +1,10 @@
 package helper
 
 import (
-	"encoding/json"
-	goerrors "errors"
-	"runtime/debug"
-
 	"github.com/pkg/errors"
 	"github.com/yougroupteam/gopenpgp/v2/crypto"
 )
 
-type ExplicitVerifyMessage struct {
-	Message                    *crypto.PlainMessage
-	SignatureVerificationError *crypto.SignatureVerificationError
-}
-
-// DecryptExplicitVerify decrypts a PGP message given a private keyring
-// and a public keyring to verify the embedded signature. Returns the plain
-// data and an error on signature verification failure.
-func DecryptExplicitVerify(
-	pgpMessage *crypto.PGPMessage,
-	privateKeyRing, publicKeyRing *crypto.KeyRing,
-	verifyTime int64,
-) (*ExplicitVerifyMessage, error) {
-	message, err := privateKeyRing.Decrypt(pgpMessage, publicKeyRing, verifyTime)
-	return newExplicitVerifyMessage(message, err)
-}
-
-// DecryptSessionKeyExplicitVerify decrypts a PGP data packet given a session key
-// and a public keyring to verify the embedded signature. Returns the plain data and
-// an error on signature verification failure.
-func DecryptSessionKeyExplicitVerify(
-	dataPacket []byte,
-	sessionKey *crypto.SessionKey,
-	publicKeyRing *crypto.KeyRing,
-	verifyTime int64,
-) (*ExplicitVerifyMessage, error) {
-	message, err := sessionKey.DecryptAndVerify(dataPacket, publicKeyRing, verifyTime)
-	return newExplicitVerifyMessage(message, err)
-}
-
-func newExplicitVerifyMessage(message *crypto.PlainMessage, err error) (*ExplicitVerifyMessage, error) {
-	var explicitVerify *ExplicitVerifyMessage
-	if err != nil {
-		castedErr := &crypto.SignatureVerificationError{}
-		isType := goerrors.As(err, castedErr)
-		if !isType {
-			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt message")
-		}
-
-		explicitVerify = &ExplicitVerifyMessage{
-			Message:                    message,
-			SignatureVerificationError: castedErr,
-		}
-	} else {
-		explicitVerify = &ExplicitVerifyMessage{
-			Message:                    message,
-			SignatureVerificationError: nil,
-		}
-	}
-
-	return explicitVerify, nil
-}
-
 // DecryptAttachment takes a keypacket and datpacket
 // and returns a decrypted PlainMessage
 // Specifically designed for attachments rather than text messages.
@@ -88,17 +31,6 @@ func EncryptAttachment(plainData []byte, filename string, keyRing *crypto.KeyRin
 	return decrypted, nil
 }
 
-// GetJsonSHA256Fingerprints returns the SHA256 fingeprints of key and subkeys,
-// encoded in JSON, since gomobile can not handle arrays.
-func GetJsonSHA256Fingerprints(publicKey string) ([]byte, error) {
-	key, err := crypto.NewKeyFromArmored(publicKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to parse key")
-	}
-
-	return json.Marshal(key.GetSHA256Fingerprints())
-}
-
 type EncryptSignArmoredDetachedMobileResult struct {
 	CiphertextArmored, EncryptedSignatureArmored string
 }
@@ -140,10 +72,3 @@ func EncryptSignBinaryDetachedMobile(
 		EncryptedSignatureArmored: encryptedSignature,
 	}, nil
 }
-
-// FreeOSMemory can be used to explicitly
-// call the garbage collector and
-// return the unused memory to the OS.
-func FreeOSMemory() {
-	debug.FreeOSMemory()
-}
@@ -0,0 +1,284 @@
+// Package mobile provides the helpers of gopenpgp for the mobile apps bound
+// with gomobile: the functions with a single result, the decryption with
+// explicit signature verification, and the adapters of the mobile readers and
+// writers. Go programs use the crypto and helper packages instead.
+package mobile
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"runtime/debug"
+
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+type ExplicitVerifyMessage struct {
+	Message                    *crypto.PlainMessage
+	SignatureVerificationError *crypto.SignatureVerificationError
+	// Set when the data is encrypted with another cipher than the one of
+	// the session key, see DecryptSessionKeyExplicitVerifyMessage
+	CipherMismatchWarning string
+}
+
+// DecryptExplicitVerify decrypts a PGP message given a private keyring
+// and a public keyring to verify the embedded signature. Returns the plain
+// data and an error on signature verification failure.
+func DecryptExplicitVerify(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	message, err := privateKeyRing.Decrypt(pgpMessage, publicKeyRing, crypto.ResolveVerifyTime(verifyTime))
+	return newExplicitVerifyMessage(message, err)
+}
+
+// MobileProgressCallback receives the progress of a decryption, see
+// crypto.ProgressCallback. It replaces the function type, which gomobile
+// can't bind.
+type MobileProgressCallback interface {
+	OnProgress(current, total int64)
+}
+
+// DecryptExplicitVerifyWithProgress decrypts a PGP message like
+// DecryptExplicitVerify, reporting the progress of the decryption to
+// callback, if not nil.
+func DecryptExplicitVerifyWithProgress(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+	callback MobileProgressCallback,
+) (*ExplicitVerifyMessage, error) {
+	message, err := privateKeyRing.DecryptWithProgress(
+		pgpMessage, publicKeyRing, crypto.ResolveVerifyTime(verifyTime), getProgressCallback(callback),
+	)
+	return newExplicitVerifyMessage(message, err)
+}
+
+// DecryptExplicitVerifyJSON decrypts a PGP message like DecryptExplicitVerify,
+// and returns the result encoded in JSON, see ExplicitVerifyMessage.MarshalJSON.
+func DecryptExplicitVerifyJSON(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) ([]byte, error) {
+	explicitVerify, err := DecryptExplicitVerify(pgpMessage, privateKeyRing, publicKeyRing, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(explicitVerify)
+}
+
+// explicitVerifyMessageJSON is the JSON encoding of an ExplicitVerifyMessage:
+// the fields of the encoding of crypto.ExplicitVerifyMessage, with the same
+// version, and the cipher mismatch warning.
+type explicitVerifyMessageJSON struct {
+	Version               int    `json:"version"`
+	Data                  string `json:"data"`
+	IsBinary              bool   `json:"is_binary"`
+	Filename              string `json:"filename"`
+	ModificationTime      int64  `json:"modification_time"`
+	SignatureStatus       int    `json:"signature_status"`
+	SignerKeyID           string `json:"signer_key_id"`
+	CipherMismatchWarning string `json:"cipher_mismatch_warning"`
+}
+
+// MarshalJSON encodes the result with stable snake_case fields: those of
+// crypto.ExplicitVerifyMessage.MarshalJSON, and cipher_mismatch_warning.
+func (msg *ExplicitVerifyMessage) MarshalJSON() ([]byte, error) {
+	encoded := &explicitVerifyMessageJSON{
+		Version:               crypto.ExplicitVerifyMessageJSONVersion,
+		SignatureStatus:       constants.SIGNATURE_OK,
+		CipherMismatchWarning: msg.CipherMismatchWarning,
+	}
+	if msg.Message != nil {
+		encoded.Data = msg.Message.GetBase64()
+		encoded.IsBinary = msg.Message.IsBinary()
+		encoded.Filename = msg.Message.Filename
+		encoded.ModificationTime = int64(msg.Message.Time)
+		encoded.SignerKeyID = msg.Message.GetHexSignerKeyID()
+	}
+	if msg.SignatureVerificationError != nil {
+		encoded.SignatureStatus = msg.SignatureVerificationError.Status
+		if msg.SignatureVerificationError.SignerKeyID != "" {
+			encoded.SignerKeyID = msg.SignatureVerificationError.SignerKeyID
+		}
+	}
+	return json.Marshal(encoded)
+}
+
+// DecryptSessionKeyExplicitVerify decrypts a PGP data packet given a session key
+// and a public keyring to verify the embedded signature. Returns the plain data and
+// an error on signature verification failure.
+func DecryptSessionKeyExplicitVerify(
+	dataPacket []byte,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	message, err := sessionKey.DecryptAndVerify(dataPacket, publicKeyRing, crypto.ResolveVerifyTime(verifyTime))
+	return newExplicitVerifyMessage(message, err)
+}
+
+// DecryptSessionKeyExplicitVerifyWithProgress decrypts a PGP data packet like
+// DecryptSessionKeyExplicitVerify, reporting the progress of the decryption to
+// callback, if not nil.
+func DecryptSessionKeyExplicitVerifyWithProgress(
+	dataPacket []byte,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+	callback MobileProgressCallback,
+) (*ExplicitVerifyMessage, error) {
+	message, err := sessionKey.DecryptAndVerifyWithProgress(
+		dataPacket, publicKeyRing, crypto.ResolveVerifyTime(verifyTime), getProgressCallback(callback),
+	)
+	return newExplicitVerifyMessage(message, err)
+}
+
+// DecryptSessionKeyExplicitVerifyMessage decrypts a full PGP message given its
+// session key, like DecryptSessionKeyExplicitVerify. The key packets of the
+// message are ignored.
+// If the data is encrypted with another cipher than the one of the session
+// key, e.g. a session key decrypted from a packet declaring the wrong cipher,
+// it is decrypted with the cipher which matches the data, and
+// CipherMismatchWarning describes the mismatch.
+func DecryptSessionKeyExplicitVerifyMessage(
+	message *crypto.PGPMessage,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	split, err := message.SplitKeyPackets()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to split message")
+	}
+
+	dataPacket := split.GetBinaryDataPacket()
+	verifyTime = crypto.ResolveVerifyTime(verifyTime)
+	plainMessage, err := sessionKey.DecryptAndVerify(dataPacket, publicKeyRing, verifyTime)
+	var mismatch crypto.CipherMismatchError
+	if !goerrors.As(err, &mismatch) {
+		return newExplicitVerifyMessage(plainMessage, err)
+	}
+
+	matchingKey := crypto.NewSessionKeyFromToken(sessionKey.Key, mismatch.UsedCipher)
+	defer matchingKey.Clear()
+	plainMessage, err = matchingKey.DecryptAndVerify(dataPacket, publicKeyRing, verifyTime)
+	explicitVerify, err := newExplicitVerifyMessage(plainMessage, err)
+	if err != nil {
+		return nil, err
+	}
+	explicitVerify.CipherMismatchWarning = mismatch.Error()
+	return explicitVerify, nil
+}
+
+// DecryptSessionKeyExplicitVerifyArmored decrypts an armored PGP message given
+// its session key, see DecryptSessionKeyExplicitVerifyMessage.
+func DecryptSessionKeyExplicitVerifyArmored(
+	armoredMessage string,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	message, err := crypto.NewPGPMessageFromArmored(armoredMessage)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse ciphertext")
+	}
+	return DecryptSessionKeyExplicitVerifyMessage(message, sessionKey, publicKeyRing, verifyTime)
+}
+
+func getProgressCallback(callback MobileProgressCallback) crypto.ProgressCallback {
+	if callback == nil {
+		return nil
+	}
+	return callback.OnProgress
+}
+
+func newExplicitVerifyMessage(message *crypto.PlainMessage, err error) (*ExplicitVerifyMessage, error) {
+	var explicitVerify *ExplicitVerifyMessage
+	if err != nil {
+		castedErr := &crypto.SignatureVerificationError{}
+		isType := goerrors.As(err, castedErr)
+		if !isType {
+			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt message")
+		}
+
+		explicitVerify = &ExplicitVerifyMessage{
+			Message:                    message,
+			SignatureVerificationError: castedErr,
+		}
+	} else {
+		explicitVerify = &ExplicitVerifyMessage{
+			Message:                    message,
+			SignatureVerificationError: nil,
+		}
+	}
+
+	return explicitVerify, nil
+}
+
+// VerificationErrorToJSON encodes the SignatureVerificationError in err, which
+// may be wrapped, in JSON with the status, message, cause, signerKeyID and
+// creationTime fields.
+func VerificationErrorToJSON(err error) ([]byte, error) {
+	var verificationErr *crypto.SignatureVerificationError
+	if !goerrors.As(err, &verificationErr) {
+		castedErr := &crypto.SignatureVerificationError{}
+		if !goerrors.As(err, castedErr) {
+			return nil, errors.New("gopenpgp: not a signature verification error")
+		}
+		verificationErr = castedErr
+	}
+	return json.Marshal(verificationErr)
+}
+
+// GetJsonSHA256Fingerprints returns the SHA256 fingeprints of key and subkeys,
+// encoded in JSON, since gomobile can not handle arrays.
+func GetJsonSHA256Fingerprints(publicKey string) ([]byte, error) {
+	key, err := crypto.NewKeyFromArmored(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse key")
+	}
+
+	return json.Marshal(key.GetSHA256Fingerprints())
+}
+
+// JsonKeyFlags contains the flags and key server preferences of a key.
+type JsonKeyFlags struct {
+	Keys                 []*crypto.KeyFlags
+	KeyServerPreferences *crypto.KeyServerPreferences
+}
+
+// GetJsonKeyFlags returns the flags of the key and subkeys and the key server
+// preferences, encoded in JSON, since gomobile can not handle arrays.
+func GetJsonKeyFlags(publicKey string) ([]byte, error) {
+	key, err := crypto.NewKeyFromArmored(publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse key")
+	}
+
+	return json.Marshal(&JsonKeyFlags{
+		Keys:                 key.GetKeyFlags(),
+		KeyServerPreferences: key.GetKeyServerPreferences(),
+	})
+}
+
+// GetJsonKeyMetadataList returns the metadata of each key of an armored key
+// ring, encoded in JSON, since gomobile can not handle arrays.
+// Keys which can't be parsed have an entry with an error message.
+func GetJsonKeyMetadataList(armoredKeys string) ([]byte, error) {
+	list, err := crypto.GetKeyMetadataListFromArmored(armoredKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read keys")
+	}
+	return json.Marshal(list)
+}
+
+// FreeOSMemory can be used to explicitly
+// call the garbage collector and
+// return the unused memory to the OS.
+func FreeOSMemory() {
+	debug.FreeOSMemory()
+}
@@ -0,0 +1,381 @@
+package mobile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestMobileSignedMessageDecryption(t *testing.T) {
+	privateKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	// Password defined in base_test
+	privateKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+
+	publicKey, _ := crypto.NewKeyFromArmored(readTestFile("mime_publicKey", false))
+	testPublicKeyRing, _ := crypto.NewKeyRing(publicKey)
+
+	pgpMessage, err := crypto.NewPGPMessageFromArmored(readTestFile("message_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	decrypted, err := DecryptExplicitVerify(pgpMessage, testPrivateKeyRing, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+
+	assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, decrypted.SignatureVerificationError.Status)
+	assert.Exactly(t, readTestFile("message_plaintext", true), decrypted.Message.GetString())
+
+	publicKey, _ = crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	testPublicKeyRing, _ = crypto.NewKeyRing(publicKey)
+
+	pgpMessage, err = testPublicKeyRing.Encrypt(decrypted.Message, testPrivateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	decrypted, err = DecryptExplicitVerify(pgpMessage, testPrivateKeyRing, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Exactly(t, readTestFile("message_plaintext", true), decrypted.Message.GetString())
+
+	decrypted, err = DecryptExplicitVerify(pgpMessage, testPublicKeyRing, testPublicKeyRing, crypto.GetUnixTime())
+	assert.NotNil(t, err)
+	assert.Nil(t, decrypted)
+}
+
+func TestMobileSignedMessageDecryptionWithSessionKey(t *testing.T) {
+	var message = crypto.NewPlainMessageFromString(
+		"The secret code is... 1, 2, 3, 4, 5. I repeat: the secret code is... 1, 2, 3, 4, 5",
+	)
+
+	privateKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	// Password defined in base_test
+	privateKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+
+	publicKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	testPublicKeyRing, _ := crypto.NewKeyRing(publicKey)
+
+	sk, err := crypto.GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error generating session key, got:", err)
+	}
+
+	pgpMessage, err := sk.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	decrypted, err := DecryptSessionKeyExplicitVerify(pgpMessage, sk, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+
+	assert.Exactly(t, constants.SIGNATURE_NOT_SIGNED, decrypted.SignatureVerificationError.Status)
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+
+	publicKey, _ = crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	testPublicKeyRing, _ = crypto.NewKeyRing(publicKey)
+
+	pgpMessage, err = sk.EncryptAndSign(message, testPrivateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	decrypted, err = DecryptSessionKeyExplicitVerify(pgpMessage, sk, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+}
+
+func TestGetJsonSHA256FingerprintsV4(t *testing.T) {
+	sha256Fingerprints, err := GetJsonSHA256Fingerprints(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	assert.Exactly(t, []byte("[\"d9ac0b857da6d2c8be985b251a9e3db31e7a1d2d832d1f07ebe838a9edce9c24\",\"203dfba1f8442c17e59214d9cd11985bfc5cc8721bb4a71740dd5507e58a1a0d\"]"), sha256Fingerprints)
+}
+
+func TestGetJsonKeyFlags(t *testing.T) {
+	jsonFlags, err := GetJsonKeyFlags(readTestFile("key_conflictingFlags", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	var keyFlags JsonKeyFlags
+	if err = json.Unmarshal(jsonFlags, &keyFlags); err != nil {
+		t.Fatal("Expected no error while decoding JSON, got:", err)
+	}
+	assert.Len(t, keyFlags.Keys, 2)
+	assert.True(t, keyFlags.Keys[0].CanAuthenticate)
+	assert.True(t, keyFlags.Keys[1].CanEncryptStorage)
+	assert.True(t, keyFlags.KeyServerPreferences.NoModify)
+}
+
+func TestGetJsonKeyMetadataList(t *testing.T) {
+	jsonList, err := GetJsonKeyMetadataList(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading metadata, got:", err)
+	}
+
+	var list []*crypto.KeyMetadata
+	if err = json.Unmarshal(jsonList, &list); err != nil {
+		t.Fatal("Expected no error while decoding JSON, got:", err)
+	}
+	assert.Len(t, list, 1)
+	assert.True(t, list[0].CanEncrypt)
+	assert.False(t, list[0].IsPrivate)
+}
+
+func TestVerificationErrorToJSON(t *testing.T) {
+	verificationErr := crypto.SignatureVerificationError{
+		Status:      constants.SIGNATURE_NO_VERIFIER,
+		Message:     "No matching signature",
+		SignerKeyID: "4b5a2e31d3b53cb1",
+	}
+
+	for _, err := range []error{verificationErr, &verificationErr, errors.Wrap(verificationErr, "wrapped")} {
+		encoded, jsonErr := VerificationErrorToJSON(err)
+		if jsonErr != nil {
+			t.Fatal("Expected no error while encoding verification error, got:", jsonErr)
+		}
+		var decoded crypto.SignatureVerificationError
+		if jsonErr := json.Unmarshal(encoded, &decoded); jsonErr != nil {
+			t.Fatal("Expected no error while decoding verification error, got:", jsonErr)
+		}
+		assert.Exactly(t, verificationErr, decoded)
+	}
+
+	_, err := VerificationErrorToJSON(errors.New("other error"))
+	assert.Error(t, err)
+}
+
+func TestMobileDecryptSessionKeyExplicitVerifyMessage(t *testing.T) {
+	message := crypto.NewPlainMessageFromString("full message decrypted with its session key")
+
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error reading privateKey, got:", err)
+	}
+	privateKey, err = privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+	publicKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	testPublicKeyRing, _ := crypto.NewKeyRing(publicKey)
+
+	pgpMessage, err := testPublicKeyRing.Encrypt(message, testPrivateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	sk, err := testPrivateKeyRing.DecryptSessionKeyFromMessage(pgpMessage)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting session key, got:", err)
+	}
+
+	decrypted, err := DecryptSessionKeyExplicitVerifyMessage(pgpMessage, sk, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Empty(t, decrypted.CipherMismatchWarning)
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+
+	armored, err := pgpMessage.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error when armoring, got:", err)
+	}
+	decrypted, err = DecryptSessionKeyExplicitVerifyArmored(armored, sk, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+}
+
+func TestMobileDecryptSessionKeyExplicitVerifyMessageCipherMismatch(t *testing.T) {
+	message := crypto.NewPlainMessageFromString("data encrypted with another cipher")
+
+	sk, err := crypto.GenerateSessionKeyAlgo(constants.AES128)
+	if err != nil {
+		t.Fatal("Expected no error generating session key, got:", err)
+	}
+	dataPacket, err := sk.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	// CAST5 has the same key size as AES-128
+	wrongCipherKey := crypto.NewSessionKeyFromToken(sk.Key, constants.CAST5)
+	decrypted, err := DecryptSessionKeyExplicitVerifyMessage(crypto.NewPGPMessage(dataPacket), wrongCipherKey, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Contains(t, decrypted.CipherMismatchWarning, "declared cast5 but the data is encrypted with aes128")
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+}
+
+func TestDecryptExplicitVerifyJSON(t *testing.T) {
+	privateKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when reading privateKey, got:", err)
+	}
+	privateKey, err = privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+	publicKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error when reading publicKey, got:", err)
+	}
+	testPublicKeyRing, _ := crypto.NewKeyRing(publicKey)
+
+	message := crypto.NewPlainMessageFromFile([]byte("json bridged"), "file.txt", 1600000000)
+	pgpMessage, err := testPublicKeyRing.Encrypt(message, testPrivateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	signerKeyID := privateKey.GetHexKeyID()
+
+	encoded, err := DecryptExplicitVerifyJSON(pgpMessage, testPrivateKeyRing, testPublicKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t,
+		`{"version":1,"data":"anNvbiBicmlkZ2Vk","is_binary":true,"filename":"file.txt",`+
+			`"modification_time":1600000000,"signature_status":0,"signer_key_id":"`+signerKeyID+`",`+
+			`"cipher_mismatch_warning":""}`,
+		string(encoded),
+	)
+
+	// Verified at a time before the signature
+	encoded, err = DecryptExplicitVerifyJSON(pgpMessage, testPrivateKeyRing, testPublicKeyRing, 1)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal("Expected no error when decoding JSON, got:", err)
+	}
+	assert.EqualValues(t, constants.SIGNATURE_FAILED, decoded["signature_status"])
+	assert.Exactly(t, signerKeyID, decoded["signer_key_id"])
+}
+
+type testProgressCallback struct {
+	calls          int
+	current, total int64
+}
+
+func (callback *testProgressCallback) OnProgress(current, total int64) {
+	callback.calls++
+	callback.current, callback.total = current, total
+}
+
+func TestMobileDecryptWithProgress(t *testing.T) {
+	privateKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	privateKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+	publicKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_publicKey", false))
+	testPublicKeyRing, _ := crypto.NewKeyRing(publicKey)
+
+	message := crypto.NewPlainMessageFromString(readTestFile("message_plaintext", true))
+	pgpMessage, err := testPublicKeyRing.Encrypt(message, testPrivateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	total := int64(len(pgpMessage.GetBinary()))
+
+	callback := &testProgressCallback{}
+	decrypted, err := DecryptExplicitVerifyWithProgress(
+		pgpMessage, testPrivateKeyRing, testPublicKeyRing, crypto.GetUnixTime(), callback,
+	)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Exactly(t, message.GetString(), decrypted.Message.GetString())
+	assert.Exactly(t, &testProgressCallback{calls: 1, current: total, total: total}, callback)
+
+	split, err := pgpMessage.SplitKeyPackets()
+	if err != nil {
+		t.Fatal("Expected no error when splitting, got:", err)
+	}
+	sessionKey, err := testPrivateKeyRing.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting session key, got:", err)
+	}
+	callback = &testProgressCallback{}
+	decrypted, err = DecryptSessionKeyExplicitVerifyWithProgress(
+		split.GetBinaryDataPacket(), sessionKey, testPublicKeyRing, crypto.GetUnixTime(), callback,
+	)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	dataLength := int64(len(split.GetBinaryDataPacket()))
+	assert.Exactly(t, &testProgressCallback{calls: 1, current: dataLength, total: dataLength}, callback)
+
+	// A nil callback disables the progress
+	_, err = DecryptExplicitVerifyWithProgress(pgpMessage, testPrivateKeyRing, testPublicKeyRing, crypto.GetUnixTime(), nil)
+	assert.NoError(t, err)
+}
+
+func TestMobileEmptyMessage(t *testing.T) {
+	privateKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	privateKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	privateKeyRing, _ := crypto.NewKeyRing(privateKey)
+
+	pgpMessage, err := privateKeyRing.Encrypt(crypto.NewPlainMessageFromString(""), privateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	decrypted, err := DecryptExplicitVerify(pgpMessage, privateKeyRing, privateKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Exactly(t, "", decrypted.Message.GetString())
+
+	sessionKey, err := crypto.GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error when generating session key, got:", err)
+	}
+	dataPacket, err := sessionKey.EncryptAndSign(crypto.NewPlainMessage(nil), privateKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	decrypted, err = DecryptSessionKeyExplicitVerify(dataPacket, sessionKey, privateKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.SignatureVerificationError)
+	assert.Empty(t, decrypted.Message.GetBinary())
+}
@@ -1,4 +1,4 @@
-package helper
+package mobile
 
 import (
 	"crypto/sha256"
@@ -0,0 +1,233 @@
+//go:build !gopenpgp_no_mobile_compat
+// +build !gopenpgp_no_mobile_compat
+
+package helper
+
+import (
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+	"github.com/yougroupteam/gopenpgp/v2/helper/mobile"
+)
+
+// The gomobile helpers moved to the helper/mobile package, so that Go
+// programs importing helper don't link them. The aliases and functions below
+// forward to it for backwards compatibility, they will be removed in the next
+// release. Build with the gopenpgp_no_mobile_compat tag to leave them out.
+
+// ExplicitVerifyMessage is an alias of mobile.ExplicitVerifyMessage.
+//
+// Deprecated: use mobile.ExplicitVerifyMessage.
+type ExplicitVerifyMessage = mobile.ExplicitVerifyMessage
+
+// MobileProgressCallback is an alias of mobile.MobileProgressCallback.
+//
+// Deprecated: use mobile.MobileProgressCallback.
+type MobileProgressCallback = mobile.MobileProgressCallback
+
+// JsonKeyFlags is an alias of mobile.JsonKeyFlags.
+//
+// Deprecated: use mobile.JsonKeyFlags.
+type JsonKeyFlags = mobile.JsonKeyFlags
+
+// Mobile2GoWriter is an alias of mobile.Mobile2GoWriter.
+//
+// Deprecated: use mobile.Mobile2GoWriter.
+type Mobile2GoWriter = mobile.Mobile2GoWriter
+
+// Mobile2GoWriterWithSHA256 is an alias of mobile.Mobile2GoWriterWithSHA256.
+//
+// Deprecated: use mobile.Mobile2GoWriterWithSHA256.
+type Mobile2GoWriterWithSHA256 = mobile.Mobile2GoWriterWithSHA256
+
+// MobileReader is an alias of mobile.MobileReader.
+//
+// Deprecated: use mobile.MobileReader.
+type MobileReader = mobile.MobileReader
+
+// MobileReadResult is an alias of mobile.MobileReadResult.
+//
+// Deprecated: use mobile.MobileReadResult.
+type MobileReadResult = mobile.MobileReadResult
+
+// Mobile2GoReader is an alias of mobile.Mobile2GoReader.
+//
+// Deprecated: use mobile.Mobile2GoReader.
+type Mobile2GoReader = mobile.Mobile2GoReader
+
+// Go2AndroidReader is an alias of mobile.Go2AndroidReader.
+//
+// Deprecated: use mobile.Go2AndroidReader.
+type Go2AndroidReader = mobile.Go2AndroidReader
+
+// Go2IOSReader is an alias of mobile.Go2IOSReader.
+//
+// Deprecated: use mobile.Go2IOSReader.
+type Go2IOSReader = mobile.Go2IOSReader
+
+// DecryptExplicitVerify forwards to mobile.DecryptExplicitVerify.
+//
+// Deprecated: use mobile.DecryptExplicitVerify.
+func DecryptExplicitVerify(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptExplicitVerify(pgpMessage, privateKeyRing, publicKeyRing, verifyTime)
+}
+
+// DecryptExplicitVerifyWithProgress forwards to mobile.DecryptExplicitVerifyWithProgress.
+//
+// Deprecated: use mobile.DecryptExplicitVerifyWithProgress.
+func DecryptExplicitVerifyWithProgress(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+	callback MobileProgressCallback,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptExplicitVerifyWithProgress(pgpMessage, privateKeyRing, publicKeyRing, verifyTime, callback)
+}
+
+// DecryptExplicitVerifyJSON forwards to mobile.DecryptExplicitVerifyJSON.
+//
+// Deprecated: use mobile.DecryptExplicitVerifyJSON.
+func DecryptExplicitVerifyJSON(
+	pgpMessage *crypto.PGPMessage,
+	privateKeyRing, publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) ([]byte, error) {
+	return mobile.DecryptExplicitVerifyJSON(pgpMessage, privateKeyRing, publicKeyRing, verifyTime)
+}
+
+// DecryptSessionKeyExplicitVerify forwards to mobile.DecryptSessionKeyExplicitVerify.
+//
+// Deprecated: use mobile.DecryptSessionKeyExplicitVerify.
+func DecryptSessionKeyExplicitVerify(
+	dataPacket []byte,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptSessionKeyExplicitVerify(dataPacket, sessionKey, publicKeyRing, verifyTime)
+}
+
+// DecryptSessionKeyExplicitVerifyWithProgress forwards to mobile.DecryptSessionKeyExplicitVerifyWithProgress.
+//
+// Deprecated: use mobile.DecryptSessionKeyExplicitVerifyWithProgress.
+func DecryptSessionKeyExplicitVerifyWithProgress(
+	dataPacket []byte,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+	callback MobileProgressCallback,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptSessionKeyExplicitVerifyWithProgress(dataPacket, sessionKey, publicKeyRing, verifyTime, callback)
+}
+
+// DecryptSessionKeyExplicitVerifyMessage forwards to mobile.DecryptSessionKeyExplicitVerifyMessage.
+//
+// Deprecated: use mobile.DecryptSessionKeyExplicitVerifyMessage.
+func DecryptSessionKeyExplicitVerifyMessage(
+	message *crypto.PGPMessage,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptSessionKeyExplicitVerifyMessage(message, sessionKey, publicKeyRing, verifyTime)
+}
+
+// DecryptSessionKeyExplicitVerifyArmored forwards to mobile.DecryptSessionKeyExplicitVerifyArmored.
+//
+// Deprecated: use mobile.DecryptSessionKeyExplicitVerifyArmored.
+func DecryptSessionKeyExplicitVerifyArmored(
+	armoredMessage string,
+	sessionKey *crypto.SessionKey,
+	publicKeyRing *crypto.KeyRing,
+	verifyTime int64,
+) (*ExplicitVerifyMessage, error) {
+	return mobile.DecryptSessionKeyExplicitVerifyArmored(armoredMessage, sessionKey, publicKeyRing, verifyTime)
+}
+
+// VerificationErrorToJSON forwards to mobile.VerificationErrorToJSON.
+//
+// Deprecated: use mobile.VerificationErrorToJSON.
+func VerificationErrorToJSON(err error) ([]byte, error) {
+	return mobile.VerificationErrorToJSON(err)
+}
+
+// GetJsonSHA256Fingerprints forwards to mobile.GetJsonSHA256Fingerprints.
+//
+// Deprecated: use mobile.GetJsonSHA256Fingerprints.
+func GetJsonSHA256Fingerprints(publicKey string) ([]byte, error) {
+	return mobile.GetJsonSHA256Fingerprints(publicKey)
+}
+
+// GetJsonKeyFlags forwards to mobile.GetJsonKeyFlags.
+//
+// Deprecated: use mobile.GetJsonKeyFlags.
+func GetJsonKeyFlags(publicKey string) ([]byte, error) {
+	return mobile.GetJsonKeyFlags(publicKey)
+}
+
+// GetJsonKeyMetadataList forwards to mobile.GetJsonKeyMetadataList.
+//
+// Deprecated: use mobile.GetJsonKeyMetadataList.
+func GetJsonKeyMetadataList(armoredKeys string) ([]byte, error) {
+	return mobile.GetJsonKeyMetadataList(armoredKeys)
+}
+
+// FreeOSMemory forwards to mobile.FreeOSMemory.
+//
+// Deprecated: use mobile.FreeOSMemory.
+func FreeOSMemory() {
+	mobile.FreeOSMemory()
+}
+
+// NewMobile2GoWriter forwards to mobile.NewMobile2GoWriter.
+//
+// Deprecated: use mobile.NewMobile2GoWriter.
+func NewMobile2GoWriter(writer crypto.Writer) *Mobile2GoWriter {
+	return mobile.NewMobile2GoWriter(writer)
+}
+
+// NewMobile2GoWriterWithSHA256 forwards to mobile.NewMobile2GoWriterWithSHA256.
+//
+// Deprecated: use mobile.NewMobile2GoWriterWithSHA256.
+func NewMobile2GoWriterWithSHA256(writer crypto.Writer) *Mobile2GoWriterWithSHA256 {
+	return mobile.NewMobile2GoWriterWithSHA256(writer)
+}
+
+// NewMobileReadResult forwards to mobile.NewMobileReadResult.
+//
+// Deprecated: use mobile.NewMobileReadResult.
+func NewMobileReadResult(n int, eof bool, data []byte) *MobileReadResult {
+	return mobile.NewMobileReadResult(n, eof, data)
+}
+
+// NewMobile2GoReader forwards to mobile.NewMobile2GoReader.
+//
+// Deprecated: use mobile.NewMobile2GoReader.
+func NewMobile2GoReader(reader MobileReader) *Mobile2GoReader {
+	return mobile.NewMobile2GoReader(reader)
+}
+
+// NewGo2AndroidReader forwards to mobile.NewGo2AndroidReader.
+//
+// Deprecated: use mobile.NewGo2AndroidReader.
+func NewGo2AndroidReader(reader crypto.Reader) *Go2AndroidReader {
+	return mobile.NewGo2AndroidReader(reader)
+}
+
+// NewGo2IOSReader forwards to mobile.NewGo2IOSReader.
+//
+// Deprecated: use mobile.NewGo2IOSReader.
+func NewGo2IOSReader(reader crypto.Reader) *Go2IOSReader {
+	return mobile.NewGo2IOSReader(reader)
+}
+
+// VerifySignatureExplicit forwards to mobile.VerifySignatureExplicit.
+//
+// Deprecated: use mobile.VerifySignatureExplicit.
+func VerifySignatureExplicit(
+	reader *crypto.PlainMessageReader,
+) (signatureVerificationError *crypto.SignatureVerificationError, err error) {
+	return mobile.VerifySignatureExplicit(reader)
+}
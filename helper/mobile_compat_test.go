@@ -0,0 +1,46 @@
+//go:build !gopenpgp_no_mobile_compat
+// +build !gopenpgp_no_mobile_compat
+
+package helper
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+	"github.com/yougroupteam/gopenpgp/v2/helper/mobile"
+)
+
+func TestMobileCompat(t *testing.T) {
+	privateKey, _ := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	privateKey, err := privateKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error unlocking privateKey, got:", err)
+	}
+	testPrivateKeyRing, _ := crypto.NewKeyRing(privateKey)
+
+	pgpMessage, err := crypto.NewPGPMessageFromArmored(readTestFile("message_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+
+	var decrypted *mobile.ExplicitVerifyMessage
+	decrypted, err = DecryptExplicitVerify(pgpMessage, testPrivateKeyRing, testPrivateKeyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, constants.SIGNATURE_NO_VERIFIER, decrypted.SignatureVerificationError.Status)
+	assert.Exactly(t, readTestFile("message_plaintext", true), decrypted.Message.GetString())
+
+	var result *mobile.MobileReadResult = NewMobileReadResult(3, true, []byte("abc"))
+	assert.Exactly(t, mobile.NewMobileReadResult(3, true, []byte("abc")), result)
+
+	var buffer bytes.Buffer
+	var writer *mobile.Mobile2GoWriter = NewMobile2GoWriter(&buffer)
+	if _, err := writer.Write([]byte("abc")); err != nil {
+		t.Fatal("Expected no error when writing, got:", err)
+	}
+	assert.Exactly(t, "abc", buffer.String())
+}
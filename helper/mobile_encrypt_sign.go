@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/constants"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+	"github.com/yougroupteam/gopenpgp/v2/internal"
+)
+
+// EncryptSignHandle encrypts and signs a text message written in chunks, so
+// that mobile apps don't have to pass the whole plaintext at once, see
+// NewEncryptSignHandle. The result is the same as the one of
+// EncryptSignMessageArmored with the concatenated chunks.
+type EncryptSignHandle struct {
+	unlockedKey *crypto.Key
+	armored     bytes.Buffer
+	armorWriter io.WriteCloser
+	plainWriter crypto.WriteCloser
+	// Whitespace at the end of the data written, which is trimmed if it ends
+	// the line
+	pending []byte
+	done    bool
+}
+
+// NewEncryptSignHandle returns a handle encrypting the chunks of a text
+// message to publicKey, and signing them with privateKey, unlocked with
+// passphrase. The chunks are written with Write, and the armored message is
+// returned by Finish.
+// The key is unlocked until the handle is finished or aborted, or garbage
+// collected if it is abandoned; the handle doesn't start goroutines.
+func NewEncryptSignHandle(publicKey, privateKey string, passphrase []byte) (*EncryptSignHandle, error) {
+	publicKeyRing, err := createPublicKeyRing(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyObj, err := crypto.NewKeyFromArmored(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read key")
+	}
+	unlockedKeyObj, err := privateKeyObj.Unlock(passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to unlock key")
+	}
+	handle := &EncryptSignHandle{unlockedKey: unlockedKeyObj}
+	runtime.SetFinalizer(handle, (*EncryptSignHandle).Abort)
+
+	privateKeyRing, err := crypto.NewKeyRing(unlockedKeyObj)
+	if err != nil {
+		handle.Abort()
+		return nil, errors.Wrap(err, "gopenpgp: unable to create new keyring")
+	}
+
+	handle.armorWriter, err = armor.Encode(&handle.armored, constants.PGPMessageHeader, internal.ArmorHeaders)
+	if err != nil {
+		handle.Abort()
+		return nil, errors.Wrap(err, "gopenpgp: unable to armor ciphertext")
+	}
+	// Same metadata as crypto.NewPlainMessageFromString
+	handle.plainWriter, err = publicKeyRing.EncryptStream(handle.armorWriter, &crypto.PlainMessageMetadata{
+		IsBinary: false,
+		Filename: "",
+		ModTime:  crypto.GetUnixTime(),
+	}, privateKeyRing)
+	if err != nil {
+		handle.Abort()
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt message")
+	}
+	return handle, nil
+}
+
+// Write encrypts the next chunk of the message. Like
+// crypto.NewPlainMessageFromString, the line endings are canonicalized to
+// CRLF and the whitespace at the end of each line is trimmed, even if a line
+// spans several chunks. The chunk is copied, it can be reused by the caller.
+// If an error is returned, the handle is aborted.
+func (handle *EncryptSignHandle) Write(chunk []byte) (n int, err error) {
+	if handle.done {
+		return 0, errors.New("gopenpgp: the encryption handle is finished")
+	}
+	data := append(handle.pending, chunk...)
+	var out []byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		out = append(out, bytes.TrimRight(data[:i], " \t\r")...)
+		out = append(out, '\r', '\n')
+		data = data[i+1:]
+	}
+	// Keep the whitespace which may end the line
+	end := len(bytes.TrimRight(data, " \t\r"))
+	out = append(out, data[:end]...)
+	handle.pending = clone(data[end:])
+
+	if _, err := handle.plainWriter.Write(out); err != nil {
+		handle.Abort()
+		return 0, errors.Wrap(err, "gopenpgp: error in writing to message")
+	}
+	return len(chunk), nil
+}
+
+// Finish encrypts the end of the message, and returns the armored message.
+// The key is cleared, and the handle can't be used anymore.
+func (handle *EncryptSignHandle) Finish() (string, error) {
+	if handle.done {
+		return "", errors.New("gopenpgp: the encryption handle is finished")
+	}
+	defer handle.Abort()
+
+	if err := handle.plainWriter.Close(); err != nil {
+		return "", errors.Wrap(err, "gopenpgp: error in closing message")
+	}
+	if err := handle.armorWriter.Close(); err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to armor ciphertext")
+	}
+	return handle.armored.String(), nil
+}
+
+// Abort clears the key without finishing the message.
+// It can be called several times, and after Finish.
+func (handle *EncryptSignHandle) Abort() {
+	if handle.done {
+		return
+	}
+	handle.done = true
+	handle.unlockedKey.ClearPrivateParams()
+	handle.unlockedKey = nil
+	handle.plainWriter = nil
+	handle.armorWriter = nil
+	handle.pending = nil
+	runtime.SetFinalizer(handle, nil)
+}
@@ -0,0 +1,92 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptSignInChunks(t *testing.T, chunks []string) string {
+	handle, err := NewEncryptSignHandle(
+		readTestFile("keyring_publicKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	for _, chunk := range chunks {
+		if _, err := handle.Write([]byte(chunk)); err != nil {
+			t.Fatal("Expected no error while writing chunk, got:", err)
+		}
+	}
+	armored, err := handle.Finish()
+	if err != nil {
+		t.Fatal("Expected no error while finishing encryption, got:", err)
+	}
+	return armored
+}
+
+func TestEncryptSignHandle(t *testing.T) {
+	chunks := []string{"first line  ", " \r", "\nsecond", " line\t\n", "\n", "last line ", " "}
+	plaintext := strings.Join(chunks, "")
+
+	oneShot, err := EncryptSignMessageArmored(
+		readTestFile("keyring_publicKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		plaintext,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	chunked := encryptSignInChunks(t, chunks)
+	assert.Len(t, chunked, len(oneShot))
+
+	expected, err := decryptMessageArmored(readTestFile("keyring_privateKey", false), testMailboxPassword, oneShot)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	decrypted, err := decryptMessageArmored(readTestFile("keyring_privateKey", false), testMailboxPassword, chunked)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "first line\r\nsecond line\r\n\r\nlast line", string(decrypted.GetBinary()))
+	assert.Exactly(t, expected.GetBinary(), decrypted.GetBinary())
+	assert.Exactly(t, expected.GetFormat(), decrypted.GetFormat())
+
+	verified, err := DecryptVerifyMessageArmored(
+		readTestFile("keyring_publicKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+		chunked,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	assert.Exactly(t, "first line\nsecond line\n\nlast line", verified)
+}
+
+func TestEncryptSignHandleAbort(t *testing.T) {
+	handle, err := NewEncryptSignHandle(
+		readTestFile("keyring_publicKey", false),
+		readTestFile("keyring_privateKey", false),
+		testMailboxPassword,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	unlockedKey := handle.unlockedKey
+	if _, err := handle.Write([]byte("abandoned")); err != nil {
+		t.Fatal("Expected no error while writing chunk, got:", err)
+	}
+
+	handle.Abort()
+	// The private parameters are cleared
+	assert.False(t, unlockedKey.IsPrivate())
+	_, err = handle.Write([]byte("more"))
+	assert.Error(t, err)
+	_, err = handle.Finish()
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"net/url"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+// zBase32Alphabet is the alphabet of the z-base-32 encoding of the hashed
+// local parts in WKD URLs.
+const zBase32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+
+// sigTypeCertificationRevocation is the type of user ID revocations, which the
+// library does not define.
+const sigTypeCertificationRevocation packet.SignatureType = 0x30
+
+// WKDURLsForAddress returns the URLs where the keys of email are published in
+// a Web Key Directory, with the direct and the advanced method, see
+// draft-koch-openpgp-webkey-service. The advanced URL should be tried first.
+func WKDURLsForAddress(email string) (direct, advanced string, err error) {
+	localPart, domain, err := splitAddress(email)
+	if err != nil {
+		return "", "", err
+	}
+	domain = strings.ToLower(domain)
+	hashed := wkdHashLocalPart(localPart)
+	query := "?l=" + url.QueryEscape(localPart)
+
+	direct = "https://" + domain + "/.well-known/openpgpkey/hu/" + hashed + query
+	advanced = "https://openpgpkey." + domain + "/.well-known/openpgpkey/" + domain + "/hu/" + hashed + query
+	return direct, advanced, nil
+}
+
+// ParseWKDResponse parses the keys of a WKD response body, binary as mandated
+// or armored, and returns the keyring of their public keys having a user ID,
+// not revoked, with the address email. Returns an error if there is none.
+func ParseWKDResponse(body []byte, email string) (*crypto.KeyRing, error) {
+	if _, _, err := splitAddress(email); err != nil {
+		return nil, err
+	}
+
+	var keys []*crypto.Key
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("-----BEGIN")) {
+		keys, err = crypto.NewKeysFromArmored(string(body))
+	} else {
+		var entities openpgp.EntityList
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(body))
+		for _, entity := range entities {
+			key, keyErr := crypto.NewKeyFromEntity(entity)
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			keys = append(keys, key)
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse WKD response")
+	}
+
+	keyRing, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if !hasUserIDWithAddress(key.GetEntity(), email) {
+			continue
+		}
+		if key.IsPrivate() {
+			if key, err = key.ToPublic(); err != nil {
+				return nil, err
+			}
+		}
+		if err := keyRing.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if keyRing.CountEntities() == 0 {
+		return nil, errors.New("gopenpgp: no key in the WKD response matches the address")
+	}
+	return keyRing, nil
+}
+
+// splitAddress splits an email address at its last @.
+func splitAddress(email string) (localPart, domain string, err error) {
+	i := strings.LastIndexByte(email, '@')
+	if i <= 0 || i == len(email)-1 || strings.ContainsAny(email, " \t\r\n/?#") {
+		return "", "", errors.Errorf("gopenpgp: invalid email address %q", email)
+	}
+	return email[:i], email[i+1:], nil
+}
+
+// wkdHashLocalPart returns the z-base-32 encoded SHA-1 hash of the local part,
+// with the ASCII letters mapped to lower case.
+func wkdHashLocalPart(localPart string) string {
+	lower := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, localPart)
+	digest := sha1.Sum([]byte(lower)) //nolint:gosec
+
+	// 160 bits, encoded 5 bits at a time
+	var encoded strings.Builder
+	var buffer uint
+	bits := 0
+	for _, b := range digest {
+		buffer = buffer<<8 | uint(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			encoded.WriteByte(zBase32Alphabet[(buffer>>uint(bits))&0x1f])
+		}
+	}
+	return encoded.String()
+}
+
+// hasUserIDWithAddress returns whether entity has a user ID with the address
+// email, compared case-insensitively, which is not revoked.
+func hasUserIDWithAddress(entity *openpgp.Entity, email string) bool {
+	for name, identity := range entity.Identities {
+		if !strings.EqualFold(identity.UserId.Email, email) {
+			continue
+		}
+		if identity.SelfSignature.SigType == sigTypeCertificationRevocation {
+			continue
+		}
+		revoked := false
+		for _, sig := range identity.Signatures {
+			if sig.SigType == sigTypeCertificationRevocation &&
+				sig.CheckKeyIdOrFingerprint(entity.PrimaryKey) &&
+				entity.PrimaryKey.VerifyUserIdSignature(name, entity.PrimaryKey, sig) == nil {
+				revoked = true
+			}
+		}
+		if !revoked {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yougroupteam/gopenpgp/v2/crypto"
+)
+
+func TestWKDURLsForAddress(t *testing.T) {
+	// Example of draft-koch-openpgp-webkey-service
+	direct, advanced, err := WKDURLsForAddress("Joe.Doe@Example.ORG")
+	if err != nil {
+		t.Fatal("Expected no error while computing WKD URLs, got:", err)
+	}
+	assert.Exactly(t, "https://example.org/.well-known/openpgpkey/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe", direct)
+	assert.Exactly(t,
+		"https://openpgpkey.example.org/.well-known/openpgpkey/example.org/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+		advanced,
+	)
+
+	for _, invalid := range []string{"", "joe.doe", "@example.org", "joe.doe@", "joe doe@example.org"} {
+		_, _, err := WKDURLsForAddress(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseWKDResponse(t *testing.T) {
+	var body []byte
+	var fingerprints []string
+	for _, name := range []string{"key_usageSubkeys", "key_certified"} {
+		key, err := crypto.NewKeyFromArmored(readTestFile(name, false))
+		if err != nil {
+			t.Fatal("Expected no error while reading key, got:", err)
+		}
+		serialized, err := key.GetPublicKey()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		body = append(body, serialized...)
+		fingerprints = append(fingerprints, key.GetFingerprint())
+	}
+
+	keyRing, err := ParseWKDResponse(body, "Usage@Example.com")
+	if err != nil {
+		t.Fatal("Expected no error while parsing WKD response, got:", err)
+	}
+	assert.Exactly(t, 1, keyRing.CountEntities())
+	assert.Exactly(t, fingerprints[0], keyRing.GetKeys()[0].GetFingerprint())
+
+	keyRing, err = ParseWKDResponse(body, "minimal@example.com")
+	if err != nil {
+		t.Fatal("Expected no error while parsing WKD response, got:", err)
+	}
+	assert.Exactly(t, fingerprints[1], keyRing.GetKeys()[0].GetFingerprint())
+
+	// Revoked user ID
+	_, err = ParseWKDResponse(body, "revoked@example.com")
+	assert.Error(t, err)
+	_, err = ParseWKDResponse(body, "unknown@example.com")
+	assert.Error(t, err)
+	_, err = ParseWKDResponse([]byte("not a key"), "usage@example.com")
+	assert.Error(t, err)
+
+	// Armored responses are tolerated
+	keyRing, err = ParseWKDResponse([]byte(readTestFile("key_usageSubkeys", false)), "usage@example.com")
+	if err != nil {
+		t.Fatal("Expected no error while parsing armored WKD response, got:", err)
+	}
+	assert.Exactly(t, fingerprints[0], keyRing.GetKeys()[0].GetFingerprint())
+}
@@ -2,17 +2,191 @@ package internal
 
 import (
 	"strings"
+	"sync/atomic"
 
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/pkg/errors"
 )
 
+// Framing variants of armored data tolerated when unarmoring, see
+// NormalizeArmor.
+const (
+	ArmorIssueCRLF             = "CRLF line endings"
+	ArmorIssueMissingBlankLine = "missing blank line after the headers"
+	ArmorIssueTrailingSpace    = "trailing whitespace"
+	ArmorIssueInlineChecksum   = "missing newline before the checksum"
+	ArmorIssueInlineEnd        = "missing newline before the end line"
+	ArmorIssueMissingChecksum  = "missing checksum"
+)
+
+const (
+	armorBegin = "-----BEGIN "
+	armorEnd   = "-----END "
+)
+
+// Whether strict mode is set, 1 or 0, accessed atomically
+var strictArmor int32
+
+// SetStrictArmor sets whether Unarmor rejects the framing variants of armored
+// data which it otherwise tolerates, except CRLF line endings, which are
+// always accepted.
+func SetStrictArmor(strict bool) {
+	var value int32
+	if strict {
+		value = 1
+	}
+	atomic.StoreInt32(&strictArmor, value)
+}
+
+func isStrictArmor() bool {
+	return atomic.LoadInt32(&strictArmor) == 1
+}
+
 // Unarmor unarmors an armored string.
+// The framing variants written by some implementations are tolerated, like
+// GnuPG does, unless strict mode is set, see SetStrictArmor.
 func Unarmor(input string) (*armor.Block, error) {
-	io := strings.NewReader(input)
-	b, err := armor.Decode(io)
+	normalized, issues := NormalizeArmor(input)
+	if isStrictArmor() && len(issues) > 0 && issues[0] == ArmorIssueCRLF {
+		issues = issues[1:]
+	}
+	if isStrictArmor() && len(issues) > 0 {
+		return nil, errors.New("gopenpgp: unable to armor: non-canonical framing: " + strings.Join(issues, ", "))
+	}
+	b, err := armor.Decode(strings.NewReader(normalized))
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: unable to armor")
 	}
 	return b, nil
 }
+
+// NormalizeArmor rewrites the armored blocks of input with the framing
+// expected by the armor decoder, and returns the variants which were fixed,
+// e.g. a checksum written on the last line of data, in the order of the
+// ArmorIssue constants.
+func NormalizeArmor(input string) (normalized string, issues []string) {
+	found := make(map[string]bool)
+	lines := strings.Split(input, "\n")
+	out := make([]string, 0, len(lines)+4)
+
+	const (
+		outside = iota
+		inHeaders
+		inBody
+	)
+	state := outside
+	hasChecksum := false
+	for _, line := range lines {
+		if state == outside {
+			out = append(out, line)
+			if strings.HasPrefix(strings.TrimSpace(line), armorBegin) {
+				state = inHeaders
+				hasChecksum = false
+			}
+			continue
+		}
+
+		if strings.HasSuffix(line, "\r") {
+			found[ArmorIssueCRLF] = true
+		}
+		trimmed := strings.TrimRight(line, " \t\r")
+		if trimmed != strings.TrimRight(line, "\r") {
+			found[ArmorIssueTrailingSpace] = true
+		}
+		line = trimmed
+
+		if state == inHeaders {
+			switch {
+			case line == "":
+				out = append(out, line)
+				state = inBody
+				continue
+			case strings.Contains(line, ": "):
+				out = append(out, line)
+				continue
+			}
+			found[ArmorIssueMissingBlankLine] = true
+			out = append(out, "")
+			state = inBody
+		}
+
+		if i := strings.Index(line, armorEnd); i > 0 {
+			found[ArmorIssueInlineEnd] = true
+			out, hasChecksum = appendArmorBodyLine(out, line[:i], hasChecksum, found)
+			line = line[i:]
+		}
+		if strings.HasPrefix(line, armorEnd) {
+			if !hasChecksum {
+				found[ArmorIssueMissingChecksum] = true
+			}
+			out = append(out, line)
+			state = outside
+			continue
+		}
+		out, hasChecksum = appendArmorBodyLine(out, line, hasChecksum, found)
+	}
+
+	for _, issue := range []string{
+		ArmorIssueCRLF,
+		ArmorIssueMissingBlankLine,
+		ArmorIssueTrailingSpace,
+		ArmorIssueInlineChecksum,
+		ArmorIssueInlineEnd,
+		ArmorIssueMissingChecksum,
+	} {
+		if found[issue] {
+			issues = append(issues, issue)
+		}
+	}
+	normalized = strings.Join(out, "\n")
+	if !strings.HasSuffix(normalized, "\n") {
+		normalized += "\n"
+	}
+	return normalized, issues
+}
+
+// appendArmorBodyLine appends a line of the body of an armored block to out,
+// splitting a checksum written at the end of the data.
+func appendArmorBodyLine(out []string, line string, hasChecksum bool, found map[string]bool) ([]string, bool) {
+	// Base64 data only contains '=' as padding at its end, so a '=' followed
+	// by four other characters is a checksum
+	if len(line) > 5 && line[len(line)-5] == '=' && !strings.Contains(line[len(line)-4:], "=") {
+		found[ArmorIssueInlineChecksum] = true
+		return append(out, line[:len(line)-5], line[len(line)-5:]), true
+	}
+	if len(line) == 5 && line[0] == '=' {
+		hasChecksum = true
+	}
+	return append(out, line), hasChecksum
+}
+
+// SplitArmoredBlocks returns the armored blocks of input of the given type,
+// e.g. "PGP MESSAGE", in order, from their begin line to their end line,
+// ignoring the text around them. A block without end line extends to the end
+// of input.
+func SplitArmoredBlocks(input, blockType string) []string {
+	begin := armorBegin + blockType + "-----"
+	end := armorEnd + blockType + "-----"
+
+	var blocks []string
+	var block []string
+	inBlock := false
+	for _, line := range strings.Split(input, "\n") {
+		if !inBlock {
+			if strings.TrimSpace(line) == begin {
+				inBlock = true
+				block = []string{line}
+			}
+			continue
+		}
+		block = append(block, line)
+		if strings.Contains(line, end) {
+			blocks = append(blocks, strings.Join(block, "\n")+"\n")
+			inBlock = false
+		}
+	}
+	if inBlock {
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+	return blocks
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/pkg/errors"
+)
+
+const (
+	crc24Init = 0xb704ce
+	crc24Poly = 0x1864cfb
+	crc24Mask = 0xffffff
+)
+
+// UnarmorConstantTime unarmors an armored string like Unarmor, but decodes
+// the body and compares its checksum without branching on, or indexing tables
+// with, the decoded data, so that unarmoring secret material, e.g. private
+// keys, doesn't leak it through timing side channels.
+// Only the framing of the block, which is public, is parsed with branches.
+func UnarmorConstantTime(input string) (*armor.Block, error) {
+	normalized, issues := NormalizeArmor(input)
+	if isStrictArmor() && len(issues) > 0 {
+		return nil, errors.New("gopenpgp: unable to armor: non-canonical framing: " + strings.Join(issues, ", "))
+	}
+
+	lines := strings.Split(normalized, "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, armorBegin) && strings.HasSuffix(line, "-----") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, errors.New("gopenpgp: unable to armor: no armored block found")
+	}
+	block := &armor.Block{
+		Type:   strings.TrimSuffix(strings.TrimPrefix(lines[start], armorBegin), "-----"),
+		Header: make(map[string]string),
+	}
+
+	i := start + 1
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		header := strings.SplitN(lines[i], ": ", 2)
+		if len(header) != 2 {
+			return nil, errors.New("gopenpgp: unable to armor: invalid header")
+		}
+		block.Header[header[0]] = header[1]
+	}
+
+	body := make([]byte, 0, len(normalized))
+	var checksum string
+	end := armorEnd + block.Type + "-----"
+	for i++; ; i++ {
+		if i >= len(lines) {
+			return nil, errors.New("gopenpgp: unable to armor: missing end line")
+		}
+		line := lines[i]
+		if line == end {
+			break
+		}
+		if len(line) == 5 && line[0] == '=' {
+			checksum = line[1:]
+			continue
+		}
+		if checksum != "" {
+			return nil, errors.New("gopenpgp: unable to armor: data after the checksum")
+		}
+		body = append(body, line...)
+	}
+
+	data, err := DecodeBase64ConstantTime(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to armor")
+	}
+	if checksum != "" {
+		expected, err := DecodeBase64ConstantTime([]byte(checksum))
+		if err != nil || len(expected) != 3 {
+			return nil, errors.New("gopenpgp: unable to armor: invalid checksum")
+		}
+		crc := crc24ConstantTime(data)
+		if subtle.ConstantTimeCompare(expected, []byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) != 1 {
+			return nil, errors.New("gopenpgp: unable to armor: checksum mismatch")
+		}
+	}
+	block.Body = bytes.NewReader(data)
+	return block, nil
+}
+
+// DecodeBase64ConstantTime decodes padded standard base64 like
+// base64.StdEncoding, without newlines, in constant time with respect to the
+// decoded data: only the length of the input and whether it is valid leak.
+func DecodeBase64ConstantTime(encoded []byte) ([]byte, error) {
+	if len(encoded)%4 != 0 {
+		return nil, errors.New("gopenpgp: invalid base64 length")
+	}
+	// The padding is at a public position
+	padding := 0
+	for padding < 2 && len(encoded)-padding > 0 && encoded[len(encoded)-padding-1] == '=' {
+		padding++
+	}
+	encoded = encoded[:len(encoded)-padding]
+
+	decoded := make([]byte, len(encoded)*3/4)
+	// Negative if any character is invalid
+	var invalid int32
+	full := len(encoded) / 4 * 4
+	for i, j := 0, 0; i < full; i, j = i+4, j+3 {
+		c0 := decodeBase64CharConstantTime(encoded[i])
+		c1 := decodeBase64CharConstantTime(encoded[i+1])
+		c2 := decodeBase64CharConstantTime(encoded[i+2])
+		c3 := decodeBase64CharConstantTime(encoded[i+3])
+		invalid |= c0 | c1 | c2 | c3
+		group := c0<<18 | c1<<12 | c2<<6 | c3
+		decoded[j], decoded[j+1], decoded[j+2] = byte(group>>16), byte(group>>8), byte(group)
+	}
+	var group int32
+	for _, c := range encoded[full:] {
+		value := decodeBase64CharConstantTime(c)
+		invalid |= value
+		group = group<<6 | value
+	}
+	// The trailing bits of the last character are ignored, like
+	// base64.StdEncoding does
+	switch len(encoded) - full {
+	case 1:
+		invalid = -1
+	case 2:
+		decoded[len(decoded)-1] = byte(group >> 4)
+	case 3:
+		decoded[len(decoded)-2], decoded[len(decoded)-1] = byte(group>>10), byte(group>>2)
+	}
+	if invalid < 0 {
+		return nil, errors.New("gopenpgp: invalid base64 data")
+	}
+	return decoded, nil
+}
+
+// decodeBase64CharConstantTime returns the 6-bit value of the base64
+// character c, or a negative value if c is invalid.
+func decodeBase64CharConstantTime(c byte) int32 {
+	// Each term is the offset of the value, plus one, where c is in the range
+	// of the term, since both differences are then negative, and 0 elsewhere
+	src := int32(c)
+	value := int32(-1)
+	value += ((('A' - 1 - src) & (src - 'Z' - 1)) >> 8) & (src - 'A' + 1)
+	value += ((('a' - 1 - src) & (src - 'z' - 1)) >> 8) & (src - 'a' + 26 + 1)
+	value += ((('0' - 1 - src) & (src - '9' - 1)) >> 8) & (src - '0' + 52 + 1)
+	value += ((('+' - 1 - src) & (src - '+' - 1)) >> 8) & (62 + 1)
+	value += ((('/' - 1 - src) & (src - '/' - 1)) >> 8) & (63 + 1)
+	return value
+}
+
+// crc24Basis holds the checksum update for each bit of a byte, see
+// crc24ConstantTime.
+var crc24Basis = func() (basis [8]uint32) {
+	for i := range basis {
+		crc := uint32(1) << (16 + i)
+		for j := 0; j < 8; j++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+		basis[i] = crc & crc24Mask
+	}
+	return basis
+}()
+
+// crc24ConstantTime computes the checksum of armored data, see RFC 4880,
+// section 6.1, without branching on the data nor indexing a table with it:
+// the update for a byte, which is linear, is the XOR of the masked updates
+// of its bits.
+func crc24ConstantTime(data []byte) uint32 {
+	var crc uint32 = crc24Init
+	for _, b := range data {
+		top := crc>>16 ^ uint32(b)
+		crc = crc<<8 ^
+			crc24Basis[0]&-(top&1) ^
+			crc24Basis[1]&-(top>>1&1) ^
+			crc24Basis[2]&-(top>>2&1) ^
+			crc24Basis[3]&-(top>>3&1) ^
+			crc24Basis[4]&-(top>>4&1) ^
+			crc24Basis[5]&-(top>>5&1) ^
+			crc24Basis[6]&-(top>>6&1) ^
+			crc24Basis[7]&-(top>>7&1)
+	}
+	return crc & crc24Mask
+}
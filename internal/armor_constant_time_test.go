@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+)
+
+const base64TestAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func TestDecodeBase64ConstantTime(t *testing.T) {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+	for i := 0; i < 10000; i++ {
+		var encoded []byte
+		if i%2 == 0 {
+			data := make([]byte, r.Intn(100))
+			_, _ = r.Read(data)
+			encoded = []byte(base64.StdEncoding.EncodeToString(data))
+		} else {
+			// Mostly valid characters, with padding and invalid ones
+			encoded = make([]byte, r.Intn(24))
+			for j := range encoded {
+				switch r.Intn(20) {
+				case 0:
+					encoded[j] = '='
+				case 1:
+					encoded[j] = byte(r.Intn(256))
+				default:
+					encoded[j] = base64TestAlphabet[r.Intn(len(base64TestAlphabet))]
+				}
+			}
+		}
+		// Change a character of valid data
+		if i%3 == 0 && len(encoded) > 0 {
+			encoded[r.Intn(len(encoded))] = base64TestAlphabet[r.Intn(len(base64TestAlphabet))]
+		}
+
+		expected, expectedErr := base64.StdEncoding.DecodeString(string(encoded))
+		if bytes.ContainsAny(encoded, "\r\n") {
+			continue
+		}
+		decoded, err := DecodeBase64ConstantTime(encoded)
+		if expectedErr != nil {
+			assert.Error(t, err, string(encoded))
+			continue
+		}
+		if err != nil {
+			t.Fatal("Expected no error while decoding", string(encoded), "got:", err)
+		}
+		assert.Exactly(t, expected, decoded, string(encoded))
+	}
+}
+
+func TestUnarmorConstantTime(t *testing.T) {
+	r := rand.New(rand.NewSource(2)) //nolint:gosec
+	for i := 0; i < 500; i++ {
+		data := make([]byte, r.Intn(2000))
+		_, _ = r.Read(data)
+		var armored bytes.Buffer
+		w, err := armor.Encode(&armored, "PGP PRIVATE KEY BLOCK", map[string]string{"Comment": "test"})
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		_, _ = w.Write(data)
+		_ = w.Close()
+
+		expected, err := Unarmor(armored.String())
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring, got:", err)
+		}
+		expectedData, _ := ioutil.ReadAll(expected.Body)
+		block, err := UnarmorConstantTime(armored.String())
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring in constant time, got:", err)
+		}
+		decoded, _ := ioutil.ReadAll(block.Body)
+		assert.Exactly(t, expectedData, decoded)
+		assert.Exactly(t, expected.Type, block.Type)
+		assert.Exactly(t, expected.Header, block.Header)
+		assert.Exactly(t, crc24ConstantTime(data), crc24(data))
+	}
+}
+
+func TestUnarmorConstantTimeChecksum(t *testing.T) {
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, "PGP PRIVATE KEY BLOCK", nil)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	_, _ = w.Write([]byte("secret material"))
+	_ = w.Close()
+
+	tampered := bytes.Replace(armored.Bytes(), []byte("c2VjcmV0"), []byte("c2VjcmV1"), 1)
+	_, err = UnarmorConstantTime(string(tampered))
+	assert.Error(t, err)
+	// The reference decoder reports the mismatch when reading the body
+	block, err := Unarmor(string(tampered))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	_, err = ioutil.ReadAll(block.Body)
+	assert.Error(t, err)
+}
+
+// crc24 is the reference implementation of the armor checksum.
+func crc24(data []byte) uint32 {
+	var crc uint32 = crc24Init
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & crc24Mask
+}
+
+func benchmarkArmoredKey(b *testing.B) string {
+	// The size of an armored 4096-bit RSA private key
+	data := make([]byte, 7000)
+	_, _ = rand.New(rand.NewSource(3)).Read(data) //nolint:gosec
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, "PGP PRIVATE KEY BLOCK", nil)
+	if err != nil {
+		b.Fatal("Expected no error while armoring, got:", err)
+	}
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return armored.String()
+}
+
+func BenchmarkUnarmor(b *testing.B) {
+	armored := benchmarkArmoredKey(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		block, err := Unarmor(armored)
+		if err != nil {
+			b.Fatal("Expected no error while unarmoring, got:", err)
+		}
+		_, _ = ioutil.ReadAll(block.Body)
+	}
+}
+
+func BenchmarkUnarmorConstantTime(b *testing.B) {
+	armored := benchmarkArmoredKey(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		block, err := UnarmorConstantTime(armored)
+		if err != nil {
+			b.Fatal("Expected no error while unarmoring, got:", err)
+		}
+		_, _ = ioutil.ReadAll(block.Body)
+	}
+}
@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/pkg/errors"
+)
+
+// Alterations of armored data copy-pasted from rich text, e.g. HTML mail,
+// repaired by SanitizeArmor.
+const (
+	ArmorFixUnicodeSpaces  = "non-ASCII spaces"
+	ArmorFixInvisibleChars = "zero-width characters"
+	ArmorFixDashes         = "typographic dashes in the armor lines"
+	ArmorFixSmartQuotes    = "typographic quotes in the headers"
+	ArmorFixLeadingSpace   = "leading whitespace"
+	ArmorFixWrappedHeaders = "wrapped header lines"
+)
+
+// armorSanitizer maps the characters of the armored blocks altered by rich
+// text editors to their ASCII originals, or drops them.
+var armorSanitizer = map[rune]struct {
+	replacement string
+	fix         string
+}{
+	'\u00a0': {" ", ArmorFixUnicodeSpaces}, // No-break space
+	'\u1680': {" ", ArmorFixUnicodeSpaces},
+	'\u2000': {" ", ArmorFixUnicodeSpaces},
+	'\u2001': {" ", ArmorFixUnicodeSpaces},
+	'\u2002': {" ", ArmorFixUnicodeSpaces},
+	'\u2003': {" ", ArmorFixUnicodeSpaces},
+	'\u2004': {" ", ArmorFixUnicodeSpaces},
+	'\u2005': {" ", ArmorFixUnicodeSpaces},
+	'\u2006': {" ", ArmorFixUnicodeSpaces},
+	'\u2007': {" ", ArmorFixUnicodeSpaces},
+	'\u2008': {" ", ArmorFixUnicodeSpaces},
+	'\u2009': {" ", ArmorFixUnicodeSpaces},
+	'\u200a': {" ", ArmorFixUnicodeSpaces},
+	'\u202f': {" ", ArmorFixUnicodeSpaces},
+	'\u205f': {" ", ArmorFixUnicodeSpaces},
+	'\u3000': {" ", ArmorFixUnicodeSpaces},
+	'\u00ad': {"", ArmorFixInvisibleChars}, // Soft hyphen
+	'\u200b': {"", ArmorFixInvisibleChars}, // Zero-width space
+	'\u200c': {"", ArmorFixInvisibleChars},
+	'\u200d': {"", ArmorFixInvisibleChars},
+	'\u2060': {"", ArmorFixInvisibleChars},
+	'\ufeff': {"", ArmorFixInvisibleChars}, // Byte order mark
+}
+
+var armorDashes = strings.NewReplacer(
+	"\u2010", "-", "\u2011", "-", "\u2012", "-", "\u2013", "-", "\u2014", "-", "\u2212", "-",
+)
+
+var armorQuotes = strings.NewReplacer("\u2018", "'", "\u2019", "'", "\u201c", `"`, "\u201d", `"`)
+
+// SanitizeArmor repairs the armored blocks of input altered by a copy-paste
+// from rich text, and returns the repairs which were made, in the order of
+// the ArmorFix constants. The text around the blocks is kept as is, and the
+// framing variants are left to NormalizeArmor.
+func SanitizeArmor(input string) (sanitized string, fixes []string) {
+	found := make(map[string]bool)
+	lines := strings.Split(input, "\n")
+	out := make([]string, 0, len(lines))
+
+	const (
+		outside = iota
+		inHeaders
+		inBody
+	)
+	state := outside
+	// Indentation of the begin line of the current block
+	indent := ""
+	for _, line := range lines {
+		cleaned := sanitizeArmorLine(line, found)
+		trimmed := strings.TrimLeft(cleaned, " \t")
+		indented := trimmed != cleaned
+		armorLine, isArmorLine := sanitizeArmorBoundary(trimmed)
+
+		switch {
+		case state == outside && isArmorLine && strings.HasPrefix(armorLine, armorBegin):
+			if armorLine != strings.TrimRight(trimmed, " \t\r") {
+				found[ArmorFixDashes] = true
+			}
+			if indented {
+				found[ArmorFixLeadingSpace] = true
+			}
+			out = append(out, armorLine)
+			state = inHeaders
+			indent = cleaned[:len(cleaned)-len(trimmed)]
+			continue
+		case state == outside:
+			out = append(out, line)
+			continue
+		case isArmorLine && strings.HasPrefix(armorLine, armorEnd):
+			if armorLine != strings.TrimRight(trimmed, " \t\r") {
+				found[ArmorFixDashes] = true
+			}
+			if indented {
+				found[ArmorFixLeadingSpace] = true
+			}
+			out = append(out, armorLine)
+			state = outside
+			continue
+		}
+
+		if state == inHeaders {
+			switch {
+			case strings.TrimSpace(trimmed) == "":
+				state = inBody
+			case strings.Contains(trimmed, ": "):
+				if quoted := armorQuotes.Replace(trimmed); quoted != trimmed {
+					found[ArmorFixSmartQuotes] = true
+					trimmed = quoted
+				}
+			case strings.Contains(out[len(out)-1], ": ") && isArmorHeaderContinuation(strings.TrimPrefix(cleaned, indent)):
+				found[ArmorFixWrappedHeaders] = true
+				if quoted := armorQuotes.Replace(trimmed); quoted != trimmed {
+					found[ArmorFixSmartQuotes] = true
+					trimmed = quoted
+				}
+				out[len(out)-1] = strings.TrimRight(out[len(out)-1], " \t\r") + " " + trimmed
+				continue
+			default:
+				// A missing blank line, see NormalizeArmor
+				state = inBody
+			}
+		}
+		if indented {
+			found[ArmorFixLeadingSpace] = true
+		}
+		out = append(out, trimmed)
+	}
+
+	for _, fix := range []string{
+		ArmorFixUnicodeSpaces,
+		ArmorFixInvisibleChars,
+		ArmorFixDashes,
+		ArmorFixSmartQuotes,
+		ArmorFixLeadingSpace,
+		ArmorFixWrappedHeaders,
+	} {
+		if found[fix] {
+			fixes = append(fixes, fix)
+		}
+	}
+	return strings.Join(out, "\n"), fixes
+}
+
+// UnarmorLenient unarmors an armored string after repairing it with
+// SanitizeArmor, and tolerates the framing variants even in strict mode.
+// Returns the repairs and framing variants which were fixed.
+func UnarmorLenient(input string) (*armor.Block, []string, error) {
+	sanitized, fixes := SanitizeArmor(input)
+	normalized, issues := NormalizeArmor(sanitized)
+	fixes = append(fixes, issues...)
+	b, err := armor.Decode(strings.NewReader(normalized))
+	if err != nil {
+		return nil, fixes, errors.Wrap(err, "gopenpgp: unable to armor")
+	}
+	return b, fixes, nil
+}
+
+// sanitizeArmorLine replaces the Unicode spaces of line and drops its
+// invisible characters.
+func sanitizeArmorLine(line string, found map[string]bool) string {
+	var b strings.Builder
+	for _, r := range line {
+		if s, ok := armorSanitizer[r]; ok {
+			found[s.fix] = true
+			b.WriteString(s.replacement)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sanitizeArmorBoundary returns the begin or end line of an armored block
+// that line is, once its typographic dashes are replaced, and whether it is
+// one.
+func sanitizeArmorBoundary(line string) (string, bool) {
+	line = strings.TrimRight(armorDashes.Replace(line), " \t\r")
+	label := strings.Trim(line, "-")
+	if !strings.HasPrefix(line, "-") || !strings.HasSuffix(line, "-") ||
+		!(strings.HasPrefix(label, "BEGIN ") || strings.HasPrefix(label, "END ")) {
+		return "", false
+	}
+	return "-----" + label + "-----", true
+}
+
+// isArmorHeaderContinuation returns whether line, which isn't a header, is
+// the rest of a wrapped header line rather than base64 data: it is indented,
+// or contains characters which base64 data doesn't.
+func isArmorHeaderContinuation(line string) bool {
+	if line == "" || strings.TrimSpace(line) == "" {
+		return false
+	}
+	if line[0] == ' ' || line[0] == '\t' {
+		return true
+	}
+	for _, r := range strings.TrimRight(line, " \t\r") {
+		if !isBase64Rune(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBase64Rune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '/' || r == '=')
+}